@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"ai-gateway-hub/internal/auth"
+	"ai-gateway-hub/internal/buildinfo"
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/handlers"
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/loadtest"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/secrets"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+)
+
+// command is a subcommand of the binary
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the subcommands in the order they are shown in the usage
+func commands() []command {
+	return []command{
+		{"serve", "serve", "Run the HTTP server (the default)", runServe},
+		{"migrate", "migrate up|down|status [-steps N]", "Apply, roll back or list database migrations", runMigrate},
+		{"export", "export -chat N [-format json|markdown|html] [-lang en] [-o file]", "Export a chat to a file or stdout", runExport},
+		{"encrypt-messages", "encrypt-messages [-decrypt] [-batch N]", "Encrypt stored messages with MESSAGE_ENCRYPTION_KEY, or decrypt them", runEncryptMessages},
+		{"hash-password", "hash-password", "Hash a password read from stdin for AUTH_USERS_FILE", runHashPassword},
+		{"config", "config validate", "Validate the configuration and print its summary", runConfig},
+		{"provider", "provider check [id...]", "Check that providers are installed and configured", runProvider},
+		{"loadtest", "loadtest -url URL [-connections N] [-rate R] [-duration D] [-provider fake]", "Load test a running hub with WebSocket clients and report latency", runLoadtest},
+		{"version", "version [-json]", "Print the build information", runVersion},
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: ai-gateway-hub <command> [flags]\n\nCommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, cmd := range commands() {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.usage, cmd.summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nRun a command with -h for its flags.\n")
+}
+
+// loadCommandConfig loads the configuration for the administration commands, which only log
+// warnings, to stderr, so their output can be piped
+func loadCommandConfig() (*config.Config, error) {
+	cfg, _, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	utils.InitLogger("warn")
+	utils.RegisterSecret(cfg.AdminToken)
+	utils.RegisterSecret(cfg.ConfigBundleSecret)
+	utils.RegisterSecret(cfg.ShareLinkSecret)
+	utils.RegisterSecret(cfg.VaultToken)
+	utils.RegisterSecret(cfg.JWTSecret)
+	return cfg, nil
+}
+
+// loadValidCommandConfig loads the configuration and rejects it when it is invalid
+func loadValidCommandConfig() (*config.Config, error) {
+	cfg, err := loadCommandConfig()
+	if err != nil {
+		return nil, err
+	}
+	if result := config.ValidateEnvironment(cfg); !result.Valid {
+		return nil, fmt.Errorf("configuration validation failed:\n%s", result.Summary())
+	}
+	return cfg, nil
+}
+
+// parseArgs parses flags placed before and after the positional arguments, e.g. `up -steps 2`
+func parseArgs(flags *flag.FlagSet, args []string) []string {
+	flags.Parse(args)
+	var positional []string
+	for flags.NArg() > 0 {
+		positional = append(positional, flags.Arg(0))
+		flags.Parse(flags.Args()[1:])
+	}
+	return positional
+}
+
+// runMigrate applies, rolls back or lists schema migrations
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations to roll back with down")
+	positional := parseArgs(flags, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("expected one of up, down or status")
+	}
+
+	cfg, err := loadValidCommandConfig()
+	if err != nil {
+		return err
+	}
+	return runMigrateCommand(cfg, positional[0], *steps)
+}
+
+// runExport writes a chat in an export format, like the export button of the chat page
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	chatID := flags.Int64("chat", 0, "ID of the chat to export")
+	format := flags.String("format", "markdown", "export format: json, markdown or html")
+	lang := flags.String("lang", "en", "language of the labels in markdown and html exports")
+	output := flags.String("o", "", "file to write, stdout when empty")
+	parseArgs(flags, args)
+	if *chatID <= 0 {
+		return fmt.Errorf("-chat is required")
+	}
+
+	cfg, err := loadValidCommandConfig()
+	if err != nil {
+		return err
+	}
+	if err := initCommandI18n(); err != nil {
+		return err
+	}
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	chatService := services.NewChatService(db)
+	cipher, err := newMessageCipher(cfg, secrets.NewManager(secretsOptions(cfg)))
+	if err != nil {
+		return err
+	}
+	if cipher != nil {
+		chatService.SetEncryption(cipher)
+	}
+	chat, err := chatService.GetChat(ctx, *chatID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat %d: %w", *chatID, err)
+	}
+	messages, err := chatService.GetMessages(ctx, *chatID, handlers.MaxExportMessages, 0)
+	if err != nil {
+		return fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	data, err := handlers.RenderChatExport(*lang, *format, chat, messages)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported chat %d (%d messages) to %s\n", chat.ID, len(messages), *output)
+	return nil
+}
+
+// runEncryptMessages encrypts the messages stored before message encryption was enabled, or
+// decrypts every message before it is disabled
+func runEncryptMessages(args []string) error {
+	flags := flag.NewFlagSet("encrypt-messages", flag.ExitOnError)
+	decrypt := flags.Bool("decrypt", false, "decrypt encrypted messages instead")
+	batch := flags.Int("batch", 500, "number of messages read at a time")
+	parseArgs(flags, args)
+
+	cfg, err := loadValidCommandConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.EnableMessageEncryption {
+		return fmt.Errorf("ENABLE_MESSAGE_ENCRYPTION and MESSAGE_ENCRYPTION_KEY must be set")
+	}
+	cipher, err := newMessageCipher(cfg, secrets.NewManager(secretsOptions(cfg)))
+	if err != nil {
+		return err
+	}
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chatService.SetEncryption(cipher)
+	ctx := context.Background()
+	if *decrypt {
+		n, err := chatService.DecryptMessages(ctx, *batch)
+		fmt.Fprintf(os.Stderr, "Decrypted %d messages\n", n)
+		return err
+	}
+	n, err := chatService.EncryptMessages(ctx, *batch)
+	fmt.Fprintf(os.Stderr, "Encrypted %d messages\n", n)
+	return err
+}
+
+// initCommandI18n loads the locale files, preferring customized ones, without extracting them
+func initCommandI18n() error {
+	if _, err := os.Stat("locales/en/messages.json"); err == nil {
+		return i18n.Init("locales", "en")
+	}
+	return i18n.InitWithFS(localeFiles, "en")
+}
+
+// runConfig validates the configuration and prints its summary and any errors or warnings
+func runConfig(args []string) error {
+	flags := flag.NewFlagSet("config", flag.ExitOnError)
+	positional := parseArgs(flags, args)
+	if len(positional) != 1 || positional[0] != "validate" {
+		return fmt.Errorf("expected validate")
+	}
+
+	cfg, err := loadCommandConfig()
+	if err != nil {
+		return err
+	}
+	result := config.ValidateEnvironment(cfg)
+	fmt.Print(utils.Redact(config.ConfigSummary(cfg)))
+	fmt.Println()
+	fmt.Println(result.Summary())
+	if !result.Valid {
+		return fmt.Errorf("configuration is invalid")
+	}
+	return nil
+}
+
+// runProvider checks the registered providers, failing when a checked one is unavailable
+func runProvider(args []string) error {
+	flags := flag.NewFlagSet("provider", flag.ExitOnError)
+	positional := parseArgs(flags, args)
+	if len(positional) == 0 || positional[0] != "check" {
+		return fmt.Errorf("expected check [id...]")
+	}
+	ids := positional[1:]
+
+	cfg, err := loadValidCommandConfig()
+	if err != nil {
+		return err
+	}
+	registry := services.NewProviderRegistry(nil)
+	registry.SetSecretResolver(secrets.NewManager(secretsOptions(cfg)))
+	if err := registry.RegisterDefaultProviders(cfg); err != nil {
+		return err
+	}
+
+	var checked []*models.Provider
+	for _, provider := range registry.List() {
+		if len(ids) == 0 || slices.Contains(ids, provider.ID) {
+			checked = append(checked, provider)
+		}
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(checked, func(p *models.Provider) bool { return p.ID == id }) {
+			return fmt.Errorf("unknown provider %q", id)
+		}
+	}
+	slices.SortFunc(checked, func(a, b *models.Provider) int { return strings.Compare(a.ID, b.ID) })
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tVERSION\tDETAILS")
+	unavailable := 0
+	for _, provider := range checked {
+		if !provider.Available {
+			unavailable++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", provider.ID, provider.Status, provider.Version, provider.Details)
+	}
+	tw.Flush()
+
+	if unavailable > 0 {
+		return fmt.Errorf("%d of %d providers unavailable", unavailable, len(checked))
+	}
+	return nil
+}
+
+// runLoadtest drives a running hub with WebSocket clients, e.g. one started with
+// ENABLE_FAKE_PROVIDER=true, and prints the latency of their prompts. It fails when no prompt
+// completed.
+func runLoadtest(args []string) error {
+	flags := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	target := flags.String("url", "http://localhost:8080", "base URL of the hub")
+	connections := flags.Int("connections", 10, "WebSocket connections, each with its own session")
+	rate := flags.Float64("rate", 5, "prompts per second across all connections")
+	duration := flags.Duration("duration", 30*time.Second, "how long prompts are sent")
+	provider := flags.String("provider", "fake", "provider the prompts are sent to")
+	prompt := flags.String("prompt", "Load test prompt", "content of the prompts")
+	token := flags.String("token", "", "bearer token sent with the API requests, e.g. the ADMIN_TOKEN")
+	timeout := flags.Duration("timeout", time.Minute, "time a prompt may take before it fails")
+	parseArgs(flags, args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Load testing %s: %d connections, %.2f prompts/s for %v\n", *target, *connections, *rate, *duration)
+	report, err := loadtest.Run(ctx, loadtest.Options{
+		URL:         *target,
+		Connections: *connections,
+		Rate:        *rate,
+		Duration:    *duration,
+		Provider:    *provider,
+		Prompt:      *prompt,
+		Token:       *token,
+		Timeout:     *timeout,
+	})
+	if report != nil {
+		report.Write(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Completed == 0 {
+		return fmt.Errorf("no prompt completed")
+	}
+	return nil
+}
+
+// runVersion prints the build information with the enabled features
+// runHashPassword prints the bcrypt hash of a password read from stdin, so it stays out of the
+// shell history
+func runHashPassword(args []string) error {
+	flags := flag.NewFlagSet("hash-password", flag.ExitOnError)
+	parseArgs(flags, args)
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return fmt.Errorf("no password given on stdin")
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hash)
+	return nil
+}
+
+func runVersion(args []string) error {
+	flags := flag.NewFlagSet("version", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print as JSON")
+	parseArgs(flags, args)
+
+	cfg, err := loadCommandConfig()
+	if err != nil {
+		return err
+	}
+	build := buildinfo.Get(cfg.FeatureFlags())
+	if *asJSON {
+		data, err := json.MarshalIndent(build, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Println(build.String())
+	if features := build.EnabledFeatures(); len(features) > 0 {
+		fmt.Printf("Features: %s\n", strings.Join(features, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestHS256_IssueAndVerify(t *testing.T) {
+	signer, err := NewHS256Signer(testSecret, "hub")
+	require.NoError(t, err)
+
+	token, issued, err := signer.Issue("alice", []string{"admin"}, time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(token, "."), 3)
+
+	claims, err := signer.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Subject)
+	assert.Equal(t, "hub", claims.Issuer)
+	assert.True(t, claims.HasRole("admin"))
+	assert.False(t, claims.HasRole("user"))
+	assert.Equal(t, issued.ID, claims.ID)
+
+	_, err = NewHS256Signer([]byte("short"), "hub")
+	assert.Error(t, err)
+}
+
+func TestVerify_RejectsForgedTokens(t *testing.T) {
+	signer, err := NewHS256Signer(testSecret, "hub")
+	require.NoError(t, err)
+	token, _, err := signer.Issue("alice", nil, time.Minute)
+	require.NoError(t, err)
+	parts := strings.Split(token, ".")
+
+	// Claims changed after signing
+	forged, err := signer.Sign(&Claims{Issuer: "hub", Subject: "mallory", ExpiresAt: time.Now().Add(time.Minute).Unix()})
+	require.NoError(t, err)
+	_, err = signer.Verify(parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2])
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Unsigned tokens are never accepted
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	_, err = signer.Verify(none + "." + parts[1] + ".")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Another key or issuer
+	other, err := NewHS256Signer([]byte("fedcba9876543210fedcba9876543210"), "hub")
+	require.NoError(t, err)
+	_, err = other.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	otherIssuer, err := NewHS256Signer(testSecret, "elsewhere")
+	require.NoError(t, err)
+	_, err = otherIssuer.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = signer.Verify("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerify_Expiry(t *testing.T) {
+	signer, err := NewHS256Signer(testSecret, "hub")
+	require.NoError(t, err)
+	now := time.Now()
+	signer.now = func() time.Time { return now }
+
+	token, _, err := signer.Issue("alice", nil, time.Minute)
+	require.NoError(t, err)
+
+	now = now.Add(59 * time.Second)
+	_, err = signer.Verify(token)
+	assert.NoError(t, err)
+
+	now = now.Add(time.Second)
+	_, err = signer.Verify(token)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
+
+func TestRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	privateKey, err := ParseRSAPrivateKey(privatePEM)
+	require.NoError(t, err)
+	publicKey, err := ParseRSAPublicKey(publicPEM)
+	require.NoError(t, err)
+
+	signer, err := NewRS256Signer(privateKey, nil, "hub")
+	require.NoError(t, err)
+	token, _, err := signer.Issue("bob", []string{"user"}, time.Minute)
+	require.NoError(t, err)
+
+	// A verifier holding only the public key accepts it but can't sign
+	verifier, err := NewRS256Signer(nil, publicKey, "hub")
+	require.NoError(t, err)
+	claims, err := verifier.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", claims.Subject)
+	_, _, err = verifier.Issue("bob", nil, time.Minute)
+	assert.Error(t, err)
+
+	// An HS256 token is rejected by the RS256 verifier even with the public key as secret
+	hs, err := NewHS256Signer(publicPEM, "hub")
+	require.NoError(t, err)
+	hsToken, _, err := hs.Issue("mallory", []string{"admin"}, time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.Verify(hsToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = ParseRSAPrivateKey([]byte("not pem"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidCredentials is returned when a user ID or password doesn't match
+var ErrInvalidCredentials = errors.New("invalid user ID or password")
+
+// User may sign in to get tokens
+type User struct {
+	ID           string   `yaml:"id"`
+	PasswordHash string   `yaml:"password_hash"` // bcrypt, e.g. from the hash-password command
+	Roles        []string `yaml:"roles"`
+	Disabled     bool     `yaml:"disabled"`
+}
+
+// Users are the users of AUTH_USERS_FILE, by ID
+type Users struct {
+	users map[string]*User
+	// Compared against for unknown IDs, so they take as long to reject as wrong passwords
+	dummyHash []byte
+}
+
+// usersFile is the layout of AUTH_USERS_FILE
+type usersFile struct {
+	Users []*User `yaml:"users"`
+}
+
+// LoadUsers reads the users of a YAML file
+func LoadUsers(path string) (*Users, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+	return ParseUsers(data)
+}
+
+// ParseUsers parses users from YAML
+func ParseUsers(data []byte) (*Users, error) {
+	var file usersFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse users file: %w", err)
+	}
+
+	users := &Users{users: make(map[string]*User)}
+	for i, u := range file.Users {
+		if u == nil || u.ID == "" {
+			return nil, fmt.Errorf("user %d has no id", i+1)
+		}
+		if _, exists := users.users[u.ID]; exists {
+			return nil, fmt.Errorf("user %s is defined twice", u.ID)
+		}
+		if _, err := bcrypt.Cost([]byte(u.PasswordHash)); err != nil {
+			return nil, fmt.Errorf("user %s: password_hash is not a bcrypt hash", u.ID)
+		}
+		users.users[u.ID] = u
+	}
+
+	dummy, err := bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	users.dummyHash = dummy
+	return users, nil
+}
+
+// Len returns the number of users
+func (u *Users) Len() int {
+	return len(u.users)
+}
+
+// Get returns an enabled user
+func (u *Users) Get(id string) (*User, bool) {
+	user, ok := u.users[id]
+	if !ok || user.Disabled {
+		return nil, false
+	}
+	return user, true
+}
+
+// Authenticate returns the enabled user with id and password
+func (u *Users) Authenticate(id, password string) (*User, error) {
+	user, ok := u.Get(id)
+	if !ok {
+		bcrypt.CompareHashAndPassword(u.dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// HashPassword returns the bcrypt hash of a password for the users file
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
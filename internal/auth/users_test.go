@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsers_Authenticate(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	require.NoError(t, err)
+
+	users, err := ParseUsers([]byte(`
+users:
+  - id: alice
+    password_hash: "` + hash + `"
+    roles: [admin]
+  - id: carol
+    password_hash: "` + hash + `"
+    disabled: true
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 2, users.Len())
+
+	user, err := users.Authenticate("alice", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, user.Roles)
+
+	_, err = users.Authenticate("alice", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = users.Authenticate("nobody", "correct horse")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = users.Authenticate("carol", "correct horse")
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "disabled users can't sign in")
+}
+
+func TestParseUsers_Invalid(t *testing.T) {
+	hash, err := HashPassword("pw")
+	require.NoError(t, err)
+
+	_, err = ParseUsers([]byte("users:\n  - password_hash: \"" + hash + "\"\n"))
+	assert.ErrorContains(t, err, "no id")
+	_, err = ParseUsers([]byte("users:\n  - id: a\n    password_hash: plain\n"))
+	assert.ErrorContains(t, err, "not a bcrypt hash")
+	_, err = ParseUsers([]byte("users:\n  - id: a\n    password_hash: \"" + hash + "\"\n  - id: a\n    password_hash: \"" + hash + "\"\n"))
+	assert.ErrorContains(t, err, "defined twice")
+}
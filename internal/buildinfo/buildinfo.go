@@ -0,0 +1,93 @@
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sort"
+)
+
+// Build metadata, set at build time with
+// -ldflags "-X ai-gateway-hub/internal/buildinfo.Version=... -X ...Commit=... -X ...BuildDate=..."
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string          `json:"version"`
+	Commit    string          `json:"commit,omitempty"`
+	BuildDate string          `json:"build_date,omitempty"`
+	GoVersion string          `json:"go_version"`
+	Features  map[string]bool `json:"features,omitempty"`
+}
+
+// Get returns the build information with the given feature flags. When the commit or build date
+// were not set through ldflags they are taken from the VCS stamp Go embeds in the binary.
+func Get(features map[string]bool) BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Features:  features,
+	}
+
+	if info.Commit == "" || info.BuildDate == "" {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = setting.Value
+					}
+				case "vcs.time":
+					if info.BuildDate == "" {
+						info.BuildDate = setting.Value
+					}
+				}
+			}
+		}
+	}
+
+	return info
+}
+
+// ShortCommit returns the first 12 characters of the commit hash
+func (b BuildInfo) ShortCommit() string {
+	if len(b.Commit) > 12 {
+		return b.Commit[:12]
+	}
+	return b.Commit
+}
+
+// String formats the build information for logs and exported transcripts
+func (b BuildInfo) String() string {
+	s := b.Version
+	if commit := b.ShortCommit(); commit != "" {
+		s += " (" + commit + ")"
+	}
+	if b.BuildDate != "" {
+		s += " built " + b.BuildDate
+	}
+	return s + " " + b.GoVersion
+}
+
+// EnabledFeatures returns the names of the enabled features in sorted order
+func (b BuildInfo) EnabledFeatures() []string {
+	var enabled []string
+	for name, on := range b.Features {
+		if on {
+			enabled = append(enabled, name)
+		}
+	}
+	sort.Strings(enabled)
+	return enabled
+}
+
+// LogLine returns a one-line summary for the startup log
+func (b BuildInfo) LogLine() string {
+	return fmt.Sprintf("%s, features: %v", b.String(), b.EnabledFeatures())
+}
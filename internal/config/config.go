@@ -1,6 +1,10 @@
 package config
 
 import (
+	"crypto/subtle"
+	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,13 +16,41 @@ type Config struct {
 	// Server settings
 	Port string
 
+	// TLS: certificate files, or certificates obtained from Let's Encrypt for the autocert hosts
+	TLSCertFile         string
+	TLSKeyFile          string
+	TLSAutocert         bool
+	TLSAutocertHosts    []string
+	TLSAutocertCacheDir string
+	TLSAutocertEmail    string
+	HTTPRedirectPort    string // plain HTTP listener redirecting to HTTPS (empty disables it)
+
+	// Reverse proxies (IPs or CIDRs) whose X-Forwarded-* headers are honored (empty trusts none)
+	TrustedProxies []string
+
 	// Database settings
+	DBDriver     string // "sqlite3" (default) or "postgres"
+	DBDSN        string // Connection string; defaults to SQLiteDBFile for SQLite
 	SQLiteDBFile string
 	RedisAddr    string
 
+	// Whether startup fails when Redis can't be reached; otherwise sessions and provider
+	// statuses are kept in memory
+	RedisRequired bool
+
+	// SQLite tuning: journal mode, how long statements wait for a lock, synchronous level,
+	// foreign key enforcement and connection pool size (0 open connections is unlimited)
+	SQLiteJournalMode  string
+	SQLiteBusyTimeout  time.Duration
+	SQLiteSynchronous  string
+	SQLiteForeignKeys  bool
+	SQLiteMaxOpenConns int
+	SQLiteMaxIdleConns int
+
 	// Static files
-	StaticDir   string
-	TemplateDir string
+	StaticDir      string
+	TemplateDir    string
+	TemplateReload bool // parse templates from TemplateDir and reload them on change instead of using the embedded ones
 
 	// Log settings
 	LogDir   string
@@ -36,10 +68,214 @@ type Config struct {
 	// Claude CLI Options
 	ClaudeSkipPermissions bool
 	ClaudeExtraArgs       string
+	ClaudeResumeSessions  bool // continue chats in the CLI's own sessions instead of resending history
+
+	// GitHub Models via `gh models run`; an empty path disables the provider
+	GHCLIPath string
+	GHModels  []string // selectable models, the first is the default
+
+	// Providers file declaring additional CLI/HTTP providers (YAML or JSON)
+	ProvidersFile string
+
+	// Extra server environment variables passed to / withheld from provider subprocesses (glob patterns)
+	ProviderEnvAllowlist []string
+	ProviderEnvDenylist  []string
+
+	// Sandbox for provider subprocesses: working directory, wrapper command prefix and ulimits (0 = unlimited)
+	ProviderWorkDir         string
+	ProviderWrapper         []string
+	ProviderLimitCPUSeconds int
+	ProviderLimitMemoryMB   int
+	ProviderLimitFileSizeMB int
+	ProviderLimitOpenFiles  int
 
 	// Feature flags
 	EnableProviderAutoDiscovery bool
 	EnableHealthChecks          bool
+	EnableWSBackplane           bool // relay WebSocket messages between instances through Redis
+	EnableCSRF                  bool // require a CSRF token on state-changing requests
+	EnableScheduledPrompts      bool // run scheduled prompts on their cron schedules
+	EnableCompression           bool // gzip/deflate API and page responses
+	EnablePprof                 bool // serve pprof profiles to admins under /api/debug/pprof
+	EnableModeration            bool // moderate prompts and responses with rules and/or an external service
+	EnablePIIRedaction          bool // mask personal data in system logs and chat log files
+	EnableMessageEncryption     bool // encrypt message content in the database with MessageEncryptionKey
+	EnableOpenAIAPI             bool // serve the OpenAI compatible /v1/chat/completions and /v1/models
+	EnableFakeProvider          bool // register the synthetic "fake" provider, for load tests
+
+	// Response compression: level (1-9), smallest compressed body and content types (patterns like text/*)
+	CompressionLevel   int
+	CompressionMinSize int
+	CompressionTypes   []string
+
+	// ID of this server instance (generated from the host name when empty)
+	InstanceID string
+
+	// Provider health checks
+	HealthCheckInterval    time.Duration
+	HealthCheckHistorySize int
+
+	// Consecutive failed generations after which a provider's circuit opens (0 disables the
+	// breaker), and how long it stays open before a probe is let through
+	ProviderCircuitThreshold int
+	ProviderCircuitCooldown  time.Duration
+
+	// Failover chains as "primary>fallback>..." entries, e.g. claude>gemini>ollama: prompts to the
+	// primary go to the next provider when it is unavailable or fails before any output
+	ProviderFailover []string
+
+	// Attempts of a prompt to a provider failing before any output with a retriable class of error
+	// (network, rate_limit, server or crash); retries wait the backoff, doubled each time up to the max
+	ProviderRetryAttempts   int
+	ProviderRetryBackoff    time.Duration
+	ProviderRetryMaxBackoff time.Duration
+	ProviderRetryOn         []string
+
+	// Apply pending database migrations at startup
+	AutoMigrate bool
+
+	// Days a deleted chat stays restorable before it is purged (0 keeps deleted chats forever)
+	DeletedChatRetentionDays int
+
+	// Retention rules run every RetentionInterval (0 disables a rule): chats idle for longer are moved
+	// to the trash, the oldest messages of longer chats are deleted and older chat log files removed.
+	// In a dry run the rules only report what they would remove.
+	RetentionIdleChatDays       int
+	RetentionMaxMessagesPerChat int
+	RetentionLogDays            int
+	RetentionInterval           time.Duration
+	RetentionDryRun             bool
+
+	// Shared secret signing exported configuration bundles (empty disables export/import)
+	ConfigBundleSecret string
+
+	// Secret signing public chat share links (empty uses a random one, invalidating links on restart)
+	ShareLinkSecret string
+
+	// Token granting the admin role (empty restricts admin routes to loopback clients in the
+	// development environment, and disables them elsewhere)
+	AdminToken string
+
+	// Whether loopback clients are admins while AdminToken is empty; set in development only, as
+	// behind a reverse proxy on the same host every request would come from loopback
+	AdminLoopback bool
+
+	// Authentication mode (session or jwt). In jwt mode users of AuthUsersFile sign in for access
+	// tokens signed with JWTAlgorithm (HS256 with JWTSecret, RS256 with the key files) and refresh
+	// tokens kept in Redis.
+	AuthMode          string
+	AuthUsersFile     string
+	JWTAlgorithm      string
+	JWTSecret         string
+	JWTPrivateKeyFile string
+	JWTPublicKeyFile  string
+	JWTIssuer         string
+	JWTAccessTTL      time.Duration
+	JWTRefreshTTL     time.Duration
+
+	// Role of sessions and access tokens not granted one (viewer or user)
+	DefaultRole string
+
+	// External secret managers for provider credentials referenced as ${vault:...} / ${aws-sm:...}
+	VaultAddr      string
+	VaultToken     string
+	VaultNamespace string
+	AWSCLIPath     string // aws CLI used for AWS Secrets Manager (empty disables it)
+
+	// Browser origins allowed for CORS and WebSocket connections (patterns, "*" for any)
+	AllowedOrigins []string
+
+	// File attachments: storage directory, size limit and allowed content types (patterns like text/*)
+	AttachmentsDir         string
+	AttachmentMaxSizeMB    int
+	AttachmentAllowedTypes []string
+
+	// Streaming responses are saved every so many bytes or so often (0 disables either)
+	StreamCheckpointBytes    int
+	StreamCheckpointInterval time.Duration
+
+	// Streamed chunks are batched into WebSocket frames of at least so many bytes or every so often
+	// (0 disables either; both 0 sends every chunk as it arrives)
+	StreamFlushBytes    int
+	StreamFlushInterval time.Duration
+
+	// What streamed output is held back until more arrives: "rune" for split characters, "markdown"
+	// also for unfinished code fence lines. Providers can be given their own as "provider=mode" entries.
+	StreamChunking         string
+	ProviderStreamChunking []string
+
+	// The fake provider answers every prompt with so many chunks, written this often
+	FakeProviderChunks int
+	FakeProviderDelay  time.Duration
+
+	// Streamed chunks are kept this long after the last one so reconnecting clients can resume
+	// the response (0 disables resuming)
+	StreamResumeWindow time.Duration
+
+	// How long a ticket from POST /api/ws/ticket may be used to open a WebSocket
+	WSTicketTTL time.Duration
+
+	// Longest a prompt may stream, and may go without output (0 disables the idle timeout).
+	// Providers can be given their own as "provider=seconds" entries.
+	PromptTimeout          time.Duration
+	PromptIdleTimeout      time.Duration
+	ProviderPromptTimeouts []string
+	ProviderIdleTimeouts   []string
+
+	// Prompts each session may send per day and per month (UTC); 0 leaves the period unlimited
+	DailyPromptQuota   int
+	MonthlyPromptQuota int
+
+	// Longest prompt in characters and longest saved response in bytes (0 disables either); responses
+	// over the limit are cut off ("truncate") or only reported ("warn")
+	MaxPromptLength         int
+	MaxResponseBytes        int
+	OversizedResponseAction string
+
+	// Moderation rules file (YAML or JSON) and external moderation service; the API key may be a
+	// secret reference. Failing moderators block content when ModerationFailClosed is set.
+	ModerationRulesFile  string
+	ModerationURL        string
+	ModerationAPIKey     string
+	ModerationTimeout    time.Duration
+	ModerationFailClosed bool
+
+	// Prompt and response processors file (YAML or JSON); empty transforms nothing
+	ProcessorsFile string
+
+	// Document collections: size limit, chunking, chunks added to prompts, and the embedder
+	// (hash, built in, or http, an OpenAI compatible API whose key may be a secret reference)
+	DocumentMaxSizeMB   int
+	RAGChunkSize        int
+	RAGChunkOverlap     int
+	RAGTopK             int
+	EmbeddingProvider   string
+	EmbeddingURL        string
+	EmbeddingAPIKey     string
+	EmbeddingModel      string
+	EmbeddingDimensions int
+	EmbeddingTimeout    time.Duration
+
+	// Tools chats can let providers call (calculator, http_fetch, shell), the most calls per
+	// response and how long each may run, the hosts http_fetch may fetch (empty for any public
+	// host) and the commands shell may run
+	ToolsEnabled           []string
+	ToolsMaxSteps          int
+	ToolsTimeout           time.Duration
+	ToolsFetchAllowedHosts []string
+	ToolsShellCommands     []string
+
+	// How long an unsent prompt draft is kept after its last save
+	DraftTTL time.Duration
+
+	// Personal data masked when PII redaction is enabled: built-in kinds (email, api_key, credit_card),
+	// extra regular expressions, and where to mask it (logs and/or chat_logs)
+	PIIRedactTypes    []string
+	PIIRedactPatterns []string
+	PIIRedactTargets  []string
+
+	// AES-256 key of message encryption in base64 or hex, or a secret reference (vault:..., aws-sm:...)
+	MessageEncryptionKey string
 }
 
 // Load initializes and loads configuration from various sources
@@ -93,13 +329,37 @@ func Load() *Config {
 	
 	return &Config{
 		Port:         v.GetString("PORT"),
+
+		TLSCertFile:         v.GetString("TLS_CERT_FILE"),
+		TLSKeyFile:          v.GetString("TLS_KEY_FILE"),
+		TLSAutocert:         getBoolWithDefault("TLS_AUTOCERT", false),
+		TLSAutocertHosts:    splitList(v.GetString("TLS_AUTOCERT_HOSTS")),
+		TLSAutocertCacheDir: v.GetString("TLS_AUTOCERT_CACHE_DIR"),
+		TLSAutocertEmail:    v.GetString("TLS_AUTOCERT_EMAIL"),
+		HTTPRedirectPort:    v.GetString("HTTP_REDIRECT_PORT"),
+
+		TrustedProxies: splitList(v.GetString("TRUSTED_PROXIES")),
+		DBDriver:     v.GetString("DB_DRIVER"),
+		DBDSN:        v.GetString("DB_DSN"),
 		SQLiteDBFile: v.GetString("SQLITE_DB_FILE"),
 		RedisAddr:    v.GetString("REDIS_ADDR"),
+
+		RedisRequired: v.GetBool("REDIS_REQUIRED"),
+
+		SQLiteJournalMode:  v.GetString("SQLITE_JOURNAL_MODE"),
+		SQLiteBusyTimeout:  time.Duration(getIntWithDefault("SQLITE_BUSY_TIMEOUT", 5000)) * time.Millisecond,
+		SQLiteSynchronous:  v.GetString("SQLITE_SYNCHRONOUS"),
+		SQLiteForeignKeys:  getBoolWithDefault("SQLITE_FOREIGN_KEYS", true),
+		SQLiteMaxOpenConns: getIntWithDefault("SQLITE_MAX_OPEN_CONNS", 16),
+		SQLiteMaxIdleConns: getIntWithDefault("SQLITE_MAX_IDLE_CONNS", 4),
+
 		StaticDir:    v.GetString("STATIC_DIR"),
 		TemplateDir:  v.GetString("TEMPLATE_DIR"),
 		LogDir:       v.GetString("LOG_DIR"),
 		LogLevel:     v.GetString("LOG_LEVEL"),
 
+		TemplateReload: getBoolWithDefault("TEMPLATE_RELOAD", false),
+
 		MaxSessions:      getIntWithDefault("MAX_SESSIONS", 100),
 		SessionTimeout:   time.Duration(getIntWithDefault("SESSION_TIMEOUT", 3600)) * time.Second,
 		WebSocketTimeout: time.Duration(getIntWithDefault("WEBSOCKET_TIMEOUT", 7200)) * time.Second,
@@ -109,12 +369,329 @@ func Load() *Config {
 
 		ClaudeSkipPermissions: getBoolWithDefault("CLAUDE_SKIP_PERMISSIONS", false),
 		ClaudeExtraArgs:       v.GetString("CLAUDE_EXTRA_ARGS"),
+		ClaudeResumeSessions:  getBoolWithDefault("CLAUDE_RESUME_SESSIONS", true),
+
+		GHCLIPath: v.GetString("GH_CLI_PATH"),
+		GHModels:  splitList(v.GetString("GH_MODELS")),
+
+		ProvidersFile: v.GetString("PROVIDERS_FILE"),
+
+		ProviderEnvAllowlist: splitList(v.GetString("PROVIDER_ENV_ALLOWLIST")),
+		ProviderEnvDenylist:  splitList(v.GetString("PROVIDER_ENV_DENYLIST")),
+
+		ProviderWorkDir:         v.GetString("PROVIDER_WORKDIR"),
+		ProviderWrapper:         strings.Fields(v.GetString("PROVIDER_WRAPPER")),
+		ProviderLimitCPUSeconds: getIntWithDefault("PROVIDER_LIMIT_CPU_SECONDS", 0),
+		ProviderLimitMemoryMB:   getIntWithDefault("PROVIDER_LIMIT_MEMORY_MB", 0),
+		ProviderLimitFileSizeMB: getIntWithDefault("PROVIDER_LIMIT_FILE_SIZE_MB", 0),
+		ProviderLimitOpenFiles:  getIntWithDefault("PROVIDER_LIMIT_OPEN_FILES", 0),
 
 		EnableProviderAutoDiscovery: getBoolWithDefault("ENABLE_PROVIDER_AUTO_DISCOVERY", true),
 		EnableHealthChecks:          getBoolWithDefault("ENABLE_HEALTH_CHECKS", true),
+		EnableWSBackplane:           getBoolWithDefault("ENABLE_WS_BACKPLANE", false),
+		EnableCSRF:                  getBoolWithDefault("ENABLE_CSRF", true),
+		EnableScheduledPrompts:      getBoolWithDefault("ENABLE_SCHEDULED_PROMPTS", true),
+		EnableCompression:           getBoolWithDefault("ENABLE_COMPRESSION", true),
+		EnablePprof:                 getBoolWithDefault("ENABLE_PPROF", false),
+		EnableModeration:            getBoolWithDefault("ENABLE_MODERATION", false),
+		EnablePIIRedaction:          getBoolWithDefault("ENABLE_PII_REDACTION", false),
+		EnableMessageEncryption:     getBoolWithDefault("ENABLE_MESSAGE_ENCRYPTION", false),
+		EnableOpenAIAPI:             getBoolWithDefault("ENABLE_OPENAI_API", true),
+		EnableFakeProvider:          getBoolWithDefault("ENABLE_FAKE_PROVIDER", false),
+
+		CompressionLevel:   getIntWithDefault("COMPRESSION_LEVEL", 5),
+		CompressionMinSize: getIntWithDefault("COMPRESSION_MIN_SIZE", 1024),
+		CompressionTypes:   splitList(v.GetString("COMPRESSION_TYPES")),
+
+		InstanceID: v.GetString("INSTANCE_ID"),
+
+		HealthCheckInterval:    time.Duration(getIntWithDefault("HEALTH_CHECK_INTERVAL", 60)) * time.Second,
+		HealthCheckHistorySize: getIntWithDefault("HEALTH_CHECK_HISTORY_SIZE", 50),
+
+		ProviderCircuitThreshold: getIntWithDefault("PROVIDER_CIRCUIT_THRESHOLD", 5),
+		ProviderCircuitCooldown:  time.Duration(getIntWithDefault("PROVIDER_CIRCUIT_COOLDOWN", 30)) * time.Second,
+		ProviderFailover:         splitList(v.GetString("PROVIDER_FAILOVER")),
+
+		ProviderRetryAttempts:   getIntWithDefault("PROVIDER_RETRY_ATTEMPTS", 3),
+		ProviderRetryBackoff:    time.Duration(getIntWithDefault("PROVIDER_RETRY_BACKOFF", 500)) * time.Millisecond,
+		ProviderRetryMaxBackoff: time.Duration(getIntWithDefault("PROVIDER_RETRY_MAX_BACKOFF", 8000)) * time.Millisecond,
+		ProviderRetryOn:         splitList(v.GetString("PROVIDER_RETRY_ON")),
+
+		AutoMigrate: getBoolWithDefault("AUTO_MIGRATE", true),
+
+		DeletedChatRetentionDays: getIntWithDefault("DELETED_CHAT_RETENTION_DAYS", 30),
+
+		RetentionIdleChatDays:       getIntWithDefault("RETENTION_IDLE_CHAT_DAYS", 0),
+		RetentionMaxMessagesPerChat: getIntWithDefault("RETENTION_MAX_MESSAGES_PER_CHAT", 0),
+		RetentionLogDays:            getIntWithDefault("RETENTION_LOG_DAYS", 0),
+		RetentionInterval:           time.Duration(getIntWithDefault("RETENTION_INTERVAL", 3600)) * time.Second,
+		RetentionDryRun:             getBoolWithDefault("RETENTION_DRY_RUN", false),
+
+		ConfigBundleSecret: v.GetString("CONFIG_BUNDLE_SECRET"),
+
+		ShareLinkSecret: v.GetString("SHARE_LINK_SECRET"),
+
+		AdminToken: v.GetString("ADMIN_TOKEN"),
+
+		AuthMode:          strings.ToLower(v.GetString("AUTH_MODE")),
+		AuthUsersFile:     v.GetString("AUTH_USERS_FILE"),
+		JWTAlgorithm:      strings.ToUpper(v.GetString("JWT_ALGORITHM")),
+		JWTSecret:         v.GetString("JWT_SECRET"),
+		JWTPrivateKeyFile: v.GetString("JWT_PRIVATE_KEY_FILE"),
+		JWTPublicKeyFile:  v.GetString("JWT_PUBLIC_KEY_FILE"),
+		JWTIssuer:         v.GetString("JWT_ISSUER"),
+		JWTAccessTTL:      time.Duration(getIntWithDefault("JWT_ACCESS_TTL", 900)) * time.Second,
+		JWTRefreshTTL:     time.Duration(getIntWithDefault("JWT_REFRESH_TTL", 604800)) * time.Second,
+
+		DefaultRole: strings.ToLower(v.GetString("DEFAULT_ROLE")),
+
+		VaultAddr:      v.GetString("VAULT_ADDR"),
+		VaultToken:     v.GetString("VAULT_TOKEN"),
+		VaultNamespace: v.GetString("VAULT_NAMESPACE"),
+		AWSCLIPath:     v.GetString("SECRETS_AWS_CLI_PATH"),
+
+		AllowedOrigins: loadAllowedOrigins(v),
+
+		AttachmentsDir:         v.GetString("ATTACHMENTS_DIR"),
+		AttachmentMaxSizeMB:    getIntWithDefault("ATTACHMENT_MAX_SIZE_MB", 10),
+		AttachmentAllowedTypes: splitList(v.GetString("ATTACHMENT_ALLOWED_TYPES")),
+
+		StreamCheckpointBytes:    getIntWithDefault("STREAM_CHECKPOINT_BYTES", 2048),
+		StreamCheckpointInterval: time.Duration(getIntWithDefault("STREAM_CHECKPOINT_INTERVAL", 5)) * time.Second,
+
+		StreamFlushBytes:    getIntWithDefault("STREAM_FLUSH_BYTES", 0),
+		StreamFlushInterval: time.Duration(getIntWithDefault("STREAM_FLUSH_INTERVAL", 50)) * time.Millisecond,
+
+		StreamChunking:         v.GetString("STREAM_CHUNKING"),
+		ProviderStreamChunking: splitList(v.GetString("PROVIDER_STREAM_CHUNKING")),
+
+		StreamResumeWindow: time.Duration(getIntWithDefault("STREAM_RESUME_WINDOW", 60)) * time.Second,
+
+		FakeProviderChunks: getIntWithDefault("FAKE_PROVIDER_CHUNKS", 20),
+		FakeProviderDelay:  time.Duration(getIntWithDefault("FAKE_PROVIDER_DELAY", 50)) * time.Millisecond,
+
+		WSTicketTTL: time.Duration(getIntWithDefault("WS_TICKET_TTL", 30)) * time.Second,
+
+		PromptTimeout:          time.Duration(getIntWithDefault("PROMPT_TIMEOUT", 300)) * time.Second,
+		PromptIdleTimeout:      time.Duration(getIntWithDefault("PROMPT_IDLE_TIMEOUT", 120)) * time.Second,
+		ProviderPromptTimeouts: splitList(v.GetString("PROVIDER_PROMPT_TIMEOUTS")),
+		ProviderIdleTimeouts:   splitList(v.GetString("PROVIDER_IDLE_TIMEOUTS")),
+
+		DailyPromptQuota:   getIntWithDefault("DAILY_PROMPT_QUOTA", 0),
+		MonthlyPromptQuota: getIntWithDefault("MONTHLY_PROMPT_QUOTA", 0),
+
+		MaxPromptLength:         getIntWithDefault("MAX_PROMPT_LENGTH", 100000),
+		MaxResponseBytes:        getIntWithDefault("MAX_RESPONSE_BYTES", 2097152),
+		OversizedResponseAction: strings.ToLower(strings.TrimSpace(v.GetString("OVERSIZED_RESPONSE_ACTION"))),
+
+		ModerationRulesFile:  v.GetString("MODERATION_RULES_FILE"),
+		ModerationURL:        v.GetString("MODERATION_URL"),
+		ModerationAPIKey:     v.GetString("MODERATION_API_KEY"),
+		ModerationTimeout:    time.Duration(getIntWithDefault("MODERATION_TIMEOUT", 5)) * time.Second,
+		ModerationFailClosed: getBoolWithDefault("MODERATION_FAIL_CLOSED", false),
+
+		ProcessorsFile: v.GetString("PROCESSORS_FILE"),
+
+		DocumentMaxSizeMB:   getIntWithDefault("DOCUMENT_MAX_SIZE_MB", 10),
+		RAGChunkSize:        getIntWithDefault("RAG_CHUNK_SIZE", 1000),
+		RAGChunkOverlap:     getIntWithDefault("RAG_CHUNK_OVERLAP", 200),
+		RAGTopK:             getIntWithDefault("RAG_TOP_K", 4),
+		EmbeddingProvider:   strings.ToLower(strings.TrimSpace(v.GetString("EMBEDDING_PROVIDER"))),
+		EmbeddingURL:        v.GetString("EMBEDDING_URL"),
+		EmbeddingAPIKey:     v.GetString("EMBEDDING_API_KEY"),
+		EmbeddingModel:      v.GetString("EMBEDDING_MODEL"),
+		EmbeddingDimensions: getIntWithDefault("EMBEDDING_DIMENSIONS", 512),
+		EmbeddingTimeout:    time.Duration(getIntWithDefault("EMBEDDING_TIMEOUT", 30)) * time.Second,
+
+		ToolsEnabled:           splitList(strings.ToLower(v.GetString("TOOLS_ENABLED"))),
+		ToolsMaxSteps:          getIntWithDefault("TOOLS_MAX_STEPS", 5),
+		ToolsTimeout:           time.Duration(getIntWithDefault("TOOLS_TIMEOUT", 10)) * time.Second,
+		ToolsFetchAllowedHosts: splitList(v.GetString("TOOLS_FETCH_ALLOWED_HOSTS")),
+		ToolsShellCommands:     splitList(v.GetString("TOOLS_SHELL_COMMANDS")),
+
+		DraftTTL: time.Duration(getIntWithDefault("DRAFT_TTL", 604800)) * time.Second,
+
+		PIIRedactTypes:    splitList(strings.ToLower(v.GetString("PII_REDACT_TYPES"))),
+		PIIRedactPatterns: splitPatterns(v.GetString("PII_REDACT_PATTERNS")),
+		PIIRedactTargets:  splitList(strings.ToLower(v.GetString("PII_REDACT_TARGETS"))),
+
+		MessageEncryptionKey: strings.TrimSpace(v.GetString("MESSAGE_ENCRYPTION_KEY")),
 	}
 }
 
+// FeatureFlags returns the feature flags keyed by their environment variable names
+func (c *Config) FeatureFlags() map[string]bool {
+	return map[string]bool{
+		"ENABLE_PROVIDER_AUTO_DISCOVERY": c.EnableProviderAutoDiscovery,
+		"ENABLE_HEALTH_CHECKS":           c.EnableHealthChecks,
+		"ENABLE_WS_BACKPLANE":            c.EnableWSBackplane,
+		"ENABLE_CSRF":                    c.EnableCSRF,
+		"ENABLE_SCHEDULED_PROMPTS":       c.EnableScheduledPrompts,
+		"ENABLE_COMPRESSION":             c.EnableCompression,
+		"ENABLE_PPROF":                   c.EnablePprof,
+		"ENABLE_MODERATION":              c.EnableModeration,
+		"ENABLE_PII_REDACTION":           c.EnablePIIRedaction,
+		"ENABLE_MESSAGE_ENCRYPTION":      c.EnableMessageEncryption,
+		"ENABLE_OPENAI_API":              c.EnableOpenAIAPI,
+		"ENABLE_FAKE_PROVIDER":           c.EnableFakeProvider,
+	}
+}
+
+// ValidAdminToken reports whether a presented token matches ADMIN_TOKEN, comparing in constant time
+func (c *Config) ValidAdminToken(presented string) bool {
+	return c.AdminToken != "" && subtle.ConstantTimeCompare([]byte(c.AdminToken), []byte(presented)) == 1
+}
+
+// TLSEnabled reports whether the server listens with HTTPS
+func (c *Config) TLSEnabled() bool {
+	return c.TLSAutocert || c.TLSCertFile != ""
+}
+
+// UsesSQLite reports whether the configured database driver is SQLite
+func (c *Config) UsesSQLite() bool {
+	switch strings.ToLower(c.DBDriver) {
+	case "", "sqlite", "sqlite3":
+		return true
+	}
+	return false
+}
+
+// DatabaseDSN returns the connection string, falling back to SQLITE_DB_FILE for SQLite
+func (c *Config) DatabaseDSN() string {
+	if c.DBDSN == "" && c.UsesSQLite() {
+		return c.SQLiteDBFile
+	}
+	return c.DBDSN
+}
+
+// loadAllowedOrigins reads ALLOWED_ORIGINS, falling back to the deprecated ALLOWED_WEBSOCKET_ORIGINS
+func loadAllowedOrigins(v *viper.Viper) []string {
+	if origins := v.GetString("ALLOWED_ORIGINS"); origins != "" {
+		return splitList(origins)
+	}
+	return splitList(v.GetString("ALLOWED_WEBSOCKET_ORIGINS"))
+}
+
+// PromptTimeouts returns how long a prompt to a provider may stream and may go without output
+func (c *Config) PromptTimeouts(provider string) (total, idle time.Duration) {
+	total, idle = c.PromptTimeout, c.PromptIdleTimeout
+	if d, ok := providerTimeout(c.ProviderPromptTimeouts, provider); ok {
+		total = d
+	}
+	if d, ok := providerTimeout(c.ProviderIdleTimeouts, provider); ok {
+		idle = d
+	}
+	return total, idle
+}
+
+// StreamChunkingFor returns how a provider's streamed output is chunked
+func (c *Config) StreamChunkingFor(provider string) string {
+	for _, entry := range c.ProviderStreamChunking {
+		if id, mode, ok := strings.Cut(entry, "="); ok && strings.TrimSpace(id) == provider {
+			return strings.TrimSpace(mode)
+		}
+	}
+	return c.StreamChunking
+}
+
+// LongestPromptTimeout returns the longest time any prompt may stream
+func (c *Config) LongestPromptTimeout() time.Duration {
+	longest := c.PromptTimeout
+	for _, entry := range c.ProviderPromptTimeouts {
+		if _, d, err := parseProviderTimeout(entry); err == nil && d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
+// FailoverChains returns the fallbacks of each primary provider, in the order they are tried
+func (c *Config) FailoverChains() map[string][]string {
+	chains := make(map[string][]string)
+	for _, entry := range c.ProviderFailover {
+		if chain, err := parseFailoverChain(entry); err == nil {
+			chains[chain[0]] = chain[1:]
+		}
+	}
+	return chains
+}
+
+// parseFailoverChain parses a "primary>fallback>..." failover entry
+func parseFailoverChain(entry string) ([]string, error) {
+	var chain []string
+	for _, id := range strings.Split(entry, ">") {
+		id = strings.TrimSpace(id)
+		if id == "" || slices.Contains(chain, id) {
+			return nil, fmt.Errorf("invalid failover chain %q, expected distinct providers as primary>fallback>...", entry)
+		}
+		chain = append(chain, id)
+	}
+	if len(chain) < 2 {
+		return nil, fmt.Errorf("failover chain %q has no fallback, expected primary>fallback>...", entry)
+	}
+	return chain, nil
+}
+
+// providerTimeout looks up a provider's entry in a list of "provider=seconds" timeouts
+func providerTimeout(entries []string, provider string) (time.Duration, bool) {
+	for _, entry := range entries {
+		id, d, err := parseProviderTimeout(entry)
+		if err == nil && id == provider {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseProviderTimeout parses a "provider=seconds" timeout entry
+func parseProviderTimeout(entry string) (string, time.Duration, error) {
+	id, seconds, ok := strings.Cut(entry, "=")
+	id = strings.TrimSpace(id)
+	if !ok || id == "" {
+		return "", 0, fmt.Errorf("invalid timeout %q, expected provider=seconds", entry)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(seconds))
+	if err != nil || n < 0 {
+		return "", 0, fmt.Errorf("invalid timeout %q, expected provider=seconds", entry)
+	}
+	return id, time.Duration(n) * time.Second, nil
+}
+
+// splitList parses a comma-separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitPatterns parses semicolon-separated regular expressions, which may contain commas
+func splitPatterns(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ";") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// RedactsPII reports whether PII redaction applies to target ("logs" or "chat_logs")
+func (c *Config) RedactsPII(target string) bool {
+	if !c.EnablePIIRedaction {
+		return false
+	}
+	for _, t := range c.PIIRedactTargets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
 // setDefaults sets default configuration values
 func setDefaults() {
 	setDefaultsForViper(viper.GetViper())
@@ -124,11 +701,102 @@ func setDefaults() {
 func setDefaultsForViper(v *viper.Viper) {
 	// Server Configuration
 	v.SetDefault("PORT", "8080")
+	v.SetDefault("DB_DRIVER", "sqlite3")
+	v.SetDefault("DB_DSN", "")
 	v.SetDefault("SQLITE_DB_FILE", "./data/ai_gateway.db")
+	v.SetDefault("SQLITE_JOURNAL_MODE", "WAL")
+	v.SetDefault("SQLITE_BUSY_TIMEOUT", 5000)
+	v.SetDefault("SQLITE_SYNCHRONOUS", "NORMAL")
+	v.SetDefault("SQLITE_FOREIGN_KEYS", true)
+	v.SetDefault("SQLITE_MAX_OPEN_CONNS", 16)
+	v.SetDefault("SQLITE_MAX_IDLE_CONNS", 4)
 	v.SetDefault("REDIS_ADDR", "localhost:6379")
+	v.SetDefault("REDIS_REQUIRED", false)
 	v.SetDefault("STATIC_DIR", "./web/static")
 	v.SetDefault("TEMPLATE_DIR", "./web/templates")
 	
+	// TLS Configuration
+	v.SetDefault("TLS_CERT_FILE", "")
+	v.SetDefault("TLS_KEY_FILE", "")
+	v.SetDefault("TLS_AUTOCERT", false)
+	v.SetDefault("TLS_AUTOCERT_HOSTS", "")
+	v.SetDefault("TLS_AUTOCERT_CACHE_DIR", "./data/autocert")
+	v.SetDefault("TLS_AUTOCERT_EMAIL", "")
+	v.SetDefault("HTTP_REDIRECT_PORT", "")
+	
+	// Reverse Proxies
+	v.SetDefault("TRUSTED_PROXIES", "")
+	
+	// File Attachments
+	v.SetDefault("ATTACHMENTS_DIR", "./data/attachments")
+	v.SetDefault("ATTACHMENT_MAX_SIZE_MB", 10)
+	v.SetDefault("ATTACHMENT_ALLOWED_TYPES", DefaultAttachmentAllowedTypes)
+
+	// Streaming Response Checkpoints
+	v.SetDefault("STREAM_CHECKPOINT_BYTES", 2048)
+	v.SetDefault("STREAM_CHECKPOINT_INTERVAL", 5)
+
+	// Streamed Chunk Batching
+	v.SetDefault("STREAM_FLUSH_BYTES", 0)
+	v.SetDefault("STREAM_FLUSH_INTERVAL", 50)
+	v.SetDefault("STREAM_CHUNKING", "rune")
+	v.SetDefault("PROVIDER_STREAM_CHUNKING", "")
+	v.SetDefault("FAKE_PROVIDER_CHUNKS", 20)
+	v.SetDefault("FAKE_PROVIDER_DELAY", 50)
+
+	// Stream Resume
+	v.SetDefault("STREAM_RESUME_WINDOW", 60)
+
+	// WebSocket Tickets
+	v.SetDefault("WS_TICKET_TTL", 30)
+
+	// Prompt Timeouts
+	v.SetDefault("PROMPT_TIMEOUT", 300)
+	v.SetDefault("PROMPT_IDLE_TIMEOUT", 120)
+	v.SetDefault("PROVIDER_PROMPT_TIMEOUTS", "")
+	v.SetDefault("PROVIDER_IDLE_TIMEOUTS", "")
+
+	// Prompt Quotas
+	v.SetDefault("DAILY_PROMPT_QUOTA", 0)
+	v.SetDefault("MONTHLY_PROMPT_QUOTA", 0)
+
+	// Message Size Limits
+	v.SetDefault("MAX_PROMPT_LENGTH", 100000)
+	v.SetDefault("MAX_RESPONSE_BYTES", 2097152)
+	v.SetDefault("OVERSIZED_RESPONSE_ACTION", "truncate")
+
+	// Moderation
+	v.SetDefault("MODERATION_RULES_FILE", "")
+	v.SetDefault("MODERATION_URL", "")
+	v.SetDefault("MODERATION_API_KEY", "")
+	v.SetDefault("MODERATION_TIMEOUT", 5)
+	v.SetDefault("MODERATION_FAIL_CLOSED", false)
+	v.SetDefault("PROCESSORS_FILE", "")
+	v.SetDefault("DOCUMENT_MAX_SIZE_MB", 10)
+	v.SetDefault("RAG_CHUNK_SIZE", 1000)
+	v.SetDefault("RAG_CHUNK_OVERLAP", 200)
+	v.SetDefault("RAG_TOP_K", 4)
+	v.SetDefault("EMBEDDING_PROVIDER", "hash")
+	v.SetDefault("EMBEDDING_URL", "")
+	v.SetDefault("EMBEDDING_API_KEY", "")
+	v.SetDefault("EMBEDDING_MODEL", "")
+	v.SetDefault("EMBEDDING_DIMENSIONS", 512)
+	v.SetDefault("EMBEDDING_TIMEOUT", 30)
+	v.SetDefault("TOOLS_ENABLED", "calculator")
+	v.SetDefault("TOOLS_MAX_STEPS", 5)
+	v.SetDefault("TOOLS_TIMEOUT", 10)
+	v.SetDefault("TOOLS_FETCH_ALLOWED_HOSTS", "")
+	v.SetDefault("TOOLS_SHELL_COMMANDS", "")
+	v.SetDefault("DRAFT_TTL", 604800)
+
+	// PII Redaction
+	v.SetDefault("PII_REDACT_TYPES", "email,api_key,credit_card")
+	v.SetDefault("PII_REDACT_PATTERNS", "")
+	v.SetDefault("PII_REDACT_TARGETS", "logs,chat_logs")
+
+	// Message Encryption
+	v.SetDefault("MESSAGE_ENCRYPTION_KEY", "")
+	
 	// Logging Configuration
 	v.SetDefault("LOG_DIR", "./logs")
 	v.SetDefault("LOG_LEVEL", "info")
@@ -145,10 +813,88 @@ func setDefaultsForViper(v *viper.Viper) {
 	// Claude CLI Options
 	v.SetDefault("CLAUDE_SKIP_PERMISSIONS", false)
 	v.SetDefault("CLAUDE_EXTRA_ARGS", "")
+	v.SetDefault("CLAUDE_RESUME_SESSIONS", true)
+	
+	// GitHub Models
+	v.SetDefault("GH_CLI_PATH", "gh")
+	v.SetDefault("GH_MODELS", "")
+	
+	// Providers File
+	v.SetDefault("PROVIDERS_FILE", "./providers.yaml")
+	
+	// Provider Environment Passthrough
+	v.SetDefault("PROVIDER_ENV_ALLOWLIST", "")
+	v.SetDefault("PROVIDER_ENV_DENYLIST", "")
+	
+	// Provider Sandbox
+	v.SetDefault("PROVIDER_WORKDIR", "")
+	v.SetDefault("PROVIDER_WRAPPER", "")
+	v.SetDefault("PROVIDER_LIMIT_CPU_SECONDS", 0)
+	v.SetDefault("PROVIDER_LIMIT_MEMORY_MB", 0)
+	v.SetDefault("PROVIDER_LIMIT_FILE_SIZE_MB", 0)
+	v.SetDefault("PROVIDER_LIMIT_OPEN_FILES", 0)
 	
 	// Feature Flags
 	v.SetDefault("ENABLE_PROVIDER_AUTO_DISCOVERY", true)
 	v.SetDefault("ENABLE_HEALTH_CHECKS", true)
+	v.SetDefault("ENABLE_WS_BACKPLANE", false)
+	v.SetDefault("ENABLE_CSRF", true)
+	v.SetDefault("ENABLE_SCHEDULED_PROMPTS", true)
+	v.SetDefault("ENABLE_COMPRESSION", true)
+	v.SetDefault("ENABLE_PPROF", false)
+	v.SetDefault("ENABLE_MODERATION", false)
+	v.SetDefault("ENABLE_OPENAI_API", true)
+	v.SetDefault("ENABLE_FAKE_PROVIDER", false)
+	v.SetDefault("COMPRESSION_LEVEL", 5)
+	v.SetDefault("COMPRESSION_MIN_SIZE", 1024)
+	v.SetDefault("COMPRESSION_TYPES", DefaultCompressionTypes)
+	v.SetDefault("INSTANCE_ID", "")
+	
+	// Provider Health Checks
+	v.SetDefault("HEALTH_CHECK_INTERVAL", 60)
+	v.SetDefault("HEALTH_CHECK_HISTORY_SIZE", 50)
+	v.SetDefault("PROVIDER_CIRCUIT_THRESHOLD", 5)
+	v.SetDefault("PROVIDER_CIRCUIT_COOLDOWN", 30)
+	v.SetDefault("PROVIDER_FAILOVER", "")
+	v.SetDefault("PROVIDER_RETRY_ATTEMPTS", 3)
+	v.SetDefault("PROVIDER_RETRY_BACKOFF", 500)
+	v.SetDefault("PROVIDER_RETRY_MAX_BACKOFF", 8000)
+	v.SetDefault("PROVIDER_RETRY_ON", "network,rate_limit,server")
+	
+	// Database Migrations
+	v.SetDefault("AUTO_MIGRATE", true)
+	
+	// Chat Retention
+	v.SetDefault("DELETED_CHAT_RETENTION_DAYS", 30)
+	v.SetDefault("RETENTION_IDLE_CHAT_DAYS", 0)
+	v.SetDefault("RETENTION_MAX_MESSAGES_PER_CHAT", 0)
+	v.SetDefault("RETENTION_LOG_DAYS", 0)
+	v.SetDefault("RETENTION_INTERVAL", 3600)
+	v.SetDefault("RETENTION_DRY_RUN", false)
+	
+	// Configuration Bundles
+	v.SetDefault("CONFIG_BUNDLE_SECRET", "")
+
+	// Admin
+	v.SetDefault("ADMIN_TOKEN", "")
+
+	// Authentication
+	v.SetDefault("AUTH_MODE", AuthModeSession)
+	v.SetDefault("AUTH_USERS_FILE", "./auth_users.yaml")
+	v.SetDefault("JWT_ALGORITHM", "HS256")
+	v.SetDefault("JWT_SECRET", "")
+	v.SetDefault("JWT_PRIVATE_KEY_FILE", "")
+	v.SetDefault("JWT_PUBLIC_KEY_FILE", "")
+	v.SetDefault("JWT_ISSUER", "ai-gateway-hub")
+	v.SetDefault("JWT_ACCESS_TTL", 900)
+	v.SetDefault("JWT_REFRESH_TTL", 604800)
+	v.SetDefault("DEFAULT_ROLE", "user")
+	
+	// Secret Managers
+	v.SetDefault("VAULT_ADDR", "")
+	v.SetDefault("VAULT_TOKEN", "")
+	v.SetDefault("VAULT_NAMESPACE", "")
+	v.SetDefault("SECRETS_AWS_CLI_PATH", "")
 }
 
 // GetString returns a configuration value as string with environment variable support
@@ -5,6 +5,16 @@ const (
 	// Default values
 	DefaultLanguage = "en"
 	DefaultTheme    = "light"
+
+	// Content types accepted for attachments unless ATTACHMENT_ALLOWED_TYPES is set
+	DefaultAttachmentAllowedTypes = "text/*,image/png,image/jpeg,image/gif,image/webp,application/pdf"
+
+	// Authentication modes: browser sessions, or signed JWTs for users of AUTH_USERS_FILE
+	AuthModeSession = "session"
+	AuthModeJWT     = "jwt"
+
+	// Content types compressed unless COMPRESSION_TYPES is set
+	DefaultCompressionTypes = "text/html,text/css,text/plain,text/markdown,text/javascript,application/javascript,application/json,image/svg+xml"
 )
 
 // Supported values
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -81,9 +82,17 @@ func applyDevelopmentConfig(config *Config) {
 		config.SessionTimeout = 1800 * time.Second // 30 minutes
 	}
 
+	// Local requests may use the admin routes without an ADMIN_TOKEN
+	config.AdminLoopback = true
+
 	// Enable all feature flags in development
 	config.EnableProviderAutoDiscovery = true
 	config.EnableHealthChecks = true
+
+	// Pick up template edits without a rebuild unless explicitly disabled
+	if os.Getenv("TEMPLATE_RELOAD") == "" {
+		config.TemplateReload = true
+	}
 }
 
 // applyTestingConfig applies testing-specific settings
@@ -105,6 +114,7 @@ func applyTestingConfig(config *Config) {
 	// Disable some features that might interfere with tests
 	config.EnableProviderAutoDiscovery = false
 	config.EnableHealthChecks = false
+	config.EnableWSBackplane = false
 
 	// Reduce session limits for tests
 	config.MaxSessions = 10
@@ -151,6 +161,11 @@ func applyProductionConfig(config *Config) {
 
 	// All features should be explicitly configured in production
 	// Don't override feature flags set by environment variables
+
+	// Keep personal data out of production logs unless explicitly disabled
+	if os.Getenv("ENABLE_PII_REDACTION") == "" {
+		config.EnablePIIRedaction = true
+	}
 }
 
 // GetEnvironmentInfo returns information about the current environment
@@ -206,6 +221,14 @@ func validateProductionEnvironment(config *Config, result *ValidationResult) {
 	if strings.Contains(config.SQLiteDBFile, "test") || strings.Contains(config.SQLiteDBFile, "dev") {
 		result.addError("Database file path suggests non-production database")
 	}
+
+	if config.EnablePprof {
+		result.addWarning("pprof endpoints enabled in production - disable ENABLE_PPROF once profiling is done")
+	}
+
+	if config.EnableFakeProvider {
+		result.addWarning("Fake provider enabled in production - disable ENABLE_FAKE_PROVIDER once load testing is done")
+	}
 }
 
 // validateStagingEnvironment adds staging-specific validations
@@ -247,16 +270,85 @@ func ConfigSummary(config *Config) string {
 	summary := fmt.Sprintf("AI Gateway Hub Configuration Summary\n")
 	summary += fmt.Sprintf("Environment: %s\n", env)
 	summary += fmt.Sprintf("Port: %s\n", config.Port)
-	summary += fmt.Sprintf("Database: %s\n", config.SQLiteDBFile)
+	if config.UsesSQLite() {
+		summary += fmt.Sprintf("Database: sqlite3 (%s)\n", config.DatabaseDSN())
+	} else {
+		summary += fmt.Sprintf("Database: %s\n", config.DBDriver)
+	}
 	summary += fmt.Sprintf("Redis: %s\n", config.RedisAddr)
 	summary += fmt.Sprintf("Log Level: %s\n", config.LogLevel)
 	summary += fmt.Sprintf("Max Sessions: %d\n", config.MaxSessions)
 	summary += fmt.Sprintf("Session Timeout: %v\n", config.SessionTimeout)
 	summary += fmt.Sprintf("WebSocket Timeout: %v\n", config.WebSocketTimeout)
-	summary += fmt.Sprintf("Claude CLI: %s\n", config.ClaudeCLIPath)
+	summary += fmt.Sprintf("WebSocket Ticket TTL: %v\n", config.WSTicketTTL)
+	summary += fmt.Sprintf("Claude CLI: %s (resume sessions=%t)\n", config.ClaudeCLIPath, config.ClaudeResumeSessions)
 	summary += fmt.Sprintf("Gemini CLI: %s\n", config.GeminiCLIPath)
-	summary += fmt.Sprintf("Features: AutoDiscovery=%t, HealthChecks=%t\n", 
-		config.EnableProviderAutoDiscovery, config.EnableHealthChecks)
+	summary += fmt.Sprintf("GitHub Models: %s (models=%v)\n", config.GHCLIPath, config.GHModels)
+	summary += fmt.Sprintf("Providers File: %s\n", config.ProvidersFile)
+	summary += fmt.Sprintf("Templates: %s (reload=%t)\n", config.TemplateDir, config.TemplateReload)
+	summary += fmt.Sprintf("Provider Env: allow=%v, deny=%v\n", config.ProviderEnvAllowlist, config.ProviderEnvDenylist)
+	summary += fmt.Sprintf("Provider Sandbox: workdir=%q, wrapper=%v, cpu=%ds, memory=%dMB, file size=%dMB, open files=%d\n",
+		config.ProviderWorkDir, config.ProviderWrapper, config.ProviderLimitCPUSeconds, config.ProviderLimitMemoryMB,
+		config.ProviderLimitFileSizeMB, config.ProviderLimitOpenFiles)
+	summary += fmt.Sprintf("Features: AutoDiscovery=%t, HealthChecks=%t, WSBackplane=%t, CSRF=%t, ScheduledPrompts=%t, Pprof=%t, Moderation=%t, OpenAIAPI=%t, FakeProvider=%t\n",
+		config.EnableProviderAutoDiscovery, config.EnableHealthChecks, config.EnableWSBackplane, config.EnableCSRF, config.EnableScheduledPrompts, config.EnablePprof, config.EnableModeration, config.EnableOpenAIAPI, config.EnableFakeProvider)
+	summary += fmt.Sprintf("Health Checks: every %v, history %d\n",
+		config.HealthCheckInterval, config.HealthCheckHistorySize)
+	if config.ProviderCircuitThreshold > 0 {
+		summary += fmt.Sprintf("Provider Circuit Breaker: open after %d consecutive failures for %v\n",
+			config.ProviderCircuitThreshold, config.ProviderCircuitCooldown)
+	} else {
+		summary += "Provider Circuit Breaker: disabled\n"
+	}
+	summary += fmt.Sprintf("Provider Failover: %v\n", config.ProviderFailover)
+	summary += fmt.Sprintf("Provider Retries: %d attempts, backoff %v up to %v, on %v\n",
+		config.ProviderRetryAttempts, config.ProviderRetryBackoff, config.ProviderRetryMaxBackoff, config.ProviderRetryOn)
+	summary += fmt.Sprintf("Auto Migrate: %t\n", config.AutoMigrate)
+	summary += fmt.Sprintf("Deleted Chat Retention: %d days\n", config.DeletedChatRetentionDays)
+	summary += fmt.Sprintf("Retention Rules: idle chats %d days, %d messages per chat, chat logs %d days, every %v (dry run=%t)\n",
+		config.RetentionIdleChatDays, config.RetentionMaxMessagesPerChat, config.RetentionLogDays, config.RetentionInterval, config.RetentionDryRun)
+	summary += fmt.Sprintf("Config Bundles: %t\n", config.ConfigBundleSecret != "")
+	summary += fmt.Sprintf("Share Link Secret: %t\n", config.ShareLinkSecret != "")
+	summary += fmt.Sprintf("Admin Token: %t\n", config.AdminToken != "")
+	summary += fmt.Sprintf("Default Role: %s\n", config.DefaultRole)
+	if config.AuthMode == AuthModeJWT {
+		summary += fmt.Sprintf("Auth: jwt (%s, users=%s, access=%v, refresh=%v)\n", config.JWTAlgorithm, config.AuthUsersFile, config.JWTAccessTTL, config.JWTRefreshTTL)
+	} else {
+		summary += fmt.Sprintf("Auth: %s\n", config.AuthMode)
+	}
+	summary += fmt.Sprintf("Secret Managers: vault=%q, aws cli=%q\n", config.VaultAddr, config.AWSCLIPath)
+	summary += fmt.Sprintf("Allowed Origins: %v\n", config.AllowedOrigins)
+	summary += fmt.Sprintf("Trusted Proxies: %v\n", config.TrustedProxies)
+	summary += fmt.Sprintf("Compression: %t (level %d, min %d bytes, %v)\n", config.EnableCompression, config.CompressionLevel, config.CompressionMinSize, config.CompressionTypes)
+	summary += fmt.Sprintf("Attachments: %s (max %d MB, %v)\n", config.AttachmentsDir, config.AttachmentMaxSizeMB, config.AttachmentAllowedTypes)
+	summary += fmt.Sprintf("Stream Checkpoints: every %d bytes or %v\n", config.StreamCheckpointBytes, config.StreamCheckpointInterval)
+	summary += fmt.Sprintf("Stream Flush: every %d bytes or %v\n", config.StreamFlushBytes, config.StreamFlushInterval)
+	summary += fmt.Sprintf("Stream Chunking: %s, per provider %v\n", config.StreamChunking, config.ProviderStreamChunking)
+	if config.EnableFakeProvider {
+		summary += fmt.Sprintf("Fake Provider: %d chunks every %v\n", config.FakeProviderChunks, config.FakeProviderDelay)
+	}
+	summary += fmt.Sprintf("Prompt Timeouts: %v, idle %v, per provider %v / idle %v\n",
+		config.PromptTimeout, config.PromptIdleTimeout, config.ProviderPromptTimeouts, config.ProviderIdleTimeouts)
+	summary += fmt.Sprintf("Message Limits: prompt %d characters, response %d bytes (%s)\n",
+		config.MaxPromptLength, config.MaxResponseBytes, config.OversizedResponseAction)
+	summary += fmt.Sprintf("Moderation: %t (rules=%q, service=%q, timeout %v, fail closed=%t)\n",
+		config.EnableModeration, config.ModerationRulesFile, config.ModerationURL, config.ModerationTimeout, config.ModerationFailClosed)
+	summary += fmt.Sprintf("Processors File: %q\n", config.ProcessorsFile)
+	summary += fmt.Sprintf("Documents: max %dMB, chunks of %d (overlap %d), top %d, embedder %s\n",
+		config.DocumentMaxSizeMB, config.RAGChunkSize, config.RAGChunkOverlap, config.RAGTopK, config.EmbeddingProvider)
+	summary += fmt.Sprintf("Tools: %v (max %d calls per response, %s each)\n", config.ToolsEnabled, config.ToolsMaxSteps, config.ToolsTimeout)
+	summary += fmt.Sprintf("Draft TTL: %v\n", config.DraftTTL)
+	summary += fmt.Sprintf("Message Encryption: %t\n", config.EnableMessageEncryption)
+	summary += fmt.Sprintf("PII Redaction: %t (types=%s, %d custom patterns, targets=%s)\n",
+		config.EnablePIIRedaction, strings.Join(config.PIIRedactTypes, ","), len(config.PIIRedactPatterns), strings.Join(config.PIIRedactTargets, ","))
+	switch {
+	case config.TLSAutocert:
+		summary += fmt.Sprintf("TLS: autocert for %v\n", config.TLSAutocertHosts)
+	case config.TLSCertFile != "":
+		summary += fmt.Sprintf("TLS: %s\n", config.TLSCertFile)
+	default:
+		summary += "TLS: disabled\n"
+	}
 	
 	return summary
 }
\ No newline at end of file
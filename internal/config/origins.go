@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// loopbackHosts are the hosts allowed as origins outside production when ALLOWED_ORIGINS is empty
+var loopbackHosts = map[string]bool{
+	"localhost": true,
+	"127.0.0.1": true,
+	"::1":       true,
+}
+
+// AllowsAllOrigins reports whether ALLOWED_ORIGINS contains "*"
+func (c *Config) AllowsAllOrigins() bool {
+	reloadMu.RLock()
+	defer reloadMu.RUnlock()
+	for _, pattern := range c.AllowedOrigins {
+		if pattern == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// OriginAllowed reports whether a browser origin may call the API cross-origin and open WebSocket
+// connections. Patterns may use * wildcards such as https://*.example.com. Without ALLOWED_ORIGINS
+// only loopback origins are allowed, and none in production.
+func (c *Config) OriginAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+
+	reloadMu.RLock()
+	defer reloadMu.RUnlock()
+
+	if len(c.AllowedOrigins) == 0 {
+		if GetCurrentEnvironment() == Production {
+			return false
+		}
+		u, err := url.Parse(origin)
+		return err == nil && loopbackHosts[u.Hostname()]
+	}
+
+	for _, pattern := range c.AllowedOrigins {
+		if pattern == "*" {
+			return true
+		}
+		if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(origin)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// validateOriginPattern checks that an ALLOWED_ORIGINS entry is * or a scheme://host[:port] pattern
+func validateOriginPattern(pattern string) error {
+	if pattern == "*" {
+		return nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	scheme, host, ok := strings.Cut(pattern, "://")
+	if !ok || (scheme != "http" && scheme != "https") {
+		return fmt.Errorf("must start with http:// or https://")
+	}
+	if host == "" || strings.ContainsAny(host, "/?#") {
+		return fmt.Errorf("must be a scheme and host without a path")
+	}
+	return nil
+}
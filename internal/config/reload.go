@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// reloadMu guards the settings that ApplyReloadable may change while requests read them
+var reloadMu sync.RWMutex
+
+// ReloadableKeys are the settings applied at runtime by a configuration reload; changing any
+// other setting needs a restart
+var ReloadableKeys = []string{"LOG_LEVEL", "DAILY_PROMPT_QUOTA", "MONTHLY_PROMPT_QUOTA", "CLAUDE_EXTRA_ARGS", "ALLOWED_ORIGINS", "PROVIDER_FAILOVER"}
+
+// Change is a setting whose value differs after a reload
+type Change struct {
+	Key string `json:"key"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %q -> %q", c.Key, c.Old, c.New)
+}
+
+// reloadableValues formats the reloadable settings for comparison, keyed like ReloadableKeys
+func (c *Config) reloadableValues() map[string]string {
+	return map[string]string{
+		"LOG_LEVEL":            c.LogLevel,
+		"DAILY_PROMPT_QUOTA":   strconv.Itoa(c.DailyPromptQuota),
+		"MONTHLY_PROMPT_QUOTA": strconv.Itoa(c.MonthlyPromptQuota),
+		"CLAUDE_EXTRA_ARGS":    c.ClaudeExtraArgs,
+		"ALLOWED_ORIGINS":      strings.Join(c.AllowedOrigins, ","),
+		"PROVIDER_FAILOVER":    strings.Join(c.ProviderFailover, ","),
+	}
+}
+
+// ApplyReloadable copies the reloadable settings of next into c and returns those that changed.
+// Other settings of next are ignored.
+func (c *Config) ApplyReloadable(next *Config) []Change {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	old, updated := c.reloadableValues(), next.reloadableValues()
+	var changes []Change
+	for _, key := range ReloadableKeys {
+		if old[key] != updated[key] {
+			changes = append(changes, Change{Key: key, Old: old[key], New: updated[key]})
+		}
+	}
+
+	c.LogLevel = next.LogLevel
+	c.DailyPromptQuota = next.DailyPromptQuota
+	c.MonthlyPromptQuota = next.MonthlyPromptQuota
+	c.ClaudeExtraArgs = next.ClaudeExtraArgs
+	c.AllowedOrigins = slices.Clone(next.AllowedOrigins)
+	c.ProviderFailover = slices.Clone(next.ProviderFailover)
+	return changes
+}
+
+// EnvFile loads a .env file into the environment without overriding variables that were already
+// set, like godotenv.Load, and can refresh the variables it set when the file changes
+type EnvFile struct {
+	path   string
+	loaded map[string]bool // variables set from the file
+}
+
+// LoadEnvFile loads the .env file at path; a missing file is returned as an error but still
+// gives an EnvFile that picks the file up on Refresh
+func LoadEnvFile(path string) (*EnvFile, error) {
+	f := &EnvFile{path: path, loaded: make(map[string]bool)}
+	return f, f.Refresh()
+}
+
+// Refresh re-reads the file: variables it set before are updated or unset, new ones are set
+// unless the environment already has them
+func (f *EnvFile) Refresh() error {
+	values, err := godotenv.Read(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+
+	for key := range f.loaded {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(f.loaded, key)
+		}
+	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set && !f.loaded[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		f.loaded[key] = true
+	}
+	return nil
+}
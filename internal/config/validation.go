@@ -1,11 +1,18 @@
 package config
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -32,6 +39,9 @@ func (c *Config) Validate() *ValidationResult {
 	// Validate directories
 	c.validateDirectories(result)
 
+	// Validate database driver
+	c.validateDatabase(result)
+
 	// Validate timeouts
 	c.validateTimeouts(result)
 
@@ -41,6 +51,47 @@ func (c *Config) Validate() *ValidationResult {
 	// Validate feature flags
 	c.validateFeatureFlags(result)
 
+	// Validate provider environment passthrough
+	c.validateProviderEnv(result)
+
+	// Validate provider sandbox
+	c.validateProviderSandbox(result)
+
+	// Validate allowed browser origins
+	c.validateAllowedOrigins(result)
+
+	// Validate TLS settings
+	c.validateTLS(result)
+
+	// Validate trusted reverse proxies
+	c.validateTrustedProxies(result)
+
+	// Validate secret managers
+	c.validateSecretManagers(result)
+
+	// Validate attachment limits
+	c.validateAttachments(result)
+	c.validateCompression(result)
+
+	// Validate streaming response checkpoints
+	c.validateStreamCheckpoints(result)
+	c.validateStreamFlush(result)
+	c.validateStreamChunking(result)
+	c.validateFakeProvider(result)
+	c.validateStreamResume(result)
+	c.validateWSTickets(result)
+	c.validateAuth(result)
+	c.validatePromptTimeouts(result)
+	c.validatePromptQuotas(result)
+	c.validateMessageLimits(result)
+	c.validateModeration(result)
+	c.validateProcessors(result)
+	c.validateDocuments(result)
+	c.validateTools(result)
+	c.validateDrafts(result)
+	c.validatePIIRedaction(result)
+	c.validateMessageEncryption(result)
+
 	// Set overall validity
 	result.Valid = len(result.Errors) == 0
 
@@ -73,9 +124,10 @@ func (c *Config) validatePort() error {
 // validateDirectories validates directory configurations
 func (c *Config) validateDirectories(result *ValidationResult) {
 	directories := map[string]string{
-		"STATIC_DIR":   c.StaticDir,
-		"TEMPLATE_DIR": c.TemplateDir,
-		"LOG_DIR":      c.LogDir,
+		"STATIC_DIR":      c.StaticDir,
+		"TEMPLATE_DIR":    c.TemplateDir,
+		"LOG_DIR":         c.LogDir,
+		"ATTACHMENTS_DIR": c.AttachmentsDir,
 	}
 
 	for name, path := range directories {
@@ -91,7 +143,7 @@ func (c *Config) validateDirectories(result *ValidationResult) {
 	}
 
 	// Validate database file directory
-	if c.SQLiteDBFile != "" {
+	if c.UsesSQLite() && c.SQLiteDBFile != "" && c.DBDSN == "" {
 		dbDir := filepath.Dir(c.SQLiteDBFile)
 		if err := c.ensureDirectoryExists(dbDir); err != nil {
 			result.addError(fmt.Sprintf("SQLITE_DB_FILE directory (%s): %v", dbDir, err))
@@ -99,6 +151,49 @@ func (c *Config) validateDirectories(result *ValidationResult) {
 	}
 }
 
+// validateDatabase validates the database driver and connection settings
+func (c *Config) validateDatabase(result *ValidationResult) {
+	switch strings.ToLower(c.DBDriver) {
+	case "", "sqlite", "sqlite3":
+		if c.DBDSN == "" && c.SQLiteDBFile == "" {
+			result.addError("SQLITE_DB_FILE or DB_DSN is required for the sqlite3 driver")
+		}
+	case "postgres", "postgresql":
+		if c.DBDSN == "" {
+			result.addError("DB_DSN is required for the postgres driver")
+		}
+	default:
+		result.addError(fmt.Sprintf("DB_DRIVER must be sqlite3 or postgres, got: %s", c.DBDriver))
+	}
+
+	if c.UsesSQLite() {
+		c.validateSQLiteTuning(result)
+	}
+}
+
+// validateSQLiteTuning validates the SQLite pragmas and connection pool size
+func (c *Config) validateSQLiteTuning(result *ValidationResult) {
+	switch strings.ToUpper(c.SQLiteJournalMode) {
+	case "", "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF":
+	default:
+		result.addError(fmt.Sprintf("SQLITE_JOURNAL_MODE must be DELETE, TRUNCATE, PERSIST, MEMORY, WAL or OFF, got: %s", c.SQLiteJournalMode))
+	}
+	switch strings.ToUpper(c.SQLiteSynchronous) {
+	case "", "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		result.addError(fmt.Sprintf("SQLITE_SYNCHRONOUS must be OFF, NORMAL, FULL or EXTRA, got: %s", c.SQLiteSynchronous))
+	}
+	if c.SQLiteBusyTimeout < 0 {
+		result.addError("SQLITE_BUSY_TIMEOUT must not be negative")
+	}
+	if c.SQLiteMaxOpenConns < 0 || c.SQLiteMaxIdleConns < 0 {
+		result.addError("SQLITE_MAX_OPEN_CONNS and SQLITE_MAX_IDLE_CONNS must not be negative")
+	}
+	if c.SQLiteBusyTimeout == 0 && c.SQLiteMaxOpenConns != 1 {
+		result.addWarning("SQLITE_BUSY_TIMEOUT is 0: concurrent writes may fail with \"database is locked\"")
+	}
+}
+
 // validateTimeouts validates timeout configurations
 func (c *Config) validateTimeouts(result *ValidationResult) {
 	if c.SessionTimeout <= 0 {
@@ -142,6 +237,597 @@ func (c *Config) validateFeatureFlags(result *ValidationResult) {
 	if c.MaxSessions > 10000 {
 		result.addWarning("MAX_SESSIONS is very high (>10000), may impact performance")
 	}
+
+	if c.EnableHealthChecks {
+		if c.HealthCheckInterval < time.Second {
+			result.addError("HEALTH_CHECK_INTERVAL must be at least 1 second")
+		} else if c.HealthCheckInterval < 10*time.Second {
+			result.addWarning("HEALTH_CHECK_INTERVAL is very short (<10s), provider CLIs will be spawned frequently")
+		}
+
+		if c.HealthCheckHistorySize <= 0 {
+			result.addError("HEALTH_CHECK_HISTORY_SIZE must be positive")
+		}
+	}
+
+	if c.ProviderCircuitThreshold < 0 {
+		result.addError("PROVIDER_CIRCUIT_THRESHOLD must not be negative (0 disables the circuit breaker)")
+	}
+	if c.ProviderCircuitThreshold > 0 && c.ProviderCircuitCooldown < time.Second {
+		result.addError("PROVIDER_CIRCUIT_COOLDOWN must be at least 1 second")
+	}
+	primaries := make(map[string]bool)
+	for _, entry := range c.ProviderFailover {
+		chain, err := parseFailoverChain(entry)
+		if err != nil {
+			result.addError(fmt.Sprintf("PROVIDER_FAILOVER: %v", err))
+		} else if primaries[chain[0]] {
+			result.addError(fmt.Sprintf("PROVIDER_FAILOVER: %s has more than one failover chain", chain[0]))
+		} else {
+			primaries[chain[0]] = true
+		}
+	}
+	if c.ProviderRetryAttempts < 1 {
+		result.addError("PROVIDER_RETRY_ATTEMPTS must be at least 1 (1 disables retries)")
+	}
+	if c.ProviderRetryAttempts > 1 && c.ProviderRetryBackoff <= 0 {
+		result.addError("PROVIDER_RETRY_BACKOFF must be positive")
+	}
+	if c.ProviderRetryMaxBackoff < c.ProviderRetryBackoff {
+		result.addError("PROVIDER_RETRY_MAX_BACKOFF must not be shorter than PROVIDER_RETRY_BACKOFF")
+	}
+	for _, class := range c.ProviderRetryOn {
+		switch class {
+		case "network", "rate_limit", "server", "crash":
+		default:
+			result.addError(fmt.Sprintf("PROVIDER_RETRY_ON must list network, rate_limit, server or crash, got %q", class))
+		}
+	}
+
+	if c.DeletedChatRetentionDays < 0 {
+		result.addError("DELETED_CHAT_RETENTION_DAYS must not be negative")
+	}
+	c.validateRetention(result)
+
+	if c.ConfigBundleSecret != "" && len(c.ConfigBundleSecret) < 16 {
+		result.addWarning("CONFIG_BUNDLE_SECRET is short (<16 characters), configuration bundles are easy to forge")
+	}
+
+	if c.ShareLinkSecret == "" {
+		result.addWarning("SHARE_LINK_SECRET is empty, shared chat links stop working when the server restarts")
+	} else if len(c.ShareLinkSecret) < 32 {
+		result.addError("SHARE_LINK_SECRET must be at least 32 characters")
+	}
+
+	if c.AdminToken == "" {
+		if c.AdminLoopback {
+			result.addWarning("ADMIN_TOKEN is empty, every loopback client is an admin, including a reverse proxy on this host that isn't in TRUSTED_PROXIES")
+		} else {
+			result.addWarning("ADMIN_TOKEN is empty, the admin dashboard and API are disabled outside the development environment")
+		}
+	} else if len(c.AdminToken) < 16 {
+		result.addWarning("ADMIN_TOKEN is short (<16 characters), the admin role is easy to guess")
+	}
+}
+
+// validateProviderEnv validates the provider environment allow/deny patterns
+func (c *Config) validateProviderEnv(result *ValidationResult) {
+	for _, pattern := range append(append([]string{}, c.ProviderEnvAllowlist...), c.ProviderEnvDenylist...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			result.addError(fmt.Sprintf("PROVIDER_ENV pattern %q is invalid: %v", pattern, err))
+		}
+	}
+
+	for _, pattern := range c.ProviderEnvAllowlist {
+		if pattern == "*" {
+			result.addWarning("PROVIDER_ENV_ALLOWLIST=* passes the full server environment to provider CLIs")
+		}
+	}
+}
+
+// validateProviderSandbox validates the provider wrapper command and resource limits
+func (c *Config) validateProviderSandbox(result *ValidationResult) {
+	limits := map[string]int{
+		"PROVIDER_LIMIT_CPU_SECONDS":  c.ProviderLimitCPUSeconds,
+		"PROVIDER_LIMIT_MEMORY_MB":    c.ProviderLimitMemoryMB,
+		"PROVIDER_LIMIT_FILE_SIZE_MB": c.ProviderLimitFileSizeMB,
+		"PROVIDER_LIMIT_OPEN_FILES":   c.ProviderLimitOpenFiles,
+	}
+	for name, value := range limits {
+		if value < 0 {
+			result.addError(fmt.Sprintf("%s must not be negative", name))
+		}
+	}
+
+	if len(c.ProviderWrapper) > 0 && !c.isExecutableAvailable(c.ProviderWrapper[0]) {
+		result.addWarning(fmt.Sprintf("PROVIDER_WRAPPER command not found: %s", c.ProviderWrapper[0]))
+	}
+	if c.ClaudeSkipPermissions && len(c.ProviderWrapper) == 0 && c.ProviderWorkDir == "" {
+		result.addWarning("CLAUDE_SKIP_PERMISSIONS runs Claude without permission prompts; consider PROVIDER_WRAPPER or PROVIDER_WORKDIR to contain it")
+	}
+}
+
+// validateSecretManagers validates the Vault and AWS Secrets Manager settings
+func (c *Config) validateSecretManagers(result *ValidationResult) {
+	if c.VaultAddr != "" {
+		u, err := url.Parse(c.VaultAddr)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			result.addError("VAULT_ADDR must be an http(s) URL")
+		} else if u.Scheme == "http" && !isLoopbackHost(u.Hostname()) {
+			result.addWarning("VAULT_ADDR uses plain http; the Vault token is sent unencrypted")
+		}
+		if c.VaultToken == "" {
+			result.addError("VAULT_TOKEN is required when VAULT_ADDR is set")
+		}
+	}
+
+	if c.AWSCLIPath != "" && !c.isExecutableAvailable(c.AWSCLIPath) {
+		result.addWarning(fmt.Sprintf("SECRETS_AWS_CLI_PATH not found: %s", c.AWSCLIPath))
+	}
+}
+
+// isLoopbackHost reports whether host names this machine
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validateAllowedOrigins validates the CORS and WebSocket origin patterns
+func (c *Config) validateAllowedOrigins(result *ValidationResult) {
+	for _, pattern := range c.AllowedOrigins {
+		if err := validateOriginPattern(pattern); err != nil {
+			result.addError(fmt.Sprintf("ALLOWED_ORIGINS entry %q is invalid: %v", pattern, err))
+		}
+	}
+
+	if c.AllowsAllOrigins() {
+		result.addWarning("ALLOWED_ORIGINS=* lets any site call the API and open WebSocket connections")
+	}
+
+	if os.Getenv("ALLOWED_ORIGINS") == "" && os.Getenv("ALLOWED_WEBSOCKET_ORIGINS") != "" {
+		result.addWarning("ALLOWED_WEBSOCKET_ORIGINS is deprecated, use ALLOWED_ORIGINS")
+	}
+}
+
+// validateTLS validates the certificate files or autocert settings and the redirect listener
+func (c *Config) validateTLS(result *ValidationResult) {
+	if c.TLSAutocert {
+		if c.TLSCertFile != "" || c.TLSKeyFile != "" {
+			result.addError("TLS_AUTOCERT cannot be combined with TLS_CERT_FILE/TLS_KEY_FILE")
+		}
+		if len(c.TLSAutocertHosts) == 0 {
+			result.addError("TLS_AUTOCERT_HOSTS is required with TLS_AUTOCERT")
+		}
+		if err := c.ensureDirectoryExists(c.TLSAutocertCacheDir); err != nil {
+			result.addError(fmt.Sprintf("TLS_AUTOCERT_CACHE_DIR (%s): %v", c.TLSAutocertCacheDir, err))
+		}
+		if c.HTTPRedirectPort != "80" {
+			result.addWarning("HTTP_REDIRECT_PORT is not 80, Let's Encrypt can only use the TLS-ALPN challenge on the HTTPS port (which must be 443)")
+		}
+	} else if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		result.addError("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	} else if c.TLSCertFile != "" {
+		if _, err := os.Stat(c.TLSCertFile); err != nil {
+			result.addError(fmt.Sprintf("TLS_CERT_FILE (%s): %v", c.TLSCertFile, err))
+		}
+		if _, err := os.Stat(c.TLSKeyFile); err != nil {
+			result.addError(fmt.Sprintf("TLS_KEY_FILE (%s): %v", c.TLSKeyFile, err))
+		}
+	}
+
+	if c.HTTPRedirectPort != "" {
+		if !c.TLSEnabled() {
+			result.addWarning("HTTP_REDIRECT_PORT is ignored without TLS")
+		} else if port, err := strconv.Atoi(c.HTTPRedirectPort); err != nil || port < 1 || port > 65535 {
+			result.addError(fmt.Sprintf("HTTP_REDIRECT_PORT must be a port number, got: %s", c.HTTPRedirectPort))
+		} else if c.HTTPRedirectPort == c.Port {
+			result.addError("HTTP_REDIRECT_PORT must differ from PORT")
+		}
+	}
+}
+
+// validateTrustedProxies validates the trusted proxy IPs and CIDRs
+func (c *Config) validateTrustedProxies(result *ValidationResult) {
+	for _, entry := range c.TrustedProxies {
+		if strings.Contains(entry, "/") {
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				result.addError(fmt.Sprintf("TRUSTED_PROXIES entry %q is not a valid CIDR", entry))
+			} else if ones, _ := ipNet.Mask.Size(); ones == 0 {
+				result.addWarning(fmt.Sprintf("TRUSTED_PROXIES entry %q trusts every client's X-Forwarded-For, client IPs can be spoofed", entry))
+			}
+		} else if net.ParseIP(entry) == nil {
+			result.addError(fmt.Sprintf("TRUSTED_PROXIES entry %q is not a valid IP address", entry))
+		}
+	}
+}
+
+// validateAttachments validates the attachment size limit and content type patterns
+func (c *Config) validateAttachments(result *ValidationResult) {
+	if c.AttachmentMaxSizeMB <= 0 {
+		result.addError("ATTACHMENT_MAX_SIZE_MB must be positive")
+	} else if c.AttachmentMaxSizeMB > 100 {
+		result.addWarning("ATTACHMENT_MAX_SIZE_MB is very large (>100), uploads are buffered on disk and passed to provider CLIs")
+	}
+
+	for _, pattern := range c.AttachmentAllowedTypes {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.Contains(pattern, "/") {
+			result.addError(fmt.Sprintf("ATTACHMENT_ALLOWED_TYPES entry %q is not a content type pattern", pattern))
+		}
+	}
+}
+
+// validateCompression validates the compression level, size threshold and content type patterns
+func (c *Config) validateCompression(result *ValidationResult) {
+	if !c.EnableCompression {
+		return
+	}
+	if c.CompressionLevel < 1 || c.CompressionLevel > 9 {
+		result.addError("COMPRESSION_LEVEL must be between 1 and 9")
+	}
+	if c.CompressionMinSize < 0 {
+		result.addError("COMPRESSION_MIN_SIZE must not be negative")
+	}
+	for _, pattern := range c.CompressionTypes {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.Contains(pattern, "/") {
+			result.addError(fmt.Sprintf("COMPRESSION_TYPES entry %q is not a content type pattern", pattern))
+		}
+	}
+}
+
+// validateStreamCheckpoints validates how often streaming responses are saved
+func (c *Config) validateStreamCheckpoints(result *ValidationResult) {
+	if c.StreamCheckpointBytes < 0 {
+		result.addError("STREAM_CHECKPOINT_BYTES must not be negative")
+	}
+	if c.StreamCheckpointInterval < 0 {
+		result.addError("STREAM_CHECKPOINT_INTERVAL must not be negative")
+	}
+	if c.StreamCheckpointBytes == 0 && c.StreamCheckpointInterval == 0 {
+		result.addWarning("Streaming response checkpoints are disabled, responses are lost if the server stops mid-stream")
+	} else if c.StreamCheckpointBytes > 0 && c.StreamCheckpointBytes < 256 {
+		result.addWarning("STREAM_CHECKPOINT_BYTES is very small (<256), responses are written to the database very often")
+	}
+}
+
+// validateStreamFlush validates how streamed chunks are batched into WebSocket frames
+func (c *Config) validateStreamFlush(result *ValidationResult) {
+	if c.StreamFlushBytes < 0 {
+		result.addError("STREAM_FLUSH_BYTES must not be negative")
+	}
+	if c.StreamFlushInterval < 0 {
+		result.addError("STREAM_FLUSH_INTERVAL must not be negative")
+	}
+	if c.StreamFlushInterval > time.Second {
+		result.addWarning("STREAM_FLUSH_INTERVAL is over 1000ms, streamed responses will appear in bursts")
+	}
+}
+
+// validateStreamChunking validates what streamed output is held back, overall and per provider
+func (c *Config) validateStreamChunking(result *ValidationResult) {
+	if !validStreamChunking(c.StreamChunking) {
+		result.addError(fmt.Sprintf("STREAM_CHUNKING must be rune or markdown, got %q", c.StreamChunking))
+	}
+	for _, entry := range c.ProviderStreamChunking {
+		id, mode, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(id) == "" {
+			result.addError(fmt.Sprintf("PROVIDER_STREAM_CHUNKING: invalid entry %q, expected provider=mode", entry))
+		} else if !validStreamChunking(strings.TrimSpace(mode)) {
+			result.addError(fmt.Sprintf("PROVIDER_STREAM_CHUNKING: mode of %q must be rune or markdown", entry))
+		}
+	}
+}
+
+func validStreamChunking(mode string) bool {
+	switch mode {
+	case "rune", "markdown":
+		return true
+	}
+	return false
+}
+
+// validateFakeProvider validates the synthetic responses of the fake provider
+func (c *Config) validateFakeProvider(result *ValidationResult) {
+	if !c.EnableFakeProvider {
+		return
+	}
+	if c.FakeProviderChunks < 1 {
+		result.addError("FAKE_PROVIDER_CHUNKS must be at least 1")
+	}
+	if c.FakeProviderDelay < 0 {
+		result.addError("FAKE_PROVIDER_DELAY must not be negative")
+	}
+}
+
+// validateStreamResume validates how long streamed chunks are kept for reconnecting clients
+func (c *Config) validateStreamResume(result *ValidationResult) {
+	if c.StreamResumeWindow < 0 {
+		result.addError("STREAM_RESUME_WINDOW must not be negative")
+	}
+	if c.StreamResumeWindow > 10*time.Minute {
+		result.addWarning("STREAM_RESUME_WINDOW is over 10 minutes, buffered responses use memory for a long time")
+	}
+}
+
+// validateWSTickets validates how long WebSocket tickets stay valid
+func (c *Config) validateWSTickets(result *ValidationResult) {
+	if c.WSTicketTTL <= 0 {
+		result.addError("WS_TICKET_TTL must be positive")
+	}
+	if c.WSTicketTTL > 5*time.Minute {
+		result.addWarning("WS_TICKET_TTL is over 5 minutes, leaked tickets stay usable for a long time")
+	}
+}
+
+// validateAuth validates the default role, the authentication mode and, in jwt mode, its keys and
+// users file
+func (c *Config) validateAuth(result *ValidationResult) {
+	if c.DefaultRole != "viewer" && c.DefaultRole != "user" {
+		result.addError("DEFAULT_ROLE must be viewer or user")
+	}
+
+	switch c.AuthMode {
+	case AuthModeSession:
+		return
+	case AuthModeJWT:
+	default:
+		result.addError(fmt.Sprintf("AUTH_MODE must be %s or %s", AuthModeSession, AuthModeJWT))
+		return
+	}
+
+	switch c.JWTAlgorithm {
+	case "HS256":
+		if len(c.JWTSecret) < 32 {
+			result.addError("JWT_SECRET must be at least 32 characters with JWT_ALGORITHM=HS256")
+		}
+	case "RS256":
+		if c.JWTPrivateKeyFile == "" {
+			result.addError("JWT_PRIVATE_KEY_FILE is required with JWT_ALGORITHM=RS256")
+		} else if _, err := os.Stat(c.JWTPrivateKeyFile); err != nil {
+			result.addError(fmt.Sprintf("JWT_PRIVATE_KEY_FILE not found: %s", c.JWTPrivateKeyFile))
+		}
+		if c.JWTPublicKeyFile != "" {
+			if _, err := os.Stat(c.JWTPublicKeyFile); err != nil {
+				result.addError(fmt.Sprintf("JWT_PUBLIC_KEY_FILE not found: %s", c.JWTPublicKeyFile))
+			}
+		}
+	default:
+		result.addError("JWT_ALGORITHM must be HS256 or RS256")
+	}
+
+	if _, err := os.Stat(c.AuthUsersFile); err != nil {
+		result.addError(fmt.Sprintf("AUTH_USERS_FILE not found: %s", c.AuthUsersFile))
+	}
+	if c.JWTIssuer == "" {
+		result.addError("JWT_ISSUER must not be empty")
+	}
+	if c.JWTAccessTTL <= 0 {
+		result.addError("JWT_ACCESS_TTL must be positive")
+	}
+	if c.JWTAccessTTL > time.Hour {
+		result.addWarning("JWT_ACCESS_TTL is over 1 hour, access tokens can't be revoked before they expire")
+	}
+	if c.JWTRefreshTTL < c.JWTAccessTTL {
+		result.addError("JWT_REFRESH_TTL must not be shorter than JWT_ACCESS_TTL")
+	}
+}
+
+// validatePromptTimeouts validates how long prompts may stream and go without output
+func (c *Config) validatePromptTimeouts(result *ValidationResult) {
+	if c.PromptTimeout <= 0 {
+		result.addError("PROMPT_TIMEOUT must be positive")
+	}
+	if c.PromptIdleTimeout < 0 {
+		result.addError("PROMPT_IDLE_TIMEOUT must not be negative")
+	}
+	for _, entry := range c.ProviderPromptTimeouts {
+		if _, d, err := parseProviderTimeout(entry); err != nil {
+			result.addError(fmt.Sprintf("PROVIDER_PROMPT_TIMEOUTS: %v", err))
+		} else if d == 0 {
+			result.addError(fmt.Sprintf("PROVIDER_PROMPT_TIMEOUTS: timeout %q must be positive", entry))
+		}
+	}
+	for _, entry := range c.ProviderIdleTimeouts {
+		if _, _, err := parseProviderTimeout(entry); err != nil {
+			result.addError(fmt.Sprintf("PROVIDER_IDLE_TIMEOUTS: %v", err))
+		}
+	}
+}
+
+// validatePromptQuotas validates the daily and monthly prompt quotas
+func (c *Config) validatePromptQuotas(result *ValidationResult) {
+	if c.DailyPromptQuota < 0 {
+		result.addError("DAILY_PROMPT_QUOTA must not be negative")
+	}
+	if c.MonthlyPromptQuota < 0 {
+		result.addError("MONTHLY_PROMPT_QUOTA must not be negative")
+	}
+	if c.DailyPromptQuota > 0 && c.MonthlyPromptQuota > 0 && c.DailyPromptQuota > c.MonthlyPromptQuota {
+		result.addWarning("DAILY_PROMPT_QUOTA is larger than MONTHLY_PROMPT_QUOTA")
+	}
+}
+
+// validateMessageLimits validates the prompt and response size limits
+func (c *Config) validateMessageLimits(result *ValidationResult) {
+	if c.MaxPromptLength < 0 {
+		result.addError("MAX_PROMPT_LENGTH must not be negative")
+	}
+	if c.MaxResponseBytes < 0 {
+		result.addError("MAX_RESPONSE_BYTES must not be negative")
+	}
+	switch c.OversizedResponseAction {
+	case "", "truncate", "warn":
+	default:
+		result.addError(fmt.Sprintf("OVERSIZED_RESPONSE_ACTION must be truncate or warn, got %q", c.OversizedResponseAction))
+	}
+}
+
+// validateModeration validates the moderation rules file and service
+func (c *Config) validateModeration(result *ValidationResult) {
+	if !c.EnableModeration {
+		return
+	}
+	if c.ModerationRulesFile == "" && c.ModerationURL == "" {
+		result.addWarning("ENABLE_MODERATION is set without MODERATION_RULES_FILE or MODERATION_URL, so nothing is moderated")
+	}
+	if c.ModerationRulesFile != "" {
+		if _, err := os.Stat(c.ModerationRulesFile); err != nil {
+			result.addError(fmt.Sprintf("MODERATION_RULES_FILE: %v", err))
+		}
+	}
+	if c.ModerationURL != "" {
+		if u, err := url.Parse(c.ModerationURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			result.addError(fmt.Sprintf("MODERATION_URL must be an http or https URL, got %q", c.ModerationURL))
+		}
+	}
+	if c.ModerationTimeout <= 0 {
+		result.addError("MODERATION_TIMEOUT must be positive")
+	}
+}
+
+// validateProcessors validates the processors file
+func (c *Config) validateProcessors(result *ValidationResult) {
+	if c.ProcessorsFile == "" {
+		return
+	}
+	if _, err := os.Stat(c.ProcessorsFile); err != nil {
+		result.addError(fmt.Sprintf("PROCESSORS_FILE: %v", err))
+	}
+}
+
+// validateDocuments validates document chunking and the embedder
+func (c *Config) validateDocuments(result *ValidationResult) {
+	if c.DocumentMaxSizeMB <= 0 {
+		result.addError("DOCUMENT_MAX_SIZE_MB must be positive")
+	}
+	if c.RAGChunkSize < 100 {
+		result.addError("RAG_CHUNK_SIZE must be at least 100")
+	}
+	if c.RAGChunkOverlap < 0 || c.RAGChunkOverlap >= c.RAGChunkSize {
+		result.addError("RAG_CHUNK_OVERLAP must be at least 0 and less than RAG_CHUNK_SIZE")
+	}
+	if c.RAGTopK < 1 || c.RAGTopK > 20 {
+		result.addError("RAG_TOP_K must be between 1 and 20")
+	}
+	switch c.EmbeddingProvider {
+	case "", "hash":
+		if c.EmbeddingDimensions < 64 || c.EmbeddingDimensions > 4096 {
+			result.addError("EMBEDDING_DIMENSIONS must be between 64 and 4096")
+		}
+	case "http":
+		if u, err := url.Parse(c.EmbeddingURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			result.addError(fmt.Sprintf("EMBEDDING_URL must be an http or https URL, got %q", c.EmbeddingURL))
+		}
+		if c.EmbeddingModel == "" {
+			result.addError("EMBEDDING_MODEL is required with EMBEDDING_PROVIDER=http")
+		}
+		if c.EmbeddingTimeout <= 0 {
+			result.addError("EMBEDDING_TIMEOUT must be positive")
+		}
+	default:
+		result.addError(fmt.Sprintf("EMBEDDING_PROVIDER must be hash or http, got %q", c.EmbeddingProvider))
+	}
+}
+
+// validateTools validates the enabled tools and their limits
+func (c *Config) validateTools(result *ValidationResult) {
+	for _, name := range c.ToolsEnabled {
+		switch name {
+		case "calculator", "http_fetch":
+		case "shell":
+			if len(c.ToolsShellCommands) == 0 {
+				result.addError("TOOLS_SHELL_COMMANDS is required when the shell tool is enabled")
+			}
+		default:
+			result.addError(fmt.Sprintf("TOOLS_ENABLED has unknown tool %q, expected calculator, http_fetch or shell", name))
+		}
+	}
+	for _, command := range c.ToolsShellCommands {
+		if strings.ContainsRune(command, '/') {
+			result.addError(fmt.Sprintf("TOOLS_SHELL_COMMANDS must name commands on the PATH, got %q", command))
+		}
+	}
+	if c.ToolsMaxSteps < 1 || c.ToolsMaxSteps > 20 {
+		result.addError("TOOLS_MAX_STEPS must be between 1 and 20")
+	}
+	if c.ToolsTimeout <= 0 {
+		result.addError("TOOLS_TIMEOUT must be positive")
+	}
+}
+
+// validateDrafts validates how long prompt drafts are kept
+func (c *Config) validateDrafts(result *ValidationResult) {
+	if c.DraftTTL <= 0 {
+		result.addError("DRAFT_TTL must be positive")
+	}
+}
+
+// validateRetention validates the retention rules and how often they run
+func (c *Config) validateRetention(result *ValidationResult) {
+	if c.RetentionIdleChatDays < 0 {
+		result.addError("RETENTION_IDLE_CHAT_DAYS must not be negative")
+	}
+	if c.RetentionMaxMessagesPerChat < 0 {
+		result.addError("RETENTION_MAX_MESSAGES_PER_CHAT must not be negative")
+	}
+	if c.RetentionLogDays < 0 {
+		result.addError("RETENTION_LOG_DAYS must not be negative")
+	}
+	if c.RetentionInterval <= 0 {
+		result.addError("RETENTION_INTERVAL must be positive")
+	}
+	if c.RetentionIdleChatDays > 0 && c.DeletedChatRetentionDays == 0 {
+		result.addWarning("RETENTION_IDLE_CHAT_DAYS moves idle chats to the trash, but DELETED_CHAT_RETENTION_DAYS=0 never purges them")
+	}
+}
+
+// validatePIIRedaction validates the kinds, patterns and targets of PII redaction
+func (c *Config) validatePIIRedaction(result *ValidationResult) {
+	for _, kind := range c.PIIRedactTypes {
+		switch kind {
+		case "email", "api_key", "credit_card":
+		default:
+			result.addError(fmt.Sprintf("PII_REDACT_TYPES must list email, api_key or credit_card, got %q", kind))
+		}
+	}
+	for _, pattern := range c.PIIRedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			result.addError(fmt.Sprintf("PII_REDACT_PATTERNS: invalid pattern %q: %v", pattern, err))
+		}
+	}
+	for _, target := range c.PIIRedactTargets {
+		if target != "logs" && target != "chat_logs" {
+			result.addError(fmt.Sprintf("PII_REDACT_TARGETS must list logs or chat_logs, got %q", target))
+		}
+	}
+	if c.EnablePIIRedaction && len(c.PIIRedactTargets) == 0 {
+		result.addWarning("ENABLE_PII_REDACTION is set without PII_REDACT_TARGETS, so nothing is redacted")
+	}
+}
+
+// validateMessageEncryption checks that a well-formed key is set when message encryption is enabled.
+// Keys given as secret references are checked once they are resolved.
+func (c *Config) validateMessageEncryption(result *ValidationResult) {
+	if !c.EnableMessageEncryption {
+		return
+	}
+	if c.MessageEncryptionKey == "" {
+		result.addError("MESSAGE_ENCRYPTION_KEY is required when ENABLE_MESSAGE_ENCRYPTION is set")
+		return
+	}
+	if strings.Contains(c.MessageEncryptionKey, ":") {
+		return
+	}
+	if key, err := base64.StdEncoding.DecodeString(c.MessageEncryptionKey); err == nil && len(key) == 32 {
+		return
+	}
+	if key, err := hex.DecodeString(c.MessageEncryptionKey); err == nil && len(key) == 32 {
+		return
+	}
+	result.addError("MESSAGE_ENCRYPTION_KEY must be a 32 byte key in base64 or hex, or a secret reference")
 }
 
 // ensureDirectoryExists checks if directory exists and creates it if needed
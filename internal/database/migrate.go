@@ -0,0 +1,341 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/utils"
+)
+
+// Migrations are embedded SQL files named NNNN_description.up.sql / NNNN_description.down.sql,
+// kept in one directory per dialect
+//
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes whether a known migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// LoadMigrations returns the embedded migrations of a dialect ordered by version
+func LoadMigrations(dialect Dialect) ([]Migration, error) {
+	dir := "migrations/sqlite"
+	if dialect == Postgres {
+		dir = "migrations/postgres"
+	}
+	return loadMigrations(migrationFiles, dir)
+}
+
+// loadMigrations parses up/down SQL files from dir into migrations ordered by version
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %s (expected NNNN_name.%s.sql)", fileName, direction)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", fileName, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// ensureSchemaVersionTable creates the table recording applied migrations
+func ensureSchemaVersionTable(db *DB) error {
+	timestampType := "DATETIME"
+	if db.Dialect() == Postgres {
+		timestampType = "TIMESTAMPTZ"
+	}
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at ` + timestampType + ` NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+	return nil
+}
+
+// appliedVersions returns the applied migration versions and when they were applied
+func appliedVersions(db *DB) (map[int]time.Time, error) {
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_version: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_version: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// SchemaVersion returns the highest applied migration version (0 for an empty database)
+func SchemaVersion(db *DB) (int, error) {
+	if err := ensureSchemaVersionTable(db); err != nil {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// MigrationStatuses lists every known migration and whether it has been applied
+func MigrationStatuses(db *DB) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(db.Dialect())
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureSchemaVersionTable(db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.Version]
+		statuses = append(statuses, MigrationStatus{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: appliedAt})
+	}
+	return statuses, nil
+}
+
+// PendingMigrations returns the migrations that have not been applied yet
+func PendingMigrations(db *DB) ([]Migration, error) {
+	migrations, err := LoadMigrations(db.Dialect())
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureSchemaVersionTable(db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies every pending migration in order and returns how many were applied
+func Migrate(db *DB) (int, error) {
+	migrations, err := LoadMigrations(db.Dialect())
+	if err != nil {
+		return 0, err
+	}
+	return migrate(db, migrations)
+}
+
+// migrate applies the pending subset of migrations, each in its own transaction
+func migrate(db *DB, migrations []Migration) (int, error) {
+	if err := ensureSchemaVersionTable(db); err != nil {
+		return 0, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(applied) == 0 && db.Dialect() == SQLite {
+		if err := upgradeLegacySchema(db); err != nil {
+			return 0, err
+		}
+	}
+
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	for version := range applied {
+		if version > latest {
+			return 0, fmt.Errorf("database schema version %d is newer than this build supports (%d)", version, latest)
+		}
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := runMigration(db, m.Version, m.Name, m.Up, true); err != nil {
+			return count, err
+		}
+		utils.Info("Applied database migration %04d_%s", m.Version, m.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Rollback reverts the most recently applied migrations, up to steps of them, and returns how many were reverted
+func Rollback(db *DB, steps int) (int, error) {
+	migrations, err := LoadMigrations(db.Dialect())
+	if err != nil {
+		return 0, err
+	}
+	return rollback(db, migrations, steps)
+}
+
+// rollback reverts applied migrations newest first
+func rollback(db *DB, migrations []Migration, steps int) (int, error) {
+	if err := ensureSchemaVersionTable(db); err != nil {
+		return 0, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return count, fmt.Errorf("migration %04d_%s has no down file and cannot be rolled back", m.Version, m.Name)
+		}
+		if err := runMigration(db, m.Version, m.Name, m.Down, false); err != nil {
+			return count, err
+		}
+		utils.Info("Rolled back database migration %04d_%s", m.Version, m.Name)
+		count++
+	}
+	return count, nil
+}
+
+// runMigration executes one migration script and records it in schema_version atomically
+func runMigration(db *DB, version int, name, script string, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %04d_%s: %w", version, name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return fmt.Errorf("failed to run migration %04d_%s: %w", version, name, err)
+	}
+
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`, version, name, time.Now())
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_version WHERE version = ?`, version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %04d_%s: %w", version, name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %04d_%s: %w", version, name, err)
+	}
+	return nil
+}
+
+// upgradeLegacySchema brings databases created before versioned migrations up to the
+// initial migration's schema by adding the columns that were backfilled at startup
+func upgradeLegacySchema(db *DB) error {
+	var name string
+	err := db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'chats'`).Scan(&name)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect existing schema: %w", err)
+	}
+
+	utils.Info("Upgrading database created before schema migrations")
+	columns := []struct{ table, column, definition string }{
+		{"messages", "provider", "TEXT NOT NULL DEFAULT ''"},
+		{"chats", "system_prompt", "TEXT NOT NULL DEFAULT ''"},
+		{"chats", "archived_at", "DATETIME"},
+		{"chats", "deleted_at", "DATETIME"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
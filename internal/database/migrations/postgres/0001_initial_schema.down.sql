@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_chats_deleted_at;
+DROP INDEX IF EXISTS idx_sessions_expires_at;
+DROP INDEX IF EXISTS idx_generation_events_generation_id;
+DROP INDEX IF EXISTS idx_generation_events_chat_id;
+DROP INDEX IF EXISTS idx_usage_records_created_at;
+DROP INDEX IF EXISTS idx_usage_records_chat_id;
+DROP INDEX IF EXISTS idx_messages_chat_id;
+
+DROP TABLE IF EXISTS app_settings;
+DROP TABLE IF EXISTS usage_records;
+DROP TABLE IF EXISTS generation_events;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS chats;
@@ -0,0 +1,70 @@
+-- Schema as of the introduction of versioned migrations
+
+CREATE TABLE IF NOT EXISTS chats (
+	id BIGSERIAL PRIMARY KEY,
+	title TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	system_prompt TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	archived_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+	id BIGSERIAL PRIMARY KEY,
+	chat_id BIGINT NOT NULL,
+	role TEXT NOT NULL CHECK(role IN ('user', 'assistant', 'system')),
+	content TEXT NOT NULL,
+	provider TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id TEXT PRIMARY KEY,
+	chat_id BIGINT,
+	data TEXT,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMPTZ,
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS generation_events (
+	id BIGSERIAL PRIMARY KEY,
+	generation_id TEXT NOT NULL,
+	chat_id BIGINT NOT NULL,
+	provider TEXT NOT NULL,
+	event TEXT NOT NULL CHECK(event IN ('queued', 'started', 'first_token', 'completed', 'failed')),
+	detail TEXT NOT NULL DEFAULT '',
+	occurred_at BIGINT NOT NULL,
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS usage_records (
+	id BIGSERIAL PRIMARY KEY,
+	chat_id BIGINT NOT NULL,
+	message_id BIGINT,
+	provider TEXT NOT NULL,
+	direction TEXT NOT NULL CHECK(direction IN ('input', 'output')),
+	bytes BIGINT NOT NULL DEFAULT 0,
+	tokens BIGINT NOT NULL DEFAULT 0,
+	estimated BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at BIGINT NOT NULL,
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE,
+	FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS app_settings (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_messages_chat_id ON messages(chat_id);
+CREATE INDEX IF NOT EXISTS idx_usage_records_chat_id ON usage_records(chat_id);
+CREATE INDEX IF NOT EXISTS idx_usage_records_created_at ON usage_records(created_at);
+CREATE INDEX IF NOT EXISTS idx_generation_events_chat_id ON generation_events(chat_id);
+CREATE INDEX IF NOT EXISTS idx_generation_events_generation_id ON generation_events(generation_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+CREATE INDEX IF NOT EXISTS idx_chats_deleted_at ON chats(deleted_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_attachments_message_id;
+DROP INDEX IF EXISTS idx_attachments_chat_id;
+
+DROP TABLE IF EXISTS attachments;
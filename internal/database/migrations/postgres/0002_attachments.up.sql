@@ -0,0 +1,17 @@
+-- Files uploaded to a chat; message_id is set once the attachment is sent with a prompt
+
+CREATE TABLE IF NOT EXISTS attachments (
+	id BIGSERIAL PRIMARY KEY,
+	chat_id BIGINT NOT NULL,
+	message_id BIGINT,
+	filename TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	storage_path TEXT NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE,
+	FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_chat_id ON attachments(chat_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_message_id ON attachments(message_id);
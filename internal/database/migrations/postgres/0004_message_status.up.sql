@@ -0,0 +1,7 @@
+-- Assistant responses are checkpointed while they stream; status tells complete messages from
+-- partial ones, which are flagged as interrupted when their stream never finished
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'complete' CHECK(status IN ('complete', 'streaming', 'interrupted'));
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS checkpointed_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_messages_streaming ON messages(status) WHERE status = 'streaming';
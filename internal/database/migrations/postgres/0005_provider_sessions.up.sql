@@ -0,0 +1,10 @@
+-- Native provider sessions continued by follow-up prompts in a chat
+
+CREATE TABLE IF NOT EXISTS provider_sessions (
+	chat_id BIGINT NOT NULL,
+	provider TEXT NOT NULL,
+	session_id TEXT NOT NULL,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (chat_id, provider),
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
+);
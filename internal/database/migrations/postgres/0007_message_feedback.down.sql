@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_message_feedback_updated_at;
+DROP INDEX IF EXISTS idx_message_feedback_chat_id;
+DROP TABLE IF EXISTS message_feedback;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS model;
@@ -0,0 +1,18 @@
+-- Thumbs-up/down feedback on assistant messages. Messages record the model they were
+-- requested with (empty for the provider default) so feedback can be reviewed per model.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS model TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS message_feedback (
+	message_id BIGINT PRIMARY KEY,
+	chat_id BIGINT NOT NULL,
+	rating INTEGER NOT NULL CHECK(rating IN (-1, 1)),
+	comment TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_feedback_chat_id ON message_feedback(chat_id);
+CREATE INDEX IF NOT EXISTS idx_message_feedback_updated_at ON message_feedback(updated_at);
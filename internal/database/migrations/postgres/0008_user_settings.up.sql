@@ -0,0 +1,8 @@
+-- Personal preferences (language, theme, default provider...) stored as a JSON document per
+-- owner, which is "admin" for the admin role or "session:<id>" for anyone else
+
+CREATE TABLE IF NOT EXISTS user_settings (
+	owner TEXT PRIMARY KEY,
+	settings TEXT NOT NULL,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_chat_shares_chat_id;
+DROP TABLE IF EXISTS chat_shares;
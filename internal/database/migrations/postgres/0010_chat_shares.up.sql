@@ -0,0 +1,15 @@
+-- Read-only public links to chats. The link token is the share ID signed with the share secret,
+-- so links can be revoked and expire without changing the secret.
+
+CREATE TABLE IF NOT EXISTS chat_shares (
+	id TEXT PRIMARY KEY,
+	chat_id BIGINT NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	views INTEGER NOT NULL DEFAULT 0,
+	last_viewed_at TIMESTAMPTZ,
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_shares_chat_id ON chat_shares(chat_id);
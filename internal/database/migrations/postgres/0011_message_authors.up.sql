@@ -0,0 +1,4 @@
+-- Collaborative chats: prompts record the participant who sent them (empty for prompts sent
+-- before, by scheduled prompts or by clients without an identity).
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS user_id TEXT NOT NULL DEFAULT '';
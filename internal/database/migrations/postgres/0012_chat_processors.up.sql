@@ -0,0 +1,10 @@
+-- Processors a chat enabled or disabled; processors without a row run if they are enabled by default.
+
+CREATE TABLE IF NOT EXISTS chat_processors (
+	chat_id BIGINT NOT NULL,
+	processor TEXT NOT NULL,
+	enabled BOOLEAN NOT NULL,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (chat_id, processor),
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
+);
@@ -0,0 +1,52 @@
+-- Document collections for retrieval: uploaded documents are split into chunks whose embeddings
+-- are searched for the chunks closest to a prompt in a chat linked to the collection.
+
+CREATE TABLE IF NOT EXISTS document_collections (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	description TEXT NOT NULL DEFAULT '',
+	embedder TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS documents (
+	id BIGSERIAL PRIMARY KEY,
+	collection_id BIGINT NOT NULL,
+	filename TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	chunks INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (collection_id) REFERENCES document_collections(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_documents_collection_id ON documents(collection_id);
+
+CREATE TABLE IF NOT EXISTS document_chunks (
+	id BIGSERIAL PRIMARY KEY,
+	document_id BIGINT NOT NULL,
+	collection_id BIGINT NOT NULL,
+	seq INTEGER NOT NULL,
+	content TEXT NOT NULL,
+	FOREIGN KEY (document_id) REFERENCES documents(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_chunks_document_id ON document_chunks(document_id);
+
+-- Embeddings kept by the database vector store, as little-endian float32s
+CREATE TABLE IF NOT EXISTS document_vectors (
+	chunk_id BIGINT PRIMARY KEY,
+	collection_id BIGINT NOT NULL,
+	embedding BYTEA NOT NULL,
+	FOREIGN KEY (chunk_id) REFERENCES document_chunks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_vectors_collection_id ON document_vectors(collection_id);
+
+-- The collection a chat retrieves context from
+CREATE TABLE IF NOT EXISTS chat_collections (
+	chat_id BIGINT PRIMARY KEY,
+	collection_id BIGINT NOT NULL,
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE,
+	FOREIGN KEY (collection_id) REFERENCES document_collections(id) ON DELETE CASCADE
+);
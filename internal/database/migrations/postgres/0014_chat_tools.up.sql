@@ -0,0 +1,9 @@
+-- Tools a chat lets providers call; chats without rows use no tools.
+
+CREATE TABLE IF NOT EXISTS chat_tools (
+	chat_id BIGINT NOT NULL,
+	tool TEXT NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (chat_id, tool),
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_chats_parent_chat_id;
+ALTER TABLE chats DROP COLUMN IF EXISTS forked_from_message_id;
+ALTER TABLE chats DROP COLUMN IF EXISTS parent_chat_id;
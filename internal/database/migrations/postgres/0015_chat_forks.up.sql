@@ -0,0 +1,6 @@
+-- Conversation branches: a forked chat records the chat and the message it was forked from.
+
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS parent_chat_id BIGINT REFERENCES chats(id) ON DELETE SET NULL;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS forked_from_message_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_chats_parent_chat_id ON chats(parent_chat_id);
@@ -0,0 +1,5 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS finish_reason;
+ALTER TABLE messages DROP COLUMN IF EXISTS tokens_estimated;
+ALTER TABLE messages DROP COLUMN IF EXISTS tokens_out;
+ALTER TABLE messages DROP COLUMN IF EXISTS tokens_in;
+ALTER TABLE messages DROP COLUMN IF EXISTS latency_ms;
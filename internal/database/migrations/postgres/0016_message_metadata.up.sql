@@ -0,0 +1,9 @@
+-- How assistant messages were generated: time from prompt to complete response, prompt and
+-- response tokens (reported by the provider or estimated) and why the response ended. Empty
+-- finish_reason marks messages without metadata, e.g. prompts and older responses.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS latency_ms BIGINT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS tokens_in BIGINT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS tokens_out BIGINT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS tokens_estimated BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS finish_reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- Providers that failed before the one that answered, comma-separated in the order they were
+-- tried, when a prompt went through its failover chain
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS failed_over TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_scheduled_prompt_runs_scheduled_prompt_id;
+DROP INDEX IF EXISTS idx_scheduled_prompts_next_run_at;
+
+DROP TABLE IF EXISTS scheduled_prompt_runs;
+DROP TABLE IF EXISTS scheduled_prompts;
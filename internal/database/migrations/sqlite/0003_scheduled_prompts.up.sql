@@ -0,0 +1,35 @@
+-- Prompts run on a cron schedule, delivering their results into a chat
+
+CREATE TABLE IF NOT EXISTS scheduled_prompts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	chat_id INTEGER NOT NULL,
+	provider TEXT NOT NULL,
+	model TEXT NOT NULL DEFAULT '',
+	prompt TEXT NOT NULL,
+	cron TEXT NOT NULL,
+	timezone TEXT NOT NULL DEFAULT '',
+	enabled BOOLEAN NOT NULL DEFAULT 1,
+	next_run_at DATETIME,
+	last_run_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_prompt_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	scheduled_prompt_id INTEGER NOT NULL,
+	chat_id INTEGER NOT NULL,
+	status TEXT NOT NULL CHECK(status IN ('running', 'completed', 'failed')),
+	error TEXT NOT NULL DEFAULT '',
+	message_id INTEGER,
+	started_at DATETIME NOT NULL,
+	finished_at DATETIME,
+	FOREIGN KEY (scheduled_prompt_id) REFERENCES scheduled_prompts(id) ON DELETE CASCADE,
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE,
+	FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_prompts_next_run_at ON scheduled_prompts(next_run_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_prompt_runs_scheduled_prompt_id ON scheduled_prompt_runs(scheduled_prompt_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_messages_streaming;
+
+ALTER TABLE messages DROP COLUMN checkpointed_at;
+ALTER TABLE messages DROP COLUMN status;
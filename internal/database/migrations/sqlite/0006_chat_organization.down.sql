@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_chat_tags_tag_id;
+DROP TABLE IF EXISTS chat_tags;
+DROP TABLE IF EXISTS tags;
+
+DROP INDEX IF EXISTS idx_chats_folder_id;
+ALTER TABLE chats DROP COLUMN folder_id;
+DROP TABLE IF EXISTS folders;
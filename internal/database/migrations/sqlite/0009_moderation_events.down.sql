@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_moderation_events_chat_id;
+DROP INDEX IF EXISTS idx_moderation_events_created_at;
+DROP TABLE IF EXISTS moderation_events;
@@ -0,0 +1,18 @@
+-- Audit trail of prompts and responses flagged or blocked by moderation. Entries outlive their
+-- chats, so chat_id isn't a foreign key; excerpt keeps the start of the moderated content.
+
+CREATE TABLE IF NOT EXISTS moderation_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	chat_id INTEGER NOT NULL,
+	direction TEXT NOT NULL CHECK(direction IN ('prompt', 'response')),
+	provider TEXT NOT NULL DEFAULT '',
+	action TEXT NOT NULL CHECK(action IN ('flag', 'block')),
+	moderator TEXT NOT NULL DEFAULT '',
+	rule TEXT NOT NULL DEFAULT '',
+	reason TEXT NOT NULL DEFAULT '',
+	excerpt TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_events_created_at ON moderation_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_moderation_events_chat_id ON moderation_events(chat_id);
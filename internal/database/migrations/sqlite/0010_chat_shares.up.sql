@@ -0,0 +1,15 @@
+-- Read-only public links to chats. The link token is the share ID signed with the share secret,
+-- so links can be revoked and expire without changing the secret.
+
+CREATE TABLE IF NOT EXISTS chat_shares (
+	id TEXT PRIMARY KEY,
+	chat_id INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	expires_at DATETIME,
+	revoked_at DATETIME,
+	views INTEGER NOT NULL DEFAULT 0,
+	last_viewed_at DATETIME,
+	FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_shares_chat_id ON chat_shares(chat_id);
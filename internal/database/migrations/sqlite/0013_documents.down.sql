@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS chat_collections;
+DROP INDEX IF EXISTS idx_document_vectors_collection_id;
+DROP TABLE IF EXISTS document_vectors;
+DROP INDEX IF EXISTS idx_document_chunks_document_id;
+DROP TABLE IF EXISTS document_chunks;
+DROP INDEX IF EXISTS idx_documents_collection_id;
+DROP TABLE IF EXISTS documents;
+DROP TABLE IF EXISTS document_collections;
@@ -0,0 +1,6 @@
+-- Conversation branches: a forked chat records the chat and the message it was forked from.
+
+ALTER TABLE chats ADD COLUMN parent_chat_id INTEGER REFERENCES chats(id) ON DELETE SET NULL;
+ALTER TABLE chats ADD COLUMN forked_from_message_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_chats_parent_chat_id ON chats(parent_chat_id);
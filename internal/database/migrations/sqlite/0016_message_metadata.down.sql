@@ -0,0 +1,5 @@
+ALTER TABLE messages DROP COLUMN finish_reason;
+ALTER TABLE messages DROP COLUMN tokens_estimated;
+ALTER TABLE messages DROP COLUMN tokens_out;
+ALTER TABLE messages DROP COLUMN tokens_in;
+ALTER TABLE messages DROP COLUMN latency_ms;
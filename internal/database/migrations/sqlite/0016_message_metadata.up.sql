@@ -0,0 +1,9 @@
+-- How assistant messages were generated: time from prompt to complete response, prompt and
+-- response tokens (reported by the provider or estimated) and why the response ended. Empty
+-- finish_reason marks messages without metadata, e.g. prompts and older responses.
+
+ALTER TABLE messages ADD COLUMN latency_ms INTEGER;
+ALTER TABLE messages ADD COLUMN tokens_in INTEGER;
+ALTER TABLE messages ADD COLUMN tokens_out INTEGER;
+ALTER TABLE messages ADD COLUMN tokens_estimated BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE messages ADD COLUMN finish_reason TEXT NOT NULL DEFAULT '';
@@ -2,12 +2,14 @@ package database
 
 import (
 	"context"
-	"log"
+	"fmt"
 
 	"github.com/go-redis/redis/v8"
 )
 
-func InitRedis(addr string) *redis.Client {
+// InitRedis connects to Redis. The client is returned even when Redis can't be reached, along
+// with the error, so callers can decide whether to continue without it.
+func InitRedis(addr string) (*redis.Client, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     addr,
 		Password: "", // no password set
@@ -17,9 +19,8 @@ func InitRedis(addr string) *redis.Client {
 	// Test connection
 	ctx := context.Background()
 	if err := client.Ping(ctx).Err(); err != nil {
-		log.Printf("Warning: Failed to connect to Redis at %s: %v", addr, err)
-		log.Printf("Some features may be limited without Redis")
+		return client, fmt.Errorf("failed to connect to Redis at %s: %w", addr, err)
 	}
 
-	return client
-}
\ No newline at end of file
+	return client, nil
+}
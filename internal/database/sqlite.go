@@ -3,71 +3,162 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"ai-gateway-hub/internal/utils"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func InitSQLite(dbPath string) (*sql.DB, error) {
+// SQLiteOptions tunes SQLite connections. The pragmas are passed as connection string parameters
+// so every pooled connection applies them; parameters already in the path take precedence.
+type SQLiteOptions struct {
+	JournalMode  string        // e.g. WAL or DELETE; empty keeps the database's mode
+	BusyTimeout  time.Duration // how long a statement waits for a lock before failing with "database is locked"
+	Synchronous  string        // e.g. NORMAL or FULL; empty keeps the default (FULL)
+	ForeignKeys  bool          // enforce foreign key constraints
+	MaxOpenConns int           // 0 is unlimited
+	MaxIdleConns int
+}
+
+// DefaultSQLiteOptions lets readers work alongside a writer (WAL) and makes writers wait for each other
+func DefaultSQLiteOptions() SQLiteOptions {
+	return SQLiteOptions{
+		JournalMode:  "WAL",
+		BusyTimeout:  5 * time.Second,
+		Synchronous:  "NORMAL",
+		ForeignKeys:  true,
+		MaxOpenConns: 16,
+		MaxIdleConns: 4,
+	}
+}
+
+// InitSQLite opens the database with the default options and applies pending schema migrations
+func InitSQLite(dbPath string) (*DB, error) {
+	return Init(SQLite, dbPath, DefaultSQLiteOptions())
+}
+
+// OpenSQLite opens the database with the default options without changing its schema
+func OpenSQLite(dbPath string) (*DB, error) {
+	return OpenSQLiteWithOptions(dbPath, DefaultSQLiteOptions())
+}
+
+// OpenSQLiteWithOptions opens the database without changing its schema
+func OpenSQLiteWithOptions(dbPath string, opts SQLiteOptions) (*DB, error) {
 	// Ensure directory exists
-	if err := utils.EnsureDirForFile(dbPath); err != nil {
+	file, _, _ := strings.Cut(strings.TrimPrefix(dbPath, "file:"), "?")
+	if err := utils.EnsureDirForFile(file); err != nil {
 		return nil, err
 	}
 
 	// Open database connection
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open("sqlite3", sqliteDSN(dbPath, opts))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
 
 	// Test connection
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Create tables
-	if err := createTables(db); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
+	sqliteDB := NewDB(db, SQLite)
+	if pragmas, err := SQLitePragmas(sqliteDB); err == nil {
+		utils.Info("SQLite: journal_mode=%s busy_timeout=%sms synchronous=%s foreign_keys=%s max_open_conns=%d max_idle_conns=%d",
+			pragmas["journal_mode"], pragmas["busy_timeout"], pragmas["synchronous"], pragmas["foreign_keys"], opts.MaxOpenConns, opts.MaxIdleConns)
+	} else {
+		utils.Warn("Failed to read SQLite pragmas: %v", err)
+	}
+
+	return sqliteDB, nil
+}
+
+// sqliteDSN adds the pragmas of the options to a database path as go-sqlite3 connection parameters
+func sqliteDSN(dbPath string, opts SQLiteOptions) string {
+	path, rawQuery, _ := strings.Cut(dbPath, "?")
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Leave connection strings we can't parse to the driver
+		return dbPath
+	}
+
+	// Each pragma can be spelled two ways; an explicit parameter wins over the options
+	set := func(name, alias, value string) {
+		if value != "" && !params.Has(name) && !params.Has(alias) {
+			params.Set(name, value)
+		}
+	}
+	set("_journal_mode", "_journal", opts.JournalMode)
+	if opts.BusyTimeout > 0 {
+		set("_busy_timeout", "_timeout", fmt.Sprint(opts.BusyTimeout.Milliseconds()))
+	}
+	set("_synchronous", "_sync", opts.Synchronous)
+	if opts.ForeignKeys {
+		set("_foreign_keys", "_fk", "1")
 	}
 
-	return db, nil
+	if len(params) == 0 {
+		return path
+	}
+	return path + "?" + params.Encode()
 }
 
-func createTables(db *sql.DB) error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS chats (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT NOT NULL,
-		provider TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS messages (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		chat_id INTEGER NOT NULL,
-		role TEXT NOT NULL CHECK(role IN ('user', 'assistant', 'system')),
-		content TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS sessions (
-		id TEXT PRIMARY KEY,
-		chat_id INTEGER,
-		data TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		expires_at DATETIME,
-		FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE SET NULL
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_messages_chat_id ON messages(chat_id);
-	CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
-	`
-
-	if _, err := db.Exec(schema); err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
+// SQLitePragmas reads the effective journal mode, busy timeout (ms), synchronous level and
+// foreign key enforcement of a connection
+func SQLitePragmas(db *DB) (map[string]string, error) {
+	synchronousLevels := map[string]string{"0": "OFF", "1": "NORMAL", "2": "FULL", "3": "EXTRA"}
+
+	pragmas := make(map[string]string)
+	for _, name := range []string{"journal_mode", "busy_timeout", "synchronous", "foreign_keys"} {
+		var value string
+		if err := db.QueryRow("PRAGMA " + name).Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to read pragma %s: %w", name, err)
+		}
+		pragmas[name] = value
+	}
+	if level, ok := synchronousLevels[pragmas["synchronous"]]; ok {
+		pragmas["synchronous"] = level
+	}
+	return pragmas, nil
+}
+
+// addColumnIfMissing adds a column to an existing table when it is not present yet
+func addColumnIfMissing(db *DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return fmt.Errorf("failed to scan table info for %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read table info for %s: %w", table, err)
+	}
+	rows.Close()
+
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
 	}
 
 	return nil
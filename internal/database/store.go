@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// Dialect identifies the SQL database a store talks to; its value is the database/sql driver name
+type Dialect string
+
+// Supported database dialects
+const (
+	SQLite   Dialect = "sqlite3"
+	Postgres Dialect = "postgres"
+)
+
+// ParseDialect validates a DB_DRIVER value ("sqlite" is accepted as an alias of "sqlite3")
+func ParseDialect(driver string) (Dialect, error) {
+	switch strings.ToLower(strings.TrimSpace(driver)) {
+	case "", "sqlite", "sqlite3":
+		return SQLite, nil
+	case "postgres", "postgresql", "pgx":
+		return Postgres, nil
+	}
+	return "", fmt.Errorf("unsupported database driver %q (expected sqlite3 or postgres)", driver)
+}
+
+// Rebind rewrites ? placeholders into the dialect's placeholder syntax
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+// UnixSeconds returns an SQL expression converting a timestamp column to Unix seconds
+func (d Dialect) UnixSeconds(column string) string {
+	if d == Postgres {
+		return "CAST(EXTRACT(EPOCH FROM " + column + ") AS BIGINT)"
+	}
+	return "CAST(strftime('%s', " + column + ") AS INTEGER)"
+}
+
+// Store is the database handle services use; queries are written with ? placeholders
+// and rebound for the underlying dialect. The Context variants stop the query when the
+// context is cancelled, e.g. when the client of a request goes away.
+type Store interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	Begin() (*Tx, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error)
+	Dialect() Dialect
+}
+
+// DB is a *sql.DB that rebinds placeholders for its dialect
+type DB struct {
+	*sql.DB
+	dialect Dialect
+}
+
+// NewDB wraps an open connection pool of the given dialect
+func NewDB(db *sql.DB, dialect Dialect) *DB {
+	return &DB{DB: db, dialect: dialect}
+}
+
+// Dialect returns the database dialect
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
+
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.DB.Exec(db.dialect.Rebind(query), args...)
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.dialect.Rebind(query), args...)
+}
+
+func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.DB.Query(db.dialect.Rebind(query), args...)
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, db.dialect.Rebind(query), args...)
+}
+
+func (db *DB) QueryRow(query string, args ...any) *sql.Row {
+	return db.DB.QueryRow(db.dialect.Rebind(query), args...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.dialect.Rebind(query), args...)
+}
+
+// Begin starts a transaction that rebinds placeholders like the DB
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, dialect: db.dialect}, nil
+}
+
+// Tx is a *sql.Tx that rebinds placeholders for its dialect
+type Tx struct {
+	*sql.Tx
+	dialect Dialect
+}
+
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.Tx.Exec(tx.dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRow(tx.dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.dialect.Rebind(query), args...)
+}
+
+// Open connects to the database of the given dialect without changing its schema;
+// sqlite tunes SQLite connections and is ignored for other dialects
+func Open(dialect Dialect, dsn string, sqlite SQLiteOptions) (*DB, error) {
+	if dialect == SQLite {
+		return OpenSQLiteWithOptions(dsn, sqlite)
+	}
+	if dsn == "" {
+		return nil, fmt.Errorf("DB_DSN is required for the %s driver", dialect)
+	}
+
+	sqlDB, err := sql.Open(string(dialect), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return NewDB(sqlDB, dialect), nil
+}
+
+// Init connects to the database and applies pending schema migrations
+func Init(dialect Dialect, dsn string, sqlite SQLiteOptions) (*DB, error) {
+	db, err := Open(dialect, dsn, sqlite)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := Migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return db, nil
+}
@@ -6,12 +6,13 @@ import (
 )
 
 // InitTestDB creates an in-memory SQLite database for testing
-func InitTestDB() (*sql.DB, error) {
+func InitTestDB() (*DB, error) {
 	// Use in-memory database for tests with foreign key support
-	db, err := sql.Open("sqlite3", ":memory:?_foreign_keys=on")
+	sqlDB, err := sql.Open("sqlite3", ":memory:?_foreign_keys=on")
 	if err != nil {
 		return nil, err
 	}
+	db := NewDB(sqlDB, SQLite)
 
 	// Enable foreign key constraints
 	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
@@ -20,7 +21,7 @@ func InitTestDB() (*sql.DB, error) {
 	}
 
 	// Create tables
-	if err := createTables(db); err != nil {
+	if _, err := Migrate(db); err != nil {
 		db.Close()
 		return nil, err
 	}
@@ -29,7 +30,7 @@ func InitTestDB() (*sql.DB, error) {
 }
 
 // InitTestDBWithFile creates a file-based SQLite database for testing
-func InitTestDBWithFile(dbPath string) (*sql.DB, error) {
+func InitTestDBWithFile(dbPath string) (*DB, error) {
 	// Remove existing test database if it exists
 	os.Remove(dbPath)
 
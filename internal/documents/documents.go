@@ -0,0 +1,105 @@
+// Package documents prepares uploaded files for retrieval: it extracts their text, splits it into
+// overlapping chunks and embeds the chunks as vectors, so the chunks closest to a prompt can be
+// added to it as context.
+package documents
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedType is returned for files that aren't text, markdown or PDF
+var ErrUnsupportedType = errors.New("unsupported document type, expected text, markdown or PDF")
+
+// Content types of the documents that can be uploaded
+const (
+	TypeText     = "text/plain"
+	TypeMarkdown = "text/markdown"
+	TypePDF      = "application/pdf"
+)
+
+// DetectType returns the content type of a document from its extension, or from its content for
+// files without a known one
+func DetectType(filename string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt", ".text":
+		return TypeText, nil
+	case ".md", ".markdown":
+		return TypeMarkdown, nil
+	case ".pdf":
+		return TypePDF, nil
+	}
+	switch {
+	case strings.HasPrefix(string(data), "%PDF-"):
+		return TypePDF, nil
+	case utf8.Valid(data):
+		return TypeText, nil
+	}
+	return "", ErrUnsupportedType
+}
+
+// Extract returns the text of a document
+func Extract(contentType string, data []byte) (string, error) {
+	switch contentType {
+	case TypeText, TypeMarkdown:
+		if !utf8.Valid(data) {
+			return "", errors.New("document is not valid UTF-8 text")
+		}
+		return string(data), nil
+	case TypePDF:
+		return extractPDF(data)
+	}
+	return "", ErrUnsupportedType
+}
+
+// Chunk splits text into chunks of at most size runes, preferring to break between paragraphs,
+// then lines, then words. Consecutive chunks share up to overlap runes so passages cut at a
+// boundary can still be retrieved whole.
+func Chunk(text string, size, overlap int) []string {
+	if size <= 0 {
+		return nil
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	runes := []rune(strings.TrimSpace(text))
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			end = breakPoint(runes, start+size/2, end)
+		}
+
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+// breakPoint returns where to end a chunk between min and max: after the last paragraph break,
+// else the last line break, else the last space, else at max
+func breakPoint(runes []rune, min, max int) int {
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		s := []rune(sep)
+		for i := max - len(s); i >= min; i-- {
+			if string(runes[i:i+len(s)]) == sep {
+				return i + len(s)
+			}
+		}
+	}
+	return max
+}
@@ -0,0 +1,129 @@
+package documents
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// minimalPDF builds a PDF whose page content stream shows the given operators
+func minimalPDF(content string, compress bool) []byte {
+	stream := []byte(content)
+	filter := ""
+	if compress {
+		var b bytes.Buffer
+		w := zlib.NewWriter(&b)
+		w.Write(stream)
+		w.Close()
+		stream = b.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+	return []byte(fmt.Sprintf("%%PDF-1.4\n1 0 obj\n<< /Length %d%s >>\nstream\n%s\nendstream\nendobj\n%%%%EOF\n", len(stream), filter, stream))
+}
+
+func TestDetectTypeAndExtract(t *testing.T) {
+	contentType, err := DetectType("notes.md", []byte("# Notes"))
+	require.NoError(t, err)
+	assert.Equal(t, TypeMarkdown, contentType)
+
+	contentType, err = DetectType("upload", []byte("%PDF-1.7"))
+	require.NoError(t, err)
+	assert.Equal(t, TypePDF, contentType)
+
+	_, err = DetectType("image.bin", []byte{0xff, 0xd8, 0xff, 0xe0})
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+
+	content := "BT /F1 12 Tf (Hello \\(PDF\\) world) Tj T* [(Kern)-20(ed)-250(text)] TJ ET"
+	for _, compress := range []bool{false, true} {
+		text, err := Extract(TypePDF, minimalPDF(content, compress))
+		require.NoError(t, err)
+		assert.Equal(t, "Hello (PDF) world\nKerned text", text)
+	}
+
+	_, err = Extract(TypePDF, minimalPDF("0 0 m 10 10 l S", false))
+	assert.ErrorContains(t, err, "no text")
+	_, err = Extract(TypeText, []byte{0xff, 0xfe})
+	assert.Error(t, err)
+}
+
+func TestChunk(t *testing.T) {
+	assert.Equal(t, []string{"short text"}, Chunk("  short text \n", 100, 10))
+	assert.Empty(t, Chunk("   ", 100, 10))
+
+	text := strings.Repeat("alpha beta gamma delta. ", 20) + "\n\n" + strings.Repeat("epsilon zeta eta theta. ", 20)
+	chunks := Chunk(text, 200, 40)
+	require.Greater(t, len(chunks), 2)
+	for i, chunk := range chunks {
+		assert.LessOrEqual(t, len([]rune(chunk)), 200)
+		// Chunks break between words and overlap with the previous one
+		assert.False(t, strings.HasSuffix(chunk, "alph"), chunk)
+		if i > 0 {
+			prev := chunks[i-1]
+			assert.Contains(t, prev, chunk[:10])
+		}
+	}
+	assert.Contains(t, chunks[len(chunks)-1], "theta.")
+}
+
+func TestHashEmbedder(t *testing.T) {
+	embedder := NewHashEmbedder(256)
+	assert.Equal(t, "hash:256", embedder.Name())
+
+	vectors, err := embedder.Embed(context.Background(), []string{
+		"How do goroutines communicate over channels?",
+		"Goroutines send values to each other through channels",
+		"Preheat the oven and bake the bread for forty minutes",
+	})
+	require.NoError(t, err)
+	require.Len(t, vectors, 3)
+	assert.Len(t, vectors[0], 256)
+	assert.InDelta(t, 1, Dot(vectors[0], vectors[0]), 1e-5)
+	assert.Greater(t, Dot(vectors[0], vectors[1]), Dot(vectors[0], vectors[2]))
+
+	decoded, err := DecodeVector(EncodeVector(vectors[1]))
+	require.NoError(t, err)
+	assert.Equal(t, vectors[1], decoded)
+	_, err = DecodeVector([]byte{1, 2, 3})
+	assert.Error(t, err)
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer embed-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "text-embedding-3-small", req.Model)
+		// Answer out of order, as the index field allows
+		data := []map[string]any{}
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]any{"index": i, "embedding": []float32{float32(len(req.Input[i])), 0}})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer server.Close()
+
+	embedder := NewHTTPEmbedder(server.URL, "embed-key", "text-embedding-3-small", time.Second)
+	assert.Equal(t, "http:text-embedding-3-small", embedder.Name())
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "bb"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {1, 0}}, vectors)
+
+	_, err = NewHTTPEmbedder(server.URL, "wrong", "text-embedding-3-small", time.Second).Embed(context.Background(), []string{"a"})
+	assert.ErrorContains(t, err, "401")
+}
@@ -0,0 +1,155 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Embedder turns texts into vectors whose cosine similarity reflects how related the texts are.
+// Vectors of different embedders, or of one with different settings, can't be compared, so
+// collections record the Name of the embedder that indexed them.
+type Embedder interface {
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// HashEmbedder embeds texts locally by hashing their words and word pairs into a fixed number of
+// dimensions. It needs no external service and matches shared vocabulary well, but knows nothing
+// of meaning: use an HTTPEmbedder for semantic search.
+type HashEmbedder struct {
+	dimensions int
+}
+
+// NewHashEmbedder creates a hashing embedder with vectors of the given number of dimensions
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	return &HashEmbedder{dimensions: dimensions}
+}
+
+func (e *HashEmbedder) Name() string {
+	return fmt.Sprintf("hash:%d", e.dimensions)
+}
+
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, e.dimensions)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		for j, word := range words {
+			e.add(vector, word, 1)
+			if j > 0 {
+				e.add(vector, words[j-1]+" "+word, 0.5)
+			}
+		}
+		vectors[i] = Normalize(vector)
+	}
+	return vectors, nil
+}
+
+// add hashes a feature into the vector; the sign bit spreads collisions around zero
+func (e *HashEmbedder) add(vector []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum&1 == 1 {
+		weight = -weight
+	}
+	vector[(sum>>1)%uint64(len(vector))] += weight
+}
+
+// HTTPEmbedder asks an OpenAI compatible embeddings API for vectors: it POSTs
+// {"model": "...", "input": ["..."]} and reads {"data": [{"index": 0, "embedding": [...]}]}.
+type HTTPEmbedder struct {
+	url    string
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+// NewHTTPEmbedder creates an embedder posting to url, authenticated with apiKey as a bearer token if set
+func NewHTTPEmbedder(url, apiKey, model string, timeout time.Duration) *HTTPEmbedder {
+	return &HTTPEmbedder{
+		url:    url,
+		apiKey: apiKey,
+		model:  model,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (e *HTTPEmbedder) Name() string {
+	return "http:" + e.model
+}
+
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embedding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("embedding service returned %s", resp.Status)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding service returned %d embeddings for %d texts", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) || vectors[d.Index] != nil {
+			return nil, fmt.Errorf("embedding service returned an invalid index %d", d.Index)
+		}
+		vectors[d.Index] = Normalize(d.Embedding)
+	}
+	return vectors, nil
+}
+
+// Normalize scales a vector to unit length, so cosine similarity is a dot product
+func Normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
@@ -0,0 +1,112 @@
+package documents
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Largest decompressed stream read from a PDF
+const maxPDFStreamBytes = 32 << 20
+
+var (
+	pdfStream = regexp.MustCompile(`(?s)stream\r?\n(.*?)\r?\n?endstream`)
+	// Text showing operators: (string) Tj, (string) ' and [(string) -120 (string)] TJ
+	pdfText = regexp.MustCompile(`(?s)\((?:\\.|[^\\)])*\)\s*(?:Tj|')|\[(?:\\.|[^\]])*\]\s*TJ|T\*|ET`)
+	// A string inside a TJ array, and kerning large enough to be a space
+	pdfString = regexp.MustCompile(`\((?:\\.|[^\\)])*\)|-\d{3,}`)
+)
+
+// extractPDF returns the text shown by the content streams of a PDF. It reads uncompressed and
+// Flate compressed streams with literal strings in standard encodings, which covers most PDFs
+// generated from text; scanned, encrypted and CID font PDFs yield no text.
+func extractPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return "", errors.New("not a PDF document")
+	}
+
+	var text strings.Builder
+	for _, match := range pdfStream.FindAllSubmatch(data, -1) {
+		content := match[1]
+		if inflated, err := inflate(content); err == nil {
+			content = inflated
+		}
+		if !bytes.Contains(content, []byte("BT")) {
+			continue
+		}
+		for _, op := range pdfText.FindAll(content, -1) {
+			switch {
+			case bytes.Equal(op, []byte("T*")):
+				text.WriteString("\n")
+			case bytes.Equal(op, []byte("ET")):
+				text.WriteString("\n")
+			case op[0] == '[':
+				for _, part := range pdfString.FindAll(op, -1) {
+					if part[0] == '-' {
+						text.WriteString(" ")
+						continue
+					}
+					text.WriteString(unescapePDF(part[1 : len(part)-1]))
+				}
+			default:
+				end := bytes.LastIndexByte(op, ')')
+				text.WriteString(unescapePDF(op[1:end]))
+			}
+		}
+	}
+
+	extracted := strings.TrimSpace(text.String())
+	if extracted == "" {
+		return "", errors.New("no text found in PDF")
+	}
+	return extracted, nil
+}
+
+// inflate decompresses a Flate encoded stream
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, maxPDFStreamBytes))
+}
+
+// unescapePDF decodes the escape sequences of a PDF literal string
+func unescapePDF(s []byte) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			out.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case 'b', 'f':
+		case '\r', '\n':
+			// Line continuation
+		default:
+			if c >= '0' && c <= '7' {
+				n := 0
+				j := i
+				for ; j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7'; j++ {
+					n = n*8 + int(s[j]-'0')
+				}
+				out.WriteRune(rune(n))
+				i = j - 1
+				continue
+			}
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
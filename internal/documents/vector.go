@@ -0,0 +1,63 @@
+package documents
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Vector is the embedding of a chunk of a collection
+type Vector struct {
+	ChunkID      int64
+	CollectionID int64
+	Embedding    []float32
+}
+
+// Match is a chunk found close to a query
+type Match struct {
+	ChunkID int64
+	Score   float32 // cosine similarity, 1 for identical directions
+}
+
+// VectorStore keeps chunk embeddings and finds the ones closest to a query. Implementations can
+// search in the database or in an external vector database.
+type VectorStore interface {
+	Add(ctx context.Context, vectors []Vector) error
+	Search(ctx context.Context, collectionID int64, query []float32, k int) ([]Match, error)
+	DeleteChunks(ctx context.Context, chunkIDs []int64) error
+	DeleteCollection(ctx context.Context, collectionID int64) error
+}
+
+// Dot returns the dot product of two vectors, their cosine similarity when both are normalized
+func Dot(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// EncodeVector serializes a vector as little-endian float32s
+func EncodeVector(vector []float32) []byte {
+	b := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	return b
+}
+
+// DecodeVector reads a vector serialized by EncodeVector
+func DecodeVector(b []byte) ([]float32, error) {
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("invalid vector of %d bytes", len(b))
+	}
+	vector := make([]float32, len(b)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return vector, nil
+}
@@ -0,0 +1,96 @@
+// Package encryption encrypts message content at rest with AES-256-GCM. Encrypted values are
+// marked with a prefix, so plaintext written before encryption was enabled stays readable.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the length of keys in bytes (AES-256)
+const KeySize = 32
+
+// Prefix marks encrypted values; the rest is the nonce and ciphertext in standard base64
+const Prefix = "enc:v1:"
+
+// Cipher encrypts and decrypts strings with one key
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher for a KeySize byte key
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// ParseKey decodes a key given in base64 or hex
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(s); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be %d bytes in base64 or hex", KeySize)
+}
+
+// GenerateKey returns a random key in base64
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// IsEncrypted reports whether s is a value returned by Encrypt
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, Prefix)
+}
+
+// Encrypt encrypts s with a random nonce
+func (c *Cipher) Encrypt(s string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(s), nil)
+	return Prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value returned by Encrypt; values without the prefix are returned as they are
+func (c *Cipher) Decrypt(s string) (string, error) {
+	if !IsEncrypted(s) {
+		return s, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(s[len(Prefix):])
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted value: %w", err)
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("encrypted value is too short")
+	}
+	plain, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value, the key may be wrong: %w", err)
+	}
+	return string(plain), nil
+}
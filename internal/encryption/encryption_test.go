@@ -0,0 +1,88 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher_RoundTrip(t *testing.T) {
+	encoded, err := GenerateKey()
+	require.NoError(t, err)
+	key, err := ParseKey(encoded)
+	require.NoError(t, err)
+	cipher, err := NewCipher(key)
+	require.NoError(t, err)
+
+	sealed, err := cipher.Encrypt("こんにちは, secret prompt")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(sealed))
+	assert.NotContains(t, sealed, "secret prompt")
+
+	again, err := cipher.Encrypt("こんにちは, secret prompt")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every encryption uses a new nonce")
+
+	plain, err := cipher.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "こんにちは, secret prompt", plain)
+
+	plain, err = cipher.Decrypt("written before encryption")
+	require.NoError(t, err)
+	assert.Equal(t, "written before encryption", plain)
+
+	empty, err := cipher.Encrypt("")
+	require.NoError(t, err)
+	plain, err = cipher.Decrypt(empty)
+	require.NoError(t, err)
+	assert.Empty(t, plain)
+}
+
+func TestCipher_RejectsTamperedValuesAndWrongKeys(t *testing.T) {
+	cipher, err := NewCipher(make([]byte, KeySize))
+	require.NoError(t, err)
+	sealed, err := cipher.Encrypt("hello")
+	require.NoError(t, err)
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, Prefix))
+	require.NoError(t, err)
+	raw[len(raw)-1] ^= 1
+	_, err = cipher.Decrypt(Prefix + base64.StdEncoding.EncodeToString(raw))
+	assert.Error(t, err)
+
+	_, err = cipher.Decrypt(Prefix + "not base64!")
+	assert.Error(t, err)
+	_, err = cipher.Decrypt(Prefix + base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+
+	other := make([]byte, KeySize)
+	other[0] = 1
+	otherCipher, err := NewCipher(other)
+	require.NoError(t, err)
+	_, err = otherCipher.Decrypt(sealed)
+	assert.Error(t, err)
+}
+
+func TestParseKey(t *testing.T) {
+	key := make([]byte, KeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	parsed, err := ParseKey(base64.StdEncoding.EncodeToString(key))
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	parsed, err = ParseKey(" " + hex.EncodeToString(key) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = ParseKey(base64.StdEncoding.EncodeToString(key[:16]))
+	assert.Error(t, err)
+	_, err = NewCipher(key[:16])
+	assert.Error(t, err)
+}
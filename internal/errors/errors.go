@@ -0,0 +1,44 @@
+// Package errors defines the kinds of errors services report so handlers can map them to HTTP status codes
+// without knowing each service's errors.
+package errors
+
+import "errors"
+
+// Kinds of service errors; match them with errors.Is
+var (
+	// ErrNotFound is the kind of errors for records that don't exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is the kind of errors for requests that clash with the current state
+	ErrConflict = errors.New("conflict")
+	// ErrValidation is the kind of errors for invalid input
+	ErrValidation = errors.New("validation failed")
+)
+
+// Error is a service error of one of the kinds above; its message is meant for clients
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// NotFound returns an error of kind ErrNotFound
+func NotFound(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+// Conflict returns an error of kind ErrConflict
+func Conflict(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+// Validation returns an error of kind ErrValidation
+func Validation(message string) error {
+	return &Error{Kind: ErrValidation, Message: message}
+}
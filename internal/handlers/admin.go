@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminDashboardHandler renders the admin dashboard page
+func AdminDashboardHandler(statsService *services.AdminStatsService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := GetLang(c)
+		t := GetTranslator(c)
+
+		stats, err := statsService.GetStats(c.Request.Context())
+		if err != nil {
+			utils.Error("AdminDashboardHandler: failed to collect stats: %v", err)
+			c.HTML(http.StatusInternalServerError, "pages/error.html", gin.H{
+				"error": t("admin.statsError"),
+				"lang":  lang,
+			})
+			return
+		}
+
+		c.HTML(http.StatusOK, "pages/admin.html", gin.H{
+			"lang":      lang,
+			"theme":     GetTheme(c),
+			"stats":     stats,
+			"csrfToken": GetCSRFToken(c),
+		})
+	}
+}
+
+// GetAdminStatsHandler returns the admin dashboard statistics
+func (h *APIHandlers) GetAdminStatsHandler(statsService *services.AdminStatsService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats, err := statsService.GetStats(c.Request.Context())
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to collect admin stats", err)
+			return
+		}
+		h.errorHandler.Success(c, stats)
+	}
+}
+
+// AdminLoginPageHandler renders the admin sign-in form
+func AdminLoginPageHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.HTML(http.StatusOK, "pages/admin_login.html", gin.H{
+			"lang":        GetLang(c),
+			"theme":       GetTheme(c),
+			"tokenNeeded": cfg.AdminToken != "",
+			"loopback":    cfg.AdminLoopback,
+			"csrfToken":   GetCSRFToken(c),
+		})
+	}
+}
+
+// AdminLoginHandler grants the admin role to the session when the submitted token matches ADMIN_TOKEN
+func AdminLoginHandler(cfg *config.Config, sessionService *services.SessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := GetLang(c)
+		t := GetTranslator(c)
+
+		renderError := func(status int, key string) {
+			c.HTML(status, "pages/admin_login.html", gin.H{
+				"lang":        lang,
+				"theme":       GetTheme(c),
+				"tokenNeeded": cfg.AdminToken != "",
+				"loopback":    cfg.AdminLoopback,
+				"error":       t(key),
+				"csrfToken":   GetCSRFToken(c),
+			})
+		}
+
+		if !cfg.ValidAdminToken(c.PostForm("token")) {
+			utils.Warn("Failed admin sign-in from %s", c.ClientIP())
+			renderError(http.StatusUnauthorized, "admin.login.invalidToken")
+			return
+		}
+
+		sessionID, err := c.Cookie("session_id")
+		if err != nil || sessionID == "" {
+			renderError(http.StatusBadRequest, "admin.login.noSession")
+			return
+		}
+		if err := sessionService.SetRole(c.Request.Context(), sessionID, models.RoleAdmin); err != nil {
+			utils.Error("Failed to grant admin role: %v", err)
+			renderError(http.StatusInternalServerError, "admin.login.noSession")
+			return
+		}
+
+		utils.Info("Admin sign-in from %s", c.ClientIP())
+		c.Redirect(http.StatusSeeOther, "/admin")
+	}
+}
+
+// AdminLogoutHandler revokes the admin role from the session
+func AdminLogoutHandler(sessionService *services.SessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sessionID, err := c.Cookie("session_id"); err == nil && sessionID != "" {
+			if err := sessionService.SetRole(c.Request.Context(), sessionID, ""); err != nil {
+				utils.Debug("Failed to revoke admin role: %v", err)
+			}
+		}
+		c.Redirect(http.StatusSeeOther, "/")
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminDashboardHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	require.NoError(t, i18n.Init("../../locales", "en"))
+	utils.InitLogger("info")
+
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Dashboard test", "claude")
+	require.NoError(t, err)
+	_, err = chatService.AddMessage(context.Background(), chat.ID, "user", "hello")
+	require.NoError(t, err)
+	archived, err := chatService.CreateChat(context.Background(), "Old", "claude")
+	require.NoError(t, err)
+	require.NoError(t, chatService.ArchiveChat(context.Background(), archived.ID))
+
+	utils.Error("provider crashed while streaming")
+
+	statsService := services.NewAdminStatsService(db, nil, services.StoreBackendRedis, nil, services.NewProviderRegistry(nil))
+	stats, err := statsService.GetStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Chats.Total)
+	assert.Equal(t, int64(1), stats.Chats.Archived)
+	assert.Equal(t, int64(1), stats.Messages)
+	assert.True(t, stats.Database.Healthy)
+	assert.False(t, stats.Redis.Healthy)
+	require.NotEmpty(t, stats.RecentErrors)
+	assert.Equal(t, "provider crashed while streaming", stats.RecentErrors[0].Message)
+
+	// Render the real page templates
+	tmpl := template.Must(template.New("").Funcs(i18n.TemplateFuncs()).ParseGlob("../../web/templates/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/pages/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/components/*.html"))
+
+	router := gin.New()
+	router.SetHTMLTemplate(tmpl)
+	router.GET("/admin", AdminDashboardHandler(statsService))
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	body := resp.Body.String()
+	assert.Contains(t, body, "Admin Dashboard")
+	assert.Contains(t, body, "1 active, 1 archived, 0 deleted")
+	assert.Contains(t, body, "provider crashed while streaming")
+	assert.Contains(t, body, "not configured")
+}
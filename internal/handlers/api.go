@@ -1,14 +1,22 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"ai-gateway-hub/internal/buildinfo"
 	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/middleware"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
 	"ai-gateway-hub/internal/services"
 	"ai-gateway-hub/internal/utils"
 
@@ -33,8 +41,9 @@ func NewAPIHandlers(logger *log.Logger) *APIHandlers {
 	}
 }
 
-// HealthCheckHandler returns the health status
-func HealthCheckHandler(redisClient *redis.Client, version string) gin.HandlerFunc {
+// HealthCheckHandler returns the health status; it is degraded while sessions are kept in memory
+// because Redis was unavailable at startup
+func HealthCheckHandler(redisClient *redis.Client, storeBackend string, build buildinfo.BuildInfo) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check Redis connection
 		redisStatus := "healthy"
@@ -42,89 +51,721 @@ func HealthCheckHandler(redisClient *redis.Client, version string) gin.HandlerFu
 			redisStatus = "unhealthy"
 		}
 
+		status := "healthy"
+		if storeBackend == services.StoreBackendMemory {
+			status = "degraded"
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"status":  "healthy",
-			"version": version,
-			"redis":   redisStatus,
+			"status":        status,
+			"version":       build.Version,
+			"build":         build,
+			"redis":         redisStatus,
+			"session_store": storeBackend,
 		})
 	}
 }
 
-// GetChatsHandler returns list of chats
-func (h *APIHandlers) GetChatsHandler(chatService *services.ChatService) gin.HandlerFunc {
+// LivenessHandler answers as long as the server can handle requests, for liveness probes
+func LivenessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
+
+// ReadinessHandler checks the database, Redis and the provider registry for readiness probes,
+// answering 503 with the failing components when the instance can't serve traffic
+func ReadinessHandler(readinessService *services.ReadinessService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		readiness := readinessService.Check(c.Request.Context())
+		status := http.StatusOK
+		if !readiness.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, readiness)
+	}
+}
+
+// VersionHandler returns the build information of the running binary
+func VersionHandler(build buildinfo.BuildInfo) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, build)
+	}
+}
+
+// GetChatsHandler returns list of chats
+func (h *APIHandlers) GetChatsHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset := pagination(c, 50, 100)
+
+		filter := services.ChatFilter{
+			Archived: c.Query("archived") == "true",
+			Tag:      c.Query("tag"),
+		}
+		// folder is a folder ID, or "none" for chats that are not in any folder
+		if f := c.Query("folder"); f != "" {
+			var folderID int64
+			if f != "none" {
+				parsed, err := strconv.ParseInt(f, 10, 64)
+				if err != nil || parsed <= 0 {
+					h.errorHandler.BadRequest(c, "Invalid folder ID", err)
+					return
+				}
+				folderID = parsed
+			}
+			filter.FolderID = &folderID
+		}
+
+		page, err := chatService.PageChats(c.Request.Context(), filter, limit, offset)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get chats", err)
+			return
+		}
+
+		h.errorHandler.Success(c, page)
+	}
+}
+
+// GetMessagesHandler returns a page of a chat's messages, oldest first (?limit=100, max 500, ?offset=)
+func (h *APIHandlers) GetMessagesHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		if _, err := chatService.GetChat(c.Request.Context(), chatID); err != nil {
+			h.errorHandler.NotFound(c, "Chat not found")
+			return
+		}
+
+		limit, offset := pagination(c, 100, 500)
+		page, err := chatService.PageMessages(c.Request.Context(), chatID, limit, offset)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get messages", err)
+			return
+		}
+
+		h.errorHandler.Success(c, page)
+	}
+}
+
+// pagination reads ?limit= and ?offset=, falling back to the defaults for missing or invalid values
+func pagination(c *gin.Context, defaultLimit, maxLimit int) (limit, offset int) {
+	limit = defaultLimit
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= maxLimit {
+			limit = parsed
+		}
+	}
+
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+	return limit, offset
+}
+
+// CreateChatHandler creates a new chat and adds the configured greeting in the caller's language
+func (h *APIHandlers) CreateChatHandler(chatService *services.ChatService, greetingService *services.GreetingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Title    string `json:"title" binding:"required"`
+			Provider string `json:"provider" binding:"required"`
+		}
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		chat, err := chatService.CreateChat(c.Request.Context(), req.Title, req.Provider)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to create chat", err)
+			return
+		}
+
+		greetNewChat(c.Request.Context(), greetingService, chat.ID, GetLang(c))
+
+		h.errorHandler.Created(c, chat, "Chat created successfully")
+	}
+}
+
+// GetChatHandler returns a chat with its message count and a preview of its latest message
+func (h *APIHandlers) GetChatHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		details, err := chatService.GetChatDetails(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to get chat", err)
+			return
+		}
+
+		h.errorHandler.Success(c, details)
+	}
+}
+
+// ForkChatHandler creates a branch of a chat with its messages up to ?from_message=M
+func (h *APIHandlers) ForkChatHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+		messageID, err := strconv.ParseInt(c.Query("from_message"), 10, 64)
+		if err != nil || messageID <= 0 {
+			h.errorHandler.BadRequest(c, "Invalid from_message, expected a message ID", err)
+			return
+		}
+
+		chat, err := chatService.ForkChat(c.Request.Context(), chatID, messageID)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to fork chat", err)
+			return
+		}
+
+		h.errorHandler.Created(c, chat, "Chat forked successfully")
+	}
+}
+
+// DeleteChatHandler deletes a chat
+func (h *APIHandlers) DeleteChatHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatIDStr := c.Param("id")
+		chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		if err := chatService.DeleteChat(c.Request.Context(), chatID); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to delete chat", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Chat deleted successfully")
+	}
+}
+
+// ArchiveChatHandler hides a chat from the default chat list
+func (h *APIHandlers) ArchiveChatHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		if err := chatService.ArchiveChat(c.Request.Context(), chatID); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to archive chat", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Chat archived successfully")
+	}
+}
+
+// RestoreChatHandler returns an archived or deleted chat to the default chat list
+func (h *APIHandlers) RestoreChatHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		if err := chatService.RestoreChat(c.Request.Context(), chatID); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to restore chat", err)
+			return
+		}
+
+		chat, err := chatService.GetChat(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get chat", err)
+			return
+		}
+
+		h.errorHandler.Success(c, chat, "Chat restored successfully")
+	}
+}
+
+// Maximum length of a chat's system prompt in characters
+const MaxSystemPromptLength = 8000
+
+// SwitchProviderHandler moves a chat to another provider. The history stays in the chat and is
+// handed to the new provider with the next prompt; the switch is recorded as a system message.
+func (h *APIHandlers) SwitchProviderHandler(chatService *services.ChatService, registry *services.ProviderRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		var req struct {
+			Provider string `json:"provider" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		chat, err := chatService.GetChat(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.NotFound(c, "Chat not found")
+			return
+		}
+
+		if _, err := registry.Get(req.Provider); err != nil {
+			h.errorHandler.ValidationError(c, "Unknown provider", err)
+			return
+		}
+
+		note := i18n.T(GetLang(c), "chat.providerSwitched", chat.Provider, req.Provider)
+		chat, err = chatService.SwitchProvider(c.Request.Context(), chatID, req.Provider, note)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to switch provider", err)
+			return
+		}
+
+		h.errorHandler.Success(c, chat, "Provider switched successfully")
+	}
+}
+
+// UpdateSystemPromptHandler sets or clears (empty string) a chat's system prompt
+func (h *APIHandlers) UpdateSystemPromptHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		var req struct {
+			SystemPrompt string `json:"system_prompt"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		if utf8.RuneCountInString(req.SystemPrompt) > MaxSystemPromptLength {
+			h.errorHandler.BadRequest(c, fmt.Sprintf("System prompt is too long (max %d characters)", MaxSystemPromptLength), nil)
+			return
+		}
+
+		if _, err := chatService.GetChat(c.Request.Context(), chatID); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to get chat", err)
+			return
+		}
+
+		if err := chatService.UpdateSystemPrompt(c.Request.Context(), chatID, req.SystemPrompt); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to update system prompt", err)
+			return
+		}
+
+		chat, err := chatService.GetChat(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get chat", err)
+			return
+		}
+
+		h.errorHandler.Success(c, chat, "System prompt updated successfully")
+	}
+}
+
+// UpdateMessageHandler edits the content of a user message; send ai_regenerate afterwards to get a new answer
+func (h *APIHandlers) UpdateMessageHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+		messageID, err := strconv.ParseInt(c.Param("msgid"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid message ID", err)
+			return
+		}
+
+		var req struct {
+			Content string `json:"content" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+		if strings.TrimSpace(req.Content) == "" {
+			h.errorHandler.BadRequest(c, "Content must not be empty", nil)
+			return
+		}
+		if err := chatService.ValidatePrompt(req.Content); err != nil {
+			h.errorHandler.ValidationError(c, promptErrorMessage(GetLang(c), err), err)
+			return
+		}
+
+		msg, err := chatService.GetMessage(c.Request.Context(), chatID, messageID)
+		if err != nil {
+			h.errorHandler.NotFound(c, "Message not found")
+			return
+		}
+		if msg.Role != "user" {
+			h.errorHandler.BadRequest(c, "Only user messages can be edited", nil)
+			return
+		}
+
+		updated, err := chatService.UpdateMessageContent(c.Request.Context(), chatID, messageID, req.Content)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to update message", err)
+			return
+		}
+
+		h.errorHandler.Success(c, updated, "Message updated successfully")
+	}
+}
+
+// GetChatGenerationsHandler returns generation timings for a chat (?events=true adds raw events)
+func (h *APIHandlers) GetChatGenerationsHandler(generationService *services.GenerationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		if c.Query("events") == "true" {
+			events, err := generationService.GetChatEvents(chatID)
+			if err != nil {
+				h.errorHandler.InternalError(c, "Failed to get generation events", err)
+				return
+			}
+			h.errorHandler.Success(c, events)
+			return
+		}
+
+		timings, err := generationService.GetChatTimings(chatID)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get generations", err)
+			return
+		}
+
+		h.errorHandler.Success(c, timings)
+	}
+}
+
+// GetGenerationStatsHandler returns aggregated generation latency per provider
+// (?since=24h limits the window, ?format=openmetrics returns the OpenMetrics text format)
+func (h *APIHandlers) GetGenerationStatsHandler(generationService *services.GenerationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		window := 24 * time.Hour
+		if s := c.Query("since"); s != "" {
+			parsed, err := time.ParseDuration(s)
+			if err != nil || parsed <= 0 {
+				h.errorHandler.BadRequest(c, "Invalid since duration", err)
+				return
+			}
+			window = parsed
+		}
+
+		stats, err := generationService.GetStats(time.Now().Add(-window))
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get generation stats", err)
+			return
+		}
+
+		if c.Query("format") == "openmetrics" {
+			c.Data(http.StatusOK, "application/openmetrics-text; version=1.0.0; charset=utf-8", []byte(services.FormatOpenMetrics(stats, providers.CancellationMetrics())))
+			return
+		}
+
+		h.errorHandler.Success(c, stats)
+	}
+}
+
+// GetCancellationStatsHandler returns how long cancelled provider processes took to terminate
+func (h *APIHandlers) GetCancellationStatsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.errorHandler.Success(c, providers.CancellationMetrics())
+	}
+}
+
+// GetChatUsageHandler returns byte/token usage for a chat (?records=false omits per-message records)
+func (h *APIHandlers) GetChatUsageHandler(chatService *services.ChatService, usageService *services.UsageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		if _, err := chatService.GetChat(c.Request.Context(), chatID); err != nil {
+			h.errorHandler.NotFound(c, "Chat not found")
+			return
+		}
+
+		usage, err := usageService.GetChatUsage(chatID)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get chat usage", err)
+			return
+		}
+
+		if c.Query("records") == "false" {
+			usage.Records = nil
+		}
+
+		h.errorHandler.Success(c, usage)
+	}
+}
+
+// GetUsageSummaryHandler returns usage totals per provider (?since=24h limits the window)
+func (h *APIHandlers) GetUsageSummaryHandler(usageService *services.UsageService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		limit := 50
-		offset := 0
+		window := 24 * time.Hour
+		if s := c.Query("since"); s != "" {
+			parsed, err := time.ParseDuration(s)
+			if err != nil || parsed <= 0 {
+				h.errorHandler.BadRequest(c, "Invalid since duration", err)
+				return
+			}
+			window = parsed
+		}
 
-		if l := c.Query("limit"); l != "" {
-			if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
-				limit = parsed
+		summary, err := usageService.GetSummary(time.Now().Add(-window))
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get usage summary", err)
+			return
+		}
+
+		h.errorHandler.Success(c, summary)
+	}
+}
+
+// GetUsageQuotaHandler returns the prompts the current session, or the user of a bearer token, has
+// used and has left today and this month
+func (h *APIHandlers) GetUsageQuotaHandler(quotaService *services.QuotaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := quotaService.Status(middleware.QuotaKey(c))
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get prompt quota", err)
+			return
+		}
+
+		h.errorHandler.Success(c, status)
+	}
+}
+
+// Maximum date ranges for activity analytics, keeping bucket counts bounded
+const (
+	MaxHourlyActivityRange = 31 * 24 * time.Hour
+	MaxDailyActivityRange  = 366 * 24 * time.Hour
+)
+
+// activityParams are the query parameters GetActivityHandler understands
+var activityParams = []string{"from", "to", "bucket", "tz", "provider", "role", "user_id"}
+
+// GetActivityHandler returns message counts bucketed by hour or day for a heatmap
+// (?from=&to= as YYYY-MM-DD or RFC 3339, ?bucket=hour|day, ?tz=, ?provider=, ?role=, ?user_id=).
+// Unknown parameters are rejected rather than ignored, so a mistyped filter isn't taken for all messages.
+func (h *APIHandlers) GetActivityHandler(analyticsService *services.AnalyticsService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for param := range c.Request.URL.Query() {
+			if !slices.Contains(activityParams, param) {
+				h.errorHandler.BadRequest(c, fmt.Sprintf("Unknown parameter %q (use %s)", param, strings.Join(activityParams, ", ")), nil)
+				return
+			}
+		}
+
+		loc := time.UTC
+		if tz := c.Query("tz"); tz != "" {
+			parsed, err := time.LoadLocation(tz)
+			if err != nil {
+				h.errorHandler.BadRequest(c, "Invalid time zone", err)
+				return
+			}
+			loc = parsed
+		}
+
+		bucket := c.DefaultQuery("bucket", services.BucketDay)
+		if bucket != services.BucketHour && bucket != services.BucketDay {
+			h.errorHandler.BadRequest(c, "Invalid bucket (use hour or day)", nil)
+			return
+		}
+
+		role := c.Query("role")
+		if role != "" && role != "user" && role != "assistant" {
+			h.errorHandler.BadRequest(c, "Invalid role (use user or assistant)", nil)
+			return
+		}
+
+		to := time.Now().In(loc)
+		if s := c.Query("to"); s != "" {
+			parsed, err := parseAnalyticsTime(s, loc, true)
+			if err != nil {
+				h.errorHandler.BadRequest(c, "Invalid to date", err)
+				return
 			}
+			to = parsed
 		}
 
-		if o := c.Query("offset"); o != "" {
-			if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
-				offset = parsed
+		from := to.AddDate(0, 0, -7)
+		if s := c.Query("from"); s != "" {
+			parsed, err := parseAnalyticsTime(s, loc, false)
+			if err != nil {
+				h.errorHandler.BadRequest(c, "Invalid from date", err)
+				return
 			}
+			from = parsed
+		}
+
+		if !to.After(from) {
+			h.errorHandler.BadRequest(c, "from must be before to", nil)
+			return
+		}
+		maxRange := MaxDailyActivityRange
+		if bucket == services.BucketHour {
+			maxRange = MaxHourlyActivityRange
+		}
+		if to.Sub(from) > maxRange {
+			h.errorHandler.BadRequest(c, fmt.Sprintf("Date range too large for %s buckets (max %d days)", bucket, int(maxRange.Hours()/24)), nil)
+			return
 		}
 
-		chats, err := chatService.GetChats(limit, offset)
+		report, err := analyticsService.GetActivity(services.ActivityQuery{
+			From:     from,
+			To:       to,
+			Bucket:   bucket,
+			Provider: c.Query("provider"),
+			Role:     role,
+			UserID:   c.Query("user_id"),
+			Location: loc,
+		})
 		if err != nil {
-			h.errorHandler.InternalError(c, "Failed to get chats", err)
+			h.errorHandler.InternalError(c, "Failed to get activity", err)
 			return
 		}
 
-		h.errorHandler.Success(c, chats)
+		h.errorHandler.Success(c, report)
+	}
+}
+
+// parseAnalyticsTime parses an RFC 3339 time or a YYYY-MM-DD date in loc.
+// A date used as the end of a range includes the whole day.
+func parseAnalyticsTime(value string, loc *time.Location, endOfRange bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1)
 	}
+	return t, nil
 }
 
-// CreateChatHandler creates a new chat
-func (h *APIHandlers) CreateChatHandler(chatService *services.ChatService) gin.HandlerFunc {
+// GetSessionsHandler returns all active sessions; session IDs are credentials, so this is admin only
+func (h *APIHandlers) GetSessionsHandler(sessionService *services.SessionService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req struct {
-			Title    string `json:"title" binding:"required"`
-			Provider string `json:"provider" binding:"required"`
+		sessions, err := sessionService.ListSessions(c.Request.Context())
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get sessions", err)
+			return
 		}
 
-		if err := c.ShouldBindJSON(&req); err != nil {
-			h.errorHandler.ValidationError(c, "Invalid request", err)
+		h.errorHandler.Success(c, sessions)
+	}
+}
+
+// CurrentSessionHandler returns the caller's own session. The ID is left out: it is the HttpOnly
+// session cookie and scripts have no use for it.
+func (h *APIHandlers) CurrentSessionHandler(sessionService *services.SessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.GetString(middleware.SessionContextKey)
+		if sessionID == "" {
+			h.errorHandler.NotFound(c, "Session not found")
 			return
 		}
 
-		chat, err := chatService.CreateChat(req.Title, req.Provider)
+		info, err := sessionService.GetSessionInfo(c.Request.Context(), sessionID)
+		if errors.Is(err, services.ErrSessionNotFound) {
+			h.errorHandler.NotFound(c, "Session not found")
+			return
+		}
 		if err != nil {
-			h.errorHandler.InternalError(c, "Failed to create chat", err)
+			h.errorHandler.InternalError(c, "Failed to get session", err)
 			return
 		}
 
-		h.errorHandler.Created(c, chat, "Chat created successfully")
+		session := *info.Session
+		session.ID = ""
+		h.errorHandler.Success(c, &models.SessionInfo{Session: &session, TTLSeconds: info.TTLSeconds})
 	}
 }
 
-// DeleteChatHandler deletes a chat
-func (h *APIHandlers) DeleteChatHandler(chatService *services.ChatService) gin.HandlerFunc {
+// DeleteSessionHandler force-expires a session
+func (h *APIHandlers) DeleteSessionHandler(sessionService *services.SessionService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		chatIDStr := c.Param("id")
-		chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
-		if err != nil {
-			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+		sessionID := c.Param("id")
+
+		if _, err := sessionService.GetSession(c.Request.Context(), sessionID); err != nil {
+			h.errorHandler.NotFound(c, "Session not found")
 			return
 		}
 
-		if err := chatService.DeleteChat(chatID); err != nil {
-			h.errorHandler.InternalError(c, "Failed to delete chat", err)
+		if err := sessionService.DeleteSession(c.Request.Context(), sessionID); err != nil {
+			h.errorHandler.InternalError(c, "Failed to delete session", err)
 			return
 		}
 
-		h.errorHandler.Success(c, nil, "Chat deleted successfully")
+		h.errorHandler.Success(c, nil, "Session deleted successfully")
+	}
+}
+
+// SetSessionRoleHandler grants a role to a session; an empty role reverts it to DEFAULT_ROLE
+func (h *APIHandlers) SetSessionRoleHandler(sessionService *services.SessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Role string `json:"role"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+		if req.Role != "" && !models.IsValidRole(req.Role) {
+			h.errorHandler.ValidationError(c, "Role must be viewer, user or admin", nil)
+			return
+		}
+
+		sessionID := c.Param("id")
+		if _, err := sessionService.GetSession(c.Request.Context(), sessionID); err != nil {
+			h.errorHandler.NotFound(c, "Session not found")
+			return
+		}
+
+		if err := sessionService.SetRole(c.Request.Context(), sessionID, req.Role); err != nil {
+			h.errorHandler.InternalError(c, "Failed to set session role", err)
+			return
+		}
+
+		h.errorHandler.Success(c, gin.H{"session_id": sessionID, "role": req.Role})
 	}
 }
 
 // GetProvidersHandler returns available AI providers
 func (h *APIHandlers) GetProvidersHandler(registry *services.ProviderRegistry) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		providers := registry.List()
-		h.errorHandler.Success(c, providers)
+		providerList := registry.List()
+		h.errorHandler.Success(c, providerList)
 	}
 }
 
@@ -146,6 +787,7 @@ func (h *APIHandlers) GetProviderStatusHandler(registry *services.ProviderRegist
 			return
 		}
 		
+		branding := providers.GetBranding(provider)
 		response := gin.H{
 			"id":        provider.GetID(),
 			"name":      provider.GetName(),
@@ -153,50 +795,121 @@ func (h *APIHandlers) GetProviderStatusHandler(registry *services.ProviderRegist
 			"status":    status.Status,
 			"version":   status.Version,
 			"details":   status.Details,
+			"icon_url":  branding.IconURL,
+			"color":     branding.Color,
 		}
 		h.errorHandler.Success(c, response)
 	}
 }
 
-// GetSettingsHandler returns current settings
-func (h *APIHandlers) GetSettingsHandler() gin.HandlerFunc {
+// GetProviderModelsHandler returns the models that can be selected per request for a provider
+func (h *APIHandlers) GetProviderModelsHandler(registry *services.ProviderRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, err := registry.Get(c.Param("id"))
+		if err != nil {
+			h.errorHandler.NotFound(c, "Provider not found")
+			return
+		}
+
+		models := provider.GetModels()
+		if models == nil {
+			models = []providers.Model{}
+		}
+
+		h.errorHandler.Success(c, models)
+	}
+}
+
+// GetProviderConfigHandler returns a provider's configuration with credentials masked
+func (h *APIHandlers) GetProviderConfigHandler(registry *services.ProviderRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, err := registry.Get(c.Param("id"))
+		if err != nil {
+			h.errorHandler.NotFound(c, "Provider not found")
+			return
+		}
+
+		configured, ok := provider.(providers.ConfiguredProvider)
+		if !ok {
+			h.errorHandler.NotFound(c, "Provider has no configuration")
+			return
+		}
+
+		h.errorHandler.Success(c, configured.GetConfig().Masked())
+	}
+}
+
+// GetProviderHealthHistoryHandler returns recent scheduled health checks for a provider, newest first
+func (h *APIHandlers) GetProviderHealthHistoryHandler(registry *services.ProviderRegistry, healthService *services.HealthCheckService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		providerID := c.Param("id")
+
+		if _, err := registry.Get(providerID); err != nil {
+			h.errorHandler.NotFound(c, "Provider not found")
+			return
+		}
+
+		limit := 0
+		if l := c.Query("limit"); l != "" {
+			if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+				limit = parsed
+			}
+		}
+
+		history, err := healthService.History(providerID, limit)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get health history", err)
+			return
+		}
+
+		h.errorHandler.Success(c, history)
+	}
+}
+
+// Chat input behaviors a user can choose
+var validInputBehaviors = []string{"enter_to_send", "ctrl_enter_to_send"}
+
+// GetSettingsHandler returns the current settings: those saved for the settings owner of the
+// request (see UserSettingsService), otherwise the preference cookies
+func (h *APIHandlers) GetSettingsHandler(userSettings *services.UserSettingsService, owner func(c *gin.Context) string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := owner(c); key != "" {
+			saved, err := userSettings.Get(key)
+			if err != nil {
+				utils.Warn("Failed to load saved settings, using cookies: %v", err)
+			} else if saved != nil {
+				// Sync the cookies, so a browser seeing these settings for the first time renders with them
+				setPreferenceCookies(c, *saved)
+				h.errorHandler.Success(c, saved)
+				return
+			}
+		}
+
 		// Get current language from context (set by i18n middleware)
 		currentLang := c.GetString("lang")
 		if currentLang == "" {
 			currentLang = config.DefaultLanguage
 		}
-		
-		// Get theme from cookie if available
-		currentTheme := config.DefaultTheme
-		if themeCookie, err := c.Cookie("theme"); err == nil && themeCookie != "" {
-			currentTheme = themeCookie
-		}
-		
+
 		// Get chat input behavior from cookie if available
 		currentChatBehavior := "enter_to_send" // Default
 		if chatBehaviorCookie, err := c.Cookie("chatInputBehavior"); err == nil && chatBehaviorCookie != "" {
 			currentChatBehavior = chatBehaviorCookie
 		}
-		
-		settings := gin.H{
-			"language": currentLang,
-			"theme":    currentTheme,
-			"chatInputBehavior": currentChatBehavior,
-		}
-		h.errorHandler.Success(c, settings)
+
+		h.errorHandler.Success(c, models.UserSettings{
+			Language:          currentLang,
+			Theme:             GetTheme(c),
+			ChatInputBehavior: currentChatBehavior,
+		})
 	}
 }
 
-// UpdateSettingsHandler updates user settings
-func (h *APIHandlers) UpdateSettingsHandler() gin.HandlerFunc {
+// UpdateSettingsHandler updates user settings, saving them for the settings owner of the request
+// and in the preference cookies
+func (h *APIHandlers) UpdateSettingsHandler(userSettings *services.UserSettingsService, registry *services.ProviderRegistry, owner func(c *gin.Context) string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req struct {
-			Language          string `json:"language"`
-			Theme            string `json:"theme"`
-			ChatInputBehavior string `json:"chatInputBehavior"`
-		}
-
+		var req models.UserSettings
 		if err := c.ShouldBindJSON(&req); err != nil {
 			h.errorHandler.ValidationError(c, "Invalid request", err)
 			return
@@ -226,26 +939,210 @@ func (h *APIHandlers) UpdateSettingsHandler() gin.HandlerFunc {
 		}
 
 		// Validate chat input behavior
-		validInputBehaviors := []string{"enter_to_send", "ctrl_enter_to_send"}
-		if req.ChatInputBehavior != "" {
-			if !slices.Contains(validInputBehaviors, req.ChatInputBehavior) {
-				h.errorHandler.BadRequest(c, "Invalid chat input behavior. Supported: "+strings.Join(validInputBehaviors, ", "), nil)
+		if !slices.Contains(validInputBehaviors, req.ChatInputBehavior) {
+			h.errorHandler.BadRequest(c, "Invalid chat input behavior. Supported: "+strings.Join(validInputBehaviors, ", "), nil)
+			return
+		}
+
+		// Validate the default provider and model
+		if req.DefaultProvider != "" {
+			provider, err := registry.Get(req.DefaultProvider)
+			if err != nil {
+				h.errorHandler.BadRequest(c, "Unknown default provider: "+req.DefaultProvider, nil)
+				return
+			}
+			if req.DefaultModel != "" && !providers.SupportsModel(provider, req.DefaultModel) {
+				h.errorHandler.BadRequest(c, fmt.Sprintf("Provider %s has no model %s", req.DefaultProvider, req.DefaultModel), nil)
 				return
 			}
+		} else if req.DefaultModel != "" {
+			h.errorHandler.BadRequest(c, "A default model needs a default provider", nil)
+			return
 		}
 
-		// Set preference cookies with security flags
-		secure := c.Request.TLS != nil // Use secure flag for HTTPS connections
-		c.SetCookie("lang", req.Language, CookieMaxAge, "/", "", secure, true)  // 30 days, httpOnly
-		c.SetCookie("theme", req.Theme, CookieMaxAge, "/", "", secure, true)    // 30 days, httpOnly
-		c.SetCookie("chatInputBehavior", req.ChatInputBehavior, CookieMaxAge, "/", "", secure, true) // 30 days, httpOnly
-		
-		response := gin.H{
-			"language": req.Language,
-			"theme":    req.Theme,
-			"chatInputBehavior": req.ChatInputBehavior,
+		if key := owner(c); key != "" {
+			if err := userSettings.Save(key, req); err != nil {
+				h.errorHandler.InternalError(c, "Failed to save settings", err)
+				return
+			}
+		}
+		setPreferenceCookies(c, req)
+
+		h.errorHandler.Success(c, req, "Settings updated successfully")
+	}
+}
+
+// setPreferenceCookies stores the settings the pages render with in cookies
+func setPreferenceCookies(c *gin.Context, settings models.UserSettings) {
+	// 30 days, httpOnly, secure over HTTPS
+	secure := utils.IsHTTPS(c.Request)
+	c.SetCookie("lang", settings.Language, CookieMaxAge, "/", "", secure, true)
+	c.SetCookie("theme", settings.Theme, CookieMaxAge, "/", "", secure, true)
+	c.SetCookie("chatInputBehavior", settings.ChatInputBehavior, CookieMaxAge, "/", "", secure, true)
+}
+
+// Maximum length of a greeting welcome message or disclaimer in characters
+const MaxGreetingLength = 4000
+
+// GetGreetingHandler returns the greeting added to new chats
+func (h *APIHandlers) GetGreetingHandler(greetingService *services.GreetingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		greeting, err := greetingService.Get()
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get greeting", err)
+			return
+		}
+
+		h.errorHandler.Success(c, greeting)
+	}
+}
+
+// UpdateGreetingHandler replaces the greeting added to new chats
+func (h *APIHandlers) UpdateGreetingHandler(greetingService *services.GreetingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.GreetingSettings
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		for _, texts := range []map[string]string{req.Welcome, req.Disclaimer} {
+			for lang, text := range texts {
+				if !config.IsValidLanguage(lang) {
+					h.errorHandler.BadRequest(c, "Unsupported language. Supported languages: "+strings.Join(config.SupportedLanguages, ", "), nil)
+					return
+				}
+				if utf8.RuneCountInString(text) > MaxGreetingLength {
+					h.errorHandler.BadRequest(c, fmt.Sprintf("Greeting is too long (max %d characters)", MaxGreetingLength), nil)
+					return
+				}
+			}
+		}
+
+		greeting, err := greetingService.Update(req)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to update greeting", err)
+			return
+		}
+
+		h.errorHandler.Success(c, greeting, "Greeting updated successfully")
+	}
+}
+
+// ExportConfigBundleHandler downloads the hub configuration as a signed bundle
+func (h *APIHandlers) ExportConfigBundleHandler(bundleService *services.ConfigBundleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bundle, err := bundleService.Export()
+		if errors.Is(err, services.ErrConfigBundlesDisabled) {
+			h.errorHandler.BadRequest(c, "Configuration bundles are disabled, set CONFIG_BUNDLE_SECRET", nil)
+			return
+		}
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to export configuration", err)
+			return
+		}
+
+		filename := fmt.Sprintf("aigwhub-config-%s.json", time.Now().Format("20060102-150405"))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		c.JSON(http.StatusOK, bundle)
+	}
+}
+
+// ImportConfigBundleHandler applies a signed configuration bundle (?dry_run=true only reports the changes)
+func (h *APIHandlers) ImportConfigBundleHandler(bundleService *services.ConfigBundleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req services.SignedConfigBundle
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.Bundle) == 0 || req.Signature == "" {
+			h.errorHandler.ValidationError(c, "Invalid configuration bundle", err)
+			return
+		}
+
+		dryRun := c.Query("dry_run") == "true"
+		result, err := bundleService.Import(&req, dryRun)
+		switch {
+		case errors.Is(err, services.ErrConfigBundlesDisabled):
+			h.errorHandler.BadRequest(c, "Configuration bundles are disabled, set CONFIG_BUNDLE_SECRET", nil)
+			return
+		case err != nil:
+			h.errorHandler.ServiceError(c, "Failed to import configuration", err)
+			return
+		}
+
+		message := "Configuration imported successfully"
+		if dryRun {
+			message = "Configuration bundle is valid"
+		}
+		h.errorHandler.Success(c, result, message)
+	}
+}
+
+// ReloadConfigHandler reloads the configuration and returns the runtime settings that changed
+func (h *APIHandlers) ReloadConfigHandler(reloadService *services.ConfigReloadService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		changes, err := reloadService.Reload()
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to reload configuration", err)
+			return
+		}
+		if changes == nil {
+			changes = []config.Change{}
+		}
+		h.errorHandler.Success(c, gin.H{"changes": changes, "reloadable": config.ReloadableKeys}, "Configuration reloaded")
+	}
+}
+
+// ReloadTranslationsHandler reloads the locale files and reports the outcome per language
+func (h *APIHandlers) ReloadTranslationsHandler(localizer *i18n.Localizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reports, err := localizer.Reload()
+		if errors.Is(err, i18n.ErrEmbedded) {
+			h.errorHandler.BadRequest(c, "Translations are embedded in the binary and can't be reloaded", nil)
+			return
+		}
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to reload translations", err)
+			return
+		}
+
+		message := "Translations reloaded"
+		for _, report := range reports {
+			if !report.Loaded {
+				message = "Some translations failed to load; their previous version is still in use"
+				break
+			}
 		}
-		h.errorHandler.Success(c, response, "Settings updated successfully")
+		h.errorHandler.Success(c, reports, message)
+	}
+}
+
+// ValidateTranslationsHandler compares every language with the default language and reports
+// missing and extra keys and mismatched format placeholders
+func (h *APIHandlers) ValidateTranslationsHandler(localizer *i18n.Localizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		issues := localizer.Validate()
+		valid := true
+		for _, language := range issues {
+			valid = valid && language.OK()
+		}
+
+		h.errorHandler.Success(c, gin.H{
+			"default_language": localizer.DefaultLanguage(),
+			"valid":            valid,
+			"languages":        issues,
+		})
+	}
+}
+
+// GetHubInstancesHandler lists the server instances sharing the WebSocket backplane
+func (h *APIHandlers) GetHubInstancesHandler(hub *Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		instances, err := hub.Instances()
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to list instances", err)
+			return
+		}
+
+		h.errorHandler.Success(c, gin.H{"instance_id": hub.InstanceID(), "instances": instances})
 	}
 }
 
@@ -258,6 +1155,7 @@ func (h *APIHandlers) LogClientErrorHandler() gin.HandlerFunc {
 			URL     string `json:"url"`
 			UserAgent string `json:"userAgent"`
 			Level   string `json:"level"`
+			RequestID string `json:"requestId"` // ID of the failed request or WebSocket connection, if known
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -266,8 +1164,11 @@ func (h *APIHandlers) LogClientErrorHandler() gin.HandlerFunc {
 		}
 
 		// Log the client error to server logs
-		clientInfo := fmt.Sprintf("URL: %s, User-Agent: %s", req.URL, req.UserAgent)
+		clientInfo := fmt.Sprintf("URL: %s, User-Agent: %s, Report request_id: %s", req.URL, req.UserAgent, requestID(c))
 		errorMessage := fmt.Sprintf("Client Error: %s", req.Message)
+		if req.RequestID != "" {
+			errorMessage += fmt.Sprintf(" (request_id=%s)", req.RequestID)
+		}
 		if req.Stack != "" {
 			errorMessage += fmt.Sprintf("\nStack: %s", req.Stack)
 		}
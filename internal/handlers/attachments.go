@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Room left for multipart headers on top of the attachment size limit
+const attachmentUploadOverhead = 1 << 20
+
+// UploadAttachmentHandler stores a file uploaded as the multipart field "file". The returned ID is
+// sent with the next prompt in attachment_ids.
+func (h *APIHandlers) UploadAttachmentHandler(chatService *services.ChatService, attachmentService *services.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		if _, err := chatService.GetChat(c.Request.Context(), chatID); err != nil {
+			h.errorHandler.NotFound(c, "Chat not found")
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, attachmentService.MaxSize()+attachmentUploadOverhead)
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				h.errorHandler.PayloadTooLarge(c, "Attachment is too large")
+				return
+			}
+			h.errorHandler.BadRequest(c, "Missing file", err)
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Failed to read file", err)
+			return
+		}
+		defer file.Close()
+
+		attachment, err := attachmentService.Save(chatID, fileHeader.Filename, file)
+		switch {
+		case errors.Is(err, services.ErrAttachmentTooLarge):
+			h.errorHandler.PayloadTooLarge(c, "Attachment is too large")
+		case err != nil:
+			h.errorHandler.ServiceError(c, "Failed to save attachment", err)
+		default:
+			h.errorHandler.Created(c, attachment)
+		}
+	}
+}
+
+// GetAttachmentsHandler lists the attachments of a chat
+func (h *APIHandlers) GetAttachmentsHandler(chatService *services.ChatService, attachmentService *services.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		if _, err := chatService.GetChat(c.Request.Context(), chatID); err != nil {
+			h.errorHandler.NotFound(c, "Chat not found")
+			return
+		}
+
+		attachments, err := attachmentService.ListByChat(chatID)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get attachments", err)
+			return
+		}
+
+		h.errorHandler.Success(c, attachments)
+	}
+}
+
+// DownloadAttachmentHandler sends an attachment's file. It is always served as a download so
+// uploaded HTML or SVG is never rendered in the hub's origin.
+func (h *APIHandlers) DownloadAttachmentHandler(attachmentService *services.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, attachmentID, ok := h.attachmentParams(c)
+		if !ok {
+			return
+		}
+
+		attachment, err := attachmentService.Get(chatID, attachmentID)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to get attachment", err)
+			return
+		}
+
+		c.Header("Content-Type", attachment.ContentType)
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.File(attachmentService.Path(attachment))
+	}
+}
+
+// DeleteAttachmentHandler removes an attachment
+func (h *APIHandlers) DeleteAttachmentHandler(attachmentService *services.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, attachmentID, ok := h.attachmentParams(c)
+		if !ok {
+			return
+		}
+
+		if err := attachmentService.Delete(chatID, attachmentID); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to delete attachment", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Attachment deleted successfully")
+	}
+}
+
+// attachmentParams parses the chat and attachment IDs, answering 400 when either is invalid
+func (h *APIHandlers) attachmentParams(c *gin.Context) (int64, int64, bool) {
+	chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+		return 0, 0, false
+	}
+	attachmentID, err := strconv.ParseInt(c.Param("attachmentId"), 10, 64)
+	if err != nil {
+		h.errorHandler.BadRequest(c, "Invalid attachment ID", err)
+		return 0, 0, false
+	}
+	return chatID, attachmentID, true
+}
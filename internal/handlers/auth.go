@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+
+	"ai-gateway-hub/internal/auth"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authClaimsContextKey is where the JWT middleware puts the claims of a request's access token
+const authClaimsContextKey = "auth_claims"
+
+// LoginRequest signs a user of AUTH_USERS_FILE in
+type LoginRequest struct {
+	UserID   string `json:"user_id" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RefreshRequest carries a refresh token to use up or revoke
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LoginHandler issues an access and a refresh token for a user's ID and password
+func (h *APIHandlers) LoginHandler(authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		tokens, err := authService.Login(c.Request.Context(), req.UserID, req.Password)
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			utils.Warn("Failed sign-in of user %q from %s", req.UserID, c.ClientIP())
+			h.errorHandler.Unauthorized(c, "Invalid user ID or password")
+			return
+		}
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to sign in", err)
+			return
+		}
+
+		utils.Info("User %s signed in from %s", tokens.UserID, c.ClientIP())
+		h.errorHandler.Success(c, tokens)
+	}
+}
+
+// RefreshTokenHandler uses up a refresh token and issues new tokens
+func (h *APIHandlers) RefreshTokenHandler(authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		tokens, err := authService.Refresh(c.Request.Context(), req.RefreshToken)
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			h.errorHandler.Unauthorized(c, "Invalid or expired refresh token")
+			return
+		}
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to refresh token", err)
+			return
+		}
+
+		h.errorHandler.Success(c, tokens)
+	}
+}
+
+// LogoutHandler revokes a refresh token
+func (h *APIHandlers) LogoutHandler(authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		if err := authService.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+			h.errorHandler.InternalError(c, "Failed to sign out", err)
+			return
+		}
+
+		h.errorHandler.Success(c, gin.H{"signed_out": true})
+	}
+}
+
+// CurrentUserHandler returns the user ID and roles of the request's access token
+func (h *APIHandlers) CurrentUserHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get(authClaimsContextKey)
+		claims, _ := value.(*auth.Claims)
+		if !ok || claims == nil {
+			h.errorHandler.Unauthorized(c, "Access token required")
+			return
+		}
+
+		h.errorHandler.Success(c, gin.H{"user_id": claims.Subject, "roles": claims.Roles, "expires_at": claims.ExpiresAt})
+	}
+}
@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"ai-gateway-hub/internal/services"
 	"ai-gateway-hub/internal/utils"
@@ -11,7 +16,7 @@ import (
 )
 
 // ChatHandler handles the chat page
-func ChatHandler(chatService *services.ChatService) gin.HandlerFunc {
+func ChatHandler(chatService *services.ChatService, attachmentService *services.AttachmentService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		lang := GetLang(c)
 		t := GetTranslator(c)
@@ -29,7 +34,7 @@ func ChatHandler(chatService *services.ChatService) gin.HandlerFunc {
 		}
 
 		// Get chat details
-		chat, err := chatService.GetChat(chatID)
+		chat, err := chatService.GetChat(c.Request.Context(), chatID)
 		if err != nil {
 			utils.Error("ChatHandler: failed to get chat %d: %v", chatID, err)
 			c.HTML(http.StatusNotFound, "pages/error.html", gin.H{
@@ -41,7 +46,7 @@ func ChatHandler(chatService *services.ChatService) gin.HandlerFunc {
 		utils.Debug("ChatHandler: found chat %d: %s", chatID, chat.Title)
 
 		// Get messages
-		messages, err := chatService.GetMessages(chatID, 1000, 0)
+		messages, err := chatService.GetMessages(c.Request.Context(), chatID, 1000, 0)
 		if err != nil {
 			utils.Error("ChatHandler: failed to get messages for chat %d: %v", chatID, err)
 			c.HTML(http.StatusInternalServerError, "pages/error.html", gin.H{
@@ -52,12 +57,159 @@ func ChatHandler(chatService *services.ChatService) gin.HandlerFunc {
 		}
 		utils.Debug("ChatHandler: found %d messages for chat %d", len(messages), chatID)
 
+		if attachmentService != nil {
+			if err := attachmentService.AddToMessages(chatID, messages); err != nil {
+				utils.Warn("ChatHandler: failed to load attachments for chat %d: %v", chatID, err)
+			}
+		}
+
+		// A prompt passed from the /new template URL is sent automatically once connected,
+		// unless the conversation already started (greeting system messages don't count). Links
+		// from other sites only fill it in, so they can't spend the user's prompts.
+		initialPrompt := c.Query("prompt")
+		autoSend := sameSiteNavigation(c.Request)
+		for _, msg := range messages {
+			if msg.Role != "system" {
+				initialPrompt = ""
+				break
+			}
+		}
+
 		utils.Debug("ChatHandler: rendering chat.html template")
 		c.HTML(http.StatusOK, "pages/chat.html", gin.H{
-			"title":    chat.Title,
-			"chat":     chat,
-			"messages": messages,
-			"lang":     lang,
+			"title":         chat.Title,
+			"chat":          chat,
+			"messages":      messages,
+			"initialPrompt": initialPrompt,
+			"autoSend":      autoSend,
+			"systemPrompt":  chat.SystemPrompt,
+			"lang":          lang,
+			"theme":         GetTheme(c),
+			"csrfToken":     GetCSRFToken(c),
 		})
 	}
+}
+
+const (
+	// Maximum prompt length accepted by the /new template URL
+	MaxTemplatePromptLength = 8000
+
+	// Maximum length of a chat title derived from the prompt
+	maxDerivedTitleLength = 50
+)
+
+// NewChatFromTemplateHandler handles GET /new?provider=...&prompt=...&title=... and its confirmation,
+// POST /new with the same form fields. A GET from another site, e.g. a link or an image, shows the
+// confirmation instead of creating the chat, as a GET can't carry a CSRF token.
+func NewChatFromTemplateHandler(chatService *services.ChatService, registry *services.ProviderRegistry, greetingService *services.GreetingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := GetLang(c)
+		t := GetTranslator(c)
+
+		field := c.Query
+		if c.Request.Method == http.MethodPost {
+			field = c.PostForm
+		}
+		providerID := strings.TrimSpace(field("provider"))
+		prompt := strings.TrimSpace(field("prompt"))
+		title := strings.TrimSpace(field("title"))
+
+		if providerID == "" {
+			c.HTML(http.StatusBadRequest, "pages/error.html", gin.H{
+				"error": t("error.providerRequired"),
+				"lang":  lang,
+			})
+			return
+		}
+
+		provider, err := registry.Get(providerID)
+		if err != nil {
+			utils.Warn("NewChatFromTemplateHandler: unknown provider %s", providerID)
+			c.HTML(http.StatusNotFound, "pages/error.html", gin.H{
+				"error": t("error.providerNotFound"),
+				"lang":  lang,
+			})
+			return
+		}
+
+		if utf8.RuneCountInString(prompt) > MaxTemplatePromptLength {
+			c.HTML(http.StatusBadRequest, "pages/error.html", gin.H{
+				"error": t("error.promptTooLong"),
+				"lang":  lang,
+			})
+			return
+		}
+
+		if c.Request.Method == http.MethodGet && !sameSiteNavigation(c.Request) {
+			c.HTML(http.StatusOK, "pages/new_chat.html", gin.H{
+				"provider":     providerID,
+				"providerName": provider.GetName(),
+				"prompt":       prompt,
+				"title":        title,
+				"lang":         lang,
+				"theme":        GetTheme(c),
+				"csrfToken":    GetCSRFToken(c),
+			})
+			return
+		}
+
+		if title == "" {
+			title = deriveChatTitle(prompt, t("home.newChat.defaultTitle"))
+		}
+
+		chat, err := chatService.CreateChat(c.Request.Context(), title, providerID)
+		if err != nil {
+			utils.Error("NewChatFromTemplateHandler: failed to create chat: %v", err)
+			c.HTML(http.StatusInternalServerError, "pages/error.html", gin.H{
+				"error": t("error.failedToCreateChat"),
+				"lang":  lang,
+			})
+			return
+		}
+		utils.Debug("NewChatFromTemplateHandler: created chat %d for provider %s", chat.ID, providerID)
+		greetNewChat(c.Request.Context(), greetingService, chat.ID, lang)
+
+		target := fmt.Sprintf("/chat/%d", chat.ID)
+		if prompt != "" {
+			target += "?" + url.Values{"prompt": {prompt}}.Encode()
+		}
+		c.Redirect(http.StatusSeeOther, target)
+	}
+}
+
+// sameSiteNavigation reports whether a page request was started on this site or by the user, e.g.
+// from a bookmark, rather than by a link, form or image on another site. Browsers that don't send
+// Sec-Fetch-Site are judged by their Referer.
+func sameSiteNavigation(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+		referer, err := url.Parse(r.Header.Get("Referer"))
+		return err == nil && referer.Host != "" && referer.Host == r.Host
+	}
+	return false
+}
+
+// greetNewChat adds the configured greeting to a new chat; failures are logged, not returned
+func greetNewChat(ctx context.Context, greetingService *services.GreetingService, chatID int64, lang string) {
+	if greetingService == nil {
+		return
+	}
+	if err := greetingService.Greet(ctx, chatID, lang); err != nil {
+		utils.Warn("Failed to add greeting to chat %d: %v", chatID, err)
+	}
+}
+
+// deriveChatTitle builds a chat title from the first line of a prompt
+func deriveChatTitle(prompt, fallback string) string {
+	line := strings.TrimSpace(strings.SplitN(prompt, "\n", 2)[0])
+	if line == "" {
+		return fallback
+	}
+	if utf8.RuneCountInString(line) > maxDerivedTitleLength {
+		runes := []rune(line)
+		line = string(runes[:maxDerivedTitleLength]) + "..."
+	}
+	return line
 }
\ No newline at end of file
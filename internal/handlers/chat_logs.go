@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"slices"
+	"strconv"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChatLogHandler returns lines of a provider's execution log of a chat: ?provider= (default
+// the chat's provider, or the latest log), ?offset= in bytes and ?limit= lines (200, max 1000), or
+// the last ?tail= lines. ?download=true sends the whole file.
+func (h *APIHandlers) GetChatLogHandler(chatService *services.ChatService, chatLogService *services.ChatLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		chat, err := chatService.GetChat(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.NotFound(c, "Chat not found")
+			return
+		}
+
+		files, err := chatLogService.Files(chatID)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to list chat logs", err)
+			return
+		}
+		provider := c.Query("provider")
+		if provider == "" {
+			if len(files) == 0 {
+				h.errorHandler.NotFound(c, "Chat has no provider logs")
+				return
+			}
+			provider = files[0].Provider
+			if slices.ContainsFunc(files, func(f *models.ChatLogFile) bool { return f.Provider == chat.Provider }) {
+				provider = chat.Provider
+			}
+		}
+
+		if c.Query("download") == "true" {
+			path, err := chatLogService.Path(chatID, provider)
+			if err != nil {
+				h.errorHandler.ServiceError(c, "Failed to get chat log", err)
+				return
+			}
+			c.Header("Content-Type", "text/plain; charset=utf-8")
+			c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fmt.Sprintf("chat_%d_%s.log", chatID, provider)}))
+			c.Header("X-Content-Type-Options", "nosniff")
+			c.File(path)
+			return
+		}
+
+		var page *models.ChatLogPage
+		limit, offset := pagination(c, 200, 1000)
+		if tail, _ := strconv.Atoi(c.Query("tail")); tail > 0 {
+			page, err = chatLogService.Tail(chatID, provider, min(tail, 1000))
+		} else {
+			page, err = chatLogService.Read(chatID, provider, int64(offset), limit)
+		}
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to read chat log", err)
+			return
+		}
+
+		page.Files = files
+		h.errorHandler.Success(c, page)
+	}
+}
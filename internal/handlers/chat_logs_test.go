@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetChatLogHandler(t *testing.T) {
+	router, chatService, cleanup := setupAPITest(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	apiHandlers := NewAPIHandlers(nil)
+	router.GET("/api/chats/:id/logs", apiHandlers.GetChatLogHandler(chatService, services.NewChatLogService(dir)))
+
+	request := func(path string) (*httptest.ResponseRecorder, models.ChatLogPage) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var response struct {
+			Data models.ChatLogPage `json:"data"`
+		}
+		if w.Code == http.StatusOK && w.Header().Get("Content-Disposition") == "" {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		}
+		return w, response.Data
+	}
+
+	chat, err := chatService.CreateChat(context.Background(), "Logs", "claude")
+	require.NoError(t, err)
+	path := "/api/chats/" + strconv.FormatInt(chat.ID, 10) + "/logs"
+
+	w, _ := request(path)
+	assert.Equal(t, http.StatusNotFound, w.Code, "the chat has no logs yet")
+	w, _ = request("/api/chats/999/logs")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	for provider, content := range map[string]string{"claude": "start\nchunk\nend\n", "gemini": "compared\n"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, provider), 0750))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, provider, "chat_"+strconv.FormatInt(chat.ID, 10)+".log"), []byte(content), 0600))
+	}
+
+	// The chat's provider is read by default
+	w, page := request(path + "?limit=2")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "claude", page.Provider)
+	assert.Equal(t, []string{"start", "chunk"}, page.Lines)
+	assert.True(t, page.HasMore)
+	assert.Len(t, page.Files, 2)
+
+	w, page = request(path + "?offset=" + strconv.FormatInt(page.NextOffset, 10))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"end"}, page.Lines)
+
+	w, page = request(path + "?tail=1&provider=gemini")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"compared"}, page.Lines)
+
+	w, _ = request(path + "?provider=../claude")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w, _ = request(path + "?download=true")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "start\nchunk\nend\n", w.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "_claude.log")
+}
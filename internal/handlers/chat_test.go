@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/middleware"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChatFromTemplateHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	require.NoError(t, i18n.Init("../../locales", "en"))
+
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	registry := services.NewProviderRegistry(nil)
+	require.NoError(t, registry.Register(&mockAIProvider{name: "mock", healthy: true}))
+
+	tmpl := template.Must(template.New("").Funcs(i18n.TemplateFuncs()).ParseGlob("../../web/templates/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/pages/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/components/*.html"))
+
+	router := gin.New()
+	router.SetHTMLTemplate(tmpl)
+	router.Use(middleware.CSRFMiddleware(&config.Config{EnableCSRF: true}))
+	router.GET("/new", NewChatFromTemplateHandler(chatService, registry, nil))
+	router.POST("/new", NewChatFromTemplateHandler(chatService, registry, nil))
+
+	get := func(query, fetchSite string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/new?"+query, nil)
+		if fetchSite != "" {
+			req.Header.Set("Sec-Fetch-Site", fetchSite)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	chatCount := func() int {
+		chats, err := chatService.GetChats(context.Background(), 100, 0)
+		require.NoError(t, err)
+		return len(chats)
+	}
+	createdChat := func(w *httptest.ResponseRecorder) (string, url.Values) {
+		require.Equal(t, http.StatusSeeOther, w.Code)
+		target, err := url.Parse(w.Header().Get("Location"))
+		require.NoError(t, err)
+		return target.Path, target.Query()
+	}
+
+	// Provider validation
+	assert.Equal(t, http.StatusBadRequest, get("prompt=hi", "same-origin").Code)
+	assert.Equal(t, http.StatusNotFound, get("provider=missing&prompt=hi", "same-origin").Code)
+	assert.Equal(t, http.StatusBadRequest, get("provider=mock&prompt="+strings.Repeat("a", MaxTemplatePromptLength+1), "same-origin").Code)
+	assert.Zero(t, chatCount())
+
+	// The title is derived from the first line of the prompt, which is handed over to the chat page
+	path, query := createdChat(get(url.Values{"provider": {"mock"}, "prompt": {"Explain goroutines\nwith examples"}}.Encode(), "same-origin"))
+	assert.Equal(t, "Explain goroutines\nwith examples", query.Get("prompt"))
+	chats, err := chatService.GetChats(context.Background(), 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "/chat/"+strconv.FormatInt(chats[0].ID, 10), path)
+	assert.Equal(t, "Explain goroutines", chats[0].Title)
+	assert.Equal(t, "mock", chats[0].Provider)
+
+	// Without a prompt the chat opens empty, with the default title
+	_, query = createdChat(get("provider=mock", "none"))
+	assert.Empty(t, query)
+	chats, err = chatService.GetChats(context.Background(), 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "New Chat", chats[0].Title)
+
+	// Links and images on other sites, and browsers that don't say where the request came from, get
+	// a confirmation instead of a new chat
+	for _, fetchSite := range []string{"cross-site", "same-site", ""} {
+		w := get("provider=mock&prompt=Spend+my+quota", fetchSite)
+		require.Equal(t, http.StatusOK, w.Code, fetchSite)
+		assert.Contains(t, w.Body.String(), `action="/new"`)
+		assert.Contains(t, w.Body.String(), "Spend my quota")
+	}
+	assert.Equal(t, 2, chatCount())
+
+	// Confirming posts the form with the CSRF token
+	form := url.Values{"provider": {"mock"}, "prompt": {"Spend my quota"}, "title": {"Confirmed"}}
+	post := func(token string) *httptest.ResponseRecorder {
+		values := url.Values{"csrf_token": {token}}
+		for key, value := range form {
+			values[key] = value
+		}
+		req := httptest.NewRequest(http.MethodPost, "/new", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: "token"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusForbidden, post("forged").Code)
+	assert.Equal(t, 2, chatCount())
+
+	_, query = createdChat(post("token"))
+	assert.Equal(t, "Spend my quota", query.Get("prompt"))
+	chats, err = chatService.GetChats(context.Background(), 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Confirmed", chats[0].Title)
+}
+
+func TestSameSiteNavigation(t *testing.T) {
+	request := func(fetchSite, referer string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "http://hub.example.com/new", nil)
+		if fetchSite != "" {
+			req.Header.Set("Sec-Fetch-Site", fetchSite)
+		}
+		if referer != "" {
+			req.Header.Set("Referer", referer)
+		}
+		return req
+	}
+
+	assert.True(t, sameSiteNavigation(request("same-origin", "")))
+	assert.True(t, sameSiteNavigation(request("none", "")))
+	assert.False(t, sameSiteNavigation(request("cross-site", "http://hub.example.com/")))
+	assert.False(t, sameSiteNavigation(request("same-site", "")))
+	assert.True(t, sameSiteNavigation(request("", "http://hub.example.com/chat/1")))
+	assert.False(t, sameSiteNavigation(request("", "https://evil.example.net/")))
+	assert.False(t, sameSiteNavigation(request("", "")))
+}
+
+func TestDeriveChatTitle(t *testing.T) {
+	assert.Equal(t, "Fallback", deriveChatTitle("", "Fallback"))
+	assert.Equal(t, "Fallback", deriveChatTitle("\nsecond line", "Fallback"))
+	assert.Equal(t, "First line", deriveChatTitle("  First line  \nSecond line", "Fallback"))
+
+	long := strings.Repeat("あ", maxDerivedTitleLength+10)
+	assert.Equal(t, strings.Repeat("あ", maxDerivedTitleLength)+"...", deriveChatTitle(long, "Fallback"), "titles are cut by characters, not bytes")
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompleteHandler answers a prompt synchronously with the provider's whole response, for scripts and
+// cron jobs without a WebSocket. Without chat_id the exchange goes to a temporary chat.
+func (h *APIHandlers) CompleteHandler(completionService *services.CompletionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CompletionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+		if req.ChatID == nil && req.Title == "" {
+			req.Title = deriveChatTitle(req.Prompt, "Completion")
+		}
+
+		completion, err := completionService.Complete(c.Request.Context(), req)
+		switch {
+		case errors.Is(err, services.ErrCompletionTimeout):
+			h.errorHandler.GatewayTimeout(c, "Provider did not complete the response in time", err)
+			return
+		case errors.Is(err, services.ErrProviderUnavailable):
+			h.errorHandler.ServiceUnavailable(c, "Provider is not available", err)
+			return
+		case errors.Is(err, services.ErrProviderDegraded):
+			h.errorHandler.ServiceUnavailable(c, "Provider is degraded after repeated failures, try again later", err)
+			return
+		case err != nil:
+			h.errorHandler.ServiceError(c, "Failed to complete prompt", err)
+			return
+		}
+
+		h.errorHandler.Success(c, completion)
+	}
+}
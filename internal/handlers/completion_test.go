@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleteHandler(t *testing.T) {
+	router, chatService, cleanup := setupAPITest(t)
+	defer cleanup()
+
+	registry := services.NewProviderRegistry(nil)
+	require.NoError(t, registry.Register(&mockAIProvider{name: "mock", healthy: true}))
+	require.NoError(t, registry.Register(&mockAIProvider{name: "down", healthy: false}))
+	completionService := services.NewCompletionService(chatService, registry, nil, func(string) (time.Duration, time.Duration) {
+		return time.Minute, time.Minute
+	})
+	router.POST("/api/complete", NewAPIHandlers(nil).CompleteHandler(completionService))
+
+	complete := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/complete", strings.NewReader(body)))
+		return w
+	}
+
+	w := complete(`{"provider": "mock", "prompt": "Status report\nfor today", "stream": false}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data models.Completion `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Mock streaming response", response.Data.Content)
+	assert.True(t, response.Data.Ephemeral)
+
+	// The temporary chat is in the trash, titled after the prompt
+	ctx := context.Background()
+	require.NoError(t, chatService.RestoreChat(ctx, response.Data.ChatID))
+	chat, err := chatService.GetChat(ctx, response.Data.ChatID)
+	require.NoError(t, err)
+	assert.Equal(t, "Status report", chat.Title)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, complete(`{"provider": "mock", "prompt": "Hi", "stream": true}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, complete(`{"provider": "mock"}`).Code)
+	assert.Equal(t, http.StatusNotFound, complete(`{"provider": "missing", "prompt": "Hi"}`).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, complete(`{"provider": "down", "prompt": "Hi"}`).Code)
+}
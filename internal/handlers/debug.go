@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/buildinfo"
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DebugInfoHandler returns build information, runtime statistics and the configuration summary
+// for diagnosing a running instance. Secrets are redacted from the summary.
+func DebugInfoHandler(cfg *config.Config, build buildinfo.BuildInfo, startedAt time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		var summary []string
+		for _, line := range strings.Split(strings.TrimSpace(config.ConfigSummary(cfg)), "\n") {
+			summary = append(summary, utils.Redact(line))
+		}
+
+		uptime := time.Since(startedAt)
+		c.JSON(http.StatusOK, gin.H{
+			"build":          build,
+			"environment":    string(config.GetCurrentEnvironment()),
+			"started_at":     startedAt,
+			"uptime":         uptime.Round(time.Second).String(),
+			"uptime_seconds": int64(uptime.Seconds()),
+			"runtime": gin.H{
+				"goroutines": runtime.NumGoroutine(),
+				"num_cpu":    runtime.NumCPU(),
+				"gomaxprocs": runtime.GOMAXPROCS(0),
+				"os":         runtime.GOOS,
+				"arch":       runtime.GOARCH,
+			},
+			"memory": gin.H{
+				"alloc_bytes":       mem.Alloc,
+				"total_alloc_bytes": mem.TotalAlloc,
+				"sys_bytes":         mem.Sys,
+				"heap_inuse_bytes":  mem.HeapInuse,
+				"heap_objects":      mem.HeapObjects,
+				"num_gc":            mem.NumGC,
+				"gc_pause_total_ns": mem.PauseTotalNs,
+			},
+			"config": summary,
+		})
+	}
+}
+
+// PprofHandler serves the net/http/pprof profiles below a route with a *profile parameter,
+// e.g. /api/debug/pprof/heap or /api/debug/pprof/profile?seconds=30
+func PprofHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch name := strings.Trim(c.Param("profile"), "/"); name {
+		case "":
+			pprof.Index(c.Writer, c.Request)
+		case "cmdline":
+			pprof.Cmdline(c.Writer, c.Request)
+		case "profile":
+			pprof.Profile(c.Writer, c.Request)
+		case "symbol":
+			pprof.Symbol(c.Writer, c.Request)
+		case "trace":
+			pprof.Trace(c.Writer, c.Request)
+		default:
+			pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+		}
+	}
+}
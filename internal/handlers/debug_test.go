@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/buildinfo"
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugInfoHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	utils.RegisterSecret("debug-info-redis-password")
+	cfg := &config.Config{Port: "8080", RedisAddr: "debug-info-redis-password@localhost:6379"}
+	build := buildinfo.BuildInfo{Version: "1.2.3", Commit: "abc123", GoVersion: "go1.23"}
+
+	router := gin.New()
+	router.GET("/api/debug/info", DebugInfoHandler(cfg, build, time.Now().Add(-time.Minute)))
+	router.GET("/api/debug/pprof/*profile", PprofHandler())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/debug/info", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var info struct {
+		Build         buildinfo.BuildInfo `json:"build"`
+		UptimeSeconds int64               `json:"uptime_seconds"`
+		Runtime       map[string]any      `json:"runtime"`
+		Memory        map[string]any      `json:"memory"`
+		Config        []string            `json:"config"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "abc123", info.Build.Commit)
+	assert.GreaterOrEqual(t, info.UptimeSeconds, int64(60))
+	assert.NotZero(t, info.Runtime["goroutines"])
+	assert.NotZero(t, info.Memory["sys_bytes"])
+	assert.Contains(t, info.Config, "Port: 8080")
+	assert.NotContains(t, w.Body.String(), "debug-info-redis-password", "secrets are redacted from the summary")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/debug/pprof/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/debug/pprof/goroutine?debug=1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine profile")
+}
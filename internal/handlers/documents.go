@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// collectionRequest is the body of POST /api/collections
+type collectionRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// GetCollectionsHandler lists the document collections
+func (h *APIHandlers) GetCollectionsHandler(documentService *services.DocumentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collections, err := documentService.ListCollections(c.Request.Context())
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get collections", err)
+			return
+		}
+
+		h.errorHandler.Success(c, collections)
+	}
+}
+
+// CreateCollectionHandler creates an empty document collection
+func (h *APIHandlers) CreateCollectionHandler(documentService *services.DocumentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req collectionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		collection, err := documentService.CreateCollection(c.Request.Context(), req.Name, req.Description)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to create collection", err)
+			return
+		}
+
+		h.errorHandler.Created(c, collection, "Collection created successfully")
+	}
+}
+
+// DeleteCollectionHandler deletes a collection with its documents
+func (h *APIHandlers) DeleteCollectionHandler(documentService *services.DocumentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collectionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid collection ID", err)
+			return
+		}
+
+		if err := documentService.DeleteCollection(c.Request.Context(), collectionID); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to delete collection", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Collection deleted successfully")
+	}
+}
+
+// GetDocumentsHandler lists the documents of a collection
+func (h *APIHandlers) GetDocumentsHandler(documentService *services.DocumentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collectionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid collection ID", err)
+			return
+		}
+
+		docs, err := documentService.ListDocuments(c.Request.Context(), collectionID)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to get documents", err)
+			return
+		}
+
+		h.errorHandler.Success(c, docs)
+	}
+}
+
+// UploadDocumentHandler indexes a text, markdown or PDF file uploaded as the multipart field "file"
+// into a collection
+func (h *APIHandlers) UploadDocumentHandler(documentService *services.DocumentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collectionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid collection ID", err)
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, documentService.MaxSize()+attachmentUploadOverhead)
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				h.errorHandler.PayloadTooLarge(c, "Document is too large")
+				return
+			}
+			h.errorHandler.BadRequest(c, "Missing file", err)
+			return
+		}
+		if fileHeader.Size > documentService.MaxSize() {
+			h.errorHandler.PayloadTooLarge(c, "Document is too large")
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Failed to read file", err)
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Failed to read file", err)
+			return
+		}
+
+		doc, err := documentService.AddDocument(c.Request.Context(), collectionID, fileHeader.Filename, data)
+		switch {
+		case errors.Is(err, services.ErrDocumentTooLarge):
+			h.errorHandler.PayloadTooLarge(c, "Document is too large")
+		case err != nil:
+			h.errorHandler.ServiceError(c, "Failed to add document", err)
+		default:
+			h.errorHandler.Created(c, doc, "Document added successfully")
+		}
+	}
+}
+
+// DeleteDocumentHandler removes a document from a collection
+func (h *APIHandlers) DeleteDocumentHandler(documentService *services.DocumentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collectionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid collection ID", err)
+			return
+		}
+		documentID, err := strconv.ParseInt(c.Param("documentId"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid document ID", err)
+			return
+		}
+
+		if err := documentService.DeleteDocument(c.Request.Context(), collectionID, documentID); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to delete document", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Document deleted successfully")
+	}
+}
+
+// SearchCollectionHandler returns the chunks of a collection closest to a query, best first
+// (?q=...&k=4), to check what prompts would retrieve
+func (h *APIHandlers) SearchCollectionHandler(documentService *services.DocumentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collectionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid collection ID", err)
+			return
+		}
+		query := c.Query("q")
+		if query == "" {
+			h.errorHandler.BadRequest(c, "Missing query", nil)
+			return
+		}
+		k := 4
+		if s := c.Query("k"); s != "" {
+			k, err = strconv.Atoi(s)
+			if err != nil || k <= 0 || k > 20 {
+				h.errorHandler.BadRequest(c, "Invalid k, expected 1 to 20", err)
+				return
+			}
+		}
+
+		chunks, err := documentService.Search(c.Request.Context(), collectionID, query, k)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to search collection", err)
+			return
+		}
+
+		h.errorHandler.Success(c, chunks)
+	}
+}
+
+// GetChatCollectionHandler returns the collection a chat retrieves context from, or null
+func (h *APIHandlers) GetChatCollectionHandler(documentService *services.DocumentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		collection, err := documentService.ChatCollection(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to get chat collection", err)
+			return
+		}
+
+		h.errorHandler.Success(c, collection)
+	}
+}
+
+// SetChatCollectionHandler links a chat to a collection; a null collection_id unlinks it
+func (h *APIHandlers) SetChatCollectionHandler(documentService *services.DocumentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		var req struct {
+			CollectionID *int64 `json:"collection_id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		if req.CollectionID == nil {
+			err = documentService.UnlinkChat(c.Request.Context(), chatID)
+		} else {
+			err = documentService.LinkChat(c.Request.Context(), chatID, *req.CollectionID)
+		}
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to update chat collection", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Chat collection updated successfully")
+	}
+}
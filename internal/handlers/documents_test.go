@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/documents"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Questions", "claude")
+	require.NoError(t, err)
+	documentService := services.NewDocumentService(db, chatService, documents.NewHashEmbedder(256), services.NewDatabaseVectorStore(db),
+		services.DocumentOptions{MaxSize: 1024, ChunkSize: 200, ChunkOverlap: 20, TopK: 2})
+
+	apiHandlers := NewAPIHandlers(nil)
+	router := gin.New()
+	router.POST("/api/collections", apiHandlers.CreateCollectionHandler(documentService))
+	router.POST("/api/collections/:id/documents", apiHandlers.UploadDocumentHandler(documentService))
+	router.GET("/api/collections/:id/search", apiHandlers.SearchCollectionHandler(documentService))
+	router.PUT("/api/chats/:id/collection", apiHandlers.SetChatCollectionHandler(documentService))
+	router.GET("/api/chats/:id/collection", apiHandlers.GetChatCollectionHandler(documentService))
+
+	serve := func(method, path, contentType string, body io.Reader) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, body)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	upload := func(path, filename, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("file", filename)
+		require.NoError(t, err)
+		part.Write([]byte(content))
+		require.NoError(t, form.Close())
+		return serve(http.MethodPost, path, form.FormDataContentType(), &body)
+	}
+
+	w := serve(http.MethodPost, "/api/collections", "application/json", strings.NewReader(`{"name": "Runbooks"}`))
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.DocumentCollection `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	collectionPath := "/api/collections/" + strconv.FormatInt(created.Data.ID, 10)
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPost, "/api/collections", "application/json", strings.NewReader(`{"name": "Runbooks"}`)).Code)
+
+	assert.Equal(t, http.StatusCreated, upload(collectionPath+"/documents", "restart.txt", "Restart the gateway with systemctl restart aigwhub.").Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload(collectionPath+"/documents", "big.txt", strings.Repeat("x", 2048)).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, upload(collectionPath+"/documents", "image.bin", "\xff\xd8\xff\xe0").Code)
+	assert.Equal(t, http.StatusNotFound, upload("/api/collections/999/documents", "restart.txt", "Restart").Code)
+
+	w = serve(http.MethodGet, collectionPath+"/search?q=how+to+restart+the+gateway", "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var found struct {
+		Data []models.DocumentChunk `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &found))
+	require.Len(t, found.Data, 1)
+	assert.Equal(t, "restart.txt", found.Data[0].Filename)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, collectionPath+"/search", "", nil).Code)
+
+	chatPath := "/api/chats/" + strconv.FormatInt(chat.ID, 10) + "/collection"
+	body := `{"collection_id": ` + strconv.FormatInt(created.Data.ID, 10) + `}`
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, chatPath, "application/json", strings.NewReader(body)).Code)
+	assert.Contains(t, serve(http.MethodGet, chatPath, "", nil).Body.String(), `"name":"Runbooks"`)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, chatPath, "application/json", strings.NewReader(`{"collection_id": null}`)).Code)
+	assert.Contains(t, serve(http.MethodGet, chatPath, "", nil).Body.String(), `"data":null`)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPut, "/api/chats/999/collection", "application/json", strings.NewReader(body)).Code)
+}
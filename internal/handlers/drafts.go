@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"strconv"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// draftRequest is the body of PUT /api/chats/:id/draft
+type draftRequest struct {
+	Content string `json:"content"`
+}
+
+// clearDraft drops the draft of the chat a prompt was saved in; failures are only logged, the draft
+// then stays until it expires
+func (c *Client) clearDraft(userMsg *models.Message) {
+	if c.hub.draftService == nil || userMsg == nil {
+		return
+	}
+	if err := c.hub.draftService.Clear(c.ctx, userMsg.ChatID); err != nil {
+		utils.Warn("[request_id=%s] Failed to clear draft of chat %d: %v", c.requestID, userMsg.ChatID, err)
+	}
+}
+
+// GetChatDraftHandler returns the prompt draft of a chat, with empty content when it has none
+func (h *APIHandlers) GetChatDraftHandler(draftService *services.DraftService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		draft, err := draftService.Get(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to get draft", err)
+			return
+		}
+
+		h.errorHandler.Success(c, draft)
+	}
+}
+
+// SaveChatDraftHandler replaces the prompt draft of a chat; empty content clears it
+func (h *APIHandlers) SaveChatDraftHandler(draftService *services.DraftService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		var req draftRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		draft, err := draftService.Save(c.Request.Context(), chatID, req.Content)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to save draft", err)
+			return
+		}
+
+		h.errorHandler.Success(c, draft)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatDraftHandlers(t *testing.T) {
+	router, chatService, cleanup := setupAPITest(t)
+	defer cleanup()
+
+	draftService := services.NewDraftService(services.NewMemoryDraftStore(), chatService, time.Hour)
+	apiHandlers := NewAPIHandlers(nil)
+	router.GET("/api/chats/:id/draft", apiHandlers.GetChatDraftHandler(draftService))
+	router.PUT("/api/chats/:id/draft", apiHandlers.SaveChatDraftHandler(draftService))
+
+	request := func(method, id, body string) (*httptest.ResponseRecorder, models.Draft) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/api/chats/"+id+"/draft", strings.NewReader(body)))
+		var response struct {
+			Data models.Draft `json:"data"`
+		}
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		}
+		return w, response.Data
+	}
+
+	ctx := context.Background()
+	chat, err := chatService.CreateChat(ctx, "Drafts", "claude")
+	require.NoError(t, err)
+	chatID := strconv.FormatInt(chat.ID, 10)
+
+	w, draft := request(http.MethodGet, chatID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, draft.Content)
+
+	w, draft = request(http.MethodPut, chatID, `{"content": "Unfinished"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Unfinished", draft.Content)
+	assert.NotNil(t, draft.ExpiresAt)
+
+	w, draft = request(http.MethodGet, chatID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Unfinished", draft.Content)
+
+	w, _ = request(http.MethodPut, chatID, `{"content": 1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w, _ = request(http.MethodGet, "999", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = request(http.MethodGet, "abc", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Sending a prompt in the chat clears its draft
+	hub := NewHub(nil, chatService, nil, nil, nil, nil)
+	hub.SetDrafts(draftService)
+	client := addTestClient(hub, chat.ID, false)
+	userMsg, err := chatService.AddUserMessage(ctx, chat.ID, "Finished", "")
+	require.NoError(t, err)
+	client.clearDraft(userMsg)
+
+	w, draft = request(http.MethodGet, chatID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, draft.Content)
+}
@@ -1,18 +1,25 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"log"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ErrorResponse represents a standardized error response
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Code    string `json:"code,omitempty"`
-	Details string `json:"details,omitempty"`
+	Error     string `json:"error"`
+	Code      string `json:"code,omitempty"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // ErrorHandler provides standardized error handling for HTTP handlers
@@ -79,17 +86,19 @@ func (eh *ErrorHandler) BadRequest(c *gin.Context, message string, err error) {
 	}
 	
 	c.JSON(http.StatusBadRequest, ErrorResponse{
-		Error:   message,
-		Code:    "BAD_REQUEST",
-		Details: eh.sanitizeErrorDetails(err),
+		Error:     message,
+		Code:      "BAD_REQUEST",
+		Details:   eh.sanitizeErrorDetails(err),
+		RequestID: requestID(c),
 	})
 }
 
 // NotFound handles 404 Not Found errors
 func (eh *ErrorHandler) NotFound(c *gin.Context, message string) {
 	c.JSON(http.StatusNotFound, ErrorResponse{
-		Error: message,
-		Code:  "NOT_FOUND",
+		Error:     message,
+		Code:      "NOT_FOUND",
+		RequestID: requestID(c),
 	})
 }
 
@@ -98,9 +107,10 @@ func (eh *ErrorHandler) InternalError(c *gin.Context, message string, err error)
 	eh.logError(c, "Internal Server Error", err)
 	
 	c.JSON(http.StatusInternalServerError, ErrorResponse{
-		Error:   message,
-		Code:    "INTERNAL_ERROR",
-		Details: eh.sanitizeErrorDetails(err),
+		Error:     message,
+		Code:      "INTERNAL_ERROR",
+		Details:   eh.sanitizeErrorDetails(err),
+		RequestID: requestID(c),
 	})
 }
 
@@ -111,9 +121,10 @@ func (eh *ErrorHandler) ValidationError(c *gin.Context, message string, err erro
 	}
 	
 	c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
-		Error:   message,
-		Code:    "VALIDATION_ERROR",
-		Details: eh.sanitizeErrorDetails(err),
+		Error:     message,
+		Code:      "VALIDATION_ERROR",
+		Details:   eh.sanitizeErrorDetails(err),
+		RequestID: requestID(c),
 	})
 }
 
@@ -124,24 +135,105 @@ func (eh *ErrorHandler) ConflictError(c *gin.Context, message string, err error)
 	}
 	
 	c.JSON(http.StatusConflict, ErrorResponse{
-		Error:   message,
-		Code:    "CONFLICT",
-		Details: eh.sanitizeErrorDetails(err),
+		Error:     message,
+		Code:      "CONFLICT",
+		Details:   eh.sanitizeErrorDetails(err),
+		RequestID: requestID(c),
+	})
+}
+
+// Unauthorized handles 401 Unauthorized errors
+func (eh *ErrorHandler) Unauthorized(c *gin.Context, message string) {
+	c.JSON(http.StatusUnauthorized, ErrorResponse{
+		Error:     message,
+		Code:      "UNAUTHORIZED",
+		RequestID: requestID(c),
+	})
+}
+
+// PayloadTooLarge handles 413 Request Entity Too Large errors
+func (eh *ErrorHandler) PayloadTooLarge(c *gin.Context, message string) {
+	c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+		Error:     message,
+		Code:      "PAYLOAD_TOO_LARGE",
+		RequestID: requestID(c),
 	})
 }
 
+// ServiceUnavailable handles 503 Service Unavailable errors
+func (eh *ErrorHandler) ServiceUnavailable(c *gin.Context, message string, err error) {
+	eh.logError(c, "Service Unavailable", err)
+
+	c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+		Error:     message,
+		Code:      "SERVICE_UNAVAILABLE",
+		Details:   eh.sanitizeErrorDetails(err),
+		RequestID: requestID(c),
+	})
+}
+
+// GatewayTimeout handles 504 Gateway Timeout errors, for providers that didn't answer in time
+func (eh *ErrorHandler) GatewayTimeout(c *gin.Context, message string, err error) {
+	eh.logError(c, "Gateway Timeout", err)
+
+	c.JSON(http.StatusGatewayTimeout, ErrorResponse{
+		Error:     message,
+		Code:      "TIMEOUT",
+		Details:   eh.sanitizeErrorDetails(err),
+		RequestID: requestID(c),
+	})
+}
+
+// ServiceError maps an error returned by a service to its status code: not found errors to 404,
+// conflicts to 409 and validation errors to 422, each described by the error's own message.
+// Any other error is a 500 described by message.
+func (eh *ErrorHandler) ServiceError(c *gin.Context, message string, err error) {
+	var serviceErr *apperrors.Error
+	if !errors.As(err, &serviceErr) {
+		eh.InternalError(c, message, err)
+		return
+	}
+
+	description := capitalize(serviceErr.Message)
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+		eh.NotFound(c, description)
+	case errors.Is(err, apperrors.ErrConflict):
+		eh.ConflictError(c, description, err)
+	case errors.Is(err, apperrors.ErrValidation):
+		eh.ValidationError(c, description, err)
+	default:
+		eh.InternalError(c, message, err)
+	}
+}
+
+// capitalize upper-cases the first letter of a service error message for use in a response
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
 // logError logs the error with context information
 func (eh *ErrorHandler) logError(c *gin.Context, errorType string, err error) {
 	if eh.logger != nil && err != nil {
-		eh.logger.Printf("[%s] %s %s - %v", 
+		eh.logger.Printf("[%s] %s %s request_id=%s - %v", 
 			errorType, 
 			c.Request.Method, 
 			c.Request.URL.Path, 
+			requestID(c),
 			err,
 		)
 	}
 }
 
+// requestID returns the ID assigned to the request by the request ID middleware
+func requestID(c *gin.Context) string {
+	return utils.RequestIDFromContext(c.Request.Context())
+}
+
 // SuccessResponse represents a standardized success response
 type SuccessResponse struct {
 	Message string      `json:"message,omitempty"`
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorResponseIncludesRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eh := NewErrorHandler(nil)
+
+	router := gin.New()
+	router.GET("/fail", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(utils.WithRequestID(c.Request.Context(), "req-123"))
+		eh.InternalError(c, "Something broke", errors.New("boom"))
+	})
+	router.GET("/missing", func(c *gin.Context) {
+		eh.NotFound(c, "Not here")
+	})
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	var body ErrorResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, "req-123", body.RequestID)
+	assert.Equal(t, "INTERNAL_ERROR", body.Code)
+
+	// Without the middleware the field is omitted
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.NotContains(t, resp.Body.String(), "request_id")
+}
+
+func TestServiceErrorMapsKinds(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eh := NewErrorHandler(nil)
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"not found", services.ErrChatNotFound, http.StatusNotFound, "NOT_FOUND", "Chat not found"},
+		{"conflict", services.ErrFolderExists, http.StatusConflict, "CONFLICT", "Folder already exists"},
+		{"wrapped validation", fmt.Errorf("%w: rating must be 1 or -1", services.ErrInvalidFeedback), http.StatusUnprocessableEntity, "VALIDATION_ERROR", "Invalid feedback"},
+		{"unexpected", errors.New("disk I/O error"), http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to do it"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", func(c *gin.Context) {
+				eh.ServiceError(c, "Failed to do it", tt.err)
+			})
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, tt.status, resp.Code)
+
+			var body ErrorResponse
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body.Code)
+			assert.Equal(t, tt.message, body.Error)
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/buildinfo"
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Maximum number of messages included in an export
+	MaxExportMessages = 10000
+)
+
+// ExportChatHandler exports a conversation as JSON (default), Markdown or standalone HTML
+func (h *APIHandlers) ExportChatHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		chat, err := chatService.GetChat(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.NotFound(c, "Chat not found")
+			return
+		}
+
+		messages, err := chatService.GetMessages(c.Request.Context(), chatID, MaxExportMessages, 0)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get messages", err)
+			return
+		}
+
+		format := c.DefaultQuery("format", "json")
+		filename := fmt.Sprintf("chat_%d", chat.ID)
+
+		switch format {
+		case "json":
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+			h.errorHandler.Success(c, gin.H{
+				"chat":     chat,
+				"messages": messages,
+				"build":    buildinfo.Get(nil),
+			})
+		case "markdown", "md":
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.md"`, filename))
+			c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(renderChatMarkdown(GetLang(c), chat, messages)))
+		case "html":
+			body, err := renderChatHTML(GetLang(c), chat, messages)
+			if err != nil {
+				h.errorHandler.InternalError(c, "Failed to render export", err)
+				return
+			}
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.html"`, filename))
+			c.Data(http.StatusOK, "text/html; charset=utf-8", body)
+		default:
+			h.errorHandler.BadRequest(c, "Unsupported export format. Supported formats: json, markdown, html", nil)
+		}
+	}
+}
+
+// roleLabel returns the localized label for a message role
+func roleLabel(lang string, msg *models.Message) string {
+	switch msg.Role {
+	case "user":
+		return i18n.T(lang, "export.user")
+	case "assistant":
+		if msg.Provider != "" {
+			return msg.Provider
+		}
+		return i18n.T(lang, "export.assistant")
+	default:
+		return i18n.T(lang, "export.system")
+	}
+}
+
+// RenderChatExport renders a conversation in an export format (json, markdown or html) outside
+// of a request, e.g. for the export command
+func RenderChatExport(lang, format string, chat *models.Chat, messages []*models.Message) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(gin.H{
+			"chat":     chat,
+			"messages": messages,
+			"build":    buildinfo.Get(nil),
+		}, "", "  ")
+	case "markdown", "md":
+		return []byte(renderChatMarkdown(lang, chat, messages)), nil
+	case "html":
+		return renderChatHTML(lang, chat, messages)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q (expected json, markdown or html)", format)
+	}
+}
+
+// renderChatMarkdown renders a conversation as Markdown
+func renderChatMarkdown(lang string, chat *models.Chat, messages []*models.Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", chat.Title)
+	fmt.Fprintf(&b, "- %s: %s\n", i18n.T(lang, "export.provider"), chat.Provider)
+	fmt.Fprintf(&b, "- %s: %s\n", i18n.T(lang, "export.exportedAt"), i18n.FormatDateTime(lang, time.Now()))
+	fmt.Fprintf(&b, "- %s: %s\n\n", i18n.T(lang, "export.hubVersion"), buildinfo.Get(nil).String())
+
+	for _, msg := range messages {
+		fmt.Fprintf(&b, "## %s\n\n%s\n\n", roleLabel(lang, msg), msg.Content)
+	}
+
+	return b.String()
+}
+
+// contentSegment is a piece of message content, either prose or a fenced code block
+type contentSegment struct {
+	Code     bool
+	Language string
+	Text     string
+}
+
+// splitCodeBlocks splits message content on ``` fences so code can be styled separately
+func splitCodeBlocks(content string) []contentSegment {
+	var segments []contentSegment
+	parts := strings.Split(content, "```")
+	for i, part := range parts {
+		if i%2 == 0 {
+			if strings.TrimSpace(part) != "" {
+				segments = append(segments, contentSegment{Text: strings.Trim(part, "\n")})
+			}
+			continue
+		}
+
+		// Odd parts are inside a fence; the first line is the optional language tag
+		lang, code, found := strings.Cut(part, "\n")
+		if !found {
+			lang, code = "", part
+		}
+		segments = append(segments, contentSegment{
+			Code:     true,
+			Language: strings.TrimSpace(lang),
+			Text:     strings.TrimRight(code, "\n"),
+		})
+	}
+	return segments
+}
+
+// exportMessage is the view model for a single message in the HTML export
+type exportMessage struct {
+	Role      string
+	Label     string
+	CreatedAt string
+	Segments  []contentSegment
+}
+
+// renderChatHTML renders a conversation as a standalone HTML document with inline styling and
+// highlighted code blocks
+func renderChatHTML(lang string, chat *models.Chat, messages []*models.Message) ([]byte, error) {
+	items := make([]exportMessage, 0, len(messages))
+	for _, msg := range messages {
+		items = append(items, exportMessage{
+			Role:      msg.Role,
+			Label:     roleLabel(lang, msg),
+			CreatedAt: i18n.FormatDateTime(lang, msg.CreatedAt),
+			Segments:  splitCodeBlocks(msg.Content),
+		})
+	}
+
+	data := map[string]any{
+		"Lang":            lang,
+		"Title":           chat.Title,
+		"Provider":        chat.Provider,
+		"ExportedAt":      i18n.FormatDateTime(lang, time.Now()),
+		"ProviderLabel":   i18n.T(lang, "export.provider"),
+		"ExportedAtLabel": i18n.T(lang, "export.exportedAt"),
+		"HubVersion":      buildinfo.Get(nil).String(),
+		"HubVersionLabel": i18n.T(lang, "export.hubVersion"),
+		"Footer":          i18n.T(lang, "export.footer"),
+		"Messages":        items,
+	}
+
+	var buf bytes.Buffer
+	if err := exportHTMLTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var exportHTMLTemplate = template.Must(template.New("export").Funcs(template.FuncMap{"highlight": highlightCode}).Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; padding: 2rem 1rem; background: #f9fafb; color: #111827; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, "Hiragino Sans", "Noto Sans JP", sans-serif; line-height: 1.6; }
+main { max-width: 48rem; margin: 0 auto; }
+h1 { font-size: 1.5rem; margin: 0 0 .25rem; }
+.meta { color: #6b7280; font-size: .875rem; margin-bottom: 2rem; }
+.message { border-radius: .5rem; padding: 1rem; margin-bottom: 1rem; border: 1px solid #e5e7eb; background: #fff; }
+.message.user { background: #eff6ff; border-color: #bfdbfe; }
+.message.system { background: #fefce8; border-color: #fde68a; }
+.label { font-weight: 600; font-size: .875rem; margin-bottom: .5rem; display: flex; justify-content: space-between; }
+.label time { font-weight: 400; color: #9ca3af; }
+.text { white-space: pre-wrap; word-wrap: break-word; margin: 0 0 .5rem; }
+pre { background: #1f2937; color: #f3f4f6; padding: .75rem 1rem; border-radius: .375rem; overflow-x: auto; font-size: .8125rem; margin: .5rem 0; }
+pre .lang { display: block; color: #9ca3af; font-size: .75rem; margin-bottom: .25rem; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; }
+.tok-k { color: #c4b5fd; font-weight: 600; }
+.tok-s { color: #86efac; }
+.tok-n { color: #fdba74; }
+.tok-c { color: #9ca3af; font-style: italic; }
+footer { color: #9ca3af; font-size: .75rem; text-align: center; margin-top: 2rem; }
+@media (prefers-color-scheme: dark) {
+  body { background: #111827; color: #f3f4f6; }
+  .message { background: #1f2937; border-color: #374151; }
+  .message.user { background: #1e3a8a; border-color: #1e40af; }
+  .message.system { background: #422006; border-color: #713f12; }
+  pre { background: #030712; }
+}
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+<div class="meta">{{.ProviderLabel}}: {{.Provider}} &middot; {{.ExportedAtLabel}}: {{.ExportedAt}}</div>
+{{range .Messages}}
+<section class="message {{.Role}}">
+<div class="label"><span>{{.Label}}</span><time>{{.CreatedAt}}</time></div>
+{{range .Segments}}{{if .Code}}<pre><code>{{if .Language}}<span class="lang">{{.Language}}</span>{{end}}{{highlight .Language .Text}}</code></pre>{{else}}<p class="text">{{.Text}}</p>{{end}}
+{{end}}</section>
+{{end}}
+<footer>{{.Footer}} &middot; {{.HubVersionLabel}}: {{.HubVersion}}</footer>
+</main>
+</body>
+</html>
+`))
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"html/template"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// codeSyntax is what the export's highlighter knows about a language
+type codeSyntax struct {
+	lineComments    []string
+	blockComment    [2]string
+	keywords        map[string]bool
+	caseInsensitive bool
+}
+
+func keywordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+var (
+	cStyleComments = codeSyntax{lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}}
+
+	// Unknown languages get comments, strings and numbers but no keywords
+	defaultSyntax = codeSyntax{lineComments: []string{"//", "#"}, blockComment: [2]string{"/*", "*/"}}
+
+	codeSyntaxes = map[string]codeSyntax{
+		"go": withKeywords(cStyleComments, `break case chan const continue default defer else fallthrough for func go goto if
+			import interface map package range return select struct switch type var nil true false`),
+		"javascript": withKeywords(cStyleComments, `async await break case catch class const continue default delete do else export
+			extends false finally for function if import in instanceof let new null return static super switch this throw
+			true try typeof undefined var void while yield interface type enum implements`),
+		"python": {lineComments: []string{"#"}, keywords: keywordSet(`and as assert async await break class continue def del
+			elif else except False finally for from global if import in is lambda None nonlocal not or pass raise return
+			True try while with yield`)},
+		"rust": withKeywords(cStyleComments, `as async await break const continue crate dyn else enum extern false fn for if
+			impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use
+			where while`),
+		"java": withKeywords(cStyleComments, `abstract boolean break byte case catch char class const continue default do
+			double else enum extends final finally float for if implements import instanceof int interface long new null
+			package private protected public return short static super switch this throw throws true false try void
+			while`),
+		"c": withKeywords(cStyleComments, `auto break case char class const continue default delete do double else enum
+			extern float for if inline int long namespace new nullptr private protected public return short signed sizeof
+			static struct switch template this typedef union unsigned using virtual void volatile while true false`),
+		"bash": {lineComments: []string{"#"}, keywords: keywordSet(`case do done elif else esac export fi for function if in
+			local return select then until while`)},
+		"sql": {lineComments: []string{"--"}, blockComment: [2]string{"/*", "*/"}, caseInsensitive: true, keywords: keywordSet(`
+			add all alter and as asc begin between by case commit create delete desc distinct drop else end exists from
+			group having in index inner insert into is join key left like limit not null on or order outer primary
+			references right rollback select set table then union unique update values view when where with`)},
+	}
+
+	// Fence tags that name the same language
+	codeLanguageAliases = map[string]string{
+		"golang": "go", "js": "javascript", "jsx": "javascript", "ts": "javascript", "tsx": "javascript",
+		"typescript": "javascript", "py": "python", "python3": "python", "rs": "rust", "kotlin": "java",
+		"cpp": "c", "c++": "c", "h": "c", "cs": "java", "csharp": "java", "sh": "bash", "shell": "bash",
+		"zsh": "bash", "console": "bash", "postgresql": "sql", "mysql": "sql", "sqlite": "sql",
+	}
+)
+
+func withKeywords(syntax codeSyntax, words string) codeSyntax {
+	syntax.keywords = keywordSet(words)
+	return syntax
+}
+
+// syntaxFor looks up the syntax of a fence's language tag
+func syntaxFor(language string) codeSyntax {
+	language = strings.ToLower(language)
+	if alias, ok := codeLanguageAliases[language]; ok {
+		language = alias
+	}
+	if syntax, ok := codeSyntaxes[language]; ok {
+		return syntax
+	}
+	return defaultSyntax
+}
+
+// highlightCode marks up a fenced code block for the HTML export: comments, strings, numbers and
+// keywords are wrapped in spans the export's stylesheet colours, everything else is escaped as is
+func highlightCode(language, code string) template.HTML {
+	syntax := syntaxFor(language)
+	var b strings.Builder
+	token := func(class, text string) {
+		b.WriteString(`<span class="tok-` + class + `">`)
+		b.WriteString(template.HTMLEscapeString(text))
+		b.WriteString(`</span>`)
+	}
+
+	for i := 0; i < len(code); {
+		rest := code[i:]
+
+		if syntax.startsLineComment(rest) {
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			token("c", rest[:end])
+			i += end
+			continue
+		}
+
+		if start := syntax.blockComment[0]; start != "" && strings.HasPrefix(rest, start) {
+			end := strings.Index(rest[len(start):], syntax.blockComment[1])
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += len(start) + len(syntax.blockComment[1])
+			}
+			token("c", rest[:end])
+			i += end
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		switch {
+		case r == '"' || r == '\'' || r == '`':
+			end := stringEnd(rest, byte(r))
+			token("s", rest[:end])
+			i += end
+
+		case unicode.IsDigit(r):
+			end := strings.IndexFunc(rest, func(r rune) bool { return !isIdentRune(r) && r != '.' })
+			if end < 0 {
+				end = len(rest)
+			}
+			token("n", rest[:end])
+			i += end
+
+		case isIdentRune(r):
+			end := strings.IndexFunc(rest, func(r rune) bool { return !isIdentRune(r) })
+			if end < 0 {
+				end = len(rest)
+			}
+			word := rest[:end]
+			lookup := word
+			if syntax.caseInsensitive {
+				lookup = strings.ToLower(word)
+			}
+			if syntax.keywords[lookup] {
+				token("k", word)
+			} else {
+				b.WriteString(template.HTMLEscapeString(word))
+			}
+			i += end
+
+		default:
+			b.WriteString(template.HTMLEscapeString(rest[:size]))
+			i += size
+		}
+	}
+	return template.HTML(b.String())
+}
+
+// startsLineComment reports whether code starts with a line comment marker
+func (s codeSyntax) startsLineComment(code string) bool {
+	for _, prefix := range s.lineComments {
+		if strings.HasPrefix(code, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringEnd returns the length of the string literal code starts with. Quotes other than
+// backticks don't span lines, so a stray apostrophe only colours the rest of its line.
+func stringEnd(code string, quote byte) int {
+	for i := 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			return i + 1
+		case '\n':
+			if quote != '`' {
+				return i
+			}
+		}
+	}
+	return len(code)
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
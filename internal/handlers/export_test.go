@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []contentSegment
+	}{
+		{
+			name:     "plain text",
+			content:  "Hello there",
+			expected: []contentSegment{{Text: "Hello there"}},
+		},
+		{
+			name:    "text with fenced code",
+			content: "Try this:\n```go\nfmt.Println(\"hi\")\n```\nDone",
+			expected: []contentSegment{
+				{Text: "Try this:"},
+				{Code: true, Language: "go", Text: "fmt.Println(\"hi\")"},
+				{Text: "Done"},
+			},
+		},
+		{
+			name:    "fence without language",
+			content: "```\nls -la\n```",
+			expected: []contentSegment{
+				{Code: true, Text: "ls -la"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, splitCodeBlocks(tt.content))
+		})
+	}
+}
+
+func TestRenderChatExport(t *testing.T) {
+	require.NoError(t, i18n.Init("../../locales", "en"))
+	chat := &models.Chat{ID: 7, Title: "Release notes", Provider: "claude"}
+	messages := []*models.Message{
+		{ChatID: 7, Role: "user", Content: "Summarize the changes"},
+		{ChatID: 7, Role: "assistant", Content: "Two fixes", Provider: "claude"},
+	}
+
+	markdown, err := RenderChatExport("en", "md", chat, messages)
+	require.NoError(t, err)
+	assert.Contains(t, string(markdown), "# Release notes")
+	assert.Contains(t, string(markdown), "Two fixes")
+
+	data, err := RenderChatExport("en", "json", chat, messages)
+	require.NoError(t, err)
+	var export struct {
+		Chat     models.Chat       `json:"chat"`
+		Messages []*models.Message `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(data, &export))
+	assert.Equal(t, "Release notes", export.Chat.Title)
+	assert.Len(t, export.Messages, 2)
+
+	_, err = RenderChatExport("en", "pdf", chat, messages)
+	assert.ErrorContains(t, err, "unsupported export format")
+}
+
+func TestHighlightCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		code     string
+		expected string
+	}{
+		{
+			name:     "go keywords, strings and comments",
+			language: "go",
+			code:     "func main() { // entry\n\tfmt.Println(\"hi\", 42)\n}",
+			expected: "<span class=\"tok-k\">func</span> main() { <span class=\"tok-c\">// entry</span>\n\tfmt.Println(<span class=\"tok-s\">&#34;hi&#34;</span>, <span class=\"tok-n\">42</span>)\n}",
+		},
+		{
+			name:     "aliases and case-insensitive keywords",
+			language: "PostgreSQL",
+			code:     "SELECT id FROM chats -- all",
+			expected: "<span class=\"tok-k\">SELECT</span> id <span class=\"tok-k\">FROM</span> chats <span class=\"tok-c\">-- all</span>",
+		},
+		{
+			name:     "markup is escaped",
+			language: "js",
+			code:     "if (a < b) alert('<b>')",
+			expected: "<span class=\"tok-k\">if</span> (a &lt; b) alert(<span class=\"tok-s\">&#39;&lt;b&gt;&#39;</span>)",
+		},
+		{
+			name:     "unknown language has no keywords",
+			language: "",
+			code:     "if x # note",
+			expected: "if x <span class=\"tok-c\"># note</span>",
+		},
+		{
+			name:     "unterminated string stops at the line end",
+			language: "python",
+			code:     "x = 'oops\nreturn x",
+			expected: "x = <span class=\"tok-s\">&#39;oops</span>\n<span class=\"tok-k\">return</span> x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, string(highlightCode(tt.language, tt.code)))
+		})
+	}
+}
+
+func TestRenderChatHTML_HighlightsCodeBlocks(t *testing.T) {
+	require.NoError(t, i18n.Init("../../locales", "en"))
+	chat := &models.Chat{ID: 7, Title: "Snippets", Provider: "claude"}
+	messages := []*models.Message{
+		{ChatID: 7, Role: "assistant", Provider: "claude", Content: "Run this:\n```go\nreturn \"<done>\"\n```\nThat's it"},
+	}
+
+	body, err := RenderChatExport("en", "html", chat, messages)
+	require.NoError(t, err)
+	html := string(body)
+	assert.Contains(t, html, `<pre><code><span class="lang">go</span><span class="tok-k">return</span> <span class="tok-s">&#34;&lt;done&gt;&#34;</span></code></pre>`)
+	assert.Contains(t, html, ".tok-k {", "the token colours are embedded in the document")
+	assert.Contains(t, html, `<p class="text">Run this:</p>`)
+	assert.NotContains(t, html, "<done>")
+}
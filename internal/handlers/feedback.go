@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Default period reviewed by the feedback listing and summary
+const DefaultFeedbackWindow = 7 * 24 * time.Hour
+
+// RateMessageHandler sets the thumbs-up/down rating of an assistant message
+func (h *APIHandlers) RateMessageHandler(feedbackService *services.FeedbackService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		messageID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid message ID", err)
+			return
+		}
+
+		var req struct {
+			Rating  int    `json:"rating" binding:"required"` // 1 or -1
+			Comment string `json:"comment"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		feedback, err := feedbackService.Rate(messageID, req.Rating, req.Comment)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to save feedback", err)
+			return
+		}
+
+		h.errorHandler.Success(c, feedback, "Feedback saved successfully")
+	}
+}
+
+// ClearMessageFeedbackHandler removes the rating of a message
+func (h *APIHandlers) ClearMessageFeedbackHandler(feedbackService *services.FeedbackService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		messageID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid message ID", err)
+			return
+		}
+
+		if err := feedbackService.Clear(messageID); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to clear feedback", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Feedback cleared successfully")
+	}
+}
+
+// GetChatFeedbackHandler lists the ratings given in a chat
+func (h *APIHandlers) GetChatFeedbackHandler(chatService *services.ChatService, feedbackService *services.FeedbackService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		if _, err := chatService.GetChat(c.Request.Context(), chatID); err != nil {
+			h.errorHandler.NotFound(c, "Chat not found")
+			return
+		}
+
+		feedback, err := feedbackService.ForChat(chatID)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get feedback", err)
+			return
+		}
+
+		h.errorHandler.Success(c, feedback)
+	}
+}
+
+// GetFeedbackHandler lists recent ratings for review (?since=168h, ?rating=up|down, ?provider=, ?limit=50, max 200)
+func (h *APIHandlers) GetFeedbackHandler(feedbackService *services.FeedbackService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		since, ok := h.feedbackSince(c)
+		if !ok {
+			return
+		}
+
+		q := services.FeedbackQuery{Since: since, Provider: c.Query("provider"), Limit: 50}
+		switch c.Query("rating") {
+		case "":
+		case "up":
+			q.Rating = models.FeedbackUp
+		case "down":
+			q.Rating = models.FeedbackDown
+		default:
+			h.errorHandler.BadRequest(c, "Invalid rating, expected up or down", nil)
+			return
+		}
+		if l := c.Query("limit"); l != "" {
+			parsed, err := strconv.Atoi(l)
+			if err != nil || parsed <= 0 || parsed > 200 {
+				h.errorHandler.BadRequest(c, "Invalid limit", err)
+				return
+			}
+			q.Limit = parsed
+		}
+
+		feedback, err := feedbackService.List(q)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get feedback", err)
+			return
+		}
+
+		h.errorHandler.Success(c, feedback)
+	}
+}
+
+// GetFeedbackSummaryHandler aggregates ratings per provider and model, worst-rated first (?since=168h)
+func (h *APIHandlers) GetFeedbackSummaryHandler(feedbackService *services.FeedbackService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		since, ok := h.feedbackSince(c)
+		if !ok {
+			return
+		}
+
+		summary, err := feedbackService.GetSummary(since)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get feedback summary", err)
+			return
+		}
+
+		h.errorHandler.Success(c, summary)
+	}
+}
+
+// feedbackSince parses the ?since duration of feedback reviews; errors are reported to the client
+func (h *APIHandlers) feedbackSince(c *gin.Context) (time.Time, bool) {
+	window := DefaultFeedbackWindow
+	if s := c.Query("since"); s != "" {
+		parsed, err := time.ParseDuration(s)
+		if err != nil || parsed <= 0 {
+			h.errorHandler.BadRequest(c, "Invalid since duration", err)
+			return time.Time{}, false
+		}
+		window = parsed
+	}
+	return time.Now().Add(-window), true
+}
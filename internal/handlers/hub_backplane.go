@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// Redis pub/sub channel hub instances relay WebSocket messages through
+	HubBackplaneChannel = "aigwhub:ws:messages"
+
+	// Presence keys announcing each instance and its client count
+	hubInstanceKeyPrefix = "aigwhub:ws:instance:"
+	hubInstanceTTL       = 30 * time.Second
+	hubHeartbeatInterval = 10 * time.Second
+)
+
+// Audiences of relayed messages
+const (
+	hubScopeChat     = "chat"      // clients viewing a chat
+	hubScopeChatList = "chat_list" // clients subscribed to chat list changes
+)
+
+// hubEnvelope is a WebSocket message relayed between hub instances
+type hubEnvelope struct {
+	Instance string          `json:"instance"`
+	Scope    string          `json:"scope"`
+	ChatID   int64           `json:"chat_id,omitempty"`
+	Message  json.RawMessage `json:"message"`
+}
+
+// NewInstanceID returns an ID for this server instance derived from the host name
+func NewInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "hub"
+	}
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%x", host, time.Now().UnixNano())
+	}
+	return host + "-" + hex.EncodeToString(b)
+}
+
+// SetInstanceID overrides the generated instance ID; call it before Run
+func (h *Hub) SetInstanceID(id string) {
+	h.instanceID = id
+}
+
+// InstanceID returns the ID of this hub instance
+func (h *Hub) InstanceID() string {
+	return h.instanceID
+}
+
+// EnableBackplane relays chat and chat list messages through Redis so clients connected to
+// other instances receive them too
+func (h *Hub) EnableBackplane(redisClient *redis.Client) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	pubsub := redisClient.Subscribe(ctx, HubBackplaneChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		cancel()
+		return fmt.Errorf("failed to subscribe to hub backplane: %w", err)
+	}
+
+	h.mu.Lock()
+	h.backplane = redisClient
+	h.backplaneCancel = cancel
+	h.mu.Unlock()
+
+	go h.receiveBackplane(ctx, pubsub)
+	go h.heartbeat(ctx)
+
+	utils.Info("WebSocket hub backplane enabled (instance %s)", h.instanceID)
+	return nil
+}
+
+// StopBackplane unsubscribes from the backplane and withdraws this instance's presence
+func (h *Hub) StopBackplane() {
+	h.mu.Lock()
+	redisClient, cancel := h.backplane, h.backplaneCancel
+	h.backplane, h.backplaneCancel = nil, nil
+	h.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	redisClient.Del(context.Background(), hubInstanceKeyPrefix+h.instanceID)
+}
+
+// publish relays a message to the other instances; without a backplane it does nothing
+func (h *Hub) publish(scope string, chatID int64, data []byte) {
+	h.mu.RLock()
+	redisClient := h.backplane
+	h.mu.RUnlock()
+	if redisClient == nil {
+		return
+	}
+
+	envelope, err := json.Marshal(hubEnvelope{Instance: h.instanceID, Scope: scope, ChatID: chatID, Message: data})
+	if err != nil {
+		utils.Error("Failed to marshal hub envelope: %v", err)
+		return
+	}
+	if err := redisClient.Publish(context.Background(), HubBackplaneChannel, envelope).Err(); err != nil {
+		utils.Warn("Failed to publish to hub backplane: %v", err)
+	}
+}
+
+// receiveBackplane delivers messages published by other instances to local clients
+func (h *Hub) receiveBackplane(ctx context.Context, pubsub *redis.PubSub) {
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			h.handleEnvelope([]byte(msg.Payload))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// handleEnvelope delivers a relayed message unless this instance published it
+func (h *Hub) handleEnvelope(payload []byte) {
+	var envelope hubEnvelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		utils.Warn("Ignoring malformed hub backplane message: %v", err)
+		return
+	}
+	if envelope.Instance == h.instanceID {
+		return
+	}
+	h.deliverLocal(envelope.Scope, envelope.ChatID, envelope.Message, nil)
+}
+
+// deliverLocal sends a message to the matching clients of this instance, skipping except: the
+// clients subscribed to the chat, or those following the chat list. Delivery is best effort: a
+// slow client misses the message.
+func (h *Hub) deliverLocal(scope string, chatID int64, data []byte, except *Client) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	deliver := func(client *Client) {
+		if client == except {
+			return
+		}
+		select {
+		case client.send <- data:
+		default:
+			utils.Debug("Dropped %s message for slow client %p", scope, client)
+		}
+	}
+
+	switch scope {
+	case hubScopeChat:
+		for client := range h.rooms[chatID] {
+			deliver(client)
+		}
+	case hubScopeChatList:
+		for client := range h.clients {
+			client.mu.Lock()
+			subscribed := client.chatListSubscribed
+			client.mu.Unlock()
+			if subscribed {
+				deliver(client)
+			}
+		}
+	}
+}
+
+// broadcastToChat sends a chat message to every other client viewing the chat, on any instance
+func (h *Hub) broadcastToChat(chatID int64, data []byte, origin *Client) {
+	h.deliverLocal(hubScopeChat, chatID, data, origin)
+	h.publish(hubScopeChat, chatID, data)
+}
+
+// heartbeat keeps this instance's presence key and client count fresh
+func (h *Hub) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(hubHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		h.announce(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// announce writes this instance's presence key
+func (h *Hub) announce(ctx context.Context) {
+	h.mu.RLock()
+	redisClient := h.backplane
+	instance := models.HubInstance{ID: h.instanceID, Clients: len(h.clients), UpdatedAt: time.Now()}
+	h.mu.RUnlock()
+	if redisClient == nil {
+		return
+	}
+
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return
+	}
+	if err := redisClient.Set(ctx, hubInstanceKeyPrefix+h.instanceID, data, hubInstanceTTL).Err(); err != nil && ctx.Err() == nil {
+		utils.Warn("Failed to announce hub instance: %v", err)
+	}
+}
+
+// Instances lists the hub instances sharing the backplane (only this one without a backplane)
+func (h *Hub) Instances() ([]models.HubInstance, error) {
+	h.mu.RLock()
+	redisClient := h.backplane
+	self := models.HubInstance{ID: h.instanceID, Clients: len(h.clients), UpdatedAt: time.Now()}
+	h.mu.RUnlock()
+	if redisClient == nil {
+		return []models.HubInstance{self}, nil
+	}
+
+	ctx := context.Background()
+	instances := []models.HubInstance{self}
+	iter := redisClient.Scan(ctx, 0, hubInstanceKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		if strings.TrimPrefix(iter.Val(), hubInstanceKeyPrefix) == h.instanceID {
+			continue
+		}
+		data, err := redisClient.Get(ctx, iter.Val()).Bytes()
+		if err != nil {
+			// Instance expired between SCAN and GET
+			continue
+		}
+		var instance models.HubInstance
+		if err := json.Unmarshal(data, &instance); err == nil {
+			instances = append(instances, instance)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list hub instances: %w", err)
+	}
+
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances, nil
+}
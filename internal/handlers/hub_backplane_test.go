@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addTestClient registers a client directly, bypassing the WebSocket connection
+func addTestClient(hub *Hub, chatID int64, chatListSubscribed bool) *Client {
+	client := &Client{hub: hub, send: make(chan []byte, 4), gone: make(chan struct{}), chatListSubscribed: chatListSubscribed, ctx: context.Background()}
+	hub.clients[client] = true
+	if chatID > 0 {
+		hub.subscribe(client, chatID)
+	}
+	return client
+}
+
+func TestHub_BroadcastToChat(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	origin := addTestClient(hub, 1, false)
+	sameChat := addTestClient(hub, 1, false)
+	otherChat := addTestClient(hub, 2, true)
+
+	hub.broadcastToChat(1, []byte(`{"type":"ai_response"}`), origin)
+
+	assert.Len(t, origin.send, 0, "the origin client is sent the message directly")
+	assert.Len(t, sameChat.send, 1)
+	assert.Len(t, otherChat.send, 0)
+
+	hub.NotifyChatListChanged("created", 3)
+	assert.Len(t, sameChat.send, 1)
+	assert.Len(t, otherChat.send, 1)
+}
+
+func TestHub_ChatSubscriptions(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	tab := addTestClient(hub, 1, false)
+	otherTab := addTestClient(hub, 1, false)
+	otherTab.send = make(chan []byte, 8)
+
+	// A client can follow several chats and stop following one
+	otherTab.subscribeChat(2)
+	hub.broadcastToChat(1, []byte(`{"type":"ai_response"}`), tab)
+	hub.broadcastToChat(2, []byte(`{"type":"ai_response"}`), nil)
+	assert.Len(t, otherTab.send, 2)
+	assert.Len(t, tab.send, 0)
+
+	hub.unsubscribe(otherTab, 2)
+	hub.broadcastToChat(2, []byte(`{"type":"ai_response"}`), nil)
+	assert.Len(t, otherTab.send, 2)
+	assert.NotContains(t, hub.rooms, int64(2), "empty rooms are dropped")
+
+	// Another tab sees prompts sent from this one
+	tab.relayUserMessage(&models.Message{ID: 10, ChatID: 1, Role: "user", Content: "hi"}, "claude")
+	require.Len(t, otherTab.send, 3)
+	<-otherTab.send
+	<-otherTab.send
+	var relayed models.WebSocketMessage
+	require.NoError(t, json.Unmarshal(<-otherTab.send, &relayed))
+	assert.Equal(t, "user_message", relayed.Type)
+	assert.Equal(t, int64(10), relayed.Data.MessageID)
+	assert.Equal(t, "hi", relayed.Data.Content)
+	assert.Len(t, tab.send, 0)
+
+	// Unregistering leaves every room
+	go hub.Run()
+	hub.unregister <- otherTab
+	<-otherTab.gone
+	hub.mu.RLock()
+	assert.NotContains(t, hub.rooms[1], otherTab)
+	hub.mu.RUnlock()
+}
+
+func TestHub_SubscriptionLimit(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	client := addTestClient(hub, 0, false)
+
+	for chatID := int64(1); chatID <= MaxChatSubscriptions; chatID++ {
+		require.True(t, hub.subscribe(client, chatID))
+	}
+	assert.False(t, hub.subscribe(client, MaxChatSubscriptions+1))
+	assert.True(t, hub.subscribe(client, 1), "already subscribed chats don't count again")
+
+	client.subscribeChat(MaxChatSubscriptions + 1)
+	assert.Equal(t, "error", receiveFrame(t, client).Type)
+}
+
+func TestHub_HandleEnvelope(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	hub.SetInstanceID("replica-a")
+	viewer := addTestClient(hub, 7, false)
+
+	envelope := func(instance string) []byte {
+		data, err := json.Marshal(hubEnvelope{Instance: instance, Scope: hubScopeChat, ChatID: 7, Message: json.RawMessage(`{"type":"ai_response"}`)})
+		require.NoError(t, err)
+		return data
+	}
+
+	// Messages this instance published are already delivered locally
+	hub.handleEnvelope(envelope("replica-a"))
+	assert.Len(t, viewer.send, 0)
+
+	hub.handleEnvelope(envelope("replica-b"))
+	require.Len(t, viewer.send, 1)
+	assert.JSONEq(t, `{"type":"ai_response"}`, string(<-viewer.send))
+
+	hub.handleEnvelope([]byte("not json"))
+	assert.Len(t, viewer.send, 0)
+}
+
+func TestHub_InstancesWithoutBackplane(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	hub.SetInstanceID("solo")
+	addTestClient(hub, 0, false)
+
+	instances, err := hub.Instances()
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, "solo", instances[0].ID)
+	assert.Equal(t, 1, instances[0].Clients)
+}
@@ -11,8 +11,10 @@ func IndexHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		lang := GetLang(c)
 		c.HTML(http.StatusOK, "pages/index.html", gin.H{
-			"title": "AI Gateway Hub", // Will be translated in template using T function
-			"lang":  lang,
+			"title":     "AI Gateway Hub", // Will be translated in template using T function
+			"lang":      lang,
+			"theme":     GetTheme(c),
+			"csrfToken": GetCSRFToken(c),
 		})
 	}
 }
\ No newline at end of file
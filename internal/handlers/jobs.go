@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"ai-gateway-hub/internal/jobs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListJobsHandler returns the background jobs and the status of their runs on this instance
+func (h *APIHandlers) ListJobsHandler(scheduler *jobs.Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.errorHandler.Success(c, gin.H{"jobs": scheduler.Status()})
+	}
+}
+
+// RunJobHandler runs a background job now, outside its interval. The run happens in the
+// background; its outcome shows up in the job's status.
+func (h *APIHandlers) RunJobHandler(scheduler *jobs.Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		if err := scheduler.Trigger(name); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to run job", err)
+			return
+		}
+
+		h.errorHandler.Success(c, gin.H{"job": name, "triggered": true})
+	}
+}
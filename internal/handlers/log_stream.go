@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Levels the live log view filters by, from the most verbose
+var logStreamLevels = []string{"debug", "info", "warn", "error"}
+
+// Interval of the comments that keep idle log streams open through proxies
+const logStreamHeartbeat = 15 * time.Second
+
+// AdminLogsHandler renders the live view of system.log
+func AdminLogsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.HTML(http.StatusOK, "pages/admin_logs.html", gin.H{
+			"lang":      GetLang(c),
+			"theme":     GetTheme(c),
+			"levels":    logStreamLevels,
+			"csrfToken": GetCSRFToken(c),
+		})
+	}
+}
+
+// LogStreamHandler streams system.log as server-sent "log" events: the entries of the last
+// ?backlog= lines (100, max 1000), then entries as they are written. ?level= (debug, info, warn
+// or error; debug by default) drops less severe entries.
+func (h *APIHandlers) LogStreamHandler(systemLogService *services.SystemLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		level := c.DefaultQuery("level", "debug")
+		if !slices.Contains(logStreamLevels, level) {
+			h.errorHandler.BadRequest(c, "Invalid level. Supported levels: debug, info, warn, error", nil)
+			return
+		}
+		backlog := 100
+		if b, err := strconv.Atoi(c.Query("backlog")); err == nil && b >= 0 && b <= 1000 {
+			backlog = b
+		}
+
+		tail, err := systemLogService.Tail(backlog)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to open system log", err)
+			return
+		}
+		defer tail.Close()
+
+		header := c.Writer.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		poll := time.NewTicker(systemLogService.PollInterval())
+		defer poll.Stop()
+		heartbeat := time.NewTicker(logStreamHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			entries, err := tail.Read()
+			if err != nil {
+				utils.Warn("[request_id=%s] Stopped streaming system log: %v", requestID(c), err)
+				logStreamEvent(c, "error", models.LogEntry{Time: time.Now(), Level: "error", Message: err.Error()})
+				return
+			}
+			for _, entry := range entries {
+				if !utils.LogLevelAllows(level, entry.Level) {
+					continue
+				}
+				if err := logStreamEvent(c, "log", entry); err != nil {
+					return
+				}
+			}
+
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				c.Writer.Flush()
+			case <-poll.C:
+			}
+		}
+	}
+}
+
+// logStreamEvent sends a log entry as a server-sent event
+func logStreamEvent(c *gin.Context, event string, entry models.LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
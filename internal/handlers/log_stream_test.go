@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogStreamHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "system.log")
+
+	router := gin.New()
+	router.GET("/api/admin/logs/stream", NewAPIHandlers(nil).LogStreamHandler(services.NewSystemLogService(dir, 10*time.Millisecond)))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/admin/logs/stream")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "system.log doesn't exist yet")
+
+	resp, err = http.Get(server.URL + "/api/admin/logs/stream?level=verbose")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	require.NoError(t, os.WriteFile(path, []byte(
+		"[APP] 2026/10/16 - 10:00:00 | INFO | Backlog info\n"+
+			"[APP] 2026/10/16 - 10:00:01 | WARNING | Backlog warning\n"), 0644))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/admin/logs/stream?level=warn", nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := bufio.NewScanner(resp.Body)
+	next := func() (string, models.LogEntry) {
+		t.Helper()
+		var event string
+		for events.Scan() {
+			line := events.Text()
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var entry models.LogEntry
+				require.NoError(t, json.Unmarshal([]byte(data), &entry))
+				return event, entry
+			}
+		}
+		t.Fatalf("Stream ended: %v", events.Err())
+		return "", models.LogEntry{}
+	}
+
+	// The backlog is filtered by level
+	event, entry := next()
+	assert.Equal(t, "log", event)
+	assert.Equal(t, "Backlog warning", entry.Message)
+
+	// Entries written later follow
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString("[APP] 2026/10/16 - 10:00:02 | INFO | Live info\n[APP] 2026/10/16 - 10:00:03 | ERROR | Live error\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	_, entry = next()
+	assert.Equal(t, "Live error", entry.Message)
+	assert.Equal(t, "error", entry.Level)
+}
+
+func TestAdminLogsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	require.NoError(t, i18n.Init("../../locales", "en"))
+	tmpl := template.Must(template.New("").Funcs(i18n.TemplateFuncs()).ParseGlob("../../web/templates/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/pages/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/components/*.html"))
+
+	router := gin.New()
+	router.SetHTMLTemplate(tmpl)
+	router.GET("/admin/logs", AdminLogsHandler())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/logs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "Live Logs")
+	assert.Contains(t, body, "/api/admin/logs/stream")
+	assert.Contains(t, body, `<option value="error">`)
+}
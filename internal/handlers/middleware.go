@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
+
+	"ai-gateway-hub/internal/config"
 	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,10 +20,36 @@ func GetLang(c *gin.Context) string {
 	return "en"
 }
 
+// GetTheme returns the theme (light, dark or auto) set by the theme middleware
+func GetTheme(c *gin.Context) string {
+	if theme := c.GetString("theme"); theme != "" {
+		return theme
+	}
+	return config.DefaultTheme
+}
+
+// GetCSRFToken returns the CSRF token CSRFMiddleware issued for the request
+func GetCSRFToken(c *gin.Context) string {
+	return c.GetString("csrf_token")
+}
+
 // GetTranslator returns a translation function for templates
 func GetTranslator(c *gin.Context) func(string, ...interface{}) string {
 	lang := GetLang(c)
 	return func(key string, args ...interface{}) string {
 		return i18n.T(lang, key, args...)
 	}
-}
\ No newline at end of file
+}
+
+// promptErrorMessage describes a prompt refused by validation in lang, or returns the error's own
+// message for other errors
+func promptErrorMessage(lang string, err error) string {
+	var promptErr *services.PromptError
+	if !errors.As(err, &promptErr) {
+		return err.Error()
+	}
+	if message := i18n.T(lang, promptErr.Key, promptErr.Args...); message != promptErr.Key {
+		return message
+	}
+	return capitalize(promptErr.Error())
+}
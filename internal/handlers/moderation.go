@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"strconv"
+
+	"ai-gateway-hub/internal/moderation"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetModerationEventsHandler lists flagged and blocked prompts and responses, newest first
+// (?since=168h&action=block&direction=prompt&limit=50)
+func (h *APIHandlers) GetModerationEventsHandler(moderationService *services.ModerationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// The same ?since window as the feedback listing
+		since, ok := h.feedbackSince(c)
+		if !ok {
+			return
+		}
+
+		q := services.ModerationQuery{Since: since, Action: c.Query("action"), Direction: c.Query("direction"), Limit: 50}
+		if q.Action != "" && q.Action != moderation.ActionFlag && q.Action != moderation.ActionBlock {
+			h.errorHandler.BadRequest(c, "Invalid action, expected flag or block", nil)
+			return
+		}
+		if q.Direction != "" && q.Direction != moderation.DirectionPrompt && q.Direction != moderation.DirectionResponse {
+			h.errorHandler.BadRequest(c, "Invalid direction, expected prompt or response", nil)
+			return
+		}
+		if l := c.Query("limit"); l != "" {
+			parsed, err := strconv.Atoi(l)
+			if err != nil || parsed <= 0 || parsed > 200 {
+				h.errorHandler.BadRequest(c, "Invalid limit", err)
+				return
+			}
+			q.Limit = parsed
+		}
+
+		events, err := moderationService.ListEvents(q)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get moderation events", err)
+			return
+		}
+
+		h.errorHandler.Success(c, events)
+	}
+}
@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAIChatRequest is the body of POST /v1/chat/completions; sampling parameters such as
+// temperature are accepted and ignored, since CLI providers don't take them
+type openAIChatRequest struct {
+	Model         string          `json:"model"`
+	Messages      []openAIMessage `json:"messages"`
+	Stream        bool            `json:"stream"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+}
+
+// openAIMessage is a message of a chat completion request or response
+type openAIMessage struct {
+	Role    string        `json:"role"`
+	Content openAIContent `json:"content"`
+}
+
+// openAIContent is the text of a message, sent either as a string or as a list of content parts
+type openAIContent string
+
+func (c *openAIContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = openAIContent(text)
+		return nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return errors.New("content must be a string or a list of content parts")
+	}
+	var texts []string
+	for _, part := range parts {
+		if part.Type != "text" {
+			return fmt.Errorf("content parts of type %q are not supported", part.Type)
+		}
+		texts = append(texts, part.Text)
+	}
+	*c = openAIContent(strings.Join(texts, "\n"))
+	return nil
+}
+
+// openAIChatCompletion is a chat completion, or a chunk of one when streaming
+type openAIChatCompletion struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+	Usage   *openAIUsage   `json:"usage,omitempty"`
+}
+
+// openAIChoice is the only choice of a completion: Message when complete, Delta in stream chunks
+type openAIChoice struct {
+	Index        int            `json:"index"`
+	Message      *openAIMessage `json:"message,omitempty"`
+	Delta        *openAIDelta   `json:"delta,omitempty"`
+	FinishReason *string        `json:"finish_reason"`
+}
+
+// openAIDelta is what a stream chunk adds to the message
+type openAIDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// openAIUsage counts the tokens of a completion; the hub estimates them
+type openAIUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// openAIModel is an entry of GET /v1/models
+type openAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// OpenAIModelsHandler lists the providers as models, plus "provider/model" for each model a
+// provider accepts per request
+func (h *APIHandlers) OpenAIModelsHandler(registry *services.ProviderRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list := registry.List()
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+		data := []openAIModel{}
+		for _, p := range list {
+			data = append(data, openAIModel{ID: p.ID, Object: "model", OwnedBy: p.ID})
+			provider, err := registry.Get(p.ID)
+			if err != nil {
+				continue
+			}
+			for _, m := range provider.GetModels() {
+				data = append(data, openAIModel{ID: p.ID + "/" + m.ID, Object: "model", OwnedBy: p.ID})
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"object": "list", "data": data})
+	}
+}
+
+// OpenAIChatCompletionsHandler answers OpenAI chat completion requests with a provider of the
+// registry, so OpenAI SDKs can use the hub. The model names a provider, "provider/model", or a model
+// one provider accepts. Earlier messages are sent as the conversation so far, system messages as
+// the system prompt; each request is answered in a temporary chat.
+func (h *APIHandlers) OpenAIChatCompletionsHandler(registry *services.ProviderRegistry, completionService *services.CompletionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req openAIChatRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			openAIError(c, http.StatusBadRequest, "invalid_request_error", "Invalid request: "+err.Error())
+			return
+		}
+
+		providerID, model, ok := resolveOpenAIModel(registry, req.Model)
+		if !ok {
+			openAIError(c, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("The model %q does not exist", req.Model))
+			return
+		}
+		systemPrompt, prompt, err := openAIPrompt(req.Messages)
+		if err != nil {
+			openAIError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+			return
+		}
+
+		completionReq := models.CompletionRequest{
+			Provider:     providerID,
+			Model:        model,
+			Prompt:       prompt,
+			Title:        deriveChatTitle(lastOpenAIUserMessage(req.Messages), "OpenAI API"),
+			SystemPrompt: systemPrompt,
+		}
+		id, err := openAICompletionID()
+		if err != nil {
+			openAIError(c, http.StatusInternalServerError, "server_error", "Failed to generate completion ID")
+			return
+		}
+		created := time.Now().Unix()
+
+		if !req.Stream {
+			completion, err := completionService.Complete(c.Request.Context(), completionReq)
+			if err != nil {
+				openAICompletionError(c, err)
+				return
+			}
+			finish := openAIFinishReason(completion)
+			c.JSON(http.StatusOK, openAIChatCompletion{
+				ID:      id,
+				Object:  "chat.completion",
+				Created: created,
+				Model:   req.Model,
+				Choices: []openAIChoice{{
+					Message:      &openAIMessage{Role: "assistant", Content: openAIContent(completion.Content)},
+					FinishReason: &finish,
+				}},
+				Usage: openAIUsageOf(completion),
+			})
+			return
+		}
+
+		stream := &openAIStream{c: c, id: id, created: created, model: req.Model}
+		completion, err := completionService.CompleteStream(c.Request.Context(), completionReq, stream)
+		if err != nil {
+			if !stream.started {
+				openAICompletionError(c, err)
+				return
+			}
+			// The status was sent with the first chunk; the error ends the stream
+			utils.Warn("[request_id=%s] OpenAI stream for %s failed: %v", requestID(c), providerID, err)
+			_, errType, message := openAIErrorOf(err)
+			stream.event(gin.H{"error": gin.H{"message": message, "type": errType, "code": nil}})
+			return
+		}
+
+		stream.flushPending()
+		finish := openAIFinishReason(completion)
+		stream.chunk(&openAIDelta{}, &finish, nil)
+		if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+			stream.chunk(nil, nil, openAIUsageOf(completion))
+		}
+		stream.done()
+	}
+}
+
+// resolveOpenAIModel finds the provider and per-request model an OpenAI model name refers to
+func resolveOpenAIModel(registry *services.ProviderRegistry, name string) (providerID, model string, ok bool) {
+	if name == "" {
+		return "", "", false
+	}
+	if _, err := registry.Get(name); err == nil {
+		return name, "", true
+	}
+	if id, m, found := strings.Cut(name, "/"); found {
+		if provider, err := registry.Get(id); err == nil && providers.SupportsModel(provider, m) {
+			return id, m, true
+		}
+		return "", "", false
+	}
+	list := registry.List()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	for _, p := range list {
+		if provider, err := registry.Get(p.ID); err == nil && providers.SupportsModel(provider, name) {
+			return p.ID, name, true
+		}
+	}
+	return "", "", false
+}
+
+// openAIPrompt turns the messages of a request into a system prompt and a prompt: the last message
+// must be the user's, and earlier ones become the conversation so far
+func openAIPrompt(messages []openAIMessage) (systemPrompt, prompt string, err error) {
+	if len(messages) == 0 {
+		return "", "", errors.New("messages must not be empty")
+	}
+	var system []string
+	var history []*models.Message
+	for i, msg := range messages {
+		content := string(msg.Content)
+		switch msg.Role {
+		case "system", "developer":
+			system = append(system, content)
+		case "user", "assistant":
+			if i == len(messages)-1 {
+				if msg.Role != "user" {
+					return "", "", errors.New("the last message must be from the user")
+				}
+				prompt = content
+				continue
+			}
+			history = append(history, &models.Message{Role: msg.Role, Content: content})
+		default:
+			return "", "", fmt.Errorf("messages with role %q are not supported", msg.Role)
+		}
+	}
+	if prompt == "" {
+		return "", "", errors.New("the last message must be from the user")
+	}
+	return strings.Join(system, "\n\n"), services.FormatHandoffHistory(history, prompt), nil
+}
+
+// lastOpenAIUserMessage returns the content of the last message, which titles the temporary chat
+func lastOpenAIUserMessage(messages []openAIMessage) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	return string(messages[len(messages)-1].Content)
+}
+
+// openAICompletionID returns a random ID in the style of OpenAI completion IDs
+func openAICompletionID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "chatcmpl-" + hex.EncodeToString(b), nil
+}
+
+// openAIFinishReason returns why a completion ended: stop, or length when it was cut off
+func openAIFinishReason(completion *models.Completion) string {
+	if completion.Metadata == nil {
+		return models.FinishStop
+	}
+	return completion.Metadata.FinishReason
+}
+
+// openAIUsageOf reports the estimated tokens of a completion
+func openAIUsageOf(completion *models.Completion) *openAIUsage {
+	if completion.Metadata == nil {
+		return nil
+	}
+	return &openAIUsage{
+		PromptTokens:     completion.Metadata.TokensIn,
+		CompletionTokens: completion.Metadata.TokensOut,
+		TotalTokens:      completion.Metadata.TokensIn + completion.Metadata.TokensOut,
+	}
+}
+
+// openAIError answers with an error in the format of the OpenAI API
+func openAIError(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, gin.H{"error": gin.H{"message": message, "type": errType, "code": nil}})
+}
+
+// openAICompletionError answers with the OpenAI error for an error of the completion service
+func openAICompletionError(c *gin.Context, err error) {
+	status, errType, message := openAIErrorOf(err)
+	if status == http.StatusInternalServerError {
+		utils.Error("[request_id=%s] OpenAI chat completion failed: %v", requestID(c), err)
+	}
+	openAIError(c, status, errType, message)
+}
+
+// openAIErrorOf maps an error of the completion service to the status, type and message of an OpenAI error
+func openAIErrorOf(err error) (status int, errType, message string) {
+	var serviceErr *apperrors.Error
+	switch {
+	case errors.Is(err, services.ErrCompletionTimeout):
+		return http.StatusGatewayTimeout, "timeout", "The provider did not complete the response in time"
+	case errors.Is(err, services.ErrProviderUnavailable):
+		return http.StatusServiceUnavailable, "server_error", "The provider is not available"
+	case errors.Is(err, services.ErrProviderDegraded):
+		return http.StatusServiceUnavailable, "server_error", "The provider is degraded after repeated failures, try again later"
+	case errors.As(err, &serviceErr) && errors.Is(err, apperrors.ErrNotFound):
+		return http.StatusNotFound, "invalid_request_error", capitalize(serviceErr.Message)
+	case errors.As(err, &serviceErr) && errors.Is(err, apperrors.ErrValidation):
+		return http.StatusBadRequest, "invalid_request_error", capitalize(serviceErr.Message)
+	default:
+		return http.StatusInternalServerError, "server_error", "Failed to complete the chat"
+	}
+}
+
+// openAIStream writes a streamed response as server-sent chat completion chunks. The status and the
+// assistant role go out with the first chunk, so errors before it can still be plain responses.
+type openAIStream struct {
+	c       *gin.Context
+	id      string
+	created int64
+	model   string
+	started bool
+	pending []byte // end of the last write, holding an incomplete UTF-8 character
+}
+
+func (s *openAIStream) Write(p []byte) (int, error) {
+	if !s.started {
+		s.started = true
+		header := s.c.Writer.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no")
+		s.c.Status(http.StatusOK)
+		s.chunk(&openAIDelta{Role: "assistant"}, nil, nil)
+	}
+
+	// Characters split between writes are sent whole with the next chunk
+	data := append(s.pending, p...)
+	end := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	s.pending = append([]byte(nil), data[end:]...)
+	if end > 0 {
+		if err := s.chunk(&openAIDelta{Content: string(data[:end])}, nil, nil); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flushPending sends what is left of an incomplete character at the end of the response
+func (s *openAIStream) flushPending() {
+	if len(s.pending) > 0 {
+		s.chunk(&openAIDelta{Content: string(s.pending)}, nil, nil)
+		s.pending = nil
+	}
+}
+
+// chunk sends a chat completion chunk; a chunk with usage has no choices
+func (s *openAIStream) chunk(delta *openAIDelta, finishReason *string, usage *openAIUsage) error {
+	chunk := openAIChatCompletion{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.model,
+		Choices: []openAIChoice{},
+		Usage:   usage,
+	}
+	if delta != nil {
+		chunk.Choices = append(chunk.Choices, openAIChoice{Delta: delta, FinishReason: finishReason})
+	}
+	return s.event(chunk)
+}
+
+// event sends a server-sent event with JSON data
+func (s *openAIStream) event(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.c.Writer, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	s.c.Writer.Flush()
+	return nil
+}
+
+// done ends the stream the way OpenAI clients expect
+func (s *openAIStream) done() {
+	fmt.Fprint(s.c.Writer, "data: [DONE]\n\n")
+	s.c.Writer.Flush()
+}
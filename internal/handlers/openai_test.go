@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIHandlers(t *testing.T) {
+	router, chatService, cleanup := setupAPITest(t)
+	defer cleanup()
+
+	registry := services.NewProviderRegistry(nil)
+	require.NoError(t, registry.Register(&mockAIProvider{name: "mock", healthy: true}))
+	// The accented character is split between two writes
+	require.NoError(t, registry.Register(&chunkedProvider{mockAIProvider{name: "chunked", healthy: true}, []string{"Caf\xc3", "\xa9 open"}}))
+	require.NoError(t, registry.Register(&mockAIProvider{name: "down", healthy: false}))
+	completionService := services.NewCompletionService(chatService, registry, nil, func(string) (time.Duration, time.Duration) {
+		return time.Minute, time.Minute
+	})
+	apiHandlers := NewAPIHandlers(nil)
+	router.GET("/v1/models", apiHandlers.OpenAIModelsHandler(registry))
+	router.POST("/v1/chat/completions", apiHandlers.OpenAIChatCompletionsHandler(registry, completionService))
+
+	complete := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var models struct {
+		Object string        `json:"object"`
+		Data   []openAIModel `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &models))
+	assert.Equal(t, "list", models.Object)
+	require.Len(t, models.Data, 3)
+	assert.Equal(t, "chunked", models.Data[0].ID)
+
+	w = complete(`{"model": "mock", "messages": [{"role": "system", "content": "Be brief"}, {"role": "user", "content": "Hi"}], "temperature": 0.2}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var completion openAIChatCompletion
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &completion))
+	assert.True(t, strings.HasPrefix(completion.ID, "chatcmpl-"))
+	assert.Equal(t, "chat.completion", completion.Object)
+	assert.Equal(t, "mock", completion.Model)
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, "Mock streaming response", string(completion.Choices[0].Message.Content))
+	require.NotNil(t, completion.Choices[0].FinishReason)
+	assert.Equal(t, "stop", *completion.Choices[0].FinishReason)
+	require.NotNil(t, completion.Usage)
+	assert.Equal(t, completion.Usage.PromptTokens+completion.Usage.CompletionTokens, completion.Usage.TotalTokens)
+
+	// Streamed responses are chunks ending with [DONE]
+	w = complete(`{"model": "chunked", "stream": true, "stream_options": {"include_usage": true}, "messages": [{"role": "user", "content": [{"type": "text", "text": "Hi"}]}]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+	require.Len(t, events, 6)
+	assert.Equal(t, "data: [DONE]", events[5])
+	var chunks []openAIChatCompletion
+	for _, event := range events[:5] {
+		var chunk openAIChatCompletion
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(event, "data: ")), &chunk))
+		assert.Equal(t, "chat.completion.chunk", chunk.Object)
+		chunks = append(chunks, chunk)
+	}
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+	assert.Equal(t, "Caf", chunks[1].Choices[0].Delta.Content)
+	assert.Equal(t, "é open", chunks[2].Choices[0].Delta.Content)
+	require.NotNil(t, chunks[3].Choices[0].FinishReason)
+	assert.Equal(t, "stop", *chunks[3].Choices[0].FinishReason)
+	assert.Empty(t, chunks[4].Choices)
+	assert.NotNil(t, chunks[4].Usage)
+
+	// Errors are in the format of the OpenAI API
+	w = complete(`{"model": "missing", "messages": [{"role": "user", "content": "Hi"}]}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"type":"invalid_request_error"`)
+	assert.Equal(t, http.StatusBadRequest, complete(`{"model": "mock", "messages": []}`).Code)
+	assert.Equal(t, http.StatusBadRequest, complete(`{"model": "mock", "messages": [{"role": "user", "content": [{"type": "image_url"}]}]}`).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, complete(`{"model": "down", "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`).Code)
+}
+
+func TestOpenAIPrompt(t *testing.T) {
+	systemPrompt, prompt, err := openAIPrompt([]openAIMessage{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "What is Go?"},
+		{Role: "assistant", Content: "A language"},
+		{Role: "user", Content: "Who made it?"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Be brief", systemPrompt)
+	assert.Contains(t, prompt, "What is Go?")
+	assert.Contains(t, prompt, "A language")
+	assert.True(t, strings.HasSuffix(prompt, "Who made it?"), "the last message is the prompt")
+
+	// A single message is sent as it is
+	_, prompt, err = openAIPrompt([]openAIMessage{{Role: "user", Content: "Hi"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", prompt)
+
+	_, _, err = openAIPrompt([]openAIMessage{{Role: "user", Content: "Hi"}, {Role: "assistant", Content: "Hello"}})
+	assert.Error(t, err, "the last message must be the user's")
+	_, _, err = openAIPrompt([]openAIMessage{{Role: "tool", Content: "42"}})
+	assert.Error(t, err)
+}
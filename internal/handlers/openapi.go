@@ -0,0 +1,501 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/buildinfo"
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/middleware"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiRoute documents a route of the REST API for the OpenAPI document. Routes registered in
+// main.go are added here too; TestOpenAPIRoutes fails for routes missing from the list.
+type apiRoute struct {
+	Method  string
+	Path    string // as registered with gin, e.g. /api/chats/:id
+	Tag     string
+	Summary string
+	Query   []apiQueryParam
+	Body    any      // value of the JSON request body type, or multipartFile for uploads
+	Data    any      // value of the type of the data of successful responses
+	Raw     bool     // the response is Data itself rather than {"data": ...}
+	Created bool     // successful responses are 201 Created
+	Formats []string // other content types the route answers with, e.g. with ?format=
+}
+
+// apiQueryParam is a query parameter of a route
+type apiQueryParam struct {
+	Name        string
+	Description string
+}
+
+// multipartFile marks request bodies uploading a file in the multipart field "file"
+type multipartFile struct{}
+
+// stringPathParams are path parameters that aren't numeric IDs
+var stringPathParams = map[string]bool{"shareId": true, "tag": true, "name": true, "profile": true}
+
+// apiRoutes lists the documented routes, grouped like main.go registers them
+var apiRoutes = []apiRoute{
+	{Method: "GET", Path: "/api/health", Tag: "System", Summary: "Health check with build information", Raw: true, Data: gin.H{}},
+	{Method: "GET", Path: "/api/version", Tag: "System", Summary: "Version, commit, build date, Go version and enabled features", Raw: true, Data: buildinfo.BuildInfo{}},
+
+	{Method: "GET", Path: "/api/chats", Tag: "Chats", Summary: "List chats", Data: models.ChatPage{}, Query: []apiQueryParam{
+		{"limit", "Page size (default 50, max 100)"}, {"offset", "Chats to skip"}, {"tag", "Only chats with this tag"},
+		{"folder", "Only chats in this folder ID, or none for chats without a folder"}, {"archived", "List archived chats instead"},
+	}},
+	{Method: "POST", Path: "/api/chats", Tag: "Chats", Summary: "Create a chat with the configured greeting", Created: true, Data: models.Chat{}, Body: struct {
+		Title    string `json:"title" binding:"required"`
+		Provider string `json:"provider" binding:"required"`
+	}{}},
+	{Method: "POST", Path: "/api/chats/bulk", Tag: "Chats", Summary: "Delete, archive or tag up to 200 chats", Data: models.BulkChatResponse{}, Body: struct {
+		Action  string  `json:"action" binding:"required"`
+		ChatIDs []int64 `json:"chat_ids" binding:"required"`
+		Tag     string  `json:"tag"`
+	}{}},
+	{Method: "GET", Path: "/api/chats/:id", Tag: "Chats", Summary: "Chat with its message count, latest message preview and branch tree", Data: models.ChatDetails{}},
+	{Method: "DELETE", Path: "/api/chats/:id", Tag: "Chats", Summary: "Move a chat to the trash"},
+	{Method: "POST", Path: "/api/chats/:id/fork", Tag: "Chats", Summary: "Branch a chat with its messages up to a message", Created: true, Data: models.Chat{}, Query: []apiQueryParam{
+		{"from_message", "Last message ID copied to the branch"},
+	}},
+	{Method: "POST", Path: "/api/chats/:id/archive", Tag: "Chats", Summary: "Hide a chat from the default list"},
+	{Method: "POST", Path: "/api/chats/:id/restore", Tag: "Chats", Summary: "Restore an archived or deleted chat", Data: models.Chat{}},
+	{Method: "PUT", Path: "/api/chats/:id/provider", Tag: "Chats", Summary: "Switch a chat to another provider", Data: models.Chat{}, Body: struct {
+		Provider string `json:"provider" binding:"required"`
+	}{}},
+	{Method: "PUT", Path: "/api/chats/:id/system-prompt", Tag: "Chats", Summary: "Set the system prompt of a chat; empty clears it", Data: models.Chat{}, Body: struct {
+		SystemPrompt string `json:"system_prompt"`
+	}{}},
+	{Method: "GET", Path: "/api/chats/:id/messages", Tag: "Messages", Summary: "Messages of a chat, oldest first", Data: models.MessagePage{}, Query: []apiQueryParam{
+		{"limit", "Page size (default 100, max 500)"}, {"offset", "Messages to skip"},
+	}},
+	{Method: "PUT", Path: "/api/chats/:id/messages/:msgid", Tag: "Messages", Summary: "Edit a user message", Data: models.Message{}, Body: struct {
+		Content string `json:"content" binding:"required"`
+	}{}},
+	{Method: "GET", Path: "/api/chats/:id/export", Tag: "Chats", Summary: "Export a chat", Data: gin.H{}, Formats: []string{"text/markdown", "text/html"}, Query: []apiQueryParam{
+		{"format", "json, markdown or html"},
+	}},
+	{Method: "POST", Path: "/api/chats/:id/share", Tag: "Sharing", Summary: "Create a public read-only link to a chat", Created: true, Data: models.ChatShare{}, Body: shareRequest{}},
+	{Method: "GET", Path: "/api/chats/:id/shares", Tag: "Sharing", Summary: "Share links of a chat", Data: []*models.ChatShare{}},
+	{Method: "DELETE", Path: "/api/chats/:id/shares/:shareId", Tag: "Sharing", Summary: "Revoke a share link"},
+
+	{Method: "GET", Path: "/api/chats/:id/collection", Tag: "Documents", Summary: "Collection the chat retrieves context from; null without one", Data: models.DocumentCollection{}},
+	{Method: "PUT", Path: "/api/chats/:id/collection", Tag: "Documents", Summary: "Link a chat to a collection; null unlinks it", Body: struct {
+		CollectionID *int64 `json:"collection_id"`
+	}{}},
+	{Method: "GET", Path: "/api/collections", Tag: "Documents", Summary: "Document collections with their document counts", Data: []*models.DocumentCollection{}},
+	{Method: "POST", Path: "/api/collections", Tag: "Documents", Summary: "Create a collection", Created: true, Data: models.DocumentCollection{}, Body: collectionRequest{}},
+	{Method: "DELETE", Path: "/api/collections/:id", Tag: "Documents", Summary: "Delete a collection with its documents"},
+	{Method: "GET", Path: "/api/collections/:id/documents", Tag: "Documents", Summary: "Documents of a collection", Data: []*models.Document{}},
+	{Method: "POST", Path: "/api/collections/:id/documents", Tag: "Documents", Summary: "Index a text, markdown or PDF file", Created: true, Data: models.Document{}, Body: multipartFile{}},
+	{Method: "DELETE", Path: "/api/collections/:id/documents/:documentId", Tag: "Documents", Summary: "Remove a document"},
+	{Method: "GET", Path: "/api/collections/:id/search", Tag: "Documents", Summary: "Chunks closest to a query", Data: []*models.DocumentChunk{}, Query: []apiQueryParam{
+		{"q", "Query"}, {"k", "Chunks to return (1-20, default 4)"},
+	}},
+
+	{Method: "GET", Path: "/api/chats/:id/processors", Tag: "Chats", Summary: "Prompt and response processors in run order, with whether the chat runs them", Data: []models.ChatProcessor{}},
+	{Method: "PUT", Path: "/api/chats/:id/processors/:name", Tag: "Chats", Summary: "Enable or disable a processor in a chat", Body: chatProcessorRequest{}},
+	{Method: "GET", Path: "/api/chats/:id/tools", Tag: "Chats", Summary: "Tools providers can call, with whether the chat enabled them", Data: []models.ChatTool{}},
+	{Method: "PUT", Path: "/api/chats/:id/tools", Tag: "Chats", Summary: "Set the tools a chat enables; an empty list disables them", Body: chatToolsRequest{}},
+	{Method: "GET", Path: "/api/chats/:id/draft", Tag: "Chats", Summary: "Unsent prompt draft of a chat", Data: models.Draft{}},
+	{Method: "PUT", Path: "/api/chats/:id/draft", Tag: "Chats", Summary: "Save the prompt draft of a chat; empty content clears it", Data: models.Draft{}, Body: draftRequest{}},
+
+	{Method: "POST", Path: "/api/chats/:id/tags", Tag: "Organization", Summary: "Tag a chat", Data: models.Chat{}, Body: struct {
+		Tag string `json:"tag" binding:"required"`
+	}{}},
+	{Method: "DELETE", Path: "/api/chats/:id/tags/:tag", Tag: "Organization", Summary: "Remove a tag from a chat"},
+	{Method: "PUT", Path: "/api/chats/:id/folder", Tag: "Organization", Summary: "File a chat in a folder; null takes it out", Body: struct {
+		FolderID *int64 `json:"folder_id"`
+	}{}},
+
+	{Method: "POST", Path: "/api/chats/:id/attachments", Tag: "Attachments", Summary: "Upload a file to a chat", Created: true, Data: models.Attachment{}, Body: multipartFile{}},
+	{Method: "GET", Path: "/api/chats/:id/attachments", Tag: "Attachments", Summary: "Attachments of a chat", Data: []*models.Attachment{}},
+	{Method: "GET", Path: "/api/chats/:id/attachments/:attachmentId", Tag: "Attachments", Summary: "Download an attachment", Raw: true, Formats: []string{"application/octet-stream"}},
+	{Method: "DELETE", Path: "/api/chats/:id/attachments/:attachmentId", Tag: "Attachments", Summary: "Delete an attachment"},
+
+	{Method: "GET", Path: "/api/chats/:id/generations", Tag: "Usage", Summary: "Timings of the generations of a chat", Data: []*models.GenerationTiming{}, Query: []apiQueryParam{
+		{"events", "true for the raw generation events"},
+	}},
+	{Method: "GET", Path: "/api/chats/:id/logs", Tag: "Usage", Summary: "Lines of a provider's execution log of a chat (admin)", Data: models.ChatLogPage{}, Formats: []string{"text/plain"}, Query: []apiQueryParam{
+		{"provider", "provider whose log is read; the chat's provider, or the latest log, by default"},
+		{"offset", "byte offset to read from; next_offset continues, also to follow the log"},
+		{"limit", "lines to return (200, max 1000)"},
+		{"tail", "return the last N lines instead (max 1000)"},
+		{"download", "true to download the whole file"},
+	}},
+	{Method: "GET", Path: "/api/generations/stats", Tag: "Usage", Summary: "Latency per provider", Data: []*models.GenerationStats{}, Formats: []string{"application/openmetrics-text"}, Query: []apiQueryParam{
+		{"since", "Window, e.g. 24h"}, {"format", "openmetrics for OpenMetrics text"},
+	}},
+	{Method: "GET", Path: "/api/chats/:id/usage", Tag: "Usage", Summary: "Byte and token usage of a chat", Data: models.ChatUsage{}, Query: []apiQueryParam{
+		{"records", "false for the totals only"},
+	}},
+	{Method: "GET", Path: "/api/chats/:id/feedback", Tag: "Feedback", Summary: "Ratings given in a chat", Data: []*models.MessageFeedback{}},
+	{Method: "POST", Path: "/api/messages/:id/feedback", Tag: "Feedback", Summary: "Rate an assistant message (1 or -1)", Data: models.MessageFeedback{}, Body: struct {
+		Rating  int    `json:"rating" binding:"required"`
+		Comment string `json:"comment"`
+	}{}},
+	{Method: "DELETE", Path: "/api/messages/:id/feedback", Tag: "Feedback", Summary: "Clear the rating of a message"},
+	{Method: "GET", Path: "/api/feedback", Tag: "Feedback", Summary: "Recent ratings with message excerpts", Data: []*models.MessageFeedback{}, Query: []apiQueryParam{
+		{"since", "Window (default 168h)"}, {"rating", "up or down"}, {"provider", "Only ratings of this provider"}, {"limit", "Ratings to return (default 50)"},
+	}},
+	{Method: "GET", Path: "/api/feedback/summary", Tag: "Feedback", Summary: "Ratings per provider and model, worst rated first", Data: models.FeedbackSummary{}, Query: []apiQueryParam{
+		{"since", "Window (default 168h)"},
+	}},
+	{Method: "GET", Path: "/api/usage/summary", Tag: "Usage", Summary: "Usage totals per provider", Data: models.UsageSummary{}, Query: []apiQueryParam{
+		{"since", "Window, e.g. 24h"},
+	}},
+	{Method: "GET", Path: "/api/usage/quota", Tag: "Usage", Summary: "Prompts the session or token user used and has left today and this month", Data: models.QuotaStatus{}},
+
+	{Method: "POST", Path: "/api/ws/ticket", Tag: "WebSocket", Summary: "Issue a single-use ticket for opening a WebSocket", Data: models.WSTicket{}},
+	{Method: "GET", Path: "/api/ws/schema", Tag: "WebSocket", Summary: "JSON Schema of every WebSocket message type", Raw: true, Data: gin.H{}},
+	{Method: "GET", Path: "/api/docs", Tag: "System", Summary: "Swagger UI for this document", Raw: true, Formats: []string{"text/html"}},
+	{Method: "GET", Path: "/api/docs/openapi.json", Tag: "System", Summary: "This OpenAPI document", Raw: true, Data: gin.H{}},
+
+	{Method: "POST", Path: "/api/auth/login", Tag: "Auth", Summary: "Sign in for an access and a refresh token (jwt mode)", Data: models.AuthTokens{}, Body: LoginRequest{}},
+	{Method: "POST", Path: "/api/auth/refresh", Tag: "Auth", Summary: "Exchange a refresh token for new tokens (jwt mode)", Data: models.AuthTokens{}, Body: RefreshRequest{}},
+	{Method: "POST", Path: "/api/auth/logout", Tag: "Auth", Summary: "Revoke a refresh token (jwt mode)", Data: gin.H{}, Body: RefreshRequest{}},
+	{Method: "GET", Path: "/api/auth/me", Tag: "Auth", Summary: "User ID, roles and expiry of the request's access token (jwt mode)", Data: gin.H{}},
+
+	{Method: "GET", Path: "/api/analytics/activity", Tag: "Usage", Summary: "Message counts over time and a weekday by hour heatmap", Data: models.ActivityReport{}, Query: []apiQueryParam{
+		{"from", "Start (RFC 3339 or date)"}, {"to", "End (RFC 3339 or date)"}, {"bucket", "hour or day"}, {"tz", "IANA time zone"},
+		{"provider", "Only messages of this provider"}, {"role", "Only messages with this role"},
+		{"user_id", "Only messages sent by this user"},
+	}},
+	{Method: "GET", Path: "/api/tags", Tag: "Organization", Summary: "Tags with their chat counts", Data: []*models.Tag{}},
+	{Method: "DELETE", Path: "/api/tags/:id", Tag: "Organization", Summary: "Delete a tag from all chats"},
+	{Method: "GET", Path: "/api/folders", Tag: "Organization", Summary: "Folders with their chat counts", Data: []*models.Folder{}},
+	{Method: "POST", Path: "/api/folders", Tag: "Organization", Summary: "Create a folder", Created: true, Data: models.Folder{}, Body: folderRequest{}},
+	{Method: "PUT", Path: "/api/folders/:id", Tag: "Organization", Summary: "Rename a folder", Data: models.Folder{}, Body: folderRequest{}},
+	{Method: "DELETE", Path: "/api/folders/:id", Tag: "Organization", Summary: "Delete a folder; its chats are kept"},
+
+	{Method: "GET", Path: "/api/schedules", Tag: "Schedules", Summary: "Scheduled prompts", Data: []*models.ScheduledPrompt{}},
+	{Method: "POST", Path: "/api/schedules", Tag: "Schedules", Summary: "Create a scheduled prompt", Created: true, Data: models.ScheduledPrompt{}, Body: scheduleRequest{}},
+	{Method: "GET", Path: "/api/schedules/:id", Tag: "Schedules", Summary: "Get a scheduled prompt", Data: models.ScheduledPrompt{}},
+	{Method: "PUT", Path: "/api/schedules/:id", Tag: "Schedules", Summary: "Update a scheduled prompt", Data: models.ScheduledPrompt{}, Body: scheduleRequest{}},
+	{Method: "DELETE", Path: "/api/schedules/:id", Tag: "Schedules", Summary: "Delete a scheduled prompt and its runs"},
+	{Method: "POST", Path: "/api/schedules/:id/run", Tag: "Schedules", Summary: "Run a scheduled prompt now", Data: models.ScheduledPrompt{}},
+	{Method: "GET", Path: "/api/schedules/:id/runs", Tag: "Schedules", Summary: "Recent runs of a scheduled prompt", Data: []*models.ScheduledPromptRun{}, Query: []apiQueryParam{
+		{"limit", "Runs to return (default 20, max 100)"},
+	}},
+	{Method: "POST", Path: "/api/complete", Tag: "Completions", Summary: "Answer a prompt synchronously", Data: models.Completion{}, Body: models.CompletionRequest{}},
+
+	{Method: "GET", Path: "/api/session", Tag: "Sessions", Summary: "The caller's own session, without its ID", Data: models.SessionInfo{}},
+	{Method: "GET", Path: "/api/providers", Tag: "Providers", Summary: "Available providers", Data: []*models.Provider{}},
+	{Method: "GET", Path: "/api/providers/:id/status", Tag: "Providers", Summary: "Status of a provider", Data: gin.H{}},
+	{Method: "GET", Path: "/api/providers/:id/models", Tag: "Providers", Summary: "Models selectable per request", Data: []providers.Model{}},
+	{Method: "GET", Path: "/api/providers/:id/config", Tag: "Providers", Summary: "Provider configuration with credentials masked (admin)", Data: providers.ProviderConfig{}},
+	{Method: "GET", Path: "/api/providers/:id/health/history", Tag: "Providers", Summary: "Recent scheduled health checks", Data: []*models.HealthCheckResult{}},
+	{Method: "GET", Path: "/api/providers/cancellations", Tag: "Providers", Summary: "Time cancelled provider processes took to terminate", Data: []providers.CancellationStats{}},
+	{Method: "GET", Path: "/api/settings", Tag: "Settings", Summary: "Personal settings", Data: models.UserSettings{}},
+	{Method: "POST", Path: "/api/settings", Tag: "Settings", Summary: "Save personal settings", Data: models.UserSettings{}, Body: models.UserSettings{}},
+	{Method: "POST", Path: "/api/logs/client", Tag: "System", Summary: "Log a client-side error", Body: struct {
+		Message   string `json:"message"`
+		Stack     string `json:"stack"`
+		URL       string `json:"url"`
+		UserAgent string `json:"userAgent"`
+		Level     string `json:"level"`
+		RequestID string `json:"requestId"`
+	}{}},
+
+	{Method: "GET", Path: "/api/admin/stats", Tag: "Admin", Summary: "Admin dashboard statistics", Data: models.AdminStats{}},
+	{Method: "GET", Path: "/api/admin/logs/stream", Tag: "Admin", Summary: "system.log as server-sent log events, followed live", Raw: true, Data: models.LogEntry{}, Formats: []string{"text/event-stream"}, Query: []apiQueryParam{
+		{"level", "minimum level: debug (default), info, warn or error"},
+		{"backlog", "lines of the log sent first (100, max 1000)"},
+	}},
+	{Method: "GET", Path: "/api/admin/sessions", Tag: "Admin", Summary: "Active sessions", Data: []*models.SessionInfo{}},
+	{Method: "DELETE", Path: "/api/admin/sessions/:id", Tag: "Admin", Summary: "Force-expire a session"},
+	{Method: "PUT", Path: "/api/admin/sessions/:id/role", Tag: "Admin", Summary: "Grant a session a role; empty reverts to DEFAULT_ROLE", Data: gin.H{}, Body: struct {
+		Role string `json:"role"`
+	}{}},
+	{Method: "GET", Path: "/api/admin/greeting", Tag: "Admin", Summary: "Welcome message and disclaimer added to new chats", Data: models.GreetingSettings{}},
+	{Method: "PUT", Path: "/api/admin/greeting", Tag: "Admin", Summary: "Set the greeting of new chats", Data: models.GreetingSettings{}, Body: models.GreetingSettings{}},
+	{Method: "GET", Path: "/api/admin/config/export", Tag: "Admin", Summary: "Signed configuration bundle", Raw: true, Data: services.SignedConfigBundle{}},
+	{Method: "POST", Path: "/api/admin/config/import", Tag: "Admin", Summary: "Apply a signed configuration bundle", Data: services.ConfigImportResult{}, Body: services.SignedConfigBundle{}, Query: []apiQueryParam{
+		{"dry_run", "true to validate only"},
+	}},
+	{Method: "POST", Path: "/api/admin/config/reload", Tag: "Admin", Summary: "Re-read .env and the environment, applying the runtime settings that changed", Data: gin.H{}},
+	{Method: "GET", Path: "/api/admin/instances", Tag: "Admin", Summary: "Instances sharing the WebSocket backplane", Data: gin.H{}},
+	{Method: "GET", Path: "/api/admin/moderation/events", Tag: "Admin", Summary: "Flagged and blocked prompts and responses, newest first", Data: []*models.ModerationEvent{}, Query: []apiQueryParam{
+		{"since", "Window (default 168h)"}, {"action", "flag or block"}, {"direction", "prompt or response"}, {"limit", "Events to return (default 50)"},
+	}},
+	{Method: "GET", Path: "/api/admin/retention", Tag: "Admin", Summary: "Retention policy, metrics and the latest run", Data: models.RetentionStatus{}, Formats: []string{"application/openmetrics-text"}, Query: []apiQueryParam{
+		{"format", "openmetrics for OpenMetrics text"},
+	}},
+	{Method: "POST", Path: "/api/admin/retention/run", Tag: "Admin", Summary: "Apply the retention rules now", Data: models.RetentionRun{}, Query: []apiQueryParam{
+		{"dry_run", "true to only report what would be removed"},
+	}},
+	{Method: "GET", Path: "/api/admin/jobs", Tag: "Admin", Summary: "Background jobs of this instance", Data: gin.H{}},
+	{Method: "POST", Path: "/api/admin/jobs/:name/run", Tag: "Admin", Summary: "Run a background job now", Data: gin.H{}},
+	{Method: "POST", Path: "/api/admin/i18n/reload", Tag: "Admin", Summary: "Reload the locale files", Data: []i18n.LanguageReport{}},
+	{Method: "GET", Path: "/api/admin/i18n/validate", Tag: "Admin", Summary: "Missing and extra keys and placeholder mismatches per language", Data: gin.H{}},
+	{Method: "GET", Path: "/api/debug/info", Tag: "Admin", Summary: "Build, uptime, runtime statistics and the redacted configuration", Raw: true, Data: gin.H{}},
+	{Method: "GET", Path: "/api/debug/pprof/*profile", Tag: "Admin", Summary: "pprof index and profiles (ENABLE_PPROF)", Raw: true, Formats: []string{"application/octet-stream"}},
+
+	{Method: "GET", Path: "/v1/models", Tag: "OpenAI", Summary: "OpenAI compatible model list (ENABLE_OPENAI_API)", Raw: true, Data: struct {
+		Object string        `json:"object"`
+		Data   []openAIModel `json:"data"`
+	}{}},
+	{Method: "POST", Path: "/v1/chat/completions", Tag: "OpenAI", Summary: "OpenAI compatible chat completions, streamed as server-sent events with stream (ENABLE_OPENAI_API)", Raw: true, Data: openAIChatCompletion{}, Body: openAIChatRequest{}, Formats: []string{"text/event-stream"}},
+}
+
+// OpenAPIHandler serves the OpenAPI document of the REST API
+func OpenAPIHandler(build buildinfo.BuildInfo) gin.HandlerFunc {
+	doc, err := json.Marshal(BuildOpenAPI(build))
+	if err != nil {
+		utils.Error("Failed to build the OpenAPI document: %v", err)
+	}
+	return func(c *gin.Context) {
+		if doc == nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "OpenAPI document unavailable", RequestID: requestID(c)})
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", doc)
+	}
+}
+
+// APIDocsHandler renders Swagger UI for the OpenAPI document
+func APIDocsHandler(build buildinfo.BuildInfo) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.HTML(http.StatusOK, "pages/api_docs.html", gin.H{
+			"lang":      GetLang(c),
+			"version":   build.Version,
+			"specURL":   "/api/docs/openapi.json",
+			"csrfToken": GetCSRFToken(c),
+		})
+	}
+}
+
+// BuildOpenAPI returns the OpenAPI 3 document of apiRoutes, versioned with the build
+func BuildOpenAPI(build buildinfo.BuildInfo) gin.H {
+	b := &openAPIBuilder{schemas: gin.H{}, names: map[reflect.Type]string{}}
+	paths := gin.H{}
+	for _, route := range apiRoutes {
+		p := openAPIPath(route.Path)
+		if paths[p] == nil {
+			paths[p] = gin.H{}
+		}
+		paths[p].(gin.H)[strings.ToLower(route.Method)] = b.operation(route)
+	}
+
+	description := "REST API of AI Gateway Hub. Successful responses wrap their data as {\"data\": ..., \"message\": ...} unless documented otherwise."
+	if build.Commit != "" {
+		description += " Commit " + build.ShortCommit() + "."
+	}
+	b.schemas["ErrorResponse"] = b.objectOf(reflect.TypeOf(ErrorResponse{}))
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":       "AI Gateway Hub API",
+			"version":     build.Version,
+			"description": description,
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": b.schemas,
+			"responses": gin.H{
+				"Error": gin.H{
+					"description": "Error",
+					"content":     gin.H{"application/json": gin.H{"schema": gin.H{"$ref": "#/components/schemas/ErrorResponse"}}},
+				},
+			},
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer", "description": "JWT access token, or the ADMIN_TOKEN"},
+				"session":    gin.H{"type": "apiKey", "in": "cookie", "name": middleware.SessionCookieName},
+				"csrf":       gin.H{"type": "apiKey", "in": "header", "name": middleware.CSRFHeaderName, "description": "Required with the session cookie for POST, PUT, PATCH and DELETE"},
+			},
+		},
+		"security": []gin.H{{"bearerAuth": []string{}}, {"session": []string{}, "csrf": []string{}}},
+	}
+}
+
+// openAPIPath turns a gin route into an OpenAPI path, e.g. /api/chats/:id into /api/chats/{id}
+func openAPIPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// openAPIBuilder collects the component schemas of the types routes use
+type openAPIBuilder struct {
+	schemas gin.H
+	names   map[reflect.Type]string
+}
+
+// operation documents a route
+func (b *openAPIBuilder) operation(route apiRoute) gin.H {
+	var params []gin.H
+	for _, segment := range strings.Split(route.Path, "/") {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		name := segment[1:]
+		schema := gin.H{"type": "integer", "format": "int64"}
+		if stringPathParams[name] || strings.HasPrefix(route.Path, "/api/admin/sessions/") {
+			schema = gin.H{"type": "string"}
+		}
+		params = append(params, gin.H{"name": name, "in": "path", "required": true, "schema": schema})
+	}
+	for _, q := range route.Query {
+		params = append(params, gin.H{"name": q.Name, "in": "query", "description": q.Description, "schema": gin.H{"type": "string"}})
+	}
+
+	status := "200"
+	if route.Created {
+		status = "201"
+	}
+	content := gin.H{}
+	if route.Data != nil || !route.Raw {
+		schema := gin.H{}
+		if route.Data != nil {
+			schema = b.schemaOf(reflect.TypeOf(route.Data))
+		}
+		if !route.Raw {
+			schema = gin.H{"type": "object", "properties": gin.H{"data": schema, "message": gin.H{"type": "string"}}}
+		}
+		content["application/json"] = gin.H{"schema": schema}
+	}
+	for _, format := range route.Formats {
+		content[format] = gin.H{"schema": gin.H{"type": "string"}}
+	}
+
+	op := gin.H{
+		"tags":        []string{route.Tag},
+		"summary":     route.Summary,
+		"operationId": strings.ToLower(route.Method) + strings.NewReplacer("/", "_", ":", "", "*", "", "-", "_", ".", "_").Replace(route.Path),
+		"responses": gin.H{
+			status:    gin.H{"description": http.StatusText(map[string]int{"200": http.StatusOK, "201": http.StatusCreated}[status]), "content": content},
+			"default": gin.H{"$ref": "#/components/responses/Error"},
+		},
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	switch route.Body.(type) {
+	case nil:
+	case multipartFile:
+		op["requestBody"] = gin.H{"required": true, "content": gin.H{"multipart/form-data": gin.H{"schema": gin.H{
+			"type":       "object",
+			"required":   []string{"file"},
+			"properties": gin.H{"file": gin.H{"type": "string", "format": "binary"}},
+		}}}}
+	default:
+		op["requestBody"] = gin.H{"required": true, "content": gin.H{"application/json": gin.H{"schema": b.schemaOf(reflect.TypeOf(route.Body))}}}
+	}
+	return op
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaOf returns the schema of a Go type as encoding/json marshals it; named structs become
+// component schemas
+func (b *openAPIBuilder) schemaOf(t reflect.Type) gin.H {
+	switch t {
+	case timeType:
+		return gin.H{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return gin.H{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schemaOf(t.Elem())
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return gin.H{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return gin.H{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return gin.H{"type": "string", "format": "byte"}
+		}
+		return gin.H{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.objectOf(t)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+			if _, taken := b.schemas[name]; taken {
+				name = path.Base(t.PkgPath()) + "." + name
+			}
+			// Registered before its fields, so types referring to themselves end
+			b.names[t] = name
+			b.schemas[name] = gin.H{}
+			b.schemas[name] = b.objectOf(t)
+		}
+		return gin.H{"$ref": "#/components/schemas/" + name}
+	}
+	return gin.H{}
+}
+
+// objectOf returns the object schema of a struct's JSON fields, embedded structs included
+func (b *openAPIBuilder) objectOf(t reflect.Type) gin.H {
+	properties := gin.H{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				object := b.objectOf(embedded)
+				for k, v := range object["properties"].(gin.H) {
+					properties[k] = v
+				}
+				if r, ok := object["required"].([]string); ok {
+					required = append(required, r...)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schemaOf(field.Type)
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			required = append(required, name)
+		}
+	}
+
+	object := gin.H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+
+	"ai-gateway-hub/internal/buildinfo"
+	"ai-gateway-hub/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAPIRoutes keeps apiRoutes in step with the API routes main.go registers
+func TestOpenAPIRoutes(t *testing.T) {
+	source, err := os.ReadFile("../../main.go")
+	require.NoError(t, err)
+
+	groups := map[string]string{}
+	for _, m := range regexp.MustCompile(`(\w+) := router\.Group\("([^"]+)"`).FindAllStringSubmatch(string(source), -1) {
+		groups[m[1]] = m[2]
+	}
+	var registered []string
+	for _, m := range regexp.MustCompile(`(\w+)\.(GET|POST|PUT|PATCH|DELETE)\("([^"]+)"`).FindAllStringSubmatch(string(source), -1) {
+		if prefix, ok := groups[m[1]]; ok {
+			registered = append(registered, m[2]+" "+prefix+m[3])
+		}
+	}
+	require.NotEmpty(t, registered)
+
+	var documented []string
+	for _, route := range apiRoutes {
+		documented = append(documented, route.Method+" "+route.Path)
+	}
+	assert.ElementsMatch(t, registered, documented)
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/docs/openapi.json", OpenAPIHandler(buildinfo.BuildInfo{Version: "1.2.3", Commit: "0123456789abcdef"}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, "1.2.3", doc.Info.Version, "the document is versioned with the build")
+
+	// Path parameters are in OpenAPI syntax
+	assert.Contains(t, doc.Paths["/api/chats/{id}/messages/{msgid}"], "put")
+	assert.Contains(t, doc.Paths["/api/debug/pprof/{profile}"], "get")
+	for path := range doc.Paths {
+		assert.NotContains(t, path, ":")
+	}
+
+	// Schemas are built from the types handlers use
+	chat := doc.Components.Schemas["Chat"]
+	assert.Contains(t, chat.Properties, "id")
+	assert.Contains(t, chat.Properties, "created_at")
+	assert.JSONEq(t, `{"type": "string", "format": "date-time"}`, string(chat.Properties["created_at"]))
+	assert.Equal(t, []string{"user_id", "password"}, doc.Components.Schemas["LoginRequest"].Required)
+	assert.Contains(t, doc.Components.Schemas, "ErrorResponse")
+	assert.Contains(t, doc.Components.Schemas, "OpenAIChatRequest")
+
+	var upload struct {
+		RequestBody struct {
+			Content map[string]json.RawMessage `json:"content"`
+		} `json:"requestBody"`
+		Responses map[string]json.RawMessage `json:"responses"`
+	}
+	require.NoError(t, json.Unmarshal(doc.Paths["/api/chats/{id}/attachments"]["post"], &upload))
+	assert.Contains(t, upload.RequestBody.Content, "multipart/form-data")
+	assert.Contains(t, upload.Responses, "201")
+}
+
+func TestAPIDocsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	require.NoError(t, i18n.Init("../../locales", "en"))
+	tmpl := template.Must(template.New("").Funcs(i18n.TemplateFuncs()).ParseGlob("../../web/templates/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/pages/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/components/*.html"))
+
+	router := gin.New()
+	router.SetHTMLTemplate(tmpl)
+	router.GET("/api/docs", APIDocsHandler(buildinfo.BuildInfo{Version: "1.2.3"}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "API Documentation 1.2.3")
+	assert.Contains(t, body, "swagger-ui-bundle.js")
+	assert.Contains(t, body, `url: '\/api\/docs\/openapi.json'`)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTagsHandler lists tags with the number of chats carrying them
+func (h *APIHandlers) GetTagsHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tags, err := chatService.ListTags(c.Request.Context())
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get tags", err)
+			return
+		}
+
+		h.errorHandler.Success(c, tags)
+	}
+}
+
+// DeleteTagHandler removes a tag from all chats and deletes it
+func (h *APIHandlers) DeleteTagHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tagID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid tag ID", err)
+			return
+		}
+
+		if err := chatService.DeleteTag(c.Request.Context(), tagID); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to delete tag", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Tag deleted successfully")
+	}
+}
+
+// TagChatHandler adds a tag to a chat
+func (h *APIHandlers) TagChatHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		var req struct {
+			Tag string `json:"tag" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		if _, err := chatService.GetChat(c.Request.Context(), chatID); err != nil {
+			h.errorHandler.NotFound(c, "Chat not found")
+			return
+		}
+
+		if err := chatService.TagChat(c.Request.Context(), chatID, req.Tag); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to tag chat", err)
+			return
+		}
+
+		chat, err := chatService.GetChat(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get chat", err)
+			return
+		}
+		h.errorHandler.Success(c, chat, "Chat tagged successfully")
+	}
+}
+
+// UntagChatHandler removes a tag from a chat
+func (h *APIHandlers) UntagChatHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		if err := chatService.UntagChat(c.Request.Context(), chatID, c.Param("tag")); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to untag chat", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Tag removed successfully")
+	}
+}
+
+// GetFoldersHandler lists folders with the number of chats filed in them
+func (h *APIHandlers) GetFoldersHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		folders, err := chatService.ListFolders(c.Request.Context())
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get folders", err)
+			return
+		}
+
+		h.errorHandler.Success(c, folders)
+	}
+}
+
+// folderRequest is the body of create and rename requests for folders
+type folderRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// CreateFolderHandler creates a folder
+func (h *APIHandlers) CreateFolderHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req folderRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		folder, err := chatService.CreateFolder(c.Request.Context(), req.Name)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to create folder", err)
+			return
+		}
+
+		h.errorHandler.Created(c, folder, "Folder created successfully")
+	}
+}
+
+// RenameFolderHandler renames a folder
+func (h *APIHandlers) RenameFolderHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		folderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid folder ID", err)
+			return
+		}
+
+		var req folderRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		folder, err := chatService.RenameFolder(c.Request.Context(), folderID, req.Name)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to rename folder", err)
+			return
+		}
+
+		h.errorHandler.Success(c, folder, "Folder renamed successfully")
+	}
+}
+
+// DeleteFolderHandler deletes a folder, keeping the chats that were in it
+func (h *APIHandlers) DeleteFolderHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		folderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid folder ID", err)
+			return
+		}
+
+		if err := chatService.DeleteFolder(c.Request.Context(), folderID); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to delete folder", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Folder deleted successfully")
+	}
+}
+
+// MoveChatToFolderHandler files a chat in a folder; a null folder_id takes it out of its folder
+func (h *APIHandlers) MoveChatToFolderHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		var req struct {
+			FolderID *int64 `json:"folder_id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		if err := chatService.MoveChatToFolder(c.Request.Context(), chatID, req.FolderID); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to move chat", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Chat moved successfully")
+	}
+}
+
+// BulkChatsHandler deletes, archives or tags several chats at once, reporting the result per chat
+func (h *APIHandlers) BulkChatsHandler(chatService *services.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Action  string  `json:"action" binding:"required"`
+			ChatIDs []int64 `json:"chat_ids" binding:"required"`
+			Tag     string  `json:"tag"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		response, err := chatService.BulkUpdateChats(c.Request.Context(), req.Action, req.ChatIDs, req.Tag)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to update chats", err)
+			return
+		}
+
+		h.errorHandler.Success(c, response, fmt.Sprintf("%d of %d chats updated", response.Succeeded, len(response.Results)))
+	}
+}
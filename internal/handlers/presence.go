@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/utils"
+)
+
+// present reports whether a participant has a connection subscribed to the chat; h.mu must be held
+func (h *Hub) present(chatID int64, userID string) bool {
+	for client := range h.rooms[chatID] {
+		if client.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// participants returns the participants subscribed to the chat on this instance, sorted; h.mu
+// must be held
+func (h *Hub) participants(chatID int64) []string {
+	seen := make(map[string]bool)
+	participants := make([]string, 0)
+	for client := range h.rooms[chatID] {
+		if client.userID != "" && !seen[client.userID] {
+			seen[client.userID] = true
+			participants = append(participants, client.userID)
+		}
+	}
+	sort.Strings(participants)
+	return participants
+}
+
+// broadcastPresence tells the other viewers of a chat, on any instance, that the client's
+// participant joined (user_joined) or left (user_left) it
+func (h *Hub) broadcastPresence(msgType string, chatID int64, client *Client) {
+	data, err := json.Marshal(models.WebSocketMessage{
+		Type:    msgType,
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    chatID,
+			UserID:    client.userID,
+			Timestamp: time.Now(),
+		},
+	})
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal %s message: %v", client.requestID, msgType, err)
+		return
+	}
+	h.broadcastToChat(chatID, data, client)
+}
+
+// sendPresence sends the client the participants of a chat it subscribed to, itself included
+func (c *Client) sendPresence(chatID int64, participants []string) {
+	data, err := json.Marshal(models.WebSocketMessage{
+		Type:    "presence",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:       chatID,
+			UserID:       c.userID,
+			Participants: participants,
+			Timestamp:    time.Now(),
+		},
+	})
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal presence message: %v", c.requestID, err)
+		return
+	}
+
+	select {
+	case c.send <- data:
+	default:
+		utils.Debug("[request_id=%s] Dropped presence message for slow client", c.requestID)
+	}
+}
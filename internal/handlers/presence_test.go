@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addParticipant registers a client of a participant directly, bypassing the WebSocket connection
+func addParticipant(hub *Hub, userID string) *Client {
+	client := &Client{hub: hub, userID: userID, send: make(chan []byte, 8), gone: make(chan struct{}), ctx: context.Background()}
+	hub.mu.Lock()
+	hub.clients[client] = true
+	hub.mu.Unlock()
+	return client
+}
+
+// nextMessage reads the next message sent to a client
+func nextMessage(t *testing.T, client *Client) models.WebSocketMessage {
+	t.Helper()
+	require.NotEmpty(t, client.send)
+	var msg models.WebSocketMessage
+	require.NoError(t, json.Unmarshal(<-client.send, &msg))
+	return msg
+}
+
+func TestHub_Presence(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	alice := addParticipant(hub, "alice")
+	bob := addParticipant(hub, "bob")
+	aliceTab := addParticipant(hub, "alice")
+
+	// Joining sends the participants to the joiner and announces it to the others
+	hub.subscribe(alice, 1)
+	msg := nextMessage(t, alice)
+	assert.Equal(t, "presence", msg.Type)
+	assert.Equal(t, []string{"alice"}, msg.Data.Participants)
+
+	hub.subscribe(bob, 1)
+	msg = nextMessage(t, bob)
+	assert.Equal(t, []string{"alice", "bob"}, msg.Data.Participants)
+	msg = nextMessage(t, alice)
+	assert.Equal(t, "user_joined", msg.Type)
+	assert.Equal(t, "bob", msg.Data.UserID)
+	assert.Equal(t, int64(1), msg.Data.ChatID)
+
+	// A second tab of a participant isn't announced, nor is closing one of them
+	hub.subscribe(aliceTab, 1)
+	nextMessage(t, aliceTab)
+	assert.Empty(t, bob.send)
+	hub.unsubscribe(alice, 1)
+	assert.Empty(t, bob.send)
+
+	// Prompts carry their sender to the other participants
+	bob.relayUserMessage(&models.Message{ID: 7, ChatID: 1, Role: "user", Content: "hi", UserID: "bob"}, "claude")
+	msg = nextMessage(t, aliceTab)
+	assert.Equal(t, "user_message", msg.Type)
+	assert.Equal(t, "bob", msg.Data.UserID)
+
+	// Disconnecting the last connection of a participant announces that it left
+	go hub.Run()
+	hub.unregister <- aliceTab
+	assert.Eventually(t, func() bool { return len(bob.send) == 1 }, time.Second, 10*time.Millisecond)
+	msg = nextMessage(t, bob)
+	assert.Equal(t, "user_left", msg.Type)
+	assert.Equal(t, "alice", msg.Data.UserID)
+}
+
+func TestHub_PresenceWithoutIdentity(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	anonymous := addParticipant(hub, "")
+	bob := addParticipant(hub, "bob")
+	hub.subscribe(bob, 1)
+	nextMessage(t, bob)
+
+	// Clients without a participant ID follow chats without presence
+	hub.subscribe(anonymous, 1)
+	assert.Empty(t, anonymous.send)
+	assert.Empty(t, bob.send)
+	hub.unsubscribe(anonymous, 1)
+	assert.Empty(t, bob.send)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"strconv"
+
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// chatProcessorRequest is the body of PUT /api/chats/:id/processors/:name
+type chatProcessorRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// GetChatProcessorsHandler lists the prompt and response processors in the order they run, with
+// whether the chat runs them
+func (h *APIHandlers) GetChatProcessorsHandler(processingService *services.ProcessingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		processors, err := processingService.ChatProcessors(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to get processors", err)
+			return
+		}
+
+		h.errorHandler.Success(c, processors)
+	}
+}
+
+// SetChatProcessorHandler enables or disables a processor in a chat
+func (h *APIHandlers) SetChatProcessorHandler(processingService *services.ProcessingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		var req chatProcessorRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		if err := processingService.SetChatProcessor(c.Request.Context(), chatID, c.Param("name"), *req.Enabled); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to update processor", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Processor updated successfully")
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/processing"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatProcessorHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Processed", "claude")
+	require.NoError(t, err)
+	pipeline, err := processing.Build([]processing.Entry{
+		{Name: "strip-html", Type: processing.TypeReplace, Stage: processing.StagePost, Pattern: "<[^>]+>"},
+	})
+	require.NoError(t, err)
+	processingService := services.NewProcessingService(db, chatService, pipeline)
+
+	apiHandlers := NewAPIHandlers(nil)
+	router := gin.New()
+	router.GET("/api/chats/:id/processors", apiHandlers.GetChatProcessorsHandler(processingService))
+	router.PUT("/api/chats/:id/processors/:name", apiHandlers.SetChatProcessorHandler(processingService))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	chatPath := "/api/chats/" + strconv.FormatInt(chat.ID, 10)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, chatPath+"/processors/strip-html", `{"enabled": false}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(http.MethodPut, chatPath+"/processors/strip-html", `{}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPut, chatPath+"/processors/translate", `{"enabled": true}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/chats/999/processors", "").Code)
+
+	w := serve(http.MethodGet, chatPath+"/processors", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data []models.ChatProcessor `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, models.ChatProcessor{Name: "strip-html", Stage: processing.StagePost, Enabled: false, Default: true}, resp.Data[0])
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRetentionHandler returns the retention policy, the metrics of each rule and the latest run
+// (?format=openmetrics returns the metrics in the OpenMetrics text format)
+func (h *APIHandlers) GetRetentionHandler(retentionService *services.RetentionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := retentionService.Status()
+		if c.Query("format") == "openmetrics" {
+			c.Data(http.StatusOK, "application/openmetrics-text; version=1.0.0; charset=utf-8", []byte(services.FormatRetentionMetrics(status.Metrics)))
+			return
+		}
+
+		h.errorHandler.Success(c, status)
+	}
+}
+
+// RunRetentionHandler applies the retention rules now (?dry_run=true only reports what they would
+// remove; defaults to RETENTION_DRY_RUN)
+func (h *APIHandlers) RunRetentionHandler(retentionService *services.RetentionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dryRun := retentionService.Status().Policy.DryRun
+		if s := c.Query("dry_run"); s != "" {
+			parsed, err := strconv.ParseBool(s)
+			if err != nil {
+				h.errorHandler.BadRequest(c, "Invalid dry_run, expected true or false", err)
+				return
+			}
+			dryRun = parsed
+		}
+
+		run, err := retentionService.Run(c.Request.Context(), services.RetentionTriggerManual, dryRun)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to run retention rules", err)
+			return
+		}
+
+		h.errorHandler.Success(c, run)
+	}
+}
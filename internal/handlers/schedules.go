@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scheduleRequest is the body of create and update requests for scheduled prompts
+type scheduleRequest struct {
+	Name     string `json:"name"`
+	ChatID   int64  `json:"chat_id"` // chat receiving the responses; 0 creates a chat named after the schedule
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Prompt   string `json:"prompt"`
+	Cron     string `json:"cron"`
+	Timezone string `json:"timezone"`
+	Enabled  *bool  `json:"enabled"` // defaults to true
+}
+
+// GetSchedulesHandler lists scheduled prompts
+func (h *APIHandlers) GetSchedulesHandler(scheduleService *services.ScheduleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schedules, err := scheduleService.List()
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get scheduled prompts", err)
+			return
+		}
+
+		h.errorHandler.Success(c, schedules)
+	}
+}
+
+// GetScheduleHandler returns a scheduled prompt
+func (h *APIHandlers) GetScheduleHandler(scheduleService *services.ScheduleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schedule, ok := h.loadSchedule(c, scheduleService)
+		if !ok {
+			return
+		}
+
+		h.errorHandler.Success(c, schedule)
+	}
+}
+
+// CreateScheduleHandler creates a scheduled prompt
+func (h *APIHandlers) CreateScheduleHandler(scheduleService *services.ScheduleService, chatService *services.ChatService, registry *services.ProviderRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req scheduleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		schedule := &models.ScheduledPrompt{}
+		if !h.applyScheduleRequest(c, schedule, req, chatService, registry) {
+			return
+		}
+
+		if schedule.ChatID == 0 {
+			chat, err := chatService.CreateChat(c.Request.Context(), schedule.Name, schedule.Provider)
+			if err != nil {
+				h.errorHandler.InternalError(c, "Failed to create chat", err)
+				return
+			}
+			schedule.ChatID = chat.ID
+		}
+
+		created, err := scheduleService.Create(schedule)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to create scheduled prompt", err)
+			return
+		}
+
+		h.errorHandler.Created(c, created, "Scheduled prompt created successfully")
+	}
+}
+
+// UpdateScheduleHandler replaces the settings of a scheduled prompt
+func (h *APIHandlers) UpdateScheduleHandler(scheduleService *services.ScheduleService, chatService *services.ChatService, registry *services.ProviderRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schedule, ok := h.loadSchedule(c, scheduleService)
+		if !ok {
+			return
+		}
+
+		var req scheduleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		// Keep the current chat unless another one is given
+		if req.ChatID == 0 {
+			req.ChatID = schedule.ChatID
+		}
+		if !h.applyScheduleRequest(c, schedule, req, chatService, registry) {
+			return
+		}
+
+		updated, err := scheduleService.Update(schedule)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to update scheduled prompt", err)
+			return
+		}
+
+		h.errorHandler.Success(c, updated, "Scheduled prompt updated successfully")
+	}
+}
+
+// DeleteScheduleHandler deletes a scheduled prompt and its run history
+func (h *APIHandlers) DeleteScheduleHandler(scheduleService *services.ScheduleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid schedule ID", err)
+			return
+		}
+
+		if err := scheduleService.Delete(id); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to delete scheduled prompt", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Scheduled prompt deleted successfully")
+	}
+}
+
+// RunScheduleHandler runs a scheduled prompt now; the result is delivered like a scheduled run
+func (h *APIHandlers) RunScheduleHandler(scheduleService *services.ScheduleService, schedulerService *services.SchedulerService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schedule, ok := h.loadSchedule(c, scheduleService)
+		if !ok {
+			return
+		}
+
+		if err := schedulerService.Trigger(schedule); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to run scheduled prompt", err)
+			return
+		}
+
+		h.errorHandler.Success(c, schedule, "Scheduled prompt started")
+	}
+}
+
+// GetScheduleRunsHandler returns the most recent runs of a scheduled prompt (?limit=20, at most 100)
+func (h *APIHandlers) GetScheduleRunsHandler(scheduleService *services.ScheduleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schedule, ok := h.loadSchedule(c, scheduleService)
+		if !ok {
+			return
+		}
+
+		limit := 20
+		if l := c.Query("limit"); l != "" {
+			if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+				limit = parsed
+			}
+		}
+
+		runs, err := scheduleService.ListRuns(schedule.ID, limit)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get scheduled prompt runs", err)
+			return
+		}
+
+		h.errorHandler.Success(c, runs)
+	}
+}
+
+// loadSchedule loads the scheduled prompt named by the :id parameter, writing an error response if it fails
+func (h *APIHandlers) loadSchedule(c *gin.Context, scheduleService *services.ScheduleService) (*models.ScheduledPrompt, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.errorHandler.BadRequest(c, "Invalid schedule ID", err)
+		return nil, false
+	}
+
+	schedule, err := scheduleService.Get(id)
+	if err != nil {
+		h.errorHandler.ServiceError(c, "Failed to get scheduled prompt", err)
+		return nil, false
+	}
+	return schedule, true
+}
+
+// applyScheduleRequest copies a request onto a scheduled prompt and validates it, writing an
+// error response if it is invalid
+func (h *APIHandlers) applyScheduleRequest(c *gin.Context, schedule *models.ScheduledPrompt, req scheduleRequest, chatService *services.ChatService, registry *services.ProviderRegistry) bool {
+	schedule.Name = req.Name
+	schedule.ChatID = req.ChatID
+	schedule.Provider = req.Provider
+	schedule.Model = req.Model
+	schedule.Prompt = req.Prompt
+	schedule.Cron = req.Cron
+	schedule.Timezone = req.Timezone
+	schedule.Enabled = req.Enabled == nil || *req.Enabled
+
+	if err := services.ValidateScheduledPrompt(schedule); err != nil {
+		h.errorHandler.ValidationError(c, "Invalid scheduled prompt", err)
+		return false
+	}
+	if err := chatService.ValidatePrompt(schedule.Prompt); err != nil {
+		h.errorHandler.ValidationError(c, promptErrorMessage(GetLang(c), err), err)
+		return false
+	}
+
+	provider, err := registry.Get(schedule.Provider)
+	if err != nil {
+		h.errorHandler.ValidationError(c, "Unknown provider", err)
+		return false
+	}
+	if schedule.Model != "" && !providers.SupportsModel(provider, schedule.Model) {
+		h.errorHandler.ValidationError(c, "Unsupported model", fmt.Errorf("model %s is not supported by %s", schedule.Model, schedule.Provider))
+		return false
+	}
+
+	if schedule.ChatID != 0 {
+		if _, err := chatService.GetChat(c.Request.Context(), schedule.ChatID); err != nil {
+			h.errorHandler.NotFound(c, "Chat not found")
+			return false
+		}
+	}
+	return true
+}
@@ -7,12 +7,15 @@ import (
 )
 
 // SettingsHandler handles the settings page
-func SettingsHandler() gin.HandlerFunc {
+func SettingsHandler(isAdmin func(c *gin.Context) bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		lang := GetLang(c)
 
 		c.HTML(http.StatusOK, "pages/settings.html", gin.H{
-			"lang": lang,
+			"lang":      lang,
+			"theme":     GetTheme(c),
+			"isAdmin":   isAdmin(c),
+			"csrfToken": GetCSRFToken(c),
 		})
 	}
 }
\ No newline at end of file
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shareRequest is the optional body of POST /api/chats/:id/share
+type shareRequest struct {
+	ExpiresInHours int `json:"expires_in_hours"` // 0 keeps the link valid until revoked
+}
+
+// CreateShareHandler creates a public read-only link to a chat
+func (h *APIHandlers) CreateShareHandler(shareService *services.ShareService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		var req shareRequest
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		share, err := shareService.Create(c.Request.Context(), chatID, time.Duration(req.ExpiresInHours)*time.Hour)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to share chat", err)
+			return
+		}
+
+		h.errorHandler.Created(c, share, "Share link created successfully")
+	}
+}
+
+// GetSharesHandler lists the share links of a chat with their view counts
+func (h *APIHandlers) GetSharesHandler(shareService *services.ShareService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		shares, err := shareService.List(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to get share links", err)
+			return
+		}
+
+		h.errorHandler.Success(c, shares)
+	}
+}
+
+// RevokeShareHandler revokes a share link of a chat
+func (h *APIHandlers) RevokeShareHandler(shareService *services.ShareService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		if err := shareService.Revoke(c.Request.Context(), chatID, c.Param("shareId")); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to revoke share link", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Share link revoked successfully")
+	}
+}
+
+// SharedChatHandler renders a shared chat read-only, without a WebSocket connection
+func SharedChatHandler(shareService *services.ShareService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := GetLang(c)
+		t := GetTranslator(c)
+
+		share, chat, messages, err := shareService.Open(c.Request.Context(), c.Param("token"))
+		if err != nil {
+			status := http.StatusNotFound
+			message := t("share.notFound")
+			if !errors.Is(err, services.ErrShareNotFound) {
+				utils.Error("SharedChatHandler: failed to open shared chat: %v", err)
+				status = http.StatusInternalServerError
+				message = t("error.failedToLoadMessages")
+			}
+			c.HTML(status, "pages/error.html", gin.H{
+				"error": message,
+				"lang":  lang,
+			})
+			return
+		}
+
+		// Shared pages must not leak the token to other sites or end up in search results
+		c.Header("Referrer-Policy", "no-referrer")
+		c.Header("X-Robots-Tag", "noindex")
+		c.HTML(http.StatusOK, "pages/share.html", gin.H{
+			"title":    chat.Title,
+			"chat":     chat,
+			"share":    share,
+			"messages": messages,
+			"lang":     lang,
+			"theme":    GetTheme(c),
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	require.NoError(t, i18n.Init("../../locales", "en"))
+
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Shared <chat>", "claude")
+	require.NoError(t, err)
+	_, err = chatService.AddMessage(context.Background(), chat.ID, "user", "What is a goroutine?")
+	require.NoError(t, err)
+	_, err = chatService.AddProviderMessage(context.Background(), chat.ID, "assistant", "A lightweight thread.", "claude")
+	require.NoError(t, err)
+	shareService := services.NewShareService(db, chatService, []byte(strings.Repeat("s", 32)))
+
+	tmpl := template.Must(template.New("").Funcs(i18n.TemplateFuncs()).ParseGlob("../../web/templates/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/pages/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/components/*.html"))
+
+	apiHandlers := NewAPIHandlers(nil)
+	router := gin.New()
+	router.SetHTMLTemplate(tmpl)
+	router.POST("/api/chats/:id/share", apiHandlers.CreateShareHandler(shareService))
+	router.GET("/api/chats/:id/shares", apiHandlers.GetSharesHandler(shareService))
+	router.DELETE("/api/chats/:id/shares/:shareId", apiHandlers.RevokeShareHandler(shareService))
+	router.GET("/share/:token", SharedChatHandler(shareService))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	chatPath := "/api/chats/" + strconv.FormatInt(chat.ID, 10)
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/api/chats/999/share", "").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(http.MethodPost, chatPath+"/share", `{"expires_in_hours": -1}`).Code)
+
+	w := serve(http.MethodPost, chatPath+"/share", "")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.ChatShare `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	share := created.Data
+	assert.Equal(t, "/share/"+share.Token, share.URL)
+	assert.Nil(t, share.ExpiresAt)
+
+	// The page shows the conversation without the chat UI and counts the view
+	w = serve(http.MethodGet, share.URL, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "Shared &lt;chat&gt;")
+	assert.Contains(t, body, "What is a goroutine?")
+	assert.Contains(t, body, "A lightweight thread.")
+	assert.NotContains(t, body, "chat.js")
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+
+	w = serve(http.MethodGet, chatPath+"/shares", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed struct {
+		Data []models.ChatShare `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed.Data, 1)
+	assert.Equal(t, int64(1), listed.Data[0].Views)
+
+	// Forged and revoked links show the not found page
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/share/"+share.ID+".forged", "").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, chatPath+"/shares/"+share.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, chatPath+"/shares/"+share.ID, "").Code)
+	w = serve(http.MethodGet, share.URL, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "This share link is invalid")
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagesRenderTheme(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	require.NoError(t, i18n.Init("../../locales", "en"))
+
+	tmpl := template.Must(template.New("").Funcs(i18n.TemplateFuncs()).ParseGlob("../../web/templates/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/pages/*.html"))
+	template.Must(tmpl.ParseGlob("../../web/templates/components/*.html"))
+
+	router := gin.New()
+	router.SetHTMLTemplate(tmpl)
+	router.Use(middleware.ThemeMiddleware())
+	router.GET("/", IndexHandler())
+	router.GET("/settings", SettingsHandler(func(c *gin.Context) bool { return false }))
+
+	render := func(path, theme string) string {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if theme != "" {
+			req.AddCookie(&http.Cookie{Name: "theme", Value: theme})
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code)
+		return resp.Body.String()
+	}
+
+	assert.Contains(t, render("/", "dark"), `data-theme="dark" class="dark"`)
+	assert.Contains(t, render("/settings", "dark"), `data-theme="dark" class="dark"`)
+	assert.Contains(t, render("/", "auto"), `data-theme="auto" class=""`, "auto is resolved by the browser before the first paint")
+	assert.Contains(t, render("/", ""), `data-theme="light"`)
+	assert.Contains(t, render("/", "neon"), `data-theme="light"`, "unknown themes fall back to the default")
+}
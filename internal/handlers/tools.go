@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/tools"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// chatToolsRequest is the body of PUT /api/chats/:id/tools
+type chatToolsRequest struct {
+	Tools []string `json:"tools"`
+}
+
+// chatToolset returns the tools a chat lets providers call; failures to load them are only logged
+func (c *Client) chatToolset(chatID int64) tools.Toolset {
+	if c.hub.toolService == nil {
+		return nil
+	}
+	toolset, err := c.hub.toolService.Toolset(c.ctx, chatID)
+	if err != nil {
+		utils.Warn("[request_id=%s] Failed to load tools of chat %d: %v", c.requestID, chatID, err)
+	}
+	return toolset
+}
+
+// continueWithTools runs the tool call of each step of a response and re-prompts the provider with
+// the result, until a step calls no tool or the response made the most calls allowed. Steps stream
+// into the same response; it returns everything sent to the provider.
+func (c *Client) continueWithTools(ctx context.Context, provider providers.AIProvider, chatID int64, generationID string, toolset tools.Toolset, session *providers.Session, input string, writer *websocketWriter) (string, error) {
+	providerID := provider.GetID()
+	sent, stepInput, stepStart := input, input, 0
+	for step := 0; step < c.hub.toolService.MaxSteps(); step++ {
+		output := (*writer.buffer)[stepStart:]
+		call, end, ok := tools.FindCall(output)
+		if !ok || writer.oversized {
+			break
+		}
+
+		// Clients get what streamed before the call; the idle timeout covers the provider, not the tool
+		if err := writer.Flush(); err != nil {
+			return sent, err
+		}
+		if writer.idleTimer != nil {
+			writer.idleTimer.Stop()
+		}
+		c.sendToolStep("tool_call", chatID, providerID, generationID, call.Name, call.Input, "")
+		result, err := c.hub.toolService.Run(ctx, toolset, call)
+		if err != nil {
+			utils.Info("[request_id=%s] %s tool call %q in chat %d failed: %v", c.requestID, providerID, call.Name, chatID, err)
+			c.sendToolStep("tool_result", chatID, providerID, generationID, call.Name, err.Error(), "failed")
+		} else {
+			utils.Info("[request_id=%s] %s called tool %s in chat %d", c.requestID, providerID, call.Name, chatID)
+			c.sendToolStep("tool_result", chatID, providerID, generationID, call.Name, tools.Truncate(result), "")
+		}
+		if ctx.Err() != nil {
+			return sent, context.Cause(ctx)
+		}
+
+		// Providers without a session are given the exchange so far with the result
+		followUp := tools.FormatResult(call, result, err)
+		if session == nil || session.ID == "" {
+			followUp = stepInput + "\n\n" + output[:end] + "\n\n" + followUp
+		}
+		if _, err := writer.Write([]byte("\n\n")); err != nil {
+			return sent, err
+		}
+		stepStart = len(*writer.buffer)
+		stepInput = followUp
+		sent += "\n\n" + followUp
+		if err := provider.StreamResponse(ctx, stepInput, chatID, writer); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// sendToolStep tells the chat's clients about a tool call (tool_call, with the input) or its
+// result (tool_result, with the output or error)
+func (c *Client) sendToolStep(msgType string, chatID int64, providerID, generationID, tool, content, action string) {
+	data, err := json.Marshal(models.WebSocketMessage{
+		Type:    msgType,
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    chatID,
+			Provider:  providerID,
+			StreamID:  generationID,
+			Tool:      tool,
+			Content:   content,
+			Action:    action,
+			Timestamp: time.Now(),
+		},
+	})
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal %s message: %v", c.requestID, msgType, err)
+		return
+	}
+
+	select {
+	case <-c.gone:
+	default:
+		select {
+		case c.send <- data:
+		default:
+			utils.Debug("[request_id=%s] Dropped %s for slow client", c.requestID, msgType)
+		}
+	}
+	c.hub.broadcastToChat(chatID, data, c)
+}
+
+// GetChatToolsHandler lists the tools providers can call, with whether the chat enabled them
+func (h *APIHandlers) GetChatToolsHandler(toolService *services.ToolService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		chatTools, err := toolService.ChatTools(c.Request.Context(), chatID)
+		if err != nil {
+			h.errorHandler.ServiceError(c, "Failed to get tools", err)
+			return
+		}
+
+		h.errorHandler.Success(c, chatTools)
+	}
+}
+
+// SetChatToolsHandler replaces the tools a chat enables; an empty list disables tool calling
+func (h *APIHandlers) SetChatToolsHandler(toolService *services.ToolService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			h.errorHandler.BadRequest(c, "Invalid chat ID", err)
+			return
+		}
+
+		var req chatToolsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorHandler.ValidationError(c, "Invalid request", err)
+			return
+		}
+
+		if err := toolService.SetChatTools(c.Request.Context(), chatID, req.Tools); err != nil {
+			h.errorHandler.ServiceError(c, "Failed to update tools", err)
+			return
+		}
+
+		h.errorHandler.Success(c, nil, "Tools updated successfully")
+	}
+}
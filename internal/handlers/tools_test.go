@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/tools"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedProvider answers each prompt with the next reply of a script, recording the prompts
+type scriptedProvider struct {
+	mockAIProvider
+	replies []string
+	prompts []string
+}
+
+func (p *scriptedProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	p.prompts = append(p.prompts, prompt)
+	reply := p.replies[0]
+	if len(p.replies) > 1 {
+		p.replies = p.replies[1:]
+	}
+	_, err := writer.Write([]byte(reply))
+	return err
+}
+
+func TestStreamProviderResponse_Tools(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(ctx, "Tools", "mock")
+	require.NoError(t, err)
+	registry, err := tools.NewRegistry(tools.Calculator{})
+	require.NoError(t, err)
+	toolService := services.NewToolService(db, chatService, registry, 2, time.Second)
+	require.NoError(t, toolService.SetChatTools(ctx, chat.ID, []string{"calculator"}))
+
+	hub := NewHub(nil, chatService, nil, nil, nil, nil)
+	hub.SetTools(toolService)
+	client := addTestClient(hub, chat.ID, false)
+	client.send = make(chan []byte, 64)
+
+	provider := &scriptedProvider{mockAIProvider: mockAIProvider{name: "mock", healthy: true}, replies: []string{
+		`<tool_call>{"name": "calculator", "input": "6 * 7"}</tool_call>`,
+		"The answer is 42.",
+	}}
+	client.streamProviderResponse(provider, chat.ID, nil, "What is 6 times 7?", nil, "", "gen-1", nil)
+
+	require.Len(t, provider.prompts, 2)
+	assert.Contains(t, provider.prompts[0], "- calculator: Evaluates", "providers are told about the chat's tools")
+	assert.Contains(t, provider.prompts[0], "What is 6 times 7?")
+	assert.Contains(t, provider.prompts[1], "<tool_result name=\"calculator\">\n42\n</tool_result>")
+	assert.Contains(t, provider.prompts[1], "What is 6 times 7?", "providers without sessions get the whole exchange")
+
+	var steps []string
+	var saved *models.WSMsgData
+	for len(client.send) > 0 {
+		msg := receiveFrame(t, client)
+		switch msg.Type {
+		case "tool_call", "tool_result":
+			assert.Equal(t, "calculator", msg.Data.Tool)
+			assert.Equal(t, "gen-1", msg.Data.StreamID)
+			steps = append(steps, msg.Type+":"+msg.Data.Content)
+		case "ai_response_saved":
+			saved = &msg.Data
+		}
+	}
+	assert.Equal(t, []string{"tool_call:6 * 7", "tool_result:42"}, steps)
+	require.NotNil(t, saved)
+	require.NotNil(t, saved.Metadata, "clients are told how the response was generated")
+	assert.Equal(t, "mock", saved.Metadata.Provider)
+	assert.Equal(t, models.FinishStop, saved.Metadata.FinishReason)
+	assert.True(t, saved.Metadata.TokensEstimated)
+	assert.Positive(t, saved.Metadata.TokensIn)
+	assert.Positive(t, saved.Metadata.TokensOut)
+
+	messages, err := chatService.GetMessages(ctx, chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "<tool_call>{\"name\": \"calculator\", \"input\": \"6 * 7\"}</tool_call>\n\nThe answer is 42.", messages[0].Content)
+	assert.Equal(t, saved.Metadata, messages[0].Metadata)
+
+	// A provider that keeps calling tools is stopped after the most calls allowed
+	looping := &scriptedProvider{mockAIProvider: mockAIProvider{name: "mock", healthy: true}, replies: []string{
+		`<tool_call>{"name": "shell", "input": "ls"}</tool_call>`,
+	}}
+	client.streamProviderResponse(looping, chat.ID, nil, "List files", nil, "", "gen-2", nil)
+	assert.Len(t, looping.prompts, 3)
+	assert.Contains(t, looping.prompts[1], `error: unknown tool "shell"`)
+
+	// Chats without tools are prompted as they are
+	require.NoError(t, toolService.SetChatTools(ctx, chat.ID, nil))
+	plain := &scriptedProvider{mockAIProvider: mockAIProvider{name: "mock", healthy: true}, replies: []string{"Hi"}}
+	client.streamProviderResponse(plain, chat.ID, nil, "Hello", nil, "", "gen-3", nil)
+	assert.Equal(t, []string{"Hello"}, plain.prompts)
+}
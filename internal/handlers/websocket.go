@@ -3,99 +3,124 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/i18n"
 	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/moderation"
+	"ai-gateway-hub/internal/processing"
+	"ai-gateway-hub/internal/providers"
 	"ai-gateway-hub/internal/services"
 	"ai-gateway-hub/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/websocket"
 )
 
 const (
 	// WebSocket message size limit (512KB)
 	MaxWebSocketMessageSize = 512 * 1024
+
+	// Maximum number of providers a single compare-mode prompt may fan out to
+	MaxCompareProviders = 4
+
+	// Maximum number of images sent with a single prompt
+	MaxPromptImages = 10
+
+	// Maximum number of chats a single connection receives live messages of
+	MaxChatSubscriptions = 32
+
+	// How often ai_progress reports a running generation
+	GenerationProgressInterval = 2 * time.Second
+)
+
+// Kinds of ai_response_timeout
+const (
+	ResponseTimeoutOverall = "overall"
+	ResponseTimeoutIdle    = "idle"
+)
+
+// Kinds of ai_response_oversized
+const (
+	OversizedResponseTruncated = "truncated"
+	OversizedResponseReported  = "reported"
+)
+
+var (
+	errResponseTimeout     = errors.New("response timed out")
+	errResponseIdleTimeout = errors.New("response stalled without output")
+	errResponseTooLarge    = errors.New("response exceeded the size limit")
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return checkWebSocketOrigin(r)
-	},
+// newUpgrader creates a WebSocket upgrader accepting the origins allowed by ALLOWED_ORIGINS
+func newUpgrader(cfg *config.Config) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			return checkWebSocketOrigin(r, cfg)
+		},
+	}
 }
 
-// checkWebSocketOrigin validates the origin of WebSocket connections
-func checkWebSocketOrigin(r *http.Request) bool {
+// checkWebSocketOrigin validates the origin of WebSocket connections. Same-origin connections are
+// always accepted; other origins must match ALLOWED_ORIGINS, like cross-origin API calls.
+func checkWebSocketOrigin(r *http.Request, cfg *config.Config) bool {
 	origin := r.Header.Get("Origin")
 	if origin == "" {
 		utils.Warn("WebSocket connection attempted without Origin header")
 		return false
 	}
 
-	// Get allowed origins from environment variable
-	allowedOrigins := os.Getenv("ALLOWED_WEBSOCKET_ORIGINS")
-	
-	// Default to development settings if not configured
-	if allowedOrigins == "" {
-		// Development mode: allow localhost and 127.0.0.1
-		if strings.Contains(origin, "localhost") || strings.Contains(origin, "127.0.0.1") || strings.Contains(origin, "172.18.0.1") {
-			return true
-		}
-		utils.Warn("WebSocket connection from unallowed origin in development: %s", origin)
-		return false
+	if origin == "http://"+r.Host || origin == "https://"+r.Host {
+		return true
 	}
 
-	// Production mode: check against whitelist
-	origins := strings.Split(allowedOrigins, ",")
-	for _, allowedOrigin := range origins {
-		allowedOrigin = strings.TrimSpace(allowedOrigin)
-		if origin == allowedOrigin {
-			utils.Debug("WebSocket connection allowed from origin: %s", origin)
-			return true
-		}
+	if cfg.OriginAllowed(origin) {
+		utils.Debug("WebSocket connection allowed from origin: %s", origin)
+		return true
 	}
 
 	utils.Warn("WebSocket connection rejected from disallowed origin: %s", origin)
 	return false
 }
 
-// authenticateWebSocketRequest performs basic authentication for WebSocket connections
-// This is a simple implementation - you should enhance this based on your authentication system
-func authenticateWebSocketRequest(r *http.Request) bool {
-	// Option 1: Check for session cookie (if you're using cookie-based sessions)
-	sessionCookie, err := r.Cookie("session_id")
-	if err != nil || sessionCookie.Value == "" {
-		// No session cookie, check for Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			// For development: allow connections without authentication
-			// In production: return false
-			env := os.Getenv("ENVIRONMENT")
-			if env == "development" || env == "" {
-				utils.Debug("WebSocket connection allowed without authentication in development mode")
-				return true
-			}
-			utils.Warn("WebSocket connection missing authentication")
-			return false
+// authenticate checks the ticket of a WebSocket connection and returns who it was issued to. Hubs
+// without a ticket service accept every connection as the guest of its session cookie with the
+// user role.
+func (h *Hub) authenticate(r *http.Request) (*services.WSTicketGrant, bool) {
+	if h.tickets == nil {
+		grant := &services.WSTicketGrant{Role: models.RoleUser}
+		if cookie, err := r.Cookie("session_id"); err == nil && cookie.Value != "" {
+			grant.SessionID = cookie.Value
+			grant.UserID = services.GuestUserID(cookie.Value)
 		}
-		
-		// TODO: Validate Authorization header (Bearer token, etc.)
-		// For now, accept any Authorization header
-		utils.Debug("WebSocket connection authenticated via Authorization header")
-		return true
+		return grant, true
 	}
 
-	// TODO: Validate session cookie with your session store
-	// For now, accept any session cookie
-	utils.Debug("WebSocket connection authenticated via session cookie: %s", sessionCookie.Value[:8]+"...")
-	return true
+	grant, err := h.tickets.Redeem(r.Context(), r.URL.Query().Get("ticket"))
+	if err != nil {
+		if !errors.Is(err, services.ErrInvalidWSTicket) {
+			utils.Error("Failed to redeem WebSocket ticket: %v", err)
+		}
+		return nil, false
+	}
+	return grant, true
+}
+
+// messageRoles are the roles needed to send WebSocket message types; others are open to viewers
+var messageRoles = map[string]string{
+	"ai_prompt":       models.RoleUser,
+	"ai_prompt_multi": models.RoleUser,
+	"ai_regenerate":   models.RoleUser,
 }
 
 // Client represents a WebSocket client
@@ -103,34 +128,195 @@ type Client struct {
 	hub      *Hub
 	conn     *websocket.Conn
 	send     chan []byte
-	chatID   int64
+	gone     chan struct{} // closed when the hub unregisters the client; send stays open for late senders
 	provider string
 	mu       sync.Mutex
+
+	// ID of the upgrade request, included in this connection's logs and error messages
+	requestID string
+
+	// Context of the connection's database calls; it keeps the upgrade request's values but isn't
+	// cancelled with it, so responses that finish after the client left are still saved
+	ctx context.Context
+
+	// Session that opened the connection; its prompts count against the prompt quotas
+	sessionID string
+
+	// Participant shown to the other viewers of its chats and recorded on its prompts
+	userID string
+
+	// Role of the connection, which decides the message types it may send
+	role string
+
+	// Language of the upgrade request, used for messages meant for the user
+	lang string
+
+	// Chats whose live messages the client receives; guarded by hub.mu
+	chats map[int64]bool
+
+	// Whether the client receives chat_list_changed events
+	chatListSubscribed bool
+
+	// Frames streamed to this client that it hasn't acknowledged yet, kept for retransmission
+	sendMu      sync.Mutex
+	frameMu     sync.Mutex
+	lastFrameID int64
+	unacked     []trackedFrame
 }
 
 // Hub maintains active WebSocket connections
 type Hub struct {
 	clients          map[*Client]bool
-	broadcast        chan []byte
+	rooms            map[int64]map[*Client]bool // clients subscribed to each chat
 	register         chan *Client
 	unregister       chan *Client
 	sessionService   *services.SessionService
-	chatService      *services.ChatService
-	providerRegistry *services.ProviderRegistry
-	mu               sync.RWMutex
+	chatService       *services.ChatService
+	providerRegistry  *services.ProviderRegistry
+	generationService *services.GenerationService
+	usageService      *services.UsageService
+	attachmentService *services.AttachmentService
+	mu                sync.RWMutex
+
+	// Streaming responses are checkpointed every checkpointBytes bytes or checkpointInterval (0 disables)
+	checkpointBytes    int
+	checkpointInterval time.Duration
+
+	// Streamed chunks are batched into frames of flushBytes bytes or every flushInterval (0 disables either)
+	flushBytes    int
+	flushInterval time.Duration
+
+	// How long a provider's response may take and go without output (nil uses StreamResponseTimeout without idle timeout)
+	promptTimeouts func(providerID string) (total, idle time.Duration)
+
+	// How each provider's streamed output is chunked into frames (nil holds back split characters only)
+	streamChunking func(providerID string) string
+
+	// Retries of prompts failing with transient errors before any output (zero retries nothing)
+	retryPolicy services.RetryPolicy
+
+	// Daily and monthly prompt quotas of each session (nil counts nothing)
+	quotaService *services.QuotaService
+
+	// Moderation of prompts and responses (nil moderates nothing)
+	moderationService *services.ModerationService
+
+	// Prompt and response processors (nil transforms nothing)
+	processingService *services.ProcessingService
+
+	// Server-side tools providers can call in chats that enable them (nil disables tool calling)
+	toolService *services.ToolService
+
+	// Prompt drafts, cleared once the prompt is sent (nil leaves drafts alone)
+	draftService *services.DraftService
+
+	// Tickets authenticating WebSocket connections (nil accepts every connection)
+	tickets *services.WSTicketService
+
+	// Streamed chunks kept for clients resuming a response after reconnecting (nil disables resuming)
+	streamBuffer services.StreamBuffer
+
+	// How often running generations send ai_progress (0 disables)
+	progressInterval time.Duration
+
+	// Instance ID and optional Redis backplane shared with other instances
+	instanceID      string
+	backplane       *redis.Client
+	backplaneCancel context.CancelFunc
 }
 
 // NewHub creates a new WebSocket hub
-func NewHub(sessionService *services.SessionService, chatService *services.ChatService, providerRegistry *services.ProviderRegistry) *Hub {
+func NewHub(sessionService *services.SessionService, chatService *services.ChatService, providerRegistry *services.ProviderRegistry, generationService *services.GenerationService, usageService *services.UsageService, attachmentService *services.AttachmentService) *Hub {
 	return &Hub{
-		clients:          make(map[*Client]bool),
-		broadcast:        make(chan []byte),
-		register:         make(chan *Client),
-		unregister:       make(chan *Client),
-		sessionService:   sessionService,
-		chatService:      chatService,
-		providerRegistry: providerRegistry,
+		clients:           make(map[*Client]bool),
+		rooms:             make(map[int64]map[*Client]bool),
+		register:          make(chan *Client),
+		unregister:        make(chan *Client),
+		sessionService:    sessionService,
+		chatService:       chatService,
+		providerRegistry:  providerRegistry,
+		generationService: generationService,
+		usageService:      usageService,
+		attachmentService: attachmentService,
+		instanceID:        NewInstanceID(),
+		progressInterval:  GenerationProgressInterval,
+	}
+}
+
+// SetStreamCheckpoints saves streaming responses every so many bytes or so often; call it before Run
+func (h *Hub) SetStreamCheckpoints(everyBytes int, every time.Duration) {
+	h.checkpointBytes = everyBytes
+	h.checkpointInterval = every
+}
+
+// SetStreamFlush batches streamed chunks into frames of at least so many bytes or sent so often;
+// call it before Run
+func (h *Hub) SetStreamFlush(everyBytes int, every time.Duration) {
+	h.flushBytes = everyBytes
+	h.flushInterval = every
+}
+
+// SetPromptTimeouts sets how long each provider's responses may take and go without output; call it before Run
+func (h *Hub) SetPromptTimeouts(timeouts func(providerID string) (total, idle time.Duration)) {
+	h.promptTimeouts = timeouts
+}
+
+// SetStreamChunking sets what of each provider's streamed output is held back until more arrives,
+// see services.StreamChunker; call it before Run
+func (h *Hub) SetStreamChunking(modes func(providerID string) string) {
+	h.streamChunking = modes
+}
+
+// SetRetryPolicy retries prompts that fail with transient errors before any output; call it before Run
+func (h *Hub) SetRetryPolicy(policy services.RetryPolicy) {
+	h.retryPolicy = policy
+}
+
+// SetPromptQuotas counts each session's prompts against its quotas; call it before Run
+func (h *Hub) SetPromptQuotas(quotaService *services.QuotaService) {
+	h.quotaService = quotaService
+}
+
+// SetModeration moderates prompts before they are sent and responses before they are saved;
+// call it before Run
+func (h *Hub) SetModeration(moderationService *services.ModerationService) {
+	h.moderationService = moderationService
+}
+
+// SetProcessing transforms prompts before they are sent and responses before they are moderated
+// and saved; call it before Run
+func (h *Hub) SetProcessing(processingService *services.ProcessingService) {
+	h.processingService = processingService
+}
+
+// SetTools lets providers call the tools chats enable, re-prompting them with the results;
+// call it before Run
+func (h *Hub) SetTools(toolService *services.ToolService) {
+	h.toolService = toolService
+}
+
+// SetDrafts clears a chat's prompt draft once a prompt is sent in it; call it before Run
+func (h *Hub) SetDrafts(draftService *services.DraftService) {
+	h.draftService = draftService
+}
+
+// SetTickets requires a ticket from POST /api/ws/ticket to open a WebSocket; call it before Run
+func (h *Hub) SetTickets(ticketService *services.WSTicketService) {
+	h.tickets = ticketService
+}
+
+// SetStreamResume keeps streamed chunks in buffer so reconnecting clients can resume responses;
+// call it before Run
+func (h *Hub) SetStreamResume(buffer services.StreamBuffer) {
+	h.streamBuffer = buffer
+}
+
+// responseTimeouts returns how long a provider's response may take and go without output
+func (h *Hub) responseTimeouts(providerID string) (total, idle time.Duration) {
+	if h.promptTimeouts == nil {
+		return services.StreamResponseTimeout, 0
 	}
+	return h.promptTimeouts(providerID)
 }
 
 // Run starts the hub
@@ -147,33 +333,150 @@ func (h *Hub) Run() {
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				left := h.leaveRooms(client)
+				close(client.gone)
 				h.mu.Unlock()
+				for _, chatID := range left {
+					h.broadcastPresence("user_left", chatID, client)
+				}
 				utils.Debug("WebSocket client unregistered: %p", client)
 			} else {
 				h.mu.Unlock()
 			}
+		}
+	}
+}
 
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
-			}
-			h.mu.RUnlock()
+// subscribe makes the client receive the chat's live messages. It reports false when the client
+// already receives MaxChatSubscriptions chats. The client is sent the chat's participants, and a
+// participant's first connection to the chat is announced to the others.
+func (h *Hub) subscribe(client *Client, chatID int64) bool {
+	h.mu.Lock()
+	if client.chats[chatID] {
+		h.mu.Unlock()
+		return true
+	}
+	if len(client.chats) >= MaxChatSubscriptions {
+		h.mu.Unlock()
+		return false
+	}
+	if client.chats == nil {
+		client.chats = make(map[int64]bool)
+	}
+	client.chats[chatID] = true
+
+	room := h.rooms[chatID]
+	if room == nil {
+		room = make(map[*Client]bool)
+		h.rooms[chatID] = room
+	}
+	joined := client.userID != "" && !h.present(chatID, client.userID)
+	room[client] = true
+	participants := h.participants(chatID)
+	h.mu.Unlock()
+
+	if client.userID != "" {
+		client.sendPresence(chatID, participants)
+	}
+	if joined {
+		h.broadcastPresence("user_joined", chatID, client)
+	}
+	return true
+}
+
+// unsubscribe stops the chat's live messages to the client
+func (h *Hub) unsubscribe(client *Client, chatID int64) {
+	h.mu.Lock()
+	left := client.chats[chatID] && h.leaveRoom(client, chatID)
+	delete(client.chats, chatID)
+	h.mu.Unlock()
+
+	if left {
+		h.broadcastPresence("user_left", chatID, client)
+	}
+}
+
+// leaveRooms removes the client from every chat it is subscribed to and returns the chats its
+// participant left; h.mu must be held
+func (h *Hub) leaveRooms(client *Client) []int64 {
+	var left []int64
+	for chatID := range client.chats {
+		if h.leaveRoom(client, chatID) {
+			left = append(left, chatID)
 		}
 	}
+	client.chats = nil
+	return left
+}
+
+// leaveRoom removes the client from a chat's subscribers, dropping rooms left empty, and reports
+// whether its participant has no other connection to the chat; h.mu must be held
+func (h *Hub) leaveRoom(client *Client, chatID int64) bool {
+	room := h.rooms[chatID]
+	delete(room, client)
+	if len(room) == 0 {
+		delete(h.rooms, chatID)
+	}
+	return client.userID != "" && !h.present(chatID, client.userID)
+}
+
+// NotifyChatListChanged sends a chat_list_changed event to clients subscribed to the chat list
+func (h *Hub) NotifyChatListChanged(action string, chatID int64) {
+	msg := models.WebSocketMessage{
+		Type:    "chat_list_changed",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    chatID,
+			Action:    action,
+			Timestamp: time.Now(),
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("Failed to marshal chat list change: %v", err)
+		return
+	}
+
+	h.deliverLocal(hubScopeChatList, 0, data, nil)
+	h.publish(hubScopeChatList, 0, data)
+}
+
+// NotifyScheduledRun delivers a finished scheduled run to the clients viewing its chat
+func (h *Hub) NotifyScheduledRun(p *models.ScheduledPrompt, run *models.ScheduledPromptRun, prompt, response *models.Message) {
+	data := models.WSMsgData{
+		ChatID:     run.ChatID,
+		Provider:   p.Provider,
+		Action:     run.Status,
+		ScheduleID: p.ID,
+		Content:    run.Error,
+		Timestamp:  time.Now(),
+	}
+	if prompt != nil {
+		data.Prompt = prompt.Content
+	}
+	if response != nil {
+		data.Content = response.Content
+		data.MessageID = response.ID
+	}
+
+	msg, err := json.Marshal(models.WebSocketMessage{Type: "scheduled_run", Version: models.WSProtocolVersion, Data: data})
+	if err != nil {
+		utils.Error("Failed to marshal scheduled run: %v", err)
+		return
+	}
+
+	h.broadcastToChat(run.ChatID, msg, nil)
 }
 
 // WebSocketHandler handles WebSocket connections
-func WebSocketHandler(hub *Hub) gin.HandlerFunc {
+func WebSocketHandler(hub *Hub, cfg *config.Config) gin.HandlerFunc {
+	upgrader := newUpgrader(cfg)
+
 	return func(c *gin.Context) {
-		// Basic authentication check - you can enhance this based on your auth system
-		if !authenticateWebSocketRequest(c.Request) {
+		// Browsers can't set headers on the upgrade, so connections authenticate with a ticket
+		grant, ok := hub.authenticate(c.Request)
+		if !ok {
 			utils.Warn("WebSocket authentication failed for %s", c.ClientIP())
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
@@ -189,14 +492,28 @@ func WebSocketHandler(hub *Hub) gin.HandlerFunc {
 		conn.SetReadLimit(MaxWebSocketMessageSize) // 512KB max message size
 
 		client := &Client{
-			hub:  hub,
-			conn: conn,
-			send: make(chan []byte, 256),
+			hub:       hub,
+			conn:      conn,
+			send:      make(chan []byte, 256),
+			gone:      make(chan struct{}),
+			requestID: utils.RequestIDFromContext(c.Request.Context()),
+			ctx:       context.WithoutCancel(c.Request.Context()),
+			lang:      GetLang(c),
+			sessionID: grant.SessionID,
+			userID:    grant.UserID,
+			role:      grant.Role,
 		}
 
 		client.hub.register <- client
 		utils.Debug("WebSocket client authenticated and registered: %s", c.ClientIP())
 
+		// Record which instance holds this session's connection
+		if client.sessionID != "" && hub.sessionService != nil {
+			if err := hub.sessionService.AttachInstance(c.Request.Context(), client.sessionID, hub.instanceID); err != nil {
+				utils.Debug("Failed to attach instance to session: %v", err)
+			}
+		}
+
 		// Start goroutines for reading and writing
 		go client.writePump()
 		go client.readPump()
@@ -216,19 +533,38 @@ func (c *Client) readPump() {
 		return nil
 	})
 
+	refused := false
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				utils.Error("WebSocket error: %v", err)
+				utils.Error("[request_id=%s] WebSocket error: %v", c.requestID, err)
 			}
 			break
 		}
 
-		// Parse message
+		// Outdated clients are told to upgrade once; their connection closes once that is delivered
+		if refused {
+			continue
+		}
 		var msg models.WebSocketMessage
-		if err := json.Unmarshal(message, &msg); err != nil {
-			utils.Error("Failed to parse WebSocket message: %v", err)
+		decodeErr := json.Unmarshal(message, &msg)
+		if decodeErr == nil && msg.Version < models.WSMinProtocolVersion {
+			refused = true
+			c.refuseOutdatedClient(msg.Version)
+			continue
+		}
+
+		// Messages breaking the protocol schema are answered with what is wrong with them
+		if msgType, errs := validateClientMessage(message); len(errs) > 0 {
+			c.sendProtocolError(msgType, errs)
+			continue
+		} else if decodeErr != nil {
+			c.sendProtocolError(msgType, []models.WSSchemaError{{Message: decodeErr.Error()}})
+			continue
+		}
+
+		if !c.maySend(msg.Type) {
 			continue
 		}
 
@@ -236,10 +572,26 @@ func (c *Client) readPump() {
 		switch msg.Type {
 		case "ai_prompt":
 			c.handleAIPrompt(msg.Data)
+		case "ai_prompt_multi":
+			c.handleAIPromptMulti(msg.Data)
 		case "session_status":
 			c.handleSessionStatus(msg.Data)
-		default:
-			utils.Warn("Unknown WebSocket message type: %s", msg.Type)
+		case "ai_regenerate":
+			c.handleAIRegenerate(msg.Data)
+		case "subscribe_chat":
+			c.subscribeChat(msg.Data.ChatID)
+		case "unsubscribe_chat":
+			c.hub.unsubscribe(c, msg.Data.ChatID)
+		case "subscribe_chat_list":
+			c.setChatListSubscription(true)
+		case "unsubscribe_chat_list":
+			c.setChatListSubscription(false)
+		case "ack":
+			c.acknowledge(msg.Ack)
+		case "resend":
+			c.resendUnacked(msg.Ack)
+		case "resume_stream":
+			c.resumeStream(msg.Data)
 		}
 	}
 }
@@ -263,6 +615,21 @@ func (c *Client) writePump() {
 
 			c.conn.WriteMessage(websocket.TextMessage, message)
 
+		case <-c.gone:
+			// Deliver what was queued before the client was unregistered, e.g. an upgrade_required error
+			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		drain:
+			for {
+				select {
+				case message := <-c.send:
+					c.conn.WriteMessage(websocket.TextMessage, message)
+				default:
+					break drain
+				}
+			}
+			c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
+
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -274,145 +641,1409 @@ func (c *Client) writePump() {
 
 // handleAIPrompt processes AI prompts
 func (c *Client) handleAIPrompt(data models.WSMsgData) {
+	if !c.validatePrompt(data.Content) || !c.moderatePrompt(data.ChatID, data.Provider, data.Content) {
+		return
+	}
+
 	c.mu.Lock()
-	c.chatID = data.ChatID
 	c.provider = data.Provider
 	c.mu.Unlock()
+	c.hub.subscribe(c, data.ChatID)
 
-	// Get the AI provider
-	provider, err := c.hub.providerRegistry.Get(data.Provider)
-	if err != nil {
-		c.sendError("Provider not found: " + err.Error())
+	// Get the AI provider; it can't be deregistered until the generation is released
+	provider, release, chain, ok := c.acquireProvider(data.Provider, data.Model)
+	if !ok {
 		return
 	}
 
-	// Check if provider is available
-	if !provider.IsAvailable() {
-		c.sendError("Provider is not available")
+	attachments, ok := c.pendingAttachments(data, provider)
+	if !ok || !c.consumeQuota(1) {
+		release()
 		return
 	}
 
 	// Save user message
-	if _, err := c.hub.chatService.AddMessage(data.ChatID, "user", data.Content); err != nil {
-		utils.Error("Failed to save user message: %v", err)
+	userMsg, err := c.hub.chatService.AddUserMessage(c.ctx, data.ChatID, data.Content, c.userID)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to save user message: %v", c.requestID, err)
 	}
+	files := c.attachToMessage(userMsg, attachments)
+	c.relayUserMessage(userMsg, data.Provider)
+	c.clearDraft(userMsg)
 
 	// Stream response
+	generationID := c.queueGeneration(data.ChatID, provider.GetID())
 	go func() {
-		// Create context for cancellation
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
-		
-		var responseContent string
-		writer := &websocketWriter{client: c, buffer: &responseContent}
-
-		err := provider.StreamResponse(ctx, data.Content, data.ChatID, writer)
-		
-		// Always send completion message to indicate end of streaming
-		c.sendStreamCompletion(data.ChatID)
-		
-		if err != nil {
-			c.sendError("Failed to get response: " + err.Error())
-			return
-		}
-
-		// Save assistant message
-		if responseContent != "" {
-			if _, err := c.hub.chatService.AddMessage(data.ChatID, "assistant", responseContent); err != nil {
-				utils.Error("Failed to save assistant message: %v", err)
-			}
-		}
+		defer release()
+		c.streamProviderResponse(provider, data.ChatID, userMsg, data.Content, files, data.Model, generationID, chain)
 	}()
 }
 
-// handleSessionStatus handles session status updates
-func (c *Client) handleSessionStatus(data models.WSMsgData) {
-	// Update session with chat ID if provided
-	if data.ChatID > 0 {
-		c.mu.Lock()
-		c.chatID = data.ChatID
-		c.mu.Unlock()
+// handleAIRegenerate discards the responses that follow a user message (the latest one unless
+// message_id is given) and streams a new response to it, e.g. after the message was edited
+func (c *Client) handleAIRegenerate(data models.WSMsgData) {
+	chat, err := c.hub.chatService.GetChat(c.ctx, data.ChatID)
+	if err != nil {
+		c.sendError("Chat not found")
+		return
 	}
-}
 
-// sendError sends an error message to the client
-func (c *Client) sendError(message string) {
-	msg := models.WebSocketMessage{
-		Type: "error",
-		Data: models.WSMsgData{
-			Content:   message,
-			Timestamp: time.Now(),
-		},
+	var userMsg *models.Message
+	if data.MessageID > 0 {
+		userMsg, err = c.hub.chatService.GetMessage(c.ctx, data.ChatID, data.MessageID)
+		if err == nil && userMsg.Role != "user" {
+			err = fmt.Errorf("message %d is not a user message", data.MessageID)
+		}
+	} else {
+		userMsg, err = c.hub.chatService.GetLastUserMessage(c.ctx, data.ChatID)
 	}
-
-	data, err := json.Marshal(msg)
 	if err != nil {
-		utils.Error("Failed to marshal error message: %v", err)
+		c.sendError("Nothing to regenerate: " + err.Error())
 		return
 	}
 
-	select {
-	case c.send <- data:
-	default:
-		utils.Error("Failed to send error message to client")
+	providerID := data.Provider
+	if providerID == "" {
+		providerID = chat.Provider
 	}
-}
 
-// sendStreamCompletion sends a stream completion message to the client
-func (c *Client) sendStreamCompletion(chatID int64) {
-	msg := models.WebSocketMessage{
-		Type: "ai_response_end",
-		Data: models.WSMsgData{
-			ChatID:    chatID,
-			Provider:  c.provider,
-			Timestamp: time.Now(),
-		},
+	provider, release, chain, ok := c.acquireProvider(providerID, data.Model)
+	if !ok {
+		return
 	}
 
-	data, err := json.Marshal(msg)
-	if err != nil {
-		utils.Error("Failed to marshal stream completion message: %v", err)
+	files := c.messageAttachments(userMsg)
+	for _, file := range files {
+		if file.IsImage() && !c.supportsImages(provider) {
+			release()
+			return
+		}
+	}
+	// Edited messages haven't been moderated yet
+	if !c.moderatePrompt(data.ChatID, providerID, userMsg.Content) || !c.consumeQuota(1) {
+		release()
 		return
 	}
 
-	select {
-	case c.send <- data:
-		utils.Debug("Stream completion sent for chat %d", chatID)
-	default:
-		utils.Error("Failed to send stream completion message to client")
+	if _, err := c.hub.chatService.DeleteMessagesAfter(c.ctx, data.ChatID, userMsg.ID); err != nil {
+		release()
+		utils.Error("[request_id=%s] Failed to discard messages after %d: %v", c.requestID, userMsg.ID, err)
+		c.sendError("Failed to discard previous response")
+		return
 	}
+
+	c.mu.Lock()
+	c.provider = providerID
+	c.mu.Unlock()
+	c.hub.subscribe(c, data.ChatID)
+
+	generationID := c.queueGeneration(data.ChatID, provider.GetID())
+	go func() {
+		defer release()
+		c.streamProviderResponse(provider, data.ChatID, userMsg, userMsg.Content, files, data.Model, generationID, chain)
+	}()
 }
 
-// websocketWriter implements io.Writer for streaming to WebSocket
-type websocketWriter struct {
-	client *Client
-	buffer *string
+// acquireProvider resolves a provider for a single generation and checks that it can serve the
+// requested model. An unavailable provider is replaced by the first available fallback of its
+// failover chain. Errors are reported to the client; on success release must be called when done.
+func (c *Client) acquireProvider(providerID, model string) (providers.AIProvider, func(), *services.Failover, bool) {
+	chain := c.hub.providerRegistry.NewFailover(providerID, model)
+	provider, release, err := c.hub.providerRegistry.Acquire(providerID)
+	if err == nil && !provider.IsAvailable() {
+		release()
+		err = fmt.Errorf("%w: %s", services.ErrProviderUnavailable, providerID)
+	}
+	if err != nil {
+		if fallback, fallbackRelease := c.hub.providerRegistry.NextFallback(chain, providerID, err, false); fallback != nil {
+			return fallback, fallbackRelease, chain, true
+		}
+		if errors.Is(err, services.ErrProviderUnavailable) {
+			c.sendError("Provider is not available")
+		} else {
+			c.sendError(acquireErrorMessage(err))
+		}
+		return nil, nil, nil, false
+	}
+
+	if model != "" && !providers.SupportsModel(provider, model) {
+		release()
+		c.sendError(fmt.Sprintf("Model %s is not supported by %s", model, providerID))
+		return nil, nil, nil, false
+	}
+
+	return provider, release, chain, true
 }
 
-func (w *websocketWriter) Write(p []byte) (n int, err error) {
-	content := string(p)
-	*w.buffer += content
 
-	msg := models.WebSocketMessage{
-		Type: "ai_response",
-		Data: models.WSMsgData{
-			ChatID:    w.client.chatID,
-			Provider:  w.client.provider,
-			Content:   content,
-			Timestamp: time.Now(),
-			Stream:    true,
-		},
+// acquireErrorMessage describes why a provider couldn't be acquired
+func acquireErrorMessage(err error) string {
+	if errors.Is(err, services.ErrProviderDegraded) {
+		return "Provider is degraded: " + err.Error()
 	}
+	return "Provider not found: " + err.Error()
+}
 
-	data, err := json.Marshal(msg)
+// handleAIPromptMulti sends the same prompt to several providers concurrently (compare mode)
+func (c *Client) handleAIPromptMulti(data models.WSMsgData) {
+	if !c.validatePrompt(data.Content) || !c.moderatePrompt(data.ChatID, strings.Join(data.Providers, ","), data.Content) {
+		return
+	}
+
+	// De-duplicate the requested providers while keeping the client's order
+	seen := make(map[string]bool)
+	var providerIDs []string
+	for _, id := range data.Providers {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		providerIDs = append(providerIDs, id)
+	}
+
+	if len(providerIDs) == 0 {
+		c.sendError("No providers selected")
+		return
+	}
+	if len(providerIDs) > MaxCompareProviders {
+		c.sendError(fmt.Sprintf("Too many providers selected (max %d)", MaxCompareProviders))
+		return
+	}
+
+	// Resolve every provider up front so a bad selection fails before anything runs
+	selected := make([]providers.AIProvider, 0, len(providerIDs))
+	releases := make([]func(), 0, len(providerIDs))
+	releaseAll := func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+	for _, id := range providerIDs {
+		provider, release, err := c.hub.providerRegistry.Acquire(id)
+		if err != nil {
+			releaseAll()
+			c.sendProviderError(id, acquireErrorMessage(err))
+			return
+		}
+		releases = append(releases, release)
+		if !provider.IsAvailable() {
+			releaseAll()
+			c.sendProviderError(id, "Provider is not available: "+id)
+			return
+		}
+		// A model override in compare mode must be understood by every selected provider
+		if data.Model != "" && !providers.SupportsModel(provider, data.Model) {
+			releaseAll()
+			c.sendProviderError(id, fmt.Sprintf("Model %s is not supported by %s", data.Model, id))
+			return
+		}
+		selected = append(selected, provider)
+	}
+
+	// Each provider answering counts as a prompt
+	attachments, ok := c.pendingAttachments(data, selected...)
+	if !ok || !c.consumeQuota(len(selected)) {
+		releaseAll()
+		return
+	}
+
+	c.hub.subscribe(c, data.ChatID)
+
+	// Save user message once for all providers
+	userMsg, err := c.hub.chatService.AddUserMessage(c.ctx, data.ChatID, data.Content, c.userID)
 	if err != nil {
-		return 0, err
+		utils.Error("[request_id=%s] Failed to save user message: %v", c.requestID, err)
 	}
+	files := c.attachToMessage(userMsg, attachments)
+	c.relayUserMessage(userMsg, data.Provider)
+	c.clearDraft(userMsg)
 
-	select {
-	case w.client.send <- data:
-		return len(p), nil
-	default:
-		return 0, io.ErrClosedPipe
+	generationIDs := make([]string, len(selected))
+	for i, provider := range selected {
+		generationIDs[i] = c.queueGeneration(data.ChatID, provider.GetID())
 	}
+
+	go func() {
+		var wg sync.WaitGroup
+		for i, provider := range selected {
+			wg.Add(1)
+			go func(p providers.AIProvider, generationID string, release func()) {
+				defer wg.Done()
+				defer release()
+				c.streamProviderResponse(p, data.ChatID, userMsg, data.Content, files, data.Model, generationID, nil)
+			}(provider, generationIDs[i], releases[i])
+		}
+		wg.Wait()
+
+		c.sendMultiCompletion(data.ChatID, providerIDs)
+	}()
+}
+
+// providerInput builds the text sent to a provider, prepending the chat's system prompt and the
+// conversation the provider hasn't seen: for a resumed session none, for a new session everything
+// before the prompt, and otherwise what was said before the chat was switched to the provider
+func (c *Client) providerInput(chatID int64, providerID string, promptMsg *models.Message, prompt string, session *providers.Session) string {
+	chat, err := c.hub.chatService.GetChat(c.ctx, chatID)
+	if err != nil {
+		utils.Warn("[request_id=%s] Failed to load chat %d for system prompt: %v", c.requestID, chatID, err)
+		return prompt
+	}
+
+	var history []*models.Message
+	switch {
+	case session != nil && session.ID != "":
+	case session != nil && promptMsg != nil:
+		history, err = c.hub.chatService.ConversationBefore(c.ctx, chatID, promptMsg.ID)
+	default:
+		history, err = c.hub.chatService.HandoffHistory(c.ctx, chatID, providerID)
+	}
+	if err != nil {
+		utils.Warn("[request_id=%s] Failed to load history for chat %d: %v", c.requestID, chatID, err)
+	}
+	return services.BuildProviderInput(chat.SystemPrompt, services.FormatHandoffHistory(history, prompt))
+}
+
+// providerSession returns the native session to continue for providers that keep one, or nil
+func (c *Client) providerSession(provider providers.AIProvider, chatID int64) *providers.Session {
+	if !providers.SupportsSessions(provider) {
+		return nil
+	}
+	sessionID, err := c.hub.chatService.ProviderSession(c.ctx, chatID, provider.GetID())
+	if err != nil {
+		utils.Warn("[request_id=%s] Failed to load %s session for chat %d: %v", c.requestID, provider.GetID(), chatID, err)
+	}
+	return &providers.Session{ID: sessionID}
+}
+
+// pendingAttachments loads the uploaded attachments listed in attachment_ids and images, and stores
+// inline images. Images are only accepted when every target provider supports vision. Errors are
+// reported to the client.
+func (c *Client) pendingAttachments(data models.WSMsgData, targets ...providers.AIProvider) ([]*models.Attachment, bool) {
+	if len(data.AttachmentIDs) == 0 && len(data.Images) == 0 {
+		return nil, true
+	}
+	if c.hub.attachmentService == nil {
+		c.sendError("Attachments are not supported")
+		return nil, false
+	}
+	if len(data.Images) > MaxPromptImages {
+		c.sendError(fmt.Sprintf("Too many images (max %d)", MaxPromptImages))
+		return nil, false
+	}
+
+	// Uploaded images are referenced like other attachments
+	ids := append([]int64{}, data.AttachmentIDs...)
+	imageIDs := make(map[int64]bool)
+	for _, image := range data.Images {
+		if image.AttachmentID > 0 {
+			imageIDs[image.AttachmentID] = true
+			ids = append(ids, image.AttachmentID)
+		}
+	}
+	seen := make(map[int64]bool)
+	unique := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	attachments, err := c.hub.attachmentService.GetPending(data.ChatID, unique)
+	if err != nil {
+		c.sendError("Invalid attachments: " + err.Error())
+		return nil, false
+	}
+
+	hasImages := len(data.Images) > 0
+	for _, attachment := range attachments {
+		if imageIDs[attachment.ID] && !attachment.IsImage() {
+			c.sendError(fmt.Sprintf("Attachment %d is not an image", attachment.ID))
+			return nil, false
+		}
+		hasImages = hasImages || attachment.IsImage()
+	}
+	if hasImages && !c.supportsImages(targets...) {
+		return nil, false
+	}
+
+	var inline []*models.Attachment
+	for _, image := range data.Images {
+		if image.AttachmentID > 0 {
+			continue
+		}
+		attachment, err := c.hub.attachmentService.SaveImage(data.ChatID, image.Filename, image.Data)
+		if err != nil {
+			// Don't leave the images saved so far behind as pending attachments
+			for _, saved := range inline {
+				if err := c.hub.attachmentService.Delete(data.ChatID, saved.ID); err != nil {
+					utils.Warn("[request_id=%s] Failed to remove image %d: %v", c.requestID, saved.ID, err)
+				}
+			}
+			c.sendError("Invalid image: " + err.Error())
+			return nil, false
+		}
+		inline = append(inline, attachment)
+	}
+
+	return append(attachments, inline...), true
+}
+
+// supportsImages checks that every provider advertises vision, reporting the first that doesn't
+func (c *Client) supportsImages(targets ...providers.AIProvider) bool {
+	for _, provider := range targets {
+		if !providers.HasCapability(provider, providers.CapabilityVision) {
+			c.sendError(fmt.Sprintf("Provider %s does not support images", provider.GetID()))
+			return false
+		}
+	}
+	return true
+}
+
+// attachToMessage links attachments to the saved user message and describes them for providers
+func (c *Client) attachToMessage(msg *models.Message, attachments []*models.Attachment) []providers.Attachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+	if msg != nil {
+		if err := c.hub.attachmentService.AttachToMessage(msg.ID, attachments); err != nil {
+			utils.Error("[request_id=%s] Failed to link attachments to message %d: %v", c.requestID, msg.ID, err)
+		}
+	}
+	return c.hub.attachmentService.ProviderAttachments(attachments)
+}
+
+// messageAttachments describes the attachments sent with a message for providers
+func (c *Client) messageAttachments(msg *models.Message) []providers.Attachment {
+	if c.hub.attachmentService == nil {
+		return nil
+	}
+	attachments, err := c.hub.attachmentService.ListByMessage(msg.ChatID, msg.ID)
+	if err != nil {
+		utils.Warn("[request_id=%s] Failed to load attachments of message %d: %v", c.requestID, msg.ID, err)
+		return nil
+	}
+	return c.hub.attachmentService.ProviderAttachments(attachments)
+}
+
+// queueGeneration allocates a generation ID and records that the prompt was queued
+func (c *Client) queueGeneration(chatID int64, providerID string) string {
+	generationID := services.NewGenerationID()
+	c.recordGenerationEvent(generationID, chatID, providerID, models.GenerationQueued, "")
+	return generationID
+}
+
+// recordGenerationEvent stores a generation lifecycle event; failures are logged but never block streaming
+func (c *Client) recordGenerationEvent(generationID string, chatID int64, providerID, event, detail string) {
+	if c.hub.generationService == nil {
+		return
+	}
+	if err := c.hub.generationService.RecordEvent(generationID, chatID, providerID, event, detail); err != nil {
+		utils.Error("[request_id=%s] Failed to record generation event %s: %v", c.requestID, event, err)
+	}
+}
+
+// streamProviderResponse streams a single provider's response to a prompt and saves it as an
+// assistant message. With a failover chain, a provider failing before any output hands the prompt
+// to the next fallback as a new generation.
+func (c *Client) streamProviderResponse(provider providers.AIProvider, chatID int64, promptMsg *models.Message, prompt string, attachments []providers.Attachment, model, generationID string, chain *services.Failover) {
+	providerID := provider.GetID()
+	original := prompt
+	if chain.FailedOver() {
+		model = chain.ModelFor(provider)
+		c.sendFailover(chatID, providerID, generationID, chain)
+	}
+	if c.hub.processingService != nil {
+		prompt = c.hub.processingService.Process(c.ctx, processing.StagePre, chatID, providerID, prompt)
+	}
+	toolset := c.chatToolset(chatID)
+	if len(toolset) > 0 {
+		prompt = toolset.Instructions() + "\n" + prompt
+	}
+	session := c.providerSession(provider, chatID)
+	input := c.providerInput(chatID, providerID, promptMsg, prompt, session)
+
+	// Create context for cancellation; the cause tells which timeout stopped the response
+	total, idle := c.hub.responseTimeouts(providerID)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	ctx, cancelTimeout := context.WithTimeoutCause(ctx, total, errResponseTimeout)
+	defer cancelTimeout()
+	ctx = providers.WithModel(ctx, model)
+	ctx = providers.WithAttachments(ctx, attachments)
+	ctx = providers.WithSession(ctx, session)
+
+	var responseContent string
+	writer := &websocketWriter{ctx: ctx, client: c, chatID: chatID, provider: providerID, generationID: generationID, buffer: &responseContent,
+		flushBytes: c.hub.flushBytes, flushInterval: c.hub.flushInterval}
+	if c.hub.streamChunking != nil {
+		writer.chunker = services.NewStreamChunker(c.hub.streamChunking(providerID))
+	}
+	writer.limits = c.hub.chatService.MessageLimits()
+	writer.stop = func() { cancel(errResponseTooLarge) }
+	if idle > 0 {
+		writer.idleTimeout = idle
+		writer.idleTimer = time.AfterFunc(idle, func() { cancel(errResponseIdleTimeout) })
+		defer writer.idleTimer.Stop()
+	}
+	if c.hub.checkpointBytes > 0 || c.hub.checkpointInterval > 0 {
+		writer.checkpoint = c.hub.chatService.NewStreamCheckpointer(c.ctx, chatID, providerID, c.hub.checkpointBytes, c.hub.checkpointInterval)
+	}
+
+	c.recordGenerationEvent(generationID, chatID, providerID, models.GenerationStarted, "")
+	writer.startedAt = time.Now()
+	writer.onFirstWrite = func() {
+		c.sendGenerationStatus("provider_started", chatID, providerID, generationID, writer.startedAt, 0)
+	}
+	c.sendGenerationStatus("ai_thinking", chatID, providerID, generationID, writer.startedAt, 0)
+	stopProgress := c.reportProgress(chatID, providerID, generationID, writer)
+	err := provider.StreamResponse(ctx, input, chatID, writer)
+	if errors.Is(err, providers.ErrSessionExpired) {
+		// Nothing was streamed yet; start a new session with the whole conversation
+		utils.Info("[request_id=%s] %s session for chat %d expired, starting a new one", c.requestID, providerID, chatID)
+		session.ID = ""
+		input = c.providerInput(chatID, providerID, promptMsg, prompt, session)
+		err = provider.StreamResponse(ctx, input, chatID, writer)
+	}
+	// Transient failures before any output are retried after a backoff, within the response timeout
+	for attempt := 1; err != nil && writer.streamed() == 0 && ctx.Err() == nil && c.hub.retryPolicy.Retries(attempt, err); attempt++ {
+		delay := c.hub.retryPolicy.Delay(attempt)
+		c.sendRetry(chatID, providerID, generationID, attempt+1, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if writer.idleTimer != nil {
+			writer.idleTimer.Reset(idle)
+		}
+		err = provider.StreamResponse(ctx, input, chatID, writer)
+	}
+	// Fallbacks get a new generation with their own timeouts; a provider that used up the whole
+	// response time isn't failed over, the prompt has waited long enough
+	if err != nil && chain != nil && writer.streamed() == 0 && !errors.Is(context.Cause(ctx), errResponseTimeout) {
+		images := slices.ContainsFunc(attachments, func(a providers.Attachment) bool { return a.IsImage() })
+		if fallback, release := c.hub.providerRegistry.NextFallback(chain, providerID, err, images); fallback != nil {
+			defer release()
+			stopProgress()
+			if writer.checkpoint != nil {
+				writer.checkpoint.Discard()
+			}
+			c.hub.providerRegistry.RecordResult(providerID, err)
+			c.recordUsage(chatID, promptMsg, providerID, models.UsageInput, input, writer.reportedInputTokens)
+			c.recordGenerationEvent(generationID, chatID, providerID, models.GenerationFailed, err.Error())
+			c.finishBufferedStream(chatID, generationID)
+			c.streamProviderResponse(fallback, chatID, promptMsg, original, attachments, model, c.queueGeneration(chatID, fallback.GetID()), chain)
+			return
+		}
+	}
+	sent := input
+	if err == nil && len(toolset) > 0 {
+		sent, err = c.continueWithTools(ctx, provider, chatID, generationID, toolset, session, input, writer)
+	}
+	// Failing to stream to the clients isn't the provider's failure. A write to a slow client that
+	// fails also fails the provider's stream, so such a generation doesn't count for the circuit
+	// breaker at all.
+	providerErr := err
+	clientFailed := writer.clientFailed()
+	if flushErr := writer.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+	stopProgress()
+	latency := time.Since(writer.startedAt)
+
+	// A response stopped for being too large is saved as far as it got
+	if err != nil && errors.Is(context.Cause(ctx), errResponseTooLarge) {
+		err, providerErr = nil, nil
+	}
+	if c.hub.providerRegistry != nil && !clientFailed {
+		c.hub.providerRegistry.RecordResult(providerID, providerErr)
+	}
+	if writer.oversized {
+		c.sendResponseOversized(chatID, providerID, writer.limits)
+	}
+
+	// Responses are processed once complete; clients replace what streamed with the processed
+	// content, which is what is moderated and saved
+	processed := ""
+	if err == nil && responseContent != "" && c.hub.processingService != nil {
+		content := c.hub.processingService.Process(c.ctx, processing.StagePost, chatID, providerID, responseContent)
+		if content != responseContent {
+			content, _ = writer.limits.TruncateResponse(content)
+			responseContent, processed = content, content
+		}
+	}
+
+	// Responses are moderated once complete; a blocked one is withdrawn from the clients and not saved
+	blocked := false
+	if err == nil && responseContent != "" && c.hub.moderationService != nil {
+		verdict := c.hub.moderationService.Check(c.ctx, moderation.Request{Direction: moderation.DirectionResponse, ChatID: chatID, Provider: providerID, Content: responseContent})
+		if verdict.Blocked() {
+			blocked = true
+			c.sendMessageBlocked(chatID, providerID, moderation.DirectionResponse, verdict)
+		}
+	}
+
+	// Timeouts are reported before the completion, so clients can mark what streamed as interrupted
+	timedOut := false
+	if err != nil {
+		switch cause := context.Cause(ctx); {
+		case errors.Is(cause, errResponseTimeout):
+			timedOut = true
+			c.sendResponseTimeout(chatID, providerID, ResponseTimeoutOverall, total)
+		case errors.Is(cause, errResponseIdleTimeout):
+			timedOut = true
+			c.sendResponseTimeout(chatID, providerID, ResponseTimeoutIdle, idle)
+		}
+	}
+
+	// Always send completion message to indicate end of streaming
+	c.finishBufferedStream(chatID, generationID)
+	c.sendStreamCompletion(chatID, providerID, generationID, writer.chunks())
+
+	// The prompt was sent whether or not the response succeeded, so input usage is always counted
+	// (per provider in compare mode), tool results included
+	c.recordUsage(chatID, promptMsg, providerID, models.UsageInput, sent, writer.reportedInputTokens)
+
+	if err != nil {
+		if writer.checkpoint != nil {
+			writer.checkpoint.Discard()
+		}
+		c.recordGenerationEvent(generationID, chatID, providerID, models.GenerationFailed, err.Error())
+		if !timedOut {
+			c.sendProviderError(providerID, "Failed to get response: "+err.Error())
+		}
+		return
+	}
+	c.recordGenerationEvent(generationID, chatID, providerID, models.GenerationCompleted, "")
+
+	// Follow-up prompts continue the session the response was given in
+	if session != nil && session.ID != "" {
+		if err := c.hub.chatService.SetProviderSession(c.ctx, chatID, providerID, session.ID); err != nil {
+			utils.Warn("[request_id=%s] Failed to save %s session for chat %d: %v", c.requestID, providerID, chatID, err)
+		}
+	}
+
+	if blocked {
+		if writer.checkpoint != nil {
+			writer.checkpoint.Discard()
+		}
+		c.recordUsage(chatID, nil, providerID, models.UsageOutput, responseContent, writer.reportedOutputTokens)
+		return
+	}
+
+	// Save assistant message, completing its checkpoint if one was saved
+	if responseContent != "" {
+		var assistantMsg *models.Message
+		var err error
+		if writer.checkpoint != nil {
+			assistantMsg, err = writer.checkpoint.Finish(responseContent)
+		} else {
+			assistantMsg, err = c.hub.chatService.AddProviderMessage(c.ctx, chatID, "assistant", responseContent, providerID)
+		}
+		if err != nil {
+			utils.Error("[request_id=%s] Failed to save assistant message: %v", c.requestID, err)
+		} else {
+			if model != "" {
+				if err := c.hub.chatService.SetMessageModel(c.ctx, assistantMsg.ID, model); err != nil {
+					utils.Warn("[request_id=%s] Failed to record model of message %d: %v", c.requestID, assistantMsg.ID, err)
+				}
+			}
+			meta := responseMetadata(writer, model, sent, responseContent, latency)
+			if chain.FailedOver() {
+				meta.FailedOver = chain.Failed
+			}
+			if err := c.hub.chatService.SetMessageMetadata(c.ctx, assistantMsg.ID, meta); err != nil {
+				utils.Warn("[request_id=%s] Failed to record metadata of message %d: %v", c.requestID, assistantMsg.ID, err)
+				meta = nil
+			}
+			c.sendResponseSaved(chatID, providerID, assistantMsg.ID, processed, meta)
+		}
+		c.recordUsage(chatID, assistantMsg, providerID, models.UsageOutput, responseContent, writer.reportedOutputTokens)
+	}
+}
+
+// responseMetadata describes how a response was generated; tokens are those the provider reported,
+// or estimated from the content when it didn't
+func responseMetadata(writer *websocketWriter, model, input, output string, latency time.Duration) *models.MessageMetadata {
+	meta := &models.MessageMetadata{
+		Model:        model,
+		Provider:     writer.provider,
+		LatencyMs:    latency.Milliseconds(),
+		FinishReason: models.FinishStop,
+	}
+	if writer.oversized && writer.limits.TruncatesResponses() {
+		meta.FinishReason = models.FinishLength
+	}
+	if writer.reportedInputTokens != nil {
+		meta.TokensIn = *writer.reportedInputTokens
+	} else {
+		meta.TokensIn = services.EstimateTokens(input)
+		meta.TokensEstimated = true
+	}
+	if writer.reportedOutputTokens != nil {
+		meta.TokensOut = *writer.reportedOutputTokens
+	} else {
+		meta.TokensOut = services.EstimateTokens(output)
+		meta.TokensEstimated = true
+	}
+	return meta
+}
+
+// recordUsage stores byte/token usage for a prompt or response. Tokens are estimated
+// from the content unless the provider reported them; failures are only logged.
+func (c *Client) recordUsage(chatID int64, msg *models.Message, providerID, direction, content string, reportedTokens *int64) {
+	if c.hub.usageService == nil {
+		return
+	}
+
+	var messageID *int64
+	if msg != nil {
+		messageID = &msg.ID
+	}
+
+	var err error
+	if reportedTokens != nil {
+		err = c.hub.usageService.Record(&models.UsageRecord{
+			ChatID:    chatID,
+			MessageID: messageID,
+			Provider:  providerID,
+			Direction: direction,
+			Bytes:     int64(len(content)),
+			Tokens:    *reportedTokens,
+		})
+	} else {
+		err = c.hub.usageService.RecordContent(chatID, messageID, providerID, direction, content)
+	}
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to record %s usage for chat %d: %v", c.requestID, direction, chatID, err)
+	}
+}
+
+// handleSessionStatus handles session status updates
+func (c *Client) handleSessionStatus(data models.WSMsgData) {
+	// The chat being viewed is followed live
+	if data.ChatID > 0 {
+		c.subscribeChat(data.ChatID)
+	}
+}
+
+// subscribeChat makes the client receive a chat's live messages, e.g. in another browser tab than
+// the one prompting. Clients following too many chats are told so.
+func (c *Client) subscribeChat(chatID int64) {
+	if chatID <= 0 {
+		c.sendError("chat_id is required to subscribe to a chat")
+		return
+	}
+	if !c.hub.subscribe(c, chatID) {
+		c.sendError(fmt.Sprintf("Too many chat subscriptions (max %d)", MaxChatSubscriptions))
+	}
+}
+
+// setChatListSubscription toggles delivery of chat_list_changed events
+func (c *Client) setChatListSubscription(subscribed bool) {
+	c.mu.Lock()
+	c.chatListSubscribed = subscribed
+	c.mu.Unlock()
+}
+
+// sendError sends an error message to the client
+func (c *Client) sendError(message string) {
+	c.sendProviderError("", message)
+}
+
+// sendProviderError sends an error message about one provider to the client, so that in compare
+// mode the failing column can be told apart
+func (c *Client) sendProviderError(providerID, message string) {
+	msg := models.WebSocketMessage{
+		Type:    "error",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			Content:   message,
+			Provider:  providerID,
+			Timestamp: time.Now(),
+			RequestID: c.requestID,
+		},
+	}
+	utils.Warn("[request_id=%s] WebSocket error sent to client: %s", c.requestID, message)
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal error message: %v", c.requestID, err)
+		return
+	}
+
+	select {
+	case c.send <- data:
+	default:
+		utils.Error("[request_id=%s] Failed to send error message to client", c.requestID)
+	}
+}
+
+// maySend reports whether the client's role allows a message type and tells the client when it
+// doesn't
+func (c *Client) maySend(msgType string) bool {
+	required, ok := messageRoles[msgType]
+	if !ok || models.RoleAllows(c.role, required) {
+		return true
+	}
+
+	utils.Warn("[request_id=%s] WebSocket message %s refused for role %q", c.requestID, msgType, c.role)
+	msg := models.WebSocketMessage{
+		Type:    "error",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			Content:   fmt.Sprintf("The %s role is required to send %s messages", required, msgType),
+			Action:    "forbidden",
+			Timestamp: time.Now(),
+			RequestID: c.requestID,
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal forbidden message: %v", c.requestID, err)
+		return false
+	}
+
+	select {
+	case c.send <- data:
+	default:
+		utils.Error("[request_id=%s] Failed to send forbidden message to client", c.requestID)
+	}
+	return false
+}
+
+// consumeQuota counts prompts against the quotas of the client's session, or of its user when the
+// ticket was issued to a bearer token, and reports whether they may be sent. Refused prompts are
+// reported to the client; if the usage can't be counted the prompts are allowed.
+func (c *Client) consumeQuota(prompts int) bool {
+	key := services.QuotaKey(c.sessionID, c.userID)
+	if c.hub.quotaService == nil || key == "" {
+		return true
+	}
+
+	status, err := c.hub.quotaService.Consume(key, int64(prompts))
+	if errors.Is(err, services.ErrQuotaExceeded) {
+		c.sendQuotaExceeded(status)
+		return false
+	}
+	if err != nil {
+		utils.Warn("[request_id=%s] Failed to count prompts against quota: %v", c.requestID, err)
+	}
+	return true
+}
+
+// sendQuotaExceeded tells the client that its session has used up its prompt quota
+func (c *Client) sendQuotaExceeded(status *models.QuotaStatus) {
+	msg := models.WebSocketMessage{
+		Type:    "error",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			Content:   fmt.Sprintf("Prompt quota exceeded: new prompts can be sent after %s", services.QuotaResetsAt(status).Format(time.RFC3339)),
+			Action:    "quota_exceeded",
+			Quota:     status,
+			Timestamp: time.Now(),
+			RequestID: c.requestID,
+		},
+	}
+	utils.Warn("[request_id=%s] Prompt quota exceeded for session", c.requestID)
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal quota exceeded message: %v", c.requestID, err)
+		return
+	}
+
+	select {
+	case c.send <- data:
+	default:
+		utils.Error("[request_id=%s] Failed to send quota exceeded message to client", c.requestID)
+	}
+}
+
+// validatePrompt checks a prompt against the chat service's limits and tells the client why it
+// was refused
+func (c *Client) validatePrompt(content string) bool {
+	err := c.hub.chatService.ValidatePrompt(content)
+	if err == nil {
+		return true
+	}
+
+	msg := models.WebSocketMessage{
+		Type:    "error",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			Content:   promptErrorMessage(c.lang, err),
+			Action:    "prompt_rejected",
+			Timestamp: time.Now(),
+			RequestID: c.requestID,
+		},
+	}
+	utils.Warn("[request_id=%s] Prompt rejected: %v", c.requestID, err)
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal prompt rejected message: %v", c.requestID, err)
+		return false
+	}
+
+	select {
+	case c.send <- data:
+	default:
+		utils.Error("[request_id=%s] Failed to send prompt rejected message to client", c.requestID)
+	}
+	return false
+}
+
+// moderatePrompt checks a prompt with the moderation service and tells the client if it was blocked
+func (c *Client) moderatePrompt(chatID int64, provider, content string) bool {
+	if c.hub.moderationService == nil {
+		return true
+	}
+	verdict := c.hub.moderationService.Check(c.ctx, moderation.Request{Direction: moderation.DirectionPrompt, ChatID: chatID, Provider: provider, Content: content})
+	if !verdict.Blocked() {
+		return true
+	}
+	c.sendMessageBlocked(chatID, provider, moderation.DirectionPrompt, verdict)
+	return false
+}
+
+// sendMessageBlocked tells the client that moderation blocked its prompt, or the client and the
+// others viewing the chat that it blocked a response
+func (c *Client) sendMessageBlocked(chatID int64, provider, direction string, verdict moderation.Verdict) {
+	key := "chat.promptBlocked"
+	if direction == moderation.DirectionResponse {
+		key = "chat.responseBlocked"
+	}
+	content := i18n.T(c.lang, key)
+	if verdict.Reason != "" {
+		content = fmt.Sprintf("%s (%s)", content, verdict.Reason)
+	}
+	msg := models.WebSocketMessage{
+		Type:    "message_blocked",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    chatID,
+			Provider:  provider,
+			Action:    direction,
+			Content:   content,
+			Timestamp: time.Now(),
+			RequestID: c.requestID,
+		},
+	}
+
+	if err := c.sendTracked(context.Background(), msg); err != nil {
+		utils.Error("[request_id=%s] Failed to send message blocked message to client: %v", c.requestID, err)
+	}
+	if direction != moderation.DirectionResponse {
+		return
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal message blocked message: %v", c.requestID, err)
+		return
+	}
+	c.hub.broadcastToChat(chatID, data, c)
+}
+
+// sendStreamCompletion sends a stream completion message to the client, with the number of chunks
+// streamed so clients that missed some can resume the stream
+func (c *Client) sendStreamCompletion(chatID int64, provider, streamID string, chunks int64) {
+	msg := models.WebSocketMessage{
+		Type:    "ai_response_end",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    chatID,
+			Provider:  provider,
+			StreamID:  streamID,
+			StreamSeq: chunks,
+			Timestamp: time.Now(),
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal stream completion message: %v", c.requestID, err)
+		return
+	}
+
+	if err := c.sendTracked(context.Background(), msg); err != nil {
+		utils.Error("[request_id=%s] Failed to send stream completion message to client: %v", c.requestID, err)
+	} else {
+		utils.Debug("[request_id=%s] Stream completion sent for chat %d", c.requestID, chatID)
+	}
+	c.hub.broadcastToChat(chatID, data, c)
+}
+
+// sendMultiCompletion tells the client that every provider in a compare-mode prompt has finished
+func (c *Client) sendMultiCompletion(chatID int64, providerIDs []string) {
+	msg := models.WebSocketMessage{
+		Type:    "ai_response_multi_end",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    chatID,
+			Providers: providerIDs,
+			Timestamp: time.Now(),
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal multi completion message: %v", c.requestID, err)
+		return
+	}
+
+	if err := c.sendTracked(context.Background(), msg); err != nil {
+		utils.Error("[request_id=%s] Failed to send multi completion message to client: %v", c.requestID, err)
+	} else {
+		utils.Debug("[request_id=%s] Multi-provider completion sent for chat %d", c.requestID, chatID)
+	}
+	c.hub.broadcastToChat(chatID, data, c)
+}
+
+// sendResponseTimeout tells the client and the others viewing the chat that a provider's response
+// was stopped by its overall or idle timeout
+func (c *Client) sendResponseTimeout(chatID int64, provider, kind string, limit time.Duration) {
+	content := fmt.Sprintf("%s did not finish its response within %v", provider, limit)
+	if kind == ResponseTimeoutIdle {
+		content = fmt.Sprintf("%s sent no output for %v and was stopped", provider, limit)
+	}
+	msg := models.WebSocketMessage{
+		Type:    "ai_response_timeout",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:      chatID,
+			Provider:    provider,
+			Action:      kind,
+			Content:     content,
+			TimeoutSecs: int64(limit / time.Second),
+			Timestamp:   time.Now(),
+			RequestID:   c.requestID,
+		},
+	}
+	utils.Warn("[request_id=%s] Response timeout for chat %d: %s", c.requestID, chatID, content)
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal response timeout message: %v", c.requestID, err)
+		return
+	}
+
+	if err := c.sendTracked(context.Background(), msg); err != nil {
+		utils.Error("[request_id=%s] Failed to send response timeout message to client: %v", c.requestID, err)
+	}
+	c.hub.broadcastToChat(chatID, data, c)
+}
+
+// sendResponseOversized tells the client and the others viewing the chat that a provider's response
+// went over the size limit, and whether it was cut off there
+func (c *Client) sendResponseOversized(chatID int64, provider string, limits services.MessageLimits) {
+	action, key := OversizedResponseTruncated, "chat.responseTruncated"
+	if !limits.TruncatesResponses() {
+		action, key = OversizedResponseReported, "chat.responseOversized"
+	}
+	msg := models.WebSocketMessage{
+		Type:    "ai_response_oversized",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    chatID,
+			Provider:  provider,
+			Action:    action,
+			Content:   i18n.T(c.lang, key, provider, limits.MaxResponseBytes),
+			Timestamp: time.Now(),
+			RequestID: c.requestID,
+		},
+	}
+	utils.Warn("[request_id=%s] %s response for chat %d is over the %d byte limit (%s)", c.requestID, provider, chatID, limits.MaxResponseBytes, action)
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal oversized response message: %v", c.requestID, err)
+		return
+	}
+
+	if err := c.sendTracked(context.Background(), msg); err != nil {
+		utils.Error("[request_id=%s] Failed to send oversized response message to client: %v", c.requestID, err)
+	}
+	c.hub.broadcastToChat(chatID, data, c)
+}
+
+// reportProgress sends ai_progress with the time elapsed and bytes streamed every progressInterval
+// until the returned stop is called; after stop returns no more progress is sent
+func (c *Client) reportProgress(chatID int64, providerID, generationID string, writer *websocketWriter) (stop func()) {
+	if c.hub.progressInterval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(c.hub.progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.sendGenerationStatus("ai_progress", chatID, providerID, generationID, writer.startedAt, writer.streamed())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// sendGenerationStatus sends a transient status of a generation (ai_thinking, provider_started,
+// ai_progress) to the prompting client and the chat's other subscribers. Status events aren't
+// numbered or kept for resending; a slow client misses them.
+func (c *Client) sendGenerationStatus(msgType string, chatID int64, providerID, generationID string, startedAt time.Time, streamed int64) {
+	data, err := json.Marshal(models.WebSocketMessage{
+		Type:    msgType,
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:        chatID,
+			Provider:      providerID,
+			StreamID:      generationID,
+			ElapsedMs:     time.Since(startedAt).Milliseconds(),
+			BytesStreamed: streamed,
+			Timestamp:     time.Now(),
+		},
+	})
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal %s message: %v", c.requestID, msgType, err)
+		return
+	}
+
+	select {
+	case <-c.gone:
+	default:
+		select {
+		case c.send <- data:
+		default:
+			utils.Debug("[request_id=%s] Dropped %s for slow client", c.requestID, msgType)
+		}
+	}
+	c.hub.broadcastToChat(chatID, data, c)
+}
+
+// relayUserMessage shows a prompt to the other clients viewing its chat, e.g. other browser tabs or
+// other participants, before the response streams to them
+func (c *Client) relayUserMessage(msg *models.Message, provider string) {
+	if msg == nil {
+		return
+	}
+	data, err := json.Marshal(models.WebSocketMessage{
+		Type:    "user_message",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    msg.ChatID,
+			Provider:  provider,
+			MessageID: msg.ID,
+			Content:   msg.Content,
+			UserID:    msg.UserID,
+			Timestamp: time.Now(),
+		},
+	})
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal user message: %v", c.requestID, err)
+		return
+	}
+	c.hub.broadcastToChat(msg.ChatID, data, c)
+}
+
+// sendRetry tells clients a prompt that failed is sent to the provider again after delay, as the
+// given attempt
+func (c *Client) sendRetry(chatID int64, provider, generationID string, attempt int, delay time.Duration, cause error) {
+	msg := models.WebSocketMessage{
+		Type:    "ai_retry",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    chatID,
+			Provider:  provider,
+			StreamID:  generationID,
+			Action:    providers.ClassifyError(cause),
+			Attempt:   attempt,
+			RetryInMs: delay.Milliseconds(),
+			Content:   fmt.Sprintf("%s failed (%v), retrying in %v (attempt %d of %d)", provider, cause, delay, attempt, c.hub.retryPolicy.MaxAttempts),
+			Timestamp: time.Now(),
+		},
+	}
+	utils.Warn("[request_id=%s] Retrying prompt for chat %d: %s", c.requestID, chatID, msg.Data.Content)
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal retry message: %v", c.requestID, err)
+		return
+	}
+
+	if err := c.sendTracked(context.Background(), msg); err != nil {
+		utils.Error("[request_id=%s] Failed to send retry message to client: %v", c.requestID, err)
+	}
+	c.hub.broadcastToChat(chatID, data, c)
+}
+
+// sendFailover tells clients a prompt went to the next provider of its failover chain, which
+// answers it as the given generation
+func (c *Client) sendFailover(chatID int64, provider, generationID string, chain *services.Failover) {
+	failed := chain.Failed[len(chain.Failed)-1]
+	msg := models.WebSocketMessage{
+		Type:    "ai_failover",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    chatID,
+			Provider:  provider,
+			Providers: chain.Failed,
+			StreamID:  generationID,
+			Content:   fmt.Sprintf("%s couldn't answer (%v), %s is answering instead", failed, chain.LastErr, provider),
+			Timestamp: time.Now(),
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal failover message: %v", c.requestID, err)
+		return
+	}
+
+	if err := c.sendTracked(context.Background(), msg); err != nil {
+		utils.Error("[request_id=%s] Failed to send failover message to client: %v", c.requestID, err)
+	}
+	c.hub.broadcastToChat(chatID, data, c)
+}
+
+// sendResponseSaved tells clients the ID a streamed response was saved under, e.g. to rate it, and
+// the processed content to show instead of what streamed if post processors changed it
+func (c *Client) sendResponseSaved(chatID int64, provider string, messageID int64, processed string, meta *models.MessageMetadata) {
+	msg := models.WebSocketMessage{
+		Type:    "ai_response_saved",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    chatID,
+			Provider:  provider,
+			MessageID: messageID,
+			Content:   processed,
+			Metadata:  meta,
+			Timestamp: time.Now(),
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal response saved message: %v", c.requestID, err)
+		return
+	}
+
+	if err := c.sendTracked(context.Background(), msg); err != nil {
+		utils.Error("[request_id=%s] Failed to send response saved message to client: %v", c.requestID, err)
+	}
+	c.hub.broadcastToChat(chatID, data, c)
+}
+
+// websocketWriter implements io.Writer for streaming to WebSocket. Chunks are batched into frames
+// of flushBytes bytes or sent every flushInterval, and coalesced while the client's send buffer is
+// full instead of failing the stream; Flush sends the rest.
+type websocketWriter struct {
+	ctx          context.Context // bounds waiting for a slow client
+	mu           sync.Mutex      // Write and the flush timer both send pending chunks
+	client       *Client
+	chatID       int64
+	provider     string
+	generationID string
+	wroteFirst   bool
+	buffer       *string
+	pending      string // chunks not sent to the client yet
+	chunker      services.StreamChunker // what of pending is held back until more output arrives
+	seq          int64  // frames sent so far; each is a chunk of the stream for resume_stream
+	startedAt    time.Time
+	onFirstWrite func() // called when the provider starts producing output (nil to ignore)
+
+	// Batching of chunks into frames (0 disables either)
+	flushBytes    int
+	flushInterval time.Duration
+	flushedAt     time.Time
+	flushTimer    *time.Timer // sends pending chunks once flushInterval passed without another write
+	flushErr      error       // failure of a timed flush, reported by the next Write or Flush
+	clientErr     error       // failure to send a frame to the client, which isn't the provider's
+
+	// Stops the response when it goes idleTimeout without output (nil without idle timeout)
+	idleTimer   *time.Timer
+	idleTimeout time.Duration
+	checkpoint   *services.StreamCheckpointer // nil when checkpointing is disabled
+
+	// Responses over limits.MaxResponseBytes are cut off and stopped with stop, or only reported
+	limits    services.MessageLimits
+	stop      func()
+	oversized bool
+
+	// Token counts reported by the provider, if any
+	reportedInputTokens  *int64
+	reportedOutputTokens *int64
+}
+
+// ReportUsage implements providers.UsageReporter
+func (w *websocketWriter) ReportUsage(inputTokens, outputTokens int64) {
+	w.reportedInputTokens = &inputTokens
+	w.reportedOutputTokens = &outputTokens
+}
+
+func (w *websocketWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.flushErr != nil {
+		return 0, w.flushErr
+	}
+
+	if w.idleTimer != nil && len(p) > 0 {
+		w.idleTimer.Reset(w.idleTimeout)
+	}
+
+	if w.oversized && w.limits.TruncatesResponses() {
+		return len(p), nil // the rest is dropped while the provider stops
+	}
+	content := string(p)
+	if w.limits.ResponseTooLarge(len(*w.buffer) + len(content)) {
+		w.oversized = true
+		if w.limits.TruncatesResponses() {
+			kept := len(services.TruncateUTF8(*w.buffer+content, w.limits.MaxResponseBytes)) - len(*w.buffer)
+			content = content[:max(kept, 0)]
+			if w.stop != nil {
+				w.stop()
+			}
+		}
+	}
+	*w.buffer += content
+	w.pending += content
+
+	if !w.wroteFirst && len(p) > 0 {
+		w.wroteFirst = true
+		w.client.recordGenerationEvent(w.generationID, w.chatID, w.provider, models.GenerationFirstToken, "")
+		if w.onFirstWrite != nil {
+			w.onFirstWrite()
+		}
+	}
+
+	// Don't hold up the provider for a slow client until the coalesced frame grows too large
+	if w.flushDue() || len(w.pending) >= MaxCoalescedBytes {
+		if err := w.flush(false); err != nil {
+			return 0, err
+		}
+	} else if w.flushInterval > 0 && w.flushTimer == nil {
+		w.flushTimer = time.AfterFunc(w.flushInterval-time.Since(w.flushedAt), w.timedFlush)
+	}
+
+	if w.checkpoint != nil {
+		w.checkpoint.Update(*w.buffer)
+	}
+	return len(p), nil
+}
+
+// flushDue reports whether the pending chunks should be sent now rather than batched further
+func (w *websocketWriter) flushDue() bool {
+	if w.client.sendBufferFull() {
+		return false
+	}
+	if w.flushBytes <= 0 && w.flushInterval <= 0 {
+		return true
+	}
+	return (w.flushBytes > 0 && len(w.pending) >= w.flushBytes) ||
+		(w.flushInterval > 0 && time.Since(w.flushedAt) >= w.flushInterval)
+}
+
+// timedFlush sends chunks that waited flushInterval for more to arrive
+func (w *websocketWriter) timedFlush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushTimer = nil
+	if w.flushErr == nil {
+		w.flushErr = w.flush(false)
+	}
+}
+
+// Flush sends the chunks batched so far as a single frame, including any held back
+func (w *websocketWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.flushErr != nil {
+		return w.flushErr
+	}
+	return w.flush(true)
+}
+
+// clientFailed reports whether sending a frame to the client failed, e.g. one too far behind
+// acknowledging frames
+func (w *websocketWriter) clientFailed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.clientErr != nil
+}
+
+// streamed returns how many bytes of the response the provider wrote so far
+func (w *websocketWriter) streamed() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return int64(len(*w.buffer))
+}
+
+// chunks returns how many frames of the stream were sent
+func (w *websocketWriter) chunks() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.seq
+}
+
+// flush sends the pending chunks, but for the end the chunker holds back unless all is set; the
+// caller holds mu
+func (w *websocketWriter) flush(all bool) error {
+	if w.flushTimer != nil {
+		w.flushTimer.Stop()
+		w.flushTimer = nil
+	}
+	content, held := w.pending, ""
+	if !all {
+		content, held = w.chunker.Split(w.pending)
+	}
+	if content == "" {
+		return nil
+	}
+
+	msg := models.WebSocketMessage{
+		Type:    "ai_response",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:      w.chatID,
+			Provider:    w.provider,
+			Content:     content,
+			Timestamp:   time.Now(),
+			Stream:      true,
+			StreamID:    w.generationID,
+			StreamSeq:   w.seq + 1,
+			StreamStart: w.seq,
+		},
+	}
+
+	// Other clients viewing the chat get the frame unnumbered, as they can't ask for it again
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := w.client.sendTracked(w.ctx, msg); err != nil {
+		w.clientErr = err
+		return err
+	}
+	w.seq++
+	w.client.bufferChunk(w.chatID, w.generationID, content)
+	w.pending = held
+	w.flushedAt = time.Now()
+
+	w.client.hub.broadcastToChat(w.chatID, data, w.client)
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crashingProvider fails every prompt before any output
+type crashingProvider struct {
+	mockAIProvider
+}
+
+func (p *crashingProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	return errors.New("exit status 1")
+}
+
+func TestStreamProviderResponse_Failover(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Failover", "crashing")
+	require.NoError(t, err)
+
+	registry := services.NewProviderRegistry(nil)
+	crashing := &crashingProvider{mockAIProvider{name: "crashing", healthy: true}}
+	require.NoError(t, registry.Register(crashing))
+	require.NoError(t, registry.Register(&mockAIProvider{name: "down"}))
+	require.NoError(t, registry.Register(&mockAIProvider{name: "mock", healthy: true}))
+	registry.SetFailoverChains(map[string][]string{"crashing": {"down", "mock"}})
+
+	hub := NewHub(nil, chatService, registry, nil, nil, nil)
+	client := addTestClient(hub, chat.ID, false)
+	client.send = make(chan []byte, 32)
+
+	provider, release, chain, ok := client.acquireProvider("crashing", "")
+	require.True(t, ok)
+	assert.Equal(t, "crashing", provider.GetID())
+	client.streamProviderResponse(provider, chat.ID, nil, "hello", nil, "", "gen-1", chain)
+	release()
+
+	assert.Equal(t, "ai_thinking", receiveFrame(t, client).Type)
+	failover := receiveFrame(t, client)
+	require.Equal(t, "ai_failover", failover.Type)
+	assert.Equal(t, "mock", failover.Data.Provider)
+	assert.Equal(t, []string{"crashing", "down"}, failover.Data.Providers, "unavailable fallbacks are skipped")
+	assert.NotEqual(t, "gen-1", failover.Data.StreamID, "the fallback answers as a new generation")
+	assert.Contains(t, failover.Data.Content, "mock is answering instead")
+
+	var saved bool
+	for len(client.send) > 0 {
+		msg := receiveFrame(t, client)
+		assert.NotEqual(t, "error", msg.Type)
+		if msg.Type == "ai_response_saved" {
+			saved = true
+			assert.Equal(t, "mock", msg.Data.Provider)
+			require.NotNil(t, msg.Data.Metadata)
+			assert.Equal(t, []string{"crashing", "down"}, msg.Data.Metadata.FailedOver)
+		}
+	}
+	assert.True(t, saved)
+
+	messages, err := chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Mock streaming response", messages[0].Content)
+	assert.Equal(t, "mock", messages[0].Provider)
+	assert.Equal(t, []string{"crashing", "down"}, messages[0].Metadata.FailedOver)
+
+	// An unavailable provider is replaced before the prompt is sent
+	registry.SetFailoverChains(map[string][]string{"down": {"mock"}})
+	provider, release, chain, ok = client.acquireProvider("down", "")
+	require.True(t, ok)
+	release()
+	assert.Equal(t, "mock", provider.GetID())
+	assert.Equal(t, []string{"down"}, chain.Failed)
+
+	// Without a chain the provider's failure is reported
+	_, _, _, ok = client.acquireProvider("mock-missing", "")
+	assert.False(t, ok)
+	assert.Equal(t, "error", receiveFrame(t, client).Type)
+}
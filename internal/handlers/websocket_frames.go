@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+)
+
+const (
+	// Maximum number of unacknowledged frames kept for retransmission per client; a client this far
+	// behind has stopped acknowledging and its stream is aborted
+	MaxUnackedFrames = 512
+
+	// Longest a frame waits for room in a slow client's send buffer
+	WebSocketSendTimeout = 10 * time.Second
+
+	// Streamed chunks are coalesced while the send buffer is full, up to this many bytes per frame
+	MaxCoalescedBytes = 64 * 1024
+)
+
+// trackedFrame is a frame sent to the prompting client that it hasn't acknowledged yet
+type trackedFrame struct {
+	id   int64
+	data []byte
+}
+
+// sendTracked numbers a frame and queues it for the client, keeping it until the client
+// acknowledges it. While the send buffer is full it waits for room until ctx is done or
+// WebSocketSendTimeout passed; a frame that still doesn't fit stays kept and is delivered when the
+// client asks for a resend. Frames for a client that left are dropped, so its streams still finish.
+func (c *Client) sendTracked(ctx context.Context, msg models.WebSocketMessage) error {
+	select {
+	case <-c.gone:
+		return nil
+	default:
+	}
+
+	// Frames are queued in the order they are numbered, also when several streams share the client
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	c.frameMu.Lock()
+	if len(c.unacked) >= MaxUnackedFrames {
+		c.frameMu.Unlock()
+		return io.ErrClosedPipe
+	}
+	msg.Version = models.WSProtocolVersion
+	msg.ID = c.lastFrameID + 1
+	data, err := json.Marshal(msg)
+	if err != nil {
+		c.frameMu.Unlock()
+		return err
+	}
+	c.lastFrameID = msg.ID
+	c.unacked = append(c.unacked, trackedFrame{id: msg.ID, data: data})
+	c.frameMu.Unlock()
+
+	select {
+	case c.send <- data:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(WebSocketSendTimeout)
+	defer timer.Stop()
+	select {
+	case c.send <- data:
+	case <-c.gone:
+	case <-ctx.Done():
+		utils.Debug("[request_id=%s] Send buffer full, frame %d kept for retransmission", c.requestID, msg.ID)
+	case <-timer.C:
+		utils.Debug("[request_id=%s] Send buffer full, frame %d kept for retransmission", c.requestID, msg.ID)
+	}
+	return nil
+}
+
+// sendBufferFull reports whether queuing another frame for the client would have to wait
+func (c *Client) sendBufferFull() bool {
+	return len(c.send) >= cap(c.send)
+}
+
+// acknowledge drops the kept frames up to and including id
+func (c *Client) acknowledge(id int64) {
+	c.frameMu.Lock()
+	defer c.frameMu.Unlock()
+
+	n := 0
+	for n < len(c.unacked) && c.unacked[n].id <= id {
+		n++
+	}
+	c.unacked = c.unacked[n:]
+}
+
+// resendUnacked acknowledges the frames up to id and sends the ones after it again, as far as
+// the send buffer allows
+func (c *Client) resendUnacked(id int64) {
+	c.acknowledge(id)
+
+	c.frameMu.Lock()
+	defer c.frameMu.Unlock()
+
+	for _, frame := range c.unacked {
+		select {
+		case c.send <- frame.data:
+		default:
+			utils.Warn("[request_id=%s] Send buffer full, resending stopped before frame %d", c.requestID, frame.id)
+			return
+		}
+	}
+	if len(c.unacked) > 0 {
+		utils.Debug("[request_id=%s] Resent %d frames after frame %d", c.requestID, len(c.unacked), id)
+	}
+}
+
+// bufferChunk keeps a streamed chunk for clients that resume the stream later; failures only mean
+// the chunk can't be replayed
+func (c *Client) bufferChunk(chatID int64, streamID, content string) {
+	if c.hub.streamBuffer == nil {
+		return
+	}
+	if err := c.hub.streamBuffer.Append(c.ctx, chatID, streamID, content); err != nil {
+		utils.Debug("[request_id=%s] Failed to buffer chunk of stream %s: %v", c.requestID, streamID, err)
+	}
+}
+
+// finishBufferedStream records that a buffered stream ended, so resuming it replays the completion
+func (c *Client) finishBufferedStream(chatID int64, streamID string) {
+	if c.hub.streamBuffer == nil {
+		return
+	}
+	if err := c.hub.streamBuffer.Finish(c.ctx, chatID, streamID); err != nil {
+		utils.Debug("[request_id=%s] Failed to finish buffered stream %s: %v", c.requestID, streamID, err)
+	}
+}
+
+// resumeStream replays the chunks of a buffered stream after stream_seq, e.g. to a client that
+// reconnected mid-response, followed by ai_response_end once the stream has ended. The client
+// views the chat from then on, so it receives the rest of the stream as it arrives.
+func (c *Client) resumeStream(data models.WSMsgData) {
+	if data.ChatID <= 0 || data.StreamID == "" {
+		c.sendError("chat_id and stream_id are required to resume a stream")
+		return
+	}
+
+	c.hub.subscribe(c, data.ChatID)
+
+	if c.hub.streamBuffer == nil {
+		c.sendStreamExpired(data)
+		return
+	}
+	chunks, done, err := c.hub.streamBuffer.Since(c.ctx, data.ChatID, data.StreamID, data.StreamSeq)
+	if errors.Is(err, services.ErrStreamNotFound) {
+		c.sendStreamExpired(data)
+		return
+	}
+	if err != nil {
+		utils.Warn("[request_id=%s] Failed to load buffered stream %s: %v", c.requestID, data.StreamID, err)
+		c.sendError("Failed to resume stream")
+		return
+	}
+
+	// Missed chunks are replayed coalesced into frames of up to MaxCoalescedBytes
+	seq := data.StreamSeq
+	for len(chunks) > 0 {
+		start := seq
+		var content strings.Builder
+		for len(chunks) > 0 && (content.Len() == 0 || content.Len()+len(chunks[0]) <= MaxCoalescedBytes) {
+			content.WriteString(chunks[0])
+			chunks = chunks[1:]
+			seq++
+		}
+
+		msg := models.WebSocketMessage{
+			Type: "ai_response",
+			Data: models.WSMsgData{
+				ChatID:      data.ChatID,
+				Provider:    data.Provider,
+				Content:     content.String(),
+				Timestamp:   time.Now(),
+				Stream:      true,
+				StreamID:    data.StreamID,
+				StreamSeq:   seq,
+				StreamStart: start,
+			},
+		}
+		if err := c.sendTracked(context.Background(), msg); err != nil {
+			utils.Warn("[request_id=%s] Failed to replay stream %s: %v", c.requestID, data.StreamID, err)
+			return
+		}
+	}
+	utils.Debug("[request_id=%s] Resumed stream %s from chunk %d to %d", c.requestID, data.StreamID, data.StreamSeq, seq)
+
+	if done {
+		msg := models.WebSocketMessage{
+			Type: "ai_response_end",
+			Data: models.WSMsgData{
+				ChatID:    data.ChatID,
+				Provider:  data.Provider,
+				StreamID:  data.StreamID,
+				StreamSeq: seq,
+				Timestamp: time.Now(),
+			},
+		}
+		if err := c.sendTracked(context.Background(), msg); err != nil {
+			utils.Warn("[request_id=%s] Failed to replay completion of stream %s: %v", c.requestID, data.StreamID, err)
+		}
+	}
+}
+
+// sendStreamExpired tells the client that a stream it asked to resume is no longer buffered; the
+// saved response can be loaded from the chat's messages instead
+func (c *Client) sendStreamExpired(data models.WSMsgData) {
+	msg := models.WebSocketMessage{
+		Type:    "error",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			ChatID:    data.ChatID,
+			Provider:  data.Provider,
+			StreamID:  data.StreamID,
+			Content:   "The response can no longer be resumed",
+			Action:    "stream_expired",
+			Timestamp: time.Now(),
+			RequestID: c.requestID,
+		},
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal stream expired message: %v", c.requestID, err)
+		return
+	}
+
+	select {
+	case c.send <- payload:
+	default:
+		utils.Error("[request_id=%s] Failed to send stream expired message to client", c.requestID)
+	}
+}
+
+// refuseOutdatedClient tells a client speaking an unsupported protocol version to upgrade and
+// closes its connection once the error was delivered
+func (c *Client) refuseOutdatedClient(version int) {
+	if version == 0 {
+		version = 1
+	}
+	msg := models.WebSocketMessage{
+		Type:    "error",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			Content:   fmt.Sprintf("Upgrade required: this page speaks WebSocket protocol version %d, but the server requires version %d or newer. Reload the page to upgrade.", version, models.WSMinProtocolVersion),
+			Action:    "upgrade_required",
+			Timestamp: time.Now(),
+			RequestID: c.requestID,
+		},
+	}
+	utils.Warn("[request_id=%s] Refusing WebSocket client with protocol version %d", c.requestID, version)
+
+	if data, err := json.Marshal(msg); err == nil {
+		select {
+		case c.send <- data:
+		default:
+		}
+	}
+
+	// Unregistering closes the send channel; writePump delivers the error before closing the connection
+	c.hub.unregister <- c
+}
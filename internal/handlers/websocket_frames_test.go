@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/moderation"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveFrame(t *testing.T, client *Client) models.WebSocketMessage {
+	t.Helper()
+	var msg models.WebSocketMessage
+	require.NoError(t, json.Unmarshal(<-client.send, &msg))
+	return msg
+}
+
+func TestClient_SendTrackedAndResend(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	client := addTestClient(hub, 1, false)
+
+	// A done context doesn't wait for room in the send buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, content := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, client.sendTracked(ctx, models.WebSocketMessage{Type: "ai_response", Data: models.WSMsgData{Content: content}}))
+	}
+
+	// The send buffer holds four frames; the fifth is kept for retransmission
+	for i := int64(1); i <= 4; i++ {
+		msg := receiveFrame(t, client)
+		assert.Equal(t, i, msg.ID)
+		assert.Equal(t, models.WSProtocolVersion, msg.Version)
+	}
+	assert.Len(t, client.send, 0)
+
+	client.acknowledge(2)
+	assert.Len(t, client.unacked, 3)
+
+	// The client missed frame 3 and asks for everything after frame 2
+	client.resendUnacked(2)
+	for _, want := range []string{"c", "d", "e"} {
+		assert.Equal(t, want, receiveFrame(t, client).Data.Content)
+	}
+
+	client.acknowledge(5)
+	assert.Empty(t, client.unacked)
+}
+
+func TestClient_SendTrackedStopsWhenClientStopsAcknowledging(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	client := addTestClient(hub, 1, false)
+	client.send = make(chan []byte, MaxUnackedFrames+1)
+
+	for i := 0; i < MaxUnackedFrames; i++ {
+		require.NoError(t, client.sendTracked(context.Background(), models.WebSocketMessage{Type: "ai_response"}))
+	}
+	assert.Error(t, client.sendTracked(context.Background(), models.WebSocketMessage{Type: "ai_response"}))
+
+	client.acknowledge(int64(MaxUnackedFrames))
+	assert.NoError(t, client.sendTracked(context.Background(), models.WebSocketMessage{Type: "ai_response"}))
+}
+
+func TestClient_RefuseOutdatedClient(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	client := &Client{hub: hub, send: make(chan []byte, 4)}
+	go func() { <-hub.unregister }()
+
+	client.refuseOutdatedClient(0)
+
+	msg := receiveFrame(t, client)
+	assert.Equal(t, "error", msg.Type)
+	assert.Equal(t, "upgrade_required", msg.Data.Action)
+	assert.Contains(t, msg.Data.Content, "version 1")
+}
+
+func TestWebsocketWriter_CoalescesWhileClientIsSlow(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	client := addTestClient(hub, 1, false)
+	client.send = make(chan []byte, 1)
+
+	var response string
+	writer := &websocketWriter{ctx: context.Background(), client: client, chatID: 1, provider: "claude", buffer: &response}
+
+	// The first chunk fills the send buffer; the following ones wait for the next frame
+	for _, chunk := range []string{"Hel", "lo, ", "wor", "ld"} {
+		n, err := writer.Write([]byte(chunk))
+		require.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+	assert.Equal(t, "Hello, world", response)
+	assert.Equal(t, "Hel", receiveFrame(t, client).Data.Content)
+
+	require.NoError(t, writer.Flush())
+	msg := receiveFrame(t, client)
+	assert.Equal(t, "lo, world", msg.Data.Content)
+	assert.Equal(t, int64(2), msg.ID)
+	assert.NoError(t, writer.Flush(), "nothing left to flush")
+	assert.Len(t, client.send, 0)
+}
+
+func TestWebsocketWriter_BatchesChunks(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	client := addTestClient(hub, 1, false)
+
+	var response string
+	writer := &websocketWriter{ctx: context.Background(), client: client, chatID: 1, provider: "claude", buffer: &response, flushBytes: 8}
+	writer.flushedAt = time.Now()
+
+	for _, chunk := range []string{"abc", "def"} {
+		_, err := writer.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	assert.Len(t, client.send, 0, "chunks wait until flushBytes accumulated")
+	_, err := writer.Write([]byte("gh"))
+	require.NoError(t, err)
+	assert.Equal(t, "abcdefgh", receiveFrame(t, client).Data.Content)
+
+	// With an interval, a chunk that was held back is sent once the interval passed
+	writer = &websocketWriter{ctx: context.Background(), client: client, chatID: 1, provider: "claude", buffer: &response, flushInterval: 20 * time.Millisecond}
+	_, err = writer.Write([]byte("first"))
+	require.NoError(t, err)
+	assert.Equal(t, "first", receiveFrame(t, client).Data.Content, "the first chunk isn't delayed")
+	_, err = writer.Write([]byte("second"))
+	require.NoError(t, err)
+	select {
+	case data := <-client.send:
+		var msg models.WebSocketMessage
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, "second", msg.Data.Content)
+	case <-time.After(time.Second):
+		t.Fatal("held back chunk was not flushed")
+	}
+	assert.NoError(t, writer.Flush())
+}
+
+func TestWebsocketWriter_HoldsBackChunkBoundaries(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	client := addTestClient(hub, 1, false)
+
+	// A character split between writes is sent whole with the next frame
+	var response string
+	writer := &websocketWriter{ctx: context.Background(), client: client, chatID: 1, provider: "claude", buffer: &response}
+	euro := []byte("€")
+	_, err := writer.Write(append([]byte("1 "), euro[:2]...))
+	require.NoError(t, err)
+	assert.Equal(t, "1 ", receiveFrame(t, client).Data.Content)
+	_, err = writer.Write(append(euro[2:], " each"...))
+	require.NoError(t, err)
+	assert.Equal(t, "€ each", receiveFrame(t, client).Data.Content)
+
+	// With markdown chunking a code fence line waits for its newline
+	response = ""
+	writer = &websocketWriter{ctx: context.Background(), client: client, chatID: 1, provider: "claude", buffer: &response,
+		chunker: services.NewStreamChunker(services.StreamChunkingMarkdown)}
+	_, err = writer.Write([]byte("Example:\n``"))
+	require.NoError(t, err)
+	assert.Equal(t, "Example:\n", receiveFrame(t, client).Data.Content)
+	_, err = writer.Write([]byte("`go\nx := **"))
+	require.NoError(t, err)
+	assert.Equal(t, "```go\nx := ", receiveFrame(t, client).Data.Content)
+
+	// What is still held is sent when the response ends
+	require.NoError(t, writer.Flush())
+	assert.Equal(t, "**", receiveFrame(t, client).Data.Content)
+	assert.Equal(t, "Example:\n```go\nx := **", response)
+}
+
+func TestClient_SendTrackedAfterClientLeft(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	client := addTestClient(hub, 1, false)
+	close(client.gone)
+
+	// Streams keep running for a client that left; its frames are dropped instead of kept
+	for i := 0; i < MaxUnackedFrames+1; i++ {
+		require.NoError(t, client.sendTracked(context.Background(), models.WebSocketMessage{Type: "ai_response"}))
+	}
+	assert.Len(t, client.send, 0)
+	assert.Empty(t, client.unacked)
+}
+
+// chunkedProvider streams its chunks as separate writes
+type chunkedProvider struct {
+	mockAIProvider
+	chunks []string
+}
+
+func (p *chunkedProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	for _, chunk := range p.chunks {
+		if _, err := io.WriteString(writer, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestClient_ResumeStream(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Resume", "mock")
+	require.NoError(t, err)
+
+	hub := NewHub(nil, chatService, nil, nil, nil, nil)
+	hub.SetStreamResume(services.NewMemoryStreamBuffer(time.Minute))
+	origin := addTestClient(hub, chat.ID, false)
+	origin.send = make(chan []byte, 16)
+
+	provider := &chunkedProvider{mockAIProvider{name: "mock", healthy: true}, []string{"a", "b", "c"}}
+	origin.streamProviderResponse(provider, chat.ID, nil, "hello", nil, "", "gen-1", nil)
+	assert.Equal(t, "ai_thinking", receiveFrame(t, origin).Type)
+	assert.Equal(t, "provider_started", receiveFrame(t, origin).Type)
+
+	// Live frames tell which chunks of the stream they carry
+	for i := int64(1); i <= 3; i++ {
+		msg := receiveFrame(t, origin)
+		assert.Equal(t, "ai_response", msg.Type)
+		assert.Equal(t, "gen-1", msg.Data.StreamID)
+		assert.Equal(t, i-1, msg.Data.StreamStart)
+		assert.Equal(t, i, msg.Data.StreamSeq)
+	}
+	end := receiveFrame(t, origin)
+	assert.Equal(t, "ai_response_end", end.Type)
+	assert.Equal(t, int64(3), end.Data.StreamSeq)
+
+	// A client that reconnected after the first chunk gets the rest in one frame, then the completion
+	resumed := addTestClient(hub, 0, false)
+	resumed.resumeStream(models.WSMsgData{ChatID: chat.ID, Provider: "mock", StreamID: "gen-1", StreamSeq: 1})
+
+	replay := receiveFrame(t, resumed)
+	assert.Equal(t, "ai_response", replay.Type)
+	assert.Equal(t, "bc", replay.Data.Content)
+	assert.Equal(t, int64(1), replay.Data.StreamStart)
+	assert.Equal(t, int64(3), replay.Data.StreamSeq)
+	assert.Equal(t, "mock", replay.Data.Provider)
+	end = receiveFrame(t, resumed)
+	assert.Equal(t, "ai_response_end", end.Type)
+	assert.Equal(t, int64(3), end.Data.StreamSeq)
+	assert.True(t, resumed.chats[chat.ID], "the resuming client views the chat")
+
+	// Streams that aren't buffered can't be resumed
+	resumed.resumeStream(models.WSMsgData{ChatID: chat.ID, StreamID: "unknown"})
+	expired := receiveFrame(t, resumed)
+	assert.Equal(t, "error", expired.Type)
+	assert.Equal(t, "stream_expired", expired.Data.Action)
+	assert.Equal(t, "unknown", expired.Data.StreamID)
+}
+
+func TestClient_StreamContinuesAfterClientLeft(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Left", "mock")
+	require.NoError(t, err)
+
+	hub := NewHub(nil, chatService, nil, nil, nil, nil)
+	hub.SetStreamResume(services.NewMemoryStreamBuffer(time.Minute))
+	origin := addTestClient(hub, chat.ID, false)
+	close(origin.gone)
+
+	provider := &chunkedProvider{mockAIProvider{name: "mock", healthy: true}, []string{"x", "y"}}
+	origin.streamProviderResponse(provider, chat.ID, nil, "hello", nil, "", "gen-2", nil)
+
+	// The response is saved and buffered although nobody received it
+	messages, err := chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, messages)
+	assert.Equal(t, "xy", messages[len(messages)-1].Content)
+
+	resumed := addTestClient(hub, 0, false)
+	resumed.resumeStream(models.WSMsgData{ChatID: chat.ID, StreamID: "gen-2"})
+	assert.Equal(t, "xy", receiveFrame(t, resumed).Data.Content)
+	assert.Equal(t, "ai_response_end", receiveFrame(t, resumed).Type)
+}
+
+func TestWebsocketWriter_LimitsResponseSize(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	client := addTestClient(hub, 1, false)
+
+	// Over the limit the response is cut off without splitting a character and stopped
+	var response string
+	stopped := 0
+	writer := &websocketWriter{ctx: context.Background(), client: client, chatID: 1, provider: "claude", buffer: &response,
+		limits: services.MessageLimits{MaxResponseBytes: 8}, stop: func() { stopped++ }}
+	for _, chunk := range []string{"abc", "dあい", "more"} {
+		n, err := writer.Write([]byte(chunk))
+		require.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+	require.NoError(t, writer.Flush())
+	assert.Equal(t, "abcdあ", response)
+	assert.True(t, writer.oversized)
+	assert.Equal(t, 1, stopped)
+
+	// With warn the whole response is kept
+	response = ""
+	writer = &websocketWriter{ctx: context.Background(), client: client, chatID: 1, provider: "claude", buffer: &response,
+		limits: services.MessageLimits{MaxResponseBytes: 4, OversizedAction: services.OversizedResponseWarn}}
+	for _, chunk := range []string{"abc", "def"} {
+		_, err := writer.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "abcdef", response)
+	assert.True(t, writer.oversized)
+}
+
+func TestClient_ValidatePrompt(t *testing.T) {
+	require.NoError(t, i18n.Init("../../locales", "en"))
+	chatService := services.NewChatService(nil)
+	chatService.SetMessageLimits(services.MessageLimits{MaxPromptLength: 10})
+	hub := NewHub(nil, chatService, nil, nil, nil, nil)
+	client := &Client{hub: hub, send: make(chan []byte, 4), lang: "ja"}
+
+	assert.True(t, client.validatePrompt("こんにちは"))
+	assert.Len(t, client.send, 0)
+
+	assert.False(t, client.validatePrompt("this prompt is too long"))
+	msg := receiveFrame(t, client)
+	assert.Equal(t, "error", msg.Type)
+	assert.Equal(t, "prompt_rejected", msg.Data.Action)
+	assert.Equal(t, i18n.T("ja", "error.promptTooLongLimit", 23, 10), msg.Data.Content)
+}
+
+func TestClient_ModeratePrompt(t *testing.T) {
+	require.NoError(t, i18n.Init("../../locales", "en"))
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	rules, err := moderation.NewRuleModerator([]moderation.Rule{{Name: "banned", Keywords: []string{"forbidden"}, Action: moderation.ActionBlock, Reason: "banned term"}})
+	require.NoError(t, err)
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	client := addTestClient(hub, 1, false)
+	assert.True(t, client.moderatePrompt(1, "claude", "forbidden"), "nothing is moderated without a moderation service")
+
+	hub.SetModeration(services.NewModerationService(db, moderation.NewPipeline(false, rules)))
+	assert.True(t, client.moderatePrompt(1, "claude", "hello"))
+	assert.Len(t, client.send, 0)
+
+	assert.False(t, client.moderatePrompt(1, "claude", "something forbidden"))
+	msg := receiveFrame(t, client)
+	assert.Equal(t, "message_blocked", msg.Type)
+	assert.Equal(t, moderation.DirectionPrompt, msg.Data.Action)
+	assert.Equal(t, i18n.T("en", "chat.promptBlocked")+" (banned term)", msg.Data.Content)
+}
+
+func TestClient_NonAckingClientDoesNotTripCircuit(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Slow client", "mock")
+	require.NoError(t, err)
+
+	registry := services.NewProviderRegistry(nil)
+	breaker := services.NewCircuitBreaker(1, time.Minute)
+	registry.SetCircuitBreaker(breaker)
+	chunks := make([]string, MaxUnackedFrames+10)
+	for i := range chunks {
+		chunks[i] = "x"
+	}
+	provider := &chunkedProvider{mockAIProvider{name: "mock", healthy: true}, chunks}
+	require.NoError(t, registry.Register(provider))
+
+	hub := NewHub(nil, chatService, registry, nil, nil, nil)
+	client := addTestClient(hub, chat.ID, false)
+	client.send = make(chan []byte, 2*MaxUnackedFrames)
+
+	// The client never acknowledges, so its stream is aborted once MaxUnackedFrames are pending
+	_, release, err := registry.Acquire("mock")
+	require.NoError(t, err)
+	client.streamProviderResponse(provider, chat.ID, nil, "hello", nil, "", "gen-1", nil)
+	release()
+
+	assert.Len(t, client.unacked, MaxUnackedFrames)
+	assert.Nil(t, breaker.State("mock"), "a client falling behind doesn't degrade the provider")
+	_, release, err = registry.Acquire("mock")
+	require.NoError(t, err)
+	release()
+}
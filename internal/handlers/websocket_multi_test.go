@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rendezvousProvider streams its first chunk, then waits until every provider sharing the barrier
+// has started before finishing, so it only completes when the providers run concurrently
+type rendezvousProvider struct {
+	mockAIProvider
+	barrier *sync.WaitGroup
+}
+
+func (p *rendezvousProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	if _, err := io.WriteString(writer, p.name+" first "); err != nil {
+		return err
+	}
+	p.barrier.Done()
+
+	started := make(chan struct{})
+	go func() {
+		p.barrier.Wait()
+		close(started)
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		return context.DeadlineExceeded
+	}
+
+	_, err := io.WriteString(writer, p.name+" second")
+	return err
+}
+
+func TestClient_HandleAIPromptMulti(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+	// Every connection to an in-memory database opens a new, empty one
+	db.SetMaxOpenConns(1)
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Compare", "alpha")
+	require.NoError(t, err)
+
+	barrier := &sync.WaitGroup{}
+	barrier.Add(2)
+	registry := services.NewProviderRegistry(nil)
+	require.NoError(t, registry.Register(&rendezvousProvider{mockAIProvider{name: "alpha", healthy: true}, barrier}))
+	require.NoError(t, registry.Register(&rendezvousProvider{mockAIProvider{name: "beta", healthy: true}, barrier}))
+	require.NoError(t, registry.Register(&crashingProvider{mockAIProvider{name: "broken", healthy: true}}))
+
+	hub := NewHub(nil, chatService, registry, nil, nil, nil)
+	client := addTestClient(hub, chat.ID, false)
+	client.send = make(chan []byte, 256)
+
+	client.handleAIPromptMulti(models.WSMsgData{ChatID: chat.ID, Content: "Compare this", Providers: []string{"alpha", "beta", "alpha", "broken"}})
+
+	streamed := make(map[string]string)
+	saved := make(map[string]int)
+	var errs []models.WebSocketMessage
+	var end *models.WebSocketMessage
+	timeout := time.After(10 * time.Second)
+	for end == nil {
+		select {
+		case data := <-client.send:
+			var msg models.WebSocketMessage
+			require.NoError(t, json.Unmarshal(data, &msg))
+			switch msg.Type {
+			case "ai_response":
+				streamed[msg.Data.Provider] += msg.Data.Content
+			case "ai_response_saved":
+				saved[msg.Data.Provider]++
+			case "error":
+				errs = append(errs, msg)
+			case "ai_response_multi_end":
+				end = &msg
+			}
+		case <-timeout:
+			t.Fatal("compare mode didn't finish")
+		}
+	}
+
+	// Both providers streamed concurrently, each under its own ID
+	assert.Equal(t, "alpha first alpha second", streamed["alpha"])
+	assert.Equal(t, "beta first beta second", streamed["beta"])
+	assert.Equal(t, []string{"alpha", "beta", "broken"}, end.Data.Providers, "duplicates are dropped")
+
+	// The failing provider's error says which column it belongs to
+	require.Len(t, errs, 1)
+	assert.Equal(t, "broken", errs[0].Data.Provider)
+	assert.Contains(t, errs[0].Data.Content, "exit status 1")
+
+	// One user message, and one assistant message per answering provider
+	assert.Equal(t, map[string]int{"alpha": 1, "beta": 1}, saved)
+	messages, err := chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "user", messages[0].Role)
+	answers := make(map[string]string)
+	for _, msg := range messages[1:] {
+		assert.Equal(t, "assistant", msg.Role)
+		answers[msg.Provider] = msg.Content
+	}
+	assert.Equal(t, map[string]string{"alpha": "alpha first alpha second", "beta": "beta first beta second"}, answers)
+
+	// A provider that can't be acquired is named in the refusal
+	client.handleAIPromptMulti(models.WSMsgData{ChatID: chat.ID, Content: "Again", Providers: []string{"alpha", "missing"}})
+	refusal := receiveFrame(t, client)
+	assert.Equal(t, "error", refusal.Type)
+	assert.Equal(t, "missing", refusal.Data.Provider)
+}
+
+func TestClient_ConsumeQuotaForTokenUser(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	quotaService := services.NewQuotaService(services.NewMemoryQuotaStore(), 3, 0)
+	hub.SetPromptQuotas(quotaService)
+
+	// Tickets issued to a bearer token carry the user but no session
+	client := addTestClient(hub, 0, false)
+	client.userID = "bob"
+
+	assert.True(t, client.consumeQuota(2))
+	assert.False(t, client.consumeQuota(2), "a compare prompt counts once per provider")
+	refusal := receiveFrame(t, client)
+	assert.Equal(t, "quota_exceeded", refusal.Data.Action)
+	assert.True(t, client.consumeQuota(1))
+
+	status, err := quotaService.Status(services.QuotaKey("", "bob"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), status.Daily.Used)
+
+	// Without a session or a user nothing can be counted
+	assert.True(t, addTestClient(hub, 0, false).consumeQuota(10))
+}
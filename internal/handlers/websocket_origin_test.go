@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"ai-gateway-hub/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckWebSocketOrigin(t *testing.T) {
+	cfg := &config.Config{AllowedOrigins: []string{"https://*.example.com"}}
+
+	check := func(host, origin string) bool {
+		req := httptest.NewRequest("GET", "/ws", nil)
+		req.Host = host
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		return checkWebSocketOrigin(req, cfg)
+	}
+
+	assert.True(t, check("hub.internal:8080", "http://hub.internal:8080"), "same origin")
+	assert.True(t, check("hub.internal:8080", "https://team.example.com"), "matches ALLOWED_ORIGINS like CORS")
+	assert.False(t, check("hub.internal:8080", "https://evil.example"))
+	assert.False(t, check("hub.internal:8080", ""), "no Origin header")
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyProvider fails the first prompts with a 503 before any output
+type flakyProvider struct {
+	mockAIProvider
+	failures int
+	calls    int
+}
+
+func (p *flakyProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	p.calls++
+	if p.calls <= p.failures {
+		return &providers.StatusError{Provider: p.name, StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Detail: "overloaded"}
+	}
+	return p.mockAIProvider.StreamResponse(ctx, prompt, chatID, writer)
+}
+
+func TestStreamProviderResponse_Retry(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Retry", "flaky")
+	require.NoError(t, err)
+
+	hub := NewHub(nil, chatService, nil, nil, nil, nil)
+	hub.SetRetryPolicy(services.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond, RetryOn: []string{providers.ErrorClassServer}})
+	client := addTestClient(hub, chat.ID, false)
+	client.send = make(chan []byte, 32)
+
+	flaky := &flakyProvider{mockAIProvider: mockAIProvider{name: "flaky", healthy: true}, failures: 2}
+	client.streamProviderResponse(flaky, chat.ID, nil, "hello", nil, "", "gen-1", nil)
+	assert.Equal(t, 3, flaky.calls)
+
+	var retries []int
+	var saved bool
+	for len(client.send) > 0 {
+		msg := receiveFrame(t, client)
+		assert.NotEqual(t, "error", msg.Type)
+		switch msg.Type {
+		case "ai_retry":
+			retries = append(retries, msg.Data.Attempt)
+			assert.Equal(t, "server", msg.Data.Action)
+			assert.Equal(t, "gen-1", msg.Data.StreamID)
+			assert.Contains(t, msg.Data.Content, "503 Service Unavailable")
+		case "ai_response_saved":
+			saved = true
+		}
+	}
+	assert.Equal(t, []int{2, 3}, retries)
+	assert.True(t, saved)
+
+	messages, err := chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Mock streaming response", messages[0].Content)
+
+	// Once the attempts are used up the error is reported
+	flaky = &flakyProvider{mockAIProvider: mockAIProvider{name: "flaky", healthy: true}, failures: 5}
+	client.streamProviderResponse(flaky, chat.ID, nil, "hello", nil, "", "gen-2", nil)
+	assert.Equal(t, 3, flaky.calls)
+	var failed bool
+	for len(client.send) > 0 {
+		if receiveFrame(t, client).Type == "error" {
+			failed = true
+		}
+	}
+	assert.True(t, failed)
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// wsSchemaJSON is the JSON Schema of the WebSocket protocol, served at /api/ws/schema
+//
+//go:embed websocket_schema.json
+var wsSchemaJSON []byte
+
+// wsProtocolSchema validates the messages clients send
+var wsProtocolSchema = mustParseWSSchema(wsSchemaJSON)
+
+// wsSchema is the subset of JSON Schema the protocol schema is written in
+type wsSchema struct {
+	Ref        string               `json:"$ref"`
+	Type       string               `json:"type"`
+	Const      any                  `json:"const"`
+	Enum       []any                `json:"enum"`
+	Required   []string             `json:"required"`
+	Properties map[string]*wsSchema `json:"properties"`
+	Items      *wsSchema            `json:"items"`
+	Minimum    *float64             `json:"minimum"`
+	MinLength  *int                 `json:"minLength"`
+	MinItems   *int                 `json:"minItems"`
+	Direction  string               `json:"x-direction"` // client or server, for message definitions
+	Defs       map[string]*wsSchema `json:"$defs"`
+}
+
+func mustParseWSSchema(data []byte) *wsSchema {
+	var schema wsSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		panic(fmt.Sprintf("invalid WebSocket protocol schema: %v", err))
+	}
+	return &schema
+}
+
+// WebSocketSchemaHandler serves the JSON Schema of every WebSocket message type
+func (h *APIHandlers) WebSocketSchemaHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/schema+json", wsSchemaJSON)
+	}
+}
+
+// validateClientMessage checks a message from a client against the protocol schema, returning its
+// type and where it breaks the schema
+func validateClientMessage(message []byte) (string, []models.WSSchemaError) {
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "", []models.WSSchemaError{{Message: "is not valid JSON: " + err.Error()}}
+	}
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return "", []models.WSSchemaError{{Message: "must be an object"}}
+	}
+
+	msgType, _ := fields["type"].(string)
+	def, ok := wsProtocolSchema.Defs[msgType]
+	if !ok || def.Direction != "client" {
+		return msgType, []models.WSSchemaError{{Path: "type", Message: fmt.Sprintf("%q is not a message type clients send", msgType)}}
+	}
+	return msgType, wsProtocolSchema.validate(def, value, "")
+}
+
+// validate checks a decoded JSON value against a schema, resolving references in the root's $defs
+func (root *wsSchema) validate(s *wsSchema, value any, path string) []models.WSSchemaError {
+	if s.Ref != "" {
+		return root.validate(root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")], value, path)
+	}
+	fail := func(format string, args ...any) []models.WSSchemaError {
+		return []models.WSSchemaError{{Path: path, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if s.Const != nil && !reflect.DeepEqual(value, s.Const) {
+		return fail("must be %v", s.Const)
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(v any) bool { return reflect.DeepEqual(value, v) }) {
+		return fail("must be one of %v", s.Enum)
+	}
+
+	switch s.Type {
+	case "object":
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return fail("must be an object")
+		}
+		var errs []models.WSSchemaError
+		for _, name := range s.Required {
+			if _, ok := fields[name]; !ok {
+				errs = append(errs, models.WSSchemaError{Path: joinSchemaPath(path, name), Message: "is required"})
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+			if field, ok := fields[name]; ok {
+				errs = append(errs, root.validate(s.Properties[name], field, joinSchemaPath(path, name))...)
+			}
+		}
+		return errs
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fail("must be an array")
+		}
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			return fail("must have at least %d items", *s.MinItems)
+		}
+		var errs []models.WSSchemaError
+		if s.Items != nil {
+			for i, item := range items {
+				errs = append(errs, root.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+		return errs
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		if s.MinLength != nil && utf8.RuneCountInString(text) < *s.MinLength {
+			return fail("must not be empty")
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fail("must be an integer")
+		}
+		n, err := number.Int64()
+		if err != nil {
+			return fail("must be an integer")
+		}
+		if s.Minimum != nil && float64(n) < *s.Minimum {
+			return fail("must be at least %v", *s.Minimum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("must be a boolean")
+		}
+	}
+	return nil
+}
+
+// joinSchemaPath names a field of the value at path, e.g. data.chat_id
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// sendProtocolError tells the client a message it sent broke the protocol schema
+func (c *Client) sendProtocolError(msgType string, errs []models.WSSchemaError) {
+	label := "WebSocket"
+	if msgType != "" {
+		label = msgType
+	}
+	problem := errs[0].Message
+	if errs[0].Path != "" {
+		problem = errs[0].Path + " " + problem
+	}
+	utils.Warn("[request_id=%s] Invalid %s message: %s", c.requestID, label, problem)
+
+	msg := models.WebSocketMessage{
+		Type:    "error",
+		Version: models.WSProtocolVersion,
+		Data: models.WSMsgData{
+			Content:   fmt.Sprintf("Invalid %s message: %s", label, problem),
+			Action:    "protocol_error",
+			Errors:    errs,
+			Timestamp: time.Now(),
+			RequestID: c.requestID,
+		},
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Error("[request_id=%s] Failed to marshal protocol error: %v", c.requestID, err)
+		return
+	}
+
+	select {
+	case c.send <- data:
+	default:
+		utils.Error("[request_id=%s] Failed to send protocol error to client", c.requestID)
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai-gateway-hub/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateClientMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		wantType string
+		wantErrs []models.WSSchemaError
+	}{
+		{
+			name:     "prompt",
+			message:  `{"type": "ai_prompt", "version": 2, "data": {"chat_id": 1, "provider": "claude", "content": "Hi", "attachment_ids": [3], "timestamp": "2024-01-01T00:00:00Z"}}`,
+			wantType: "ai_prompt",
+		},
+		{name: "session status", message: `{"type": "session_status", "version": 2, "data": {"chat_id": 1, "provider": "claude"}}`, wantType: "session_status"},
+		{name: "ack", message: `{"type": "ack", "version": 2, "ack": 16, "data": {}}`, wantType: "ack"},
+		{name: "resume stream", message: `{"type": "resume_stream", "version": 2, "data": {"chat_id": 1, "provider": "claude", "stream_id": "gen-1", "stream_seq": 4}}`, wantType: "resume_stream"},
+		{
+			name:     "missing fields",
+			message:  `{"type": "ai_prompt", "version": 2, "data": {"content": "Hi"}}`,
+			wantType: "ai_prompt",
+			wantErrs: []models.WSSchemaError{{Path: "data.chat_id", Message: "is required"}, {Path: "data.provider", Message: "is required"}},
+		},
+		{
+			name:     "wrong types",
+			message:  `{"type": "ai_prompt_multi", "version": 2, "data": {"chat_id": "1", "providers": [], "content": "Hi", "images": [{"data": 5}]}}`,
+			wantType: "ai_prompt_multi",
+			wantErrs: []models.WSSchemaError{
+				{Path: "data.chat_id", Message: "must be an integer"},
+				{Path: "data.images[0].data", Message: "must be a string"},
+				{Path: "data.providers", Message: "must have at least 1 items"},
+			},
+		},
+		{
+			name:     "fractional ack",
+			message:  `{"type": "resend", "version": 2, "ack": 1.5}`,
+			wantType: "resend",
+			wantErrs: []models.WSSchemaError{{Path: "ack", Message: "must be an integer"}},
+		},
+		{
+			name:     "unknown type",
+			message:  `{"type": "ai_promt", "version": 2, "data": {}}`,
+			wantType: "ai_promt",
+			wantErrs: []models.WSSchemaError{{Path: "type", Message: `"ai_promt" is not a message type clients send`}},
+		},
+		{
+			name:     "server message",
+			message:  `{"type": "ai_response", "version": 2, "data": {}}`,
+			wantType: "ai_response",
+			wantErrs: []models.WSSchemaError{{Path: "type", Message: `"ai_response" is not a message type clients send`}},
+		},
+		{name: "not an object", message: `[1]`, wantErrs: []models.WSSchemaError{{Message: "must be an object"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgType, errs := validateClientMessage([]byte(tt.message))
+			assert.Equal(t, tt.wantType, msgType)
+			assert.Equal(t, tt.wantErrs, errs)
+		})
+	}
+
+	_, errs := validateClientMessage([]byte(`{"type":`))
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Message, "is not valid JSON")
+}
+
+func TestWebSocketSchema(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/ws/schema", NewAPIHandlers(nil).WebSocketSchemaHandler())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ws/schema", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/schema+json", w.Header().Get("Content-Type"))
+
+	// The schema keeps up with the protocol version
+	var schema struct {
+		Version int `json:"x-protocol-version"`
+		OneOf   []struct {
+			Ref string `json:"$ref"`
+		} `json:"oneOf"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &schema))
+	assert.Equal(t, models.WSProtocolVersion, schema.Version)
+	assert.Equal(t, float64(models.WSMinProtocolVersion), *wsProtocolSchema.Defs["version"].Minimum)
+
+	// Every message type is listed, and every client message is one readPump handles
+	handled := []string{"ai_prompt", "ai_prompt_multi", "ai_regenerate", "session_status", "subscribe_chat", "unsubscribe_chat",
+		"subscribe_chat_list", "unsubscribe_chat_list", "ack", "resend", "resume_stream"}
+	var listed, clientTypes []string
+	for _, ref := range schema.OneOf {
+		listed = append(listed, ref.Ref)
+	}
+	for name, def := range wsProtocolSchema.Defs {
+		if def.Direction == "" {
+			continue
+		}
+		assert.Contains(t, listed, "#/$defs/"+name)
+		if def.Direction == "client" {
+			clientTypes = append(clientTypes, name)
+		}
+	}
+	assert.ElementsMatch(t, handled, clientTypes)
+}
+
+func TestClient_SendProtocolError(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	client := addTestClient(hub, 0, false)
+	client.requestID = "req-1"
+
+	msgType, errs := validateClientMessage([]byte(`{"type": "subscribe_chat", "version": 2, "data": {"chat_id": 0}}`))
+	require.NotEmpty(t, errs)
+	client.sendProtocolError(msgType, errs)
+
+	msg := receiveFrame(t, client)
+	assert.Equal(t, "error", msg.Type)
+	assert.Equal(t, "protocol_error", msg.Data.Action)
+	assert.Equal(t, "Invalid subscribe_chat message: data.chat_id must be at least 1", msg.Data.Content)
+	assert.Equal(t, []models.WSSchemaError{{Path: "data.chat_id", Message: "must be at least 1"}}, msg.Data.Errors)
+	assert.Equal(t, "req-1", msg.Data.RequestID)
+}
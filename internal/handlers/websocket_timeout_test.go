@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stallingProvider writes a chunk and then waits for the request to be cancelled
+type stallingProvider struct {
+	mockAIProvider
+}
+
+func (p *stallingProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	if _, err := writer.Write([]byte("partial")); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestStreamProviderResponse_Timeouts(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Timeouts", "slow")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		total, idle time.Duration
+		wantAction  string
+	}{
+		{name: "idle", total: time.Minute, idle: 20 * time.Millisecond, wantAction: ResponseTimeoutIdle},
+		{name: "overall", total: 20 * time.Millisecond, idle: time.Minute, wantAction: ResponseTimeoutOverall},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(nil, chatService, nil, nil, nil, nil)
+			hub.SetPromptTimeouts(func(string) (time.Duration, time.Duration) { return tt.total, tt.idle })
+			client := addTestClient(hub, chat.ID, false)
+			client.send = make(chan []byte, 8)
+
+			client.streamProviderResponse(&stallingProvider{mockAIProvider{name: "slow", healthy: true}}, chat.ID, nil, "hello", nil, "", "", nil)
+
+			assert.Equal(t, "ai_thinking", receiveFrame(t, client).Type)
+			assert.Equal(t, "provider_started", receiveFrame(t, client).Type)
+			assert.Equal(t, "ai_response", receiveFrame(t, client).Type)
+			timeout := receiveFrame(t, client)
+			assert.Equal(t, "ai_response_timeout", timeout.Type)
+			assert.Equal(t, tt.wantAction, timeout.Data.Action)
+			assert.Equal(t, "slow", timeout.Data.Provider)
+			assert.Equal(t, "ai_response_end", receiveFrame(t, client).Type)
+			assert.Len(t, client.send, 0, "no generic error follows the timeout")
+		})
+	}
+}
+
+// pausingProvider writes a chunk, pauses and writes another
+type pausingProvider struct {
+	mockAIProvider
+	pause time.Duration
+}
+
+func (p *pausingProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	if _, err := writer.Write([]byte("first")); err != nil {
+		return err
+	}
+	time.Sleep(p.pause)
+	_, err := writer.Write([]byte("second"))
+	return err
+}
+
+func TestStreamProviderResponse_StatusEvents(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := services.NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Progress", "mock")
+	require.NoError(t, err)
+
+	hub := NewHub(nil, chatService, nil, nil, nil, nil)
+	hub.progressInterval = 10 * time.Millisecond
+	client := addTestClient(hub, chat.ID, false)
+	client.send = make(chan []byte, 64)
+	otherTab := addTestClient(hub, chat.ID, false)
+	otherTab.send = make(chan []byte, 64)
+
+	client.streamProviderResponse(&pausingProvider{mockAIProvider{name: "mock", healthy: true}, 50 * time.Millisecond}, chat.ID, nil, "hello", nil, "", "gen-1", nil)
+
+	var types []string
+	var progress []models.WebSocketMessage
+	for len(client.send) > 0 {
+		msg := receiveFrame(t, client)
+		types = append(types, msg.Type)
+		if msg.Type == "ai_progress" {
+			progress = append(progress, msg)
+		}
+	}
+	require.GreaterOrEqual(t, len(types), 4)
+	assert.Equal(t, []string{"ai_thinking", "provider_started"}, types[:2])
+	assert.NotContains(t, types[:len(types)-2], "ai_response_end", "no progress follows the completion")
+	assert.Equal(t, "ai_response_saved", types[len(types)-1])
+
+	require.NotEmpty(t, progress, "progress is reported while the provider pauses")
+	first := progress[0]
+	assert.Equal(t, "gen-1", first.Data.StreamID)
+	assert.Equal(t, int64(len("first")), first.Data.BytesStreamed)
+	assert.Positive(t, first.Data.ElapsedMs)
+
+	// Other tabs viewing the chat see the status too
+	assert.Equal(t, "ai_thinking", receiveFrame(t, otherTab).Type)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IssueWSTicketHandler issues a short-lived, single-use ticket for opening a WebSocket; clients
+// pass it as /ws?ticket=... The connection gets the participant ID and role identify returns for
+// the request.
+func (h *APIHandlers) IssueWSTicketHandler(ticketService *services.WSTicketService, identify func(*gin.Context) (userID, role string)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.GetString("session_id")
+		if sessionID == "" {
+			sessionID, _ = c.Cookie("session_id")
+		}
+
+		userID, role := identify(c)
+		grant := services.WSTicketGrant{SessionID: sessionID, UserID: userID, Role: role}
+		ticket, err := ticketService.Issue(c.Request.Context(), grant)
+		if err != nil {
+			h.errorHandler.InternalError(c, "Failed to issue WebSocket ticket", err)
+			return
+		}
+
+		h.errorHandler.Success(c, ticket)
+	}
+}
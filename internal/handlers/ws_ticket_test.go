@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocketTickets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tickets := services.NewWSTicketService(services.NewMemoryTicketStore(), 30*time.Second)
+	hub := NewHub(nil, nil, nil, nil, nil, nil)
+	hub.SetTickets(tickets)
+
+	apiHandlers := NewAPIHandlers(nil)
+	router := gin.New()
+	router.POST("/api/ws/ticket", apiHandlers.IssueWSTicketHandler(tickets, func(*gin.Context) (string, string) { return "alice", models.RoleViewer }))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ws/ticket", nil)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "session-1"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data models.WSTicket `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.Data.Ticket)
+
+	// Connections without a valid ticket are refused, even with a session cookie
+	noTicket := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	noTicket.AddCookie(&http.Cookie{Name: "session_id", Value: "session-1"})
+	_, ok := hub.authenticate(noTicket)
+	assert.False(t, ok)
+	_, ok = hub.authenticate(httptest.NewRequest(http.MethodGet, "/ws?ticket=forged", nil))
+	assert.False(t, ok)
+
+	// The connection gets the session, participant and role the ticket was issued to, once
+	grant, ok := hub.authenticate(httptest.NewRequest(http.MethodGet, "/ws?ticket="+resp.Data.Ticket, nil))
+	assert.True(t, ok)
+	assert.Equal(t, services.WSTicketGrant{SessionID: "session-1", UserID: "alice", Role: models.RoleViewer}, *grant)
+	_, ok = hub.authenticate(httptest.NewRequest(http.MethodGet, "/ws?ticket="+resp.Data.Ticket, nil))
+	assert.False(t, ok)
+
+	// Unauthenticated upgrades are rejected before the handshake
+	router.GET("/ws", WebSocketHandler(hub, nil))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestWebSocketMessageRoles(t *testing.T) {
+	viewer := &Client{send: make(chan []byte, 1), role: models.RoleViewer}
+
+	// Viewers may follow chats but not send prompts
+	assert.True(t, viewer.maySend("subscribe_chat"))
+	assert.True(t, viewer.maySend("ack"))
+	assert.Empty(t, viewer.send)
+
+	assert.False(t, viewer.maySend("ai_prompt"))
+	require.Len(t, viewer.send, 1)
+	var msg models.WebSocketMessage
+	require.NoError(t, json.Unmarshal(<-viewer.send, &msg))
+	assert.Equal(t, "error", msg.Type)
+	assert.Equal(t, "forbidden", msg.Data.Action)
+
+	user := &Client{send: make(chan []byte, 1), role: models.RoleUser}
+	assert.True(t, user.maySend("ai_prompt"))
+	assert.True(t, user.maySend("ai_regenerate"))
+	admin := &Client{send: make(chan []byte, 1), role: models.RoleAdmin}
+	assert.True(t, admin.maySend("ai_prompt_multi"))
+}
@@ -0,0 +1,175 @@
+package i18n
+
+import (
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Go time layouts used when a language does not define its own format keys
+const (
+	defaultDateLayout     = "Jan 2, 2006"
+	defaultDateTimeLayout = "Jan 2, 2006 15:04"
+	defaultTimeLayout     = "15:04"
+)
+
+// translateOr returns the translation for key, or fallback if no language defines it
+func translateOr(lang, key, fallback string) string {
+	if value := T(lang, key); value != key {
+		return value
+	}
+	return fallback
+}
+
+// FormatDate formats t as a date using the language's format.date layout
+func FormatDate(lang string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(translateOr(lang, "format.date", defaultDateLayout))
+}
+
+// FormatDateTime formats t as a date and time using the language's format.dateTime layout
+func FormatDateTime(lang string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(translateOr(lang, "format.dateTime", defaultDateTimeLayout))
+}
+
+// FormatTime formats the time of day of t using the language's format.time layout
+func FormatTime(lang string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(translateOr(lang, "format.time", defaultTimeLayout))
+}
+
+// FormatRelativeTime describes t relative to now ("3 minutes ago", "yesterday"),
+// falling back to the localized date for anything older than a week
+func FormatRelativeTime(lang string, t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	elapsed := now.Sub(t)
+	switch {
+	case elapsed < time.Minute:
+		return T(lang, "time.justNow")
+	case elapsed < time.Hour:
+		if minutes := int(elapsed / time.Minute); minutes > 1 {
+			return T(lang, "time.minutesAgo", minutes)
+		}
+		return T(lang, "time.minuteAgo")
+	case elapsed < 24*time.Hour:
+		if hours := int(elapsed / time.Hour); hours > 1 {
+			return T(lang, "time.hoursAgo", hours)
+		}
+		return T(lang, "time.hourAgo")
+	}
+
+	days := calendarDaysBetween(t.Local(), now.Local())
+	switch {
+	case days <= 1:
+		return T(lang, "time.yesterday")
+	case days < 7:
+		return T(lang, "time.daysAgo", days)
+	}
+	return FormatDate(lang, t)
+}
+
+// calendarDaysBetween counts midnights crossed between from and to
+func calendarDaysBetween(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDay.Sub(fromDay) / (24 * time.Hour))
+}
+
+// FormatNumber formats an integer or floating point value with the language's
+// thousands and decimal separators. Floats keep at most two decimal places.
+func FormatNumber(lang string, n any) string {
+	var digits string
+	switch v := n.(type) {
+	case int:
+		digits = strconv.FormatInt(int64(v), 10)
+	case int32:
+		digits = strconv.FormatInt(int64(v), 10)
+	case int64:
+		digits = strconv.FormatInt(v, 10)
+	case uint:
+		digits = strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		digits = strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		digits = strconv.FormatUint(v, 10)
+	case float32:
+		digits = formatFloat(float64(v))
+	case float64:
+		digits = formatFloat(v)
+	default:
+		return ""
+	}
+
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(digits, ".")
+
+	thousands := translateOr(lang, "format.thousandsSeparator", ",")
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, r := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(thousands)
+		}
+		b.WriteRune(r)
+	}
+	if hasFraction {
+		b.WriteString(translateOr(lang, "format.decimalSeparator", "."))
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// formatFloat renders f with at most two decimal places and no trailing zeros
+func formatFloat(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// templateLang converts the language value passed from template data, defaulting to English
+func templateLang(lang any) string {
+	if l, ok := lang.(string); ok && l != "" {
+		return l
+	}
+	return "en"
+}
+
+// TemplateFuncs returns the localization helpers available to HTML templates
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"T": func(lang any, key string, args ...any) string {
+			return T(templateLang(lang), key, args...)
+		},
+		"Date": func(lang any, t time.Time) string {
+			return FormatDate(templateLang(lang), t)
+		},
+		"DateTime": func(lang any, t time.Time) string {
+			return FormatDateTime(templateLang(lang), t)
+		},
+		"Time": func(lang any, t time.Time) string {
+			return FormatTime(templateLang(lang), t)
+		},
+		"RelativeTime": func(lang any, t time.Time) string {
+			return FormatRelativeTime(templateLang(lang), t, time.Now())
+		},
+		"Number": func(lang any, n any) string {
+			return FormatNumber(templateLang(lang), n)
+		},
+	}
+}
@@ -13,10 +13,14 @@ import (
 	"ai-gateway-hub/internal/utils"
 )
 
+// Languages with a translation file
+var languages = []string{"en", "ja"}
+
 // Localizer handles internationalization
 type Localizer struct {
 	translations map[string]map[string]string
 	defaultLang  string
+	localesDir   string // empty when the translations are embedded
 	mu           sync.RWMutex
 }
 
@@ -29,11 +33,7 @@ var (
 func Init(localesDir string, defaultLang string) error {
 	var initErr error
 	once.Do(func() {
-		instance = &Localizer{
-			translations: make(map[string]map[string]string),
-			defaultLang:  defaultLang,
-		}
-		initErr = instance.loadTranslations(localesDir)
+		instance, initErr = NewLocalizer(localesDir, defaultLang)
 	})
 	return initErr
 }
@@ -51,6 +51,19 @@ func InitWithFS(localeFS embed.FS, defaultLang string) error {
 	return initErr
 }
 
+// NewLocalizer creates a localizer with the translation files of localesDir
+func NewLocalizer(localesDir string, defaultLang string) (*Localizer, error) {
+	l := &Localizer{
+		translations: make(map[string]map[string]string),
+		defaultLang:  defaultLang,
+		localesDir:   localesDir,
+	}
+	if err := l.loadTranslations(localesDir); err != nil {
+		return l, err
+	}
+	return l, nil
+}
+
 // Get returns the singleton localizer instance
 func Get() *Localizer {
 	if instance == nil {
@@ -66,62 +79,60 @@ func T(lang, key string, args ...interface{}) string {
 
 // loadTranslations loads all translation files
 func (l *Localizer) loadTranslations(localesDir string) error {
-	languages := []string{"en", "ja"}
-	
 	for _, lang := range languages {
 		filePath := filepath.Join(localesDir, lang, "messages.json")
 		data, err := ioutil.ReadFile(filePath)
 		if err != nil {
 			return fmt.Errorf("failed to read translation file %s: %w", filePath, err)
 		}
-		
-		// Parse as nested JSON
-		var nestedTranslations map[string]interface{}
-		if err := json.Unmarshal(data, &nestedTranslations); err != nil {
+
+		flatTranslations, err := parseTranslations(data)
+		if err != nil {
 			return fmt.Errorf("failed to parse translation file %s: %w", filePath, err)
 		}
-		
-		// Flatten nested structure
-		flatTranslations := make(map[string]string)
-		flattenMap("", nestedTranslations, flatTranslations)
-		
+
 		l.mu.Lock()
 		l.translations[lang] = flatTranslations
 		l.mu.Unlock()
 	}
-	
+
 	return nil
 }
 
 // loadTranslationsFS loads all translation files from embedded file system
 func (l *Localizer) loadTranslationsFS(localeFS embed.FS) error {
-	languages := []string{"en", "ja"}
-	
 	for _, lang := range languages {
 		filePath := filepath.Join("locales", lang, "messages.json")
 		data, err := fs.ReadFile(localeFS, filePath)
 		if err != nil {
 			return fmt.Errorf("failed to read translation file %s: %w", filePath, err)
 		}
-		
-		// Parse as nested JSON
-		var nestedTranslations map[string]interface{}
-		if err := json.Unmarshal(data, &nestedTranslations); err != nil {
+
+		flatTranslations, err := parseTranslations(data)
+		if err != nil {
 			return fmt.Errorf("failed to parse translation file %s: %w", filePath, err)
 		}
-		
-		// Flatten nested structure
-		flatTranslations := make(map[string]string)
-		flattenMap("", nestedTranslations, flatTranslations)
-		
+
 		l.mu.Lock()
 		l.translations[lang] = flatTranslations
 		l.mu.Unlock()
 	}
-	
+
 	return nil
 }
 
+// parseTranslations parses a nested JSON translation file into flat dotted keys
+func parseTranslations(data []byte) (map[string]string, error) {
+	var nestedTranslations map[string]interface{}
+	if err := json.Unmarshal(data, &nestedTranslations); err != nil {
+		return nil, err
+	}
+
+	flatTranslations := make(map[string]string)
+	flattenMap("", nestedTranslations, flatTranslations)
+	return flatTranslations, nil
+}
+
 // flattenMap recursively flattens a nested map structure
 func flattenMap(prefix string, nested map[string]interface{}, flat map[string]string) {
 	for key, value := range nested {
@@ -0,0 +1,126 @@
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/utils"
+)
+
+// WatchInterval is how often Watch checks the translation files for changes
+const WatchInterval = 5 * time.Second
+
+// ErrEmbedded is returned by Reload when the translations come from the binary
+var ErrEmbedded = errors.New("translations are embedded in the binary; there are no files to reload")
+
+// LanguageReport is the outcome of reloading one language's translation file
+type LanguageReport struct {
+	Language string   `json:"language"`
+	Loaded   bool     `json:"loaded"`          // false when the previous translations are still in use
+	Keys     int      `json:"keys"`            // keys in use after the reload
+	Error    string   `json:"error,omitempty"` // why the file was rejected
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Reload reads the translation files again. Each language is validated on its own: a file that
+// can't be read or parsed keeps its previous translations, while the issues found by Validate
+// are reported as warnings.
+func (l *Localizer) Reload() ([]LanguageReport, error) {
+	if l.localesDir == "" {
+		return nil, ErrEmbedded
+	}
+
+	parsed := make(map[string]map[string]string)
+	reports := make([]LanguageReport, 0, len(languages))
+	for _, lang := range languages {
+		report := LanguageReport{Language: lang}
+		filePath := filepath.Join(l.localesDir, lang, "messages.json")
+		data, err := os.ReadFile(filePath)
+		if err == nil {
+			parsed[lang], err = parseTranslations(data)
+		}
+		switch {
+		case err != nil:
+			report.Error = fmt.Sprintf("failed to load %s: %v", filePath, err)
+		case len(parsed[lang]) == 0:
+			report.Error = fmt.Sprintf("%s has no translations", filePath)
+		default:
+			report.Loaded = true
+		}
+		if !report.Loaded {
+			delete(parsed, lang)
+		}
+		reports = append(reports, report)
+	}
+
+	l.mu.Lock()
+	for lang, translations := range parsed {
+		l.translations[lang] = translations
+	}
+	defaults := l.translations[l.defaultLang]
+	for i := range reports {
+		current := l.translations[reports[i].Language]
+		reports[i].Keys = len(current)
+		if reports[i].Loaded && reports[i].Language != l.defaultLang {
+			reports[i].Warnings = compareLanguage(reports[i].Language, defaults, current).Warnings(l.defaultLang)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, report := range reports {
+		if report.Error != "" {
+			utils.Warn("Kept previous %s translations: %s", report.Language, report.Error)
+			continue
+		}
+		utils.Info("Reloaded %d %s translation(s)", report.Keys, report.Language)
+		for _, warning := range report.Warnings {
+			utils.Warn("Translations %s: %s", report.Language, warning)
+		}
+	}
+	return reports, nil
+}
+
+// Watch polls the translation files and reloads them when one changes, until ctx is done.
+// It returns immediately for embedded translations.
+func (l *Localizer) Watch(ctx context.Context, interval time.Duration) {
+	if l.localesDir == "" {
+		return
+	}
+
+	last := l.fileVersions()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			current := l.fileVersions()
+			if current == last {
+				continue
+			}
+			last = current
+
+			if _, err := l.Reload(); err != nil {
+				utils.Warn("Failed to reload translations: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// fileVersions summarizes the modification time and size of every translation file
+func (l *Localizer) fileVersions() string {
+	var b strings.Builder
+	for _, lang := range languages {
+		if info, err := os.Stat(filepath.Join(l.localesDir, lang, "messages.json")); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d;", lang, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return b.String()
+}
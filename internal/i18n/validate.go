@@ -0,0 +1,112 @@
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Maximum keys listed in a warning
+const maxListedKeys = 10
+
+// placeholderPattern matches fmt verbs such as %s, %d or %.1f
+var placeholderPattern = regexp.MustCompile(`%[-+# 0]*[0-9.]*[a-zA-Z]`)
+
+// LanguageIssues are the differences between a language's translations and the default language's
+type LanguageIssues struct {
+	Language   string                `json:"language"`
+	Missing    []string              `json:"missing"`    // shown in the default language instead
+	Extra      []string              `json:"extra"`      // unknown to the default language, so never used
+	Mismatched []PlaceholderMismatch `json:"mismatched"` // rendered with wrong or missing values
+}
+
+// PlaceholderMismatch is a translation whose fmt verbs differ from the default language's
+type PlaceholderMismatch struct {
+	Key      string   `json:"key"`
+	Expected []string `json:"expected"`
+	Actual   []string `json:"actual"`
+}
+
+// OK reports whether the language has no issues
+func (i LanguageIssues) OK() bool {
+	return len(i.Missing) == 0 && len(i.Extra) == 0 && len(i.Mismatched) == 0
+}
+
+// Warnings describes the issues as log lines
+func (i LanguageIssues) Warnings(defaultLang string) []string {
+	var warnings []string
+	if len(i.Missing) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d key(s) missing, %s is shown instead: %s", len(i.Missing), defaultLang, listKeys(i.Missing)))
+	}
+	if len(i.Extra) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d key(s) not in %s are never used: %s", len(i.Extra), defaultLang, listKeys(i.Extra)))
+	}
+	for _, m := range i.Mismatched {
+		warnings = append(warnings, fmt.Sprintf("%s: placeholders %v differ from %s %v", m.Key, m.Actual, defaultLang, m.Expected))
+	}
+	return warnings
+}
+
+// DefaultLanguage returns the language missing translations fall back to
+func (l *Localizer) DefaultLanguage() string {
+	return l.defaultLang
+}
+
+// Validate compares every language with the default language
+func (l *Localizer) Validate() []LanguageIssues {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	defaults := l.translations[l.defaultLang]
+	var issues []LanguageIssues
+	for _, lang := range languages {
+		if lang == l.defaultLang {
+			continue
+		}
+		issues = append(issues, compareLanguage(lang, defaults, l.translations[lang]))
+	}
+	return issues
+}
+
+// compareLanguage lists the keys of translations that differ from defaults
+func compareLanguage(lang string, defaults, translations map[string]string) LanguageIssues {
+	issues := LanguageIssues{Language: lang, Missing: []string{}, Extra: []string{}, Mismatched: []PlaceholderMismatch{}}
+	for key, value := range defaults {
+		translated, ok := translations[key]
+		if !ok {
+			issues.Missing = append(issues.Missing, key)
+			continue
+		}
+		if expected, actual := placeholders(value), placeholders(translated); !slices.Equal(expected, actual) {
+			issues.Mismatched = append(issues.Mismatched, PlaceholderMismatch{Key: key, Expected: expected, Actual: actual})
+		}
+	}
+	for key := range translations {
+		if _, ok := defaults[key]; !ok {
+			issues.Extra = append(issues.Extra, key)
+		}
+	}
+
+	sort.Strings(issues.Missing)
+	sort.Strings(issues.Extra)
+	sort.Slice(issues.Mismatched, func(a, b int) bool { return issues.Mismatched[a].Key < issues.Mismatched[b].Key })
+	return issues
+}
+
+// placeholders returns the sorted fmt verbs of a translation, ignoring escaped percent signs
+func placeholders(s string) []string {
+	verbs := placeholderPattern.FindAllString(strings.ReplaceAll(s, "%%", ""), -1)
+	sort.Strings(verbs)
+	return verbs
+}
+
+// listKeys joins the first keys of a list
+func listKeys(keys []string) string {
+	listed := strings.Join(keys[:min(len(keys), maxListedKeys)], ", ")
+	if len(keys) > maxListedKeys {
+		listed += ", ..."
+	}
+	return listed
+}
@@ -0,0 +1,258 @@
+// Package jobs runs periodic background work. Jobs are registered with a Scheduler, which runs
+// each on its interval and on demand, and reports their status. Exclusive jobs take a lock first,
+// so with a shared Redis only one instance runs them at a time.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/utils"
+)
+
+// Prefix of the lock keys of exclusive jobs
+const lockKeyPrefix = "jobs:lock:"
+
+var (
+	// ErrUnknownJob is returned when triggering a job that isn't registered
+	ErrUnknownJob = apperrors.NotFound("job not found")
+	// ErrJobRunning is returned when triggering a job that is running or already triggered
+	ErrJobRunning = apperrors.Conflict("job is already running")
+)
+
+// Job is periodic work
+type Job struct {
+	Name       string
+	Interval   time.Duration
+	Timeout    time.Duration // cancels a run that takes longer; defaults to Interval
+	RunAtStart bool          // run once as soon as the scheduler starts
+	Exclusive  bool          // run on one instance at a time
+	Run        func(ctx context.Context) error
+}
+
+// timeout returns how long a run may take
+func (j Job) timeout() time.Duration {
+	if j.Timeout > 0 {
+		return j.Timeout
+	}
+	return j.Interval
+}
+
+// Status describes a job and its runs since the scheduler started
+type Status struct {
+	Name            string     `json:"name"`
+	IntervalSeconds float64    `json:"interval_seconds"`
+	Exclusive       bool       `json:"exclusive"`
+	Running         bool       `json:"running"`
+	Runs            int64      `json:"runs"`
+	Failures        int64      `json:"failures"`
+	Skipped         int64      `json:"skipped"` // runs left to another instance holding the lock
+	LastStartedAt   *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt  *time.Time `json:"last_finished_at,omitempty"`
+	LastDurationMs  int64      `json:"last_duration_ms"`
+	LastError       string     `json:"last_error,omitempty"`
+	NextRunAt       *time.Time `json:"next_run_at,omitempty"`
+}
+
+// entry is a registered job and its status
+type entry struct {
+	job     Job
+	trigger chan struct{}
+	status  Status
+}
+
+// Scheduler runs registered jobs
+type Scheduler struct {
+	locker  Locker
+	mu      sync.Mutex
+	jobs    map[string]*entry
+	started bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler locking exclusive jobs with locker
+func NewScheduler(locker Locker) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{locker: locker, jobs: make(map[string]*entry), ctx: ctx, cancel: cancel}
+}
+
+// Register adds a job; jobs must be registered before Start
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("job must have a name and a run function")
+	}
+	if job.Interval <= 0 {
+		return fmt.Errorf("job %s: interval must be positive", job.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return fmt.Errorf("job %s: the scheduler is already running", job.Name)
+	}
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+	s.jobs[job.Name] = &entry{
+		job:     job,
+		trigger: make(chan struct{}, 1),
+		status:  Status{Name: job.Name, IntervalSeconds: job.Interval.Seconds(), Exclusive: job.Exclusive},
+	}
+	return nil
+}
+
+// Start runs every registered job on its interval
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	for _, e := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(e)
+	}
+	utils.Info("Background jobs started: %d registered", len(s.jobs))
+}
+
+// Stop stops scheduling, cancels the running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// loop runs a job on its interval and when it is triggered
+func (s *Scheduler) loop(e *entry) {
+	defer s.wg.Done()
+
+	if e.job.RunAtStart {
+		s.run(e)
+	}
+	s.setNextRun(e, time.Now().Add(e.job.Interval))
+
+	ticker := time.NewTicker(e.job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.setNextRun(e, time.Now().Add(e.job.Interval))
+			s.run(e)
+		case <-e.trigger:
+			s.run(e)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Scheduler) setNextRun(e *entry, next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.status.NextRunAt = &next
+}
+
+// Trigger runs a job as soon as possible, outside its interval
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.jobs[name]
+	if !ok {
+		return ErrUnknownJob
+	}
+	if !s.started {
+		return apperrors.Conflict("background jobs are not running")
+	}
+	if e.status.Running {
+		return ErrJobRunning
+	}
+	select {
+	case e.trigger <- struct{}{}:
+		return nil
+	default:
+		return ErrJobRunning
+	}
+}
+
+// run runs a job once, taking its lock first if it is exclusive
+func (s *Scheduler) run(e *entry) {
+	ctx, cancel := context.WithTimeout(s.ctx, e.job.timeout())
+	defer cancel()
+
+	if e.job.Exclusive {
+		unlock, ok, err := s.locker.TryLock(ctx, lockKeyPrefix+e.job.Name, e.job.timeout())
+		if err != nil {
+			utils.Warn("Job %s not run: %v", e.job.Name, err)
+			s.finish(e, time.Now(), err)
+			return
+		}
+		if !ok {
+			s.mu.Lock()
+			e.status.Skipped++
+			s.mu.Unlock()
+			return
+		}
+		defer unlock()
+	}
+
+	start := time.Now()
+	s.mu.Lock()
+	e.status.Running = true
+	e.status.LastStartedAt = &start
+	s.mu.Unlock()
+
+	err := runSafely(ctx, e.job)
+	if err != nil {
+		utils.Error("Job %s failed: %v", e.job.Name, err)
+	}
+	s.finish(e, start, err)
+}
+
+// runSafely runs a job, turning a panic into an error so one job can't take the server down
+func runSafely(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// finish records the outcome of a run
+func (s *Scheduler) finish(e *entry, start time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	e.status.Running = false
+	e.status.Runs++
+	e.status.LastFinishedAt = &now
+	e.status.LastDurationMs = now.Sub(start).Milliseconds()
+	e.status.LastError = ""
+	if err != nil {
+		e.status.Failures++
+		e.status.LastError = err.Error()
+	}
+}
+
+// Status returns the status of every registered job, by name
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		statuses = append(statuses, e.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
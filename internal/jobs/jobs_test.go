@@ -0,0 +1,154 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusOf returns the status of the named job
+func statusOf(t *testing.T, s *Scheduler, name string) Status {
+	for _, status := range s.Status() {
+		if status.Name == name {
+			return status
+		}
+	}
+	t.Fatalf("job %s not found", name)
+	return Status{}
+}
+
+func TestRegisterValidatesJobs(t *testing.T) {
+	s := NewScheduler(NewLocalLocker())
+	run := func(context.Context) error { return nil }
+
+	assert.Error(t, s.Register(Job{Interval: time.Second, Run: run}))
+	assert.Error(t, s.Register(Job{Name: "no-run", Interval: time.Second}))
+	assert.Error(t, s.Register(Job{Name: "no-interval", Run: run}))
+	require.NoError(t, s.Register(Job{Name: "ok", Interval: time.Second, Run: run}))
+	assert.Error(t, s.Register(Job{Name: "ok", Interval: time.Second, Run: run}))
+
+	s.Start()
+	defer s.Stop()
+	assert.Error(t, s.Register(Job{Name: "late", Interval: time.Second, Run: run}))
+}
+
+func TestSchedulerRunsAndRecordsStatus(t *testing.T) {
+	s := NewScheduler(NewLocalLocker())
+	var ticks, fails atomic.Int32
+	require.NoError(t, s.Register(Job{Name: "tick", Interval: 20 * time.Millisecond, Run: func(context.Context) error {
+		ticks.Add(1)
+		return nil
+	}}))
+	require.NoError(t, s.Register(Job{Name: "fail", Interval: time.Hour, RunAtStart: true, Run: func(context.Context) error {
+		fails.Add(1)
+		return errors.New("boom")
+	}}))
+	require.NoError(t, s.Register(Job{Name: "panic", Interval: time.Hour, RunAtStart: true, Run: func(context.Context) error {
+		panic("oops")
+	}}))
+
+	s.Start()
+	require.Eventually(t, func() bool { return ticks.Load() >= 2 && statusOf(t, s, "panic").Runs == 1 }, 2*time.Second, 10*time.Millisecond)
+	s.Stop()
+
+	statuses := s.Status()
+	require.Len(t, statuses, 3)
+	assert.Equal(t, "fail", statuses[0].Name)
+
+	fail := statusOf(t, s, "fail")
+	assert.Equal(t, int64(1), fail.Runs)
+	assert.Equal(t, int64(1), fail.Failures)
+	assert.Equal(t, "boom", fail.LastError)
+	assert.NotNil(t, fail.NextRunAt)
+
+	assert.Contains(t, statusOf(t, s, "panic").LastError, "panic: oops")
+
+	tick := statusOf(t, s, "tick")
+	assert.GreaterOrEqual(t, tick.Runs, int64(2))
+	assert.Zero(t, tick.Failures)
+	assert.NotNil(t, tick.LastFinishedAt)
+}
+
+func TestTrigger(t *testing.T) {
+	s := NewScheduler(NewLocalLocker())
+	release := make(chan struct{})
+	var runs atomic.Int32
+	require.NoError(t, s.Register(Job{Name: "manual", Interval: time.Hour, Run: func(ctx context.Context) error {
+		runs.Add(1)
+		<-release
+		return nil
+	}}))
+
+	assert.ErrorIs(t, s.Trigger("manual"), apperrors.ErrConflict, "jobs can't be triggered before the scheduler starts")
+
+	s.Start()
+	defer s.Stop()
+
+	assert.ErrorIs(t, s.Trigger("missing"), ErrUnknownJob)
+	require.NoError(t, s.Trigger("manual"))
+	require.Eventually(t, func() bool { return statusOf(t, s, "manual").Running }, time.Second, 5*time.Millisecond)
+	assert.ErrorIs(t, s.Trigger("manual"), ErrJobRunning)
+
+	close(release)
+	require.Eventually(t, func() bool { return statusOf(t, s, "manual").Runs == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), runs.Load())
+}
+
+func TestExclusiveJobSkippedWhileLocked(t *testing.T) {
+	locker := NewLocalLocker()
+	unlock, ok, err := locker.TryLock(context.Background(), lockKeyPrefix+"purge", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	s := NewScheduler(locker)
+	var runs atomic.Int32
+	require.NoError(t, s.Register(Job{Name: "purge", Interval: time.Hour, Exclusive: true, Run: func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}}))
+	s.Start()
+	defer s.Stop()
+
+	// Another instance holds the lock
+	require.NoError(t, s.Trigger("purge"))
+	require.Eventually(t, func() bool { return statusOf(t, s, "purge").Skipped == 1 }, time.Second, 5*time.Millisecond)
+	assert.Zero(t, runs.Load())
+
+	unlock()
+	require.NoError(t, s.Trigger("purge"))
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestLocalLocker(t *testing.T) {
+	locker := NewLocalLocker()
+	ctx := context.Background()
+
+	unlock, ok, err := locker.TryLock(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, ok, _ = locker.TryLock(ctx, "a", time.Minute)
+	assert.False(t, ok)
+	_, ok, _ = locker.TryLock(ctx, "b", time.Minute)
+	assert.True(t, ok)
+
+	unlock()
+	_, ok, _ = locker.TryLock(ctx, "a", time.Minute)
+	assert.True(t, ok)
+
+	// Expired locks can be taken again, and releasing the old one leaves the new one alone
+	stale, ok, _ := locker.TryLock(ctx, "c", time.Millisecond)
+	require.True(t, ok)
+	time.Sleep(5 * time.Millisecond)
+	_, ok, _ = locker.TryLock(ctx, "c", time.Minute)
+	require.True(t, ok)
+	stale()
+	_, ok, _ = locker.TryLock(ctx, "c", time.Minute)
+	assert.False(t, ok)
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Locker hands out named locks so an exclusive job runs on one instance at a time. TryLock returns
+// ok=false without waiting when the lock is held; the lock expires after ttl unless released first.
+type Locker interface {
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// releaseScript deletes a lock only if it is still held by the caller's token, so a lock that
+// expired and was taken by another instance isn't released
+var releaseScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0
+`)
+
+// RedisLocker holds locks in Redis, shared by every instance using the same server
+type RedisLocker struct {
+	client *redis.Client
+	owner  string
+}
+
+// NewRedisLocker creates a locker whose locks name owner (e.g. the instance ID) as their holder
+func NewRedisLocker(client *redis.Client, owner string) *RedisLocker {
+	return &RedisLocker{client: client, owner: owner}
+}
+
+// TryLock implements Locker
+func (l *RedisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	token, err := lockToken(l.owner)
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := l.client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire lock %s: %w", key, err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		releaseScript.Run(ctx, l.client, []string{key}, token)
+	}, true, nil
+}
+
+// lockToken identifies one acquisition of a lock
+func lockToken(owner string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	return owner + ":" + hex.EncodeToString(b), nil
+}
+
+// LocalLocker holds locks in memory, for a single instance running without Redis
+type LocalLocker struct {
+	mu    sync.Mutex
+	locks map[string]time.Time // expiry of the held locks
+}
+
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{locks: make(map[string]time.Time)}
+}
+
+// TryLock implements Locker
+func (l *LocalLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if expiry, held := l.locks[key]; held && now.Before(expiry) {
+		return nil, false, nil
+	}
+	expiry := now.Add(ttl)
+	l.locks[key] = expiry
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.locks[key].Equal(expiry) {
+			delete(l.locks, key)
+		}
+	}, true, nil
+}
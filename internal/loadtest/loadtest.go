@@ -0,0 +1,389 @@
+// Package loadtest drives a running hub with synthetic WebSocket clients and reports the latency
+// of their prompts, for validating MAX_SESSIONS and hub scaling changes
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// Options configure a load test
+type Options struct {
+	URL         string        // base URL of the hub, e.g. http://localhost:8080
+	Connections int           // WebSocket connections, each with its own session and chat
+	Rate        float64       // prompts per second across all connections
+	Duration    time.Duration // how long prompts are sent
+	Provider    string        // provider the prompts are sent to, e.g. the fake provider
+	Prompt      string
+	Token       string        // bearer token (ADMIN_TOKEN or an access token); sessions are used without one
+	Timeout     time.Duration // prompts not answered within it fail
+}
+
+// Report summarizes a load test. A prompt completes when its response is saved; the first chunk
+// latency is the time until the first ai_response frame.
+type Report struct {
+	Connections     int
+	ConnectFailures int
+	Sent            int
+	Completed       int
+	Failed          int
+	Skipped         int // prompts not sent because every connection was busy
+	Elapsed         time.Duration
+	FirstChunk      Percentiles
+	Total           Percentiles
+	Errors          map[string]int
+}
+
+// Percentiles of a set of latencies
+type Percentiles struct {
+	P50, P90, P95, P99, Max time.Duration
+}
+
+// errPromptTimeout fails prompts not answered within Options.Timeout
+var errPromptTimeout = errors.New("no response within the timeout")
+
+// Run opens the connections, sends prompts at the configured rate until the duration passed or
+// ctx is done, and waits for the prompts in flight
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	base, err := url.Parse(strings.TrimSuffix(opts.URL, "/"))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid hub URL %q", opts.URL)
+	}
+	if opts.Connections < 1 || opts.Rate <= 0 || opts.Duration <= 0 || opts.Provider == "" {
+		return nil, fmt.Errorf("connections, rate, duration and provider are required")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Minute
+	}
+
+	report := &Report{Errors: map[string]int{}}
+	var mu sync.Mutex
+	recordError := func(err error) {
+		mu.Lock()
+		report.Errors[err.Error()]++
+		mu.Unlock()
+	}
+
+	// Every connection is a separate visitor with its own session
+	clients := make([]*client, opts.Connections)
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := connect(ctx, base, opts, i+1)
+			if err != nil {
+				recordError(err)
+				return
+			}
+			clients[i] = c
+		}(i)
+	}
+	wg.Wait()
+
+	idle := make(chan *client, opts.Connections)
+	for _, c := range clients {
+		if c == nil {
+			report.ConnectFailures++
+			continue
+		}
+		report.Connections++
+		idle <- c
+		defer c.close()
+	}
+	if report.Connections == 0 {
+		return report, fmt.Errorf("no connection could be opened: %s", report.firstError())
+	}
+
+	var firstChunks, totals []time.Duration
+	ticker := time.NewTicker(max(time.Duration(float64(time.Second)/opts.Rate), time.Microsecond))
+	defer ticker.Stop()
+	deadline := time.NewTimer(opts.Duration)
+	defer deadline.Stop()
+	started := time.Now()
+
+	var inFlight sync.WaitGroup
+	sent := 0
+send:
+	for {
+		select {
+		case <-ctx.Done():
+			break send
+		case <-deadline.C:
+			break send
+		case <-ticker.C:
+		}
+
+		select {
+		case c := <-idle:
+			sent++
+			inFlight.Add(1)
+			go func(n int) {
+				defer inFlight.Done()
+				firstChunk, total, err := c.prompt(opts, fmt.Sprintf("%s #%d", opts.Prompt, n))
+				mu.Lock()
+				if err != nil {
+					report.Failed++
+					report.Errors[err.Error()]++
+				} else {
+					report.Completed++
+					firstChunks = append(firstChunks, firstChunk)
+					totals = append(totals, total)
+				}
+				mu.Unlock()
+				// A connection that failed may be broken, so it isn't used again
+				if err == nil {
+					idle <- c
+				}
+			}(sent)
+		default:
+			report.Skipped++
+		}
+	}
+	report.Elapsed = time.Since(started)
+	inFlight.Wait()
+
+	report.Sent = sent
+	report.FirstChunk = percentiles(firstChunks)
+	report.Total = percentiles(totals)
+	return report, nil
+}
+
+// firstError returns the most frequent error
+func (r *Report) firstError() string {
+	first, count := "", 0
+	for msg, n := range r.Errors {
+		if n > count || (n == count && msg < first) {
+			first, count = msg, n
+		}
+	}
+	return first
+}
+
+// Write prints the report
+func (r *Report) Write(w io.Writer) {
+	fmt.Fprintf(w, "Connections: %d opened, %d failed\n", r.Connections, r.ConnectFailures)
+	fmt.Fprintf(w, "Prompts:     %d sent, %d completed, %d failed, %d skipped (every connection busy)\n", r.Sent, r.Completed, r.Failed, r.Skipped)
+	if r.Elapsed > 0 {
+		fmt.Fprintf(w, "Throughput:  %.2f completed/s over %v\n", float64(r.Completed)/r.Elapsed.Seconds(), r.Elapsed.Round(time.Millisecond))
+	}
+	if r.Completed > 0 {
+		fmt.Fprintf(w, "First chunk: %s\n", r.FirstChunk)
+		fmt.Fprintf(w, "Complete:    %s\n", r.Total)
+	}
+	if len(r.Errors) > 0 {
+		fmt.Fprintf(w, "Errors:\n")
+		messages := make([]string, 0, len(r.Errors))
+		for msg := range r.Errors {
+			messages = append(messages, msg)
+		}
+		slices.Sort(messages)
+		for _, msg := range messages {
+			fmt.Fprintf(w, "  %6d  %s\n", r.Errors[msg], msg)
+		}
+	}
+}
+
+func (p Percentiles) String() string {
+	return fmt.Sprintf("p50 %v  p90 %v  p95 %v  p99 %v  max %v", round(p.P50), round(p.P90), round(p.P95), round(p.P99), round(p.Max))
+}
+
+// round keeps latencies readable: microseconds below 1ms, milliseconds above
+func round(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
+
+// percentiles computes nearest-rank percentiles
+func percentiles(latencies []time.Duration) Percentiles {
+	if len(latencies) == 0 {
+		return Percentiles{}
+	}
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	rank := func(p float64) time.Duration {
+		i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+		return sorted[max(i, 0)]
+	}
+	return Percentiles{P50: rank(50), P90: rank(90), P95: rank(95), P99: rank(99), Max: sorted[len(sorted)-1]}
+}
+
+// client is a visitor with a session, a chat and a WebSocket connection
+type client struct {
+	conn    *websocket.Conn
+	chatID  int64
+	events  chan models.WebSocketMessage
+	writeMu sync.Mutex
+}
+
+// connect signs in as a new visitor, creates a chat and opens a WebSocket
+func connect(ctx context.Context, base *url.URL, opts Options, n int) (*client, error) {
+	jar, _ := cookiejar.New(nil)
+	httpClient := &http.Client{Jar: jar, Timeout: 30 * time.Second}
+	call := func(method, path string, body, out any) error {
+		var payload io.Reader
+		if body != nil {
+			data, err := json.Marshal(body)
+			if err != nil {
+				return err
+			}
+			payload = bytes.NewReader(data)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, base.String()+path, payload)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if opts.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+opts.Token)
+		}
+		// Mutating requests repeat the CSRF cookie, like the browser client
+		for _, cookie := range jar.Cookies(base) {
+			if cookie.Name == "csrf_token" {
+				req.Header.Set("X-CSRF-Token", cookie.Value)
+			}
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", method, path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		if out == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(&struct {
+			Data any `json:"data"`
+		}{Data: out})
+	}
+
+	// The first request issues the session and CSRF cookies
+	if err := call(http.MethodGet, "/api/chats?limit=1", nil, nil); err != nil {
+		return nil, err
+	}
+	var chat models.Chat
+	if err := call(http.MethodPost, "/api/chats", map[string]string{"title": fmt.Sprintf("Load test %d", n), "provider": opts.Provider}, &chat); err != nil {
+		return nil, err
+	}
+	var ticket models.WSTicket
+	if err := call(http.MethodPost, "/api/ws/ticket", nil, &ticket); err != nil {
+		return nil, err
+	}
+
+	wsURL := *base
+	wsURL.Scheme = strings.Replace(base.Scheme, "http", "ws", 1)
+	wsURL.Path += "/ws"
+	wsURL.RawQuery = url.Values{"ticket": {ticket.Ticket}}.Encode()
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, wsURL.String(), http.Header{"Origin": {base.Scheme + "://" + base.Host}})
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("WebSocket: %s", resp.Status)
+		}
+		return nil, fmt.Errorf("WebSocket: %w", err)
+	}
+
+	c := &client{conn: conn, chatID: chat.ID, events: make(chan models.WebSocketMessage, 256)}
+	go c.readPump()
+	return c, nil
+}
+
+// readPump forwards the client's messages, acknowledging frames like the browser client
+func (c *client) readPump() {
+	defer close(c.events)
+	for {
+		var msg models.WebSocketMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.ID > 0 {
+			c.write(models.WebSocketMessage{Type: "ack", Version: models.WSProtocolVersion, Ack: msg.ID})
+		}
+		c.events <- msg
+	}
+}
+
+func (c *client) write(msg models.WebSocketMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(msg)
+}
+
+// prompt sends a prompt and waits until its response is saved
+func (c *client) prompt(opts Options, content string) (firstChunk, total time.Duration, err error) {
+	// Messages that arrived while the connection was idle, e.g. presence, aren't about this prompt
+	for drained := false; !drained; {
+		select {
+		case _, ok := <-c.events:
+			if !ok {
+				return 0, 0, errors.New("connection closed")
+			}
+		default:
+			drained = true
+		}
+	}
+
+	started := time.Now()
+	err = c.write(models.WebSocketMessage{
+		Type:    "ai_prompt",
+		Version: models.WSProtocolVersion,
+		Data:    models.WSMsgData{ChatID: c.chatID, Provider: opts.Provider, Content: content, Timestamp: started},
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("send prompt: %w", err)
+	}
+
+	timeout := time.NewTimer(opts.Timeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-timeout.C:
+			return 0, 0, errPromptTimeout
+		case msg, ok := <-c.events:
+			if !ok {
+				return 0, 0, errors.New("connection closed")
+			}
+			switch msg.Type {
+			case "ai_response":
+				if firstChunk == 0 {
+					firstChunk = time.Since(started)
+				}
+			case "ai_response_saved":
+				return firstChunk, time.Since(started), nil
+			case "ai_response_timeout":
+				return 0, 0, fmt.Errorf("%s timeout of the provider", msg.Data.Action)
+			case "error":
+				if msg.Data.Action != "" {
+					return 0, 0, fmt.Errorf("error: %s", msg.Data.Action)
+				}
+				return 0, 0, fmt.Errorf("error: %s", msg.Data.Content)
+			}
+		}
+	}
+}
+
+func (c *client) close() {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.conn.Close()
+}
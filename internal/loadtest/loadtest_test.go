@@ -0,0 +1,125 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/handlers"
+	"ai-gateway-hub/internal/middleware"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestHub serves the routes the load test uses, with sessions and CSRF protection like main.go
+func newTestHub(t *testing.T) *httptest.Server {
+	t.Helper()
+	require.NoError(t, utils.InitPathManager())
+	cfg := &config.Config{MaxSessions: 100, SessionTimeout: time.Hour, WebSocketTimeout: time.Hour, EnableCSRF: true}
+
+	db, err := database.InitTestDBWithFile(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	sessionService := services.NewSessionService(services.NewMemorySessionStore())
+	chatService := services.NewChatService(db)
+	providerRegistry := services.NewProviderRegistry(services.NewMemoryStatusCache())
+	fake := providers.NewFakeProvider("fake")
+	fake.DefaultReply = providers.FakeLoadReply(3, 5*time.Millisecond)
+	require.NoError(t, providerRegistry.Register(fake))
+
+	hub := handlers.NewHub(sessionService, chatService, providerRegistry, nil, nil, nil)
+	ticketService := services.NewWSTicketService(services.NewMemoryTicketStore(), time.Minute)
+	hub.SetTickets(ticketService)
+	go hub.Run()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.SessionMiddleware(sessionService, cfg.SessionTimeout))
+	router.Use(middleware.CSRFMiddleware(cfg))
+	apiHandlers := handlers.NewAPIHandlers(nil)
+	router.GET("/api/chats", apiHandlers.GetChatsHandler(chatService))
+	router.POST("/api/chats", apiHandlers.CreateChatHandler(chatService, nil))
+	router.POST("/api/ws/ticket", apiHandlers.IssueWSTicketHandler(ticketService, func(c *gin.Context) (string, string) {
+		return services.GuestUserID(c.GetString(middleware.SessionContextKey)), models.RoleUser
+	}))
+	router.GET("/ws", handlers.WebSocketHandler(hub, cfg))
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRun(t *testing.T) {
+	server := newTestHub(t)
+
+	report, err := Run(context.Background(), Options{
+		URL:         server.URL,
+		Connections: 3,
+		Rate:        50,
+		Duration:    500 * time.Millisecond,
+		Provider:    "fake",
+		Prompt:      "Load test",
+		Timeout:     5 * time.Second,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Connections)
+	assert.Zero(t, report.ConnectFailures)
+	assert.Empty(t, report.Errors)
+	assert.Zero(t, report.Failed)
+	assert.Positive(t, report.Completed)
+	assert.Equal(t, report.Sent, report.Completed)
+	assert.Positive(t, report.FirstChunk.P50)
+	assert.GreaterOrEqual(t, report.Total.P50, report.FirstChunk.P50)
+
+	var out bytes.Buffer
+	report.Write(&out)
+	assert.Contains(t, out.String(), "Connections: 3 opened, 0 failed")
+	assert.Contains(t, out.String(), "First chunk: p50")
+}
+
+func TestRunErrors(t *testing.T) {
+	_, err := Run(context.Background(), Options{URL: "localhost:8080", Connections: 1, Rate: 1, Duration: time.Second, Provider: "fake"})
+	assert.ErrorContains(t, err, "invalid hub URL")
+
+	_, err = Run(context.Background(), Options{URL: "http://localhost:8080", Rate: 1, Duration: time.Second, Provider: "fake"})
+	assert.ErrorContains(t, err, "required")
+
+	// Nothing listens on the address of a closed server
+	server := httptest.NewServer(nil)
+	server.Close()
+	report, err := Run(context.Background(), Options{URL: server.URL, Connections: 2, Rate: 1, Duration: time.Second, Provider: "fake"})
+	assert.ErrorContains(t, err, "no connection could be opened")
+	assert.Equal(t, 2, report.ConnectFailures)
+}
+
+func TestPercentiles(t *testing.T) {
+	assert.Equal(t, Percentiles{}, percentiles(nil))
+
+	latencies := make([]time.Duration, 0, 100)
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	p := percentiles(latencies)
+	assert.Equal(t, 50*time.Millisecond, p.P50)
+	assert.Equal(t, 90*time.Millisecond, p.P90)
+	assert.Equal(t, 95*time.Millisecond, p.P95)
+	assert.Equal(t, 99*time.Millisecond, p.P99)
+	assert.Equal(t, 100*time.Millisecond, p.Max)
+	assert.Equal(t, 100*time.Millisecond, latencies[0], "the input isn't reordered")
+
+	p = percentiles([]time.Duration{3 * time.Second})
+	assert.Equal(t, 3*time.Second, p.P50)
+	assert.Equal(t, 3*time.Second, p.P99)
+}
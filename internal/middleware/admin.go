@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminLoginPath is the page where a session is granted the admin role
+const AdminLoginPath = "/admin/login"
+
+// AdminMiddleware restricts a route group to the admin role. A request has the role when it
+// carries "Authorization: Bearer <ADMIN_TOKEN>", an access token with the admin role or its session
+// signed in at /admin/login. Without an ADMIN_TOKEN only loopback clients are admins, and only in
+// the development environment. Pages redirect to the login form, API calls get 403.
+func AdminMiddleware(cfg *config.Config, sessionService *services.SessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsAdmin(c, cfg, sessionService) {
+			c.Next()
+			return
+		}
+
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") || c.Request.Method != http.MethodGet {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":      "Admin role required",
+				"code":       "FORBIDDEN",
+				"request_id": c.GetString(RequestIDContextKey),
+			})
+			return
+		}
+		c.Redirect(http.StatusSeeOther, AdminLoginPath)
+		c.Abort()
+	}
+}
+
+// IsAdmin reports whether the request has the admin role
+func IsAdmin(c *gin.Context, cfg *config.Config, sessionService *services.SessionService) bool {
+	if claims, ok := AuthClaims(c); ok {
+		return claims.HasRole(models.RoleAdmin)
+	}
+
+	if cfg.AdminToken == "" {
+		if !cfg.AdminLoopback {
+			return false
+		}
+		// ClientIP only honors X-Forwarded-For from TRUSTED_PROXIES, so a loopback client behind a
+		// local reverse proxy is not mistaken for the proxy itself
+		ip := net.ParseIP(c.ClientIP())
+		return ip != nil && ip.IsLoopback()
+	}
+
+	if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return cfg.ValidAdminToken(bearer)
+	}
+
+	sessionID := c.GetString(SessionContextKey)
+	if sessionID == "" || sessionService == nil {
+		return false
+	}
+	session, err := sessionService.GetSession(c.Request.Context(), sessionID)
+	return err == nil && session.Role == models.RoleAdmin
+}
@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompressionOptions configures the compression middleware
+type CompressionOptions struct {
+	Level         int      // 1 (fastest) to 9 (smallest)
+	MinSize       int      // smaller responses are sent uncompressed
+	ContentTypes  []string // content type patterns such as text/*
+	ExcludedPaths []string // path prefixes never compressed, such as the WebSocket endpoint
+}
+
+// compressor is a gzip or deflate writer that can be reused
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Content codings in order of preference, with their writer factories
+var compressionEncodings = []struct {
+	name      string
+	newWriter func(w io.Writer, level int) (compressor, error)
+}{
+	{"gzip", func(w io.Writer, level int) (compressor, error) { return gzip.NewWriterLevel(w, level) }},
+	{"deflate", func(w io.Writer, level int) (compressor, error) { return flate.NewWriter(w, level) }},
+}
+
+// CompressionMiddleware compresses responses of the configured content types with gzip or
+// deflate, as negotiated with Accept-Encoding. Responses are buffered up to MinSize bytes to
+// decide; WebSocket upgrades, excluded paths, event streams and already encoded bodies pass through.
+func CompressionMiddleware(opts CompressionOptions) gin.HandlerFunc {
+	pools := make(map[string]*sync.Pool, len(compressionEncodings))
+	for _, encoding := range compressionEncodings {
+		newWriter := encoding.newWriter
+		pools[encoding.name] = &sync.Pool{New: func() any {
+			w, err := newWriter(io.Discard, opts.Level)
+			if err != nil {
+				// Invalid levels are rejected by configuration validation
+				w, _ = newWriter(io.Discard, gzip.DefaultCompression)
+			}
+			return w
+		}}
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" || excludedPath(c.Request.URL.Path, opts.ExcludedPaths) {
+			c.Next()
+			return
+		}
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: c.Writer, opts: &opts, encoding: encoding, pool: pools[encoding]}
+		c.Writer = writer
+		c.Next()
+		writer.finish()
+		c.Writer = writer.ResponseWriter
+	}
+}
+
+// excludedPath reports whether a path starts with one of the excluded prefixes
+func excludedPath(requestPath string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(requestPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding picks the preferred supported coding the client accepts, or "" for none
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q > 0
+	}
+
+	for _, encoding := range compressionEncodings {
+		if enabled, ok := accepted[encoding.name]; ok {
+			if enabled {
+				return encoding.name
+			}
+			continue
+		}
+		if accepted["*"] {
+			return encoding.name
+		}
+	}
+	return ""
+}
+
+// compressWriter buffers the start of a response until it knows whether to compress it
+type compressWriter struct {
+	gin.ResponseWriter
+	opts     *CompressionOptions
+	encoding string
+	pool     *sync.Pool
+
+	buf     []byte
+	decided bool
+	encoder compressor // set once the response is being compressed
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.opts.MinSize {
+			return len(b), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what was buffered so far, so streamed responses keep streaming
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.encoder != nil {
+		w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide compresses the response if it is large enough and of a configured type, then writes the buffer
+func (w *compressWriter) decide() error {
+	w.decided = true
+	if w.compressible() {
+		header := w.Header()
+		header.Set("Content-Encoding", w.encoding)
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+
+		w.encoder = w.pool.Get().(compressor)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// compressible reports whether the buffered response should be compressed
+func (w *compressWriter) compressible() bool {
+	// Headers already sent can't announce the encoding
+	if len(w.buf) < w.opts.MinSize || len(w.buf) == 0 || w.ResponseWriter.Written() {
+		return false
+	}
+	switch status := w.Status(); {
+	case status < http.StatusOK, status == http.StatusNoContent, status == http.StatusPartialContent, status == http.StatusNotModified:
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(w.buf)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "text/event-stream" {
+		return false
+	}
+	for _, pattern := range w.opts.ContentTypes {
+		if ok, _ := path.Match(pattern, mediaType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// finish writes a response that stayed below MinSize and completes a compressed one
+func (w *compressWriter) finish() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+		w.encoder.Reset(io.Discard)
+		w.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
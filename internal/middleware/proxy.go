@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProxyHeadersMiddleware applies X-Forwarded-Host and X-Forwarded-Proto from trusted proxies, so
+// same-origin checks (CORS, WebSocket) see the host the browser used and cookies are marked Secure
+// behind a TLS-terminating proxy. The client IP comes from gin's ClientIP, which honors
+// X-Forwarded-For only from the proxies passed to SetTrustedProxies.
+func ProxyHeadersMiddleware(trustedProxies []string) gin.HandlerFunc {
+	nets := parseTrustedProxies(trustedProxies)
+
+	return func(c *gin.Context) {
+		if len(nets) == 0 || !ipInNets(c.RemoteIP(), nets) {
+			c.Next()
+			return
+		}
+
+		if host := firstForwardedValue(c.GetHeader("X-Forwarded-Host")); host != "" {
+			c.Request.Host = host
+		}
+		if strings.EqualFold(firstForwardedValue(c.GetHeader("X-Forwarded-Proto")), "https") {
+			c.Request = c.Request.WithContext(utils.WithForwardedHTTPS(c.Request.Context()))
+		}
+
+		c.Next()
+	}
+}
+
+// parseTrustedProxies converts IP addresses and CIDRs to networks, skipping invalid entries
+// (rejected by config validation)
+func parseTrustedProxies(trustedProxies []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range trustedProxies {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				continue
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, ipNet)
+		}
+	}
+	return nets
+}
+
+// ipInNets reports whether the address belongs to one of the networks
+func ipInNets(addr string, nets []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstForwardedValue returns the value the outermost proxy set in a comma-separated forwarding header
+func firstForwardedValue(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
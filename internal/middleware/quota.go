@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PromptQuotaMiddleware counts a request that sends a prompt against the prompt quotas of its
+// session, or of its access token's user, and answers 429 with a Retry-After header once they are
+// used up. Requests with neither, or whose usage can't be counted, are let through.
+func PromptQuotaMiddleware(quotaService *services.QuotaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := QuotaKey(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		status, err := quotaService.Consume(key, 1)
+		if errors.Is(err, services.ErrQuotaExceeded) {
+			resetsAt := services.QuotaResetsAt(status)
+			retryAfter := int(time.Until(resetsAt).Round(time.Second).Seconds())
+			c.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":      "Prompt quota exceeded",
+				"code":       "QUOTA_EXCEEDED",
+				"quota":      status,
+				"request_id": c.GetString(RequestIDContextKey),
+			})
+			return
+		}
+		if err != nil {
+			utils.Warn("Failed to count prompt against quota: %v", err)
+		}
+
+		c.Next()
+	}
+}
+
+// QuotaKey returns what a request's prompts are counted against: its session, or the user of its
+// access token since those requests have no session
+func QuotaKey(c *gin.Context) string {
+	var userID string
+	if claims, ok := AuthClaims(c); ok {
+		userID = claims.Subject
+	}
+	return services.QuotaKey(c.GetString(SessionContextKey), userID)
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleContextKey is the gin context key caching the role of the request
+const RoleContextKey = "role"
+
+// roleExemptPaths are API paths open to every role: health and version probes, signing in and out,
+// WebSocket tickets (messages are checked per type), client logs and per-visitor settings
+var roleExemptPaths = []string{
+	"/api/health",
+	"/api/version",
+	"/api/auth/",
+	"/api/ws/ticket",
+	"/api/logs/client",
+	"/api/settings",
+}
+
+// RequestRole returns the role of the request: admin for admins (see IsAdmin), otherwise the most
+// privileged role of the access token or the role granted to the session, falling back to
+// DEFAULT_ROLE
+func RequestRole(c *gin.Context, cfg *config.Config, sessionService *services.SessionService) string {
+	if role := c.GetString(RoleContextKey); role != "" {
+		return role
+	}
+
+	role := requestRole(c, cfg, sessionService)
+	c.Set(RoleContextKey, role)
+	return role
+}
+
+func requestRole(c *gin.Context, cfg *config.Config, sessionService *services.SessionService) string {
+	if IsAdmin(c, cfg, sessionService) {
+		return models.RoleAdmin
+	}
+
+	if claims, ok := AuthClaims(c); ok {
+		if role := models.HighestRole(claims.Roles); role != "" {
+			return role
+		}
+		return cfg.DefaultRole
+	}
+
+	if sessionID := c.GetString(SessionContextKey); sessionID != "" && sessionService != nil {
+		session, err := sessionService.GetSession(c.Request.Context(), sessionID)
+		if err == nil && models.IsValidRole(session.Role) {
+			return session.Role
+		}
+	}
+	return cfg.DefaultRole
+}
+
+// RequireRole restricts a route to requests with at least role
+func RequireRole(cfg *config.Config, sessionService *services.SessionService, role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !models.RoleAllows(RequestRole(c, cfg, sessionService), role) {
+			abortForbidden(c, role)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RBACMiddleware enforces roles on the API: reads need the viewer role, writes the user role.
+// Admin routes are guarded separately by AdminMiddleware.
+func RBACMiddleware(cfg *config.Config, sessionService *services.SessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		for _, exempt := range roleExemptPaths {
+			if path == exempt || (strings.HasSuffix(exempt, "/") && strings.HasPrefix(path, exempt)) {
+				c.Next()
+				return
+			}
+		}
+
+		required := models.RoleUser
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			required = models.RoleViewer
+		}
+
+		if !models.RoleAllows(RequestRole(c, cfg, sessionService), required) {
+			abortForbidden(c, required)
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortForbidden rejects a request lacking the required role
+func abortForbidden(c *gin.Context, required string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":      "The " + required + " role is required",
+		"code":       "FORBIDDEN",
+		"request_id": c.GetString(RequestIDContextKey),
+	})
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDContextKey is the gin context key holding the current request ID
+	RequestIDContextKey = "request_id"
+
+	// Longest client-supplied request ID that is honored
+	maxRequestIDLength = 64
+)
+
+// RequestIDMiddleware assigns every request an ID, honoring a valid X-Request-ID from the client.
+// The ID is stored in the gin and request contexts and echoed in the response header.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(utils.RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = utils.NewRequestID()
+		}
+
+		c.Set(RequestIDContextKey, requestID)
+		c.Request = c.Request.WithContext(utils.WithRequestID(c.Request.Context(), requestID))
+		c.Header(utils.RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// validRequestID reports whether a client-supplied ID is safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestLogger writes gin's access log line with the request ID
+func RequestLogger() gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Output: utils.GetLogger().Out,
+		Formatter: func(param gin.LogFormatterParams) string {
+			requestID, _ := param.Keys[RequestIDContextKey].(string)
+			if param.Latency > time.Minute {
+				param.Latency = param.Latency.Truncate(time.Second)
+			}
+			return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %s | %-7s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				param.StatusCode,
+				param.Latency,
+				param.ClientIP,
+				requestID,
+				param.Method,
+				param.Path,
+				param.ErrorMessage,
+			)
+		},
+	})
+}
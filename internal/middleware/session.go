@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// SessionCookieName is the cookie that carries the session ID
+	SessionCookieName = "session_id"
+
+	// SessionContextKey is the gin context key holding the current session ID
+	SessionContextKey = "session_id"
+)
+
+// SessionMiddleware creates a session for new visitors and refreshes the TTL of existing ones
+func SessionMiddleware(sessionService *services.SessionService, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Static assets and health checks don't need a session
+		path := c.Request.URL.Path
+		if strings.HasPrefix(path, "/static/") || path == "/api/health" || path == "/healthz" || path == "/readyz" {
+			c.Next()
+			return
+		}
+
+		// Requests authenticated with an access token are stateless
+		if _, ok := AuthClaims(c); ok {
+			c.Next()
+			return
+		}
+
+		sessionID, err := c.Cookie(SessionCookieName)
+		if err == nil && sessionID != "" {
+			// Known session: slide its expiration forward, on the cookie too
+			err := sessionService.ExtendSession(c.Request.Context(), sessionID, ttl)
+			if err == nil {
+				setSessionCookie(c, sessionID, ttl)
+				c.Set(SessionContextKey, sessionID)
+				c.Next()
+				return
+			}
+			if !errors.Is(err, services.ErrSessionNotFound) {
+				utils.Debug("Failed to refresh session: %v", err)
+			}
+		}
+
+		// Unknown or missing session: issue a new one
+		sessionID, err = generateSessionID()
+		if err != nil {
+			utils.Error("Failed to generate session ID: %v", err)
+			c.Next()
+			return
+		}
+
+		if err := sessionService.CreateClientSession(c.Request.Context(), sessionID, c.ClientIP(), c.Request.UserAgent(), ttl); err != nil {
+			// Redis may be unavailable; the request can still be served without a session
+			utils.Debug("Failed to create session: %v", err)
+			c.Next()
+			return
+		}
+
+		setSessionCookie(c, sessionID, ttl)
+		c.Set(SessionContextKey, sessionID)
+
+		c.Next()
+	}
+}
+
+// setSessionCookie issues the session cookie for ttl; a ttl of 0 makes it last for the browser session
+func setSessionCookie(c *gin.Context, sessionID string, ttl time.Duration) {
+	secure := utils.IsHTTPS(c.Request)
+	c.SetCookie(SessionCookieName, sessionID, int(ttl.Seconds()), "/", "", secure, true)
+}
+
+// generateSessionID returns a random 128-bit hex session ID
+func generateSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
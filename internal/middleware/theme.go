@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"ai-gateway-hub/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ThemeContextKey is the gin context key holding the theme pages are rendered with
+const ThemeContextKey = "theme"
+
+// ThemeMiddleware reads the theme cookie so pages are rendered in the right theme on first paint;
+// unknown values fall back to the default theme
+func ThemeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		theme := config.DefaultTheme
+		if cookieTheme, err := c.Cookie("theme"); err == nil && config.IsValidTheme(cookieTheme) {
+			theme = cookieTheme
+		}
+		c.Set(ThemeContextKey, theme)
+
+		c.Next()
+	}
+}
@@ -2,16 +2,104 @@ package models
 
 import (
 	"database/sql/driver"
+	"strings"
 	"time"
 )
 
 // Chat represents a conversation session
 type Chat struct {
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	Provider     string    `json:"provider"`
+	SystemPrompt string     `json:"system_prompt"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	FolderID     *int64     `json:"folder_id,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+
+	// Chat and message the chat was forked from, if it is a branch
+	ParentChatID        *int64 `json:"parent_chat_id,omitempty"`
+	ForkedFromMessageID *int64 `json:"forked_from_message_id,omitempty"`
+}
+
+// ChatDetails is a chat with the number of its messages and a preview of the latest one
+type ChatDetails struct {
+	*Chat
+	MessageCount int64           `json:"message_count"`
+	LastMessage  *MessagePreview `json:"last_message,omitempty"`
+	BranchTree   *ChatBranch     `json:"branch_tree,omitempty"` // forks of the chat's conversation, from their root
+}
+
+// ChatBranch is a chat in a tree of conversation branches
+type ChatBranch struct {
+	ID                  int64         `json:"id"`
+	Title               string        `json:"title"`
+	ParentChatID        *int64        `json:"parent_chat_id,omitempty"`
+	ForkedFromMessageID *int64        `json:"forked_from_message_id,omitempty"`
+	CreatedAt           time.Time     `json:"created_at"`
+	Children            []*ChatBranch `json:"children"`
+}
+
+// MessagePreview is the start of a message, e.g. the latest message of a chat
+type MessagePreview struct {
 	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Provider  string    `json:"provider"`
+	Role      string    `json:"role"`
+	Provider  string    `json:"provider,omitempty"`
+	Preview   string    `json:"preview"` // at most 200 characters, ending with "…" when cut
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Pagination describes one page of a list
+type Pagination struct {
+	Total   int64 `json:"total"`
+	Limit   int   `json:"limit"`
+	Offset  int   `json:"offset"`
+	HasMore bool  `json:"has_more"`
+}
+
+// ChatPage is a page of the chat list
+type ChatPage struct {
+	Items []*Chat `json:"items"`
+	Pagination
+}
+
+// MessagePage is a page of a chat's messages
+type MessagePage struct {
+	Items []*Message `json:"items"`
+	Pagination
+}
+
+// Tag labels chats; a chat can carry any number of tags
+type Tag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	ChatCount int       `json:"chat_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Folder groups chats; a chat belongs to at most one folder
+type Folder struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	ChatCount int       `json:"chat_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BulkChatResult is the outcome of a bulk operation for one chat
+type BulkChatResult struct {
+	ChatID int64  `json:"chat_id"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkChatResponse reports a bulk operation on several chats
+type BulkChatResponse struct {
+	Action    string            `json:"action"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []*BulkChatResult `json:"results"`
 }
 
 // Message represents a single message in a chat
@@ -20,7 +108,112 @@ type Message struct {
 	ChatID    int64     `json:"chat_id"`
 	Role      string    `json:"role"` // user, assistant, system
 	Content   string    `json:"content"`
+	Provider  string    `json:"provider,omitempty"` // provider that generated an assistant message
+	Model     string    `json:"model,omitempty"`    // model requested for an assistant message; empty for the provider default
+	Status    string    `json:"status"`             // complete, streaming or interrupted
+	UserID    string    `json:"user_id,omitempty"`  // participant who sent a user message
 	CreatedAt time.Time `json:"created_at"`
+	// How an assistant message was generated; nil for other messages and older responses
+	Metadata *MessageMetadata `json:"metadata,omitempty"`
+	// Files sent with a user message
+	Attachments []*Attachment `json:"attachments,omitempty"`
+}
+
+// MessageMetadata is how an assistant message was generated, for showing per-response details
+type MessageMetadata struct {
+	Model           string   `json:"model,omitempty"` // empty for the provider default
+	Provider        string   `json:"provider"`
+	LatencyMs       int64    `json:"latency_ms"`            // from sending the prompt to the complete response
+	TokensIn        int64    `json:"tokens_in"`             // prompt tokens, tool results and history included
+	TokensOut       int64    `json:"tokens_out"`            // response tokens
+	TokensEstimated bool     `json:"tokens_estimated"`      // whether tokens were estimated from the content rather than reported by the provider
+	FinishReason    string   `json:"finish_reason"`         // stop, or length when the response was cut off at the size limit
+	FailedOver      []string `json:"failed_over,omitempty"` // providers of the failover chain that failed before this one answered, in order
+}
+
+// Why a response ended
+const (
+	FinishStop   = "stop"
+	FinishLength = "length"
+)
+
+// Message statuses; streaming and interrupted messages hold a partial response
+const (
+	MessageComplete    = "complete"
+	MessageStreaming   = "streaming"
+	MessageInterrupted = "interrupted"
+)
+
+// Attachment is a file uploaded to a chat and sent to providers with a prompt
+type Attachment struct {
+	ID          int64     `json:"id"`
+	ChatID      int64     `json:"chat_id"`
+	MessageID   *int64    `json:"message_id,omitempty"` // nil until sent with a prompt
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	StoragePath string    `json:"-"` // relative to ATTACHMENTS_DIR
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IsImage reports whether the attachment is an image
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+// ChatLogFile is the execution log a provider wrote for a chat (LOG_DIR/<provider>/chat_<id>.log)
+type ChatLogFile struct {
+	Provider   string    `json:"provider"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// ChatLogPage is a page of lines of a chat log. Offsets are in bytes, so polling with next_offset
+// follows the log as the provider writes it.
+type ChatLogPage struct {
+	Provider   string         `json:"provider"`
+	Lines      []string       `json:"lines"`
+	Offset     int64          `json:"offset"`
+	NextOffset int64          `json:"next_offset"`
+	Size       int64          `json:"size"`
+	HasMore    bool           `json:"has_more"`
+	Files      []*ChatLogFile `json:"files"` // every provider log of the chat, newest first
+}
+
+// ScheduledPrompt is a prompt run against a provider on a cron schedule; results are added to ChatID
+type ScheduledPrompt struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	ChatID    int64      `json:"chat_id"`
+	Provider  string     `json:"provider"`
+	Model     string     `json:"model,omitempty"`
+	Prompt    string     `json:"prompt"`
+	Cron      string     `json:"cron"`
+	Timezone  string     `json:"timezone,omitempty"` // IANA name; empty for the server's local time
+	Enabled   bool       `json:"enabled"`
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Scheduled prompt run statuses
+const (
+	ScheduledRunRunning   = "running"
+	ScheduledRunCompleted = "completed"
+	ScheduledRunFailed    = "failed"
+)
+
+// ScheduledPromptRun is one execution of a scheduled prompt
+type ScheduledPromptRun struct {
+	ID                int64      `json:"id"`
+	ScheduledPromptID int64      `json:"scheduled_prompt_id"`
+	ChatID            int64      `json:"chat_id"`
+	Status            string     `json:"status"` // running, completed, failed
+	Error             string     `json:"error,omitempty"`
+	MessageID         *int64     `json:"message_id,omitempty"` // assistant message holding the result
+	StartedAt         time.Time  `json:"started_at"`
+	FinishedAt        *time.Time `json:"finished_at,omitempty"`
 }
 
 // Session represents a WebSocket session
@@ -28,34 +221,567 @@ type Session struct {
 	ID        string     `json:"id"`
 	ChatID    *int64     `json:"chat_id,omitempty"`
 	Data      string     `json:"data,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
-	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ClientIP  string     `json:"client_ip,omitempty"`  // client the session cookie was issued to
+	UserAgent string     `json:"user_agent,omitempty"` // user agent the session cookie was issued to
+	// Hub instance holding the session's latest WebSocket connection
+	InstanceID string     `json:"instance_id,omitempty"`
+	// Role granted to the session, e.g. RoleAdmin after signing in at /admin/login
+	Role       string     `json:"role,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Last request seen on the session; each one slides ExpiresAt forward
+	LastSeen   time.Time  `json:"last_seen"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // nil for sessions that never expire
+}
+
+// CompletionRequest is the body of POST /api/complete, a prompt answered synchronously without a WebSocket
+type CompletionRequest struct {
+	Provider    string `json:"provider"`                  // default: the chat's provider; required without chat_id
+	Model       string `json:"model,omitempty"`           // per-request model override
+	Prompt      string `json:"prompt" binding:"required"` // prompt to send
+	ChatID      *int64 `json:"chat_id,omitempty"`         // chat to add the prompt and response to; without one a temporary chat is used
+	Title        string `json:"title,omitempty"`         // title of the temporary chat (default: the prompt's first line)
+	SystemPrompt string `json:"system_prompt,omitempty"` // system prompt of the temporary chat
+	Stream      bool   `json:"stream"`                    // must be false; streamed responses need the WebSocket
+	TimeoutSecs int    `json:"timeout_seconds,omitempty"` // at most the provider's prompt timeout, the default
+}
+
+// Completion is the response to a CompletionRequest
+type Completion struct {
+	ChatID    int64            `json:"chat_id"`
+	Ephemeral bool             `json:"ephemeral"` // whether the chat was temporary; it is in the trash until purged
+	MessageID int64            `json:"message_id"`
+	Provider  string           `json:"provider"`
+	Model     string           `json:"model,omitempty"`
+	Content   string           `json:"content"`
+	Metadata  *MessageMetadata `json:"metadata,omitempty"`
+}
+
+// Draft is a prompt being written in a chat, saved so it survives a page reload or a switch of device
+type Draft struct {
+	ChatID    int64      `json:"chat_id"`
+	Content   string     `json:"content"`              // empty when the chat has no draft
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // last save; nil without a draft
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // when the draft is dropped unless saved again
+}
+
+// WSTicket is a short-lived, single-use ticket authenticating a WebSocket connection, passed as
+// the ticket query parameter of /ws
+type WSTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ChatShare is a public, read-only link to a chat
+type ChatShare struct {
+	ID           string     `json:"id"`
+	ChatID       int64      `json:"chat_id"`
+	Token        string     `json:"token"` // the share ID signed with SHARE_LINK_SECRET
+	URL          string     `json:"url"`   // path of the shared page, /share/<token>
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	Views        int64      `json:"views"`
+	LastViewedAt *time.Time `json:"last_viewed_at,omitempty"`
+}
+
+// ChatProcessor is a prompt or response processor and whether a chat runs it
+type ChatProcessor struct {
+	Name      string   `json:"name"`
+	Stage     string   `json:"stage"` // pre (prompts) or post (responses)
+	Order     int      `json:"order"`
+	Providers []string `json:"providers,omitempty"` // empty for all providers
+	Enabled   bool     `json:"enabled"`             // whether the chat runs it
+	Default   bool     `json:"default"`             // whether chats run it unless they disable it
+}
+
+// ChatTool is a tool providers can call and whether a chat lets them
+type ChatTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// DocumentCollection is a named set of documents chats can retrieve context from
+type DocumentCollection struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Embedder    string    `json:"embedder,omitempty"` // embedder of its vectors, set by the first document
+	Documents   int64     `json:"documents"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Document is a file indexed into a collection; only its chunks are kept
+type Document struct {
+	ID           int64     `json:"id"`
+	CollectionID int64     `json:"collection_id"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Chunks       int       `json:"chunks"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DocumentChunk is a passage of a document retrieved for a query
+type DocumentChunk struct {
+	ID         int64   `json:"id"`
+	DocumentID int64   `json:"document_id"`
+	Filename   string  `json:"filename"`
+	Seq        int     `json:"seq"` // position in the document, from 0
+	Content    string  `json:"content"`
+	Score      float32 `json:"score"` // similarity to the query, up to 1
+}
+
+// AuthTokens are the tokens issued on sign-in and refresh in the jwt auth mode
+type AuthTokens struct {
+	AccessToken      string   `json:"access_token"`
+	TokenType        string   `json:"token_type"`
+	ExpiresIn        int64    `json:"expires_in"` // seconds
+	RefreshToken     string   `json:"refresh_token"`
+	RefreshExpiresIn int64    `json:"refresh_expires_in"` // seconds
+	UserID           string   `json:"user_id"`
+	Roles            []string `json:"roles"`
+}
+
+// Roles of sessions and users, from least to most privileged: viewers read chats, users also
+// create chats and send prompts, admins also manage the hub
+const (
+	RoleViewer = "viewer"
+	RoleUser   = "user"
+	RoleAdmin  = "admin"
+)
+
+// roleRanks orders the roles by privilege
+var roleRanks = map[string]int{RoleViewer: 1, RoleUser: 2, RoleAdmin: 3}
+
+// IsValidRole reports whether role is one of the roles above
+func IsValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// RoleAllows reports whether role has at least the privileges of required
+func RoleAllows(role, required string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[required]
+}
+
+// HighestRole returns the most privileged of roles, or "" when none is valid
+func HighestRole(roles []string) string {
+	highest := ""
+	for _, role := range roles {
+		if roleRanks[role] > roleRanks[highest] {
+			highest = role
+		}
+	}
+	return highest
+}
+
+// SessionInfo describes an active session together with its remaining lifetime
+type SessionInfo struct {
+	*Session
+	TTLSeconds int64 `json:"ttl_seconds"`
+}
+
+// HubInstance describes one server instance sharing the WebSocket backplane
+type HubInstance struct {
+	ID        string    `json:"id"`
+	Clients   int       `json:"clients"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// WebSocket protocol versions. Clients send the version they speak with every message (messages
+// without one are version 1); clients older than WSMinProtocolVersion are refused.
+const (
+	WSProtocolVersion    = 2
+	WSMinProtocolVersion = 2
+)
+
 // WebSocketMessage represents messages sent over WebSocket
 type WebSocketMessage struct {
-	Type      string    `json:"type"` // ai_prompt, ai_response, session_status, error
-	Data      WSMsgData `json:"data"`
+	Type    string    `json:"type"` // ai_prompt, ai_prompt_multi, ai_regenerate, ai_thinking, provider_started, ai_progress, ai_response, user_message, session_status, subscribe_chat, unsubscribe_chat, subscribe_chat_list, chat_list_changed, ack, resend, resume_stream, error
+	Version int       `json:"version,omitempty"`
+	ID      int64     `json:"id,omitempty"`  // frames streamed to the prompting client: sequence number to acknowledge
+	Ack     int64     `json:"ack,omitempty"` // ack/resend: highest frame ID received without a gap
+	Data    WSMsgData `json:"data"`
 }
 
 // WSMsgData contains the actual message data
 type WSMsgData struct {
-	ChatID    int64     `json:"chat_id,omitempty"`
+	ChatID        int64            `json:"chat_id,omitempty"`
+	Provider      string           `json:"provider,omitempty"`
+	Content       string           `json:"content"`
+	Timestamp     time.Time        `json:"timestamp"`
+	Stream        bool             `json:"stream,omitempty"`
+	Providers     []string         `json:"providers,omitempty"`       // target providers for ai_prompt_multi; ai_failover: providers that couldn't answer
+	Action        string           `json:"action,omitempty"`          // chat_list_changed: created, renamed, deleted, message; scheduled_run: completed, failed; ai_response_timeout: overall, idle; ai_response_oversized: truncated, reported; message_blocked: prompt, response; tool_result: failed; ai_retry: network, rate_limit, server, crash; error: upgrade_required, quota_exceeded, prompt_rejected, stream_expired, forbidden, protocol_error
+	Model         string           `json:"model,omitempty"`           // per-request model override for ai_prompt/ai_prompt_multi/ai_regenerate
+	MessageID     int64            `json:"message_id,omitempty"`      // ai_regenerate: user message to answer again (default: latest); user_message: the saved prompt
+	RequestID     string           `json:"request_id,omitempty"`      // error: ID of the WebSocket connection's upgrade request
+	AttachmentIDs []int64          `json:"attachment_ids,omitempty"`  // ai_prompt/ai_prompt_multi: uploaded attachments to send with the prompt
+	Images        []WSImage        `json:"images,omitempty"`          // ai_prompt/ai_prompt_multi: images for vision-capable providers
+	ScheduleID    int64            `json:"schedule_id,omitempty"`     // scheduled_run: scheduled prompt that ran
+	Prompt        string           `json:"prompt,omitempty"`          // scheduled_run: prompt added to the chat
+	TimeoutSecs   int64            `json:"timeout_seconds,omitempty"` // ai_response_timeout: limit that was exceeded
+	Quota         *QuotaStatus     `json:"quota,omitempty"`           // error (quota_exceeded): the session's prompt usage
+	StreamID      string           `json:"stream_id,omitempty"`       // ai_response/ai_response_end/resume_stream: streamed response the frame belongs to
+	StreamSeq     int64            `json:"stream_seq,omitempty"`      // ai_response: chunks of the stream up to this frame; ai_response_end: chunks in the stream; resume_stream: chunks received
+	StreamStart   int64            `json:"stream_start,omitempty"`    // ai_response: chunks of the stream before this frame (replayed frames cover several)
+	ElapsedMs     int64            `json:"elapsed_ms,omitempty"`      // ai_thinking/provider_started/ai_progress: time since the provider was asked
+	BytesStreamed int64            `json:"bytes_streamed,omitempty"`  // ai_progress: bytes of the response streamed so far
+	UserID        string           `json:"user_id,omitempty"`         // user_message: participant who sent the prompt; user_joined/user_left: participant
+	Participants  []string         `json:"participants,omitempty"`    // presence: participants viewing the chat on this instance
+	Tool          string           `json:"tool,omitempty"`            // tool_call/tool_result: tool the provider called
+	Metadata      *MessageMetadata `json:"metadata,omitempty"`        // ai_response_saved: how the response was generated
+	Errors        []WSSchemaError  `json:"errors,omitempty"`          // error (protocol_error): where the message broke the protocol schema
+	Attempt       int              `json:"attempt,omitempty"`         // ai_retry: attempt the prompt is sent again as, from 2
+	RetryInMs     int64            `json:"retry_in_ms,omitempty"`     // ai_retry: wait before the attempt
+}
+
+// WSSchemaError is a field of a WebSocket message that breaks the protocol schema
+type WSSchemaError struct {
+	Path    string `json:"path"` // e.g. data.chat_id; empty for the whole message
+	Message string `json:"message"`
+}
+
+// WSImage is an image sent with a prompt, either inline as base64 or as an uploaded attachment
+type WSImage struct {
+	AttachmentID int64  `json:"attachment_id,omitempty"` // uploaded image attachment
+	Data         string `json:"data,omitempty"`          // base64 image data, optionally as a data: URL
+	Filename     string `json:"filename,omitempty"`
+}
+
+// Generation lifecycle events
+const (
+	GenerationQueued     = "queued"
+	GenerationStarted    = "started"
+	GenerationFirstToken = "first_token"
+	GenerationCompleted  = "completed"
+	GenerationFailed     = "failed"
+)
+
+// GenerationEvent is a single lifecycle event of one provider generation
+type GenerationEvent struct {
+	ID           int64     `json:"id"`
+	GenerationID string    `json:"generation_id"`
+	ChatID       int64     `json:"chat_id"`
+	Provider     string    `json:"provider"`
+	Event        string    `json:"event"` // queued, started, first_token, completed, failed
+	Detail       string    `json:"detail,omitempty"`
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// GenerationTiming summarizes the events of one generation
+type GenerationTiming struct {
+	GenerationID       string     `json:"generation_id"`
+	ChatID             int64      `json:"chat_id"`
+	Provider           string     `json:"provider"`
+	Status             string     `json:"status"` // running, completed, failed
+	QueuedAt           *time.Time `json:"queued_at,omitempty"`
+	StartedAt          *time.Time `json:"started_at,omitempty"`
+	FirstTokenAt       *time.Time `json:"first_token_at,omitempty"`
+	FinishedAt         *time.Time `json:"finished_at,omitempty"`
+	TimeToFirstTokenMs *int64     `json:"time_to_first_token_ms,omitempty"`
+	DurationMs         *int64     `json:"duration_ms,omitempty"`
+	Error              string     `json:"error,omitempty"`
+}
+
+// GenerationStats aggregates generation timings for one provider
+type GenerationStats struct {
+	Provider              string  `json:"provider"`
+	Total                 int64   `json:"total"`
+	Completed             int64   `json:"completed"`
+	Failed                int64   `json:"failed"`
+	AvgTimeToFirstTokenMs float64 `json:"avg_time_to_first_token_ms"`
+	AvgDurationMs         float64 `json:"avg_duration_ms"`
+	TimeToFirstTokenSumMs int64   `json:"-"`
+	TimeToFirstTokenCount int64   `json:"-"`
+	DurationSumMs         int64   `json:"-"`
+	DurationCount         int64   `json:"-"`
+}
+
+// Usage directions
+const (
+	UsageInput  = "input"
+	UsageOutput = "output"
+)
+
+// UsageRecord is the byte/token usage of a single prompt or response
+type UsageRecord struct {
+	ID        int64     `json:"id"`
+	ChatID    int64     `json:"chat_id"`
+	MessageID *int64    `json:"message_id,omitempty"`
+	Provider  string    `json:"provider"`
+	Direction string    `json:"direction"` // input, output
+	Bytes     int64     `json:"bytes"`
+	Tokens    int64     `json:"tokens"`
+	Estimated bool      `json:"estimated"` // true when tokens were derived from the byte count
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UsageTotals sums usage records
+type UsageTotals struct {
+	InputBytes   int64 `json:"input_bytes"`
+	OutputBytes  int64 `json:"output_bytes"`
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	Count        int64 `json:"count"` // number of usage records
+}
+
+// ProviderUsage is the usage attributed to one provider
+type ProviderUsage struct {
+	Provider string `json:"provider"`
+	UsageTotals
+}
+
+// ChatUsage is the usage of a single chat
+type ChatUsage struct {
+	ChatID int64 `json:"chat_id"`
+	UsageTotals
+	Providers []*ProviderUsage `json:"providers"`
+	Records   []*UsageRecord   `json:"records,omitempty"`
+}
+
+// UsageSummary aggregates usage across all chats since a point in time
+type UsageSummary struct {
+	Since time.Time `json:"since"`
+	Chats int64     `json:"chats"`
+	UsageTotals
+	Providers []*ProviderUsage `json:"providers"`
+}
+
+// QuotaPeriod is the prompt usage of a session in the current day or month
+type QuotaPeriod struct {
+	Limit     int64     `json:"limit"`     // 0 means unlimited
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"` // -1 when unlimited
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// QuotaStatus is the prompt usage of a session against its daily and monthly quotas
+type QuotaStatus struct {
+	Daily     QuotaPeriod `json:"daily"`
+	Monthly   QuotaPeriod `json:"monthly"`
+	Exhausted bool        `json:"exhausted"` // no prompts left until the next reset
+}
+
+// Feedback ratings
+const (
+	FeedbackDown = -1
+	FeedbackUp   = 1
+)
+
+// MessageFeedback is a thumbs-up/down rating of an assistant message
+type MessageFeedback struct {
+	MessageID int64     `json:"message_id"`
+	ChatID    int64     `json:"chat_id"`
+	Rating    int       `json:"rating"` // 1 (up) or -1 (down)
+	Comment   string    `json:"comment,omitempty"`
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model,omitempty"`
+	Excerpt   string    `json:"excerpt,omitempty"` // start of the rated message, in listings
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ModerationEvent records a prompt or response flagged or blocked by moderation
+type ModerationEvent struct {
+	ID        int64     `json:"id"`
+	ChatID    int64     `json:"chat_id"`
+	Direction string    `json:"direction"` // prompt or response
 	Provider  string    `json:"provider,omitempty"`
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
-	Stream    bool      `json:"stream,omitempty"`
+	Action    string    `json:"action"` // flag or block
+	Moderator string    `json:"moderator"`
+	Rule      string    `json:"rule,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Excerpt   string    `json:"excerpt"` // start of the moderated content
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RetentionPolicy lists the retention rules in effect; a limit of 0 disables its rule
+type RetentionPolicy struct {
+	IdleChatDays       int  `json:"idle_chat_days"`        // move chats without activity for longer to the trash
+	MaxMessagesPerChat int  `json:"max_messages_per_chat"` // delete the oldest messages of chats with more
+	LogDays            int  `json:"log_days"`              // delete chat log files not written to for longer
+	IntervalSeconds    int  `json:"interval_seconds"`
+	DryRun             bool `json:"dry_run"` // scheduled runs only report what they would remove
+}
+
+// RetentionRuleResult is what a retention rule removed in a run, or would have removed in a dry run
+type RetentionRuleResult struct {
+	Rule       string `json:"rule"`
+	Affected   int64  `json:"affected"` // chats, messages or files, depending on the rule
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RetentionRun is the outcome of applying the retention rules once
+type RetentionRun struct {
+	StartedAt time.Time             `json:"started_at"`
+	Trigger   string                `json:"trigger"` // schedule or manual
+	DryRun    bool                  `json:"dry_run"`
+	Rules     []RetentionRuleResult `json:"rules"`
+}
+
+// RetentionRuleMetrics accumulates the runs of a retention rule since the server started
+type RetentionRuleMetrics struct {
+	Rule           string     `json:"rule"`
+	Runs           int64      `json:"runs"`
+	DryRuns        int64      `json:"dry_runs"`
+	Errors         int64      `json:"errors"`
+	Removed        int64      `json:"removed"` // total affected by runs that weren't dry runs
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"`
+	LastAffected   int64      `json:"last_affected"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+// RetentionStatus reports the retention policy, its metrics and the latest run
+type RetentionStatus struct {
+	Policy  RetentionPolicy        `json:"policy"`
+	Metrics []RetentionRuleMetrics `json:"metrics"`
+	LastRun *RetentionRun          `json:"last_run,omitempty"`
+}
+
+// FeedbackStats aggregates the ratings given to one provider and model
+type FeedbackStats struct {
+	Provider   string  `json:"provider"`
+	Model      string  `json:"model"` // empty for the provider default
+	Up         int64   `json:"up"`
+	Down       int64   `json:"down"`
+	Total      int64   `json:"total"`
+	DownRatio  float64 `json:"down_ratio"`  // share of ratings that are thumbs-down
+	Responses  int64   `json:"responses"`   // assistant messages in the period, rated or not
+	RatedRatio float64 `json:"rated_ratio"` // share of responses that were rated
+}
+
+// FeedbackSummary aggregates feedback since a point in time, worst-rated first
+type FeedbackSummary struct {
+	Since     time.Time        `json:"since"`
+	Up        int64            `json:"up"`
+	Down      int64            `json:"down"`
+	Providers []*FeedbackStats `json:"providers"`
+}
+
+// ActivityBucket is the number of messages in one hour or day
+type ActivityBucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+// ActivityReport is chat activity over a date range
+type ActivityReport struct {
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Bucket   string           `json:"bucket"` // hour, day
+	Timezone string           `json:"timezone"`
+	Total    int64            `json:"total"`
+	Buckets  []ActivityBucket `json:"buckets"`
+	Heatmap  [7][24]int64     `json:"heatmap"` // message counts by weekday (0 = Sunday) and hour
+}
+
+// GreetingSettings are the localized system messages added to every new chat, keyed by language
+type GreetingSettings struct {
+	Enabled    bool              `json:"enabled"`
+	Welcome    map[string]string `json:"welcome"`
+	Disclaimer map[string]string `json:"disclaimer"`
+}
+
+// UserSettings are the personal preferences of the admin or of a browser session
+type UserSettings struct {
+	Language          string `json:"language"`
+	Theme             string `json:"theme"`
+	ChatInputBehavior string `json:"chatInputBehavior"`
+	DefaultProvider   string `json:"defaultProvider"` // preselected for new chats; empty for the first available
+	DefaultModel      string `json:"defaultModel"`    // model of DefaultProvider preselected in its chats
+}
+
+// HealthCheckResult is the outcome of a single scheduled provider health check
+type HealthCheckResult struct {
+	ProviderID string    `json:"provider_id"`
+	CheckedAt  time.Time `json:"checked_at"`
+	Success    bool      `json:"success"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Status     string    `json:"status"`
+	Details    string    `json:"details,omitempty"`
+}
+
+// LogEntry is a server log line kept in memory
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// DependencyStatus is the health of a backing service such as the database or Redis
+type DependencyStatus struct {
+	Name      string `json:"name"`
+	Healthy   bool   `json:"healthy"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ReadinessCheck is one component of a readiness report; failing optional components
+// don't make the instance unready
+type ReadinessCheck struct {
+	DependencyStatus
+	Optional bool `json:"optional,omitempty"`
+}
+
+// Readiness reports whether the instance can serve traffic
+type Readiness struct {
+	Ready      bool             `json:"ready"`
+	Components []ReadinessCheck `json:"components"`
+}
+
+// ChatCounts counts chats by state
+type ChatCounts struct {
+	Total    int64 `json:"total"` // active and archived
+	Active   int64 `json:"active"`
+	Archived int64 `json:"archived"`
+	Deleted  int64 `json:"deleted"` // awaiting purge
+}
+
+// AdminStats are the aggregate statistics shown on the admin dashboard
+type AdminStats struct {
+	GeneratedAt        time.Time        `json:"generated_at"`
+	Chats              ChatCounts       `json:"chats"`
+	Messages           int64            `json:"messages"`
+	ActiveSessions     int64            `json:"active_sessions"`
+	Providers          []*Provider      `json:"providers"`
+	ProvidersAvailable int              `json:"providers_available"`
+	Database           DependencyStatus `json:"database"`
+	Redis              DependencyStatus `json:"redis"`
+	SessionStore       string           `json:"session_store"` // "redis", or "memory" when Redis was unavailable at startup
+	RecentErrors       []LogEntry       `json:"recent_errors"`
 }
 
 // Provider represents an AI provider
 type Provider struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Available   bool   `json:"available"`
-	Status      string `json:"status,omitempty"`  // "ready", "not_installed", "not_configured", "error"
-	Version     string `json:"version,omitempty"`
-	Details     string `json:"details,omitempty"`
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Available   bool          `json:"available"`
+	Status      string        `json:"status,omitempty"` // "ready", "not_installed", "not_configured", "error", "degraded"
+	Version     string        `json:"version,omitempty"`
+	Details     string        `json:"details,omitempty"`
+	IconURL     string        `json:"icon_url,omitempty"`
+	Color       string        `json:"color,omitempty"`
+	Circuit     *CircuitState `json:"circuit,omitempty"` // failures counted by the circuit breaker, if any
+}
+
+// Circuit breaker states of a provider
+const (
+	CircuitClosed   = "closed"    // generations run
+	CircuitOpen     = "open"      // generations are refused until retry_at
+	CircuitHalfOpen = "half_open" // one probe generation decides whether the circuit closes again
+)
+
+// CircuitState is the circuit breaker state of a provider
+type CircuitState struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
 }
 
 // NullTime implements sql.Scanner and driver.Valuer for nullable time fields
@@ -0,0 +1,71 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPModerator asks an external service to moderate content. The service receives the Request as
+// JSON in a POST and answers with {"action": "allow|flag|block", "rule": "...", "reason": "..."}.
+type HTTPModerator struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewHTTPModerator creates a moderator posting to url, authenticated with apiKey as a bearer token if set
+func NewHTTPModerator(url, apiKey string, timeout time.Duration) *HTTPModerator {
+	return &HTTPModerator{
+		url:    url,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (m *HTTPModerator) Name() string {
+	return "http"
+}
+
+func (m *HTTPModerator) Moderate(ctx context.Context, req Request) (Verdict, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to marshal moderation request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to create moderation request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if m.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	resp, err := m.client.Do(httpReq)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return Verdict{}, fmt.Errorf("moderation service returned %s", resp.Status)
+	}
+
+	var result struct {
+		Action string `json:"action"`
+		Rule   string `json:"rule"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result); err != nil {
+		return Verdict{}, fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+	if !ValidAction(result.Action) {
+		return Verdict{}, fmt.Errorf("moderation service returned unknown action %q", result.Action)
+	}
+	return Verdict{Action: result.Action, Rule: result.Rule, Reason: result.Reason}, nil
+}
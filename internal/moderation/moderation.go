@@ -0,0 +1,110 @@
+// Package moderation checks prompts before they are sent to providers and responses before they
+// are saved, with built-in keyword and regular expression rules and optional external moderators.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// What is being moderated
+const (
+	DirectionPrompt   = "prompt"
+	DirectionResponse = "response"
+)
+
+// Actions a moderator can decide on, from weakest to strongest
+const (
+	ActionAllow = "allow" // nothing to report
+	ActionFlag  = "flag"  // let the content through but record it for review
+	ActionBlock = "block" // stop the content and record it
+)
+
+// Request is content to moderate
+type Request struct {
+	Direction string `json:"direction"`
+	ChatID    int64  `json:"chat_id"`
+	Provider  string `json:"provider,omitempty"`
+	Content   string `json:"content"`
+}
+
+// Verdict is what a moderator decided about a request
+type Verdict struct {
+	Action    string
+	Moderator string // name of the moderator that decided
+	Rule      string // rule or category that matched, if any
+	Reason    string // explanation meant for users (optional)
+}
+
+// Blocked reports whether the content must be stopped
+func (v Verdict) Blocked() bool {
+	return v.Action == ActionBlock
+}
+
+// Moderator decides whether content may pass
+type Moderator interface {
+	Name() string
+	Moderate(ctx context.Context, req Request) (Verdict, error)
+}
+
+// Pipeline runs moderators in order. The strongest verdict wins and a block skips the remaining
+// moderators. A failing moderator is skipped, or blocks the content when the pipeline fails closed.
+type Pipeline struct {
+	moderators []Moderator
+	failClosed bool
+}
+
+// NewPipeline creates a pipeline of moderators
+func NewPipeline(failClosed bool, moderators ...Moderator) *Pipeline {
+	return &Pipeline{moderators: moderators, failClosed: failClosed}
+}
+
+// Len returns the number of moderators in the pipeline
+func (p *Pipeline) Len() int {
+	return len(p.moderators)
+}
+
+// Moderate returns the strongest verdict on a request, and the errors of the moderators that
+// failed so they can be logged
+func (p *Pipeline) Moderate(ctx context.Context, req Request) (Verdict, error) {
+	verdict := Verdict{Action: ActionAllow}
+	var errs []error
+	for _, moderator := range p.moderators {
+		v, err := moderator.Moderate(ctx, req)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("moderator %s failed: %w", moderator.Name(), err))
+			if !p.failClosed {
+				continue
+			}
+			v = Verdict{Action: ActionBlock, Rule: "unavailable", Reason: "content could not be moderated"}
+		}
+		if v.Moderator == "" {
+			v.Moderator = moderator.Name()
+		}
+		if strength(v.Action) > strength(verdict.Action) {
+			verdict = v
+		}
+		if verdict.Blocked() {
+			break
+		}
+	}
+	return verdict, errors.Join(errs...)
+}
+
+// strength orders actions so the strongest verdict can be picked; unknown actions allow
+func strength(action string) int {
+	switch action {
+	case ActionBlock:
+		return 2
+	case ActionFlag:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ValidAction reports whether action is one a rule or moderator can decide on
+func ValidAction(action string) bool {
+	return action == ActionAllow || action == ActionFlag || action == ActionBlock
+}
@@ -0,0 +1,151 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleModerator(t *testing.T) {
+	moderator, err := NewRuleModerator([]Rule{
+		{Name: "secrets", Pattern: `(?i)api[_-]?key\s*[:=]`, Action: ActionFlag},
+		{Name: "banned", Keywords: []string{"Forbidden Word"}, Action: ActionBlock, Reason: "banned term"},
+		{Name: "responses-only", Keywords: []string{"internal"}, Action: ActionBlock, AppliesTo: []string{DirectionResponse}},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	verdict, _ := moderator.Moderate(ctx, Request{Direction: DirectionPrompt, Content: "hello"})
+	assert.Equal(t, ActionAllow, verdict.Action)
+
+	verdict, _ = moderator.Moderate(ctx, Request{Direction: DirectionPrompt, Content: "my API_KEY = 123"})
+	assert.Equal(t, Verdict{Action: ActionFlag, Rule: "secrets"}, verdict)
+
+	// Keywords match case-insensitively and blocks win over flags
+	verdict, _ = moderator.Moderate(ctx, Request{Direction: DirectionPrompt, Content: "api_key: x and a FORBIDDEN word"})
+	assert.Equal(t, Verdict{Action: ActionBlock, Rule: "banned", Reason: "banned term"}, verdict)
+
+	verdict, _ = moderator.Moderate(ctx, Request{Direction: DirectionPrompt, Content: "internal"})
+	assert.Equal(t, ActionAllow, verdict.Action)
+	verdict, _ = moderator.Moderate(ctx, Request{Direction: DirectionResponse, Content: "internal"})
+	assert.Equal(t, ActionBlock, verdict.Action)
+
+	for _, rule := range []Rule{
+		{Name: "", Keywords: []string{"x"}, Action: ActionBlock},
+		{Name: "no-match", Action: ActionBlock},
+		{Name: "bad-action", Keywords: []string{"x"}, Action: "delete"},
+		{Name: "bad-pattern", Pattern: "(", Action: ActionFlag},
+		{Name: "bad-direction", Keywords: []string{"x"}, Action: ActionFlag, AppliesTo: []string{"both"}},
+	} {
+		_, err := NewRuleModerator([]Rule{rule})
+		assert.Error(t, err, rule.Name)
+	}
+}
+
+func TestLoadRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "moderation.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+rules:
+  - name: banned
+    keywords: [forbidden]
+    action: block
+    applies_to: [prompt]
+`), 0600))
+
+	rules, err := LoadRulesFile(path)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "banned", rules[0].Name)
+	assert.Equal(t, []string{DirectionPrompt}, rules[0].AppliesTo)
+
+	_, err = LoadRulesFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+// stubModerator returns a fixed verdict or error
+type stubModerator struct {
+	name    string
+	verdict Verdict
+	err     error
+	calls   int
+}
+
+func (m *stubModerator) Name() string { return m.name }
+
+func (m *stubModerator) Moderate(ctx context.Context, req Request) (Verdict, error) {
+	m.calls++
+	return m.verdict, m.err
+}
+
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	flag := &stubModerator{name: "flagger", verdict: Verdict{Action: ActionFlag, Rule: "r1"}}
+	block := &stubModerator{name: "blocker", verdict: Verdict{Action: ActionBlock}}
+	after := &stubModerator{name: "after", verdict: Verdict{Action: ActionFlag}}
+
+	verdict, err := NewPipeline(false, flag, block, after).Moderate(ctx, Request{})
+	require.NoError(t, err)
+	assert.Equal(t, Verdict{Action: ActionBlock, Moderator: "blocker"}, verdict)
+	assert.Zero(t, after.calls, "a block skips the remaining moderators")
+
+	verdict, err = NewPipeline(false).Moderate(ctx, Request{})
+	require.NoError(t, err)
+	assert.Equal(t, ActionAllow, verdict.Action)
+
+	// Failing moderators are skipped, or block when the pipeline fails closed
+	failing := &stubModerator{name: "down", err: errors.New("connection refused")}
+	verdict, err = NewPipeline(false, failing, flag).Moderate(ctx, Request{})
+	assert.ErrorContains(t, err, "moderator down failed")
+	assert.Equal(t, Verdict{Action: ActionFlag, Moderator: "flagger", Rule: "r1"}, verdict)
+
+	verdict, err = NewPipeline(true, failing, flag).Moderate(ctx, Request{})
+	assert.Error(t, err)
+	assert.True(t, verdict.Blocked())
+	assert.Equal(t, "down", verdict.Moderator)
+}
+
+func TestHTTPModerator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer mod-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req Request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch req.Content {
+		case "bad":
+			w.Write([]byte(`{"action": "block", "rule": "violence", "reason": "violent content"}`))
+		case "odd":
+			w.Write([]byte(`{"action": "maybe"}`))
+		default:
+			w.Write([]byte(`{"action": "allow"}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	moderator := NewHTTPModerator(server.URL, "mod-key", time.Second)
+
+	verdict, err := moderator.Moderate(ctx, Request{Direction: DirectionPrompt, Content: "bad"})
+	require.NoError(t, err)
+	assert.Equal(t, Verdict{Action: ActionBlock, Rule: "violence", Reason: "violent content"}, verdict)
+
+	verdict, err = moderator.Moderate(ctx, Request{Direction: DirectionPrompt, Content: "fine"})
+	require.NoError(t, err)
+	assert.Equal(t, ActionAllow, verdict.Action)
+
+	_, err = moderator.Moderate(ctx, Request{Content: "odd"})
+	assert.ErrorContains(t, err, "unknown action")
+
+	_, err = NewHTTPModerator(server.URL, "wrong", time.Second).Moderate(ctx, Request{Content: "bad"})
+	assert.ErrorContains(t, err, "401")
+}
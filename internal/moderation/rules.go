@@ -0,0 +1,148 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule flags or blocks content containing one of its keywords or matching its pattern
+type Rule struct {
+	Name      string   `yaml:"name" json:"name"`
+	Keywords  []string `yaml:"keywords" json:"keywords"`     // matched case-insensitively
+	Pattern   string   `yaml:"pattern" json:"pattern"`       // regular expression
+	Action    string   `yaml:"action" json:"action"`         // flag or block
+	AppliesTo []string `yaml:"applies_to" json:"applies_to"` // prompt and/or response; empty for both
+	Reason    string   `yaml:"reason" json:"reason"`         // shown to users whose content is blocked (optional)
+
+	keywords []string
+	pattern  *regexp.Regexp
+}
+
+// RulesFile is the top-level structure of the moderation rules file
+type RulesFile struct {
+	Rules []Rule `yaml:"rules" json:"rules"`
+}
+
+// LoadRulesFile reads moderation rules in YAML or JSON format
+func LoadRulesFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read moderation rules file %s: %w", path, err)
+	}
+
+	var file RulesFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &file)
+	default:
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse moderation rules file %s: %w", path, err)
+	}
+	return file.Rules, nil
+}
+
+// compile validates a rule and prepares it for matching
+func (r *Rule) compile() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Action != ActionFlag && r.Action != ActionBlock {
+		return fmt.Errorf("rule %s: action must be flag or block, got %q", r.Name, r.Action)
+	}
+	for _, direction := range r.AppliesTo {
+		if direction != DirectionPrompt && direction != DirectionResponse {
+			return fmt.Errorf("rule %s: applies_to must list prompt or response, got %q", r.Name, direction)
+		}
+	}
+
+	r.keywords = nil
+	for _, keyword := range r.Keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			r.keywords = append(r.keywords, keyword)
+		}
+	}
+	r.pattern = nil
+	if r.Pattern != "" {
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %s: invalid pattern: %w", r.Name, err)
+		}
+		r.pattern = pattern
+	}
+	if len(r.keywords) == 0 && r.pattern == nil {
+		return fmt.Errorf("rule %s: keywords or pattern is required", r.Name)
+	}
+	return nil
+}
+
+// appliesTo reports whether the rule checks content going in direction
+func (r *Rule) appliesTo(direction string) bool {
+	if len(r.AppliesTo) == 0 {
+		return true
+	}
+	for _, d := range r.AppliesTo {
+		if d == direction {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether content contains a keyword or matches the pattern; lower is content in lower case
+func (r *Rule) matches(content, lower string) bool {
+	for _, keyword := range r.keywords {
+		if strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	return r.pattern != nil && r.pattern.MatchString(content)
+}
+
+// RuleModerator applies keyword and regular expression rules
+type RuleModerator struct {
+	rules []Rule
+}
+
+// NewRuleModerator validates rules and creates a moderator applying them
+func NewRuleModerator(rules []Rule) (*RuleModerator, error) {
+	compiled := make([]Rule, len(rules))
+	copy(compiled, rules)
+	for i := range compiled {
+		if err := compiled[i].compile(); err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+	return &RuleModerator{rules: compiled}, nil
+}
+
+func (m *RuleModerator) Name() string {
+	return "rules"
+}
+
+// Moderate returns the verdict of the first blocking rule that matches, or else of the first flagging one
+func (m *RuleModerator) Moderate(ctx context.Context, req Request) (Verdict, error) {
+	verdict := Verdict{Action: ActionAllow}
+	lower := strings.ToLower(req.Content)
+	for i := range m.rules {
+		rule := &m.rules[i]
+		if !rule.appliesTo(req.Direction) || !rule.matches(req.Content, lower) {
+			continue
+		}
+		if strength(rule.Action) > strength(verdict.Action) {
+			verdict = Verdict{Action: rule.Action, Rule: rule.Name, Reason: rule.Reason}
+		}
+		if verdict.Blocked() {
+			break
+		}
+	}
+	return verdict, nil
+}
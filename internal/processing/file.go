@@ -0,0 +1,119 @@
+package processing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Processor types of the processors file
+const (
+	TypePrepend = "prepend"
+	TypeAppend  = "append"
+	TypeReplace = "replace"
+	TypeHTTP    = "http"
+)
+
+// Timeout of plugin requests without one
+const defaultPluginTimeout = 10 * time.Second
+
+// Entry configures a processor in the processors file
+type Entry struct {
+	Name      string   `yaml:"name" json:"name"`
+	Type      string   `yaml:"type" json:"type"`           // prepend, append, replace or http
+	Stage     string   `yaml:"stage" json:"stage"`         // pre (prompts) or post (responses)
+	Order     int      `yaml:"order" json:"order"`         // ascending within a stage
+	Providers []string `yaml:"providers" json:"providers"` // empty for all providers
+	Enabled   *bool    `yaml:"enabled" json:"enabled"`     // default for chats; true when omitted
+
+	Text        string `yaml:"text" json:"text"`               // prepend and append
+	Pattern     string `yaml:"pattern" json:"pattern"`         // replace
+	Replacement string `yaml:"replacement" json:"replacement"` // replace
+	URL         string `yaml:"url" json:"url"`                 // http
+	APIKey      string `yaml:"api_key" json:"api_key"`         // http; may be a secret reference
+	Timeout     int    `yaml:"timeout" json:"timeout"`         // http, in seconds
+}
+
+// File is the top-level structure of the processors file
+type File struct {
+	Processors []Entry `yaml:"processors" json:"processors"`
+}
+
+// LoadFile reads processors in YAML or JSON format
+func LoadFile(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processors file %s: %w", path, err)
+	}
+
+	var file File
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &file)
+	default:
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse processors file %s: %w", path, err)
+	}
+	return file.Processors, nil
+}
+
+// Step validates an entry and creates the step it configures
+func (e *Entry) Step() (Step, error) {
+	if e.Name == "" {
+		return Step{}, fmt.Errorf("name is required")
+	}
+	step := Step{Name: e.Name, Stage: e.Stage, Order: e.Order, Providers: e.Providers, Enabled: e.Enabled == nil || *e.Enabled}
+
+	switch e.Type {
+	case TypePrepend:
+		step.Processor = &PrependProcessor{Text: e.Text}
+	case TypeAppend:
+		step.Processor = &AppendProcessor{Text: e.Text}
+	case TypeReplace:
+		if e.Pattern == "" {
+			return Step{}, fmt.Errorf("processor %s: pattern is required", e.Name)
+		}
+		processor, err := NewReplaceProcessor(e.Pattern, e.Replacement)
+		if err != nil {
+			return Step{}, fmt.Errorf("processor %s: invalid pattern: %w", e.Name, err)
+		}
+		step.Processor = processor
+	case TypeHTTP:
+		if u, err := url.Parse(e.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Step{}, fmt.Errorf("processor %s: url must be an http or https URL, got %q", e.Name, e.URL)
+		}
+		if e.Timeout < 0 {
+			return Step{}, fmt.Errorf("processor %s: timeout must not be negative", e.Name)
+		}
+		timeout := defaultPluginTimeout
+		if e.Timeout > 0 {
+			timeout = time.Duration(e.Timeout) * time.Second
+		}
+		step.Processor = NewHTTPProcessor(e.URL, e.APIKey, timeout)
+	default:
+		return Step{}, fmt.Errorf("processor %s: type must be prepend, append, replace or http, got %q", e.Name, e.Type)
+	}
+	return step, nil
+}
+
+// Build validates entries and creates the pipeline they configure, with built-in steps
+func Build(entries []Entry, builtin ...Step) (*Pipeline, error) {
+	steps := make([]Step, 0, len(entries)+len(builtin))
+	steps = append(steps, builtin...)
+	for i := range entries {
+		step, err := entries[i].Step()
+		if err != nil {
+			return nil, fmt.Errorf("processors[%d]: %w", i, err)
+		}
+		steps = append(steps, step)
+	}
+	return NewPipeline(steps...)
+}
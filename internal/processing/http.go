@@ -0,0 +1,69 @@
+package processing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Largest response accepted from a plugin
+const maxPluginResponseBytes = 4 * 1024 * 1024
+
+// HTTPProcessor asks an external plugin to transform content, e.g. to translate it or add
+// citations. The plugin receives the Request as JSON in a POST and answers with
+// {"content": "..."}.
+type HTTPProcessor struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewHTTPProcessor creates a processor posting to url, authenticated with apiKey as a bearer token if set
+func NewHTTPProcessor(url, apiKey string, timeout time.Duration) *HTTPProcessor {
+	return &HTTPProcessor{
+		url:    url,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *HTTPProcessor) Process(ctx context.Context, req Request) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal processing request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create processing request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("processing request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("plugin returned %s", resp.Status)
+	}
+
+	var result struct {
+		Content *string `json:"content"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxPluginResponseBytes)).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode processing response: %w", err)
+	}
+	if result.Content == nil {
+		return "", fmt.Errorf("plugin returned no content")
+	}
+	return *result.Content, nil
+}
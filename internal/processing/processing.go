@@ -0,0 +1,139 @@
+// Package processing transforms prompts before they are sent to providers (pre processors, e.g.
+// injecting context or appending instructions) and responses before they are saved (post
+// processors, e.g. stripping markup or adding citations), with built-in text processors and
+// external plugins.
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// When a processor runs
+const (
+	StagePre  = "pre"  // on prompts, before they are sent to providers
+	StagePost = "post" // on responses, before they are saved
+)
+
+// Request is content to transform
+type Request struct {
+	Stage    string `json:"stage"`
+	ChatID   int64  `json:"chat_id"`
+	Provider string `json:"provider,omitempty"`
+	Content  string `json:"content"`
+}
+
+// Processor transforms content, returning the content to pass on
+type Processor interface {
+	Process(ctx context.Context, req Request) (string, error)
+}
+
+// Step is a processor with where and when it runs
+type Step struct {
+	Name      string
+	Stage     string
+	Order     int      // steps of a stage run in ascending order
+	Providers []string // providers the step applies to; empty for all
+	Enabled   bool     // whether chats run the step unless they disable it
+	Processor Processor
+}
+
+// Info describes a step for listings
+type Info struct {
+	Name      string   `json:"name"`
+	Stage     string   `json:"stage"`
+	Order     int      `json:"order"`
+	Providers []string `json:"providers,omitempty"`
+	Enabled   bool     `json:"enabled"`
+}
+
+// appliesTo reports whether the step transforms content of the provider
+func (s *Step) appliesTo(provider string) bool {
+	if len(s.Providers) == 0 {
+		return true
+	}
+	for _, p := range s.Providers {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// Pipeline runs the steps of a stage in order, each one transforming the output of the previous.
+// A failing step is skipped, leaving the content as it was.
+type Pipeline struct {
+	steps []Step
+}
+
+// NewPipeline creates a pipeline of steps, which must have unique names
+func NewPipeline(steps ...Step) (*Pipeline, error) {
+	seen := make(map[string]bool)
+	for _, step := range steps {
+		if step.Name == "" {
+			return nil, fmt.Errorf("processor name is required")
+		}
+		if seen[step.Name] {
+			return nil, fmt.Errorf("duplicate processor %s", step.Name)
+		}
+		seen[step.Name] = true
+		if step.Stage != StagePre && step.Stage != StagePost {
+			return nil, fmt.Errorf("processor %s: stage must be pre or post, got %q", step.Name, step.Stage)
+		}
+	}
+
+	sorted := make([]Step, len(steps))
+	copy(sorted, steps)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+	return &Pipeline{steps: sorted}, nil
+}
+
+// Len returns the number of steps in the pipeline
+func (p *Pipeline) Len() int {
+	return len(p.steps)
+}
+
+// Steps describes the steps of the pipeline in the order they run
+func (p *Pipeline) Steps() []Info {
+	infos := make([]Info, len(p.steps))
+	for i, step := range p.steps {
+		infos[i] = Info{Name: step.Name, Stage: step.Stage, Order: step.Order, Providers: step.Providers, Enabled: step.Enabled}
+	}
+	return infos
+}
+
+// Has reports whether the pipeline has a step with the given name
+func (p *Pipeline) Has(name string) bool {
+	for _, step := range p.steps {
+		if step.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Process runs the steps of the request's stage that apply to its provider and are enabled, per
+// enabled (nil runs the steps enabled by default). It returns the transformed content, and the
+// errors of the steps that failed so they can be logged.
+func (p *Pipeline) Process(ctx context.Context, req Request, enabled func(step string, byDefault bool) bool) (string, error) {
+	var errs []error
+	for i := range p.steps {
+		step := &p.steps[i]
+		if step.Stage != req.Stage || !step.appliesTo(req.Provider) {
+			continue
+		}
+		if enabled != nil && !enabled(step.Name, step.Enabled) || enabled == nil && !step.Enabled {
+			continue
+		}
+
+		content, err := step.Processor.Process(ctx, req)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("processor %s failed: %w", step.Name, err))
+			continue
+		}
+		req.Content = content
+	}
+	return req.Content, errors.Join(errs...)
+}
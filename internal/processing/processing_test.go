@@ -0,0 +1,136 @@
+package processing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingProcessor struct{}
+
+func (failingProcessor) Process(ctx context.Context, req Request) (string, error) {
+	return "", errors.New("down")
+}
+
+func TestPipeline(t *testing.T) {
+	strip, err := NewReplaceProcessor(`</?b>`, "")
+	require.NoError(t, err)
+	pipeline, err := NewPipeline(
+		Step{Name: "instructions", Stage: StagePre, Order: 20, Enabled: true, Processor: &AppendProcessor{Text: "\nBe brief."}},
+		Step{Name: "context", Stage: StagePre, Order: 10, Enabled: true, Processor: &PrependProcessor{Text: "Project: hub\n"}},
+		Step{Name: "broken", Stage: StagePre, Order: 15, Enabled: true, Processor: failingProcessor{}},
+		Step{Name: "claude-only", Stage: StagePre, Order: 30, Providers: []string{"claude"}, Enabled: true, Processor: &AppendProcessor{Text: " (claude)"}},
+		Step{Name: "optional", Stage: StagePre, Order: 40, Processor: &AppendProcessor{Text: " (optional)"}},
+		Step{Name: "strip", Stage: StagePost, Enabled: true, Processor: strip},
+	)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Steps run in order, each on the output of the previous; failing steps are skipped
+	content, err := pipeline.Process(ctx, Request{Stage: StagePre, Provider: "gemini", Content: "Hi"}, nil)
+	assert.ErrorContains(t, err, "processor broken failed")
+	assert.Equal(t, "Project: hub\nHi\nBe brief.", content)
+
+	content, _ = pipeline.Process(ctx, Request{Stage: StagePre, Provider: "claude", Content: "Hi"}, nil)
+	assert.Equal(t, "Project: hub\nHi\nBe brief. (claude)", content)
+
+	// Chats can disable steps enabled by default and enable the others
+	enabled := func(step string, byDefault bool) bool {
+		return step == "optional" || byDefault && step != "context"
+	}
+	content, _ = pipeline.Process(ctx, Request{Stage: StagePre, Content: "Hi"}, enabled)
+	assert.Equal(t, "Hi\nBe brief. (optional)", content)
+
+	content, err = pipeline.Process(ctx, Request{Stage: StagePost, Content: "<b>Done</b>"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Done", content)
+
+	names := make([]string, 0)
+	for _, step := range pipeline.Steps() {
+		names = append(names, step.Name)
+	}
+	assert.Equal(t, []string{"strip", "context", "broken", "instructions", "claude-only", "optional"}, names)
+
+	_, err = NewPipeline(Step{Name: "a", Stage: StagePre}, Step{Name: "a", Stage: StagePost})
+	assert.ErrorContains(t, err, "duplicate")
+	_, err = NewPipeline(Step{Name: "a", Stage: "during"})
+	assert.ErrorContains(t, err, "stage must be pre or post")
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "processors.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+processors:
+  - name: context
+    type: prepend
+    stage: pre
+    text: "Context\n"
+  - name: strip-html
+    type: replace
+    stage: post
+    order: 5
+    pattern: '<[^>]+>'
+    enabled: false
+`), 0644))
+
+	entries, err := LoadFile(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	pipeline, err := Build(entries)
+	require.NoError(t, err)
+
+	steps := pipeline.Steps()
+	assert.Equal(t, Info{Name: "strip-html", Stage: StagePost, Order: 5, Enabled: false}, steps[1])
+	assert.True(t, steps[0].Enabled)
+
+	_, err = Build([]Entry{{Name: "bad", Type: TypeReplace, Stage: StagePost, Pattern: "("}})
+	assert.ErrorContains(t, err, "invalid pattern")
+	_, err = Build([]Entry{{Name: "bad", Type: TypeHTTP, Stage: StagePre, URL: "ftp://plugins"}})
+	assert.ErrorContains(t, err, "http or https")
+	_, err = Build([]Entry{{Name: "bad", Type: "translate", Stage: StagePre}})
+	assert.ErrorContains(t, err, "type must be")
+
+	_, err = LoadFile(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestHTTPProcessor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer plugin-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req Request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch req.Content {
+		case "empty":
+			w.Write([]byte(`{}`))
+		default:
+			json.NewEncoder(w).Encode(map[string]string{"content": req.Stage + ":" + strings.ToUpper(req.Content)})
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	processor := NewHTTPProcessor(server.URL, "plugin-key", defaultPluginTimeout)
+
+	content, err := processor.Process(ctx, Request{Stage: StagePost, Content: "answer"})
+	require.NoError(t, err)
+	assert.Equal(t, "post:ANSWER", content)
+
+	_, err = processor.Process(ctx, Request{Stage: StagePost, Content: "empty"})
+	assert.ErrorContains(t, err, "no content")
+
+	_, err = NewHTTPProcessor(server.URL, "wrong", defaultPluginTimeout).Process(ctx, Request{Content: "answer"})
+	assert.ErrorContains(t, err, "401")
+}
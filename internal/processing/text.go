@@ -0,0 +1,44 @@
+package processing
+
+import (
+	"context"
+	"regexp"
+)
+
+// PrependProcessor adds text before content, e.g. context for every prompt
+type PrependProcessor struct {
+	Text string
+}
+
+func (p *PrependProcessor) Process(ctx context.Context, req Request) (string, error) {
+	return p.Text + req.Content, nil
+}
+
+// AppendProcessor adds text after content, e.g. instructions for every prompt
+type AppendProcessor struct {
+	Text string
+}
+
+func (p *AppendProcessor) Process(ctx context.Context, req Request) (string, error) {
+	return req.Content + p.Text, nil
+}
+
+// ReplaceProcessor replaces the matches of a regular expression, e.g. to strip markup. The
+// replacement may refer to submatches as $1 or ${name}.
+type ReplaceProcessor struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// NewReplaceProcessor compiles pattern and creates a processor replacing its matches
+func NewReplaceProcessor(pattern, replacement string) (*ReplaceProcessor, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplaceProcessor{pattern: re, replacement: replacement}, nil
+}
+
+func (p *ReplaceProcessor) Process(ctx context.Context, req Request) (string, error) {
+	return p.pattern.ReplaceAllString(req.Content, p.replacement), nil
+}
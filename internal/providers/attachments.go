@@ -0,0 +1,96 @@
+package providers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Attachment is a file sent with a prompt, stored where local CLI providers can read it
+type Attachment struct {
+	Path        string // absolute path of the stored file
+	Filename    string // name the file was uploaded with
+	ContentType string
+	Size        int64
+}
+
+type attachmentsContextKey struct{}
+
+// WithAttachments returns a context that asks the provider to include the given files
+func WithAttachments(ctx context.Context, attachments []Attachment) context.Context {
+	if len(attachments) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, attachmentsContextKey{}, attachments)
+}
+
+// AttachmentsFromContext returns the files requested for this call
+func AttachmentsFromContext(ctx context.Context) []Attachment {
+	attachments, _ := ctx.Value(attachmentsContextKey{}).([]Attachment)
+	return attachments
+}
+
+// AppendAttachmentReferences lists the attachments after the prompt, each path prefixed with
+// refPrefix (e.g. "@" for CLIs that resolve @path file references)
+func AppendAttachmentReferences(prompt string, attachments []Attachment, refPrefix string) string {
+	if len(attachments) == 0 {
+		return prompt
+	}
+
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\nAttached files:\n")
+	for _, a := range attachments {
+		fmt.Fprintf(&b, "- %s%s (%s, %s, %d bytes)\n", refPrefix, a.Path, a.Filename, a.ContentType, a.Size)
+	}
+	return b.String()
+}
+
+// IsImage reports whether the attachment is an image
+func (a Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+// encodedImage is an image attachment embedded in an http provider request
+type encodedImage struct {
+	Filename  string `json:"filename"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"` // base64
+}
+
+// encodeImages reads the image attachments for embedding in a request body
+func encodeImages(attachments []Attachment) ([]encodedImage, error) {
+	var images []encodedImage
+	for _, a := range attachments {
+		if !a.IsImage() {
+			continue
+		}
+		data, err := os.ReadFile(a.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image %s: %w", a.Filename, err)
+		}
+		images = append(images, encodedImage{
+			Filename:  a.Filename,
+			MediaType: a.ContentType,
+			Data:      base64.StdEncoding.EncodeToString(data),
+		})
+	}
+	return images, nil
+}
+
+// attachmentDirs returns the distinct directories holding the attachments
+func attachmentDirs(attachments []Attachment) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, a := range attachments {
+		dir := filepath.Dir(a.Path)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
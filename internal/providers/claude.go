@@ -13,12 +13,24 @@ import (
 	"ai-gateway-hub/internal/utils"
 )
 
+// ClaudeModels are the model aliases accepted by `claude --model`
+var ClaudeModels = []Model{
+	{ID: "sonnet", Name: "Claude Sonnet", Default: true},
+	{ID: "opus", Name: "Claude Opus"},
+	{ID: "haiku", Name: "Claude Haiku"},
+}
+
 // ClaudeProvider implements the AIProvider interface for Claude CLI
 type ClaudeProvider struct {
 	cliPath         string
 	logDir          string
 	skipPermissions bool
-	extraArgs       string
+	extraArgsMu     sync.RWMutex
+	extraArgs       string // replaced when the configuration is reloaded
+	models          []Model
+	envPolicy       EnvPolicy
+	sessions        bool // continue chats in the CLI's own sessions
+	sandbox         Sandbox
 }
 
 // NewClaudeProvider creates a new Claude provider instance
@@ -28,7 +40,62 @@ func NewClaudeProvider(cliPath, logDir string, skipPermissions bool, extraArgs s
 		logDir:          logDir,
 		skipPermissions: skipPermissions,
 		extraArgs:       extraArgs,
+		models:          ClaudeModels,
+		envPolicy:       DefaultEnvPolicy.With(ClaudeEnvAllowlist, nil),
+		sessions:        true,
+	}
+}
+
+// SetEnvPolicy replaces the policy deciding which server environment variables the CLI inherits
+func (p *ClaudeProvider) SetEnvPolicy(policy EnvPolicy) {
+	p.envPolicy = policy
+}
+
+// GetConfig implements ConfiguredProvider, describing the CLI settings as a providers file entry
+func (p *ClaudeProvider) GetConfig() ProviderConfig {
+	models := make([]string, 0, len(p.models))
+	for _, m := range p.models {
+		models = append(models, m.ID)
 	}
+	return ProviderConfig{
+		ID:           p.GetID(),
+		Name:         p.GetName(),
+		Description:  p.GetDescription(),
+		Type:         ProviderTypeClaude,
+		Command:      p.cliPath,
+		Args:         strings.Fields(p.getExtraArgs()),
+		Models:       models,
+		Capabilities: p.GetCapabilities(),
+		Sandbox:      p.sandbox,
+	}
+}
+
+// SetExtraArgs replaces the arguments added to every CLI invocation; running prompts keep theirs
+func (p *ClaudeProvider) SetExtraArgs(extraArgs string) {
+	p.extraArgsMu.Lock()
+	defer p.extraArgsMu.Unlock()
+	p.extraArgs = extraArgs
+}
+
+func (p *ClaudeProvider) getExtraArgs() string {
+	p.extraArgsMu.RLock()
+	defer p.extraArgsMu.RUnlock()
+	return p.extraArgs
+}
+
+// SetSandbox implements SandboxedProvider
+func (p *ClaudeProvider) SetSandbox(sandbox Sandbox) {
+	p.sandbox = sandbox
+}
+
+// SetSessions enables or disables continuing chats with --resume
+func (p *ClaudeProvider) SetSessions(enabled bool) {
+	p.sessions = enabled
+}
+
+// SupportsSessions implements SessionProvider
+func (p *ClaudeProvider) SupportsSessions() bool {
+	return p.sessions
 }
 
 func (p *ClaudeProvider) GetID() string {
@@ -43,24 +110,41 @@ func (p *ClaudeProvider) GetDescription() string {
 	return "Anthropic's Claude AI assistant via CLI"
 }
 
+func (p *ClaudeProvider) GetBranding() ProviderBranding {
+	return ProviderBranding{
+		IconURL: ProviderIconBasePath + "/claude.svg",
+		Color:   "#D97757",
+	}
+}
+
+// GetCapabilities reports vision support: the CLI reads images referenced in the prompt
+func (p *ClaudeProvider) GetCapabilities() []string {
+	return []string{CapabilityVision}
+}
+
+func (p *ClaudeProvider) GetModels() []Model {
+	return p.models
+}
+
 func (p *ClaudeProvider) IsAvailable() bool {
 	// Check if claude CLI is available
 	cmd := exec.Command(p.cliPath, "--version")
-	cmd.Env = os.Environ()
+	cmd.Env = p.envPolicy.Environ()
 	err := cmd.Run()
 	return err == nil
 }
 
 func (p *ClaudeProvider) GetStatus() ProviderStatus {
 	status := ProviderStatus{
-		Available: false,
-		Status:    "not_installed",
-		Details:   "Claude CLI not found",
+		Available:    false,
+		Status:       "not_installed",
+		Details:      "Claude CLI not found",
+		Capabilities: p.GetCapabilities(),
 	}
 
 	// Check if claude CLI exists with a quick version check only
 	cmd := exec.Command(p.cliPath, "--version")
-	cmd.Env = os.Environ()
+	cmd.Env = p.envPolicy.Environ()
 	
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -95,44 +179,89 @@ func (p *ClaudeProvider) GetStatus() ProviderStatus {
 }
 
 // buildArgs constructs the command arguments based on provider configuration
-func (p *ClaudeProvider) buildArgs(baseArgs ...string) []string {
+func (p *ClaudeProvider) buildArgs(ctx context.Context, baseArgs ...string) ([]string, error) {
 	args := make([]string, 0)
 	
 	// Add base arguments
 	args = append(args, baseArgs...)
 	
+	// Continue or start the chat's session
+	sessionArgs, err := p.sessionArgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, sessionArgs...)
+	
+	// Add per-request model override
+	if model := ModelFromContext(ctx); model != "" {
+		args = append(args, "--model", model)
+	}
+	
+	// Allow the CLI to read attachments stored outside its working directory
+	for _, dir := range attachmentDirs(AttachmentsFromContext(ctx)) {
+		args = append(args, "--add-dir", dir)
+	}
+	
 	// Add skip permissions flag if enabled
 	if p.skipPermissions {
 		args = append(args, "--dangerously-skip-permissions")
 	}
 	
 	// Add extra arguments if provided
-	if p.extraArgs != "" {
+	if extraArgs := p.getExtraArgs(); extraArgs != "" {
 		// Split extra args by space, respecting quoted strings
-		extraArgsList := strings.Fields(p.extraArgs)
+		extraArgsList := strings.Fields(extraArgs)
 		args = append(args, extraArgsList...)
 	}
 	
-	return args
+	return args, nil
+}
+
+// sessionArgs continues the requested session with --resume, or starts it under a new --session-id
+func (p *ClaudeProvider) sessionArgs(ctx context.Context) ([]string, error) {
+	session := SessionFromContext(ctx)
+	if session == nil || !p.sessions {
+		return nil, nil
+	}
+	if session.ID != "" {
+		return []string{"--resume", session.ID}, nil
+	}
+
+	id, err := NewSessionID()
+	if err != nil {
+		return nil, err
+	}
+	session.ID = id
+	return []string{"--session-id", id}, nil
+}
+
+// isSessionExpired reports whether the CLI's error output says the resumed session is gone
+func isSessionExpired(stderr string) bool {
+	return strings.Contains(strings.ToLower(stderr), "no conversation found")
 }
 
 func (p *ClaudeProvider) SendPrompt(ctx context.Context, prompt string, chatID int64) (io.ReadCloser, error) {
+	prompt = AppendAttachmentReferences(prompt, AttachmentsFromContext(ctx), "@")
+
 	// Create log file for this chat
 	logPath := fmt.Sprintf("%s/claude/chat_%d.log", p.logDir, chatID)
-	logFile, err := utils.CreateFile(logPath)
+	logFile, err := utils.CreateChatLog(logPath)
 	if err != nil {
 		return nil, err
 	}
 	defer logFile.Close()
 
 	// Execute claude CLI with --print flag for non-interactive output
-	args := p.buildArgs("--print")
-	cmd := exec.CommandContext(ctx, p.cliPath, args...)
+	args, err := p.buildArgs(ctx, "--print")
+	if err != nil {
+		return nil, err
+	}
+	cmd := newProviderCommand(ctx, p.GetID(), p.sandbox, p.cliPath, args...)
 	cmd.Stdin = bytes.NewReader([]byte(prompt))
 	
-	// Inherit environment variables including PATH and HOME for Claude auth
+	// Inherit allowlisted environment variables including PATH and HOME for Claude auth
 	// Add environment variables to prevent TTY issues in Docker
-	cmd.Env = append(os.Environ(), 
+	cmd.Env = append(p.envPolicy.Environ(), 
 		"CI=true",                    // Prevent interactive prompts
 		"TERM=dumb",                  // Simple terminal
 		"NO_COLOR=1",                 // Disable colors
@@ -163,6 +292,9 @@ func (p *ClaudeProvider) SendPrompt(ctx context.Context, prompt string, chatID i
 
 // StreamResponse streams Claude CLI response to the provided writer
 func (p *ClaudeProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	// Claude resolves @path references to the attached files
+	prompt = AppendAttachmentReferences(prompt, AttachmentsFromContext(ctx), "@")
+
 	// Setup logging
 	logFile, err := p.setupLogging(chatID, prompt)
 	if err != nil {
@@ -192,9 +324,9 @@ func (p *ClaudeProvider) StreamResponse(ctx context.Context, prompt string, chat
 }
 
 // setupLogging creates and initializes the log file for the chat
-func (p *ClaudeProvider) setupLogging(chatID int64, prompt string) (*os.File, error) {
+func (p *ClaudeProvider) setupLogging(chatID int64, prompt string) (*utils.ChatLog, error) {
 	logPath := fmt.Sprintf("%s/claude/chat_%d.log", p.logDir, chatID)
-	logFile, err := utils.CreateFile(logPath)
+	logFile, err := utils.CreateChatLog(logPath)
 	if err != nil {
 		return nil, err
 	}
@@ -230,10 +362,13 @@ func (p *ClaudeProvider) createTempPromptFile(prompt string) (string, func(), er
 }
 
 // setupClaudeCommand creates and configures the Claude CLI command
-func (p *ClaudeProvider) setupClaudeCommand(ctx context.Context, tmpFileName string) (*exec.Cmd, io.ReadCloser, io.ReadCloser, error) {
+func (p *ClaudeProvider) setupClaudeCommand(ctx context.Context, tmpFileName string) (*providerCommand, io.ReadCloser, io.ReadCloser, error) {
 	// Build command arguments
-	args := p.buildArgs("--print")
-	cmd := exec.CommandContext(ctx, p.cliPath, args...)
+	args, err := p.buildArgs(ctx, "--print")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cmd := newProviderCommand(ctx, p.GetID(), p.sandbox, p.cliPath, args...)
 
 	// Set stdin to read from temp file
 	tmpFileForRead, err := os.Open(tmpFileName)
@@ -243,7 +378,7 @@ func (p *ClaudeProvider) setupClaudeCommand(ctx context.Context, tmpFileName str
 	cmd.Stdin = tmpFileForRead
 
 	// Set environment variables to prevent TTY issues
-	cmd.Env = append(os.Environ(),
+	cmd.Env = append(p.envPolicy.Environ(),
 		"CI=true",
 		"TERM=dumb",
 		"NO_COLOR=1",
@@ -265,7 +400,7 @@ func (p *ClaudeProvider) setupClaudeCommand(ctx context.Context, tmpFileName str
 }
 
 // handleCommandExecution manages the execution and output handling of the Claude CLI command
-func (p *ClaudeProvider) handleCommandExecution(cmd *exec.Cmd, stdout, stderr io.ReadCloser, writer io.Writer, logFile *os.File) error {
+func (p *ClaudeProvider) handleCommandExecution(cmd *providerCommand, stdout, stderr io.ReadCloser, writer io.Writer, logFile *utils.ChatLog) error {
 	// Ensure stdout and stderr are closed properly
 	defer stdout.Close()
 	defer stderr.Close()
@@ -279,19 +414,23 @@ func (p *ClaudeProvider) handleCommandExecution(cmd *exec.Cmd, stdout, stderr io
 	
 	// Handle stderr with proper error handling and synchronization
 	var wg sync.WaitGroup
+	var stderrOutput string
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		p.handleStderr(stderr, logFile)
+		stderrOutput = p.handleStderr(stderr, logFile)
 	}()
 
-	// Create multi-writer to write to both output and log
-	multiWriter := io.MultiWriter(writer, logFile)
+	// Create multi-writer to write to both output and log, without terminal escapes
+	multiWriter := NewSanitizer(io.MultiWriter(writer, logFile))
 
 	// Copy output
 	if _, err := io.Copy(multiWriter, stdout); err != nil {
 		return fmt.Errorf("failed to copy output: %w", err)
 	}
+	if err := multiWriter.Flush(); err != nil {
+		return fmt.Errorf("failed to copy output: %w", err)
+	}
 
 	// Wait for stderr goroutine to complete
 	wg.Wait()
@@ -301,30 +440,34 @@ func (p *ClaudeProvider) handleCommandExecution(cmd *exec.Cmd, stdout, stderr io
 
 	// Wait for command to finish
 	if err := cmd.Wait(); err != nil {
+		if isSessionExpired(stderrOutput) {
+			return fmt.Errorf("%w: %v", ErrSessionExpired, err)
+		}
 		return fmt.Errorf("claude CLI failed: %w", err)
 	}
 
 	return nil
 }
 
-// handleStderr processes stderr output from the Claude CLI command
-func (p *ClaudeProvider) handleStderr(stderr io.ReadCloser, logFile *os.File) {
+// handleStderr logs and returns the stderr output of the Claude CLI command
+func (p *ClaudeProvider) handleStderr(stderr io.ReadCloser, logFile *utils.ChatLog) string {
 	stderrBytes, err := io.ReadAll(stderr)
 	if err != nil {
 		utils.Error("Claude CLI stderr read error: %v", err)
-		return
+		return ""
 	}
 	if len(stderrBytes) > 0 {
 		utils.Error("Claude CLI stderr: %s", string(stderrBytes))
 		fmt.Fprintf(logFile, "\nERROR: %s\n", string(stderrBytes))
 	}
+	return string(stderrBytes)
 }
 
 // loggingReader wraps a reader and logs its output
 type loggingReader struct {
 	reader  io.Reader
-	logFile *os.File
-	cmd     *exec.Cmd
+	logFile *utils.ChatLog
+	cmd     *providerCommand
 	buffer  []byte
 }
 
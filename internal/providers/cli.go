@@ -0,0 +1,214 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/utils"
+)
+
+// CLIProvider implements the AIProvider interface for an arbitrary CLI declared in the providers file.
+// The prompt is written to the command's stdin and stdout is streamed back as the response.
+type CLIProvider struct {
+	config    ProviderConfig
+	logDir    string
+	timeout   time.Duration
+	envPolicy EnvPolicy
+	sandbox   Sandbox
+}
+
+// NewCLIProvider creates a new generic CLI provider instance. Only server environment
+// variables passing envPolicy (plus the provider's env_allow/env_deny) are inherited.
+func NewCLIProvider(config ProviderConfig, logDir string, envPolicy EnvPolicy) *CLIProvider {
+	timeout, err := config.TimeoutDuration()
+	if err != nil {
+		timeout = DefaultProviderTimeout
+	}
+	return &CLIProvider{
+		config:    config,
+		logDir:    logDir,
+		timeout:   timeout,
+		envPolicy: envPolicy.With(config.EnvAllow, config.EnvDeny),
+		sandbox:   config.Sandbox,
+	}
+}
+
+// GetConfig implements ConfiguredProvider
+func (p *CLIProvider) GetConfig() ProviderConfig {
+	return p.config
+}
+
+// SetSandbox implements SandboxedProvider
+func (p *CLIProvider) SetSandbox(sandbox Sandbox) {
+	p.sandbox = sandbox
+}
+
+func (p *CLIProvider) GetID() string {
+	return p.config.ID
+}
+
+func (p *CLIProvider) GetName() string {
+	return p.config.Name
+}
+
+func (p *CLIProvider) GetDescription() string {
+	return p.config.Description
+}
+
+func (p *CLIProvider) GetBranding() ProviderBranding {
+	return p.config.Branding()
+}
+
+func (p *CLIProvider) IsAvailable() bool {
+	_, err := exec.LookPath(p.config.Command)
+	return err == nil
+}
+
+func (p *CLIProvider) GetStatus() ProviderStatus {
+	path, err := exec.LookPath(p.config.Command)
+	if err != nil {
+		return ProviderStatus{
+			Available:    false,
+			Status:       "not_installed",
+			Details:      fmt.Sprintf("%s not found at '%s'", p.config.Name, p.config.Command),
+			Capabilities: p.config.Capabilities,
+		}
+	}
+
+	return ProviderStatus{
+		Available:    true,
+		Status:       "ready",
+		Details:      fmt.Sprintf("%s is available at %s", p.config.Name, path),
+		Capabilities: p.config.Capabilities,
+	}
+}
+
+func (p *CLIProvider) GetCapabilities() []string {
+	return p.config.Capabilities
+}
+
+func (p *CLIProvider) GetModels() []Model {
+	return p.config.ModelList()
+}
+
+// newCommand builds the command for a prompt with the configured args and environment.
+// Attachments are passed with attachment_arg when configured and listed after the prompt otherwise.
+func (p *CLIProvider) newCommand(ctx context.Context, prompt string) *providerCommand {
+	args := append([]string{}, p.config.Args...)
+	if model := ModelFromContext(ctx); model != "" && p.config.ModelArg != "" {
+		args = append(args, p.config.ModelArg, model)
+	}
+	if attachments := AttachmentsFromContext(ctx); p.config.AttachmentArg != "" {
+		for _, a := range attachments {
+			args = append(args, p.config.AttachmentArg, a.Path)
+		}
+	} else {
+		prompt = AppendAttachmentReferences(prompt, attachments, "")
+	}
+
+	cmd := newProviderCommand(ctx, p.config.ID, p.sandbox, p.config.Command, args...)
+	cmd.Stdin = strings.NewReader(prompt)
+	cmd.Env = append(p.envPolicy.Environ(),
+		"CI=true",
+		"TERM=dumb",
+		"NO_COLOR=1",
+	)
+	cmd.Env = append(cmd.Env, p.config.expandedEnv()...)
+	return cmd
+}
+
+func (p *CLIProvider) SendPrompt(ctx context.Context, prompt string, chatID int64) (io.ReadCloser, error) {
+	logFile, err := p.openLog(chatID, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := p.newCommand(ctx, prompt)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		logFile.Close()
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return nil, fmt.Errorf("failed to start %s: %w", p.config.Command, err)
+	}
+
+	return &cliReader{reader: io.TeeReader(stdout, logFile), logFile: logFile, cmd: cmd}, nil
+}
+
+func (p *CLIProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	logFile, err := p.openLog(chatID, prompt)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	var stderr bytes.Buffer
+	cmd := p.newCommand(ctx, prompt)
+	sanitizer := NewSanitizer(io.MultiWriter(writer, logFile))
+	cmd.Stdout = sanitizer
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if flushErr := sanitizer.Flush(); err == nil {
+		err = flushErr
+	}
+	fmt.Fprintf(logFile, "\n")
+
+	if stderr.Len() > 0 {
+		utils.Error("%s stderr: %s", p.config.ID, stderr.String())
+		fmt.Fprintf(logFile, "ERROR: %s\n", stderr.String())
+	}
+
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s timed out after %v", p.config.ID, p.timeout)
+		}
+		return fmt.Errorf("%s failed: %w", p.config.ID, err)
+	}
+
+	return nil
+}
+
+// openLog opens the per-chat log file and records the prompt
+func (p *CLIProvider) openLog(chatID int64, prompt string) (*utils.ChatLog, error) {
+	logPath := fmt.Sprintf("%s/%s/chat_%d.log", p.logDir, p.config.ID, chatID)
+	logFile, err := utils.CreateChatLog(logPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(logFile, "USER: %s\n", prompt)
+	fmt.Fprintf(logFile, "ASSISTANT: ")
+	return logFile, nil
+}
+
+// cliReader streams a command's stdout and waits for the process on Close
+type cliReader struct {
+	reader  io.Reader
+	logFile *utils.ChatLog
+	cmd     *providerCommand
+}
+
+func (r *cliReader) Read(p []byte) (int, error) {
+	return r.reader.Read(p)
+}
+
+func (r *cliReader) Close() error {
+	defer r.logFile.Close()
+	fmt.Fprintf(r.logFile, "\n")
+	if err := r.cmd.Wait(); err != nil {
+		utils.Error("CLI provider wait error: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,382 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/secrets"
+	"ai-gateway-hub/internal/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Provider types supported in the providers file
+const (
+	ProviderTypeCLI    = "cli"
+	ProviderTypeHTTP   = "http"
+	ProviderTypeClaude = "claude"
+	ProviderTypeRelay  = "relay"
+)
+
+// Default timeout for a single prompt when a provider doesn't declare one
+const DefaultProviderTimeout = 5 * time.Minute
+
+var providerIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ProviderConfig declares a single provider in providers.yaml / providers.json
+type ProviderConfig struct {
+	ID            string            `yaml:"id" json:"id"`
+	Name          string            `yaml:"name" json:"name"`
+	Description   string            `yaml:"description" json:"description"`
+	Type          string            `yaml:"type" json:"type"`         // cli, http, claude or relay
+	Command       string            `yaml:"command" json:"command"`   // executable for cli/claude providers
+	BaseURL       string            `yaml:"base_url" json:"base_url"` // endpoint for http providers, API root for relay providers
+	Args          []string          `yaml:"args" json:"args"`
+	Env           map[string]string `yaml:"env" json:"env"`             // values may reference ${VARS} and ${scheme:secret} references
+	EnvAllow      []string          `yaml:"env_allow" json:"env_allow"` // extra server variables to pass through (glob patterns)
+	EnvDeny       []string          `yaml:"env_deny" json:"env_deny"`   // server variables never passed through (glob patterns)
+	Headers       map[string]string `yaml:"headers" json:"headers"`     // values may reference ${VARS}
+	Timeout       string            `yaml:"timeout" json:"timeout"`     // Go duration, e.g. "90s" or "5m"
+	IconURL       string            `yaml:"icon_url" json:"icon_url"`
+	Color         string            `yaml:"color" json:"color"`
+	Models        []string          `yaml:"models" json:"models"`                 // selectable models; the first is the default
+	ModelArg      string            `yaml:"model_arg" json:"model_arg"`           // cli flag that selects a model, e.g. "--model" or "-m"
+	AttachmentArg string            `yaml:"attachment_arg" json:"attachment_arg"` // cli flag passing an attached file, e.g. "--file"
+	Capabilities  []string          `yaml:"capabilities" json:"capabilities"`     // optional features, e.g. ["vision"]
+	ModelMap      map[string]string `yaml:"model_map" json:"model_map"`           // relay model names by listed model, e.g. {"fast": "openai/gpt-4o-mini"}
+	Sandbox       Sandbox           `yaml:"sandbox,omitempty" json:"sandbox"`     // overrides the server-wide sandbox for cli/claude providers
+
+	resolved map[string]string // secret values by reference, filled by ResolveSecrets
+}
+
+// SecretResolver resolves secret references such as "file:/run/secrets/key" or "vault:secret/data/ai#key"
+type SecretResolver interface {
+	Resolve(ctx context.Context, reference string) (string, error)
+}
+
+// ConfiguredProvider is implemented by providers that can describe their configuration
+type ConfiguredProvider interface {
+	GetConfig() ProviderConfig
+}
+
+// MaskedValue replaces credential values in masked configurations
+const MaskedValue = "********"
+
+// ProvidersFile is the top-level structure of the providers file
+type ProvidersFile struct {
+	Providers []ProviderConfig `yaml:"providers" json:"providers"`
+}
+
+// LoadProvidersFile reads and validates a providers file in YAML or JSON format
+func LoadProvidersFile(path string) ([]ProviderConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read providers file %s: %w", path, err)
+	}
+
+	var file ProvidersFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &file)
+	default:
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse providers file %s: %w", path, err)
+	}
+
+	if err := ValidateProviders(file.Providers); err != nil {
+		return nil, err
+	}
+	return file.Providers, nil
+}
+
+// ValidateProviders validates each declaration in place and rejects duplicate IDs
+func ValidateProviders(configs []ProviderConfig) error {
+	seen := make(map[string]bool)
+	for i := range configs {
+		pc := &configs[i]
+		if err := pc.Validate(); err != nil {
+			return fmt.Errorf("providers[%d]: %w", i, err)
+		}
+		if seen[pc.ID] {
+			return fmt.Errorf("providers[%d]: duplicate provider id %q", i, pc.ID)
+		}
+		seen[pc.ID] = true
+	}
+	return nil
+}
+
+// WriteProvidersFile replaces the providers file, in JSON or YAML depending on its extension
+func WriteProvidersFile(path string, configs []ProviderConfig) error {
+	file := ProvidersFile{Providers: configs}
+	if file.Providers == nil {
+		file.Providers = []ProviderConfig{}
+	}
+
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err = json.MarshalIndent(file, "", "  ")
+	default:
+		data, err = yaml.Marshal(file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode providers file: %w", err)
+	}
+
+	// Write to a temporary file first so the file watcher never sees a partial file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write providers file %s: %w", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace providers file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks a provider declaration and fills in defaults
+func (pc *ProviderConfig) Validate() error {
+	if !providerIDPattern.MatchString(pc.ID) {
+		return fmt.Errorf("invalid provider id %q: use lowercase letters, digits, '-' and '_'", pc.ID)
+	}
+
+	if pc.Name == "" {
+		pc.Name = pc.ID
+	}
+
+	if pc.Type == "" {
+		pc.Type = ProviderTypeCLI
+	}
+
+	switch pc.Type {
+	case ProviderTypeCLI, ProviderTypeClaude:
+		if pc.Command == "" {
+			return fmt.Errorf("provider %s: command is required for type %s", pc.ID, pc.Type)
+		}
+	case ProviderTypeHTTP, ProviderTypeRelay:
+		u, err := url.Parse(pc.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("provider %s: base_url must be an http(s) URL", pc.ID)
+		}
+	default:
+		return fmt.Errorf("provider %s: unsupported type %q (supported: cli, http, claude, relay)", pc.ID, pc.Type)
+	}
+
+	if _, err := pc.TimeoutDuration(); err != nil {
+		return fmt.Errorf("provider %s: %w", pc.ID, err)
+	}
+
+	for _, model := range pc.Models {
+		if model == "" || strings.HasPrefix(model, "-") {
+			return fmt.Errorf("provider %s: invalid model %q", pc.ID, model)
+		}
+	}
+	if pc.Type == ProviderTypeCLI && len(pc.Models) > 0 && pc.ModelArg == "" {
+		return fmt.Errorf("provider %s: model_arg is required when models are listed", pc.ID)
+	}
+	if pc.Type == ProviderTypeRelay && len(pc.Models) == 0 {
+		return fmt.Errorf("provider %s: relay providers need at least one model", pc.ID)
+	}
+	if len(pc.ModelMap) > 0 && pc.Type != ProviderTypeRelay {
+		return fmt.Errorf("provider %s: model_map is only supported by relay providers", pc.ID)
+	}
+	for model, upstream := range pc.ModelMap {
+		if !containsString(pc.Models, model) {
+			return fmt.Errorf("provider %s: model_map entry %q is not a listed model", pc.ID, model)
+		}
+		if upstream == "" {
+			return fmt.Errorf("provider %s: model_map entry %q has no target", pc.ID, model)
+		}
+	}
+
+	if err := pc.Sandbox.Validate(); err != nil {
+		return fmt.Errorf("provider %s: %w", pc.ID, err)
+	}
+
+	for _, capability := range pc.Capabilities {
+		if !isKnownCapability(capability) {
+			return fmt.Errorf("provider %s: unknown capability %q (supported: %s)", pc.ID, capability, strings.Join(KnownCapabilities, ", "))
+		}
+	}
+
+	return nil
+}
+
+// isKnownCapability reports whether a declared capability is one of KnownCapabilities
+func isKnownCapability(capability string) bool {
+	return containsString(KnownCapabilities, capability)
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// TimeoutDuration returns the per-prompt timeout, defaulting to DefaultProviderTimeout
+func (pc *ProviderConfig) TimeoutDuration() (time.Duration, error) {
+	if pc.Timeout == "" {
+		return DefaultProviderTimeout, nil
+	}
+	d, err := time.ParseDuration(pc.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q", pc.Timeout)
+	}
+	return d, nil
+}
+
+// Branding returns the branding declared for this provider
+func (pc *ProviderConfig) Branding() ProviderBranding {
+	return ProviderBranding{IconURL: pc.IconURL, Color: pc.Color}
+}
+
+// ModelList returns the declared models, marking the first one as the default
+func (pc *ProviderConfig) ModelList() []Model {
+	models := make([]Model, 0, len(pc.Models))
+	for i, id := range pc.Models {
+		models = append(models, Model{ID: id, Default: i == 0})
+	}
+	return models
+}
+
+// UpstreamModel returns the name a relay provider sends for a model, defaulting to the first listed model.
+// Models without a model_map entry are sent unchanged.
+func (pc *ProviderConfig) UpstreamModel(model string) string {
+	if model == "" && len(pc.Models) > 0 {
+		model = pc.Models[0]
+	}
+	if upstream, ok := pc.ModelMap[model]; ok {
+		return upstream
+	}
+	return model
+}
+
+// expandedEnv returns the declared environment as KEY=value pairs with ${VARS} expanded
+func (pc *ProviderConfig) expandedEnv() []string {
+	env := make([]string, 0, len(pc.Env))
+	for k, v := range pc.Env {
+		env = append(env, k+"="+pc.expand(v))
+	}
+	return env
+}
+
+// expand replaces ${scheme:reference} with resolved secrets and ${VARS} with server environment variables
+func (pc *ProviderConfig) expand(value string) string {
+	return os.Expand(value, func(name string) string {
+		if secrets.IsReference(name) {
+			return pc.resolved[name]
+		}
+		return os.Getenv(name)
+	})
+}
+
+// ResolveSecrets reads the secrets referenced in env and headers, failing on the first that can't
+// be read. Values of ${VARS} are registered for redaction as well; unset ones are logged.
+func (pc *ProviderConfig) ResolveSecrets(ctx context.Context, resolver SecretResolver) error {
+	resolved := make(map[string]string)
+	var firstErr error
+	visit := func(value string) {
+		os.Expand(value, func(name string) string {
+			if !secrets.IsReference(name) {
+				if v, ok := os.LookupEnv(name); ok {
+					utils.RegisterSecret(v)
+				} else {
+					utils.Warn("Provider %s references unset environment variable %s", pc.ID, name)
+				}
+				return ""
+			}
+			if _, done := resolved[name]; done || firstErr != nil {
+				return ""
+			}
+			v, err := resolver.Resolve(ctx, name)
+			if err != nil {
+				firstErr = fmt.Errorf("provider %s: %w", pc.ID, err)
+				return ""
+			}
+			resolved[name] = v
+			return ""
+		})
+	}
+	for _, v := range pc.Env {
+		visit(v)
+	}
+	for _, v := range pc.Headers {
+		visit(v)
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	pc.resolved = resolved
+	return nil
+}
+
+// Masked returns a copy safe to show: env and header values that aren't references are masked,
+// URL passwords removed and registered secrets redacted
+func (pc ProviderConfig) Masked() ProviderConfig {
+	masked := pc
+	masked.resolved = nil
+	masked.Env = maskValues(pc.Env)
+	masked.Headers = maskValues(pc.Headers)
+	if u, err := url.Parse(pc.BaseURL); err == nil && pc.BaseURL != "" {
+		masked.BaseURL = utils.Redact(u.Redacted())
+	}
+	masked.Args = make([]string, len(pc.Args))
+	for i, arg := range pc.Args {
+		masked.Args[i] = utils.Redact(arg)
+	}
+	return masked
+}
+
+// maskValues masks literal values, which may be credentials; references only name where a secret lives
+func maskValues(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(values))
+	for k, v := range values {
+		if strings.Contains(v, "${") {
+			masked[k] = utils.Redact(v)
+		} else {
+			masked[k] = MaskedValue
+		}
+	}
+	return masked
+}
+
+// NewProviderFromConfig builds a provider instance from its declaration.
+// envPolicy is the server-wide environment passthrough policy for subprocesses.
+func NewProviderFromConfig(pc ProviderConfig, logDir string, envPolicy EnvPolicy) (AIProvider, error) {
+	switch pc.Type {
+	case ProviderTypeCLI:
+		return NewCLIProvider(pc, logDir, envPolicy), nil
+	case ProviderTypeHTTP:
+		return NewHTTPProvider(pc, logDir), nil
+	case ProviderTypeRelay:
+		return NewRelayProvider(pc, logDir), nil
+	case ProviderTypeClaude:
+		provider := NewClaudeProvider(pc.Command, logDir, false, strings.Join(pc.Args, " "))
+		provider.SetEnvPolicy(envPolicy.With(ClaudeEnvAllowlist, nil).With(pc.EnvAllow, pc.EnvDeny))
+		provider.SetSandbox(pc.Sandbox)
+		if len(pc.Models) > 0 {
+			provider.models = pc.ModelList()
+		}
+		return provider, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider type %q", pc.Type)
+	}
+}
@@ -0,0 +1,68 @@
+package providers
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// DefaultEnvAllowlist are the server environment variables every provider subprocess inherits.
+// Patterns use shell glob syntax, e.g. "LC_*".
+var DefaultEnvAllowlist = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TMPDIR", "TZ",
+	"LANG", "LC_*", "XDG_*",
+	"SSL_CERT_FILE", "SSL_CERT_DIR",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+}
+
+// ClaudeEnvAllowlist are the extra variables the Claude CLI needs for authentication and settings
+var ClaudeEnvAllowlist = []string{"ANTHROPIC_*", "CLAUDE_*"}
+
+// DefaultEnvPolicy passes only DefaultEnvAllowlist through to provider subprocesses
+var DefaultEnvPolicy = EnvPolicy{Allow: DefaultEnvAllowlist}
+
+// EnvPolicy decides which server environment variables a provider subprocess inherits.
+// A variable is passed through if it matches an Allow pattern and no Deny pattern.
+type EnvPolicy struct {
+	Allow []string
+	Deny  []string
+}
+
+// With returns a copy of the policy with additional allow and deny patterns
+func (p EnvPolicy) With(allow, deny []string) EnvPolicy {
+	return EnvPolicy{
+		Allow: append(append([]string{}, p.Allow...), allow...),
+		Deny:  append(append([]string{}, p.Deny...), deny...),
+	}
+}
+
+// Allows reports whether a variable name passes the policy
+func (p EnvPolicy) Allows(name string) bool {
+	return !matchesAnyEnvPattern(p.Deny, name) && matchesAnyEnvPattern(p.Allow, name)
+}
+
+// Filter returns the KEY=value pairs of environ that pass the policy
+func (p EnvPolicy) Filter(environ []string) []string {
+	filtered := make([]string, 0, len(p.Allow))
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if p.Allows(name) {
+			filtered = append(filtered, kv)
+		}
+	}
+	return filtered
+}
+
+// Environ returns the filtered server environment
+func (p EnvPolicy) Environ() []string {
+	return p.Filter(os.Environ())
+}
+
+func matchesAnyEnvPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
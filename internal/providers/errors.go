@@ -0,0 +1,60 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"syscall"
+)
+
+// Classes of provider errors, to decide which are worth retrying
+const (
+	ErrorClassNetwork   = "network"    // the connection was refused, reset or dropped
+	ErrorClassRateLimit = "rate_limit" // HTTP 429
+	ErrorClassServer    = "server"     // HTTP 408 or 5xx
+	ErrorClassCrash     = "crash"      // the CLI exited with an error
+)
+
+// ErrorClasses lists every error class
+var ErrorClasses = []string{ErrorClassNetwork, ErrorClassRateLimit, ErrorClassServer, ErrorClassCrash}
+
+// StatusError is an unsuccessful HTTP response of a provider's API
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Status     string
+	Detail     string // start of the response body, redacted
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned %s: %s", e.Provider, e.Status, e.Detail)
+}
+
+// ClassifyError returns the class of a provider error, or "" for errors outside every class such as
+// cancellations and timeouts
+func ClassifyError(err error) string {
+	var statusErr *StatusError
+	var exitErr *exec.ExitError
+	var netErr net.Error
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ""
+	case errors.As(err, &statusErr):
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			return ErrorClassRateLimit
+		case statusErr.StatusCode == http.StatusRequestTimeout, statusErr.StatusCode >= 500:
+			return ErrorClassServer
+		}
+		return ""
+	case errors.As(err, &exitErr):
+		return ErrorClassCrash
+	case errors.As(err, &netErr), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorClassNetwork
+	}
+	return ""
+}
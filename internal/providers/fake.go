@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeReply is how a FakeProvider answers one prompt: Chunks are written one by one, each after
+// Delay, and Err is returned once they were written
+type FakeReply struct {
+	Chunks []string
+	Delay  time.Duration
+	Err    error
+}
+
+// FakeProvider is a scripted provider. Each prompt gets the next queued reply, or DefaultReply once
+// the script ran out, so tests control latency, failures and how responses are streamed. With
+// ENABLE_FAKE_PROVIDER the server registers one as "fake" for load tests.
+type FakeProvider struct {
+	ID           string
+	Unavailable  bool
+	DefaultReply FakeReply
+
+	mu      sync.Mutex
+	script  []FakeReply
+	prompts []string
+}
+
+// NewFakeProvider returns an available provider answering "Fake response" until scripted
+func NewFakeProvider(id string) *FakeProvider {
+	return &FakeProvider{ID: id, DefaultReply: FakeReply{Chunks: []string{"Fake response"}}}
+}
+
+// FakeLoadReply is a synthetic response of chunks written every delay
+func FakeLoadReply(chunks int, delay time.Duration) FakeReply {
+	reply := FakeReply{Delay: delay}
+	for i := 1; i <= chunks; i++ {
+		reply.Chunks = append(reply.Chunks, fmt.Sprintf("chunk %d ", i))
+	}
+	return reply
+}
+
+// Script queues replies for the next prompts
+func (p *FakeProvider) Script(replies ...FakeReply) *FakeProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.script = append(p.script, replies...)
+	return p
+}
+
+// Prompts returns the prompts the provider was sent, in order
+func (p *FakeProvider) Prompts() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.prompts...)
+}
+
+// nextReply records a prompt and takes the reply to it
+func (p *FakeProvider) nextReply(prompt string) FakeReply {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = append(p.prompts, prompt)
+	if len(p.script) == 0 {
+		return p.DefaultReply
+	}
+	reply := p.script[0]
+	p.script = p.script[1:]
+	return reply
+}
+
+func (p *FakeProvider) GetID() string {
+	return p.ID
+}
+
+func (p *FakeProvider) GetName() string {
+	return "Fake " + p.ID
+}
+
+func (p *FakeProvider) GetDescription() string {
+	return "Scripted provider for tests"
+}
+
+func (p *FakeProvider) IsAvailable() bool {
+	return !p.Unavailable
+}
+
+func (p *FakeProvider) GetStatus() ProviderStatus {
+	if p.Unavailable {
+		return ProviderStatus{Available: false, Status: "error", Details: "Fake provider marked unavailable"}
+	}
+	return ProviderStatus{Available: true, Status: "ready", Version: "fake"}
+}
+
+func (p *FakeProvider) GetModels() []Model {
+	return nil
+}
+
+func (p *FakeProvider) SendPrompt(ctx context.Context, prompt string, chatID int64) (io.ReadCloser, error) {
+	var b strings.Builder
+	if err := p.StreamResponse(ctx, prompt, chatID, &b); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader(b.String())), nil
+}
+
+// StreamResponse plays the next reply, stopping early when ctx is done like a real provider process
+func (p *FakeProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	reply := p.nextReply(prompt)
+	for _, chunk := range reply.Chunks {
+		if reply.Delay > 0 {
+			select {
+			case <-time.After(reply.Delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if _, err := writer.Write([]byte(chunk)); err != nil {
+			return err
+		}
+	}
+	return reply.Err
+}
@@ -0,0 +1,252 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"ai-gateway-hub/internal/utils"
+)
+
+// GHModelsProviderID identifies the GitHub Models provider
+const GHModelsProviderID = "gh-models"
+
+// GHModels are the models offered when GH_MODELS isn't set; see `gh models list` for more
+var GHModels = []string{"openai/gpt-4.1", "openai/gpt-4o-mini", "meta/Llama-4-Scout-17B-16E-Instruct"}
+
+// GHEnvAllowlist are the extra variables gh needs for authentication and settings
+var GHEnvAllowlist = []string{"GH_*", "GITHUB_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+
+// GHModelsProvider implements the AIProvider interface for `gh models run`, the GitHub Models
+// extension of the GitHub CLI. It uses the credentials gh is already logged in with.
+type GHModelsProvider struct {
+	cliPath   string
+	logDir    string
+	models    []Model
+	envPolicy EnvPolicy
+	sandbox   Sandbox
+}
+
+// NewGHModelsProvider creates a new GitHub Models provider; the first model is the default
+func NewGHModelsProvider(cliPath, logDir string, models []string) *GHModelsProvider {
+	if len(models) == 0 {
+		models = GHModels
+	}
+	pc := ProviderConfig{Models: models}
+	return &GHModelsProvider{
+		cliPath:   cliPath,
+		logDir:    logDir,
+		models:    pc.ModelList(),
+		envPolicy: DefaultEnvPolicy.With(GHEnvAllowlist, nil),
+	}
+}
+
+// SetEnvPolicy replaces the policy deciding which server environment variables gh inherits
+func (p *GHModelsProvider) SetEnvPolicy(policy EnvPolicy) {
+	p.envPolicy = policy
+}
+
+// GetConfig implements ConfiguredProvider, describing the provider as the cli entry it amounts to
+func (p *GHModelsProvider) GetConfig() ProviderConfig {
+	models := make([]string, 0, len(p.models))
+	for _, m := range p.models {
+		models = append(models, m.ID)
+	}
+	return ProviderConfig{
+		ID:          p.GetID(),
+		Name:        p.GetName(),
+		Description: p.GetDescription(),
+		Type:        ProviderTypeCLI,
+		Command:     p.cliPath,
+		Args:        []string{"models", "run"},
+		Models:      models,
+		Sandbox:     p.sandbox,
+	}
+}
+
+// SetSandbox implements SandboxedProvider
+func (p *GHModelsProvider) SetSandbox(sandbox Sandbox) {
+	p.sandbox = sandbox
+}
+
+func (p *GHModelsProvider) GetID() string {
+	return GHModelsProviderID
+}
+
+func (p *GHModelsProvider) GetName() string {
+	return "GitHub Models"
+}
+
+func (p *GHModelsProvider) GetDescription() string {
+	return "Models from GitHub Models via the gh CLI"
+}
+
+func (p *GHModelsProvider) GetBranding() ProviderBranding {
+	return ProviderBranding{
+		IconURL: ProviderIconBasePath + "/github.svg",
+		Color:   "#24292F",
+	}
+}
+
+func (p *GHModelsProvider) GetModels() []Model {
+	return p.models
+}
+
+func (p *GHModelsProvider) IsAvailable() bool {
+	return p.GetStatus().Available
+}
+
+// GetStatus checks that gh is installed and has the models extension
+func (p *GHModelsProvider) GetStatus() ProviderStatus {
+	output, err := p.command("--version").CombinedOutput()
+	if err != nil {
+		var execErr *exec.Error
+		if errors.As(err, &execErr) || strings.Contains(err.Error(), "no such file or directory") {
+			return ProviderStatus{Status: "not_installed", Details: fmt.Sprintf("GitHub CLI not found at '%s'", p.cliPath)}
+		}
+		return ProviderStatus{Status: "error", Details: fmt.Sprintf("GitHub CLI error: %v", err)}
+	}
+	version, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+
+	if err := p.command("models", "--help").Run(); err != nil {
+		return ProviderStatus{
+			Status:  "not_configured",
+			Version: version,
+			Details: "gh models extension not installed; run `gh extension install github/gh-models`",
+		}
+	}
+
+	return ProviderStatus{
+		Available: true,
+		Status:    "ready",
+		Version:   version,
+		Details:   "GitHub CLI with the models extension is available",
+	}
+}
+
+// command creates a quick gh invocation for status checks
+func (p *GHModelsProvider) command(args ...string) *exec.Cmd {
+	cmd := exec.Command(p.cliPath, args...)
+	cmd.Env = p.environ()
+	return cmd
+}
+
+// environ returns the allowlisted environment with gh's prompts and colors turned off
+func (p *GHModelsProvider) environ() []string {
+	return append(p.envPolicy.Environ(),
+		"GH_PROMPT_DISABLED=1",
+		"GH_NO_UPDATE_NOTIFIER=1",
+		"NO_COLOR=1",
+		"TERM=dumb",
+	)
+}
+
+// buildArgs selects the requested model, or the default one
+func (p *GHModelsProvider) buildArgs(ctx context.Context) []string {
+	model := ModelFromContext(ctx)
+	if model == "" && len(p.models) > 0 {
+		model = p.models[0].ID
+	}
+	return []string{"models", "run", model}
+}
+
+// start runs `gh models run` with the prompt on stdin
+func (p *GHModelsProvider) start(ctx context.Context, prompt string) (*providerCommand, io.ReadCloser, io.ReadCloser, error) {
+	cmd := newProviderCommand(ctx, p.GetID(), p.sandbox, p.cliPath, p.buildArgs(ctx)...)
+	cmd.Stdin = strings.NewReader(prompt)
+	cmd.Env = p.environ()
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to start gh models: %w", err)
+	}
+	return cmd, stdout, stderr, nil
+}
+
+func (p *GHModelsProvider) SendPrompt(ctx context.Context, prompt string, chatID int64) (io.ReadCloser, error) {
+	logPath := fmt.Sprintf("%s/%s/chat_%d.log", p.logDir, p.GetID(), chatID)
+	logFile, err := utils.CreateChatLog(logPath)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(logFile, "USER: %s\n", prompt)
+
+	cmd, stdout, stderr, err := p.start(ctx, prompt)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	go io.Copy(io.Discard, stderr)
+
+	return &ghModelsReader{loggingReader: loggingReader{reader: stdout, logFile: logFile, cmd: cmd}}, nil
+}
+
+// StreamResponse streams the gh models output to the provided writer
+func (p *GHModelsProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	logPath := fmt.Sprintf("%s/%s/chat_%d.log", p.logDir, p.GetID(), chatID)
+	logFile, err := utils.CreateChatLog(logPath)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	fmt.Fprintf(logFile, "USER: %s\nASSISTANT: ", prompt)
+
+	cmd, stdout, stderr, err := p.start(ctx, prompt)
+	if err != nil {
+		fmt.Fprintf(logFile, "\nERROR: %v\n", err)
+		return err
+	}
+
+	var wg sync.WaitGroup
+	var stderrOutput bytes.Buffer
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		io.Copy(&stderrOutput, stderr)
+	}()
+
+	sanitizer := NewSanitizer(io.MultiWriter(writer, logFile))
+	_, copyErr := io.Copy(sanitizer, stdout)
+	if copyErr == nil {
+		copyErr = sanitizer.Flush()
+	}
+	wg.Wait()
+	fmt.Fprintf(logFile, "\n")
+
+	if err := cmd.Wait(); err != nil {
+		detail := strings.TrimSpace(stderrOutput.String())
+		fmt.Fprintf(logFile, "ERROR: %s\n", detail)
+		if detail != "" {
+			return fmt.Errorf("gh models failed: %w: %s", err, detail)
+		}
+		return fmt.Errorf("gh models failed: %w", err)
+	}
+	if copyErr != nil {
+		return fmt.Errorf("failed to copy output: %w", copyErr)
+	}
+	return nil
+}
+
+// ghModelsReader closes the log file once the response has been read
+type ghModelsReader struct {
+	loggingReader
+}
+
+func (r *ghModelsReader) Close() error {
+	err := r.loggingReader.Close()
+	r.logFile.Close()
+	return err
+}
@@ -0,0 +1,186 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/utils"
+)
+
+// HTTPProvider implements the AIProvider interface for an HTTP endpoint declared in the providers file.
+// It POSTs {"prompt": ..., "chat_id": ..., "model": ...} as JSON to base_url and streams the response body back.
+type HTTPProvider struct {
+	config  ProviderConfig
+	logDir  string
+	timeout time.Duration
+	client  *http.Client
+}
+
+// NewHTTPProvider creates a new generic HTTP provider instance
+func NewHTTPProvider(config ProviderConfig, logDir string) *HTTPProvider {
+	timeout, err := config.TimeoutDuration()
+	if err != nil {
+		timeout = DefaultProviderTimeout
+	}
+	return &HTTPProvider{
+		config:  config,
+		logDir:  logDir,
+		timeout: timeout,
+		client:  &http.Client{},
+	}
+}
+
+func (p *HTTPProvider) GetID() string {
+	return p.config.ID
+}
+
+func (p *HTTPProvider) GetName() string {
+	return p.config.Name
+}
+
+func (p *HTTPProvider) GetDescription() string {
+	return p.config.Description
+}
+
+func (p *HTTPProvider) GetBranding() ProviderBranding {
+	return p.config.Branding()
+}
+
+func (p *HTTPProvider) IsAvailable() bool {
+	return p.GetStatus().Available
+}
+
+func (p *HTTPProvider) GetStatus() ProviderStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.BaseURL, nil)
+	if err != nil {
+		return ProviderStatus{Status: "not_configured", Details: fmt.Sprintf("Invalid base URL: %v", err), Capabilities: p.config.Capabilities}
+	}
+	p.setHeaders(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return ProviderStatus{Status: "error", Details: fmt.Sprintf("%s is unreachable", p.config.Name), Capabilities: p.config.Capabilities}
+	}
+	resp.Body.Close()
+
+	// Any non-5xx answer means the endpoint is up; many APIs reject GET on the prompt route
+	if resp.StatusCode >= 500 {
+		return ProviderStatus{Status: "error", Details: fmt.Sprintf("%s returned %s", p.config.Name, resp.Status), Capabilities: p.config.Capabilities}
+	}
+
+	return ProviderStatus{
+		Available:    true,
+		Status:       "ready",
+		Details:      fmt.Sprintf("%s is reachable", p.config.Name),
+		Capabilities: p.config.Capabilities,
+	}
+}
+
+func (p *HTTPProvider) GetModels() []Model {
+	return p.config.ModelList()
+}
+
+func (p *HTTPProvider) GetCapabilities() []string {
+	return p.config.Capabilities
+}
+
+// GetConfig implements ConfiguredProvider
+func (p *HTTPProvider) GetConfig() ProviderConfig {
+	return p.config
+}
+
+// setHeaders applies the configured headers with ${VARS} and secret references expanded
+func (p *HTTPProvider) setHeaders(req *http.Request) {
+	for k, v := range p.config.Headers {
+		req.Header.Set(k, p.config.expand(v))
+	}
+}
+
+// post sends the prompt to the endpoint and returns the response on success
+func (p *HTTPProvider) post(ctx context.Context, prompt string, chatID int64) (*http.Response, error) {
+	payload := map[string]any{
+		"prompt":  prompt,
+		"chat_id": chatID,
+	}
+	if model := ModelFromContext(ctx); model != "" {
+		payload["model"] = model
+	}
+	if HasCapability(p, CapabilityVision) {
+		images, err := encodeImages(AttachmentsFromContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if len(images) > 0 {
+			payload["images"] = images
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.BaseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.setHeaders(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", p.config.ID, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &StatusError{Provider: p.config.ID, StatusCode: resp.StatusCode, Status: resp.Status, Detail: utils.Redact(strings.TrimSpace(string(detail)))}
+	}
+
+	return resp, nil
+}
+
+func (p *HTTPProvider) SendPrompt(ctx context.Context, prompt string, chatID int64) (io.ReadCloser, error) {
+	resp, err := p.post(ctx, prompt, chatID)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (p *HTTPProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	logPath := fmt.Sprintf("%s/%s/chat_%d.log", p.logDir, p.config.ID, chatID)
+	logFile, err := utils.CreateChatLog(logPath)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	fmt.Fprintf(logFile, "USER: %s\nASSISTANT: ", prompt)
+
+	resp, err := p.post(ctx, prompt, chatID)
+	if err != nil {
+		fmt.Fprintf(logFile, "\nERROR: %v\n", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(io.MultiWriter(writer, logFile), resp.Body); err != nil {
+		return fmt.Errorf("failed to read %s response: %w", p.config.ID, err)
+	}
+	fmt.Fprintf(logFile, "\n")
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package providers
+
+import (
+	"context"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProcessWaitDelay bounds how long Wait keeps stdout/stderr open after a cancelled
+// provider process exits, e.g. when a process outside its group still holds the pipes
+const ProcessWaitDelay = 2 * time.Second
+
+// providerCommand is an exec.Cmd whose cancellation kills the provider's whole process
+// group and records how long the processes took to go away
+type providerCommand struct {
+	*exec.Cmd
+	providerID string
+
+	mu          sync.Mutex
+	cancelledAt time.Time
+}
+
+// newProviderCommand creates a command that runs inside the sandbox in its own process group and
+// is killed with it when ctx is done
+func newProviderCommand(ctx context.Context, providerID string, sandbox Sandbox, name string, args ...string) *providerCommand {
+	name, args = sandbox.command(name, args...)
+	pc := &providerCommand{Cmd: exec.CommandContext(ctx, name, args...), providerID: providerID}
+	pc.Cmd.Dir = sandbox.workDir()
+	setProcessGroup(pc.Cmd)
+	pc.Cmd.Cancel = func() error {
+		pc.mu.Lock()
+		pc.cancelledAt = time.Now()
+		pc.mu.Unlock()
+		return killProcessGroup(pc.Cmd)
+	}
+	pc.Cmd.WaitDelay = ProcessWaitDelay
+	return pc
+}
+
+// Run starts the command and waits for it
+func (pc *providerCommand) Run() error {
+	if err := pc.Start(); err != nil {
+		return err
+	}
+	return pc.Wait()
+}
+
+// Wait waits for the command and its pipes, recording the cancellation latency if it was cancelled
+func (pc *providerCommand) Wait() error {
+	err := pc.Cmd.Wait()
+
+	pc.mu.Lock()
+	cancelledAt := pc.cancelledAt
+	pc.mu.Unlock()
+	if !cancelledAt.IsZero() {
+		cancellations.observe(pc.providerID, time.Since(cancelledAt))
+	}
+	return err
+}
+
+// CancellationStats summarizes how long cancelled provider processes took to terminate
+type CancellationStats struct {
+	Provider string `json:"provider"`
+	Count    int64  `json:"count"`
+	SumMs    int64  `json:"sum_ms"`
+	MaxMs    int64  `json:"max_ms"`
+}
+
+// cancellationMetrics aggregates cancellation latencies per provider
+type cancellationMetrics struct {
+	mu    sync.Mutex
+	stats map[string]*CancellationStats
+}
+
+var cancellations = &cancellationMetrics{stats: make(map[string]*CancellationStats)}
+
+func (m *cancellationMetrics) observe(providerID string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.stats[providerID]
+	if !ok {
+		stats = &CancellationStats{Provider: providerID}
+		m.stats[providerID] = stats
+	}
+	ms := latency.Milliseconds()
+	stats.Count++
+	stats.SumMs += ms
+	if ms > stats.MaxMs {
+		stats.MaxMs = ms
+	}
+}
+
+// CancellationMetrics returns the cancellation latency stats of every provider, sorted by provider
+func CancellationMetrics() []CancellationStats {
+	cancellations.mu.Lock()
+	defer cancellations.mu.Unlock()
+
+	result := make([]CancellationStats, 0, len(cancellations.stats))
+	for _, stats := range cancellations.stats {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Provider < result[j].Provider })
+	return result
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package providers
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup starts the command as the leader of a new process group
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// killProcessGroup kills the command and every process it spawned in its group
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// limitCommand runs the command through sh, which applies the limits and then execs it in place.
+// Each limit gets its own ulimit call since dash accepts only one per call.
+func limitCommand(limits ResourceLimits, name string, args []string) (string, []string) {
+	script := "ulimit " + strings.Join(limits.ulimitArgs(), " && ulimit ") + ` && exec "$@"`
+	return "/bin/sh", append([]string{"-c", script, "sh", name}, args...)
+}
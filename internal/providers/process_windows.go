@@ -0,0 +1,31 @@
+//go:build windows
+
+package providers
+
+import (
+	"os/exec"
+	"sync"
+
+	"ai-gateway-hub/internal/utils"
+)
+
+// setProcessGroup is a no-op on Windows; child processes are not grouped
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command; WaitDelay bounds how long its children can hold the pipes
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+var warnLimitsOnce sync.Once
+
+// limitCommand leaves the command unchanged: resource limits are only supported on Unix
+func limitCommand(limits ResourceLimits, name string, args []string) (string, []string) {
+	warnLimitsOnce.Do(func() {
+		utils.Warn("Provider resource limits are not supported on Windows and are ignored")
+	})
+	return name, args
+}
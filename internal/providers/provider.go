@@ -7,10 +7,112 @@ import (
 
 // ProviderStatus represents the detailed status of an AI provider
 type ProviderStatus struct {
-	Available bool   `json:"available"`
-	Status    string `json:"status"` // "ready", "not_installed", "not_configured", "error"
-	Version   string `json:"version,omitempty"`
-	Details   string `json:"details,omitempty"`
+	Available    bool     `json:"available"`
+	Status       string   `json:"status"` // "ready", "not_installed", "not_configured", "error"
+	Version      string   `json:"version,omitempty"`
+	Details      string   `json:"details,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"` // optional features such as "vision"
+}
+
+// Capabilities a provider can advertise
+const (
+	CapabilityVision = "vision" // accepts images with the prompt
+)
+
+// KnownCapabilities lists the capabilities providers may declare
+var KnownCapabilities = []string{CapabilityVision}
+
+// ProviderBranding describes how a provider is presented in the UI
+type ProviderBranding struct {
+	IconURL string `json:"icon_url,omitempty"` // URL of an icon served from the static assets
+	Color   string `json:"color,omitempty"`    // Brand color as a CSS hex value
+}
+
+// Static asset location of provider icons
+const ProviderIconBasePath = "/static/images/providers"
+
+// DefaultBranding is used for providers that do not supply their own branding
+var DefaultBranding = ProviderBranding{
+	IconURL: ProviderIconBasePath + "/default.svg",
+	Color:   "#6B7280",
+}
+
+// BrandedProvider is implemented by providers that supply icon and color metadata
+type BrandedProvider interface {
+	GetBranding() ProviderBranding
+}
+
+// CapableProvider is implemented by providers that advertise optional capabilities
+type CapableProvider interface {
+	GetCapabilities() []string
+}
+
+// UsageReporter is implemented by response writers that accept provider-reported token usage.
+// Providers that know their real token counts should report them; otherwise usage is estimated.
+type UsageReporter interface {
+	ReportUsage(inputTokens, outputTokens int64)
+}
+
+// GetBranding returns the branding for a provider, falling back to DefaultBranding
+func GetBranding(provider AIProvider) ProviderBranding {
+	if branded, ok := provider.(BrandedProvider); ok {
+		branding := branded.GetBranding()
+		if branding.IconURL == "" {
+			branding.IconURL = DefaultBranding.IconURL
+		}
+		if branding.Color == "" {
+			branding.Color = DefaultBranding.Color
+		}
+		return branding
+	}
+	return DefaultBranding
+}
+
+// HasCapability reports whether a provider advertises the given capability
+func HasCapability(provider AIProvider, capability string) bool {
+	capable, ok := provider.(CapableProvider)
+	if !ok {
+		return false
+	}
+	for _, c := range capable.GetCapabilities() {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// Model describes a model that can be selected for a single request
+type Model struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Default bool   `json:"default,omitempty"`
+}
+
+type modelContextKey struct{}
+
+// WithModel returns a context that asks the provider to use the given model
+func WithModel(ctx context.Context, model string) context.Context {
+	if model == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, modelContextKey{}, model)
+}
+
+// ModelFromContext returns the model requested for this call, or "" for the provider default
+func ModelFromContext(ctx context.Context) string {
+	model, _ := ctx.Value(modelContextKey{}).(string)
+	return model
+}
+
+// SupportsModel reports whether a provider lists the given model
+func SupportsModel(provider AIProvider, model string) bool {
+	for _, m := range provider.GetModels() {
+		if m.ID == model {
+			return true
+		}
+	}
+	return false
 }
 
 // AIProvider defines the interface for AI providers
@@ -30,6 +132,9 @@ type AIProvider interface {
 	// GetStatus returns detailed status information about the provider
 	GetStatus() ProviderStatus
 
+	// GetModels returns the models that can be selected per request (empty if unsupported)
+	GetModels() []Model
+
 	// SendPrompt sends a prompt to the AI and returns a response reader
 	SendPrompt(ctx context.Context, prompt string, chatID int64) (io.ReadCloser, error)
 
@@ -0,0 +1,259 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/utils"
+)
+
+// RelayProvider forwards prompts to another AI gateway speaking the OpenAI chat completions API,
+// such as LiteLLM or OpenRouter. base_url is the gateway's API root (e.g. https://openrouter.ai/api/v1);
+// models are mapped to the gateway's model names through model_map.
+type RelayProvider struct {
+	*HTTPProvider
+}
+
+// NewRelayProvider creates a provider relaying to the gateway at config.BaseURL
+func NewRelayProvider(config ProviderConfig, logDir string) *RelayProvider {
+	return &RelayProvider{HTTPProvider: NewHTTPProvider(config, logDir)}
+}
+
+func (p *RelayProvider) IsAvailable() bool {
+	return p.GetStatus().Available
+}
+
+// GetStatus lists the gateway's models, which also checks the configured credentials
+func (p *RelayProvider) GetStatus() ProviderStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoint("models"), nil)
+	if err != nil {
+		return ProviderStatus{Status: "not_configured", Details: fmt.Sprintf("Invalid base URL: %v", err), Capabilities: p.config.Capabilities}
+	}
+	p.setHeaders(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return ProviderStatus{Status: "error", Details: fmt.Sprintf("%s is unreachable", p.config.Name), Capabilities: p.config.Capabilities}
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return ProviderStatus{Status: "not_configured", Details: fmt.Sprintf("%s rejected the credentials (%s)", p.config.Name, resp.Status), Capabilities: p.config.Capabilities}
+	case resp.StatusCode >= 500:
+		return ProviderStatus{Status: "error", Details: fmt.Sprintf("%s returned %s", p.config.Name, resp.Status), Capabilities: p.config.Capabilities}
+	}
+
+	return ProviderStatus{
+		Available:    true,
+		Status:       "ready",
+		Details:      fmt.Sprintf("%s is reachable", p.config.Name),
+		Capabilities: p.config.Capabilities,
+	}
+}
+
+// endpoint joins a path to the gateway's API root
+func (p *RelayProvider) endpoint(path string) string {
+	return strings.TrimSuffix(p.config.BaseURL, "/") + "/" + path
+}
+
+// relayMessage is a chat message in the OpenAI format; content is a string or a list of parts
+type relayMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// relayChunk is one server-sent event of a streamed chat completion
+type relayChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int64 `json:"prompt_tokens"`
+		CompletionTokens int64 `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// message builds the user message, embedding image attachments for vision providers
+func (p *RelayProvider) message(ctx context.Context, prompt string) (relayMessage, error) {
+	if !HasCapability(p, CapabilityVision) {
+		return relayMessage{Role: "user", Content: prompt}, nil
+	}
+	images, err := encodeImages(AttachmentsFromContext(ctx))
+	if err != nil {
+		return relayMessage{}, err
+	}
+	if len(images) == 0 {
+		return relayMessage{Role: "user", Content: prompt}, nil
+	}
+
+	parts := []map[string]any{{"type": "text", "text": prompt}}
+	for _, image := range images {
+		parts = append(parts, map[string]any{
+			"type":      "image_url",
+			"image_url": map[string]string{"url": "data:" + image.MediaType + ";base64," + image.Data},
+		})
+	}
+	return relayMessage{Role: "user", Content: parts}, nil
+}
+
+// post starts a streamed chat completion and returns the response on success
+func (p *RelayProvider) post(ctx context.Context, prompt string) (*http.Response, error) {
+	message, err := p.message(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := map[string]any{
+		"model":          p.config.UpstreamModel(ModelFromContext(ctx)),
+		"messages":       []relayMessage{message},
+		"stream":         true,
+		"stream_options": map[string]bool{"include_usage": true},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint("chat/completions"), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	p.setHeaders(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", p.config.ID, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &StatusError{Provider: p.config.ID, StatusCode: resp.StatusCode, Status: resp.Status, Detail: utils.Redact(strings.TrimSpace(string(detail)))}
+	}
+
+	return resp, nil
+}
+
+// copyStream writes the content of each event to writer and reports the final token usage
+func (p *RelayProvider) copyStream(body io.Reader, writer io.Writer) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue // blank separators, comments and keep-alives
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var chunk relayChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode %s event: %w", p.config.ID, err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("%s stream failed: %s", p.config.ID, chunk.Error.Message)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			if _, err := io.WriteString(writer, choice.Delta.Content); err != nil {
+				return err
+			}
+		}
+		if chunk.Usage != nil {
+			if reporter, ok := writer.(UsageReporter); ok {
+				reporter.ReportUsage(chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s response: %w", p.config.ID, err)
+	}
+	return nil
+}
+
+func (p *RelayProvider) SendPrompt(ctx context.Context, prompt string, chatID int64) (io.ReadCloser, error) {
+	resp, err := p.post(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer resp.Body.Close()
+		writer.CloseWithError(p.copyStream(resp.Body, writer))
+	}()
+	return reader, nil
+}
+
+func (p *RelayProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	logPath := fmt.Sprintf("%s/%s/chat_%d.log", p.logDir, p.config.ID, chatID)
+	logFile, err := utils.CreateChatLog(logPath)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	fmt.Fprintf(logFile, "USER: %s\nASSISTANT: ", prompt)
+
+	resp, err := p.post(ctx, prompt)
+	if err != nil {
+		fmt.Fprintf(logFile, "\nERROR: %v\n", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := p.copyStream(resp.Body, &loggedWriter{writer: writer, log: logFile}); err != nil {
+		fmt.Fprintf(logFile, "\nERROR: %v\n", err)
+		return err
+	}
+	fmt.Fprintf(logFile, "\n")
+
+	return nil
+}
+
+// loggedWriter copies output to the chat log and passes usage reports through to the writer
+type loggedWriter struct {
+	writer io.Writer
+	log    io.Writer
+}
+
+func (w *loggedWriter) Write(b []byte) (int, error) {
+	n, err := w.writer.Write(b)
+	if err != nil {
+		return n, err
+	}
+	w.log.Write(b[:n])
+	return n, nil
+}
+
+// ReportUsage implements UsageReporter
+func (w *loggedWriter) ReportUsage(inputTokens, outputTokens int64) {
+	if reporter, ok := w.writer.(UsageReporter); ok {
+		reporter.ReportUsage(inputTokens, outputTokens)
+	}
+}
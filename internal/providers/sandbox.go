@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"ai-gateway-hub/internal/utils"
+)
+
+// Sandbox restricts how provider subprocesses run. The zero value runs them directly
+// in the server's working directory without limits.
+type Sandbox struct {
+	WorkDir string         `yaml:"work_dir" json:"work_dir"` // working directory, created if missing
+	Wrapper []string       `yaml:"wrapper" json:"wrapper"`   // command prefix, e.g. ["firejail", "--quiet", "--"]
+	Limits  ResourceLimits `yaml:"limits" json:"limits"`
+}
+
+// ResourceLimits are ulimits applied to a provider process and everything it starts; 0 means unlimited
+type ResourceLimits struct {
+	CPUSeconds int `yaml:"cpu_seconds" json:"cpu_seconds"`   // CPU time (ulimit -t)
+	MemoryMB   int `yaml:"memory_mb" json:"memory_mb"`       // virtual memory (ulimit -v)
+	FileSizeMB int `yaml:"file_size_mb" json:"file_size_mb"` // largest file written (ulimit -f)
+	OpenFiles  int `yaml:"open_files" json:"open_files"`     // open file descriptors (ulimit -n)
+}
+
+// SandboxedProvider is implemented by providers that run subprocesses
+type SandboxedProvider interface {
+	SetSandbox(sandbox Sandbox)
+}
+
+// IsZero reports whether no limit is set
+func (l ResourceLimits) IsZero() bool {
+	return l == ResourceLimits{}
+}
+
+// With returns the sandbox with the settings of override that are set replacing its own
+func (s Sandbox) With(override Sandbox) Sandbox {
+	if override.WorkDir != "" {
+		s.WorkDir = override.WorkDir
+	}
+	if len(override.Wrapper) > 0 {
+		s.Wrapper = override.Wrapper
+	}
+	if override.Limits.CPUSeconds > 0 {
+		s.Limits.CPUSeconds = override.Limits.CPUSeconds
+	}
+	if override.Limits.MemoryMB > 0 {
+		s.Limits.MemoryMB = override.Limits.MemoryMB
+	}
+	if override.Limits.FileSizeMB > 0 {
+		s.Limits.FileSizeMB = override.Limits.FileSizeMB
+	}
+	if override.Limits.OpenFiles > 0 {
+		s.Limits.OpenFiles = override.Limits.OpenFiles
+	}
+	return s
+}
+
+// Validate checks the sandbox settings
+func (s Sandbox) Validate() error {
+	if len(s.Wrapper) > 0 && strings.TrimSpace(s.Wrapper[0]) == "" {
+		return fmt.Errorf("sandbox wrapper command is empty")
+	}
+	l := s.Limits
+	if l.CPUSeconds < 0 || l.MemoryMB < 0 || l.FileSizeMB < 0 || l.OpenFiles < 0 {
+		return fmt.Errorf("sandbox limits must not be negative")
+	}
+	return nil
+}
+
+// ulimitArgs returns the sh ulimit options for the set limits
+func (l ResourceLimits) ulimitArgs() []string {
+	var args []string
+	if l.CPUSeconds > 0 {
+		args = append(args, fmt.Sprintf("-t %d", l.CPUSeconds))
+	}
+	if l.MemoryMB > 0 {
+		args = append(args, fmt.Sprintf("-v %d", l.MemoryMB*1024)) // KiB
+	}
+	if l.FileSizeMB > 0 {
+		args = append(args, fmt.Sprintf("-f %d", l.FileSizeMB*2048)) // 512-byte blocks
+	}
+	if l.OpenFiles > 0 {
+		args = append(args, fmt.Sprintf("-n %d", l.OpenFiles))
+	}
+	return args
+}
+
+// command returns the program and arguments that run name args... inside the sandbox
+func (s Sandbox) command(name string, args ...string) (string, []string) {
+	if len(s.Wrapper) > 0 {
+		args = append(append(append([]string{}, s.Wrapper[1:]...), name), args...)
+		name = s.Wrapper[0]
+	}
+	if !s.Limits.IsZero() {
+		name, args = limitCommand(s.Limits, name, args)
+	}
+	return name, args
+}
+
+// workDir returns the working directory for a provider process, creating it if needed
+func (s Sandbox) workDir() string {
+	if s.WorkDir == "" {
+		return ""
+	}
+	if err := os.MkdirAll(s.WorkDir, 0755); err != nil {
+		utils.Warn("Failed to create provider working directory %s: %v", s.WorkDir, err)
+	}
+	return s.WorkDir
+}
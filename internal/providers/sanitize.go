@@ -0,0 +1,160 @@
+package providers
+
+import "io"
+
+// maxEscapeSequence is the longest escape sequence dropped; output after an unterminated one is
+// kept from this point on
+const maxEscapeSequence = 4096
+
+// maxHeldLine is the longest line held back in case a carriage return discards it; longer lines
+// are passed on as they grow
+const maxHeldLine = 64 * 1024
+
+// sanitizeState is where a Sanitizer is within an escape sequence
+type sanitizeState int
+
+const (
+	stateText         sanitizeState = iota
+	stateEscape                     // after ESC
+	stateIntermediate               // ESC followed by intermediate bytes, e.g. ESC ( B
+	stateCSI                        // control sequence: ESC [ parameters final, or CSI (U+009B) parameters final
+	stateString                     // OSC, DCS, SOS, PM and APC strings, ended by BEL, ESC \ or ST (U+009C)
+	stateStringEscape               // ESC within a string
+)
+
+// Sanitizer writes CLI output without ANSI escape sequences (colors, cursor movement, titles) and
+// control characters other than newlines and tabs, which some CLIs emit despite TERM=dumb. This
+// includes the C1 controls U+0080 to U+009F, which start sequences of their own.
+// Sequences split between writes are dropped whole.
+//
+// Output is passed on a line at a time: a carriage return that isn't part of a CRLF discards the
+// line written so far, as a terminal redrawing a spinner or progress bar would. Flush passes on the
+// last line once the output is complete.
+type Sanitizer struct {
+	w        io.Writer
+	state    sanitizeState
+	sequence int    // bytes of the current escape sequence
+	line     []byte // the current line, held back until it ends
+	cr       bool   // the last byte was a carriage return
+	c2       bool   // the last byte was 0xC2, the first byte of a C1 control in UTF-8
+}
+
+func NewSanitizer(w io.Writer) *Sanitizer {
+	return &Sanitizer{w: w}
+}
+
+func (s *Sanitizer) Write(p []byte) (int, error) {
+	var clean []byte
+	for _, b := range p {
+		if s.state != stateText {
+			s.sequence++
+			if s.sequence > maxEscapeSequence {
+				s.state = stateText
+			}
+		}
+
+		// A C1 control is 0xC2 followed by 0x80 to 0x9F; 0xC2 is only kept once the next byte
+		// shows it starts another character
+		c1 := s.c2 && b >= 0x80 && b <= 0x9f
+		if s.c2 && !c1 && s.state == stateText {
+			s.text(0xc2)
+		}
+		s.c2 = b == 0xc2
+
+		switch s.state {
+		case stateText:
+			if s.cr {
+				s.cr = false
+				if b != '\n' {
+					s.line = s.line[:0]
+				}
+			}
+			switch {
+			case c1:
+				s.startC1(b)
+			case b == 0xc2:
+			case b == 0x1b:
+				s.state, s.sequence = stateEscape, 1
+			case b == '\r':
+				s.cr = true
+			case b == '\n':
+				clean = append(append(clean, s.line...), '\n')
+				s.line = s.line[:0]
+			case b == '\t' || (b >= 0x20 && b != 0x7f):
+				s.text(b)
+				if len(s.line) >= maxHeldLine {
+					clean = append(clean, s.line...)
+					s.line = s.line[:0]
+				}
+			}
+		case stateEscape:
+			switch {
+			case b == '[':
+				s.state = stateCSI
+			case b == ']' || b == 'P' || b == 'X' || b == '^' || b == '_':
+				s.state = stateString
+			case b >= 0x20 && b <= 0x2f:
+				s.state = stateIntermediate
+			default:
+				s.state = stateText
+			}
+		case stateIntermediate:
+			if b < 0x20 || b > 0x2f {
+				s.state = stateText
+			}
+		case stateCSI:
+			if b >= 0x40 && b <= 0x7e {
+				s.state = stateText
+			}
+		case stateString:
+			if b == 0x07 || (c1 && b == 0x9c) {
+				s.state = stateText
+			} else if b == 0x1b {
+				s.state = stateStringEscape
+			}
+		case stateStringEscape:
+			s.state = stateString
+			if b == '\\' {
+				s.state = stateText
+			}
+		}
+	}
+
+	if len(clean) > 0 {
+		if _, err := s.w.Write(clean); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush passes on the line held back, which a final carriage return doesn't discard since nothing
+// replaces it
+func (s *Sanitizer) Flush() error {
+	if s.c2 && s.state == stateText {
+		s.text(0xc2)
+	}
+	s.c2, s.cr = false, false
+	if len(s.line) == 0 {
+		return nil
+	}
+	_, err := s.w.Write(s.line)
+	s.line = s.line[:0]
+	return err
+}
+
+// text adds a byte of text to the current line
+func (s *Sanitizer) text(b byte) {
+	s.line = append(s.line, b)
+}
+
+// startC1 handles the C1 control whose second UTF-8 byte is b: those that start a control sequence
+// or a string are dropped with it, the others on their own
+func (s *Sanitizer) startC1(b byte) {
+	switch b {
+	case 0x9b: // CSI
+		s.state, s.sequence = stateCSI, 1
+	case 0x90, 0x98, 0x9d, 0x9e, 0x9f: // DCS, SOS, OSC, PM, APC
+		s.state, s.sequence = stateString, 1
+	}
+}
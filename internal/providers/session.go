@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrSessionExpired is returned when a provider session can no longer be resumed. Nothing was
+// written to the response, so the prompt can be sent again in a new session.
+var ErrSessionExpired = errors.New("provider session expired")
+
+// Session is a provider's native conversation session for a chat. Providers supporting sessions
+// continue the session named by ID, or start a new one and store its ID.
+type Session struct {
+	ID string
+}
+
+// SessionProvider is implemented by providers that can keep a chat's conversation in a native
+// session, so follow-up prompts don't need the earlier messages
+type SessionProvider interface {
+	SupportsSessions() bool
+}
+
+// SupportsSessions reports whether a provider keeps conversations in native sessions
+func SupportsSessions(provider AIProvider) bool {
+	sessionProvider, ok := provider.(SessionProvider)
+	return ok && sessionProvider.SupportsSessions()
+}
+
+type sessionContextKey struct{}
+
+// WithSession returns a context that asks the provider to continue or start a session
+func WithSession(ctx context.Context, session *Session) context.Context {
+	if session == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext returns the session requested for this call, or nil
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return session
+}
+
+// NewSessionID returns a random version 4 UUID
+func NewSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,50 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// AWSBackend reads secrets from AWS Secrets Manager through the aws CLI, using its usual
+// credential chain (environment, profile or instance role). References are "<secret id>" for
+// plain secrets or "<secret id>#<key>" for a field of a JSON secret.
+type AWSBackend struct {
+	cliPath string
+}
+
+// NewAWSBackend creates a backend running the aws CLI at cliPath
+func NewAWSBackend(cliPath string) *AWSBackend {
+	return &AWSBackend{cliPath: cliPath}
+}
+
+func (b *AWSBackend) Fetch(ctx context.Context, reference string) (string, error) {
+	id, key := splitKey(reference)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, b.cliPath, "secretsmanager", "get-secret-value",
+		"--secret-id", id, "--query", "SecretString", "--output", "text")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("aws secretsmanager failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	value := strings.TrimRight(stdout.String(), "\r\n")
+	if key == "" {
+		return value, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("aws secret %s is not JSON, so it has no key %q", id, key)
+	}
+	field, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("aws secret %s has no string key %q", id, key)
+	}
+	return field, nil
+}
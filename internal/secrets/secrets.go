@@ -0,0 +1,156 @@
+// Package secrets resolves provider credentials from the environment, files or an external
+// secret manager. References have the form scheme:reference, e.g. "file:/run/secrets/api_key",
+// "vault:secret/data/ai#openai_key" or "aws-sm:prod/ai-hub#openrouter". Every resolved value is
+// registered with utils.RegisterSecret so it is masked in logs.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"ai-gateway-hub/internal/utils"
+)
+
+// Reference schemes
+const (
+	SchemeEnv   = "env"    // env:NAME
+	SchemeFile  = "file"   // file:/path/to/secret
+	SchemeVault = "vault"  // vault:<path>#<key>, read from the Vault HTTP API (KV v1 or v2)
+	SchemeAWS   = "aws-sm" // aws-sm:<secret id>[#<json key>], read with the aws CLI
+)
+
+// Backend fetches secrets of one scheme
+type Backend interface {
+	Fetch(ctx context.Context, reference string) (string, error)
+}
+
+// Options configures the external secret managers
+type Options struct {
+	VaultAddr      string
+	VaultToken     string
+	VaultNamespace string
+	AWSCLIPath     string
+}
+
+// Manager resolves secret references and caches the values until Forget is called
+type Manager struct {
+	backends map[string]Backend
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// NewManager creates a manager with the env and file backends, plus Vault when VaultAddr is set
+// and AWS Secrets Manager when AWSCLIPath is set
+func NewManager(opts Options) *Manager {
+	utils.RegisterSecret(opts.VaultToken)
+
+	m := &Manager{
+		backends: map[string]Backend{
+			SchemeEnv:  envBackend{},
+			SchemeFile: fileBackend{},
+		},
+		cache: make(map[string]string),
+	}
+	if opts.VaultAddr != "" {
+		m.backends[SchemeVault] = NewVaultBackend(opts.VaultAddr, opts.VaultToken, opts.VaultNamespace)
+	}
+	if opts.AWSCLIPath != "" {
+		m.backends[SchemeAWS] = NewAWSBackend(opts.AWSCLIPath)
+	}
+	return m
+}
+
+// SetBackend adds or replaces the backend of a scheme
+func (m *Manager) SetBackend(scheme string, backend Backend) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.backends[scheme] = backend
+}
+
+// ParseReference splits "scheme:reference"; ok is false for plain variable names
+func ParseReference(s string) (scheme, reference string, ok bool) {
+	scheme, reference, found := strings.Cut(s, ":")
+	if !found || scheme == "" || reference == "" {
+		return "", "", false
+	}
+	switch scheme {
+	case SchemeEnv, SchemeFile, SchemeVault, SchemeAWS:
+		return scheme, reference, true
+	}
+	return "", "", false
+}
+
+// IsReference reports whether s is a secret reference rather than a plain variable name
+func IsReference(s string) bool {
+	_, _, ok := ParseReference(s)
+	return ok
+}
+
+// Resolve returns the value of a secret reference
+func (m *Manager) Resolve(ctx context.Context, ref string) (string, error) {
+	scheme, reference, ok := ParseReference(ref)
+	if !ok {
+		return "", fmt.Errorf("invalid secret reference %q", ref)
+	}
+
+	m.mu.Lock()
+	if value, ok := m.cache[ref]; ok {
+		m.mu.Unlock()
+		return value, nil
+	}
+	backend, ok := m.backends[scheme]
+	m.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("secret backend %q is not configured", scheme)
+	}
+
+	value, err := backend.Fetch(ctx, reference)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %s: %w", ref, err)
+	}
+	utils.RegisterSecret(value)
+
+	m.mu.Lock()
+	m.cache[ref] = value
+	m.mu.Unlock()
+	return value, nil
+}
+
+// Forget drops the cached values, so the next Resolve fetches them again
+func (m *Manager) Forget() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache = make(map[string]string)
+}
+
+// splitKey splits "path#key" into the path and the optional key
+func splitKey(reference string) (string, string) {
+	path, key, _ := strings.Cut(reference, "#")
+	return path, key
+}
+
+// envBackend reads server environment variables
+type envBackend struct{}
+
+func (envBackend) Fetch(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// fileBackend reads files such as Docker or Kubernetes secrets, without the trailing newline
+type fileBackend struct{}
+
+func (fileBackend) Fetch(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	scheme, ref, ok := ParseReference("vault:secret/data/ai#key")
+	assert.True(t, ok)
+	assert.Equal(t, SchemeVault, scheme)
+	assert.Equal(t, "secret/data/ai#key", ref)
+
+	for _, s := range []string{"OPENAI_API_KEY", "http://host", "vault:", ":x"} {
+		assert.False(t, IsReference(s), s)
+	}
+}
+
+func TestManager_EnvAndFile(t *testing.T) {
+	ctx := context.Background()
+	manager := NewManager(Options{})
+
+	t.Setenv("SECRETS_TEST_KEY", "env-secret-value")
+	value, err := manager.Resolve(ctx, "env:SECRETS_TEST_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "env-secret-value", value)
+	assert.Equal(t, "token "+utils.RedactedPlaceholder, utils.Redact("token env-secret-value"), "resolved values are redacted")
+
+	_, err = manager.Resolve(ctx, "env:SECRETS_TEST_UNSET")
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "api_key")
+	require.NoError(t, os.WriteFile(path, []byte("file-secret-value\n"), 0600))
+	value, err = manager.Resolve(ctx, "file:"+path)
+	require.NoError(t, err)
+	assert.Equal(t, "file-secret-value", value)
+
+	// Values are cached until forgotten
+	require.NoError(t, os.WriteFile(path, []byte("rotated-value"), 0600))
+	value, _ = manager.Resolve(ctx, "file:"+path)
+	assert.Equal(t, "file-secret-value", value)
+	manager.Forget()
+	value, _ = manager.Resolve(ctx, "file:"+path)
+	assert.Equal(t, "rotated-value", value)
+
+	_, err = manager.Resolve(ctx, "vault:secret/data/ai#key")
+	assert.ErrorContains(t, err, "not configured")
+}
+
+func TestVaultBackend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root-token" || r.Header.Get("X-Vault-Namespace") != "team" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/ai":
+			w.Write([]byte(`{"data": {"data": {"openai": "kv2-value"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/ai":
+			w.Write([]byte(`{"data": {"openai": "kv1-value"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	manager := NewManager(Options{VaultAddr: server.URL + "/", VaultToken: "root-token", VaultNamespace: "team"})
+
+	value, err := manager.Resolve(ctx, "vault:secret/data/ai#openai")
+	require.NoError(t, err)
+	assert.Equal(t, "kv2-value", value)
+
+	value, err = manager.Resolve(ctx, "vault:kv/ai#openai")
+	require.NoError(t, err)
+	assert.Equal(t, "kv1-value", value)
+
+	_, err = manager.Resolve(ctx, "vault:secret/data/ai#missing")
+	assert.ErrorContains(t, err, "no key")
+	_, err = manager.Resolve(ctx, "vault:secret/data/other#openai")
+	assert.ErrorContains(t, err, "404")
+	_, err = manager.Resolve(ctx, "vault:secret/data/ai")
+	assert.ErrorContains(t, err, "#key")
+}
+
+// stubBackend returns a fixed value or error
+type stubBackend struct {
+	value string
+	err   error
+}
+
+func (b stubBackend) Fetch(ctx context.Context, reference string) (string, error) {
+	return b.value, b.err
+}
+
+func TestManager_SetBackend(t *testing.T) {
+	manager := NewManager(Options{})
+	manager.SetBackend(SchemeAWS, stubBackend{err: errors.New("access denied")})
+
+	_, err := manager.Resolve(context.Background(), "aws-sm:prod/hub#key")
+	assert.ErrorContains(t, err, "access denied")
+	assert.ErrorContains(t, err, "aws-sm:prod/hub#key")
+}
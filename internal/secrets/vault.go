@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultBackend reads secrets from HashiCorp Vault's HTTP API. References are "<path>#<key>",
+// e.g. "secret/data/ai-hub#openai" for a KV v2 engine mounted at secret/.
+type VaultBackend struct {
+	addr      string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+// NewVaultBackend creates a backend for the Vault server at addr
+func NewVaultBackend(addr, token, namespace string) *VaultBackend {
+	return &VaultBackend{
+		addr:      strings.TrimSuffix(addr, "/"),
+		token:     token,
+		namespace: namespace,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (b *VaultBackend) Fetch(ctx context.Context, reference string) (string, error) {
+	path, key := splitKey(reference)
+	if key == "" {
+		return "", fmt.Errorf("vault reference %q needs a #key", reference)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.addr+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", b.token)
+	if b.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", b.namespace)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	// KV v2 nests the secret under data.data, KV v1 returns it in data
+	fields := body.Data
+	if nested, ok := body.Data["data"]; ok {
+		var inner map[string]json.RawMessage
+		if err := json.Unmarshal(nested, &inner); err == nil {
+			fields = inner
+		}
+	}
+
+	raw, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %q", path, key)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("vault secret %s key %q is not a string", path, key)
+	}
+	return value, nil
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/utils"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Server runs the hub's HTTP or HTTPS listener and the optional HTTP→HTTPS redirect listener
+type Server struct {
+	cfg      *config.Config
+	main     *http.Server
+	redirect *http.Server
+}
+
+// New creates the server for the handler. With TLS_AUTOCERT, certificates for the configured hosts
+// are obtained from Let's Encrypt and cached in TLS_AUTOCERT_CACHE_DIR.
+func New(cfg *config.Config, handler http.Handler) *Server {
+	s := &Server{
+		cfg: cfg,
+		main: &http.Server{
+			Addr:    ":" + cfg.Port,
+			Handler: handler,
+		},
+	}
+
+	var redirect http.Handler = RedirectHandler(cfg.Port)
+	if cfg.TLSAutocert {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLSAutocertHosts...),
+			Cache:      autocert.DirCache(cfg.TLSAutocertCacheDir),
+			Email:      cfg.TLSAutocertEmail,
+		}
+		s.main.TLSConfig = manager.TLSConfig()
+		// Answers HTTP-01 challenges and redirects everything else
+		redirect = manager.HTTPHandler(redirect)
+	}
+
+	if cfg.TLSEnabled() && cfg.HTTPRedirectPort != "" {
+		s.redirect = &http.Server{
+			Addr:    ":" + cfg.HTTPRedirectPort,
+			Handler: redirect,
+		}
+	}
+
+	return s
+}
+
+// ListenAndServe serves until Shutdown; it returns http.ErrServerClosed after a graceful shutdown
+func (s *Server) ListenAndServe() error {
+	if s.redirect != nil {
+		go func() {
+			utils.Info("Redirecting HTTP on port %s to HTTPS", s.cfg.HTTPRedirectPort)
+			if err := s.redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				utils.Error("HTTP redirect listener failed: %v", err)
+			}
+		}()
+	}
+
+	switch {
+	case s.cfg.TLSAutocert:
+		utils.Info("Starting AI Gateway Hub on port %s with HTTPS (autocert for %v)", s.cfg.Port, s.cfg.TLSAutocertHosts)
+		return s.main.ListenAndServeTLS("", "")
+	case s.cfg.TLSCertFile != "":
+		utils.Info("Starting AI Gateway Hub on port %s with HTTPS", s.cfg.Port)
+		return s.main.ListenAndServeTLS(s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+	default:
+		utils.Info("Starting AI Gateway Hub on port %s", s.cfg.Port)
+		return s.main.ListenAndServe()
+	}
+}
+
+// Shutdown gracefully stops both listeners
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.redirect != nil {
+		if err := s.redirect.Shutdown(ctx); err != nil {
+			utils.Warn("Failed to shut down HTTP redirect listener: %v", err)
+		}
+	}
+	return s.main.Shutdown(ctx)
+}
+
+// RedirectHandler redirects GET and HEAD requests to the same URL on the HTTPS port and rejects
+// other methods, whose bodies would be sent unencrypted again on redirect
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Use HTTPS", http.StatusBadRequest)
+			return
+		}
+
+		host := (&url.URL{Host: r.Host}).Hostname()
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
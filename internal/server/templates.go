@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gin-gonic/gin/render"
+)
+
+// TemplatePatterns are the template files, relative to the templates directory
+var TemplatePatterns = []string{"*.html", "pages/*.html", "components/*.html"}
+
+// Delay after the last change before templates are parsed again, since editors often write
+// a file in several steps
+const templateReloadDelay = 100 * time.Millisecond
+
+// ParseTemplates parses the page templates of fsys with the i18n template functions
+func ParseTemplates(fsys fs.FS) (*template.Template, error) {
+	return template.New("").Funcs(i18n.TemplateFuncs()).ParseFS(fsys, TemplatePatterns...)
+}
+
+// ReloadableHTML is a gin HTML renderer whose templates are parsed again from a directory when
+// they change, so template edits show up without a rebuild during development
+type ReloadableHTML struct {
+	dir  string
+	tmpl atomic.Pointer[template.Template]
+}
+
+// NewReloadableHTML parses the templates of dir
+func NewReloadableHTML(dir string) (*ReloadableHTML, error) {
+	r := &ReloadableHTML{dir: dir}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Instance implements render.HTMLRender with the latest parsed templates
+func (r *ReloadableHTML) Instance(name string, data any) render.Render {
+	return render.HTML{Template: r.tmpl.Load(), Name: name, Data: data}
+}
+
+// Reload parses the templates again; on error the previous templates stay in use
+func (r *ReloadableHTML) Reload() error {
+	tmpl, err := ParseTemplates(os.DirFS(r.dir))
+	if err != nil {
+		return fmt.Errorf("failed to parse templates in %s: %w", r.dir, err)
+	}
+	r.tmpl.Store(tmpl)
+	return nil
+}
+
+// Watch reloads the templates when a file in the templates directories changes, until ctx is done
+func (r *ReloadableHTML) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create template watcher: %w", err)
+	}
+
+	for _, pattern := range TemplatePatterns {
+		dir := filepath.Join(r.dir, filepath.Dir(pattern))
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var reload <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if strings.HasSuffix(event.Name, ".html") && !event.Has(fsnotify.Chmod) {
+					reload = time.After(templateReloadDelay)
+				}
+			case <-reload:
+				reload = nil
+				if err := r.Reload(); err != nil {
+					utils.Warn("Kept previous templates: %v", err)
+					continue
+				}
+				utils.Info("Reloaded templates from %s", r.dir)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				utils.Warn("Template watcher error: %v", err)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
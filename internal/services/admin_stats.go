@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Timeout of each dependency check made for the admin dashboard
+const adminStatsCheckTimeout = 2 * time.Second
+
+// AdminStatsService aggregates the statistics shown on the admin dashboard
+type AdminStatsService struct {
+	db             database.Store
+	redisClient    *redis.Client
+	storeBackend   string
+	sessionService *SessionService
+	registry       *ProviderRegistry
+}
+
+func NewAdminStatsService(db database.Store, redisClient *redis.Client, storeBackend string, sessionService *SessionService, registry *ProviderRegistry) *AdminStatsService {
+	return &AdminStatsService{db: db, redisClient: redisClient, storeBackend: storeBackend, sessionService: sessionService, registry: registry}
+}
+
+// GetStats collects the current statistics; unavailable dependencies are reported, not returned as errors
+func (s *AdminStatsService) GetStats(ctx context.Context) (*models.AdminStats, error) {
+	stats := &models.AdminStats{
+		GeneratedAt:  time.Now(),
+		Database:     s.databaseStatus(),
+		Redis:        s.redisStatus(),
+		SessionStore: s.storeBackend,
+		Providers:    s.registry.List(),
+		RecentErrors: utils.RecentErrors(),
+	}
+
+	if stats.Database.Healthy {
+		err := s.db.QueryRowContext(ctx, `
+			SELECT
+				(SELECT COUNT(*) FROM chats WHERE deleted_at IS NULL AND archived_at IS NULL),
+				(SELECT COUNT(*) FROM chats WHERE deleted_at IS NULL AND archived_at IS NOT NULL),
+				(SELECT COUNT(*) FROM chats WHERE deleted_at IS NOT NULL),
+				(SELECT COUNT(*) FROM messages)
+		`).Scan(&stats.Chats.Active, &stats.Chats.Archived, &stats.Chats.Deleted, &stats.Messages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count chats: %w", err)
+		}
+		stats.Chats.Total = stats.Chats.Active + stats.Chats.Archived
+	}
+
+	// In-memory sessions can be counted without Redis
+	if s.sessionService != nil && (stats.Redis.Healthy || s.storeBackend == StoreBackendMemory) {
+		active, err := s.sessionService.GetActiveSessions(ctx)
+		if err != nil {
+			utils.Warn("Failed to count active sessions: %v", err)
+		}
+		stats.ActiveSessions = active
+	}
+
+	if stats.Providers == nil {
+		stats.Providers = []*models.Provider{}
+	}
+	sort.Slice(stats.Providers, func(i, j int) bool { return stats.Providers[i].ID < stats.Providers[j].ID })
+	for _, p := range stats.Providers {
+		if p.Available {
+			stats.ProvidersAvailable++
+		}
+	}
+
+	return stats, nil
+}
+
+// databaseStatus checks that the database answers a trivial query
+func (s *AdminStatsService) databaseStatus() models.DependencyStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), adminStatsCheckTimeout)
+	defer cancel()
+	return checkDatabase(ctx, s.db)
+}
+
+// redisStatus pings Redis
+func (s *AdminStatsService) redisStatus() models.DependencyStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), adminStatsCheckTimeout)
+	defer cancel()
+	return checkRedis(ctx, s.redisClient)
+}
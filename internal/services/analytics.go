@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+)
+
+// Activity bucket sizes
+const (
+	BucketHour = "hour"
+	BucketDay  = "day"
+)
+
+// ActivityQuery selects the messages counted by GetActivity
+type ActivityQuery struct {
+	From     time.Time // inclusive
+	To       time.Time // exclusive
+	Bucket   string    // hour or day
+	Provider string    // optional; matches the message's provider, or the chat's for untagged messages
+	Role     string    // optional; user or assistant
+	UserID   string    // optional; the user who sent the message
+	Location *time.Location
+}
+
+// AnalyticsService aggregates chat history for analytics
+type AnalyticsService struct {
+	db database.Store
+}
+
+func NewAnalyticsService(db database.Store) *AnalyticsService {
+	return &AnalyticsService{db: db}
+}
+
+// GetActivity returns message counts bucketed by hour or day, plus a weekday x hour heatmap.
+// Messages of deleted chats aren't counted; archived chats still are.
+func (s *AnalyticsService) GetActivity(q ActivityQuery) (*models.ActivityReport, error) {
+	if q.Bucket != BucketHour && q.Bucket != BucketDay {
+		return nil, fmt.Errorf("invalid bucket %q", q.Bucket)
+	}
+	if !q.To.After(q.From) {
+		return nil, fmt.Errorf("invalid range: to must be after from")
+	}
+	if q.Location == nil {
+		q.Location = time.UTC
+	}
+
+	report := &models.ActivityReport{
+		From:     q.From.In(q.Location),
+		To:       q.To.In(q.Location),
+		Bucket:   q.Bucket,
+		Timezone: q.Location.String(),
+	}
+
+	// Count per local hour in SQL, shifting times by the zone's offset so zones that are off UTC by
+	// half or quarter hours get the right hours. The range is queried in spans of one offset each,
+	// split at daylight saving transitions.
+	counts := make(map[int64]int64)
+	for start := q.From; start.Before(q.To); {
+		local := start.In(q.Location)
+		_, offset := local.Zone()
+		end := q.To
+		if _, zoneEnd := local.ZoneBounds(); !zoneEnd.IsZero() && zoneEnd.Before(end) {
+			end = zoneEnd
+		}
+		if err := s.countLocalHours(q, start, end, offset, func(hour, count int64) {
+			at := time.Unix(hour*3600-int64(offset), 0).In(q.Location)
+			counts[bucketStart(at, q.Bucket).Unix()] += count
+			report.Heatmap[at.Weekday()][at.Hour()] += count
+			report.Total += count
+		}); err != nil {
+			return nil, err
+		}
+		start = end
+	}
+
+	// Emit every bucket in the range, including empty ones, so charts need no gap filling
+	for start := bucketStart(q.From.In(q.Location), q.Bucket); start.Before(q.To); start = nextBucket(start, q.Bucket) {
+		report.Buckets = append(report.Buckets, models.ActivityBucket{
+			Start: start,
+			Count: counts[start.Unix()],
+		})
+	}
+
+	return report, nil
+}
+
+// countLocalHours counts the messages of q sent in [from, to) per hour of the local time offset
+// seconds from UTC, numbered from the Unix epoch in that local time
+func (s *AnalyticsService) countLocalHours(q ActivityQuery, from, to time.Time, offset int, add func(hour, count int64)) error {
+	createdAt := s.db.Dialect().UnixSeconds("m.created_at")
+	query := `
+		SELECT (` + createdAt + ` + ?) / 3600 AS hour, COUNT(*)
+		FROM messages m
+		JOIN chats c ON c.id = m.chat_id
+		WHERE c.deleted_at IS NULL
+			AND ` + createdAt + ` >= ?
+			AND ` + createdAt + ` < ?
+			AND (? = '' OR COALESCE(NULLIF(m.provider, ''), c.provider) = ?)
+			AND (? = '' OR m.role = ?)
+			AND (? = '' OR m.user_id = ?)
+		GROUP BY hour
+	`
+
+	rows, err := s.db.Query(query, offset, from.Unix(), to.Unix(), q.Provider, q.Provider, q.Role, q.Role, q.UserID, q.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get activity: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hour, count int64
+		if err := rows.Scan(&hour, &count); err != nil {
+			return fmt.Errorf("failed to scan activity: %w", err)
+		}
+		add(hour, count)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate activity: %w", err)
+	}
+	return nil
+}
+
+// bucketStart truncates t to the start of its hour or day in t's location
+func bucketStart(t time.Time, bucket string) time.Time {
+	if bucket == BucketDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+func nextBucket(t time.Time, bucket string) time.Time {
+	if bucket == BucketDay {
+		return t.AddDate(0, 0, 1)
+	}
+	return t.Add(time.Hour)
+}
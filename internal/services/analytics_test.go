@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func insertMessageAt(t *testing.T, db *database.DB, chatID int64, role, provider string, at time.Time) {
+	_, err := db.Exec(`INSERT INTO messages (chat_id, role, content, provider, created_at) VALUES (?, ?, 'x', ?, ?)`,
+		chatID, role, provider, at)
+	require.NoError(t, err)
+}
+
+func TestAnalyticsService_GetActivity(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := NewChatService(db)
+	analytics := NewAnalyticsService(db)
+
+	claudeChat, err := chatService.CreateChat(context.Background(), "Claude", "claude")
+	require.NoError(t, err)
+	geminiChat, err := chatService.CreateChat(context.Background(), "Gemini", "gemini")
+	require.NoError(t, err)
+
+	// Thursday 2026-10-15
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	insertMessageAt(t, db, claudeChat.ID, "user", "", day.Add(9*time.Hour))
+	insertMessageAt(t, db, claudeChat.ID, "assistant", "claude", day.Add(9*time.Hour+time.Minute))
+	insertMessageAt(t, db, geminiChat.ID, "user", "", day.Add(23*time.Hour+30*time.Minute))
+	insertMessageAt(t, db, geminiChat.ID, "user", "", day.AddDate(0, 0, 3)) // outside the range
+
+	report, err := analytics.GetActivity(ActivityQuery{From: day, To: day.AddDate(0, 0, 2), Bucket: BucketDay})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), report.Total)
+	require.Len(t, report.Buckets, 2)
+	assert.Equal(t, int64(3), report.Buckets[0].Count)
+	assert.Equal(t, int64(0), report.Buckets[1].Count)
+	assert.Equal(t, int64(2), report.Heatmap[time.Thursday][9])
+
+	report, err = analytics.GetActivity(ActivityQuery{From: day, To: day.AddDate(0, 0, 1), Bucket: BucketHour, Provider: "gemini"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Total)
+	assert.Len(t, report.Buckets, 24)
+	assert.Equal(t, int64(1), report.Buckets[23].Count)
+
+	report, err = analytics.GetActivity(ActivityQuery{From: day, To: day.AddDate(0, 0, 1), Bucket: BucketDay, Role: "assistant"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Total)
+
+	// In Tokyo (UTC+9) the 23:30 UTC message falls on the next day at 08:30
+	tokyo := time.FixedZone("JST", 9*3600)
+	report, err = analytics.GetActivity(ActivityQuery{From: day, To: day.AddDate(0, 0, 2), Bucket: BucketDay, Location: tokyo})
+	require.NoError(t, err)
+	require.Len(t, report.Buckets, 3)
+	assert.Equal(t, int64(2), report.Buckets[0].Count)
+	assert.Equal(t, int64(1), report.Buckets[1].Count)
+	assert.Equal(t, int64(1), report.Heatmap[time.Friday][8])
+
+	// Deleted chats no longer count, archived ones still do
+	require.NoError(t, chatService.ArchiveChat(context.Background(), claudeChat.ID))
+	require.NoError(t, chatService.DeleteChat(context.Background(), geminiChat.ID))
+	report, err = analytics.GetActivity(ActivityQuery{From: day, To: day.AddDate(0, 0, 2), Bucket: BucketDay})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.Total)
+	assert.Equal(t, int64(0), report.Heatmap[time.Thursday][23])
+
+	_, err = analytics.GetActivity(ActivityQuery{From: day, To: day, Bucket: BucketDay})
+	assert.Error(t, err)
+}
+
+func TestAnalyticsService_GetActivityByUser(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chat, err := NewChatService(db).CreateChat(context.Background(), "Claude", "claude")
+	require.NoError(t, err)
+
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	for _, userID := range []string{"alice", "alice", "bob"} {
+		_, err := db.Exec(`INSERT INTO messages (chat_id, role, content, user_id, created_at) VALUES (?, 'user', 'x', ?, ?)`,
+			chat.ID, userID, day.Add(9*time.Hour))
+		require.NoError(t, err)
+	}
+	insertMessageAt(t, db, chat.ID, "assistant", "claude", day.Add(9*time.Hour))
+
+	report, err := NewAnalyticsService(db).GetActivity(ActivityQuery{From: day, To: day.AddDate(0, 0, 1), Bucket: BucketDay, UserID: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.Total)
+
+	report, err = NewAnalyticsService(db).GetActivity(ActivityQuery{From: day, To: day.AddDate(0, 0, 1), Bucket: BucketDay})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), report.Total)
+}
+
+func TestAnalyticsService_GetActivityHalfHourZone(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chat, err := NewChatService(db).CreateChat(context.Background(), "Claude", "claude")
+	require.NoError(t, err)
+
+	// 09:10 and 09:40 UTC are 14:40 and 15:10 in Kolkata (UTC+5:30), so they fall in different local hours
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	insertMessageAt(t, db, chat.ID, "user", "", day.Add(9*time.Hour+10*time.Minute))
+	insertMessageAt(t, db, chat.ID, "user", "", day.Add(9*time.Hour+40*time.Minute))
+
+	kolkata := time.FixedZone("IST", 5*3600+1800)
+	from := time.Date(2026, 10, 15, 0, 0, 0, 0, kolkata)
+	report, err := NewAnalyticsService(db).GetActivity(ActivityQuery{From: from, To: from.AddDate(0, 0, 1), Bucket: BucketHour, Location: kolkata})
+	require.NoError(t, err)
+	require.Len(t, report.Buckets, 24)
+	assert.Equal(t, int64(1), report.Buckets[14].Count)
+	assert.Equal(t, int64(1), report.Buckets[15].Count)
+	assert.Equal(t, int64(1), report.Heatmap[time.Thursday][14])
+	assert.Equal(t, int64(1), report.Heatmap[time.Thursday][15])
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"ai-gateway-hub/internal/database"
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+)
+
+// Attachment errors callers map to client errors
+var (
+	ErrAttachmentTooLarge       = errors.New("attachment is too large")
+	ErrAttachmentTypeNotAllowed = apperrors.Validation("attachment type is not allowed")
+	ErrAttachmentNotFound       = apperrors.NotFound("attachment not found")
+	ErrAttachmentNotImage       = apperrors.Validation("attachment is not an image")
+)
+
+// Longest file name kept for an attachment
+const maxAttachmentFilenameLength = 255
+
+// Columns selected for an attachment, in the order scanAttachment expects
+const attachmentColumns = "id, chat_id, message_id, filename, content_type, size, storage_path, created_at"
+
+// AttachmentService stores files uploaded to chats and links them to the messages they were sent with.
+// Files live in one directory per chat below the attachments directory.
+type AttachmentService struct {
+	db           database.Store
+	dir          string
+	maxSize      int64
+	allowedTypes []string
+}
+
+func NewAttachmentService(db database.Store, dir string, maxSize int64, allowedTypes []string) *AttachmentService {
+	return &AttachmentService{
+		db:           db,
+		dir:          dir,
+		maxSize:      maxSize,
+		allowedTypes: allowedTypes,
+	}
+}
+
+// MaxSize returns the largest accepted attachment in bytes
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Save stores an uploaded file for a chat. The content type is detected from the data, not taken
+// from the client, and must match one of the allowed types.
+func (s *AttachmentService) Save(chatID int64, filename string, r io.Reader) (*models.Attachment, error) {
+	chatDir := filepath.Join(s.dir, strconv.FormatInt(chatID, 10))
+	if err := os.MkdirAll(chatDir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create attachment directory: %w", err)
+	}
+
+	filename = sanitizeAttachmentFilename(filename)
+	storageName, err := newStorageName(filename)
+	if err != nil {
+		return nil, err
+	}
+	storagePath := filepath.Join(strconv.FormatInt(chatID, 10), storageName)
+	fullPath := filepath.Join(s.dir, storagePath)
+
+	file, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment file: %w", err)
+	}
+	keep := false
+	defer func() {
+		file.Close()
+		if !keep {
+			os.Remove(fullPath)
+		}
+	}()
+
+	// Read one byte past the limit to detect oversized uploads
+	size, err := io.Copy(file, io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write attachment: %w", err)
+	}
+	if size > s.maxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	contentType, err := detectContentType(fullPath, filename)
+	if err != nil {
+		return nil, err
+	}
+	if !s.typeAllowed(contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrAttachmentTypeNotAllowed, contentType)
+	}
+
+	query := `
+		INSERT INTO attachments (chat_id, filename, content_type, size, storage_path, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING ` + attachmentColumns
+	attachment, err := scanAttachment(s.db.QueryRow(query, chatID, filename, contentType, size, storagePath, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save attachment: %w", err)
+	}
+
+	keep = true
+	return attachment, nil
+}
+
+// SaveImage stores a base64 encoded image (optionally a data: URL) sent inline with a prompt
+func (s *AttachmentService) SaveImage(chatID int64, filename, data string) (*models.Attachment, error) {
+	if strings.HasPrefix(data, "data:") {
+		_, encoded, ok := strings.Cut(data, ";base64,")
+		if !ok {
+			return nil, fmt.Errorf("%w: data URL is not base64 encoded", ErrAttachmentNotImage)
+		}
+		data = encoded
+	}
+	if filename == "" {
+		filename = "image"
+	}
+
+	decoded := base64.NewDecoder(base64.StdEncoding, strings.NewReader(strings.TrimSpace(data)))
+	attachment, err := s.Save(chatID, filename, decoded)
+	if err != nil {
+		return nil, err
+	}
+	if !attachment.IsImage() {
+		if err := s.Delete(chatID, attachment.ID); err != nil {
+			utils.Warn("Failed to remove rejected image %d: %v", attachment.ID, err)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrAttachmentNotImage, attachment.ContentType)
+	}
+	return attachment, nil
+}
+
+// Get retrieves an attachment of a chat
+func (s *AttachmentService) Get(chatID, attachmentID int64) (*models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = ? AND chat_id = ?`
+	attachment, err := scanAttachment(s.db.QueryRow(query, attachmentID, chatID))
+	if err == sql.ErrNoRows {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// ListByChat returns the attachments of a chat in upload order
+func (s *AttachmentService) ListByChat(chatID int64) ([]*models.Attachment, error) {
+	return s.list(`SELECT `+attachmentColumns+` FROM attachments WHERE chat_id = ? ORDER BY id`, chatID)
+}
+
+// ListByMessage returns the attachments sent with a message
+func (s *AttachmentService) ListByMessage(chatID, messageID int64) ([]*models.Attachment, error) {
+	return s.list(`SELECT `+attachmentColumns+` FROM attachments WHERE chat_id = ? AND message_id = ? ORDER BY id`, chatID, messageID)
+}
+
+// GetPending returns uploaded attachments of a chat that have not been sent yet, in the given order
+func (s *AttachmentService) GetPending(chatID int64, attachmentIDs []int64) ([]*models.Attachment, error) {
+	attachments := make([]*models.Attachment, 0, len(attachmentIDs))
+	for _, id := range attachmentIDs {
+		attachment, err := s.Get(chatID, id)
+		if err != nil {
+			return nil, err
+		}
+		if attachment.MessageID != nil {
+			return nil, fmt.Errorf("attachment %d was already sent", id)
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// AttachToMessage links pending attachments to the message they were sent with
+func (s *AttachmentService) AttachToMessage(messageID int64, attachments []*models.Attachment) error {
+	for _, attachment := range attachments {
+		query := `UPDATE attachments SET message_id = ? WHERE id = ? AND message_id IS NULL`
+		if _, err := s.db.Exec(query, messageID, attachment.ID); err != nil {
+			return fmt.Errorf("failed to link attachment %d: %w", attachment.ID, err)
+		}
+		attachment.MessageID = &messageID
+	}
+	return nil
+}
+
+// AddToMessages sets the Attachments of the given messages of a chat
+func (s *AttachmentService) AddToMessages(chatID int64, messages []*models.Message) error {
+	attachments, err := s.ListByChat(chatID)
+	if err != nil {
+		return err
+	}
+
+	byMessage := make(map[int64][]*models.Attachment)
+	for _, attachment := range attachments {
+		if attachment.MessageID != nil {
+			byMessage[*attachment.MessageID] = append(byMessage[*attachment.MessageID], attachment)
+		}
+	}
+	for _, msg := range messages {
+		msg.Attachments = byMessage[msg.ID]
+	}
+	return nil
+}
+
+// Delete removes an attachment and its file
+func (s *AttachmentService) Delete(chatID, attachmentID int64) error {
+	attachment, err := s.Get(chatID, attachmentID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`DELETE FROM attachments WHERE id = ?`, attachment.ID); err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	if err := os.Remove(s.Path(attachment)); err != nil && !os.IsNotExist(err) {
+		utils.Warn("Failed to remove attachment file %s: %v", attachment.StoragePath, err)
+	}
+	return nil
+}
+
+// Path returns the absolute path of an attachment's file
+func (s *AttachmentService) Path(attachment *models.Attachment) string {
+	fullPath := filepath.Join(s.dir, attachment.StoragePath)
+	if abs, err := filepath.Abs(fullPath); err == nil {
+		return abs
+	}
+	return fullPath
+}
+
+// ProviderAttachments describes attachments for providers
+func (s *AttachmentService) ProviderAttachments(attachments []*models.Attachment) []providers.Attachment {
+	result := make([]providers.Attachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		result = append(result, providers.Attachment{
+			Path:        s.Path(attachment),
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Size:        attachment.Size,
+		})
+	}
+	return result
+}
+
+// PurgeOrphanedFiles removes the attachment directories of chats that no longer exist
+// (e.g. after PurgeDeletedChats) and returns how many were removed
+func (s *AttachmentService) PurgeOrphanedFiles() (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read attachments directory: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		chatID, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+
+		var exists int
+		err = s.db.QueryRow(`SELECT COUNT(*) FROM chats WHERE id = ?`, chatID).Scan(&exists)
+		if err != nil {
+			return removed, fmt.Errorf("failed to check chat %d: %w", chatID, err)
+		}
+		if exists > 0 {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(s.dir, entry.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove attachments of chat %d: %w", chatID, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// list runs an attachment query
+func (s *AttachmentService) list(query string, args ...any) ([]*models.Attachment, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []*models.Attachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// typeAllowed reports whether a content type matches one of the allowed patterns
+func (s *AttachmentService) typeAllowed(contentType string) bool {
+	for _, pattern := range s.allowedTypes {
+		if matched, _ := path.Match(pattern, contentType); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// scanAttachment reads an attachment selected with attachmentColumns
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	var attachment models.Attachment
+	var messageID sql.NullInt64
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.ChatID,
+		&messageID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.StoragePath,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if messageID.Valid {
+		attachment.MessageID = &messageID.Int64
+	}
+	return &attachment, nil
+}
+
+// detectContentType sniffs the content type of a stored file. Plain text is refined by the file
+// extension (e.g. text/markdown, text/csv) as long as the extension also maps to a text type.
+func detectContentType(fullPath, filename string) (string, error) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read attachment: %w", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if contentType == "text/plain" {
+		if byExt, _, err := mime.ParseMediaType(mime.TypeByExtension(filepath.Ext(filename))); err == nil && strings.HasPrefix(byExt, "text/") {
+			contentType = byExt
+		}
+	}
+	return contentType, nil
+}
+
+// sanitizeAttachmentFilename keeps the base name of an uploaded file without control characters
+func sanitizeAttachmentFilename(filename string) string {
+	filename = filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, filename)
+	if filename == "" || filename == "." || filename == "/" {
+		filename = "attachment"
+	}
+	if len(filename) > maxAttachmentFilenameLength {
+		filename = filename[:maxAttachmentFilenameLength]
+	}
+	return filename
+}
+
+// newStorageName returns a random file name keeping the upload's extension
+func newStorageName(filename string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate attachment name: %w", err)
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	if len(ext) > 16 || strings.ContainsAny(ext, " /\\") {
+		ext = ""
+	}
+	return hex.EncodeToString(b) + ext, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAttachmentService(t *testing.T) (*AttachmentService, *ChatService, *database.DB) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return NewAttachmentService(db, t.TempDir(), 1024, []string{"text/*", "image/png"}), NewChatService(db), db
+}
+
+func TestAttachmentService_Save(t *testing.T) {
+	service, chatService, _ := newTestAttachmentService(t)
+	chat, err := chatService.CreateChat(context.Background(), "Files", "claude")
+	require.NoError(t, err)
+
+	attachment, err := service.Save(chat.ID, "../../notes.md", strings.NewReader("# Notes\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "notes.md", attachment.Filename, "directories are stripped from the name")
+	assert.Equal(t, "text/markdown", attachment.ContentType, "text types are refined by extension")
+	assert.Equal(t, int64(8), attachment.Size)
+	assert.Nil(t, attachment.MessageID)
+
+	content, err := os.ReadFile(service.Path(attachment))
+	require.NoError(t, err)
+	assert.Equal(t, "# Notes\n", string(content))
+
+	got, err := service.Get(chat.ID, attachment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, attachment.StoragePath, got.StoragePath)
+
+	_, err = service.Get(chat.ID+1, attachment.ID)
+	assert.ErrorIs(t, err, ErrAttachmentNotFound, "attachments are scoped to their chat")
+}
+
+func TestAttachmentService_SaveRejects(t *testing.T) {
+	service, chatService, _ := newTestAttachmentService(t)
+	chat, err := chatService.CreateChat(context.Background(), "Files", "claude")
+	require.NoError(t, err)
+
+	_, err = service.Save(chat.ID, "big.txt", bytes.NewReader(bytes.Repeat([]byte("a"), 1025)))
+	assert.True(t, errors.Is(err, ErrAttachmentTooLarge))
+
+	// A PDF renamed to .txt is detected from its content
+	_, err = service.Save(chat.ID, "report.txt", strings.NewReader("%PDF-1.4\n"))
+	assert.True(t, errors.Is(err, ErrAttachmentTypeNotAllowed))
+
+	attachments, err := service.ListByChat(chat.ID)
+	require.NoError(t, err)
+	assert.Empty(t, attachments)
+
+	entries, err := os.ReadDir(filepath.Join(service.dir, strconv.FormatInt(chat.ID, 10)))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "rejected files are removed")
+}
+
+func TestAttachmentService_AttachToMessage(t *testing.T) {
+	service, chatService, _ := newTestAttachmentService(t)
+	chat, err := chatService.CreateChat(context.Background(), "Files", "claude")
+	require.NoError(t, err)
+
+	first, err := service.Save(chat.ID, "a.txt", strings.NewReader("a"))
+	require.NoError(t, err)
+	second, err := service.Save(chat.ID, "b.txt", strings.NewReader("b"))
+	require.NoError(t, err)
+
+	pending, err := service.GetPending(chat.ID, []int64{second.ID, first.ID})
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+
+	msg, err := chatService.AddMessage(context.Background(), chat.ID, "user", "Summarize these")
+	require.NoError(t, err)
+	require.NoError(t, service.AttachToMessage(msg.ID, pending))
+
+	linked, err := service.ListByMessage(chat.ID, msg.ID)
+	require.NoError(t, err)
+	require.Len(t, linked, 2)
+	assert.Equal(t, first.ID, linked[0].ID)
+
+	_, err = service.GetPending(chat.ID, []int64{first.ID})
+	assert.Error(t, err, "sent attachments can't be sent again")
+
+	messages, err := chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.NoError(t, service.AddToMessages(chat.ID, messages))
+	assert.Len(t, messages[len(messages)-1].Attachments, 2)
+
+	files := service.ProviderAttachments(linked)
+	require.Len(t, files, 2)
+	assert.Equal(t, "a.txt", files[0].Filename)
+	assert.Equal(t, service.Path(first), files[0].Path)
+}
+
+func TestAttachmentService_DeleteAndPurge(t *testing.T) {
+	service, chatService, _ := newTestAttachmentService(t)
+	kept, err := chatService.CreateChat(context.Background(), "Kept", "claude")
+	require.NoError(t, err)
+	purged, err := chatService.CreateChat(context.Background(), "Purged", "claude")
+	require.NoError(t, err)
+
+	deleted, err := service.Save(kept.ID, "a.txt", strings.NewReader("a"))
+	require.NoError(t, err)
+	remaining, err := service.Save(kept.ID, "b.txt", strings.NewReader("b"))
+	require.NoError(t, err)
+	orphan, err := service.Save(purged.ID, "c.txt", strings.NewReader("c"))
+	require.NoError(t, err)
+
+	require.NoError(t, service.Delete(kept.ID, deleted.ID))
+	assert.NoFileExists(t, service.Path(deleted))
+	assert.ErrorIs(t, service.Delete(kept.ID, deleted.ID), ErrAttachmentNotFound)
+
+	require.NoError(t, chatService.DeleteChat(context.Background(), purged.ID))
+	count, err := chatService.PurgeDeletedChats(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	removed, err := service.PurgeOrphanedFiles()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.NoFileExists(t, service.Path(orphan))
+	assert.FileExists(t, service.Path(remaining))
+}
+
+func TestAttachmentService_SaveImage(t *testing.T) {
+	service, chatService, _ := newTestAttachmentService(t)
+	chat, err := chatService.CreateChat(context.Background(), "Images", "claude")
+	require.NoError(t, err)
+
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"))
+	image, err := service.SaveImage(chat.ID, "", "data:image/png;base64,"+png)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", image.ContentType)
+	assert.Equal(t, "image", image.Filename)
+	assert.True(t, image.IsImage())
+
+	_, err = service.SaveImage(chat.ID, "notes.txt", base64.StdEncoding.EncodeToString([]byte("plain text")))
+	assert.ErrorIs(t, err, ErrAttachmentNotImage)
+
+	_, err = service.SaveImage(chat.ID, "broken.png", "not base64!")
+	assert.Error(t, err)
+
+	attachments, err := service.ListByChat(chat.ID)
+	require.NoError(t, err)
+	assert.Len(t, attachments, 1, "rejected images are not kept")
+}
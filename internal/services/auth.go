@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"ai-gateway-hub/internal/auth"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrInvalidRefreshToken is returned for refresh tokens that were never issued, expired, were
+// already used or belong to a user who can no longer sign in
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// NewRedisRefreshTokenStore keeps refresh tokens in Redis, so any instance can refresh them
+func NewRedisRefreshTokenStore(redisClient *redis.Client) *RedisTicketStore {
+	return &RedisTicketStore{redis: redisClient, prefix: "refresh_token"}
+}
+
+// AuthService signs users in for the jwt auth mode. Access tokens are stateless JWTs; refresh
+// tokens are random, kept hashed in a TicketStore and rotated on every use.
+type AuthService struct {
+	signer        *auth.Signer
+	users         *auth.Users
+	refreshTokens TicketStore
+	accessTTL     time.Duration
+	refreshTTL    time.Duration
+}
+
+func NewAuthService(signer *auth.Signer, users *auth.Users, refreshTokens TicketStore, accessTTL, refreshTTL time.Duration) *AuthService {
+	return &AuthService{
+		signer:        signer,
+		users:         users,
+		refreshTokens: refreshTokens,
+		accessTTL:     accessTTL,
+		refreshTTL:    refreshTTL,
+	}
+}
+
+// Login issues tokens for a user's ID and password
+func (s *AuthService) Login(ctx context.Context, userID, password string) (*models.AuthTokens, error) {
+	user, err := s.users.Authenticate(userID, password)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user)
+}
+
+// Refresh uses up a refresh token and issues new tokens with the user's current roles
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.AuthTokens, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	userID, ok, err := s.refreshTokens.Take(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+	user, ok := s.users.Get(userID)
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+	return s.issue(ctx, user)
+}
+
+// Logout revokes a refresh token; access tokens stay valid until they expire
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return nil
+	}
+	_, _, err := s.refreshTokens.Take(ctx, hashToken(refreshToken))
+	return err
+}
+
+// Verify checks an access token and returns its claims
+func (s *AuthService) Verify(accessToken string) (*auth.Claims, error) {
+	return s.signer.Verify(accessToken)
+}
+
+// issue signs an access token and stores a new refresh token for user
+func (s *AuthService) issue(ctx context.Context, user *auth.User) (*models.AuthTokens, error) {
+	access, _, err := s.signer.Issue(user.ID, user.Roles, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refresh := hex.EncodeToString(b)
+	if err := s.refreshTokens.Put(ctx, hashToken(refresh), user.ID, s.refreshTTL); err != nil {
+		return nil, err
+	}
+
+	roles := user.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return &models.AuthTokens{
+		AccessToken:      access,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(s.accessTTL / time.Second),
+		RefreshToken:     refresh,
+		RefreshExpiresIn: int64(s.refreshTTL / time.Second),
+		UserID:           user.ID,
+		Roles:            roles,
+	}, nil
+}
+
+// hashToken returns the key a refresh token is stored under, so stored keys can't be used as tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
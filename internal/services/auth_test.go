@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAuthService(t *testing.T) *AuthService {
+	hash, err := auth.HashPassword("secret")
+	require.NoError(t, err)
+	users, err := auth.ParseUsers([]byte("users:\n  - id: alice\n    password_hash: \"" + hash + "\"\n    roles: [admin]\n"))
+	require.NoError(t, err)
+	signer, err := auth.NewHS256Signer([]byte("0123456789abcdef0123456789abcdef"), "hub")
+	require.NoError(t, err)
+	return NewAuthService(signer, users, NewMemoryTicketStore(), 15*time.Minute, time.Hour)
+}
+
+func TestAuthService_LoginAndVerify(t *testing.T) {
+	ctx := context.Background()
+	service := newTestAuthService(t)
+
+	_, err := service.Login(ctx, "alice", "wrong")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+
+	tokens, err := service.Login(ctx, "alice", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, int64(900), tokens.ExpiresIn)
+	assert.Equal(t, int64(3600), tokens.RefreshExpiresIn)
+	assert.Equal(t, []string{"admin"}, tokens.Roles)
+
+	claims, err := service.Verify(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Subject)
+	assert.True(t, claims.HasRole("admin"))
+
+	// The refresh token isn't an access token
+	_, err = service.Verify(tokens.RefreshToken)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestAuthService_RefreshRotates(t *testing.T) {
+	ctx := context.Background()
+	service := newTestAuthService(t)
+
+	tokens, err := service.Login(ctx, "alice", "secret")
+	require.NoError(t, err)
+
+	refreshed, err := service.Refresh(ctx, tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+	assert.Equal(t, "alice", refreshed.UserID)
+
+	// Refresh tokens are single use
+	_, err = service.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = service.Refresh(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Stored keys are hashes, not the tokens themselves
+	store := service.refreshTokens.(*MemoryTicketStore)
+	assert.NotContains(t, store.tickets, refreshed.RefreshToken)
+
+	require.NoError(t, service.Logout(ctx, refreshed.RefreshToken))
+	_, err = service.Refresh(ctx, refreshed.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
@@ -1,85 +1,333 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/encryption"
+	apperrors "ai-gateway-hub/internal/errors"
 	"ai-gateway-hub/internal/models"
 )
 
+// Chat change actions reported to listeners
+const (
+	ChatCreated         = "created"
+	ChatRenamed         = "renamed"
+	ChatDeleted         = "deleted"
+	ChatMessage         = "message"
+	ChatArchived        = "archived"
+	ChatRestored        = "restored"
+	ChatProviderChanged = "provider_changed"
+	ChatOrganized       = "organized"
+)
+
+// ErrChatNotFound is returned for chats that don't exist or were deleted
+var ErrChatNotFound = apperrors.NotFound("chat not found")
+
+// ChatChangeListener is notified after a chat is created, renamed, archived, deleted, restored, switched to another provider,
+// tagged, moved to a folder or receives a message
+type ChatChangeListener func(action string, chatID int64)
+
 // ChatService handles chat-related operations
 type ChatService struct {
-	db *sql.DB
+	db        database.Store
+	listeners []ChatChangeListener
+	limits    MessageLimits
+	cipher    *encryption.Cipher
+	mu        sync.RWMutex
 }
 
-func NewChatService(db *sql.DB) *ChatService {
+func NewChatService(db database.Store) *ChatService {
 	return &ChatService{db: db}
 }
 
+// OnChange registers a listener for chat list changes
+func (s *ChatService) OnChange(listener ChatChangeListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// notify calls all registered change listeners
+func (s *ChatService) notify(action string, chatID int64) {
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(action, chatID)
+	}
+}
+
 // CreateChat creates a new chat
-func (s *ChatService) CreateChat(title, provider string) (*models.Chat, error) {
+func (s *ChatService) CreateChat(ctx context.Context, title, provider string) (*models.Chat, error) {
 	query := `
 		INSERT INTO chats (title, provider, created_at, updated_at)
 		VALUES (?, ?, ?, ?)
-		RETURNING id, title, provider, created_at, updated_at
+		RETURNING ` + chatColumns + `
 	`
 	
 	now := time.Now()
-	var chat models.Chat
+	chat, err := scanChat(s.db.QueryRowContext(ctx, query, title, provider, now, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat: %w", err)
+	}
 	
-	err := s.db.QueryRow(query, title, provider, now, now).Scan(
+	s.notify(ChatCreated, chat.ID)
+	return chat, nil
+}
+
+// Columns selected for a chat, in the order scanChat expects
+const chatColumns = "id, title, provider, system_prompt, created_at, updated_at, archived_at, deleted_at, folder_id, parent_chat_id, forked_from_message_id"
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanChat reads a chat selected with chatColumns
+func scanChat(row rowScanner) (*models.Chat, error) {
+	var chat models.Chat
+	var archivedAt, deletedAt sql.NullTime
+	var folderID, parentChatID, forkedFromMessageID sql.NullInt64
+	err := row.Scan(
 		&chat.ID,
 		&chat.Title,
 		&chat.Provider,
+		&chat.SystemPrompt,
 		&chat.CreatedAt,
 		&chat.UpdatedAt,
+		&archivedAt,
+		&deletedAt,
+		&folderID,
+		&parentChatID,
+		&forkedFromMessageID,
 	)
-	
 	if err != nil {
-		return nil, fmt.Errorf("failed to create chat: %w", err)
+		return nil, err
+	}
+	if archivedAt.Valid {
+		chat.ArchivedAt = &archivedAt.Time
+	}
+	if deletedAt.Valid {
+		chat.DeletedAt = &deletedAt.Time
+	}
+	if folderID.Valid {
+		chat.FolderID = &folderID.Int64
+	}
+	if parentChatID.Valid {
+		chat.ParentChatID = &parentChatID.Int64
+	}
+	if forkedFromMessageID.Valid {
+		chat.ForkedFromMessageID = &forkedFromMessageID.Int64
 	}
-	
 	return &chat, nil
 }
 
-// GetChat retrieves a chat by ID
-func (s *ChatService) GetChat(id int64) (*models.Chat, error) {
+// Columns selected for a message, in the order scanMessage expects
+const messageColumns = "id, chat_id, role, content, provider, model, status, user_id, created_at, latency_ms, tokens_in, tokens_out, tokens_estimated, finish_reason, failed_over"
+
+// scanMessage reads a message selected with messageColumns
+func scanMessage(row rowScanner) (*models.Message, error) {
+	var msg models.Message
+	var latencyMs, tokensIn, tokensOut sql.NullInt64
+	var tokensEstimated bool
+	var finishReason, failedOver string
+	err := row.Scan(
+		&msg.ID,
+		&msg.ChatID,
+		&msg.Role,
+		&msg.Content,
+		&msg.Provider,
+		&msg.Model,
+		&msg.Status,
+		&msg.UserID,
+		&msg.CreatedAt,
+		&latencyMs,
+		&tokensIn,
+		&tokensOut,
+		&tokensEstimated,
+		&finishReason,
+		&failedOver,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if finishReason != "" {
+		msg.Metadata = &models.MessageMetadata{
+			Model:           msg.Model,
+			Provider:        msg.Provider,
+			LatencyMs:       latencyMs.Int64,
+			TokensIn:        tokensIn.Int64,
+			TokensOut:       tokensOut.Int64,
+			TokensEstimated: tokensEstimated,
+			FinishReason:    finishReason,
+		}
+		if failedOver != "" {
+			msg.Metadata.FailedOver = strings.Split(failedOver, ",")
+		}
+	}
+	return &msg, nil
+}
+
+// GetChat retrieves a chat by ID; archived chats are returned, deleted chats are not
+func (s *ChatService) GetChat(ctx context.Context, id int64) (*models.Chat, error) {
 	query := `
-		SELECT id, title, provider, created_at, updated_at
+		SELECT ` + chatColumns + `
 		FROM chats
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 	
-	var chat models.Chat
-	err := s.db.QueryRow(query, id).Scan(
-		&chat.ID,
-		&chat.Title,
-		&chat.Provider,
-		&chat.CreatedAt,
-		&chat.UpdatedAt,
-	)
-	
+	chat, err := scanChat(s.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("chat not found")
+		return nil, ErrChatNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chat: %w", err)
 	}
 	
-	return &chat, nil
+	if err := s.loadChatTags(ctx, []*models.Chat{chat}); err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+// Length of the latest message's preview in chat details
+const chatPreviewLength = 200
+
+// GetChatDetails retrieves a chat with its message count and a preview of its latest message
+func (s *ChatService) GetChatDetails(ctx context.Context, id int64) (*models.ChatDetails, error) {
+	chat, err := s.GetChat(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &models.ChatDetails{Chat: chat}
+	if details.BranchTree, err = s.BranchTree(ctx, chat); err != nil {
+		return nil, err
+	}
+	if details.MessageCount, err = s.CountMessages(ctx, id); err != nil {
+		return nil, err
+	}
+	if details.MessageCount == 0 {
+		return details, nil
+	}
+
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE chat_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+	msg, err := s.scanMessage(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last message: %w", err)
+	}
+	details.LastMessage = &models.MessagePreview{
+		ID:        msg.ID,
+		Role:      msg.Role,
+		Provider:  msg.Provider,
+		Preview:   truncateRunes(msg.Content, chatPreviewLength),
+		CreatedAt: msg.CreatedAt,
+	}
+	return details, nil
+}
+
+// GetChats retrieves active chats, excluding archived and deleted ones
+func (s *ChatService) GetChats(ctx context.Context, limit, offset int) ([]*models.Chat, error) {
+	return s.ListChats(ctx, ChatFilter{}, limit, offset)
+}
+
+// GetArchivedChats retrieves archived chats that have not been deleted
+func (s *ChatService) GetArchivedChats(ctx context.Context, limit, offset int) ([]*models.Chat, error) {
+	return s.ListChats(ctx, ChatFilter{Archived: true}, limit, offset)
+}
+
+// ChatFilter narrows a chat listing
+type ChatFilter struct {
+	// Archived lists archived chats instead of active ones
+	Archived bool
+	// Tag lists only chats carrying this tag
+	Tag string
+	// FolderID lists only chats in this folder; 0 lists chats that are not in any folder
+	FolderID *int64
+}
+
+// ListChats retrieves chats that have not been deleted and match the filter, most recently updated first
+func (s *ChatService) ListChats(ctx context.Context, filter ChatFilter, limit, offset int) ([]*models.Chat, error) {
+	condition, args := filter.where()
+	return s.listChats(ctx, condition, args, limit, offset)
+}
+
+// CountChats counts the chats that have not been deleted and match the filter
+func (s *ChatService) CountChats(ctx context.Context, filter ChatFilter) (int64, error) {
+	condition, args := filter.where()
+	var total int64
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM chats WHERE `+condition, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count chats: %w", err)
+	}
+	return total, nil
+}
+
+// PageChats retrieves a page of the chats matching the filter along with their total count
+func (s *ChatService) PageChats(ctx context.Context, filter ChatFilter, limit, offset int) (*models.ChatPage, error) {
+	total, err := s.CountChats(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	chats, err := s.ListChats(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if chats == nil {
+		chats = []*models.Chat{}
+	}
+	return &models.ChatPage{Items: chats, Pagination: newPagination(total, limit, offset, len(chats))}, nil
+}
+
+// where returns the WHERE condition selecting the chats of the filter, with its arguments
+func (filter ChatFilter) where() (string, []any) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []any
+	
+	if filter.Archived {
+		conditions = append(conditions, "archived_at IS NOT NULL")
+	} else {
+		conditions = append(conditions, "archived_at IS NULL")
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, "id IN (SELECT ct.chat_id FROM chat_tags ct JOIN tags t ON t.id = ct.tag_id WHERE t.name = ?)")
+		args = append(args, NormalizeTag(filter.Tag))
+	}
+	if filter.FolderID != nil {
+		if *filter.FolderID == 0 {
+			conditions = append(conditions, "folder_id IS NULL")
+		} else {
+			conditions = append(conditions, "folder_id = ?")
+			args = append(args, *filter.FolderID)
+		}
+	}
+	
+	return strings.Join(conditions, " AND "), args
 }
 
-// GetChats retrieves all chats
-func (s *ChatService) GetChats(limit, offset int) ([]*models.Chat, error) {
+// listChats retrieves chats matching a WHERE condition with its arguments, most recently updated first
+func (s *ChatService) listChats(ctx context.Context, condition string, args []any, limit, offset int) ([]*models.Chat, error) {
 	query := `
-		SELECT id, title, provider, created_at, updated_at
+		SELECT ` + chatColumns + `
 		FROM chats
+		WHERE ` + condition + `
 		ORDER BY updated_at DESC
 		LIMIT ? OFFSET ?
 	`
 	
-	rows, err := s.db.Query(query, limit, offset)
+	rows, err := s.db.QueryContext(ctx, query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chats: %w", err)
 	}
@@ -87,93 +335,407 @@ func (s *ChatService) GetChats(limit, offset int) ([]*models.Chat, error) {
 	
 	var chats []*models.Chat
 	for rows.Next() {
-		var chat models.Chat
-		err := rows.Scan(
-			&chat.ID,
-			&chat.Title,
-			&chat.Provider,
-			&chat.CreatedAt,
-			&chat.UpdatedAt,
-		)
+		chat, err := scanChat(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan chat: %w", err)
 		}
-		chats = append(chats, &chat)
+		chats = append(chats, chat)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get chats: %w", err)
+	}
+	rows.Close()
 	
+	if err := s.loadChatTags(ctx, chats); err != nil {
+		return nil, err
+	}
 	return chats, nil
 }
 
 // UpdateChat updates a chat's details
-func (s *ChatService) UpdateChat(id int64, title string) error {
+func (s *ChatService) UpdateChat(ctx context.Context, id int64, title string) error {
 	query := `
 		UPDATE chats
 		SET title = ?, updated_at = ?
 		WHERE id = ?
 	`
 	
-	_, err := s.db.Exec(query, title, time.Now(), id)
+	_, err := s.db.ExecContext(ctx, query, title, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("failed to update chat: %w", err)
 	}
 	
+	s.notify(ChatRenamed, id)
+	return nil
+}
+
+// UpdateSystemPrompt sets the system prompt prepended to every prompt in a chat
+func (s *ChatService) UpdateSystemPrompt(ctx context.Context, id int64, systemPrompt string) error {
+	query := `
+		UPDATE chats
+		SET system_prompt = ?, updated_at = ?
+		WHERE id = ?
+	`
+	
+	result, err := s.db.ExecContext(ctx, query, systemPrompt, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update system prompt: %w", err)
+	}
+	
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrChatNotFound
+	}
+	
+	return nil
+}
+
+// BuildProviderInput prepends a chat's system prompt to the user's prompt
+func BuildProviderInput(systemPrompt, prompt string) string {
+	if strings.TrimSpace(systemPrompt) == "" {
+		return prompt
+	}
+	return strings.TrimSpace(systemPrompt) + "\n\n" + prompt
+}
+
+// Maximum size of the conversation handed over to a provider after a switch; older messages are left out
+const MaxHandoffHistoryBytes = 32 << 10
+
+// FormatHandoffHistory prepends the conversation a provider hasn't seen to a prompt
+func FormatHandoffHistory(history []*models.Message, prompt string) string {
+	var lines []string
+	size := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		msg := history[i]
+		speaker := "User"
+		if msg.Role == "assistant" {
+			speaker = "Assistant"
+			if msg.Provider != "" {
+				speaker += " (" + msg.Provider + ")"
+			}
+		}
+		line := speaker + ": " + msg.Content
+		if size+len(line) > MaxHandoffHistoryBytes {
+			break
+		}
+		size += len(line)
+		lines = append([]string{line}, lines...)
+	}
+	if len(lines) == 0 {
+		return prompt
+	}
+	return "Conversation so far:\n\n" + strings.Join(lines, "\n\n") + "\n\nContinue the conversation and answer:\n\n" + prompt
+}
+
+// SwitchProvider moves a chat to another provider and records the switch as a system message
+// annotated with the new provider. Switching to the current provider is a no-op.
+func (s *ChatService) SwitchProvider(ctx context.Context, id int64, provider, note string) (*models.Chat, error) {
+	chat, err := s.GetChat(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if chat.Provider == provider {
+		return chat, nil
+	}
+
+	if _, err := s.db.ExecContext(ctx, `UPDATE chats SET provider = ?, updated_at = ? WHERE id = ?`, provider, time.Now(), id); err != nil {
+		return nil, fmt.Errorf("failed to switch provider: %w", err)
+	}
+	if _, err := s.AddProviderMessage(ctx, id, "system", note, provider); err != nil {
+		return nil, err
+	}
+	// A session from before the switch hasn't seen the messages since
+	if err := s.ClearProviderSessions(ctx, id); err != nil {
+		return nil, err
+	}
+
+	s.notify(ChatProviderChanged, id)
+	return s.GetChat(ctx, id)
+}
+
+// HandoffHistory returns the conversation a provider hasn't seen because the chat was switched to
+// it: the user and assistant messages before the latest switch, until the provider first answers
+func (s *ChatService) HandoffHistory(ctx context.Context, chatID int64, provider string) ([]*models.Message, error) {
+	// Switches are the only system messages annotated with a provider
+	var switchID int64
+	var switchedTo string
+	query := `
+		SELECT id, provider FROM messages
+		WHERE chat_id = ? AND role = 'system' AND provider <> ''
+		ORDER BY id DESC
+		LIMIT 1
+	`
+	err := s.db.QueryRowContext(ctx, query, chatID).Scan(&switchID, &switchedTo)
+	if err == sql.ErrNoRows || (err == nil && switchedTo != provider) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find provider switch: %w", err)
+	}
+
+	var answered int
+	query = `SELECT COUNT(*) FROM messages WHERE chat_id = ? AND id > ? AND role = 'assistant' AND provider = ? AND status = ?`
+	if err := s.db.QueryRowContext(ctx, query, chatID, switchID, provider, models.MessageComplete).Scan(&answered); err != nil {
+		return nil, fmt.Errorf("failed to check provider answers: %w", err)
+	}
+	if answered > 0 {
+		return nil, nil
+	}
+
+	return s.ConversationBefore(ctx, chatID, switchID)
+}
+
+// ConversationBefore returns the user and assistant messages of a chat that precede a message,
+// leaving out responses that are still streaming
+func (s *ChatService) ConversationBefore(ctx context.Context, chatID, messageID int64) ([]*models.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE chat_id = ? AND id < ? AND role IN ('user', 'assistant') AND status <> ?
+		ORDER BY id
+	`
+	rows, err := s.db.QueryContext(ctx, query, chatID, messageID, models.MessageStreaming)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*models.Message
+	for rows.Next() {
+		msg, err := s.scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		history = append(history, msg)
+	}
+	return history, rows.Err()
+}
+
+// ProviderSession returns the native session a provider keeps for a chat, or "" when there is none
+func (s *ChatService) ProviderSession(ctx context.Context, chatID int64, provider string) (string, error) {
+	var sessionID string
+	query := `SELECT session_id FROM provider_sessions WHERE chat_id = ? AND provider = ?`
+	err := s.db.QueryRowContext(ctx, query, chatID, provider).Scan(&sessionID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get provider session: %w", err)
+	}
+	return sessionID, nil
+}
+
+// SetProviderSession stores the native session a provider continues a chat in
+func (s *ChatService) SetProviderSession(ctx context.Context, chatID int64, provider, sessionID string) error {
+	query := `
+		INSERT INTO provider_sessions (chat_id, provider, session_id, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (chat_id, provider) DO UPDATE SET session_id = excluded.session_id, updated_at = excluded.updated_at
+	`
+	if _, err := s.db.ExecContext(ctx, query, chatID, provider, sessionID, time.Now()); err != nil {
+		return fmt.Errorf("failed to save provider session: %w", err)
+	}
 	return nil
 }
 
-// DeleteChat deletes a chat and its messages
-func (s *ChatService) DeleteChat(id int64) error {
-	query := `DELETE FROM chats WHERE id = ?`
+// ClearProviderSessions forgets the native sessions of a chat, e.g. after its history was edited
+// so the sessions no longer match it
+func (s *ChatService) ClearProviderSessions(ctx context.Context, chatID int64) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM provider_sessions WHERE chat_id = ?`, chatID); err != nil {
+		return fmt.Errorf("failed to clear provider sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteChat moves a chat to the trash; it is hidden everywhere and permanently
+// removed with its messages by PurgeDeletedChats. Deleting twice is a no-op;
+// ErrChatNotFound is returned for chats that don't exist.
+func (s *ChatService) DeleteChat(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin chat deletion: %w", err)
+	}
+	defer tx.Rollback()
+	
+	var deletedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `SELECT deleted_at FROM chats WHERE id = ?`, id).Scan(&deletedAt)
+	if err == sql.ErrNoRows {
+		return ErrChatNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get chat: %w", err)
+	}
+	if deletedAt.Valid {
+		return nil
+	}
 	
-	_, err := s.db.Exec(query, id)
+	result, err := tx.ExecContext(ctx, `UPDATE chats SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("failed to delete chat: %w", err)
 	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check chat deletion: %w", err)
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chat deletion: %w", err)
+	}
+	if n > 0 {
+		s.notify(ChatDeleted, id)
+	}
+	return nil
+}
+
+// ArchiveChat hides a chat from the default listing without deleting it
+func (s *ChatService) ArchiveChat(ctx context.Context, id int64) error {
+	query := `
+		UPDATE chats
+		SET archived_at = COALESCE(archived_at, ?)
+		WHERE id = ? AND deleted_at IS NULL
+	`
+	
+	result, err := s.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to archive chat: %w", err)
+	}
+	
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrChatNotFound
+	}
+	
+	s.notify(ChatArchived, id)
+	return nil
+}
+
+// RestoreChat brings an archived or deleted (but not yet purged) chat back to the default listing
+func (s *ChatService) RestoreChat(ctx context.Context, id int64) error {
+	query := `UPDATE chats SET archived_at = NULL, deleted_at = NULL WHERE id = ?`
+	
+	result, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore chat: %w", err)
+	}
 	
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrChatNotFound
+	}
+	
+	s.notify(ChatRestored, id)
 	return nil
 }
 
+// PurgeDeletedChats permanently removes chats deleted before the cutoff together with
+// their messages, feedback, share links, attachments, scheduled prompts, provider sessions, tags, generation events and usage records, and returns how many were removed
+func (s *ChatService) PurgeDeletedChats(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin purge: %w", err)
+	}
+	defer tx.Rollback()
+	
+	// Dependent rows are removed explicitly so purging doesn't rely on foreign key enforcement
+	purged := `SELECT id FROM chats WHERE deleted_at IS NOT NULL AND deleted_at < ?`
+	for _, table := range []string{"attachments", "scheduled_prompt_runs", "scheduled_prompts", "provider_sessions", "chat_tags", "message_feedback", "chat_shares", "chat_processors", "chat_collections", "chat_tools", "messages", "generation_events", "usage_records"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE chat_id IN (`+purged+`)`, before); err != nil {
+			return 0, fmt.Errorf("failed to purge %s: %w", table, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE sessions SET chat_id = NULL WHERE chat_id IN (`+purged+`)`, before); err != nil {
+		return 0, fmt.Errorf("failed to purge sessions: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE chats SET parent_chat_id = NULL WHERE parent_chat_id IN (`+purged+`)`, before); err != nil {
+		return 0, fmt.Errorf("failed to purge chat branches: %w", err)
+	}
+	
+	result, err := tx.ExecContext(ctx, `DELETE FROM chats WHERE deleted_at IS NOT NULL AND deleted_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge chats: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count purged chats: %w", err)
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	return count, nil
+}
+
 // AddMessage adds a message to a chat
-func (s *ChatService) AddMessage(chatID int64, role, content string) (*models.Message, error) {
-	// Update chat's updated_at timestamp
-	updateQuery := `UPDATE chats SET updated_at = ? WHERE id = ?`
-	if _, err := s.db.Exec(updateQuery, time.Now(), chatID); err != nil {
+func (s *ChatService) AddMessage(ctx context.Context, chatID int64, role, content string) (*models.Message, error) {
+	return s.AddProviderMessage(ctx, chatID, role, content, "")
+}
+
+// AddProviderMessage adds a message to a chat annotated with the provider that produced it.
+// The chat's timestamp and the message are written together; ErrChatNotFound is returned
+// for chats that don't exist. Chats in the trash still take messages so a response that
+// finishes after its chat was deleted is kept for a restore.
+func (s *ChatService) AddProviderMessage(ctx context.Context, chatID int64, role, content, provider string) (*models.Message, error) {
+	return s.addMessage(ctx, chatID, role, content, provider, "")
+}
+
+// AddUserMessage adds a prompt to a chat attributed to the participant who sent it
+func (s *ChatService) AddUserMessage(ctx context.Context, chatID int64, content, userID string) (*models.Message, error) {
+	return s.addMessage(ctx, chatID, "user", content, "", userID)
+}
+
+// addMessage writes a message and the chat's timestamp together
+func (s *ChatService) addMessage(ctx context.Context, chatID int64, role, content, provider, userID string) (*models.Message, error) {
+	sealed, err := s.sealContent(content)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin adding message: %w", err)
+	}
+	defer tx.Rollback()
+	
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `UPDATE chats SET updated_at = ? WHERE id = ?`, now, chatID)
+	if err != nil {
 		return nil, fmt.Errorf("failed to update chat timestamp: %w", err)
 	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chat timestamp: %w", err)
+	}
+	if n == 0 {
+		return nil, ErrChatNotFound
+	}
 	
-	// Insert message
 	query := `
-		INSERT INTO messages (chat_id, role, content, created_at)
-		VALUES (?, ?, ?, ?)
-		RETURNING id, chat_id, role, content, created_at
+		INSERT INTO messages (chat_id, role, content, provider, user_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING ` + messageColumns + `
 	`
 	
-	var msg models.Message
-	err := s.db.QueryRow(query, chatID, role, content, time.Now()).Scan(
-		&msg.ID,
-		&msg.ChatID,
-		&msg.Role,
-		&msg.Content,
-		&msg.CreatedAt,
-	)
-	
+	msg, err := s.scanMessage(tx.QueryRowContext(ctx, query, chatID, role, sealed, provider, userID, now))
 	if err != nil {
 		return nil, fmt.Errorf("failed to add message: %w", err)
 	}
 	
-	return &msg, nil
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit message: %w", err)
+	}
+	
+	s.notify(ChatMessage, chatID)
+	return msg, nil
 }
 
 // GetMessages retrieves messages for a chat
-func (s *ChatService) GetMessages(chatID int64, limit, offset int) ([]*models.Message, error) {
+func (s *ChatService) GetMessages(ctx context.Context, chatID int64, limit, offset int) ([]*models.Message, error) {
 	query := `
-		SELECT id, chat_id, role, content, created_at
+		SELECT ` + messageColumns + `
 		FROM messages
 		WHERE chat_id = ?
 		ORDER BY created_at ASC
 		LIMIT ? OFFSET ?
 	`
 	
-	rows, err := s.db.Query(query, chatID, limit, offset)
+	rows, err := s.db.QueryContext(ctx, query, chatID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get messages: %w", err)
 	}
@@ -181,19 +743,248 @@ func (s *ChatService) GetMessages(chatID int64, limit, offset int) ([]*models.Me
 	
 	var messages []*models.Message
 	for rows.Next() {
-		var msg models.Message
-		err := rows.Scan(
-			&msg.ID,
-			&msg.ChatID,
-			&msg.Role,
-			&msg.Content,
-			&msg.CreatedAt,
-		)
+		msg, err := s.scanMessage(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
-		messages = append(messages, &msg)
+		messages = append(messages, msg)
 	}
 	
 	return messages, nil
-}
\ No newline at end of file
+}
+
+// CountMessages counts the messages of a chat
+func (s *ChatService) CountMessages(ctx context.Context, chatID int64) (int64, error) {
+	var total int64
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM messages WHERE chat_id = ?`, chatID).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count messages: %w", err)
+	}
+	return total, nil
+}
+
+// PageMessages retrieves a page of a chat's messages, oldest first, along with their total count
+func (s *ChatService) PageMessages(ctx context.Context, chatID int64, limit, offset int) (*models.MessagePage, error) {
+	total, err := s.CountMessages(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.GetMessages(ctx, chatID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if messages == nil {
+		messages = []*models.Message{}
+	}
+	return &models.MessagePage{Items: messages, Pagination: newPagination(total, limit, offset, len(messages))}, nil
+}
+
+// newPagination describes a page of count items taken at offset from a list of total items
+func newPagination(total int64, limit, offset, count int) models.Pagination {
+	return models.Pagination{
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: int64(offset+count) < total,
+	}
+}
+
+// GetMessage retrieves a single message of a chat
+func (s *ChatService) GetMessage(ctx context.Context, chatID, messageID int64) (*models.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE id = ? AND chat_id = ?
+	`
+	
+	msg, err := s.scanMessage(s.db.QueryRowContext(ctx, query, messageID, chatID))
+	
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("message not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	
+	return msg, nil
+}
+
+// GetLastUserMessage retrieves the most recent user message of a chat
+func (s *ChatService) GetLastUserMessage(ctx context.Context, chatID int64) (*models.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE chat_id = ? AND role = 'user'
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+	
+	msg, err := s.scanMessage(s.db.QueryRowContext(ctx, query, chatID))
+	
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("message not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	
+	return msg, nil
+}
+
+// UpdateMessageContent replaces the content of a message
+func (s *ChatService) UpdateMessageContent(ctx context.Context, chatID, messageID int64, content string) (*models.Message, error) {
+	sealed, err := s.sealContent(content)
+	if err != nil {
+		return nil, err
+	}
+	query := `UPDATE messages SET content = ? WHERE id = ? AND chat_id = ?`
+	
+	result, err := s.db.ExecContext(ctx, query, sealed, messageID, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update message: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return nil, fmt.Errorf("message not found")
+	}
+	
+	if _, err := s.db.ExecContext(ctx, `UPDATE chats SET updated_at = ? WHERE id = ?`, time.Now(), chatID); err != nil {
+		return nil, fmt.Errorf("failed to update chat timestamp: %w", err)
+	}
+	if err := s.ClearProviderSessions(ctx, chatID); err != nil {
+		return nil, err
+	}
+	
+	s.notify(ChatMessage, chatID)
+	return s.GetMessage(ctx, chatID, messageID)
+}
+
+// SetMessageModel records the model an assistant message was requested with
+func (s *ChatService) SetMessageModel(ctx context.Context, messageID int64, model string) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE messages SET model = ? WHERE id = ?`, model, messageID); err != nil {
+		return fmt.Errorf("failed to set message model: %w", err)
+	}
+	return nil
+}
+
+// SetMessageMetadata records how an assistant message was generated; the model and provider are
+// the message's own
+func (s *ChatService) SetMessageMetadata(ctx context.Context, messageID int64, meta *models.MessageMetadata) error {
+	query := `UPDATE messages SET latency_ms = ?, tokens_in = ?, tokens_out = ?, tokens_estimated = ?, finish_reason = ?, failed_over = ? WHERE id = ?`
+	if _, err := s.db.ExecContext(ctx, query, meta.LatencyMs, meta.TokensIn, meta.TokensOut, meta.TokensEstimated, meta.FinishReason, strings.Join(meta.FailedOver, ","), messageID); err != nil {
+		return fmt.Errorf("failed to set message metadata: %w", err)
+	}
+	return nil
+}
+
+// DeleteMessagesAfter deletes every message that follows the given one in a chat
+// (e.g. the responses to a prompt that is regenerated) and returns how many were removed
+func (s *ChatService) DeleteMessagesAfter(ctx context.Context, chatID, messageID int64) (int64, error) {
+	// Feedback on a regenerated response doesn't apply to the new one
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM message_feedback WHERE chat_id = ? AND message_id > ?`, chatID, messageID); err != nil {
+		return 0, fmt.Errorf("failed to delete message feedback: %w", err)
+	}
+	
+	query := `DELETE FROM messages WHERE chat_id = ? AND id > ?`
+	
+	result, err := s.db.ExecContext(ctx, query, chatID, messageID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete messages: %w", err)
+	}
+	
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted messages: %w", err)
+	}
+	
+	if deleted > 0 {
+		if err := s.ClearProviderSessions(ctx, chatID); err != nil {
+			return 0, err
+		}
+		s.notify(ChatMessage, chatID)
+	}
+	return deleted, nil
+}
+
+// StartStreamingMessage saves the first checkpoint of an assistant response that is still streaming
+func (s *ChatService) StartStreamingMessage(ctx context.Context, chatID int64, provider, content string) (*models.Message, error) {
+	sealed, err := s.sealContent(content)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		INSERT INTO messages (chat_id, role, content, provider, status, checkpointed_at, created_at)
+		VALUES (?, 'assistant', ?, ?, ?, ?, ?)
+		RETURNING ` + messageColumns + `
+	`
+
+	now := time.Now()
+	msg, err := s.scanMessage(s.db.QueryRowContext(ctx, query, chatID, sealed, provider, models.MessageStreaming, now, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkpoint message: %w", err)
+	}
+	return msg, nil
+}
+
+// CheckpointMessage saves the response received so far for a streaming message
+func (s *ChatService) CheckpointMessage(ctx context.Context, messageID int64, content string) error {
+	sealed, err := s.sealContent(content)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE messages SET content = ?, checkpointed_at = ? WHERE id = ? AND status = ?`
+	if _, err := s.db.ExecContext(ctx, query, sealed, time.Now(), messageID, models.MessageStreaming); err != nil {
+		return fmt.Errorf("failed to checkpoint message: %w", err)
+	}
+	return nil
+}
+
+// FinishStreamingMessage saves the complete response of a streaming message
+func (s *ChatService) FinishStreamingMessage(ctx context.Context, chatID, messageID int64, content string) (*models.Message, error) {
+	sealed, err := s.sealContent(content)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE chats SET updated_at = ? WHERE id = ?`, time.Now(), chatID); err != nil {
+		return nil, fmt.Errorf("failed to update chat timestamp: %w", err)
+	}
+
+	query := `
+		UPDATE messages SET content = ?, status = ?, checkpointed_at = NULL
+		WHERE id = ? AND chat_id = ?
+		RETURNING ` + messageColumns + `
+	`
+	msg, err := s.scanMessage(s.db.QueryRowContext(ctx, query, sealed, models.MessageComplete, messageID, chatID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("message not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish message: %w", err)
+	}
+
+	s.notify(ChatMessage, chatID)
+	return msg, nil
+}
+
+// DiscardStreamingMessage removes the checkpoint of a response that failed
+func (s *ChatService) DiscardStreamingMessage(ctx context.Context, messageID int64) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM messages WHERE id = ? AND status = ?`, messageID, models.MessageStreaming); err != nil {
+		return fmt.Errorf("failed to discard message: %w", err)
+	}
+	return nil
+}
+
+// FlagInterruptedMessages marks responses that started streaming before the given time and never
+// finished (e.g. because the server crashed) as interrupted, keeping their partial content
+func (s *ChatService) FlagInterruptedMessages(ctx context.Context, before time.Time) (int64, error) {
+	query := `UPDATE messages SET status = ? WHERE status = ? AND created_at < ?`
+
+	result, err := s.db.ExecContext(ctx, query, models.MessageInterrupted, models.MessageStreaming, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to flag interrupted messages: %w", err)
+	}
+
+	flagged, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count interrupted messages: %w", err)
+	}
+	return flagged, nil
+}
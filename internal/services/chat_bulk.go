@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+)
+
+// Bulk operations on chats
+const (
+	BulkDelete  = "delete"
+	BulkArchive = "archive"
+	BulkTag     = "tag"
+)
+
+// Maximum number of chats in one bulk operation
+const MaxBulkChats = 200
+
+// ErrInvalidBulkRequest is returned for unknown actions and empty or oversized chat lists
+var ErrInvalidBulkRequest = apperrors.Validation("invalid bulk request")
+
+// bulkNotifications maps each bulk action to the chat list change it announces
+var bulkNotifications = map[string]string{
+	BulkDelete:  ChatDeleted,
+	BulkArchive: ChatArchived,
+	BulkTag:     ChatOrganized,
+}
+
+// BulkUpdateChats deletes, archives or tags (with tag) several chats in one transaction.
+// Chats that don't exist are reported in their result without affecting the others; a
+// database error rolls the whole operation back.
+func (s *ChatService) BulkUpdateChats(ctx context.Context, action string, chatIDs []int64, tag string) (*models.BulkChatResponse, error) {
+	if _, ok := bulkNotifications[action]; !ok {
+		return nil, fmt.Errorf("%w: action must be delete, archive or tag", ErrInvalidBulkRequest)
+	}
+	chatIDs = uniqueIDs(chatIDs)
+	if len(chatIDs) == 0 || len(chatIDs) > MaxBulkChats {
+		return nil, fmt.Errorf("%w: between 1 and %d chat IDs are required", ErrInvalidBulkRequest, MaxBulkChats)
+	}
+	if action == BulkTag {
+		var err error
+		if tag, err = ValidateTag(tag); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin bulk %s: %w", action, err)
+	}
+	defer tx.Rollback()
+
+	var tagID int64
+	if action == BulkTag {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING`, tag); err != nil {
+			return nil, fmt.Errorf("failed to create tag: %w", err)
+		}
+		if err := tx.QueryRowContext(ctx, `SELECT id FROM tags WHERE name = ?`, tag).Scan(&tagID); err != nil {
+			return nil, fmt.Errorf("failed to get tag: %w", err)
+		}
+	}
+
+	response := &models.BulkChatResponse{Action: action, Results: make([]*models.BulkChatResult, 0, len(chatIDs))}
+	var changed []int64
+	now := time.Now()
+	for _, id := range chatIDs {
+		var applied bool
+		switch action {
+		case BulkDelete:
+			applied, err = bulkDeleteChat(ctx, tx, id, now)
+		case BulkArchive:
+			applied, err = bulkArchiveChat(ctx, tx, id, now)
+		case BulkTag:
+			applied, err = bulkTagChat(ctx, tx, id, tagID)
+		}
+
+		result := &models.BulkChatResult{ChatID: id, OK: err == nil}
+		switch {
+		case errors.Is(err, ErrChatNotFound):
+			result.Error = err.Error()
+			response.Failed++
+		case err != nil:
+			return nil, fmt.Errorf("failed to %s chat %d: %w", action, id, err)
+		default:
+			response.Succeeded++
+			if applied {
+				changed = append(changed, id)
+			}
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit bulk %s: %w", action, err)
+	}
+	for _, id := range changed {
+		s.notify(bulkNotifications[action], id)
+	}
+	return response, nil
+}
+
+// bulkDeleteChat moves a chat to the trash; deleting it twice is a no-op
+func bulkDeleteChat(ctx context.Context, tx *database.Tx, id int64, now time.Time) (bool, error) {
+	var deletedAt sql.NullTime
+	err := tx.QueryRowContext(ctx, `SELECT deleted_at FROM chats WHERE id = ?`, id).Scan(&deletedAt)
+	if err == sql.ErrNoRows {
+		return false, ErrChatNotFound
+	}
+	if err != nil || deletedAt.Valid {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE chats SET deleted_at = ? WHERE id = ?`, now, id)
+	return err == nil, err
+}
+
+// bulkArchiveChat archives a chat that isn't deleted
+func bulkArchiveChat(ctx context.Context, tx *database.Tx, id int64, now time.Time) (bool, error) {
+	result, err := tx.ExecContext(ctx, `UPDATE chats SET archived_at = COALESCE(archived_at, ?) WHERE id = ? AND deleted_at IS NULL`, now, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, ErrChatNotFound
+	}
+	return true, nil
+}
+
+// bulkTagChat adds a tag to a chat that isn't deleted; tagging twice is a no-op
+func bulkTagChat(ctx context.Context, tx *database.Tx, id, tagID int64) (bool, error) {
+	var exists int
+	err := tx.QueryRowContext(ctx, `SELECT 1 FROM chats WHERE id = ? AND deleted_at IS NULL`, id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, ErrChatNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	result, err := tx.ExecContext(ctx, `INSERT INTO chat_tags (chat_id, tag_id) VALUES (?, ?) ON CONFLICT (chat_id, tag_id) DO NOTHING`, id, tagID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// uniqueIDs drops repeated IDs, keeping the first occurrence of each
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
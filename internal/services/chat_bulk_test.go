@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	apperrors "ai-gateway-hub/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatService_BulkUpdateChats(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var ids []int64
+	for _, title := range []string{"One", "Two", "Three"} {
+		chat, err := service.CreateChat(ctx, title, "claude")
+		require.NoError(t, err)
+		ids = append(ids, chat.ID)
+	}
+
+	var notified []string
+	service.OnChange(func(action string, chatID int64) { notified = append(notified, action) })
+
+	// Missing chats fail on their own, duplicates are applied once
+	response, err := service.BulkUpdateChats(ctx, BulkTag, []int64{ids[0], ids[1], 999, ids[0]}, " Cleanup ")
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Equal(t, 1, response.Failed)
+	require.Len(t, response.Results, 3)
+	assert.False(t, response.Results[2].OK)
+	assert.Equal(t, "chat not found", response.Results[2].Error)
+	assert.Equal(t, []string{ChatOrganized, ChatOrganized}, notified)
+
+	tagged, err := service.ListChats(ctx, ChatFilter{Tag: "cleanup"}, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, tagged, 2)
+
+	response, err = service.BulkUpdateChats(ctx, BulkArchive, []int64{ids[0], ids[1]}, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Succeeded)
+	archived, err := service.ListChats(ctx, ChatFilter{Archived: true}, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, archived, 2)
+
+	response, err = service.BulkUpdateChats(ctx, BulkDelete, ids, "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, response.Succeeded)
+	_, err = service.GetChat(ctx, ids[2])
+	assert.ErrorIs(t, err, ErrChatNotFound)
+
+	// Deleted chats can't be archived or tagged, deleting again is a no-op
+	response, err = service.BulkUpdateChats(ctx, BulkArchive, ids[:1], "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.Failed)
+	response, err = service.BulkUpdateChats(ctx, BulkDelete, ids[:1], "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.Succeeded)
+
+	tooMany := make([]int64, MaxBulkChats+1)
+	for i := range tooMany {
+		tooMany[i] = int64(i + 1)
+	}
+	for _, invalid := range []struct {
+		action string
+		ids    []int64
+		tag    string
+	}{
+		{"rename", ids, ""},
+		{BulkDelete, nil, ""},
+		{BulkDelete, tooMany, ""},
+		{BulkTag, ids, "a/b"},
+	} {
+		_, err := service.BulkUpdateChats(ctx, invalid.action, invalid.ids, invalid.tag)
+		assert.ErrorIs(t, err, apperrors.ErrValidation, invalid.action)
+	}
+}
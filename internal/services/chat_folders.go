@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+)
+
+var (
+	// ErrFolderNotFound is returned for unknown folders
+	ErrFolderNotFound = apperrors.NotFound("folder not found")
+	// ErrFolderExists is returned when a folder name is already taken
+	ErrFolderExists = apperrors.Conflict("folder already exists")
+	// ErrInvalidFolderName is returned for folder names that are empty or too long
+	ErrInvalidFolderName = apperrors.Validation("invalid folder name")
+)
+
+// Maximum length of a folder name
+const MaxFolderNameLength = 100
+
+// ValidateFolderName trims a folder name and checks its length
+func ValidateFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidFolderName)
+	}
+	if utf8.RuneCountInString(name) > MaxFolderNameLength {
+		return "", fmt.Errorf("%w: name must be at most %d characters", ErrInvalidFolderName, MaxFolderNameLength)
+	}
+	return name, nil
+}
+
+// ListFolders returns all folders by name with the number of chats (including archived ones) filed in them
+func (s *ChatService) ListFolders(ctx context.Context) ([]*models.Folder, error) {
+	query := `
+		SELECT f.id, f.name, f.created_at, COUNT(c.id)
+		FROM folders f
+		LEFT JOIN chats c ON c.folder_id = f.id AND c.deleted_at IS NULL
+		GROUP BY f.id, f.name, f.created_at
+		ORDER BY f.name
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", err)
+	}
+	defer rows.Close()
+
+	folders := []*models.Folder{}
+	for rows.Next() {
+		var folder models.Folder
+		if err := rows.Scan(&folder.ID, &folder.Name, &folder.CreatedAt, &folder.ChatCount); err != nil {
+			return nil, fmt.Errorf("failed to scan folder: %w", err)
+		}
+		folders = append(folders, &folder)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", err)
+	}
+	return folders, nil
+}
+
+// GetFolder retrieves a folder by ID
+func (s *ChatService) GetFolder(ctx context.Context, id int64) (*models.Folder, error) {
+	var folder models.Folder
+	err := s.db.QueryRowContext(ctx, `SELECT id, name, created_at FROM folders WHERE id = ?`, id).Scan(&folder.ID, &folder.Name, &folder.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrFolderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folder: %w", err)
+	}
+	return &folder, nil
+}
+
+// CreateFolder creates an empty folder
+func (s *ChatService) CreateFolder(ctx context.Context, name string) (*models.Folder, error) {
+	name, err := ValidateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkFolderName(ctx, name, 0); err != nil {
+		return nil, err
+	}
+
+	var folder models.Folder
+	err = s.db.QueryRowContext(ctx, `INSERT INTO folders (name) VALUES (?) RETURNING id, name, created_at`, name).Scan(&folder.ID, &folder.Name, &folder.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create folder: %w", err)
+	}
+
+	s.notify(ChatOrganized, 0)
+	return &folder, nil
+}
+
+// RenameFolder changes a folder's name
+func (s *ChatService) RenameFolder(ctx context.Context, id int64, name string) (*models.Folder, error) {
+	name, err := ValidateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkFolderName(ctx, name, id); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.ExecContext(ctx, `UPDATE folders SET name = ? WHERE id = ?`, name, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename folder: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return nil, ErrFolderNotFound
+	}
+
+	s.notify(ChatOrganized, 0)
+	return s.GetFolder(ctx, id)
+}
+
+// checkFolderName returns ErrFolderExists if a folder other than id already uses the name
+func (s *ChatService) checkFolderName(ctx context.Context, name string, id int64) error {
+	var existing int64
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM folders WHERE name = ? AND id <> ?`, name, id).Scan(&existing)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check folder name: %w", err)
+	}
+	return ErrFolderExists
+}
+
+// DeleteFolder deletes a folder; the chats in it are kept and no longer filed in any folder
+func (s *ChatService) DeleteFolder(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin folder deletion: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE chats SET folder_id = NULL WHERE folder_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to unfile chats: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM folders WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete folder: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrFolderNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit folder deletion: %w", err)
+	}
+
+	s.notify(ChatOrganized, 0)
+	return nil
+}
+
+// MoveChatToFolder files a chat in a folder; a nil folderID takes it out of its folder
+func (s *ChatService) MoveChatToFolder(ctx context.Context, chatID int64, folderID *int64) error {
+	if folderID != nil {
+		if _, err := s.GetFolder(ctx, *folderID); err != nil {
+			return err
+		}
+	}
+
+	result, err := s.db.ExecContext(ctx, `UPDATE chats SET folder_id = ? WHERE id = ? AND deleted_at IS NULL`, folderID, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to move chat: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrChatNotFound
+	}
+
+	s.notify(ChatOrganized, chatID)
+	return nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+)
+
+// ErrForkMessageNotFound is returned when forking a chat from a message it doesn't have
+var ErrForkMessageNotFound = apperrors.NotFound("message not found")
+
+// Most chats loaded into a branch tree, so a runaway tree can't load the whole database
+const maxBranchTreeSize = 500
+
+// ForkChat creates a branch of a chat with its conversation up to and including a message, for
+// trying another direction without losing the original. The branch keeps the chat's provider,
+// system prompt and folder, and starts without provider sessions, so providers keeping sessions
+// are sent its history with the next prompt; attachments stay with the original messages.
+func (s *ChatService) ForkChat(ctx context.Context, chatID, fromMessageID int64) (*models.Chat, error) {
+	parent, err := s.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	var from struct {
+		createdAt time.Time
+		status    string
+	}
+	query := `SELECT created_at, status FROM messages WHERE id = ? AND chat_id = ?`
+	err = s.db.QueryRowContext(ctx, query, fromMessageID, chatID).Scan(&from.createdAt, &from.status)
+	if errors.Is(err, sql.ErrNoRows) || from.status == models.MessageStreaming {
+		return nil, ErrForkMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin fork: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query = `
+		INSERT INTO chats (title, provider, system_prompt, folder_id, parent_chat_id, forked_from_message_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + chatColumns + `
+	`
+	chat, err := scanChat(tx.QueryRowContext(ctx, query, parent.Title, parent.Provider, parent.SystemPrompt, parent.FolderID, parent.ID, fromMessageID, now, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fork: %w", err)
+	}
+
+	// Messages are copied as stored, so encrypted content stays encrypted; responses still
+	// streaming in the original aren't part of the branch
+	query = `
+		INSERT INTO messages (chat_id, role, content, provider, model, status, user_id, created_at, latency_ms, tokens_in, tokens_out, tokens_estimated, finish_reason, failed_over)
+		SELECT ?, role, content, provider, model, status, user_id, created_at, latency_ms, tokens_in, tokens_out, tokens_estimated, finish_reason, failed_over
+		FROM messages
+		WHERE chat_id = ? AND status <> ? AND (created_at < ? OR (created_at = ? AND id <= ?))
+		ORDER BY created_at ASC, id ASC
+	`
+	if _, err := tx.ExecContext(ctx, query, chat.ID, chatID, models.MessageStreaming, from.createdAt, from.createdAt, fromMessageID); err != nil {
+		return nil, fmt.Errorf("failed to copy messages: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit fork: %w", err)
+	}
+
+	s.notify(ChatCreated, chat.ID)
+	return chat, nil
+}
+
+// BranchTree returns the tree of branches a chat belongs to, from the oldest ancestor that still
+// exists, or nil if the chat was never forked and isn't a fork. Deleted chats are left out with
+// their branches.
+func (s *ChatService) BranchTree(ctx context.Context, chat *models.Chat) (*models.ChatBranch, error) {
+	root := chat
+	visited := map[int64]bool{chat.ID: true}
+	for root.ParentChatID != nil && !visited[*root.ParentChatID] {
+		parent, err := s.GetChat(ctx, *root.ParentChatID)
+		if errors.Is(err, ErrChatNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		visited[parent.ID] = true
+		root = parent
+	}
+
+	tree := &models.ChatBranch{
+		ID:                  root.ID,
+		Title:               root.Title,
+		ParentChatID:        root.ParentChatID,
+		ForkedFromMessageID: root.ForkedFromMessageID,
+		CreatedAt:           root.CreatedAt,
+		Children:            []*models.ChatBranch{},
+	}
+	branches := map[int64]*models.ChatBranch{root.ID: tree}
+	level := []int64{root.ID}
+	for len(level) > 0 && len(branches) < maxBranchTreeSize {
+		var next []int64
+		for _, parentID := range level {
+			children, err := s.childBranches(ctx, parentID)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				if branches[child.ID] != nil || len(branches) >= maxBranchTreeSize {
+					continue
+				}
+				branches[child.ID] = child
+				branches[parentID].Children = append(branches[parentID].Children, child)
+				next = append(next, child.ID)
+			}
+		}
+		level = next
+	}
+
+	if root.ID == chat.ID && len(tree.Children) == 0 && chat.ParentChatID == nil {
+		return nil, nil
+	}
+	return tree, nil
+}
+
+// childBranches returns the chats forked from a chat, oldest first
+func (s *ChatService) childBranches(ctx context.Context, parentID int64) ([]*models.ChatBranch, error) {
+	query := `
+		SELECT id, title, parent_chat_id, forked_from_message_id, created_at
+		FROM chats
+		WHERE parent_chat_id = ? AND deleted_at IS NULL
+		ORDER BY created_at ASC, id ASC
+	`
+	rows, err := s.db.QueryContext(ctx, query, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat branches: %w", err)
+	}
+	defer rows.Close()
+
+	var children []*models.ChatBranch
+	for rows.Next() {
+		var branch models.ChatBranch
+		var parentChatID, forkedFromMessageID sql.NullInt64
+		if err := rows.Scan(&branch.ID, &branch.Title, &parentChatID, &forkedFromMessageID, &branch.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chat branch: %w", err)
+		}
+		if parentChatID.Valid {
+			branch.ParentChatID = &parentChatID.Int64
+		}
+		if forkedFromMessageID.Valid {
+			branch.ForkedFromMessageID = &forkedFromMessageID.Int64
+		}
+		branch.Children = []*models.ChatBranch{}
+		children = append(children, &branch)
+	}
+	return children, rows.Err()
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatService_ForkChat(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	chat, err := service.CreateChat(ctx, "Plans", "claude")
+	require.NoError(t, err)
+	require.NoError(t, service.UpdateSystemPrompt(ctx, chat.ID, "Be brief."))
+	first, err := service.AddMessage(ctx, chat.ID, "user", "Plan a trip")
+	require.NoError(t, err)
+	answer, err := service.AddProviderMessage(ctx, chat.ID, "assistant", "Go to Kyoto", "claude")
+	require.NoError(t, err)
+	_, err = service.AddMessage(ctx, chat.ID, "user", "What about food?")
+	require.NoError(t, err)
+	_, err = service.StartStreamingMessage(ctx, chat.ID, "claude", "Try")
+	require.NoError(t, err)
+
+	// Chats that were never forked have no tree
+	tree, err := service.BranchTree(ctx, chat)
+	require.NoError(t, err)
+	assert.Nil(t, tree)
+
+	var notified []string
+	service.OnChange(func(action string, chatID int64) { notified = append(notified, action) })
+
+	branch, err := service.ForkChat(ctx, chat.ID, answer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Plans", branch.Title)
+	assert.Equal(t, "Be brief.", branch.SystemPrompt)
+	assert.Equal(t, chat.ID, *branch.ParentChatID)
+	assert.Equal(t, answer.ID, *branch.ForkedFromMessageID)
+	assert.Equal(t, []string{ChatCreated}, notified)
+
+	messages, err := service.GetMessages(ctx, branch.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "Plan a trip", messages[0].Content)
+	assert.Equal(t, "Go to Kyoto", messages[1].Content)
+	assert.Equal(t, "claude", messages[1].Provider)
+
+	// Branches of branches form one tree, seen from any of its chats
+	other, err := service.ForkChat(ctx, chat.ID, first.ID)
+	require.NoError(t, err)
+	nested, err := service.ForkChat(ctx, branch.ID, messages[0].ID)
+	require.NoError(t, err)
+
+	for _, c := range []int64{chat.ID, other.ID, nested.ID} {
+		current, err := service.GetChat(ctx, c)
+		require.NoError(t, err)
+		tree, err := service.BranchTree(ctx, current)
+		require.NoError(t, err)
+		require.NotNil(t, tree)
+		assert.Equal(t, chat.ID, tree.ID)
+		require.Len(t, tree.Children, 2)
+		assert.Equal(t, branch.ID, tree.Children[0].ID)
+		assert.Equal(t, other.ID, tree.Children[1].ID)
+		require.Len(t, tree.Children[0].Children, 1)
+		assert.Equal(t, nested.ID, tree.Children[0].Children[0].ID)
+	}
+
+	// Deleted chats are left out; their forks become the roots of what remains
+	require.NoError(t, service.DeleteChat(ctx, chat.ID))
+	current, err := service.GetChat(ctx, nested.ID)
+	require.NoError(t, err)
+	tree, err = service.BranchTree(ctx, current)
+	require.NoError(t, err)
+	assert.Equal(t, branch.ID, tree.ID)
+
+	_, err = service.PurgeDeletedChats(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	branch, err = service.GetChat(ctx, branch.ID)
+	require.NoError(t, err)
+	assert.Nil(t, branch.ParentChatID)
+
+	_, err = service.ForkChat(ctx, branch.ID, answer.ID)
+	assert.ErrorIs(t, err, ErrForkMessageNotFound, "the message must belong to the chat")
+	_, err = service.ForkChat(ctx, chat.ID, first.ID)
+	assert.ErrorIs(t, err, ErrChatNotFound)
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+)
+
+// ErrChatLogNotFound is returned for chats without a log of the requested provider
+var ErrChatLogNotFound = apperrors.NotFound("chat log not found")
+
+// Most bytes of lines returned in one page of a chat log; a longer first line is still returned
+const maxChatLogPageBytes = 1 << 20
+
+// ChatLogService reads the execution logs providers write per chat, in one directory per provider
+// below the log directory
+type ChatLogService struct {
+	dir string
+}
+
+func NewChatLogService(dir string) *ChatLogService {
+	return &ChatLogService{dir: dir}
+}
+
+// Files lists the provider logs of a chat, most recently written first
+func (s *ChatLogService) Files(chatID int64) ([]*models.ChatLogFile, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []*models.ChatLogFile{}, nil
+		}
+		return nil, fmt.Errorf("failed to list chat logs: %w", err)
+	}
+
+	files := []*models.ChatLogFile{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(s.dir, entry.Name(), chatLogName(chatID)))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, &models.ChatLogFile{Provider: entry.Name(), Size: info.Size(), ModifiedAt: info.ModTime()})
+	}
+	slices.SortFunc(files, func(a, b *models.ChatLogFile) int { return b.ModifiedAt.Compare(a.ModifiedAt) })
+	return files, nil
+}
+
+// Path returns the path of a provider's log of a chat
+func (s *ChatLogService) Path(chatID int64, provider string) (string, error) {
+	// The provider names a directory, so it can't reach outside the log directory
+	if provider == "" || provider == "." || provider == ".." || strings.ContainsAny(provider, `/\`) {
+		return "", ErrChatLogNotFound
+	}
+	path := filepath.Join(s.dir, provider, chatLogName(chatID))
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return "", ErrChatLogNotFound
+	}
+	return path, nil
+}
+
+// Read returns up to limit lines of a provider's log of a chat starting at byte offset
+func (s *ChatLogService) Read(chatID int64, provider string, offset int64, limit int) (*models.ChatLogPage, error) {
+	file, size, err := s.open(chatID, provider)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readChatLog(file, provider, min(offset, size), size, limit)
+}
+
+// Tail returns the last lines of a provider's log of a chat
+func (s *ChatLogService) Tail(chatID int64, provider string, lines int) (*models.ChatLogPage, error) {
+	file, size, err := s.open(chatID, provider)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	offset, err := tailOffset(file, size, lines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chat log: %w", err)
+	}
+	return readChatLog(file, provider, offset, size, lines)
+}
+
+// open opens a chat log and returns its current size; lines appended later are left for the next read
+func (s *ChatLogService) open(chatID int64, provider string) (*os.File, int64, error) {
+	path, err := s.Path(chatID, provider)
+	if err != nil {
+		return nil, 0, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open chat log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to open chat log: %w", err)
+	}
+	return file, info.Size(), nil
+}
+
+// readChatLog reads up to limit lines between offset and size
+func readChatLog(file io.ReaderAt, provider string, offset, size int64, limit int) (*models.ChatLogPage, error) {
+	page := &models.ChatLogPage{Provider: provider, Lines: []string{}, Offset: offset, NextOffset: offset, Size: size}
+	reader := bufio.NewReader(io.NewSectionReader(file, offset, size-offset))
+	for len(page.Lines) < limit && page.NextOffset-offset < maxChatLogPageBytes {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			page.NextOffset += int64(len(line))
+			page.Lines = append(page.Lines, strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chat log: %w", err)
+		}
+	}
+	page.HasMore = page.NextOffset < size
+	return page, nil
+}
+
+// tailOffset returns where the last n lines of a file of the given size start
+func tailOffset(file io.ReaderAt, size int64, n int) (int64, error) {
+	buf := make([]byte, 32<<10)
+	newlines := 0
+	for end := size; end > 0; {
+		start := max(end-int64(len(buf)), 0)
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i := len(chunk) - 1; i >= 0; i-- {
+			// A final newline ends the last line rather than starting another
+			if chunk[i] != '\n' || start+int64(i) == size-1 {
+				continue
+			}
+			if newlines++; newlines == n {
+				return start + int64(i) + 1, nil
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// chatLogName is the file name providers log a chat's executions to
+func chatLogName(chatID int64) string {
+	return fmt.Sprintf("chat_%d.log", chatID)
+}
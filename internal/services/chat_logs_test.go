@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeChatLog writes a provider's log of a chat below dir
+func writeChatLog(t *testing.T, dir, provider string, chatID int64, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, provider), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, provider, chatLogName(chatID)), []byte(content), 0600))
+}
+
+func TestChatLogService_Files(t *testing.T) {
+	dir := t.TempDir()
+	service := NewChatLogService(dir)
+
+	files, err := service.Files(1)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	writeChatLog(t, dir, "claude", 1, "old\n")
+	writeChatLog(t, dir, "gemini", 1, "newer\n")
+	writeChatLog(t, dir, "claude", 2, "other chat\n")
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "claude", "chat_1.log"), past, past))
+
+	files, err = service.Files(1)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "gemini", files[0].Provider, "the latest log comes first")
+	assert.Equal(t, "claude", files[1].Provider)
+	assert.Equal(t, int64(4), files[1].Size)
+
+	// Missing directories are empty rather than an error
+	files, err = NewChatLogService(filepath.Join(dir, "missing")).Files(1)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestChatLogService_Path(t *testing.T) {
+	dir := t.TempDir()
+	service := NewChatLogService(dir)
+	writeChatLog(t, dir, "claude", 1, "line\n")
+
+	path, err := service.Path(1, "claude")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "claude", "chat_1.log"), path)
+
+	for _, provider := range []string{"", ".", "..", "../claude", "claude/..", `..\claude`, "gemini"} {
+		_, err := service.Path(1, provider)
+		assert.ErrorIs(t, err, ErrChatLogNotFound, provider)
+	}
+	_, err = service.Path(2, "claude")
+	assert.ErrorIs(t, err, ErrChatLogNotFound)
+}
+
+func TestChatLogService_Read(t *testing.T) {
+	dir := t.TempDir()
+	service := NewChatLogService(dir)
+	writeChatLog(t, dir, "claude", 1, "one\ntwo\r\nthree\nfour")
+
+	page, err := service.Read(1, "claude", 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, page.Lines)
+	assert.Equal(t, int64(0), page.Offset)
+	assert.Equal(t, int64(9), page.NextOffset)
+	assert.Equal(t, int64(19), page.Size)
+	assert.True(t, page.HasMore)
+
+	// An unterminated last line is returned as well
+	page, err = service.Read(1, "claude", page.NextOffset, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"three", "four"}, page.Lines)
+	assert.Equal(t, int64(19), page.NextOffset)
+	assert.False(t, page.HasMore)
+
+	// Polling at the end returns what was appended since
+	page, err = service.Read(1, "claude", 100, 10)
+	require.NoError(t, err)
+	assert.Empty(t, page.Lines)
+	assert.Equal(t, int64(19), page.Offset)
+
+	_, err = service.Read(1, "gemini", 0, 10)
+	assert.ErrorIs(t, err, ErrChatLogNotFound)
+}
+
+func TestChatLogService_Tail(t *testing.T) {
+	dir := t.TempDir()
+	service := NewChatLogService(dir)
+
+	// Longer than the block tailOffset reads at a time
+	var content strings.Builder
+	for i := 1; i <= 5000; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	writeChatLog(t, dir, "claude", 1, content.String())
+
+	page, err := service.Tail(1, "claude", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 4998", "line 4999", "line 5000"}, page.Lines)
+	assert.Equal(t, page.Size, page.NextOffset)
+	assert.False(t, page.HasMore)
+
+	page, err = service.Tail(1, "claude", 10000)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), page.Offset)
+	assert.Len(t, page.Lines, 5000)
+
+	writeChatLog(t, dir, "gemini", 1, "a\nb")
+	page, err = service.Tail(1, "gemini", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, page.Lines)
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ai-gateway-hub/internal/jobs"
+	"ai-gateway-hub/internal/utils"
+)
+
+// How often the purge job looks for expired deleted chats
+const ChatPurgeInterval = time.Hour
+
+// ChatPurgeService removes chats that have been in the trash longer than the retention period
+type ChatPurgeService struct {
+	chatService       *ChatService
+	attachmentService *AttachmentService
+	retention         time.Duration
+}
+
+func NewChatPurgeService(chatService *ChatService, attachmentService *AttachmentService, retention time.Duration) *ChatPurgeService {
+	return &ChatPurgeService{
+		chatService:       chatService,
+		attachmentService: attachmentService,
+		retention:         retention,
+	}
+}
+
+// Job purges on every interval; it is exclusive so instances sharing a database don't race
+func (s *ChatPurgeService) Job() jobs.Job {
+	return jobs.Job{
+		Name:       "chat_purge",
+		Interval:   ChatPurgeInterval,
+		RunAtStart: true,
+		Exclusive:  true,
+		Run: func(ctx context.Context) error {
+			_, err := s.Purge(ctx)
+			return err
+		},
+	}
+}
+
+// Purge removes chats deleted longer ago than the retention period and their attachment files
+func (s *ChatPurgeService) Purge(ctx context.Context) (int64, error) {
+	count, err := s.chatService.PurgeDeletedChats(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted chats: %w", err)
+	}
+	if count > 0 {
+		utils.Info("Purged %d deleted chats", count)
+	}
+
+	if s.attachmentService != nil {
+		if _, err := s.attachmentService.PurgeOrphanedFiles(); err != nil {
+			return count, fmt.Errorf("failed to purge attachment files: %w", err)
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+)
+
+var (
+	// ErrInvalidTag is returned for tag names that are empty, too long or contain reserved characters
+	ErrInvalidTag = apperrors.Validation("invalid tag")
+	// ErrTagNotFound is returned for unknown tags and tags a chat doesn't carry
+	ErrTagNotFound = apperrors.NotFound("tag not found")
+)
+
+// Maximum length of a tag name
+const MaxTagLength = 50
+
+// NormalizeTag trims and lowercases a tag name so tags match case-insensitively
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ValidateTag normalizes a tag name and checks that it can be stored
+func ValidateTag(name string) (string, error) {
+	name = NormalizeTag(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: tag is required", ErrInvalidTag)
+	}
+	if utf8.RuneCountInString(name) > MaxTagLength {
+		return "", fmt.Errorf("%w: tag must be at most %d characters", ErrInvalidTag, MaxTagLength)
+	}
+	if strings.ContainsAny(name, ",/") {
+		return "", fmt.Errorf("%w: tag must not contain commas or slashes", ErrInvalidTag)
+	}
+	return name, nil
+}
+
+// TagChat adds a tag to a chat, creating the tag if it doesn't exist yet. Tagging twice is a no-op.
+func (s *ChatService) TagChat(ctx context.Context, chatID int64, name string) error {
+	name, err := ValidateTag(name)
+	if err != nil {
+		return err
+	}
+	if _, err := s.GetChat(ctx, chatID); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tagging: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING`, name); err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+	var tagID int64
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM tags WHERE name = ?`, name).Scan(&tagID); err != nil {
+		return fmt.Errorf("failed to get tag: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `INSERT INTO chat_tags (chat_id, tag_id) VALUES (?, ?) ON CONFLICT (chat_id, tag_id) DO NOTHING`, chatID, tagID)
+	if err != nil {
+		return fmt.Errorf("failed to tag chat: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tagging: %w", err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		s.notify(ChatOrganized, chatID)
+	}
+	return nil
+}
+
+// UntagChat removes a tag from a chat; the tag itself is kept for other chats
+func (s *ChatService) UntagChat(ctx context.Context, chatID int64, name string) error {
+	query := `DELETE FROM chat_tags WHERE chat_id = ? AND tag_id IN (SELECT id FROM tags WHERE name = ?)`
+
+	result, err := s.db.ExecContext(ctx, query, chatID, NormalizeTag(name))
+	if err != nil {
+		return fmt.Errorf("failed to untag chat: %w", err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrTagNotFound
+	}
+
+	s.notify(ChatOrganized, chatID)
+	return nil
+}
+
+// ListTags returns all tags by name with the number of chats (including archived ones) carrying them
+func (s *ChatService) ListTags(ctx context.Context) ([]*models.Tag, error) {
+	query := `
+		SELECT t.id, t.name, t.created_at, COUNT(c.id)
+		FROM tags t
+		LEFT JOIN chat_tags ct ON ct.tag_id = t.id
+		LEFT JOIN chats c ON c.id = ct.chat_id AND c.deleted_at IS NULL
+		GROUP BY t.id, t.name, t.created_at
+		ORDER BY t.name
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []*models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.CreatedAt, &tag.ChatCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, &tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return tags, nil
+}
+
+// DeleteTag removes a tag from every chat and deletes it
+func (s *ChatService) DeleteTag(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tag deletion: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chat_tags WHERE tag_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to untag chats: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrTagNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tag deletion: %w", err)
+	}
+
+	s.notify(ChatOrganized, 0)
+	return nil
+}
+
+// loadChatTags fills in the tags of the given chats with a single query
+func (s *ChatService) loadChatTags(ctx context.Context, chats []*models.Chat) error {
+	if len(chats) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*models.Chat, len(chats))
+	placeholders := make([]string, 0, len(chats))
+	args := make([]any, 0, len(chats))
+	for _, chat := range chats {
+		byID[chat.ID] = chat
+		placeholders = append(placeholders, "?")
+		args = append(args, chat.ID)
+	}
+
+	query := `
+		SELECT ct.chat_id, t.name
+		FROM chat_tags ct
+		JOIN tags t ON t.id = ct.tag_id
+		WHERE ct.chat_id IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY t.name
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get chat tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var chatID int64
+		var name string
+		if err := rows.Scan(&chatID, &name); err != nil {
+			return fmt.Errorf("failed to scan chat tag: %w", err)
+		}
+		if chat := byID[chatID]; chat != nil {
+			chat.Tags = append(chat.Tags, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get chat tags: %w", err)
+	}
+	return nil
+}
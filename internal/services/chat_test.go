@@ -1,11 +1,15 @@
 package services
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
 )
 
 func setupTestChatService(t *testing.T) (*ChatService, func()) {
@@ -53,7 +57,7 @@ func TestChatService_CreateChat(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			chat, err := service.CreateChat(tt.title, tt.provider)
+			chat, err := service.CreateChat(context.Background(), tt.title, tt.provider)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -74,7 +78,7 @@ func TestChatService_GetChat(t *testing.T) {
 	defer cleanup()
 
 	// Create a test chat
-	originalChat, err := service.CreateChat("Test Chat for Get", "claude")
+	originalChat, err := service.CreateChat(context.Background(), "Test Chat for Get", "claude")
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -101,7 +105,7 @@ func TestChatService_GetChat(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			chat, err := service.GetChat(tt.chatID)
+			chat, err := service.GetChat(context.Background(), tt.chatID)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -120,7 +124,7 @@ func TestChatService_GetChats(t *testing.T) {
 
 	// Create multiple test chats
 	for i := 0; i < 5; i++ {
-		_, err := service.CreateChat("Test Chat "+string(rune('A'+i)), "claude")
+		_, err := service.CreateChat(context.Background(), "Test Chat "+string(rune('A'+i)), "claude")
 		require.NoError(t, err)
 	}
 
@@ -152,7 +156,7 @@ func TestChatService_GetChats(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			chats, err := service.GetChats(tt.limit, tt.offset)
+			chats, err := service.GetChats(context.Background(), tt.limit, tt.offset)
 			assert.NoError(t, err)
 			assert.Len(t, chats, tt.wantCount)
 
@@ -169,7 +173,7 @@ func TestChatService_UpdateChat(t *testing.T) {
 	defer cleanup()
 
 	// Create a test chat
-	originalChat, err := service.CreateChat("Original Title", "claude")
+	originalChat, err := service.CreateChat(context.Background(), "Original Title", "claude")
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -194,7 +198,7 @@ func TestChatService_UpdateChat(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := service.UpdateChat(tt.chatID, tt.title)
+			err := service.UpdateChat(context.Background(), tt.chatID, tt.title)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -202,7 +206,7 @@ func TestChatService_UpdateChat(t *testing.T) {
 
 				// Verify the update for existing chat
 				if tt.chatID == originalChat.ID {
-					updatedChat, err := service.GetChat(tt.chatID)
+					updatedChat, err := service.GetChat(context.Background(), tt.chatID)
 					assert.NoError(t, err)
 					assert.Equal(t, tt.title, updatedChat.Title)
 					assert.True(t, updatedChat.UpdatedAt.After(originalChat.UpdatedAt))
@@ -217,13 +221,13 @@ func TestChatService_DeleteChat(t *testing.T) {
 	defer cleanup()
 
 	// Create test chats
-	chat1, err := service.CreateChat("Chat 1", "claude")
+	chat1, err := service.CreateChat(context.Background(), "Chat 1", "claude")
 	require.NoError(t, err)
-	chat2, err := service.CreateChat("Chat 2", "gemini")
+	chat2, err := service.CreateChat(context.Background(), "Chat 2", "gemini")
 	require.NoError(t, err)
 
 	// Add messages to chat1
-	_, err = service.AddMessage(chat1.ID, "user", "Hello")
+	_, err = service.AddMessage(context.Background(), chat1.ID, "user", "Hello")
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -237,14 +241,14 @@ func TestChatService_DeleteChat(t *testing.T) {
 			chatID:  chat1.ID,
 			wantErr: false,
 			verify: func(t *testing.T) {
-				// Verify chat is deleted
-				_, err := service.GetChat(chat1.ID)
+				// Verify chat is hidden
+				_, err := service.GetChat(context.Background(), chat1.ID)
 				assert.Error(t, err)
 
-				// Verify messages are deleted (cascade)
-				messages, err := service.GetMessages(chat1.ID, 10, 0)
+				// Messages are kept until the chat is purged
+				messages, err := service.GetMessages(context.Background(), chat1.ID, 10, 0)
 				assert.NoError(t, err)
-				assert.Empty(t, messages)
+				assert.Len(t, messages, 1)
 			},
 		},
 		{
@@ -252,22 +256,27 @@ func TestChatService_DeleteChat(t *testing.T) {
 			chatID:  chat2.ID,
 			wantErr: false,
 			verify: func(t *testing.T) {
-				_, err := service.GetChat(chat2.ID)
+				_, err := service.GetChat(context.Background(), chat2.ID)
 				assert.Error(t, err)
 			},
 		},
 		{
 			name:    "delete non-existing chat",
 			chatID:  99999,
-			wantErr: false, // SQLite doesn't return error for DELETE with no matches
+			wantErr: true,
+		},
+		{
+			name:    "delete already deleted chat",
+			chatID:  chat1.ID,
+			wantErr: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := service.DeleteChat(tt.chatID)
+			err := service.DeleteChat(context.Background(), tt.chatID)
 			if tt.wantErr {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, ErrChatNotFound)
 			} else {
 				assert.NoError(t, err)
 				if tt.verify != nil {
@@ -283,7 +292,7 @@ func TestChatService_AddMessage(t *testing.T) {
 	defer cleanup()
 
 	// Create a test chat
-	chat, err := service.CreateChat("Test Chat", "claude")
+	chat, err := service.CreateChat(context.Background(), "Test Chat", "claude")
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -325,7 +334,7 @@ func TestChatService_AddMessage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			msg, err := service.AddMessage(tt.chatID, tt.role, tt.content)
+			msg, err := service.AddMessage(context.Background(), tt.chatID, tt.role, tt.content)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -339,6 +348,9 @@ func TestChatService_AddMessage(t *testing.T) {
 			}
 		})
 	}
+
+	_, err = service.AddMessage(context.Background(), 99999, "user", "Nowhere")
+	assert.ErrorIs(t, err, ErrChatNotFound)
 }
 
 func TestChatService_GetMessages(t *testing.T) {
@@ -346,9 +358,9 @@ func TestChatService_GetMessages(t *testing.T) {
 	defer cleanup()
 
 	// Create test chats
-	chat1, err := service.CreateChat("Chat 1", "claude")
+	chat1, err := service.CreateChat(context.Background(), "Chat 1", "claude")
 	require.NoError(t, err)
-	chat2, err := service.CreateChat("Chat 2", "gemini")
+	chat2, err := service.CreateChat(context.Background(), "Chat 2", "gemini")
 	require.NoError(t, err)
 
 	// Add messages to chat1
@@ -363,7 +375,7 @@ func TestChatService_GetMessages(t *testing.T) {
 	}
 
 	for _, msg := range messages {
-		_, err := service.AddMessage(chat1.ID, msg.role, msg.content)
+		_, err := service.AddMessage(context.Background(), chat1.ID, msg.role, msg.content)
 		require.NoError(t, err)
 	}
 
@@ -413,7 +425,7 @@ func TestChatService_GetMessages(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			msgs, err := service.GetMessages(tt.chatID, tt.limit, tt.offset)
+			msgs, err := service.GetMessages(context.Background(), tt.chatID, tt.limit, tt.offset)
 			assert.NoError(t, err)
 			assert.Len(t, msgs, tt.expectedCount)
 
@@ -423,4 +435,478 @@ func TestChatService_GetMessages(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestChatService_AddProviderMessage(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	chat, err := service.CreateChat(context.Background(), "Compare Chat", "claude")
+	require.NoError(t, err)
+
+	_, err = service.AddMessage(context.Background(), chat.ID, "user", "Compare this")
+	require.NoError(t, err)
+	for _, provider := range []string{"claude", "gemini"} {
+		msg, err := service.AddProviderMessage(context.Background(), chat.ID, "assistant", "Answer from "+provider, provider)
+		require.NoError(t, err)
+		assert.Equal(t, provider, msg.Provider)
+	}
+
+	msgs, err := service.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "", msgs[0].Provider)
+	assert.Equal(t, "claude", msgs[1].Provider)
+	assert.Equal(t, "gemini", msgs[2].Provider)
+}
+
+func TestChatService_AddUserMessage(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	chat, err := service.CreateChat(context.Background(), "Team Chat", "claude")
+	require.NoError(t, err)
+
+	_, err = service.AddUserMessage(context.Background(), chat.ID, "From Alice", "alice")
+	require.NoError(t, err)
+	_, err = service.AddUserMessage(context.Background(), chat.ID, "From Bob", "bob")
+	require.NoError(t, err)
+	_, err = service.AddProviderMessage(context.Background(), chat.ID, "assistant", "Hello both", "claude")
+	require.NoError(t, err)
+	_, err = service.AddUserMessage(context.Background(), 99999, "Nowhere", "alice")
+	assert.ErrorIs(t, err, ErrChatNotFound)
+
+	msgs, err := service.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "alice", msgs[0].UserID)
+	assert.Equal(t, "user", msgs[0].Role)
+	assert.Equal(t, "bob", msgs[1].UserID)
+	assert.Equal(t, "", msgs[2].UserID)
+}
+
+func TestChatService_SetMessageMetadata(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	chat, err := service.CreateChat(ctx, "Metadata Chat", "claude")
+	require.NoError(t, err)
+	prompt, err := service.AddMessage(ctx, chat.ID, "user", "Hello")
+	require.NoError(t, err)
+	response, err := service.AddProviderMessage(ctx, chat.ID, "assistant", "Hi there", "claude")
+	require.NoError(t, err)
+	require.NoError(t, service.SetMessageModel(ctx, response.ID, "opus"))
+
+	meta := &models.MessageMetadata{LatencyMs: 1500, TokensIn: 12, TokensOut: 3, TokensEstimated: true, FinishReason: models.FinishStop}
+	require.NoError(t, service.SetMessageMetadata(ctx, response.ID, meta))
+
+	msgs, err := service.GetMessages(ctx, chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Nil(t, msgs[0].Metadata, "messages without metadata have none")
+	assert.Equal(t, &models.MessageMetadata{
+		Model:           "opus",
+		Provider:        "claude",
+		LatencyMs:       1500,
+		TokensIn:        12,
+		TokensOut:       3,
+		TokensEstimated: true,
+		FinishReason:    models.FinishStop,
+	}, msgs[1].Metadata)
+
+	msg, err := service.GetMessage(ctx, chat.ID, prompt.ID)
+	require.NoError(t, err)
+	assert.Nil(t, msg.Metadata)
+}
+
+func TestChatService_OnChange(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	var actions []string
+	service.OnChange(func(action string, chatID int64) {
+		actions = append(actions, action)
+	})
+
+	chat, err := service.CreateChat(context.Background(), "Live Chat", "claude")
+	require.NoError(t, err)
+	_, err = service.AddMessage(context.Background(), chat.ID, "user", "Hello")
+	require.NoError(t, err)
+	require.NoError(t, service.UpdateChat(context.Background(), chat.ID, "Renamed"))
+	require.NoError(t, service.DeleteChat(context.Background(), chat.ID))
+
+	assert.Equal(t, []string{ChatCreated, ChatMessage, ChatRenamed, ChatDeleted}, actions)
+}
+
+func TestChatService_UpdateSystemPrompt(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	chat, err := service.CreateChat(context.Background(), "Persona Chat", "claude")
+	require.NoError(t, err)
+	assert.Empty(t, chat.SystemPrompt)
+
+	require.NoError(t, service.UpdateSystemPrompt(context.Background(), chat.ID, "You are a pirate."))
+	updated, err := service.GetChat(context.Background(), chat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "You are a pirate.", updated.SystemPrompt)
+
+	assert.Error(t, service.UpdateSystemPrompt(context.Background(), 99999, "Nobody"))
+}
+
+func TestBuildProviderInput(t *testing.T) {
+	assert.Equal(t, "Hello", BuildProviderInput("", "Hello"))
+	assert.Equal(t, "Hello", BuildProviderInput("  \n", "Hello"))
+	assert.Equal(t, "Be brief.\n\nHello", BuildProviderInput(" Be brief.\n", "Hello"))
+}
+
+func TestChatService_EditAndTruncateMessages(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	chat, err := service.CreateChat(context.Background(), "Regenerate Chat", "claude")
+	require.NoError(t, err)
+
+	first, err := service.AddMessage(context.Background(), chat.ID, "user", "First question")
+	require.NoError(t, err)
+	_, err = service.AddMessage(context.Background(), chat.ID, "assistant", "First answer")
+	require.NoError(t, err)
+	second, err := service.AddMessage(context.Background(), chat.ID, "user", "Second question")
+	require.NoError(t, err)
+	_, err = service.AddMessage(context.Background(), chat.ID, "assistant", "Second answer")
+	require.NoError(t, err)
+
+	last, err := service.GetLastUserMessage(context.Background(), chat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, last.ID)
+
+	edited, err := service.UpdateMessageContent(context.Background(), chat.ID, first.ID, "First question, edited")
+	require.NoError(t, err)
+	assert.Equal(t, "First question, edited", edited.Content)
+
+	// A message ID from another chat is not found
+	_, err = service.UpdateMessageContent(context.Background(), chat.ID+1, first.ID, "x")
+	assert.Error(t, err)
+
+	deleted, err := service.DeleteMessagesAfter(context.Background(), chat.ID, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	msgs, err := service.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "First question, edited", msgs[0].Content)
+
+	_, err = service.GetMessage(context.Background(), chat.ID, second.ID)
+	assert.Error(t, err)
+}
+
+func TestChatService_ArchiveAndRestore(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	active, err := service.CreateChat(context.Background(), "Active", "claude")
+	require.NoError(t, err)
+	archived, err := service.CreateChat(context.Background(), "Archived", "claude")
+	require.NoError(t, err)
+
+	var actions []string
+	service.OnChange(func(action string, chatID int64) {
+		actions = append(actions, action)
+	})
+
+	require.NoError(t, service.ArchiveChat(context.Background(), archived.ID))
+
+	chats, err := service.GetChats(context.Background(), 10, 0)
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, active.ID, chats[0].ID)
+
+	archivedChats, err := service.GetArchivedChats(context.Background(), 10, 0)
+	require.NoError(t, err)
+	require.Len(t, archivedChats, 1)
+	assert.Equal(t, archived.ID, archivedChats[0].ID)
+	assert.NotNil(t, archivedChats[0].ArchivedAt)
+
+	// Archived chats can still be opened
+	chat, err := service.GetChat(context.Background(), archived.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, chat.ArchivedAt)
+
+	require.NoError(t, service.RestoreChat(context.Background(), archived.ID))
+	chats, err = service.GetChats(context.Background(), 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, chats, 2)
+
+	// Deleted chats are hidden from both lists but can be restored
+	require.NoError(t, service.DeleteChat(context.Background(), active.ID))
+	chats, err = service.GetChats(context.Background(), 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, chats, 1)
+	require.NoError(t, service.RestoreChat(context.Background(), active.ID))
+	_, err = service.GetChat(context.Background(), active.ID)
+	assert.NoError(t, err)
+
+	assert.Error(t, service.ArchiveChat(context.Background(), 99999))
+	assert.Error(t, service.RestoreChat(context.Background(), 99999))
+	assert.Equal(t, []string{ChatArchived, ChatRestored, ChatDeleted, ChatRestored}, actions)
+}
+
+func TestChatService_PurgeDeletedChats(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	expired, err := service.CreateChat(context.Background(), "Expired", "claude")
+	require.NoError(t, err)
+	_, err = service.AddMessage(context.Background(), expired.ID, "user", "Hello")
+	require.NoError(t, err)
+	recent, err := service.CreateChat(context.Background(), "Recent", "claude")
+	require.NoError(t, err)
+	kept, err := service.CreateChat(context.Background(), "Kept", "claude")
+	require.NoError(t, err)
+
+	require.NoError(t, service.DeleteChat(context.Background(), expired.ID))
+	_, err = service.db.Exec(`UPDATE chats SET deleted_at = ? WHERE id = ?`, time.Now().Add(-48*time.Hour), expired.ID)
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteChat(context.Background(), recent.ID))
+
+	purged, err := service.PurgeDeletedChats(context.Background(), time.Now().Add(-24 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var count int
+	require.NoError(t, service.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE chat_id = ?`, expired.ID).Scan(&count))
+	assert.Zero(t, count)
+	assert.Error(t, service.RestoreChat(context.Background(), expired.ID))
+
+	// Recently deleted chats stay restorable and active chats are untouched
+	require.NoError(t, service.RestoreChat(context.Background(), recent.ID))
+	_, err = service.GetChat(context.Background(), kept.ID)
+	assert.NoError(t, err)
+}
+
+func TestChatService_SwitchProvider(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	chat, err := service.CreateChat(context.Background(), "Switch", "claude")
+	require.NoError(t, err)
+	_, err = service.AddMessage(context.Background(), chat.ID, "system", "Welcome")
+	require.NoError(t, err)
+	_, err = service.AddMessage(context.Background(), chat.ID, "user", "What is Go?")
+	require.NoError(t, err)
+	_, err = service.AddProviderMessage(context.Background(), chat.ID, "assistant", "A programming language.", "claude")
+	require.NoError(t, err)
+
+	history, err := service.HandoffHistory(context.Background(), chat.ID, "claude")
+	require.NoError(t, err)
+	assert.Empty(t, history, "nothing to hand over before a switch")
+
+	var actions []string
+	service.OnChange(func(action string, chatID int64) { actions = append(actions, action) })
+
+	switched, err := service.SwitchProvider(context.Background(), chat.ID, "gemini", "Switched")
+	require.NoError(t, err)
+	assert.Equal(t, "gemini", switched.Provider)
+	assert.Contains(t, actions, ChatProviderChanged)
+
+	// Switching to the current provider records nothing
+	_, err = service.SwitchProvider(context.Background(), chat.ID, "gemini", "Switched")
+	require.NoError(t, err)
+	messages, err := service.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 4)
+	assert.Equal(t, "system", messages[3].Role)
+	assert.Equal(t, "gemini", messages[3].Provider)
+
+	// The new provider gets the conversation before the switch, without system messages
+	_, err = service.AddMessage(context.Background(), chat.ID, "user", "And Rust?")
+	require.NoError(t, err)
+	history, err = service.HandoffHistory(context.Background(), chat.ID, "gemini")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "What is Go?", history[0].Content)
+
+	input := FormatHandoffHistory(history, "And Rust?")
+	assert.Contains(t, input, "User: What is Go?")
+	assert.Contains(t, input, "Assistant (claude): A programming language.")
+	assert.True(t, strings.HasSuffix(input, "And Rust?"))
+
+	history, err = service.HandoffHistory(context.Background(), chat.ID, "claude")
+	require.NoError(t, err)
+	assert.Empty(t, history, "only the provider switched to gets the history")
+
+	// Once the new provider answered it has the conversation
+	_, err = service.AddProviderMessage(context.Background(), chat.ID, "assistant", "Another language.", "gemini")
+	require.NoError(t, err)
+	history, err = service.HandoffHistory(context.Background(), chat.ID, "gemini")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestFormatHandoffHistory_KeepsRecentMessages(t *testing.T) {
+	old := &models.Message{Role: "user", Content: strings.Repeat("a", MaxHandoffHistoryBytes)}
+	recent := &models.Message{Role: "user", Content: "recent"}
+
+	input := FormatHandoffHistory([]*models.Message{old, recent}, "prompt")
+	assert.Contains(t, input, "User: recent")
+	assert.NotContains(t, input, "aaaa")
+
+	assert.Equal(t, "prompt", FormatHandoffHistory(nil, "prompt"))
+}
+
+func TestChatService_ProviderSessions(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	chat, err := service.CreateChat(context.Background(), "Sessions", "claude")
+	require.NoError(t, err)
+	first, err := service.AddMessage(context.Background(), chat.ID, "user", "What is Go?")
+	require.NoError(t, err)
+	_, err = service.AddProviderMessage(context.Background(), chat.ID, "assistant", "A programming language.", "claude")
+	require.NoError(t, err)
+	prompt, err := service.AddMessage(context.Background(), chat.ID, "user", "And Rust?")
+	require.NoError(t, err)
+
+	history, err := service.ConversationBefore(context.Background(), chat.ID, prompt.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, first.ID, history[0].ID)
+
+	sessionID, err := service.ProviderSession(context.Background(), chat.ID, "claude")
+	require.NoError(t, err)
+	assert.Empty(t, sessionID)
+
+	require.NoError(t, service.SetProviderSession(context.Background(), chat.ID, "claude", "one"))
+	require.NoError(t, service.SetProviderSession(context.Background(), chat.ID, "claude", "two"))
+	sessionID, err = service.ProviderSession(context.Background(), chat.ID, "claude")
+	require.NoError(t, err)
+	assert.Equal(t, "two", sessionID)
+
+	// Editing the history forgets the sessions, which no longer match it
+	_, err = service.DeleteMessagesAfter(context.Background(), chat.ID, first.ID)
+	require.NoError(t, err)
+	sessionID, err = service.ProviderSession(context.Background(), chat.ID, "claude")
+	require.NoError(t, err)
+	assert.Empty(t, sessionID)
+}
+
+func TestChatService_Tags(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	work, err := service.CreateChat(context.Background(), "Work", "claude")
+	require.NoError(t, err)
+	home, err := service.CreateChat(context.Background(), "Home", "claude")
+	require.NoError(t, err)
+
+	require.NoError(t, service.TagChat(context.Background(), work.ID, " Project-X "))
+	require.NoError(t, service.TagChat(context.Background(), work.ID, "project-x"))
+	require.NoError(t, service.TagChat(context.Background(), work.ID, "urgent"))
+	require.NoError(t, service.TagChat(context.Background(), home.ID, "urgent"))
+
+	assert.ErrorIs(t, service.TagChat(context.Background(), work.ID, "  "), ErrInvalidTag)
+	assert.ErrorIs(t, service.TagChat(context.Background(), work.ID, "a/b"), ErrInvalidTag)
+	assert.ErrorIs(t, service.TagChat(context.Background(), work.ID, strings.Repeat("x", MaxTagLength+1)), ErrInvalidTag)
+
+	chat, err := service.GetChat(context.Background(), work.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"project-x", "urgent"}, chat.Tags)
+
+	chats, err := service.ListChats(context.Background(), ChatFilter{Tag: "Project-X"}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, work.ID, chats[0].ID)
+
+	chats, err = service.ListChats(context.Background(), ChatFilter{Tag: "urgent"}, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, chats, 2)
+
+	tags, err := service.ListTags(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.Equal(t, "project-x", tags[0].Name)
+	assert.Equal(t, 1, tags[0].ChatCount)
+	assert.Equal(t, 2, tags[1].ChatCount)
+
+	require.NoError(t, service.UntagChat(context.Background(), work.ID, "URGENT"))
+	assert.ErrorIs(t, service.UntagChat(context.Background(), work.ID, "urgent"), ErrTagNotFound)
+
+	require.NoError(t, service.DeleteTag(context.Background(), tags[1].ID))
+	assert.ErrorIs(t, service.DeleteTag(context.Background(), tags[1].ID), ErrTagNotFound)
+	chat, err = service.GetChat(context.Background(), home.ID)
+	require.NoError(t, err)
+	assert.Empty(t, chat.Tags)
+}
+
+func TestChatService_Folders(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	filed, err := service.CreateChat(context.Background(), "Filed", "claude")
+	require.NoError(t, err)
+	loose, err := service.CreateChat(context.Background(), "Loose", "claude")
+	require.NoError(t, err)
+
+	folder, err := service.CreateFolder(context.Background(), "  Research ")
+	require.NoError(t, err)
+	assert.Equal(t, "Research", folder.Name)
+
+	_, err = service.CreateFolder(context.Background(), "Research")
+	assert.ErrorIs(t, err, ErrFolderExists)
+	_, err = service.CreateFolder(context.Background(), "")
+	assert.ErrorIs(t, err, ErrInvalidFolderName)
+
+	require.NoError(t, service.MoveChatToFolder(context.Background(), filed.ID, &folder.ID))
+	missing := folder.ID + 100
+	assert.ErrorIs(t, service.MoveChatToFolder(context.Background(), loose.ID, &missing), ErrFolderNotFound)
+
+	chats, err := service.ListChats(context.Background(), ChatFilter{FolderID: &folder.ID}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, filed.ID, chats[0].ID)
+	require.NotNil(t, chats[0].FolderID)
+	assert.Equal(t, folder.ID, *chats[0].FolderID)
+
+	unfiled := int64(0)
+	chats, err = service.ListChats(context.Background(), ChatFilter{FolderID: &unfiled}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, loose.ID, chats[0].ID)
+
+	renamed, err := service.RenameFolder(context.Background(), folder.ID, "Papers")
+	require.NoError(t, err)
+	assert.Equal(t, "Papers", renamed.Name)
+
+	folders, err := service.ListFolders(context.Background())
+	require.NoError(t, err)
+	require.Len(t, folders, 1)
+	assert.Equal(t, 1, folders[0].ChatCount)
+
+	// Deleting a folder keeps its chats
+	require.NoError(t, service.DeleteFolder(context.Background(), folder.ID))
+	assert.ErrorIs(t, service.DeleteFolder(context.Background(), folder.ID), ErrFolderNotFound)
+	chat, err := service.GetChat(context.Background(), filed.ID)
+	require.NoError(t, err)
+	assert.Nil(t, chat.FolderID)
+}
+
+func TestChatService_CancelledContext(t *testing.T) {
+	service, cleanup := setupTestChatService(t)
+	defer cleanup()
+
+	chat, err := service.CreateChat(context.Background(), "Cancelled", "claude")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = service.GetChat(ctx, chat.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = service.AddMessage(ctx, chat.ID, "user", "Too late")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	messages, err := service.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+}
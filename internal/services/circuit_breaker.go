@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/utils"
+)
+
+// ErrProviderDegraded is returned for generations refused because the provider's circuit is open
+var ErrProviderDegraded = errors.New("provider is degraded")
+
+// CircuitBreaker stops sending generations to providers that keep failing. A provider's circuit
+// opens after threshold consecutive failures and refuses generations for the cooldown; then one
+// probe is let through (half-open), which closes the circuit when it succeeds or opens it again.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	circuits  map[string]*circuit
+	now       func() time.Time
+}
+
+// circuit is the state of one provider
+type circuit struct {
+	failures  int
+	lastError string
+	open      bool
+	openedAt  time.Time
+	probeAt   time.Time // when the probe of a half-open circuit was let through, zero before
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  make(map[string]*circuit),
+		now:       time.Now,
+	}
+}
+
+// Allow reports whether a generation may be sent to the provider. A half-open circuit lets one
+// probe through; another is allowed after a cooldown without an outcome, e.g. when the probe was
+// refused before it ran.
+func (b *CircuitBreaker) Allow(providerID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuits[providerID]
+	if c == nil || !c.open {
+		return nil
+	}
+	now := b.now()
+	retryAt := c.openedAt.Add(b.cooldown)
+	if now.Before(retryAt) {
+		return fmt.Errorf("%w after %d consecutive failures (last: %s), retry in %v",
+			ErrProviderDegraded, c.failures, c.lastError, retryAt.Sub(now).Round(time.Second))
+	}
+	if !c.probeAt.IsZero() && now.Before(c.probeAt.Add(b.cooldown)) {
+		return fmt.Errorf("%w, a probe request is checking whether it recovered", ErrProviderDegraded)
+	}
+	c.probeAt = now
+	utils.Info("Circuit of provider %s is half-open, sending a probe request", providerID)
+	return nil
+}
+
+// Record counts the outcome of a generation; a nil err is a success
+func (b *CircuitBreaker) Record(providerID string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuits[providerID]
+	if err == nil {
+		if c != nil && c.open {
+			utils.Info("Circuit of provider %s closed, the provider recovered", providerID)
+		}
+		delete(b.circuits, providerID)
+		return
+	}
+
+	if c == nil {
+		c = &circuit{}
+		b.circuits[providerID] = c
+	}
+	c.failures++
+	c.lastError = err.Error()
+	if c.open || c.failures >= b.threshold {
+		if !c.open {
+			utils.Warn("Circuit of provider %s opened after %d consecutive failures: %v", providerID, c.failures, err)
+		}
+		c.open = true
+		c.openedAt = b.now()
+		c.probeAt = time.Time{}
+	}
+}
+
+// State returns the state of a provider's circuit, or nil while it is closed without failures
+func (b *CircuitBreaker) State(providerID string) *models.CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuits[providerID]
+	if c == nil {
+		return nil
+	}
+	state := &models.CircuitState{State: models.CircuitClosed, ConsecutiveFailures: c.failures, LastError: c.lastError}
+	if c.open {
+		openedAt, retryAt := c.openedAt, c.openedAt.Add(b.cooldown)
+		state.OpenedAt, state.RetryAt = &openedAt, &retryAt
+		state.State = models.CircuitOpen
+		if !b.now().Before(retryAt) {
+			state.State = models.CircuitHalfOpen
+		}
+	}
+	return state
+}
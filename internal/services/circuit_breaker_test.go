@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(3, 30*time.Second)
+	breaker.now = func() time.Time { return now }
+	failure := errors.New("exit status 1")
+
+	// Failures below the threshold leave the circuit closed
+	breaker.Record("claude", failure)
+	breaker.Record("claude", failure)
+	assert.NoError(t, breaker.Allow("claude"))
+	assert.Equal(t, &models.CircuitState{State: models.CircuitClosed, ConsecutiveFailures: 2, LastError: "exit status 1"}, breaker.State("claude"))
+
+	// A success resets the count
+	breaker.Record("claude", nil)
+	assert.Nil(t, breaker.State("claude"))
+
+	for i := 0; i < 3; i++ {
+		breaker.Record("claude", failure)
+	}
+	err := breaker.Allow("claude")
+	require.ErrorIs(t, err, ErrProviderDegraded)
+	assert.Contains(t, err.Error(), "after 3 consecutive failures (last: exit status 1), retry in 30s")
+	assert.Equal(t, models.CircuitOpen, breaker.State("claude").State)
+	assert.NoError(t, breaker.Allow("gemini"), "circuits are per provider")
+
+	// After the cooldown a single probe is let through
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, models.CircuitHalfOpen, breaker.State("claude").State)
+	assert.NoError(t, breaker.Allow("claude"))
+	assert.ErrorIs(t, breaker.Allow("claude"), ErrProviderDegraded)
+
+	// A failed probe opens the circuit again
+	breaker.Record("claude", failure)
+	assert.ErrorIs(t, breaker.Allow("claude"), ErrProviderDegraded)
+	assert.Equal(t, 4, breaker.State("claude").ConsecutiveFailures)
+
+	// A probe without an outcome is replaced after another cooldown
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, breaker.Allow("claude"))
+	now = now.Add(29 * time.Second)
+	assert.ErrorIs(t, breaker.Allow("claude"), ErrProviderDegraded)
+	now = now.Add(time.Second)
+	assert.NoError(t, breaker.Allow("claude"))
+
+	// A successful probe closes the circuit
+	breaker.Record("claude", nil)
+	assert.NoError(t, breaker.Allow("claude"))
+	assert.NoError(t, breaker.Allow("claude"))
+	assert.Nil(t, breaker.State("claude"))
+}
+
+func TestProviderRegistry_CircuitBreaker(t *testing.T) {
+	registry := NewProviderRegistry(nil)
+	require.NoError(t, registry.Register(&stubProvider{id: "stub"}))
+	breaker := NewCircuitBreaker(1, time.Minute)
+	registry.SetCircuitBreaker(breaker)
+
+	_, release, err := registry.Acquire("stub")
+	require.NoError(t, err)
+	release()
+	registry.RecordResult("stub", errors.New("provider crashed"))
+
+	_, _, err = registry.Acquire("stub")
+	assert.ErrorIs(t, err, ErrProviderDegraded)
+
+	list := registry.List()
+	require.Len(t, list, 1)
+	assert.Equal(t, "degraded", list[0].Status)
+	assert.False(t, list[0].Available)
+	assert.Equal(t, "1 consecutive failures, last: provider crashed", list[0].Details)
+	require.NotNil(t, list[0].Circuit)
+	assert.Equal(t, models.CircuitOpen, list[0].Circuit.State)
+
+	// Once half-open, the provider is listed as available for the probe
+	breaker.now = func() time.Time { return time.Now().Add(time.Minute) }
+	list = registry.List()
+	assert.Equal(t, models.CircuitHalfOpen, list[0].Circuit.State)
+	assert.True(t, list[0].Available)
+}
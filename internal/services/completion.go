@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/moderation"
+	"ai-gateway-hub/internal/processing"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+)
+
+var (
+	// ErrCompletionStreaming is returned for completions asking to stream, which needs the WebSocket
+	ErrCompletionStreaming = apperrors.Validation("streamed responses are only available over the WebSocket, set stream to false")
+
+	// ErrCompletionTimeout is returned when the provider didn't complete the response in time
+	ErrCompletionTimeout = errors.New("completion timed out")
+
+	// ErrProviderUnavailable is returned when the provider asked for can't take prompts
+	ErrProviderUnavailable = errors.New("provider is not available")
+)
+
+// CompletionService answers single prompts synchronously, for scripts and cron jobs that don't keep
+// a WebSocket open. The prompt and response are added to a given chat, or to a temporary chat that
+// is moved to the trash afterwards.
+type CompletionService struct {
+	chatService  *ChatService
+	registry     *ProviderRegistry
+	usageService *UsageService
+	moderation   *ModerationService // nil moderates nothing
+	processing   *ProcessingService // nil transforms nothing
+	timeouts     func(providerID string) (total, idle time.Duration)
+}
+
+// NewCompletionService limits each completion to the total prompt timeout of its provider
+func NewCompletionService(chatService *ChatService, registry *ProviderRegistry, usageService *UsageService, timeouts func(providerID string) (total, idle time.Duration)) *CompletionService {
+	return &CompletionService{
+		chatService:  chatService,
+		registry:     registry,
+		usageService: usageService,
+		timeouts:     timeouts,
+	}
+}
+
+// SetModeration moderates completion prompts and their responses
+func (s *CompletionService) SetModeration(moderationService *ModerationService) {
+	s.moderation = moderationService
+}
+
+// SetProcessing transforms completion prompts and their responses with the configured processors
+func (s *CompletionService) SetProcessing(processingService *ProcessingService) {
+	s.processing = processingService
+}
+
+// Complete sends a prompt to a provider and waits for the whole response
+func (s *CompletionService) Complete(ctx context.Context, req models.CompletionRequest) (*models.Completion, error) {
+	if req.Stream {
+		return nil, ErrCompletionStreaming
+	}
+	return s.complete(ctx, req, nil)
+}
+
+// CompleteStream is Complete writing the response to w as the provider streams it. Post processors
+// and response moderation only apply to the completion returned and saved, once w got the response.
+func (s *CompletionService) CompleteStream(ctx context.Context, req models.CompletionRequest, w io.Writer) (*models.Completion, error) {
+	return s.complete(ctx, req, w)
+}
+
+func (s *CompletionService) complete(ctx context.Context, req models.CompletionRequest, w io.Writer) (*models.Completion, error) {
+	if err := s.chatService.MessageLimits().ValidatePrompt(req.Prompt); err != nil {
+		return nil, err
+	}
+
+	var chat *models.Chat
+	if req.ChatID != nil {
+		var err error
+		if chat, err = s.chatService.GetChat(ctx, *req.ChatID); err != nil {
+			return nil, err
+		}
+		if req.Provider == "" {
+			req.Provider = chat.Provider
+		}
+		if req.SystemPrompt != "" {
+			return nil, apperrors.Validation("system_prompt only applies to temporary chats, without chat_id")
+		}
+	} else if req.Provider == "" {
+		return nil, apperrors.Validation("provider is required without chat_id")
+	}
+
+	// Providers that can't take prompts are replaced by the first available fallback of their chain
+	chain := s.registry.NewFailover(req.Provider, req.Model)
+	provider, release, err := s.registry.Acquire(req.Provider)
+	if err == nil && !provider.IsAvailable() {
+		release()
+		err = fmt.Errorf("%w: %s", ErrProviderUnavailable, req.Provider)
+	}
+	if err != nil {
+		fallback, fallbackRelease := s.registry.NextFallback(chain, req.Provider, err, false)
+		switch {
+		case fallback != nil:
+			provider, release = fallback, fallbackRelease
+			req.Provider, req.Model = provider.GetID(), chain.ModelFor(provider)
+		case errors.Is(err, ErrProviderDegraded), errors.Is(err, ErrProviderUnavailable):
+			return nil, err
+		default:
+			return nil, apperrors.NotFound(fmt.Sprintf("provider %s not found", req.Provider))
+		}
+	}
+	defer func() { release() }()
+	if req.Model != "" && !providers.SupportsModel(provider, req.Model) {
+		return nil, apperrors.Validation(fmt.Sprintf("model %s is not supported by %s", req.Model, req.Provider))
+	}
+
+	timeout, _ := s.timeouts(req.Provider)
+	if req.TimeoutSecs < 0 || (req.TimeoutSecs > 0 && time.Duration(req.TimeoutSecs)*time.Second > timeout) {
+		return nil, apperrors.Validation(fmt.Sprintf("timeout_seconds must be between 1 and %d", int(timeout.Seconds())))
+	}
+	if req.TimeoutSecs > 0 {
+		timeout = time.Duration(req.TimeoutSecs) * time.Second
+	}
+
+	completion := &models.Completion{Provider: req.Provider, Model: req.Model}
+	if chat == nil {
+		if chat, err = s.chatService.CreateChat(ctx, req.Title, req.Provider); err != nil {
+			return nil, err
+		}
+		completion.Ephemeral = true
+		// The temporary chat is only kept in the trash, like chats deleted by hand
+		defer func() {
+			if err := s.chatService.DeleteChat(context.WithoutCancel(ctx), chat.ID); err != nil {
+				utils.Warn("Failed to delete completion chat %d: %v", chat.ID, err)
+			}
+		}()
+		if req.SystemPrompt != "" {
+			if err := s.chatService.UpdateSystemPrompt(ctx, chat.ID, req.SystemPrompt); err != nil {
+				return nil, err
+			}
+			chat.SystemPrompt = req.SystemPrompt
+		}
+	}
+	completion.ChatID = chat.ID
+
+	if err := s.moderate(ctx, chat.ID, req.Provider, moderation.DirectionPrompt, req.Prompt); err != nil {
+		return nil, err
+	}
+	promptMsg, err := s.chatService.AddMessage(ctx, chat.ID, "user", req.Prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	var response strings.Builder
+	var out io.Writer = &response
+	if w != nil {
+		out = io.MultiWriter(&response, w)
+	}
+	var input string
+	var latency time.Duration
+	var timedOut bool
+	for {
+		input, latency, timedOut, err = s.generate(ctx, provider, req, chat, timeout, out)
+		// Callers going away isn't the provider's failure
+		if ctx.Err() == nil {
+			s.registry.RecordResult(req.Provider, err)
+		}
+		s.recordUsage(chat.ID, &promptMsg.ID, req.Provider, models.UsageInput, input)
+		if err == nil || timedOut || response.Len() > 0 || ctx.Err() != nil {
+			break
+		}
+
+		// Nothing was answered yet, so the next fallback can answer instead
+		fallback, fallbackRelease := s.registry.NextFallback(chain, req.Provider, err, false)
+		if fallback == nil {
+			break
+		}
+		release()
+		provider, release = fallback, fallbackRelease
+		req.Provider, req.Model = provider.GetID(), chain.ModelFor(provider)
+		if req.TimeoutSecs == 0 {
+			timeout, _ = s.timeouts(req.Provider)
+		}
+	}
+	completion.Provider, completion.Model = req.Provider, req.Model
+	if err != nil {
+		if timedOut {
+			return nil, fmt.Errorf("%w after %s", ErrCompletionTimeout, timeout)
+		}
+		return nil, fmt.Errorf("failed to get response: %w", err)
+	}
+	if response.Len() == 0 {
+		return nil, fmt.Errorf("%s returned an empty response", req.Provider)
+	}
+
+	limits := s.chatService.MessageLimits()
+	content, oversized := limits.TruncateResponse(s.process(ctx, chat.ID, req.Provider, processing.StagePost, response.String()))
+	if err := s.moderate(ctx, chat.ID, req.Provider, moderation.DirectionResponse, content); err != nil {
+		s.recordUsage(chat.ID, nil, req.Provider, models.UsageOutput, content)
+		return nil, err
+	}
+
+	responseMsg, err := s.chatService.AddProviderMessage(ctx, chat.ID, "assistant", content, req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if req.Model != "" {
+		if err := s.chatService.SetMessageModel(ctx, responseMsg.ID, req.Model); err != nil {
+			utils.Warn("Failed to record model of completion %d: %v", responseMsg.ID, err)
+		}
+	}
+	meta := &models.MessageMetadata{
+		Model:           req.Model,
+		Provider:        req.Provider,
+		LatencyMs:       latency.Milliseconds(),
+		TokensIn:        EstimateTokens(input),
+		TokensOut:       EstimateTokens(content),
+		TokensEstimated: true,
+		FinishReason:    models.FinishStop,
+	}
+	if oversized && limits.TruncatesResponses() {
+		meta.FinishReason = models.FinishLength
+	}
+	if chain.FailedOver() {
+		meta.FailedOver = chain.Failed
+	}
+	if err := s.chatService.SetMessageMetadata(ctx, responseMsg.ID, meta); err != nil {
+		utils.Warn("Failed to record metadata of completion %d: %v", responseMsg.ID, err)
+	} else {
+		completion.Metadata = meta
+	}
+	s.recordUsage(chat.ID, &responseMsg.ID, req.Provider, models.UsageOutput, content)
+
+	completion.MessageID = responseMsg.ID
+	completion.Content = content
+	return completion, nil
+}
+
+// generate sends the prompt to a provider, which writes the response to out, and returns the
+// input it was sent and whether it ran out of time
+func (s *CompletionService) generate(ctx context.Context, provider providers.AIProvider, req models.CompletionRequest, chat *models.Chat, timeout time.Duration, out io.Writer) (input string, latency time.Duration, timedOut bool, err error) {
+	genCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	genCtx = providers.WithModel(genCtx, req.Model)
+
+	input = BuildProviderInput(chat.SystemPrompt, s.process(ctx, chat.ID, req.Provider, processing.StagePre, req.Prompt))
+	startedAt := time.Now()
+	err = provider.StreamResponse(genCtx, input, chat.ID, out)
+	return input, time.Since(startedAt), errors.Is(genCtx.Err(), context.DeadlineExceeded), err
+}
+
+// process runs the processors of a stage over content
+func (s *CompletionService) process(ctx context.Context, chatID int64, providerID, stage, content string) string {
+	if s.processing == nil {
+		return content
+	}
+	return s.processing.Process(ctx, stage, chatID, providerID, content)
+}
+
+// moderate checks content with the moderation service, returning a validation error if it was blocked
+func (s *CompletionService) moderate(ctx context.Context, chatID int64, providerID, direction, content string) error {
+	if s.moderation == nil {
+		return nil
+	}
+	verdict := s.moderation.Check(ctx, moderation.Request{Direction: direction, ChatID: chatID, Provider: providerID, Content: content})
+	if verdict.Blocked() {
+		return apperrors.Validation(fmt.Sprintf("%s was blocked by moderation (%s)", direction, verdict.Rule))
+	}
+	return nil
+}
+
+// recordUsage stores estimated usage for a completion; failures are only logged
+func (s *CompletionService) recordUsage(chatID int64, messageID *int64, providerID, direction, content string) {
+	if s.usageService == nil {
+		return
+	}
+	if err := s.usageService.RecordContent(chatID, messageID, providerID, direction, content); err != nil {
+		utils.Error("Failed to record usage for completion in chat %d: %v", chatID, err)
+	}
+}
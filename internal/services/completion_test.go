@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hangingProvider never answers, until the prompt is cancelled
+type hangingProvider struct {
+	stubProvider
+}
+
+func (p *hangingProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestCompletionService_Complete(t *testing.T) {
+	chatService, cleanup := setupTestChatService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	registry := NewProviderRegistry(nil)
+	require.NoError(t, registry.Register(&stubProvider{id: "stub"}))
+	require.NoError(t, registry.Register(&hangingProvider{stubProvider{id: "hanging"}}))
+	service := NewCompletionService(chatService, registry, nil, func(string) (time.Duration, time.Duration) {
+		return time.Minute, time.Minute
+	})
+
+	// Without a chat the exchange goes to a temporary chat, moved to the trash afterwards
+	completion, err := service.Complete(ctx, models.CompletionRequest{Provider: "stub", Prompt: "Echo this", Title: "Script"})
+	require.NoError(t, err)
+	assert.Equal(t, "Echo this", completion.Content, "the stub echoes the prompt")
+	assert.True(t, completion.Ephemeral)
+	require.NotNil(t, completion.Metadata)
+	assert.Equal(t, models.FinishStop, completion.Metadata.FinishReason)
+	_, err = chatService.GetChat(ctx, completion.ChatID)
+	assert.ErrorIs(t, err, ErrChatNotFound)
+
+	// A given chat keeps the exchange, answered by its provider
+	chat, err := chatService.CreateChat(ctx, "Reports", "stub")
+	require.NoError(t, err)
+	completion, err = service.Complete(ctx, models.CompletionRequest{ChatID: &chat.ID, Prompt: "Daily report"})
+	require.NoError(t, err)
+	assert.False(t, completion.Ephemeral)
+	assert.Equal(t, "stub", completion.Provider)
+	messages, err := chatService.GetMessages(ctx, chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, completion.MessageID, messages[1].ID)
+
+	_, err = service.Complete(ctx, models.CompletionRequest{Provider: "stub", Prompt: "Hi", Stream: true})
+	assert.ErrorIs(t, err, ErrCompletionStreaming)
+	_, err = service.Complete(ctx, models.CompletionRequest{Prompt: "Hi"})
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
+	_, err = service.Complete(ctx, models.CompletionRequest{Provider: "missing", Prompt: "Hi"})
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	_, err = service.Complete(ctx, models.CompletionRequest{Provider: "stub", Prompt: "Hi", TimeoutSecs: 120})
+	assert.ErrorIs(t, err, apperrors.ErrValidation, "requests can't wait longer than the prompt timeout")
+
+	// Providers that don't answer in time fail the completion
+	_, err = service.Complete(ctx, models.CompletionRequest{ChatID: &chat.ID, Provider: "hanging", Prompt: "Hi", TimeoutSecs: 1})
+	assert.ErrorIs(t, err, ErrCompletionTimeout)
+}
+
+func TestCompletionService_CompleteStream(t *testing.T) {
+	chatService, cleanup := setupTestChatService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	registry := NewProviderRegistry(nil)
+	require.NoError(t, registry.Register(&stubProvider{id: "stub"}))
+	service := NewCompletionService(chatService, registry, nil, func(string) (time.Duration, time.Duration) {
+		return time.Minute, time.Minute
+	})
+
+	// The response is written as it streams, with the system prompt of the temporary chat sent first
+	var streamed strings.Builder
+	completion, err := service.CompleteStream(ctx, models.CompletionRequest{Provider: "stub", Prompt: "Echo this", SystemPrompt: "Be brief"}, &streamed)
+	require.NoError(t, err)
+	assert.Equal(t, completion.Content, streamed.String())
+	assert.Contains(t, completion.Content, "Be brief")
+	assert.Contains(t, completion.Content, "Echo this")
+
+	chat, err := chatService.CreateChat(ctx, "Reports", "stub")
+	require.NoError(t, err)
+	_, err = service.CompleteStream(ctx, models.CompletionRequest{ChatID: &chat.ID, Prompt: "Hi", SystemPrompt: "Be brief"}, &streamed)
+	assert.ErrorIs(t, err, apperrors.ErrValidation, "chats keep their own system prompt")
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"ai-gateway-hub/internal/config"
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+)
+
+// ConfigBundleVersion is the bundle format written by Export; Import accepts this version and older
+const ConfigBundleVersion = 1
+
+var (
+	// ErrConfigBundlesDisabled is returned when no CONFIG_BUNDLE_SECRET is configured
+	ErrConfigBundlesDisabled = errors.New("configuration bundles are disabled: CONFIG_BUNDLE_SECRET is not set")
+	// ErrInvalidBundleSignature is returned when a bundle was not signed with this hub's secret or was modified
+	ErrInvalidBundleSignature = apperrors.Validation("invalid configuration bundle signature")
+	// ErrInvalidConfigBundle is returned when a correctly signed bundle cannot be applied
+	ErrInvalidConfigBundle = apperrors.Validation("invalid configuration bundle")
+)
+
+// ConfigBundle is the runtime configuration of a hub that can be moved to another instance
+type ConfigBundle struct {
+	Version    int                        `json:"version"`
+	ExportedAt time.Time                  `json:"exported_at"`
+	Providers  []providers.ProviderConfig `json:"providers"`          // contents of the providers file
+	Flags      map[string]bool            `json:"flags"`              // feature flags, for reference only
+	Settings   map[string]json.RawMessage `json:"settings,omitempty"` // admin settings such as the greeting
+}
+
+// SignedConfigBundle is the exported document: the bundle and its HMAC-SHA256 signature
+type SignedConfigBundle struct {
+	Bundle    json.RawMessage `json:"bundle"`
+	Signature string          `json:"signature"`
+}
+
+// ConfigImportResult reports what an import changed (or would change for a dry run)
+type ConfigImportResult struct {
+	DryRun    bool     `json:"dry_run"`
+	Providers []string `json:"providers"`
+	Settings  []string `json:"settings"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// ConfigBundleService exports and imports signed configuration bundles
+type ConfigBundleService struct {
+	cfg             *config.Config
+	registry        *ProviderRegistry
+	settings        *SettingsService
+	greetingService *GreetingService
+}
+
+func NewConfigBundleService(cfg *config.Config, registry *ProviderRegistry, settings *SettingsService, greetingService *GreetingService) *ConfigBundleService {
+	return &ConfigBundleService{cfg: cfg, registry: registry, settings: settings, greetingService: greetingService}
+}
+
+// Export collects the current configuration into a signed bundle
+func (s *ConfigBundleService) Export() (*SignedConfigBundle, error) {
+	if s.cfg.ConfigBundleSecret == "" {
+		return nil, ErrConfigBundlesDisabled
+	}
+
+	settings, err := s.settings.All()
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := ConfigBundle{
+		Version:    ConfigBundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Providers:  s.registry.ProviderConfigs(),
+		Flags:      s.cfg.FeatureFlags(),
+		Settings:   settings,
+	}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode configuration bundle: %w", err)
+	}
+	return &SignedConfigBundle{Bundle: data, Signature: signConfigBundle(s.cfg.ConfigBundleSecret, data)}, nil
+}
+
+// Import verifies a signed bundle and applies it: the providers file is replaced and the bundled
+// settings are overwritten. Feature flags come from the environment and are only compared.
+func (s *ConfigBundleService) Import(signed *SignedConfigBundle, dryRun bool) (*ConfigImportResult, error) {
+	if s.cfg.ConfigBundleSecret == "" {
+		return nil, ErrConfigBundlesDisabled
+	}
+	if !hmac.Equal([]byte(signed.Signature), []byte(signConfigBundle(s.cfg.ConfigBundleSecret, signed.Bundle))) {
+		return nil, ErrInvalidBundleSignature
+	}
+
+	var bundle ConfigBundle
+	if err := json.Unmarshal(signed.Bundle, &bundle); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfigBundle, err)
+	}
+	if bundle.Version < 1 || bundle.Version > ConfigBundleVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidConfigBundle, bundle.Version)
+	}
+
+	result := &ConfigImportResult{DryRun: dryRun, Providers: []string{}, Settings: []string{}}
+
+	// Validate everything before changing anything
+	importProviders := bundle.Providers != nil
+	if importProviders {
+		if err := providers.ValidateProviders(bundle.Providers); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfigBundle, err)
+		}
+		if s.cfg.ProvidersFile == "" {
+			importProviders = false
+			if len(bundle.Providers) > 0 {
+				result.Warnings = append(result.Warnings, "PROVIDERS_FILE is not set, providers were not imported")
+			}
+		}
+	}
+	for key, value := range bundle.Settings {
+		if !json.Valid(value) {
+			return nil, fmt.Errorf("%w: invalid value for setting %s", ErrInvalidConfigBundle, key)
+		}
+	}
+
+	local := s.cfg.FeatureFlags()
+	for _, name := range sortedKeys(bundle.Flags) {
+		if current, ok := local[name]; ok && current != bundle.Flags[name] {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("%s is %t in the bundle but %t here; feature flags are set through the environment", name, bundle.Flags[name], current))
+		}
+	}
+
+	if importProviders {
+		for _, pc := range bundle.Providers {
+			result.Providers = append(result.Providers, pc.ID)
+		}
+	}
+	result.Settings = sortedKeys(bundle.Settings)
+
+	if dryRun {
+		return result, nil
+	}
+
+	if importProviders {
+		if err := s.registry.ReplaceProvidersFile(s.cfg.ProvidersFile, s.cfg.LogDir, bundle.Providers); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range result.Settings {
+		if err := s.settings.Set(key, bundle.Settings[key]); err != nil {
+			return nil, err
+		}
+	}
+	if s.greetingService != nil {
+		s.greetingService.Invalidate()
+	}
+
+	utils.Info("Imported configuration bundle exported at %s (%d providers, %d settings)",
+		bundle.ExportedAt.Format(time.RFC3339), len(result.Providers), len(result.Settings))
+	return result, nil
+}
+
+// signConfigBundle returns the hex HMAC-SHA256 of the bundle bytes
+func signConfigBundle(secret string, data []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBundleTestHub creates the services of one hub instance with its own database and providers file
+func newBundleTestHub(t *testing.T, secret string) (*ConfigBundleService, *ProviderRegistry, *GreetingService) {
+	t.Helper()
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		ConfigBundleSecret: secret,
+		ProvidersFile:      filepath.Join(dir, "providers.yaml"),
+		LogDir:             filepath.Join(dir, "logs"),
+		EnableHealthChecks: true,
+	}
+
+	settings := NewSettingsService(db)
+	registry := NewProviderRegistry(nil)
+	greeting := NewGreetingService(settings, NewChatService(db))
+	return NewConfigBundleService(cfg, registry, settings, greeting), registry, greeting
+}
+
+func TestConfigBundleService_ExportImport(t *testing.T) {
+	require.NoError(t, utils.InitPathManager())
+	const secret = "staging-to-production-secret"
+
+	staging, stagingRegistry, stagingGreeting := newBundleTestHub(t, secret)
+	production, productionRegistry, productionGreeting := newBundleTestHub(t, secret)
+
+	require.NoError(t, stagingRegistry.ReplaceProvidersFile(staging.cfg.ProvidersFile, staging.cfg.LogDir, []providers.ProviderConfig{
+		{ID: "gemini", Command: "gemini", Args: []string{"--yolo"}},
+	}))
+	_, err := stagingGreeting.Update(models.GreetingSettings{Enabled: true, Welcome: map[string]string{"en": "Welcome"}})
+	require.NoError(t, err)
+
+	// Production has already loaded its greeting, which the import must refresh
+	greeting, err := productionGreeting.Get()
+	require.NoError(t, err)
+	assert.False(t, greeting.Enabled)
+
+	signed, err := staging.Export()
+	require.NoError(t, err)
+
+	// The bundle survives a round trip through JSON, as when downloaded and uploaded
+	data, err := json.Marshal(signed)
+	require.NoError(t, err)
+	var uploaded SignedConfigBundle
+	require.NoError(t, json.Unmarshal(data, &uploaded))
+
+	result, err := production.Import(&uploaded, true)
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, []string{"gemini"}, result.Providers)
+	assert.Equal(t, []string{greetingSettingsKey}, result.Settings)
+	_, err = productionRegistry.Get("gemini")
+	assert.Error(t, err, "dry run must not change anything")
+
+	result, err = production.Import(&uploaded, false)
+	require.NoError(t, err)
+	assert.False(t, result.DryRun)
+	assert.Empty(t, result.Warnings)
+
+	provider, err := productionRegistry.Get("gemini")
+	require.NoError(t, err)
+	assert.Equal(t, "gemini", provider.GetID())
+
+	configs, err := providers.LoadProvidersFile(production.cfg.ProvidersFile)
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, []string{"--yolo"}, configs[0].Args)
+
+	greeting, err = productionGreeting.Get()
+	require.NoError(t, err)
+	assert.True(t, greeting.Enabled)
+	assert.Equal(t, "Welcome", greeting.Welcome["en"])
+}
+
+func TestConfigBundleService_RejectsInvalidBundles(t *testing.T) {
+	require.NoError(t, utils.InitPathManager())
+
+	source, _, _ := newBundleTestHub(t, "source-secret-value")
+	signed, err := source.Export()
+	require.NoError(t, err)
+
+	// Signed with another secret
+	other, _, _ := newBundleTestHub(t, "another-secret-value")
+	_, err = other.Import(signed, false)
+	assert.ErrorIs(t, err, ErrInvalidBundleSignature)
+
+	// Modified after signing
+	tampered := &SignedConfigBundle{Bundle: json.RawMessage(`{"version":1,"providers":[]}`), Signature: signed.Signature}
+	_, err = source.Import(tampered, false)
+	assert.ErrorIs(t, err, ErrInvalidBundleSignature)
+
+	// Correctly signed but invalid content
+	bad := json.RawMessage(`{"version":1,"providers":[{"id":"Bad ID","command":"x"}]}`)
+	_, err = source.Import(&SignedConfigBundle{Bundle: bad, Signature: signConfigBundle("source-secret-value", bad)}, false)
+	assert.ErrorIs(t, err, ErrInvalidConfigBundle)
+
+	future := json.RawMessage(`{"version":99}`)
+	_, err = source.Import(&SignedConfigBundle{Bundle: future, Signature: signConfigBundle("source-secret-value", future)}, false)
+	assert.ErrorIs(t, err, ErrInvalidConfigBundle)
+
+	// Disabled without a secret
+	disabled, _, _ := newBundleTestHub(t, "")
+	_, err = disabled.Export()
+	assert.ErrorIs(t, err, ErrConfigBundlesDisabled)
+}
+
+func TestConfigBundleService_WarnsAboutFlags(t *testing.T) {
+	require.NoError(t, utils.InitPathManager())
+	const secret = "flag-comparison-secret"
+
+	source, _, _ := newBundleTestHub(t, secret)
+	source.cfg.EnableHealthChecks = false
+	signed, err := source.Export()
+	require.NoError(t, err)
+
+	target, _, _ := newBundleTestHub(t, secret)
+	result, err := target.Import(signed, true)
+	require.NoError(t, err)
+	require.Len(t, result.Warnings, 1)
+	assert.Contains(t, result.Warnings[0], "ENABLE_HEALTH_CHECKS")
+}
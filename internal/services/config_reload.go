@@ -0,0 +1,60 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"ai-gateway-hub/internal/config"
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/utils"
+)
+
+// ConfigReloadService re-reads the configuration and applies the settings listed in
+// config.ReloadableKeys without a restart, keeping connections open
+type ConfigReloadService struct {
+	mu       sync.Mutex
+	cfg      *config.Config
+	load     func() (*config.Config, error)
+	quota    *QuotaService
+	registry *ProviderRegistry
+}
+
+// NewConfigReloadService creates a reload service updating cfg with what load returns
+func NewConfigReloadService(cfg *config.Config, load func() (*config.Config, error), quota *QuotaService, registry *ProviderRegistry) *ConfigReloadService {
+	return &ConfigReloadService{cfg: cfg, load: load, quota: quota, registry: registry}
+}
+
+// Reload loads and validates the configuration, then applies the reloadable settings that
+// changed. An invalid configuration is rejected as a whole.
+func (s *ConfigReloadService) Reload() ([]config.Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := s.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if result := config.ValidateEnvironment(next); !result.Valid {
+		return nil, apperrors.Validation("configuration is invalid: " + strings.Join(result.Errors, "; "))
+	}
+
+	changes := s.cfg.ApplyReloadable(next)
+	if len(changes) == 0 {
+		utils.Info("Configuration reloaded, nothing changed")
+		return changes, nil
+	}
+
+	utils.SetLogLevel(next.LogLevel)
+	if s.quota != nil {
+		s.quota.SetLimits(int64(next.DailyPromptQuota), int64(next.MonthlyPromptQuota))
+	}
+	if s.registry != nil {
+		s.registry.SetClaudeExtraArgs(next.ClaudeExtraArgs)
+		s.registry.SetFailoverChains(next.FailoverChains())
+	}
+	for _, change := range changes {
+		utils.Info("Configuration reloaded: %s", change)
+	}
+	return changes, nil
+}
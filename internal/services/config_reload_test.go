@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"ai-gateway-hub/internal/config"
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/providers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigReloadService_Reload(t *testing.T) {
+	cfg := config.Load()
+	next := config.Load()
+	load := func() (*config.Config, error) {
+		copied := *next
+		return &copied, nil
+	}
+
+	quota := NewQuotaService(nil, 0, 0)
+	registry := NewProviderRegistry(nil)
+	claude := providers.NewClaudeProvider("claude", t.TempDir(), false, "")
+	require.NoError(t, registry.Register(claude))
+	s := NewConfigReloadService(cfg, load, quota, registry)
+
+	changes, err := s.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	next.DailyPromptQuota = 5
+	next.ClaudeExtraArgs = "--verbose"
+	changes, err = s.Reload()
+	require.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, 5, cfg.DailyPromptQuota)
+	status, err := quota.Status("")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), status.Daily.Limit)
+	assert.Equal(t, []string{"--verbose"}, claude.GetConfig().Args)
+
+	// An invalid configuration is rejected without applying anything
+	next.DailyPromptQuota = 7
+	next.Port = ""
+	_, err = s.Reload()
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
+	assert.Equal(t, 5, cfg.DailyPromptQuota)
+
+	_, err = NewConfigReloadService(cfg, func() (*config.Config, error) { return nil, errors.New("unreadable") }, quota, registry).Reload()
+	assert.ErrorContains(t, err, "unreadable")
+}
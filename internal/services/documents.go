@@ -0,0 +1,536 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/documents"
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/processing"
+)
+
+var (
+	ErrCollectionNotFound = apperrors.NotFound("collection not found")
+	ErrCollectionExists   = apperrors.Conflict("a collection with this name already exists")
+	ErrDocumentNotFound   = apperrors.NotFound("document not found")
+	ErrInvalidDocument    = apperrors.Validation("invalid document")
+	// ErrEmbedderMismatch is returned for collections indexed with another embedder, whose vectors
+	// can't be compared with the current embedder's
+	ErrEmbedderMismatch  = apperrors.Conflict("collection was indexed with another embedder")
+	ErrInvalidCollection = apperrors.Validation("invalid collection")
+	ErrDocumentTooLarge  = errors.New("document is too large")
+)
+
+// Longest collection name
+const maxCollectionNameLength = 100
+
+// Chunks embedded per embedder call
+const embedBatchSize = 32
+
+// DocumentOptions sets how documents are chunked and retrieved
+type DocumentOptions struct {
+	MaxSize      int64 // largest document in bytes
+	ChunkSize    int   // in characters
+	ChunkOverlap int   // characters shared by consecutive chunks
+	TopK         int   // chunks added to prompts
+}
+
+// DocumentService indexes documents into collections and retrieves the chunks closest to prompts
+// of the chats linked to a collection. It is the "documents" pre processor of the processing
+// pipeline.
+type DocumentService struct {
+	db       database.Store
+	chats    *ChatService
+	embedder documents.Embedder
+	vectors  documents.VectorStore
+	opts     DocumentOptions
+}
+
+func NewDocumentService(db database.Store, chatService *ChatService, embedder documents.Embedder, vectors documents.VectorStore, opts DocumentOptions) *DocumentService {
+	return &DocumentService{db: db, chats: chatService, embedder: embedder, vectors: vectors, opts: opts}
+}
+
+// MaxSize returns the largest accepted document in bytes
+func (s *DocumentService) MaxSize() int64 {
+	return s.opts.MaxSize
+}
+
+// Columns selected for a collection, in the order scanCollection expects
+const collectionColumns = "c.id, c.name, c.description, c.embedder, c.created_at, (SELECT COUNT(*) FROM documents d WHERE d.collection_id = c.id)"
+
+func scanCollection(row rowScanner) (*models.DocumentCollection, error) {
+	var c models.DocumentCollection
+	if err := row.Scan(&c.ID, &c.Name, &c.Description, &c.Embedder, &c.CreatedAt, &c.Documents); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateCollection creates an empty collection with a unique name
+func (s *DocumentService) CreateCollection(ctx context.Context, name, description string) (*models.DocumentCollection, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxCollectionNameLength {
+		return nil, fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidCollection, maxCollectionNameLength)
+	}
+
+	var id int64
+	query := `INSERT INTO document_collections (name, description) VALUES (?, ?) ON CONFLICT (name) DO NOTHING RETURNING id`
+	err := s.db.QueryRowContext(ctx, query, name, strings.TrimSpace(description)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCollectionExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	return s.GetCollection(ctx, id)
+}
+
+// GetCollection returns a collection by ID
+func (s *DocumentService) GetCollection(ctx context.Context, id int64) (*models.DocumentCollection, error) {
+	query := `SELECT ` + collectionColumns + ` FROM document_collections c WHERE c.id = ?`
+	collection, err := scanCollection(s.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCollectionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	return collection, nil
+}
+
+// ListCollections returns the collections by name
+func (s *DocumentService) ListCollections(ctx context.Context) ([]*models.DocumentCollection, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+collectionColumns+` FROM document_collections c ORDER BY c.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	defer rows.Close()
+
+	collections := make([]*models.DocumentCollection, 0)
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan collection: %w", err)
+		}
+		collections = append(collections, collection)
+	}
+	return collections, rows.Err()
+}
+
+// DeleteCollection deletes a collection with its documents, and unlinks it from chats
+func (s *DocumentService) DeleteCollection(ctx context.Context, id int64) error {
+	if _, err := s.GetCollection(ctx, id); err != nil {
+		return err
+	}
+	if err := s.vectors.DeleteCollection(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete collection vectors: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Dependent rows are removed explicitly so deleting doesn't rely on foreign key enforcement
+	for _, table := range []string{"chat_collections", "document_chunks", "documents"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE collection_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM document_collections WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Columns selected for a document, in the order scanDocument expects
+const documentColumns = "id, collection_id, filename, content_type, size, chunks, created_at"
+
+func scanDocument(row rowScanner) (*models.Document, error) {
+	var d models.Document
+	if err := row.Scan(&d.ID, &d.CollectionID, &d.Filename, &d.ContentType, &d.Size, &d.Chunks, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// AddDocument extracts the text of a file, splits it into chunks and indexes their embeddings
+// into a collection
+func (s *DocumentService) AddDocument(ctx context.Context, collectionID int64, filename string, data []byte) (*models.Document, error) {
+	collection, err := s.GetCollection(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.opts.MaxSize {
+		return nil, ErrDocumentTooLarge
+	}
+	if collection.Embedder != "" && collection.Embedder != s.embedder.Name() {
+		return nil, fmt.Errorf("%w: %s, now %s", ErrEmbedderMismatch, collection.Embedder, s.embedder.Name())
+	}
+
+	filename = sanitizeAttachmentFilename(filename)
+	contentType, err := documents.DetectType(filename, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	text, err := documents.Extract(contentType, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	chunks := documents.Chunk(text, s.opts.ChunkSize, s.opts.ChunkOverlap)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("%w: document has no text", ErrInvalidDocument)
+	}
+	embeddings, err := s.embed(ctx, chunks)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, vectors, err := s.insertDocument(ctx, collectionID, filename, contentType, int64(len(data)), chunks, embeddings)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.vectors.Add(ctx, vectors); err != nil {
+		// Chunks without vectors could never be retrieved
+		s.deleteDocumentRows(ctx, doc.ID)
+		return nil, fmt.Errorf("failed to store vectors: %w", err)
+	}
+	return doc, nil
+}
+
+// embed embeds chunks in batches
+func (s *DocumentService) embed(ctx context.Context, chunks []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(chunks))
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(chunks))
+		batch, err := s.embedder.Embed(ctx, chunks[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed document: %w", err)
+		}
+		embeddings = append(embeddings, batch...)
+	}
+	return embeddings, nil
+}
+
+// insertDocument saves a document with its chunks and returns the vectors to store for them
+func (s *DocumentService) insertDocument(ctx context.Context, collectionID int64, filename, contentType string, size int64, chunks []string, embeddings [][]float32) (*models.Document, []documents.Vector, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO documents (collection_id, filename, content_type, size, chunks)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING ` + documentColumns
+	doc, err := scanDocument(tx.QueryRowContext(ctx, query, collectionID, filename, contentType, size, len(chunks)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to save document: %w", err)
+	}
+
+	vectors := make([]documents.Vector, len(chunks))
+	for i, chunk := range chunks {
+		var chunkID int64
+		query := `INSERT INTO document_chunks (document_id, collection_id, seq, content) VALUES (?, ?, ?, ?) RETURNING id`
+		if err := tx.QueryRowContext(ctx, query, doc.ID, collectionID, i, chunk).Scan(&chunkID); err != nil {
+			return nil, nil, fmt.Errorf("failed to save document chunk: %w", err)
+		}
+		vectors[i] = documents.Vector{ChunkID: chunkID, CollectionID: collectionID, Embedding: embeddings[i]}
+	}
+
+	// The first document decides which embedder the collection is searched with
+	query = `UPDATE document_collections SET embedder = ? WHERE id = ? AND embedder = ''`
+	if _, err := tx.ExecContext(ctx, query, s.embedder.Name(), collectionID); err != nil {
+		return nil, nil, fmt.Errorf("failed to record collection embedder: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to save document: %w", err)
+	}
+	return doc, vectors, nil
+}
+
+// ListDocuments returns the documents of a collection, newest first
+func (s *DocumentService) ListDocuments(ctx context.Context, collectionID int64) ([]*models.Document, error) {
+	if _, err := s.GetCollection(ctx, collectionID); err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + documentColumns + ` FROM documents WHERE collection_id = ? ORDER BY created_at DESC, id DESC`
+	rows, err := s.db.QueryContext(ctx, query, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	docs := make([]*models.Document, 0)
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// DeleteDocument removes a document and its chunks from a collection
+func (s *DocumentService) DeleteDocument(ctx context.Context, collectionID, documentID int64) error {
+	var exists int
+	query := `SELECT 1 FROM documents WHERE id = ? AND collection_id = ?`
+	if err := s.db.QueryRowContext(ctx, query, documentID, collectionID).Scan(&exists); errors.Is(err, sql.ErrNoRows) {
+		return ErrDocumentNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to get document: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM document_chunks WHERE document_id = ?`, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to get document chunks: %w", err)
+	}
+	var chunkIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan document chunk: %w", err)
+		}
+		chunkIDs = append(chunkIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get document chunks: %w", err)
+	}
+
+	if err := s.vectors.DeleteChunks(ctx, chunkIDs); err != nil {
+		return fmt.Errorf("failed to delete document vectors: %w", err)
+	}
+	return s.deleteDocumentRows(ctx, documentID)
+}
+
+// deleteDocumentRows deletes a document and its chunks
+func (s *DocumentService) deleteDocumentRows(ctx context.Context, documentID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM document_chunks WHERE document_id = ?`, documentID); err != nil {
+		return fmt.Errorf("failed to delete document chunks: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM documents WHERE id = ?`, documentID); err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Search returns the k chunks of a collection closest to a query, best first
+func (s *DocumentService) Search(ctx context.Context, collectionID int64, query string, k int) ([]*models.DocumentChunk, error) {
+	collection, err := s.GetCollection(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if collection.Embedder == "" {
+		return []*models.DocumentChunk{}, nil
+	}
+	if collection.Embedder != s.embedder.Name() {
+		return nil, fmt.Errorf("%w: %s, now %s", ErrEmbedderMismatch, collection.Embedder, s.embedder.Name())
+	}
+
+	embeddings, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	matches, err := s.vectors.Search(ctx, collectionID, embeddings[0], k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search vectors: %w", err)
+	}
+
+	chunks := make([]*models.DocumentChunk, 0, len(matches))
+	for _, match := range matches {
+		chunk := &models.DocumentChunk{ID: match.ChunkID, Score: match.Score}
+		query := `
+			SELECT ch.document_id, d.filename, ch.seq, ch.content
+			FROM document_chunks ch JOIN documents d ON d.id = ch.document_id
+			WHERE ch.id = ? AND ch.collection_id = ?
+		`
+		err := s.db.QueryRowContext(ctx, query, match.ChunkID, collectionID).Scan(&chunk.DocumentID, &chunk.Filename, &chunk.Seq, &chunk.Content)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Vectors of an external store may outlive their chunk
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document chunk: %w", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// LinkChat makes a chat retrieve context from a collection
+func (s *DocumentService) LinkChat(ctx context.Context, chatID, collectionID int64) error {
+	if _, err := s.chats.GetChat(ctx, chatID); err != nil {
+		return err
+	}
+	if _, err := s.GetCollection(ctx, collectionID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO chat_collections (chat_id, collection_id) VALUES (?, ?)
+		ON CONFLICT (chat_id) DO UPDATE SET collection_id = excluded.collection_id
+	`
+	if _, err := s.db.ExecContext(ctx, query, chatID, collectionID); err != nil {
+		return fmt.Errorf("failed to link chat to collection: %w", err)
+	}
+	return nil
+}
+
+// UnlinkChat stops a chat from retrieving context
+func (s *DocumentService) UnlinkChat(ctx context.Context, chatID int64) error {
+	if _, err := s.chats.GetChat(ctx, chatID); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM chat_collections WHERE chat_id = ?`, chatID); err != nil {
+		return fmt.Errorf("failed to unlink chat from collection: %w", err)
+	}
+	return nil
+}
+
+// ChatCollection returns the collection a chat retrieves context from, or nil
+func (s *DocumentService) ChatCollection(ctx context.Context, chatID int64) (*models.DocumentCollection, error) {
+	if _, err := s.chats.GetChat(ctx, chatID); err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + collectionColumns + ` FROM document_collections c JOIN chat_collections cc ON cc.collection_id = c.id WHERE cc.chat_id = ?`
+	collection, err := scanCollection(s.db.QueryRowContext(ctx, query, chatID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat collection: %w", err)
+	}
+	return collection, nil
+}
+
+// Process adds the chunks closest to a prompt to it when its chat is linked to a collection
+func (s *DocumentService) Process(ctx context.Context, req processing.Request) (string, error) {
+	var collectionID int64
+	var name string
+	query := `SELECT c.id, c.name FROM document_collections c JOIN chat_collections cc ON cc.collection_id = c.id WHERE cc.chat_id = ?`
+	err := s.db.QueryRowContext(ctx, query, req.ChatID).Scan(&collectionID, &name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return req.Content, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get chat collection: %w", err)
+	}
+
+	chunks, err := s.Search(ctx, collectionID, req.Content, s.opts.TopK)
+	if err != nil {
+		return "", err
+	}
+	return formatContext(name, chunks, req.Content), nil
+}
+
+// formatContext adds retrieved chunks to a prompt, leaving out those unrelated to it
+func formatContext(collection string, chunks []*models.DocumentChunk, prompt string) string {
+	relevant := make([]*models.DocumentChunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.Score > 0 {
+			relevant = append(relevant, chunk)
+		}
+	}
+	if len(relevant) == 0 {
+		return prompt
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Use the following excerpts from the %q documents to answer if they are relevant.\n\n", collection)
+	for i, chunk := range relevant {
+		fmt.Fprintf(&b, "[%d] %s (part %d)\n%s\n\n", i+1, chunk.Filename, chunk.Seq+1, chunk.Content)
+	}
+	b.WriteString("Question:\n")
+	b.WriteString(prompt)
+	return b.String()
+}
+
+// DatabaseVectorStore keeps embeddings in the document_vectors table and searches them exhaustively
+// in memory, which suits collections of up to some tens of thousands of chunks
+type DatabaseVectorStore struct {
+	db database.Store
+}
+
+func NewDatabaseVectorStore(db database.Store) *DatabaseVectorStore {
+	return &DatabaseVectorStore{db: db}
+}
+
+func (s *DatabaseVectorStore) Add(ctx context.Context, vectors []documents.Vector) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, v := range vectors {
+		query := `INSERT INTO document_vectors (chunk_id, collection_id, embedding) VALUES (?, ?, ?)`
+		if _, err := tx.ExecContext(ctx, query, v.ChunkID, v.CollectionID, documents.EncodeVector(v.Embedding)); err != nil {
+			return fmt.Errorf("failed to save vector: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *DatabaseVectorStore) Search(ctx context.Context, collectionID int64, query []float32, k int) ([]documents.Match, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT chunk_id, embedding FROM document_vectors WHERE collection_id = ?`, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vectors: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []documents.Match
+	for rows.Next() {
+		var chunkID int64
+		var encoded []byte
+		if err := rows.Scan(&chunkID, &encoded); err != nil {
+			return nil, fmt.Errorf("failed to scan vector: %w", err)
+		}
+		embedding, err := documents.DecodeVector(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d: %w", chunkID, err)
+		}
+		matches = append(matches, documents.Match{ChunkID: chunkID, Score: documents.Dot(query, embedding)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get vectors: %w", err)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+func (s *DatabaseVectorStore) DeleteChunks(ctx context.Context, chunkIDs []int64) error {
+	for _, id := range chunkIDs {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM document_vectors WHERE chunk_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete vector: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *DatabaseVectorStore) DeleteCollection(ctx context.Context, collectionID int64) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM document_vectors WHERE collection_id = ?`, collectionID); err != nil {
+		return fmt.Errorf("failed to delete vectors: %w", err)
+	}
+	return nil
+}
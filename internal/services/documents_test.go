@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/documents"
+	"ai-gateway-hub/internal/processing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const handbook = `Deployments run every weekday at ten in the morning. A deployment needs two approvals.
+
+Vacation requests go through the people team portal at least two weeks in advance.
+
+The coffee machine on the third floor is descaled every Friday afternoon.`
+
+func TestDocumentService(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	chatService := NewChatService(db)
+	chat, err := chatService.CreateChat(ctx, "Questions", "claude")
+	require.NoError(t, err)
+	opts := DocumentOptions{MaxSize: 1 << 20, ChunkSize: 100, ChunkOverlap: 0, TopK: 1}
+	vectors := NewDatabaseVectorStore(db)
+	documentService := NewDocumentService(db, chatService, documents.NewHashEmbedder(256), vectors, opts)
+
+	collection, err := documentService.CreateCollection(ctx, " Handbook ", "Company handbook")
+	require.NoError(t, err)
+	assert.Equal(t, "Handbook", collection.Name)
+	_, err = documentService.CreateCollection(ctx, "Handbook", "")
+	assert.ErrorIs(t, err, ErrCollectionExists)
+	_, err = documentService.CreateCollection(ctx, " ", "")
+	assert.ErrorIs(t, err, ErrInvalidCollection)
+
+	doc, err := documentService.AddDocument(ctx, collection.ID, "handbook.md", []byte(handbook))
+	require.NoError(t, err)
+	assert.Equal(t, documents.TypeMarkdown, doc.ContentType)
+	assert.Equal(t, 3, doc.Chunks)
+	_, err = documentService.AddDocument(ctx, collection.ID, "big.txt", []byte(strings.Repeat("a", 2<<20)))
+	assert.ErrorIs(t, err, ErrDocumentTooLarge)
+	_, err = documentService.AddDocument(ctx, collection.ID, "blank.txt", []byte("  "))
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+
+	collection, err = documentService.GetCollection(ctx, collection.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "hash:256", collection.Embedder)
+	assert.Equal(t, int64(1), collection.Documents)
+
+	chunks, err := documentService.Search(ctx, collection.ID, "how many approvals does a deployment need", 1)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Contains(t, chunks[0].Content, "two approvals")
+	assert.Equal(t, "handbook.md", chunks[0].Filename)
+
+	// Prompts of linked chats get the closest chunks as context
+	req := processing.Request{Stage: processing.StagePre, ChatID: chat.ID, Content: "When is the coffee machine descaled?"}
+	prompt, err := documentService.Process(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, req.Content, prompt)
+
+	require.NoError(t, documentService.LinkChat(ctx, chat.ID, collection.ID))
+	linked, err := documentService.ChatCollection(ctx, chat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, collection.ID, linked.ID)
+	prompt, err = documentService.Process(ctx, req)
+	require.NoError(t, err)
+	assert.Contains(t, prompt, `"Handbook" documents`)
+	assert.Contains(t, prompt, "[1] handbook.md (part 3)\nThe coffee machine")
+	assert.True(t, strings.HasSuffix(prompt, "Question:\n"+req.Content))
+	assert.NotContains(t, prompt, "Vacation")
+
+	// Another embedder can't search or extend the collection
+	other := NewDocumentService(db, chatService, documents.NewHashEmbedder(128), vectors, opts)
+	_, err = other.Search(ctx, collection.ID, "deployments", 1)
+	assert.ErrorIs(t, err, ErrEmbedderMismatch)
+	_, err = other.AddDocument(ctx, collection.ID, "more.txt", []byte("More"))
+	assert.ErrorIs(t, err, ErrEmbedderMismatch)
+
+	assert.ErrorIs(t, documentService.DeleteDocument(ctx, collection.ID, doc.ID+1), ErrDocumentNotFound)
+	require.NoError(t, documentService.DeleteDocument(ctx, collection.ID, doc.ID))
+	chunks, err = documentService.Search(ctx, collection.ID, "deployments", 1)
+	require.NoError(t, err)
+	assert.Empty(t, chunks)
+
+	// Deleting a collection unlinks its chats
+	require.NoError(t, documentService.DeleteCollection(ctx, collection.ID))
+	linked, err = documentService.ChatCollection(ctx, chat.ID)
+	require.NoError(t, err)
+	assert.Nil(t, linked)
+	assert.ErrorIs(t, documentService.LinkChat(ctx, chat.ID, collection.ID), ErrCollectionNotFound)
+	assert.ErrorIs(t, documentService.LinkChat(ctx, 999, collection.ID), ErrChatNotFound)
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DraftStore keeps the prompt drafts of chats until they are cleared or expire
+type DraftStore interface {
+	// Get returns a chat's draft; ok is false when it has none or it expired
+	Get(ctx context.Context, chatID int64) (data []byte, ok bool, err error)
+	Set(ctx context.Context, chatID int64, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, chatID int64) error
+}
+
+// RedisDraftStore keeps drafts in Redis, so a draft saved through one instance is found by any
+type RedisDraftStore struct {
+	redis *redis.Client
+}
+
+func NewRedisDraftStore(redisClient *redis.Client) *RedisDraftStore {
+	return &RedisDraftStore{redis: redisClient}
+}
+
+func (s *RedisDraftStore) Get(ctx context.Context, chatID int64) ([]byte, bool, error) {
+	data, err := s.redis.Get(ctx, s.key(chatID)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get draft: %w", err)
+	}
+	return data, true, nil
+}
+
+func (s *RedisDraftStore) Set(ctx context.Context, chatID int64, data []byte, ttl time.Duration) error {
+	if err := s.redis.Set(ctx, s.key(chatID), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save draft: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisDraftStore) Delete(ctx context.Context, chatID int64) error {
+	if err := s.redis.Del(ctx, s.key(chatID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear draft: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisDraftStore) key(chatID int64) string {
+	return fmt.Sprintf("draft:%d", chatID)
+}
+
+// MemoryDraftStore keeps drafts in this process when Redis is unavailable
+type MemoryDraftStore struct {
+	mu     sync.Mutex
+	drafts map[int64]memoryDraft
+	now    func() time.Time
+}
+
+// memoryDraft is a stored draft and when it expires
+type memoryDraft struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+func NewMemoryDraftStore() *MemoryDraftStore {
+	return &MemoryDraftStore{
+		drafts: make(map[int64]memoryDraft),
+		now:    time.Now,
+	}
+}
+
+func (s *MemoryDraftStore) Get(ctx context.Context, chatID int64) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.drafts[chatID]
+	if !ok || !s.now().Before(d.expiresAt) {
+		return nil, false, nil
+	}
+	return d.data, true, nil
+}
+
+func (s *MemoryDraftStore) Set(ctx context.Context, chatID int64, data []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop drafts that expired without being sent
+	now := s.now()
+	for id, d := range s.drafts {
+		if !now.Before(d.expiresAt) {
+			delete(s.drafts, id)
+		}
+	}
+	s.drafts[chatID] = memoryDraft{data: data, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryDraftStore) Delete(ctx context.Context, chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.drafts, chatID)
+	return nil
+}
+
+// DraftService saves the prompt being written in a chat, so it survives a page reload or is picked
+// up on another device. Drafts expire ttl after their last save and are cleared once the prompt is sent.
+type DraftService struct {
+	store       DraftStore
+	chatService *ChatService
+	ttl         time.Duration
+	now         func() time.Time
+}
+
+func NewDraftService(store DraftStore, chatService *ChatService, ttl time.Duration) *DraftService {
+	return &DraftService{store: store, chatService: chatService, ttl: ttl, now: time.Now}
+}
+
+// storedDraft is a draft as kept in the DraftStore
+type storedDraft struct {
+	Content   string    `json:"content"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Get returns a chat's draft, with empty content when it has none
+func (s *DraftService) Get(ctx context.Context, chatID int64) (*models.Draft, error) {
+	if _, err := s.chatService.GetChat(ctx, chatID); err != nil {
+		return nil, err
+	}
+	draft := &models.Draft{ChatID: chatID}
+	data, ok, err := s.store.Get(ctx, chatID)
+	if err != nil || !ok {
+		return draft, err
+	}
+	var stored storedDraft
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode draft: %w", err)
+	}
+	expiresAt := stored.UpdatedAt.Add(s.ttl)
+	draft.Content = stored.Content
+	draft.UpdatedAt = &stored.UpdatedAt
+	draft.ExpiresAt = &expiresAt
+	return draft, nil
+}
+
+// Save replaces a chat's draft; blank content clears it. Drafts are held to the prompt length limit.
+func (s *DraftService) Save(ctx context.Context, chatID int64, content string) (*models.Draft, error) {
+	if _, err := s.chatService.GetChat(ctx, chatID); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(content) == "" {
+		if err := s.store.Delete(ctx, chatID); err != nil {
+			return nil, err
+		}
+		return &models.Draft{ChatID: chatID}, nil
+	}
+	if !utf8.ValidString(content) {
+		return nil, apperrors.Validation("draft is not valid UTF-8")
+	}
+	if limit := s.chatService.MessageLimits().MaxPromptLength; limit > 0 {
+		if length := utf8.RuneCountInString(content); length > limit {
+			return nil, apperrors.Validation(fmt.Sprintf("draft is %d characters long, the limit is %d", length, limit))
+		}
+	}
+
+	now := s.now()
+	data, err := json.Marshal(storedDraft{Content: content, UpdatedAt: now})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode draft: %w", err)
+	}
+	if err := s.store.Set(ctx, chatID, data, s.ttl); err != nil {
+		return nil, err
+	}
+	expiresAt := now.Add(s.ttl)
+	return &models.Draft{ChatID: chatID, Content: content, UpdatedAt: &now, ExpiresAt: &expiresAt}, nil
+}
+
+// Clear drops a chat's draft, e.g. once its prompt was sent
+func (s *DraftService) Clear(ctx context.Context, chatID int64) error {
+	return s.store.Delete(ctx, chatID)
+}
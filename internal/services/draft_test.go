@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDraftService(t *testing.T) {
+	chatService, cleanup := setupTestChatService(t)
+	defer cleanup()
+	chatService.SetMessageLimits(MessageLimits{MaxPromptLength: 20})
+	ctx := context.Background()
+
+	chat, err := chatService.CreateChat(ctx, "Drafts", "claude")
+	require.NoError(t, err)
+	service := NewDraftService(NewMemoryDraftStore(), chatService, time.Hour)
+
+	draft, err := service.Get(ctx, chat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, chat.ID, draft.ChatID)
+	assert.Empty(t, draft.Content)
+	assert.Nil(t, draft.UpdatedAt)
+
+	saved, err := service.Save(ctx, chat.ID, "Half a thought")
+	require.NoError(t, err)
+	require.NotNil(t, saved.ExpiresAt)
+	assert.Equal(t, time.Hour, saved.ExpiresAt.Sub(*saved.UpdatedAt))
+
+	draft, err = service.Get(ctx, chat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Half a thought", draft.Content)
+	require.NotNil(t, draft.UpdatedAt)
+	assert.True(t, saved.UpdatedAt.Equal(*draft.UpdatedAt))
+
+	// Drafts are held to the prompt length limit
+	_, err = service.Save(ctx, chat.ID, strings.Repeat("x", 21))
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
+
+	// Blank content clears the draft, as does sending the prompt
+	_, err = service.Save(ctx, chat.ID, "  ")
+	require.NoError(t, err)
+	draft, err = service.Get(ctx, chat.ID)
+	require.NoError(t, err)
+	assert.Empty(t, draft.Content)
+
+	_, err = service.Save(ctx, chat.ID, "Another")
+	require.NoError(t, err)
+	require.NoError(t, service.Clear(ctx, chat.ID))
+	draft, err = service.Get(ctx, chat.ID)
+	require.NoError(t, err)
+	assert.Empty(t, draft.Content)
+
+	_, err = service.Get(ctx, 999)
+	assert.ErrorIs(t, err, ErrChatNotFound)
+	_, err = service.Save(ctx, 999, "Nowhere")
+	assert.ErrorIs(t, err, ErrChatNotFound)
+}
+
+func TestMemoryDraftStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDraftStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Set(ctx, 1, []byte("a"), time.Minute))
+	require.NoError(t, store.Set(ctx, 2, []byte("b"), time.Hour))
+
+	now = now.Add(2 * time.Minute)
+	_, ok, err := store.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, ok, "expired drafts are gone")
+	data, ok, err := store.Get(ctx, 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "b", string(data))
+
+	// Saving drops drafts that expired
+	require.NoError(t, store.Set(ctx, 3, []byte("c"), time.Hour))
+	assert.Len(t, store.drafts, 2)
+}
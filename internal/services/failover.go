@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+)
+
+// Failover is a prompt's way through the failover chain of the provider it was sent to
+type Failover struct {
+	Model     string   // model asked of the primary, which fallbacks only use if they support it
+	Fallbacks []string // providers not tried yet, in order
+	Failed    []string // providers that couldn't answer, in order
+	LastErr   error    // why the last of them couldn't
+}
+
+// NewFailover starts the failover chain of a prompt to a provider
+func (r *ProviderRegistry) NewFailover(providerID, model string) *Failover {
+	return &Failover{Model: model, Fallbacks: r.Fallbacks(providerID)}
+}
+
+// NextFallback records that failedID couldn't answer and acquires the next fallback of the chain
+// that can take the prompt, skipping those without vision for prompts with images. It returns nil
+// once the chain is exhausted; otherwise release must be called when the generation is done.
+func (r *ProviderRegistry) NextFallback(chain *Failover, failedID string, err error, images bool) (providers.AIProvider, func()) {
+	chain.Failed = append(chain.Failed, failedID)
+	chain.LastErr = err
+	for len(chain.Fallbacks) > 0 {
+		id := chain.Fallbacks[0]
+		chain.Fallbacks = chain.Fallbacks[1:]
+
+		provider, release, err := r.Acquire(id)
+		if err == nil && !provider.IsAvailable() {
+			release()
+			err = fmt.Errorf("%w: %s", ErrProviderUnavailable, id)
+		}
+		if err == nil && images && !providers.HasCapability(provider, providers.CapabilityVision) {
+			release()
+			err = fmt.Errorf("provider %s does not support images", id)
+		}
+		if err == nil {
+			utils.Info("Provider %s couldn't answer (%v), failing over to %s", failedID, chain.LastErr, id)
+			return provider, release
+		}
+		chain.Failed = append(chain.Failed, id)
+		chain.LastErr = err
+	}
+	return nil, nil
+}
+
+// FailedOver reports whether the prompt went to a fallback
+func (f *Failover) FailedOver() bool {
+	return f != nil && len(f.Failed) > 0
+}
+
+// ModelFor returns the model a provider of the chain answers with: the one asked of the primary if
+// it supports it, otherwise its default
+func (f *Failover) ModelFor(provider providers.AIProvider) string {
+	if f.Model != "" && !providers.SupportsModel(provider, f.Model) {
+		return ""
+	}
+	return f.Model
+}
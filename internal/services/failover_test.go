@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingProvider fails every prompt without writing anything
+type failingProvider struct {
+	stubProvider
+}
+
+func (p *failingProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	return errors.New("exit status 1")
+}
+
+// unavailableProvider can't take prompts
+type unavailableProvider struct {
+	stubProvider
+}
+
+func (p *unavailableProvider) IsAvailable() bool { return false }
+
+// visionProvider advertises vision and a model
+type visionProvider struct {
+	stubProvider
+}
+
+func (p *visionProvider) GetModels() []providers.Model {
+	return []providers.Model{{ID: "large"}}
+}
+
+func (p *visionProvider) GetCapabilities() []string {
+	return []string{providers.CapabilityVision}
+}
+
+func TestProviderRegistry_NextFallback(t *testing.T) {
+	registry := NewProviderRegistry(nil)
+	require.NoError(t, registry.Register(&unavailableProvider{stubProvider{id: "down"}}))
+	require.NoError(t, registry.Register(&stubProvider{id: "text"}))
+	require.NoError(t, registry.Register(&visionProvider{stubProvider{id: "vision"}}))
+	registry.SetFailoverChains(map[string][]string{"primary": {"missing", "down", "text", "vision"}})
+
+	err := errors.New("primary failed")
+	chain := registry.NewFailover("primary", "large")
+	fallback, release := registry.NextFallback(chain, "primary", err, false)
+	require.NotNil(t, fallback)
+	release()
+	assert.Equal(t, "text", fallback.GetID())
+	assert.Equal(t, []string{"primary", "missing", "down"}, chain.Failed, "fallbacks that can't take prompts are skipped")
+	assert.ErrorIs(t, chain.LastErr, ErrProviderUnavailable)
+	assert.Equal(t, "", chain.ModelFor(fallback), "fallbacks use their default model unless they support the one asked for")
+
+	// Prompts with images skip fallbacks without vision
+	chain = registry.NewFailover("primary", "large")
+	fallback, release = registry.NextFallback(chain, "primary", err, true)
+	require.NotNil(t, fallback)
+	release()
+	assert.Equal(t, "vision", fallback.GetID())
+	assert.Equal(t, "large", chain.ModelFor(fallback))
+
+	fallback, _ = registry.NextFallback(chain, "vision", err, true)
+	assert.Nil(t, fallback, "the chain is exhausted")
+	assert.True(t, chain.FailedOver())
+
+	chain = registry.NewFailover("text", "")
+	assert.Empty(t, chain.Fallbacks, "providers without a chain have no fallbacks")
+	assert.False(t, chain.FailedOver())
+}
+
+func TestCompletionService_Failover(t *testing.T) {
+	chatService, cleanup := setupTestChatService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	registry := NewProviderRegistry(nil)
+	require.NoError(t, registry.Register(&failingProvider{stubProvider{id: "flaky"}}))
+	require.NoError(t, registry.Register(&unavailableProvider{stubProvider{id: "down"}}))
+	require.NoError(t, registry.Register(&stubProvider{id: "stub"}))
+	registry.SetFailoverChains(map[string][]string{"flaky": {"stub"}, "down": {"flaky", "stub"}})
+	service := NewCompletionService(chatService, registry, nil, func(string) (time.Duration, time.Duration) {
+		return time.Minute, time.Minute
+	})
+
+	// A provider failing before any output hands the prompt to its fallback
+	chat, err := chatService.CreateChat(ctx, "Failover", "flaky")
+	require.NoError(t, err)
+	completion, err := service.Complete(ctx, models.CompletionRequest{ChatID: &chat.ID, Prompt: "Echo this"})
+	require.NoError(t, err)
+	assert.Equal(t, "Echo this", completion.Content)
+	assert.Equal(t, "stub", completion.Provider)
+	assert.Equal(t, []string{"flaky"}, completion.Metadata.FailedOver)
+
+	messages, err := chatService.GetMessages(ctx, chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "stub", messages[1].Provider, "the response belongs to the provider that answered")
+	require.NotNil(t, messages[1].Metadata)
+	assert.Equal(t, []string{"flaky"}, messages[1].Metadata.FailedOver)
+
+	// An unavailable provider is skipped before anything is sent
+	completion, err = service.Complete(ctx, models.CompletionRequest{Provider: "down", Prompt: "Hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "stub", completion.Provider)
+	assert.Equal(t, []string{"down", "flaky"}, completion.Metadata.FailedOver)
+
+	// Without fallbacks left the failure is returned
+	registry.SetFailoverChains(nil)
+	_, err = service.Complete(ctx, models.CompletionRequest{Provider: "flaky", Prompt: "Hi"})
+	assert.ErrorContains(t, err, "exit status 1")
+	_, err = service.Complete(ctx, models.CompletionRequest{Provider: "down", Prompt: "Hi"})
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+}
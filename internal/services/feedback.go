@@ -0,0 +1,260 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/encryption"
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+)
+
+var (
+	// ErrRatedMessageNotFound is returned when rating a message that doesn't exist or whose chat was deleted
+	ErrRatedMessageNotFound = apperrors.NotFound("message not found")
+	// ErrFeedbackNotFound is returned when a message has no feedback
+	ErrFeedbackNotFound = apperrors.NotFound("feedback not found")
+	// ErrFeedbackNotAllowed is returned for feedback on anything but a finished assistant message
+	ErrFeedbackNotAllowed = apperrors.Validation("only assistant responses can be rated")
+	// ErrInvalidFeedback is returned for ratings other than 1 and -1 and comments that are too long
+	ErrInvalidFeedback = apperrors.Validation("invalid feedback")
+)
+
+// Maximum length of a feedback comment
+const MaxFeedbackCommentLength = 2000
+
+// Length of the message excerpt included in feedback listings
+const feedbackExcerptLength = 200
+
+// FeedbackService stores ratings of assistant messages and aggregates them per provider and model
+type FeedbackService struct {
+	db     database.Store
+	cipher *encryption.Cipher
+}
+
+func NewFeedbackService(db database.Store) *FeedbackService {
+	return &FeedbackService{db: db}
+}
+
+// SetEncryption sets the cipher used to decrypt the message excerpts of feedback listings
+func (s *FeedbackService) SetEncryption(cipher *encryption.Cipher) {
+	s.cipher = cipher
+}
+
+// FeedbackQuery selects feedback to list
+type FeedbackQuery struct {
+	Since    time.Time
+	Rating   int    // 0 lists all ratings
+	Provider string // empty lists all providers
+	Limit    int
+}
+
+// Rate sets the rating of an assistant message, replacing any earlier rating
+func (s *FeedbackService) Rate(messageID int64, rating int, comment string) (*models.MessageFeedback, error) {
+	if rating != models.FeedbackUp && rating != models.FeedbackDown {
+		return nil, fmt.Errorf("%w: rating must be 1 or -1", ErrInvalidFeedback)
+	}
+	if utf8.RuneCountInString(comment) > MaxFeedbackCommentLength {
+		return nil, fmt.Errorf("%w: comment must be at most %d characters", ErrInvalidFeedback, MaxFeedbackCommentLength)
+	}
+
+	var chatID int64
+	var role, status string
+	query := `
+		SELECT m.chat_id, m.role, m.status
+		FROM messages m
+		JOIN chats c ON c.id = m.chat_id
+		WHERE m.id = ? AND c.deleted_at IS NULL
+	`
+	err := s.db.QueryRow(query, messageID).Scan(&chatID, &role, &status)
+	if err == sql.ErrNoRows {
+		return nil, ErrRatedMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if role != "assistant" || status == models.MessageStreaming {
+		return nil, ErrFeedbackNotAllowed
+	}
+
+	now := time.Now()
+	upsert := `
+		INSERT INTO message_feedback (message_id, chat_id, rating, comment, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (message_id) DO UPDATE SET rating = excluded.rating, comment = excluded.comment, updated_at = excluded.updated_at
+	`
+	if _, err := s.db.Exec(upsert, messageID, chatID, rating, comment, now, now); err != nil {
+		return nil, fmt.Errorf("failed to save feedback: %w", err)
+	}
+
+	return s.Get(messageID)
+}
+
+// Clear removes the rating of a message
+func (s *FeedbackService) Clear(messageID int64) error {
+	result, err := s.db.Exec(`DELETE FROM message_feedback WHERE message_id = ?`, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to clear feedback: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrFeedbackNotFound
+	}
+	return nil
+}
+
+// Columns selected for feedback, in the order scanFeedback expects
+const feedbackColumns = "f.message_id, f.chat_id, f.rating, f.comment, m.provider, m.model, m.content, f.created_at, f.updated_at"
+
+// scanFeedback reads feedback selected with feedbackColumns; excerpt keeps the start of the message content
+func (s *FeedbackService) scanFeedback(row rowScanner, excerpt bool) (*models.MessageFeedback, error) {
+	var f models.MessageFeedback
+	var content string
+	if err := row.Scan(&f.MessageID, &f.ChatID, &f.Rating, &f.Comment, &f.Provider, &f.Model, &content, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if excerpt {
+		content, err := openContent(s.cipher, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt message %d: %w", f.MessageID, err)
+		}
+		f.Excerpt = truncateRunes(content, feedbackExcerptLength)
+	}
+	return &f, nil
+}
+
+// truncateRunes shortens s to at most n characters, marking the cut with an ellipsis
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
+
+// Get returns the feedback given on a message
+func (s *FeedbackService) Get(messageID int64) (*models.MessageFeedback, error) {
+	query := `
+		SELECT ` + feedbackColumns + `
+		FROM message_feedback f
+		JOIN messages m ON m.id = f.message_id
+		WHERE f.message_id = ?
+	`
+
+	f, err := s.scanFeedback(s.db.QueryRow(query, messageID), false)
+	if err == sql.ErrNoRows {
+		return nil, ErrFeedbackNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feedback: %w", err)
+	}
+	return f, nil
+}
+
+// ForChat returns the feedback given in a chat, in message order
+func (s *FeedbackService) ForChat(chatID int64) ([]*models.MessageFeedback, error) {
+	query := `
+		SELECT ` + feedbackColumns + `
+		FROM message_feedback f
+		JOIN messages m ON m.id = f.message_id
+		WHERE f.chat_id = ?
+		ORDER BY f.message_id ASC
+	`
+	return s.query(query, false, chatID)
+}
+
+// List returns recent feedback matching the query, newest first, with an excerpt of each rated message
+func (s *FeedbackService) List(q FeedbackQuery) ([]*models.MessageFeedback, error) {
+	query := `
+		SELECT ` + feedbackColumns + `
+		FROM message_feedback f
+		JOIN messages m ON m.id = f.message_id
+		JOIN chats c ON c.id = f.chat_id
+		WHERE c.deleted_at IS NULL
+			AND f.updated_at >= ?
+			AND (? = 0 OR f.rating = ?)
+			AND (? = '' OR m.provider = ?)
+		ORDER BY f.updated_at DESC, f.message_id DESC
+		LIMIT ?
+	`
+	return s.query(query, true, q.Since, q.Rating, q.Rating, q.Provider, q.Provider, q.Limit)
+}
+
+func (s *FeedbackService) query(query string, excerpt bool, args ...any) ([]*models.MessageFeedback, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feedback: %w", err)
+	}
+	defer rows.Close()
+
+	feedback := []*models.MessageFeedback{}
+	for rows.Next() {
+		f, err := s.scanFeedback(rows, excerpt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feedback: %w", err)
+		}
+		feedback = append(feedback, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get feedback: %w", err)
+	}
+	return feedback, nil
+}
+
+// GetSummary aggregates the ratings of assistant messages created since a point in time per provider and model,
+// with the largest share of thumbs-down first
+func (s *FeedbackService) GetSummary(since time.Time) (*models.FeedbackSummary, error) {
+	createdAt := s.db.Dialect().UnixSeconds("m.created_at")
+	query := `
+		SELECT m.provider, m.model,
+			SUM(CASE WHEN f.rating = 1 THEN 1 ELSE 0 END),
+			SUM(CASE WHEN f.rating = -1 THEN 1 ELSE 0 END),
+			COUNT(*)
+		FROM messages m
+		JOIN chats c ON c.id = m.chat_id
+		LEFT JOIN message_feedback f ON f.message_id = m.id
+		WHERE m.role = 'assistant' AND c.deleted_at IS NULL AND ` + createdAt + ` >= ?
+		GROUP BY m.provider, m.model
+	`
+
+	rows, err := s.db.Query(query, since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feedback summary: %w", err)
+	}
+	defer rows.Close()
+
+	summary := &models.FeedbackSummary{Since: since, Providers: []*models.FeedbackStats{}}
+	for rows.Next() {
+		var stats models.FeedbackStats
+		if err := rows.Scan(&stats.Provider, &stats.Model, &stats.Up, &stats.Down, &stats.Responses); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback summary: %w", err)
+		}
+		stats.Total = stats.Up + stats.Down
+		if stats.Total > 0 {
+			stats.DownRatio = float64(stats.Down) / float64(stats.Total)
+		}
+		if stats.Responses > 0 {
+			stats.RatedRatio = float64(stats.Total) / float64(stats.Responses)
+		}
+		summary.Up += stats.Up
+		summary.Down += stats.Down
+		summary.Providers = append(summary.Providers, &stats)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get feedback summary: %w", err)
+	}
+
+	sort.SliceStable(summary.Providers, func(i, j int) bool {
+		a, b := summary.Providers[i], summary.Providers[j]
+		if a.DownRatio != b.DownRatio {
+			return a.DownRatio > b.DownRatio
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Model < b.Model
+	})
+	return summary, nil
+}
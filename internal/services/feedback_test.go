@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedbackService(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := NewChatService(db)
+	feedbackService := NewFeedbackService(db)
+
+	chat, err := chatService.CreateChat(context.Background(), "Feedback", "claude")
+	require.NoError(t, err)
+	prompt, err := chatService.AddMessage(context.Background(), chat.ID, "user", "Explain channels")
+	require.NoError(t, err)
+	good, err := chatService.AddProviderMessage(context.Background(), chat.ID, "assistant", "Channels connect goroutines.", "claude")
+	require.NoError(t, err)
+	bad, err := chatService.AddProviderMessage(context.Background(), chat.ID, "assistant", strings.Repeat("wrong ", 100), "claude")
+	require.NoError(t, err)
+	require.NoError(t, chatService.SetMessageModel(context.Background(), bad.ID, "opus"))
+	_, err = chatService.AddProviderMessage(context.Background(), chat.ID, "assistant", "Unrated", "gemini")
+	require.NoError(t, err)
+
+	_, err = feedbackService.Rate(good.ID, 2, "")
+	assert.ErrorIs(t, err, ErrInvalidFeedback)
+	_, err = feedbackService.Rate(good.ID, models.FeedbackUp, strings.Repeat("x", MaxFeedbackCommentLength+1))
+	assert.ErrorIs(t, err, ErrInvalidFeedback)
+	_, err = feedbackService.Rate(prompt.ID, models.FeedbackUp, "")
+	assert.ErrorIs(t, err, ErrFeedbackNotAllowed)
+	_, err = feedbackService.Rate(99999, models.FeedbackUp, "")
+	assert.ErrorIs(t, err, ErrRatedMessageNotFound)
+
+	// Rating again replaces the earlier rating
+	_, err = feedbackService.Rate(good.ID, models.FeedbackDown, "")
+	require.NoError(t, err)
+	feedback, err := feedbackService.Rate(good.ID, models.FeedbackUp, "clear")
+	require.NoError(t, err)
+	assert.Equal(t, models.FeedbackUp, feedback.Rating)
+	assert.Equal(t, "clear", feedback.Comment)
+	assert.Equal(t, "claude", feedback.Provider)
+
+	feedback, err = feedbackService.Rate(bad.ID, models.FeedbackDown, "made things up")
+	require.NoError(t, err)
+	assert.Equal(t, "opus", feedback.Model)
+
+	forChat, err := feedbackService.ForChat(chat.ID)
+	require.NoError(t, err)
+	require.Len(t, forChat, 2)
+	assert.Equal(t, good.ID, forChat[0].MessageID)
+
+	down, err := feedbackService.List(FeedbackQuery{Since: time.Now().Add(-time.Hour), Rating: models.FeedbackDown, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, down, 1)
+	assert.Equal(t, bad.ID, down[0].MessageID)
+	assert.Len(t, []rune(down[0].Excerpt), feedbackExcerptLength+1)
+	assert.True(t, strings.HasSuffix(down[0].Excerpt, "…"))
+
+	summary, err := feedbackService.GetSummary(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), summary.Up)
+	assert.Equal(t, int64(1), summary.Down)
+	require.Len(t, summary.Providers, 3)
+	// Worst-rated first
+	assert.Equal(t, "opus", summary.Providers[0].Model)
+	assert.Equal(t, 1.0, summary.Providers[0].DownRatio)
+	assert.Equal(t, "claude", summary.Providers[1].Provider)
+	assert.Equal(t, "", summary.Providers[1].Model)
+	assert.Equal(t, int64(1), summary.Providers[1].Up)
+	assert.Equal(t, "gemini", summary.Providers[2].Provider)
+	assert.Equal(t, int64(1), summary.Providers[2].Responses)
+	assert.Equal(t, 0.0, summary.Providers[2].RatedRatio)
+
+	require.NoError(t, feedbackService.Clear(good.ID))
+	assert.ErrorIs(t, feedbackService.Clear(good.ID), ErrFeedbackNotFound)
+
+	// Regenerating a response drops its feedback
+	_, err = chatService.DeleteMessagesAfter(context.Background(), chat.ID, prompt.ID)
+	require.NoError(t, err)
+	forChat, err = feedbackService.ForChat(chat.ID)
+	require.NoError(t, err)
+	assert.Empty(t, forChat)
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+)
+
+// GenerationService records generation lifecycle events for latency analytics
+type GenerationService struct {
+	db database.Store
+}
+
+func NewGenerationService(db database.Store) *GenerationService {
+	return &GenerationService{db: db}
+}
+
+// NewGenerationID returns a random ID identifying one provider generation
+func NewGenerationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// RecordEvent stores a lifecycle event for a generation
+func (s *GenerationService) RecordEvent(generationID string, chatID int64, provider, event, detail string) error {
+	query := `
+		INSERT INTO generation_events (generation_id, chat_id, provider, event, detail, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.Exec(query, generationID, chatID, provider, event, detail, time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to record generation event: %w", err)
+	}
+
+	return nil
+}
+
+// GetChatEvents returns the raw events of all generations in a chat
+func (s *GenerationService) GetChatEvents(chatID int64) ([]*models.GenerationEvent, error) {
+	query := `
+		SELECT id, generation_id, chat_id, provider, event, detail, occurred_at
+		FROM generation_events
+		WHERE chat_id = ?
+		ORDER BY occurred_at ASC, id ASC
+	`
+
+	return s.queryEvents(query, chatID)
+}
+
+// GetChatTimings returns a timing summary for each generation in a chat
+func (s *GenerationService) GetChatTimings(chatID int64) ([]*models.GenerationTiming, error) {
+	events, err := s.GetChatEvents(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeGenerations(events), nil
+}
+
+// GetStats aggregates generation timings per provider since the given time
+func (s *GenerationService) GetStats(since time.Time) ([]*models.GenerationStats, error) {
+	query := `
+		SELECT id, generation_id, chat_id, provider, event, detail, occurred_at
+		FROM generation_events
+		WHERE occurred_at >= ?
+		ORDER BY occurred_at ASC, id ASC
+	`
+
+	events, err := s.queryEvents(query, since.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+
+	byProvider := make(map[string]*models.GenerationStats)
+	for _, timing := range summarizeGenerations(events) {
+		stats, ok := byProvider[timing.Provider]
+		if !ok {
+			stats = &models.GenerationStats{Provider: timing.Provider}
+			byProvider[timing.Provider] = stats
+		}
+
+		stats.Total++
+		switch timing.Status {
+		case models.GenerationCompleted:
+			stats.Completed++
+		case models.GenerationFailed:
+			stats.Failed++
+		}
+		if timing.TimeToFirstTokenMs != nil {
+			stats.TimeToFirstTokenSumMs += *timing.TimeToFirstTokenMs
+			stats.TimeToFirstTokenCount++
+		}
+		if timing.DurationMs != nil && timing.Status == models.GenerationCompleted {
+			stats.DurationSumMs += *timing.DurationMs
+			stats.DurationCount++
+		}
+	}
+
+	result := make([]*models.GenerationStats, 0, len(byProvider))
+	for _, stats := range byProvider {
+		if stats.TimeToFirstTokenCount > 0 {
+			stats.AvgTimeToFirstTokenMs = float64(stats.TimeToFirstTokenSumMs) / float64(stats.TimeToFirstTokenCount)
+		}
+		if stats.DurationCount > 0 {
+			stats.AvgDurationMs = float64(stats.DurationSumMs) / float64(stats.DurationCount)
+		}
+		result = append(result, stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Provider < result[j].Provider })
+
+	return result, nil
+}
+
+// queryEvents runs an event query and scans the rows
+func (s *GenerationService) queryEvents(query string, args ...any) ([]*models.GenerationEvent, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get generation events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.GenerationEvent
+	for rows.Next() {
+		var event models.GenerationEvent
+		var occurredAt int64
+		err := rows.Scan(
+			&event.ID,
+			&event.GenerationID,
+			&event.ChatID,
+			&event.Provider,
+			&event.Event,
+			&event.Detail,
+			&occurredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan generation event: %w", err)
+		}
+		event.OccurredAt = time.UnixMilli(occurredAt)
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// summarizeGenerations folds ordered events into one timing per generation
+func summarizeGenerations(events []*models.GenerationEvent) []*models.GenerationTiming {
+	var order []string
+	timings := make(map[string]*models.GenerationTiming)
+
+	for _, event := range events {
+		timing, ok := timings[event.GenerationID]
+		if !ok {
+			timing = &models.GenerationTiming{
+				GenerationID: event.GenerationID,
+				ChatID:       event.ChatID,
+				Provider:     event.Provider,
+				Status:       "running",
+			}
+			timings[event.GenerationID] = timing
+			order = append(order, event.GenerationID)
+		}
+
+		at := event.OccurredAt
+		switch event.Event {
+		case models.GenerationQueued:
+			timing.QueuedAt = &at
+		case models.GenerationStarted:
+			timing.StartedAt = &at
+		case models.GenerationFirstToken:
+			timing.FirstTokenAt = &at
+		case models.GenerationCompleted, models.GenerationFailed:
+			timing.FinishedAt = &at
+			timing.Status = event.Event
+			timing.Error = event.Detail
+		}
+	}
+
+	result := make([]*models.GenerationTiming, 0, len(order))
+	for _, id := range order {
+		timing := timings[id]
+		start := timing.QueuedAt
+		if start == nil {
+			start = timing.StartedAt
+		}
+		if start != nil && timing.FirstTokenAt != nil {
+			ms := timing.FirstTokenAt.Sub(*start).Milliseconds()
+			timing.TimeToFirstTokenMs = &ms
+		}
+		if start != nil && timing.FinishedAt != nil {
+			ms := timing.FinishedAt.Sub(*start).Milliseconds()
+			timing.DurationMs = &ms
+		}
+		result = append(result, timing)
+	}
+
+	return result
+}
+
+// FormatOpenMetrics renders aggregated stats and provider cancellation latencies in the
+// OpenMetrics text exposition format
+func FormatOpenMetrics(stats []*models.GenerationStats, cancellations []providers.CancellationStats) string {
+	var b strings.Builder
+
+	b.WriteString("# TYPE aigwhub_generations counter\n")
+	b.WriteString("# HELP aigwhub_generations Generations by provider and outcome.\n")
+	for _, s := range stats {
+		fmt.Fprintf(&b, "aigwhub_generations_total{provider=%q,status=\"completed\"} %d\n", s.Provider, s.Completed)
+		fmt.Fprintf(&b, "aigwhub_generations_total{provider=%q,status=\"failed\"} %d\n", s.Provider, s.Failed)
+		fmt.Fprintf(&b, "aigwhub_generations_total{provider=%q,status=\"running\"} %d\n", s.Provider, s.Total-s.Completed-s.Failed)
+	}
+
+	b.WriteString("# TYPE aigwhub_time_to_first_token_seconds summary\n")
+	b.WriteString("# UNIT aigwhub_time_to_first_token_seconds seconds\n")
+	b.WriteString("# HELP aigwhub_time_to_first_token_seconds Time from prompt to first streamed token.\n")
+	for _, s := range stats {
+		fmt.Fprintf(&b, "aigwhub_time_to_first_token_seconds_count{provider=%q} %d\n", s.Provider, s.TimeToFirstTokenCount)
+		fmt.Fprintf(&b, "aigwhub_time_to_first_token_seconds_sum{provider=%q} %.3f\n", s.Provider, float64(s.TimeToFirstTokenSumMs)/1000)
+	}
+
+	b.WriteString("# TYPE aigwhub_generation_duration_seconds summary\n")
+	b.WriteString("# UNIT aigwhub_generation_duration_seconds seconds\n")
+	b.WriteString("# HELP aigwhub_generation_duration_seconds Time from prompt to completed response.\n")
+	for _, s := range stats {
+		fmt.Fprintf(&b, "aigwhub_generation_duration_seconds_count{provider=%q} %d\n", s.Provider, s.DurationCount)
+		fmt.Fprintf(&b, "aigwhub_generation_duration_seconds_sum{provider=%q} %.3f\n", s.Provider, float64(s.DurationSumMs)/1000)
+	}
+
+	b.WriteString("# TYPE aigwhub_provider_cancellation_seconds summary\n")
+	b.WriteString("# UNIT aigwhub_provider_cancellation_seconds seconds\n")
+	b.WriteString("# HELP aigwhub_provider_cancellation_seconds Time from cancelling a provider process to its termination.\n")
+	for _, s := range cancellations {
+		fmt.Fprintf(&b, "aigwhub_provider_cancellation_seconds_count{provider=%q} %d\n", s.Provider, s.Count)
+		fmt.Fprintf(&b, "aigwhub_provider_cancellation_seconds_sum{provider=%q} %.3f\n", s.Provider, float64(s.SumMs)/1000)
+	}
+
+	b.WriteString("# EOF\n")
+	return b.String()
+}
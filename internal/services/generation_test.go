@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerationService_Timings(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := NewChatService(db)
+	service := NewGenerationService(db)
+
+	chat, err := chatService.CreateChat(context.Background(), "Latency", "claude")
+	require.NoError(t, err)
+
+	ok := NewGenerationID()
+	failed := NewGenerationID()
+	for _, event := range []string{models.GenerationQueued, models.GenerationStarted, models.GenerationFirstToken, models.GenerationCompleted} {
+		require.NoError(t, service.RecordEvent(ok, chat.ID, "claude", event, ""))
+	}
+	require.NoError(t, service.RecordEvent(failed, chat.ID, "gemini", models.GenerationQueued, ""))
+	require.NoError(t, service.RecordEvent(failed, chat.ID, "gemini", models.GenerationFailed, "exit status 1"))
+
+	timings, err := service.GetChatTimings(chat.ID)
+	require.NoError(t, err)
+	require.Len(t, timings, 2)
+
+	assert.Equal(t, models.GenerationCompleted, timings[0].Status)
+	assert.NotNil(t, timings[0].TimeToFirstTokenMs)
+	assert.NotNil(t, timings[0].DurationMs)
+	assert.Equal(t, models.GenerationFailed, timings[1].Status)
+	assert.Equal(t, "exit status 1", timings[1].Error)
+	assert.Nil(t, timings[1].TimeToFirstTokenMs)
+
+	stats, err := service.GetStats(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, "claude", stats[0].Provider)
+	assert.Equal(t, int64(1), stats[0].Completed)
+	assert.Equal(t, int64(1), stats[1].Failed)
+
+	metrics := FormatOpenMetrics(stats, []providers.CancellationStats{{Provider: "claude", Count: 2, SumMs: 150, MaxMs: 100}})
+	assert.Contains(t, metrics, `aigwhub_generations_total{provider="claude",status="completed"} 1`)
+	assert.Contains(t, metrics, `aigwhub_provider_cancellation_seconds_count{provider="claude"} 2`)
+	assert.Contains(t, metrics, `aigwhub_provider_cancellation_seconds_sum{provider="claude"} 0.150`)
+	assert.True(t, strings.HasSuffix(metrics, "# EOF\n"))
+}
+
+func TestGenerationService_RejectsUnknownEvent(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chat, err := NewChatService(db).CreateChat(context.Background(), "Latency", "claude")
+	require.NoError(t, err)
+
+	err = NewGenerationService(db).RecordEvent(NewGenerationID(), chat.ID, "claude", "paused", "")
+	assert.Error(t, err)
+}
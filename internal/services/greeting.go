@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/models"
+)
+
+// Settings key the greeting is stored under
+const greetingSettingsKey = "greeting"
+
+// GreetingService adds the configured welcome message and disclaimer to new chats
+type GreetingService struct {
+	settings    *SettingsService
+	chatService *ChatService
+	current     models.GreetingSettings
+	loaded      bool
+	mu          sync.RWMutex
+}
+
+func NewGreetingService(settings *SettingsService, chatService *ChatService) *GreetingService {
+	return &GreetingService{settings: settings, chatService: chatService}
+}
+
+// Get returns the greeting settings; greetings are disabled until an admin configures them
+func (s *GreetingService) Get() (models.GreetingSettings, error) {
+	s.mu.RLock()
+	if s.loaded {
+		defer s.mu.RUnlock()
+		return s.current, nil
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var greeting models.GreetingSettings
+	if _, err := s.settings.Get(greetingSettingsKey, &greeting); err != nil {
+		return models.GreetingSettings{}, err
+	}
+	s.current = normalizeGreeting(greeting)
+	s.loaded = true
+	return s.current, nil
+}
+
+// Update stores new greeting settings, dropping empty translations
+func (s *GreetingService) Update(greeting models.GreetingSettings) (models.GreetingSettings, error) {
+	greeting = normalizeGreeting(greeting)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.settings.Set(greetingSettingsKey, greeting); err != nil {
+		return models.GreetingSettings{}, err
+	}
+	s.current = greeting
+	s.loaded = true
+	return greeting, nil
+}
+
+// Invalidate drops the cached settings so the next Get reloads them, e.g. after an import
+func (s *GreetingService) Invalidate() {
+	s.mu.Lock()
+	s.loaded = false
+	s.mu.Unlock()
+}
+
+// Messages returns the welcome message and disclaimer for a language, skipping empty ones
+func (s *GreetingService) Messages(lang string) ([]string, error) {
+	greeting, err := s.Get()
+	if err != nil {
+		return nil, err
+	}
+	if !greeting.Enabled {
+		return nil, nil
+	}
+
+	var messages []string
+	for _, texts := range []map[string]string{greeting.Welcome, greeting.Disclaimer} {
+		if text := localizedText(texts, lang); text != "" {
+			messages = append(messages, text)
+		}
+	}
+	return messages, nil
+}
+
+// Greet adds the greeting messages for the given language to a new chat as system messages
+func (s *GreetingService) Greet(ctx context.Context, chatID int64, lang string) error {
+	messages, err := s.Messages(lang)
+	if err != nil {
+		return err
+	}
+
+	for _, content := range messages {
+		if _, err := s.chatService.AddMessage(ctx, chatID, "system", content); err != nil {
+			return fmt.Errorf("failed to add greeting: %w", err)
+		}
+	}
+	return nil
+}
+
+// localizedText picks the text for lang, falling back to the default language and then any translation
+func localizedText(texts map[string]string, lang string) string {
+	if text := texts[lang]; text != "" {
+		return text
+	}
+	if text := texts[config.DefaultLanguage]; text != "" {
+		return text
+	}
+
+	langs := make([]string, 0, len(texts))
+	for l := range texts {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	for _, l := range langs {
+		if texts[l] != "" {
+			return texts[l]
+		}
+	}
+	return ""
+}
+
+// normalizeGreeting trims translations and removes empty ones
+func normalizeGreeting(greeting models.GreetingSettings) models.GreetingSettings {
+	clean := func(texts map[string]string) map[string]string {
+		result := make(map[string]string, len(texts))
+		for lang, text := range texts {
+			if text = strings.TrimSpace(text); text != "" {
+				result[lang] = text
+			}
+		}
+		return result
+	}
+	greeting.Welcome = clean(greeting.Welcome)
+	greeting.Disclaimer = clean(greeting.Disclaimer)
+	return greeting
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGreetingService(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := NewChatService(db)
+	service := NewGreetingService(NewSettingsService(db), chatService)
+
+	// Greetings are disabled until configured
+	greeting, err := service.Get()
+	require.NoError(t, err)
+	assert.False(t, greeting.Enabled)
+
+	chat, err := chatService.CreateChat(context.Background(), "Quiet", "claude")
+	require.NoError(t, err)
+	require.NoError(t, service.Greet(context.Background(), chat.ID, "en"))
+	messages, err := chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	greeting, err = service.Update(models.GreetingSettings{
+		Enabled:    true,
+		Welcome:    map[string]string{"en": "  Welcome!  ", "ja": "ようこそ！"},
+		Disclaimer: map[string]string{"en": "Responses may be wrong.", "ja": "   "},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome!", greeting.Welcome["en"])
+	assert.NotContains(t, greeting.Disclaimer, "ja")
+
+	// Japanese has no disclaimer translation and falls back to English
+	jaMessages, err := service.Messages("ja")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ようこそ！", "Responses may be wrong."}, jaMessages)
+
+	chat, err = chatService.CreateChat(context.Background(), "Greeted", "claude")
+	require.NoError(t, err)
+	require.NoError(t, service.Greet(context.Background(), chat.ID, "en"))
+	messages, err = chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "system", messages[0].Role)
+	assert.Equal(t, "Welcome!", messages[0].Content)
+	assert.Equal(t, "Responses may be wrong.", messages[1].Content)
+
+	// Settings persist for a new service instance
+	reloaded, err := NewGreetingService(NewSettingsService(db), chatService).Get()
+	require.NoError(t, err)
+	assert.Equal(t, greeting, reloaded)
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"ai-gateway-hub/internal/jobs"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// HealthCheckService periodically checks every provider and keeps a bounded history in Redis
+type HealthCheckService struct {
+	registry    *ProviderRegistry
+	redisClient *redis.Client
+	interval    time.Duration
+	historySize int
+	ctx         context.Context
+}
+
+func NewHealthCheckService(registry *ProviderRegistry, redisClient *redis.Client, interval time.Duration, historySize int) *HealthCheckService {
+	return &HealthCheckService{
+		registry:    registry,
+		redisClient: redisClient,
+		interval:    interval,
+		historySize: historySize,
+		ctx:         context.Background(),
+	}
+}
+
+// Job checks all providers on the configured interval. Every instance checks its own providers,
+// so it isn't exclusive.
+func (s *HealthCheckService) Job() jobs.Job {
+	return jobs.Job{
+		Name:       "health_checks",
+		Interval:   s.interval,
+		RunAtStart: true,
+		Run: func(ctx context.Context) error {
+			s.CheckAll()
+			return nil
+		},
+	}
+}
+
+// CheckAll checks every registered provider concurrently
+func (s *HealthCheckService) CheckAll() {
+	var wg sync.WaitGroup
+	for id, provider := range s.registry.snapshot() {
+		wg.Add(1)
+		go func(providerID string, p providers.AIProvider) {
+			defer wg.Done()
+			s.Check(providerID, p)
+		}(id, provider)
+	}
+	wg.Wait()
+}
+
+// Check runs a single health check, records it in the history and refreshes the status cache
+func (s *HealthCheckService) Check(providerID string, provider providers.AIProvider) *models.HealthCheckResult {
+	start := time.Now()
+	status := provider.GetStatus()
+	latency := time.Since(start)
+
+	result := &models.HealthCheckResult{
+		ProviderID: providerID,
+		CheckedAt:  start,
+		Success:    status.Available,
+		LatencyMs:  latency.Milliseconds(),
+		Status:     status.Status,
+		Details:    status.Details,
+	}
+
+	s.registry.cacheStatus(providerID, status)
+
+	if err := s.record(result); err != nil {
+		utils.Debug("Failed to record health check for %s: %v", providerID, err)
+	}
+	if !result.Success {
+		utils.Warn("Provider %s health check failed: %s", providerID, status.Details)
+	}
+
+	return result
+}
+
+// History returns the most recent health checks for a provider, newest first
+func (s *HealthCheckService) History(providerID string, limit int) ([]*models.HealthCheckResult, error) {
+	if limit <= 0 || limit > s.historySize {
+		limit = s.historySize
+	}
+
+	entries, err := s.redisClient.LRange(s.ctx, s.key(providerID), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get health history: %w", err)
+	}
+
+	results := make([]*models.HealthCheckResult, 0, len(entries))
+	for _, entry := range entries {
+		var result models.HealthCheckResult
+		if err := json.Unmarshal([]byte(entry), &result); err != nil {
+			continue
+		}
+		results = append(results, &result)
+	}
+
+	return results, nil
+}
+
+// record pushes a result onto the provider's history list, trimmed to the configured size
+func (s *HealthCheckService) record(result *models.HealthCheckResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	key := s.key(result.ProviderID)
+	pipe := s.redisClient.TxPipeline()
+	pipe.LPush(s.ctx, key, data)
+	pipe.LTrim(s.ctx, key, 0, int64(s.historySize-1))
+	_, err = pipe.Exec(s.ctx)
+	return err
+}
+
+// key generates the Redis key for a provider's health history
+func (s *HealthCheckService) key(providerID string) string {
+	return fmt.Sprintf("provider_health:%s", providerID)
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"ai-gateway-hub/internal/encryption"
+	"ai-gateway-hub/internal/models"
+)
+
+// SetEncryption encrypts the content of messages written from now on with cipher; messages are
+// decrypted on read whether or not they were written encrypted
+func (s *ChatService) SetEncryption(cipher *encryption.Cipher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cipher = cipher
+}
+
+// encryption returns the cipher of message content, or nil when it is stored as plaintext
+func (s *ChatService) encryption() *encryption.Cipher {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cipher
+}
+
+// sealContent encrypts message content before it is written, if encryption is enabled
+func (s *ChatService) sealContent(content string) (string, error) {
+	cipher := s.encryption()
+	if cipher == nil {
+		return content, nil
+	}
+	sealed, err := cipher.Encrypt(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt message: %w", err)
+	}
+	return sealed, nil
+}
+
+// openContent decrypts message content that was written encrypted
+func openContent(cipher *encryption.Cipher, content string) (string, error) {
+	if !encryption.IsEncrypted(content) {
+		return content, nil
+	}
+	if cipher == nil {
+		return "", fmt.Errorf("message is encrypted but no encryption key is configured")
+	}
+	return cipher.Decrypt(content)
+}
+
+// scanMessage reads a message selected with messageColumns and decrypts its content
+func (s *ChatService) scanMessage(row rowScanner) (*models.Message, error) {
+	msg, err := scanMessage(row)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Content, err = openContent(s.encryption(), msg.Content); err != nil {
+		return nil, fmt.Errorf("failed to decrypt message %d: %w", msg.ID, err)
+	}
+	return msg, nil
+}
+
+// EncryptMessages encrypts the messages stored as plaintext, batchSize at a time, and returns how
+// many were encrypted. It can be run while the server is up and resumed after an interruption.
+func (s *ChatService) EncryptMessages(ctx context.Context, batchSize int) (int64, error) {
+	cipher := s.encryption()
+	if cipher == nil {
+		return 0, fmt.Errorf("message encryption is not configured")
+	}
+	return s.convertMessages(ctx, batchSize, false, cipher.Encrypt)
+}
+
+// DecryptMessages stores encrypted messages as plaintext again, e.g. before encryption is
+// disabled, and returns how many were decrypted
+func (s *ChatService) DecryptMessages(ctx context.Context, batchSize int) (int64, error) {
+	cipher := s.encryption()
+	if cipher == nil {
+		return 0, fmt.Errorf("message encryption is not configured")
+	}
+	return s.convertMessages(ctx, batchSize, true, cipher.Decrypt)
+}
+
+// convertMessages rewrites the content of the messages that are (or aren't) encrypted with
+// convert, in batches ordered by ID
+func (s *ChatService) convertMessages(ctx context.Context, batchSize int, encrypted bool, convert func(string) (string, error)) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	var converted, lastID int64
+	for {
+		query := `SELECT id, content FROM messages WHERE id > ? ORDER BY id LIMIT ?`
+		rows, err := s.db.QueryContext(ctx, query, lastID, batchSize)
+		if err != nil {
+			return converted, fmt.Errorf("failed to get messages: %w", err)
+		}
+
+		type pending struct {
+			id      int64
+			content string
+		}
+		var batch []pending
+		scanned := 0
+		for rows.Next() {
+			var p pending
+			if err := rows.Scan(&p.id, &p.content); err != nil {
+				rows.Close()
+				return converted, fmt.Errorf("failed to scan message: %w", err)
+			}
+			scanned++
+			lastID = p.id
+			if encryption.IsEncrypted(p.content) == encrypted {
+				batch = append(batch, p)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return converted, fmt.Errorf("failed to get messages: %w", err)
+		}
+
+		for _, p := range batch {
+			content, err := convert(p.content)
+			if err != nil {
+				return converted, fmt.Errorf("failed to convert message %d: %w", p.id, err)
+			}
+			// Only rewrite the message if nothing else changed it in the meantime
+			result, err := s.db.ExecContext(ctx, `UPDATE messages SET content = ? WHERE id = ? AND content = ?`, content, p.id, p.content)
+			if err != nil {
+				return converted, fmt.Errorf("failed to update message %d: %w", p.id, err)
+			}
+			if n, err := result.RowsAffected(); err == nil {
+				converted += n
+			}
+		}
+
+		if scanned < batchSize {
+			return converted, nil
+		}
+	}
+}
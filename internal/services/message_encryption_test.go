@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/encryption"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storedContent returns the content of a message as it is stored in the database
+func storedContent(t *testing.T, db database.Store, messageID int64) string {
+	t.Helper()
+	var content string
+	require.NoError(t, db.QueryRow(`SELECT content FROM messages WHERE id = ?`, messageID).Scan(&content))
+	return content
+}
+
+func TestChatService_EncryptsMessages(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	cipher, err := encryption.NewCipher(make([]byte, encryption.KeySize))
+	require.NoError(t, err)
+	service := NewChatService(db)
+	ctx := context.Background()
+	chat, err := service.CreateChat(ctx, "Encrypted", "claude")
+	require.NoError(t, err)
+
+	plain, err := service.AddMessage(ctx, chat.ID, "user", "written before encryption")
+	require.NoError(t, err)
+
+	service.SetEncryption(cipher)
+	msg, err := service.AddMessage(ctx, chat.ID, "user", "my secret prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "my secret prompt", msg.Content)
+	assert.True(t, encryption.IsEncrypted(storedContent(t, db, msg.ID)))
+	assert.Equal(t, "written before encryption", storedContent(t, db, plain.ID))
+
+	streaming, err := service.StartStreamingMessage(ctx, chat.ID, "claude", "partial")
+	require.NoError(t, err)
+	assert.Equal(t, "partial", streaming.Content)
+	require.NoError(t, service.CheckpointMessage(ctx, streaming.ID, "partial answer"))
+	assert.True(t, encryption.IsEncrypted(storedContent(t, db, streaming.ID)))
+	finished, err := service.FinishStreamingMessage(ctx, chat.ID, streaming.ID, "partial answer, done")
+	require.NoError(t, err)
+	assert.Equal(t, "partial answer, done", finished.Content)
+
+	edited, err := service.UpdateMessageContent(ctx, chat.ID, msg.ID, "my edited prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "my edited prompt", edited.Content)
+	assert.True(t, encryption.IsEncrypted(storedContent(t, db, msg.ID)))
+
+	messages, err := service.GetMessages(ctx, chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "written before encryption", messages[0].Content)
+	assert.Equal(t, "my edited prompt", messages[1].Content)
+	assert.Equal(t, "partial answer, done", messages[2].Content)
+
+	details, err := service.GetChatDetails(ctx, chat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "partial answer, done", details.LastMessage.Preview)
+
+	feedback := NewFeedbackService(db)
+	feedback.SetEncryption(cipher)
+	_, err = feedback.Rate(finished.ID, -1, "")
+	require.NoError(t, err)
+	listed, err := feedback.List(FeedbackQuery{Since: time.Now().Add(-time.Hour), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "partial answer, done", listed[0].Excerpt)
+
+	// Encrypted messages can't be read without the key
+	_, err = NewChatService(db).GetMessages(ctx, chat.ID, 10, 0)
+	assert.ErrorContains(t, err, "no encryption key is configured")
+}
+
+func TestChatService_EncryptAndDecryptExistingMessages(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	service := NewChatService(db)
+	ctx := context.Background()
+	chat, err := service.CreateChat(ctx, "Existing", "claude")
+	require.NoError(t, err)
+	for i := 0; i < 7; i++ {
+		_, err := service.AddMessage(ctx, chat.ID, "user", fmt.Sprintf("message %d", i))
+		require.NoError(t, err)
+	}
+
+	_, err = service.EncryptMessages(ctx, 3)
+	assert.Error(t, err, "encryption must be configured")
+
+	cipher, err := encryption.NewCipher(make([]byte, encryption.KeySize))
+	require.NoError(t, err)
+	service.SetEncryption(cipher)
+	_, err = service.AddMessage(ctx, chat.ID, "user", "already encrypted")
+	require.NoError(t, err)
+
+	n, err := service.EncryptMessages(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), n)
+	n, err = service.EncryptMessages(ctx, 3)
+	require.NoError(t, err)
+	assert.Zero(t, n, "encrypted messages are skipped")
+
+	messages, err := service.GetMessages(ctx, chat.ID, 20, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 8)
+	for i, msg := range messages[:7] {
+		assert.Equal(t, fmt.Sprintf("message %d", i), msg.Content)
+		assert.True(t, encryption.IsEncrypted(storedContent(t, db, msg.ID)))
+	}
+
+	n, err = service.DecryptMessages(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), n)
+	messages, err = NewChatService(db).GetMessages(ctx, chat.ID, 20, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "already encrypted", messages[7].Content)
+}
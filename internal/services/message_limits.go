@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	apperrors "ai-gateway-hub/internal/errors"
+)
+
+// What happens to responses longer than MessageLimits.MaxResponseBytes
+const (
+	OversizedResponseTruncate = "truncate" // stop the response and keep what fit
+	OversizedResponseWarn     = "warn"     // keep the whole response and only report it
+)
+
+// MessageLimits bounds the size of prompts and of the responses saved for them (0 disables either)
+type MessageLimits struct {
+	MaxPromptLength  int    // characters
+	MaxResponseBytes int    // bytes
+	OversizedAction  string // OversizedResponseTruncate (the default) or OversizedResponseWarn
+}
+
+// PromptError is a prompt refused by ValidatePrompt. Key and Args translate it for the user;
+// it is a validation error, so handlers report it as 422.
+type PromptError struct {
+	Key  string
+	Args []any
+	err  error
+}
+
+func (e *PromptError) Error() string {
+	return e.err.Error()
+}
+
+func (e *PromptError) Unwrap() error {
+	return e.err
+}
+
+// newPromptError returns a PromptError with an English message for logs and API clients
+func newPromptError(key, message string, args ...any) error {
+	return &PromptError{Key: key, Args: args, err: apperrors.Validation(message)}
+}
+
+// ValidatePrompt checks a prompt before it is saved or sent to a provider
+func (l MessageLimits) ValidatePrompt(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return newPromptError("error.promptEmpty", "prompt must not be empty")
+	}
+	if !utf8.ValidString(content) {
+		return newPromptError("error.promptInvalidEncoding", "prompt is not valid UTF-8")
+	}
+	if strings.ContainsRune(content, 0) {
+		return newPromptError("error.promptInvalidCharacters", "prompt must not contain NUL characters")
+	}
+	if l.MaxPromptLength > 0 {
+		if length := utf8.RuneCountInString(content); length > l.MaxPromptLength {
+			return newPromptError("error.promptTooLongLimit",
+				fmt.Sprintf("prompt is %d characters long, the limit is %d", length, l.MaxPromptLength),
+				length, l.MaxPromptLength)
+		}
+	}
+	return nil
+}
+
+// TruncatesResponses reports whether responses over MaxResponseBytes are cut off
+func (l MessageLimits) TruncatesResponses() bool {
+	return l.MaxResponseBytes > 0 && l.OversizedAction != OversizedResponseWarn
+}
+
+// ResponseTooLarge reports whether a response of size bytes is over MaxResponseBytes
+func (l MessageLimits) ResponseTooLarge(size int) bool {
+	return l.MaxResponseBytes > 0 && size > l.MaxResponseBytes
+}
+
+// TruncateResponse cuts a response down to MaxResponseBytes without splitting a character and
+// reports whether it was cut; responses are only reported as oversized with OversizedResponseWarn
+func (l MessageLimits) TruncateResponse(content string) (string, bool) {
+	if !l.ResponseTooLarge(len(content)) {
+		return content, false
+	}
+	if !l.TruncatesResponses() {
+		return content, true
+	}
+	return TruncateUTF8(content, l.MaxResponseBytes), true
+}
+
+// TruncateUTF8 returns the longest prefix of s of at most n bytes that doesn't split a character
+func TruncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// SetMessageLimits sets the limits prompts and responses are checked against
+func (s *ChatService) SetMessageLimits(limits MessageLimits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits = limits
+}
+
+// MessageLimits returns the limits prompts and responses are checked against
+func (s *ChatService) MessageLimits() MessageLimits {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.limits
+}
+
+// ValidatePrompt checks a prompt against the service's limits
+func (s *ChatService) ValidatePrompt(content string) error {
+	return s.MessageLimits().ValidatePrompt(content)
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	apperrors "ai-gateway-hub/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageLimits_ValidatePrompt(t *testing.T) {
+	limits := MessageLimits{MaxPromptLength: 5}
+	assert.NoError(t, limits.ValidatePrompt("hello"))
+	assert.NoError(t, limits.ValidatePrompt("こんにちは"), "the limit counts characters, not bytes")
+
+	for content, key := range map[string]string{
+		"   \n":   "error.promptEmpty",
+		"ab\xffc": "error.promptInvalidEncoding",
+		"a\x00b":  "error.promptInvalidCharacters",
+		"hello!":  "error.promptTooLongLimit",
+	} {
+		err := limits.ValidatePrompt(content)
+		var promptErr *PromptError
+		require.True(t, errors.As(err, &promptErr), "%q", content)
+		assert.Equal(t, key, promptErr.Key)
+		assert.True(t, errors.Is(err, apperrors.ErrValidation))
+	}
+
+	err := limits.ValidatePrompt("hello!")
+	var promptErr *PromptError
+	require.True(t, errors.As(err, &promptErr))
+	assert.Equal(t, []any{6, 5}, promptErr.Args)
+	assert.EqualError(t, err, "prompt is 6 characters long, the limit is 5")
+
+	assert.NoError(t, MessageLimits{}.ValidatePrompt(strings.Repeat("a", 1<<20)), "0 disables the limit")
+}
+
+func TestMessageLimits_TruncateResponse(t *testing.T) {
+	limits := MessageLimits{MaxResponseBytes: 7}
+	assert.True(t, limits.TruncatesResponses())
+
+	content, oversized := limits.TruncateResponse("short")
+	assert.False(t, oversized)
+	assert.Equal(t, "short", content)
+
+	// Characters aren't split: each of these is 3 bytes
+	content, oversized = limits.TruncateResponse("あいうえ")
+	assert.True(t, oversized)
+	assert.Equal(t, "あい", content)
+
+	limits.OversizedAction = OversizedResponseWarn
+	content, oversized = limits.TruncateResponse("あいうえ")
+	assert.True(t, oversized)
+	assert.Equal(t, "あいうえ", content, "warn keeps the whole response")
+
+	content, oversized = MessageLimits{}.TruncateResponse("あいうえ")
+	assert.False(t, oversized)
+	assert.Equal(t, "あいうえ", content)
+}
+
+func TestChatService_MessageLimits(t *testing.T) {
+	service := NewChatService(nil)
+	assert.NoError(t, service.ValidatePrompt(strings.Repeat("a", 100)))
+
+	service.SetMessageLimits(MessageLimits{MaxPromptLength: 10})
+	assert.Equal(t, 10, service.MessageLimits().MaxPromptLength)
+	assert.Error(t, service.ValidatePrompt(strings.Repeat("a", 11)))
+}
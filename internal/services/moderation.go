@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/moderation"
+	"ai-gateway-hub/internal/utils"
+)
+
+// Length of the content excerpt kept with moderation events
+const moderationExcerptLength = 200
+
+// ModerationService moderates prompts and responses and keeps an audit trail of the content that
+// was flagged or blocked
+type ModerationService struct {
+	db       database.Store
+	pipeline *moderation.Pipeline
+}
+
+func NewModerationService(db database.Store, pipeline *moderation.Pipeline) *ModerationService {
+	return &ModerationService{db: db, pipeline: pipeline}
+}
+
+// ModerationQuery selects moderation events to list
+type ModerationQuery struct {
+	Since     time.Time
+	Action    string // empty lists flagged and blocked content
+	Direction string // empty lists prompts and responses
+	Limit     int
+}
+
+// Check moderates content and records it if it was flagged or blocked. Moderators that fail and
+// failures to record are only logged.
+func (s *ModerationService) Check(ctx context.Context, req moderation.Request) moderation.Verdict {
+	verdict, err := s.pipeline.Moderate(ctx, req)
+	if err != nil {
+		utils.Warn("Moderation of %s for chat %d: %v", req.Direction, req.ChatID, err)
+	}
+	if verdict.Action != moderation.ActionFlag && verdict.Action != moderation.ActionBlock {
+		return verdict
+	}
+
+	utils.Info("Moderation %s %s for chat %d (moderator=%s, rule=%s)", verdictPastTense(verdict.Action), req.Direction, req.ChatID, verdict.Moderator, verdict.Rule)
+	if err := s.record(req, verdict); err != nil {
+		utils.Error("Failed to record moderation event for chat %d: %v", req.ChatID, err)
+	}
+	return verdict
+}
+
+// verdictPastTense describes an action in log messages
+func verdictPastTense(action string) string {
+	if action == moderation.ActionBlock {
+		return "blocked"
+	}
+	return "flagged"
+}
+
+// record stores a moderation event
+func (s *ModerationService) record(req moderation.Request, verdict moderation.Verdict) error {
+	query := `
+		INSERT INTO moderation_events (chat_id, direction, provider, action, moderator, rule, reason, excerpt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.Exec(query, req.ChatID, req.Direction, req.Provider, verdict.Action, verdict.Moderator,
+		verdict.Rule, verdict.Reason, truncateRunes(req.Content, moderationExcerptLength))
+	if err != nil {
+		return fmt.Errorf("failed to record moderation event: %w", err)
+	}
+	return nil
+}
+
+// ListEvents returns moderation events, newest first
+func (s *ModerationService) ListEvents(q ModerationQuery) ([]*models.ModerationEvent, error) {
+	query := `
+		SELECT id, chat_id, direction, provider, action, moderator, rule, reason, excerpt, created_at
+		FROM moderation_events
+		WHERE created_at >= ?
+			AND (? = '' OR action = ?)
+			AND (? = '' OR direction = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := s.db.Query(query, q.Since, q.Action, q.Action, q.Direction, q.Direction, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.ModerationEvent{}
+	for rows.Next() {
+		e := &models.ModerationEvent{}
+		if err := rows.Scan(&e.ID, &e.ChatID, &e.Direction, &e.Provider, &e.Action, &e.Moderator,
+			&e.Rule, &e.Reason, &e.Excerpt, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan moderation event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get moderation events: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/moderation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModerationService(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	rules, err := moderation.NewRuleModerator([]moderation.Rule{
+		{Name: "banned", Keywords: []string{"forbidden"}, Action: moderation.ActionBlock, Reason: "banned term"},
+		{Name: "watch", Keywords: []string{"password"}, Action: moderation.ActionFlag},
+	})
+	require.NoError(t, err)
+	service := NewModerationService(db, moderation.NewPipeline(false, rules))
+	ctx := context.Background()
+
+	verdict := service.Check(ctx, moderation.Request{Direction: moderation.DirectionPrompt, ChatID: 1, Provider: "claude", Content: "hello"})
+	assert.Equal(t, moderation.ActionAllow, verdict.Action)
+
+	verdict = service.Check(ctx, moderation.Request{Direction: moderation.DirectionPrompt, ChatID: 1, Provider: "claude", Content: "my password is hunter2"})
+	assert.Equal(t, moderation.ActionFlag, verdict.Action)
+
+	verdict = service.Check(ctx, moderation.Request{Direction: moderation.DirectionResponse, ChatID: 2, Provider: "gemini", Content: "forbidden " + strings.Repeat("x", 300)})
+	assert.True(t, verdict.Blocked())
+	assert.Equal(t, "banned term", verdict.Reason)
+
+	// Only flagged and blocked content is recorded, newest first
+	since := time.Now().Add(-time.Hour)
+	events, err := service.ListEvents(ModerationQuery{Since: since, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, moderation.ActionBlock, events[0].Action)
+	assert.Equal(t, moderation.DirectionResponse, events[0].Direction)
+	assert.Equal(t, "rules", events[0].Moderator)
+	assert.Equal(t, "banned", events[0].Rule)
+	assert.Equal(t, "gemini", events[0].Provider)
+	assert.Equal(t, int64(2), events[0].ChatID)
+	assert.True(t, strings.HasSuffix(events[0].Excerpt, "…"), "long content is cut to an excerpt")
+	assert.Equal(t, "my password is hunter2", events[1].Excerpt)
+
+	events, err = service.ListEvents(ModerationQuery{Since: since, Action: moderation.ActionFlag, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "watch", events[0].Rule)
+
+	events, err = service.ListEvents(ModerationQuery{Since: since, Direction: moderation.DirectionResponse, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	events, err = service.ListEvents(ModerationQuery{Since: time.Now().Add(time.Hour), Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
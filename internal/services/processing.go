@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/processing"
+	"ai-gateway-hub/internal/utils"
+)
+
+// ErrProcessorNotFound is returned for processors that aren't configured
+var ErrProcessorNotFound = apperrors.NotFound("processor not found")
+
+// ProcessingService transforms prompts and responses with the configured processors, which chats
+// can enable and disable individually
+type ProcessingService struct {
+	db       database.Store
+	chats    *ChatService
+	pipeline *processing.Pipeline
+}
+
+func NewProcessingService(db database.Store, chatService *ChatService, pipeline *processing.Pipeline) *ProcessingService {
+	return &ProcessingService{db: db, chats: chatService, pipeline: pipeline}
+}
+
+// Process runs the processors of a stage enabled in the chat and returns the transformed content.
+// Processors that fail are skipped and only logged, as are failures to load the chat's settings.
+func (s *ProcessingService) Process(ctx context.Context, stage string, chatID int64, provider, content string) string {
+	if s.pipeline.Len() == 0 {
+		return content
+	}
+
+	overrides, err := s.overrides(ctx, chatID)
+	if err != nil {
+		utils.Warn("Failed to load processors of chat %d: %v", chatID, err)
+	}
+	enabled := func(name string, byDefault bool) bool {
+		if e, ok := overrides[name]; ok {
+			return e
+		}
+		return byDefault
+	}
+
+	processed, err := s.pipeline.Process(ctx, processing.Request{Stage: stage, ChatID: chatID, Provider: provider, Content: content}, enabled)
+	if err != nil {
+		utils.Warn("Processing of %s for chat %d: %v", stage, chatID, err)
+	}
+	return processed
+}
+
+// overrides returns the processors a chat enabled or disabled
+func (s *ProcessingService) overrides(ctx context.Context, chatID int64) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT processor, enabled FROM chat_processors WHERE chat_id = ?`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat processors: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var enabled bool
+		if err := rows.Scan(&name, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan chat processor: %w", err)
+		}
+		overrides[name] = enabled
+	}
+	return overrides, rows.Err()
+}
+
+// ChatProcessors lists the configured processors in the order they run, with whether the chat runs them
+func (s *ProcessingService) ChatProcessors(ctx context.Context, chatID int64) ([]models.ChatProcessor, error) {
+	if _, err := s.chats.GetChat(ctx, chatID); err != nil {
+		return nil, err
+	}
+	overrides, err := s.overrides(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := s.pipeline.Steps()
+	processors := make([]models.ChatProcessor, 0, len(steps))
+	for _, step := range steps {
+		enabled, ok := overrides[step.Name]
+		if !ok {
+			enabled = step.Enabled
+		}
+		processors = append(processors, models.ChatProcessor{
+			Name:      step.Name,
+			Stage:     step.Stage,
+			Order:     step.Order,
+			Providers: step.Providers,
+			Enabled:   enabled,
+			Default:   step.Enabled,
+		})
+	}
+	return processors, nil
+}
+
+// SetChatProcessor enables or disables a processor in a chat
+func (s *ProcessingService) SetChatProcessor(ctx context.Context, chatID int64, name string, enabled bool) error {
+	if !s.pipeline.Has(name) {
+		return ErrProcessorNotFound
+	}
+	if _, err := s.chats.GetChat(ctx, chatID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO chat_processors (chat_id, processor, enabled, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (chat_id, processor) DO UPDATE SET enabled = excluded.enabled, updated_at = excluded.updated_at
+	`
+	if _, err := s.db.ExecContext(ctx, query, chatID, name, enabled, time.Now()); err != nil {
+		return fmt.Errorf("failed to save chat processor: %w", err)
+	}
+	return nil
+}
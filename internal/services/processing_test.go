@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/processing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessingService(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	chatService := NewChatService(db)
+	chat, err := chatService.CreateChat(ctx, "Processed", "claude")
+	require.NoError(t, err)
+	other, err := chatService.CreateChat(ctx, "Other", "claude")
+	require.NoError(t, err)
+
+	disabled := false
+	pipeline, err := processing.Build([]processing.Entry{
+		{Name: "context", Type: processing.TypePrepend, Stage: processing.StagePre, Text: "Context: "},
+		{Name: "japanese", Type: processing.TypeAppend, Stage: processing.StagePre, Order: 1, Text: " (in Japanese)", Enabled: &disabled},
+	})
+	require.NoError(t, err)
+	processingService := NewProcessingService(db, chatService, pipeline)
+
+	assert.Equal(t, "Context: Hi", processingService.Process(ctx, processing.StagePre, chat.ID, "claude", "Hi"))
+	assert.Equal(t, "Hi", processingService.Process(ctx, processing.StagePost, chat.ID, "claude", "Hi"))
+
+	// Settings are per chat
+	require.NoError(t, processingService.SetChatProcessor(ctx, chat.ID, "context", false))
+	require.NoError(t, processingService.SetChatProcessor(ctx, chat.ID, "japanese", true))
+	assert.Equal(t, "Hi (in Japanese)", processingService.Process(ctx, processing.StagePre, chat.ID, "claude", "Hi"))
+	assert.Equal(t, "Context: Hi", processingService.Process(ctx, processing.StagePre, other.ID, "claude", "Hi"))
+
+	processors, err := processingService.ChatProcessors(ctx, chat.ID)
+	require.NoError(t, err)
+	require.Len(t, processors, 2)
+	assert.Equal(t, "context", processors[0].Name)
+	assert.False(t, processors[0].Enabled)
+	assert.True(t, processors[0].Default)
+	assert.True(t, processors[1].Enabled)
+	assert.False(t, processors[1].Default)
+
+	assert.ErrorIs(t, processingService.SetChatProcessor(ctx, chat.ID, "missing", true), ErrProcessorNotFound)
+	assert.ErrorIs(t, processingService.SetChatProcessor(ctx, 999, "context", true), ErrChatNotFound)
+	_, err = processingService.ChatProcessors(ctx, 999)
+	assert.ErrorIs(t, err, ErrChatNotFound)
+}
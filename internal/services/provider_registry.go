@@ -2,30 +2,82 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
 	"sync"
 	"time"
 
 	"ai-gateway-hub/internal/config"
 	"ai-gateway-hub/internal/models"
 	"ai-gateway-hub/internal/providers"
-	"github.com/go-redis/redis/v8"
+	"ai-gateway-hub/internal/secrets"
+	"ai-gateway-hub/internal/utils"
 )
 
-// ProviderRegistry manages AI providers with Redis-based caching
+const (
+	// How often the providers file is checked for changes
+	providersFilePollInterval = 5 * time.Second
+
+	// How long a reload of the providers file waits for generations of the providers it changes
+	providerDrainTimeout = 30 * time.Second
+)
+
+// ProviderRegistry manages AI providers, caching their statuses
 type ProviderRegistry struct {
 	providers   map[string]providers.AIProvider
 	mu          sync.RWMutex
-	redisClient *redis.Client
+	reloadMu    sync.Mutex // serializes loads of the providers file
+	statusCache StatusCache
 	ctx         context.Context
+
+	// Providers loaded from the providers file, and the built-ins they replaced
+	fileProviderIDs map[string]bool
+	fileConfigs     []providers.ProviderConfig
+	shadowed        map[string]providers.AIProvider
+
+	// In-flight generations per provider instance, used to drain before removal
+	inflight map[providers.AIProvider]*inflightGenerations
+
+	// Server-wide environment passthrough policy for provider subprocesses
+	envPolicy providers.EnvPolicy
+
+	// Server-wide sandbox for provider subprocesses; providers file entries may override it
+	sandbox providers.Sandbox
+
+	// Resolves the secret references in providers file env and headers
+	secretResolver providers.SecretResolver
+
+	// Refuses generations of providers that keep failing, if set
+	breaker *CircuitBreaker
+
+	// Fallbacks of primary providers, in the order they are tried
+	failover map[string][]string
+
+	// Set once the built-in providers are registered
+	initialized bool
+}
+
+// inflightGenerations counts active generations of one provider instance;
+// idle is closed when the count drops back to zero
+type inflightGenerations struct {
+	count int
+	idle  chan struct{}
 }
 
-func NewProviderRegistry(redisClient *redis.Client) *ProviderRegistry {
+// NewProviderRegistry creates a registry; a nil statusCache checks statuses on every listing
+func NewProviderRegistry(statusCache StatusCache) *ProviderRegistry {
 	registry := &ProviderRegistry{
-		providers:   make(map[string]providers.AIProvider),
-		redisClient: redisClient,
-		ctx:         context.Background(),
+		providers:       make(map[string]providers.AIProvider),
+		statusCache:     statusCache,
+		ctx:             context.Background(),
+		fileProviderIDs: make(map[string]bool),
+		shadowed:        make(map[string]providers.AIProvider),
+		inflight:        make(map[providers.AIProvider]*inflightGenerations),
+		envPolicy:       providers.DefaultEnvPolicy,
+		secretResolver:  secrets.NewManager(secrets.Options{}),
 	}
 	
 	// Start background status update routine
@@ -61,6 +113,114 @@ func (r *ProviderRegistry) Get(id string) (providers.AIProvider, error) {
 	return provider, nil
 }
 
+// Acquire retrieves a provider and marks a generation as in flight until release is called.
+// Deregister and Replace wait for acquired generations to finish before returning. Providers whose
+// circuit is open are refused with ErrProviderDegraded; callers report the outcome of generations
+// with RecordResult.
+func (r *ProviderRegistry) Acquire(id string) (providers.AIProvider, func(), error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	provider, exists := r.providers[id]
+	if !exists {
+		return nil, nil, fmt.Errorf("provider %s not found", id)
+	}
+	if r.breaker != nil {
+		if err := r.breaker.Allow(id); err != nil {
+			return nil, nil, fmt.Errorf("provider %s: %w", id, err)
+		}
+	}
+
+	active, ok := r.inflight[provider]
+	if !ok {
+		active = &inflightGenerations{idle: make(chan struct{})}
+		r.inflight[provider] = active
+	}
+	active.count++
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			active.count--
+			if active.count == 0 {
+				close(active.idle)
+				delete(r.inflight, provider)
+			}
+		})
+	}
+
+	return provider, release, nil
+}
+
+// Deregister removes a provider so no new generations can start, then waits for
+// in-flight generations to drain (or ctx to expire) and closes it if it implements io.Closer
+func (r *ProviderRegistry) Deregister(ctx context.Context, id string) error {
+	r.mu.Lock()
+	provider, exists := r.providers[id]
+	if !exists {
+		r.mu.Unlock()
+		return fmt.Errorf("provider %s not found", id)
+	}
+	delete(r.providers, id)
+	delete(r.fileProviderIDs, id)
+	delete(r.shadowed, id)
+	r.mu.Unlock()
+
+	r.invalidateStatus(id)
+	utils.Info("Deregistered provider %s", id)
+
+	return r.drain(ctx, provider)
+}
+
+// Replace swaps a registered provider for a new instance with the same ID. New generations
+// use the new instance immediately; Replace returns once the old one has drained.
+func (r *ProviderRegistry) Replace(ctx context.Context, provider providers.AIProvider) error {
+	id := provider.GetID()
+
+	r.mu.Lock()
+	old, exists := r.providers[id]
+	if !exists {
+		r.mu.Unlock()
+		return fmt.Errorf("provider %s not registered", id)
+	}
+	r.providers[id] = provider
+	r.mu.Unlock()
+
+	r.invalidateStatus(id)
+	utils.Info("Replaced provider %s", id)
+
+	if old == provider {
+		return nil
+	}
+	return r.drain(ctx, old)
+}
+
+// drain waits until a removed provider instance has no in-flight generations, then closes it
+func (r *ProviderRegistry) drain(ctx context.Context, provider providers.AIProvider) error {
+	r.mu.RLock()
+	active := r.inflight[provider]
+	r.mu.RUnlock()
+
+	if active != nil {
+		select {
+		case <-active.idle:
+		case <-ctx.Done():
+			return fmt.Errorf("provider %s still has in-flight generations: %w", provider.GetID(), ctx.Err())
+		}
+	}
+
+	if closer, ok := provider.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return fmt.Errorf("failed to close provider %s: %w", provider.GetID(), err)
+		}
+	}
+
+	return nil
+}
+
 // List returns all registered providers with cached status
 func (r *ProviderRegistry) List() []*models.Provider {
 	r.mu.RLock()
@@ -68,10 +228,13 @@ func (r *ProviderRegistry) List() []*models.Provider {
 
 	var result []*models.Provider
 	for _, p := range r.providers {
+		branding := providers.GetBranding(p)
 		provider := &models.Provider{
 			ID:          p.GetID(),
 			Name:        p.GetName(),
 			Description: p.GetDescription(),
+			IconURL:     branding.IconURL,
+			Color:       branding.Color,
 		}
 		
 		// Try to get cached status first
@@ -91,6 +254,19 @@ func (r *ProviderRegistry) List() []*models.Provider {
 			// Cache the status asynchronously
 			go r.cacheStatus(p.GetID(), status)
 		}
+
+		// A tripped circuit breaker overrides the installation status
+		if r.breaker != nil {
+			if circuit := r.breaker.State(p.GetID()); circuit != nil {
+				provider.Circuit = circuit
+				if circuit.State != models.CircuitClosed {
+					provider.Status = "degraded"
+					provider.Details = fmt.Sprintf("%d consecutive failures, last: %s", circuit.ConsecutiveFailures, circuit.LastError)
+					// Once the cooldown passed the next generation probes the provider
+					provider.Available = provider.Available && circuit.State == models.CircuitHalfOpen
+				}
+			}
+		}
 		
 		result = append(result, provider)
 	}
@@ -98,8 +274,82 @@ func (r *ProviderRegistry) List() []*models.Provider {
 	return result
 }
 
+// SetCircuitBreaker makes Acquire refuse providers that keep failing
+func (r *ProviderRegistry) SetCircuitBreaker(breaker *CircuitBreaker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.breaker = breaker
+}
+
+// SetFailoverChains sets the fallbacks prompts to each primary provider go to when it can't answer
+func (r *ProviderRegistry) SetFailoverChains(chains map[string][]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failover = chains
+}
+
+// Fallbacks returns the providers to try, in order, when a provider can't answer a prompt
+func (r *ProviderRegistry) Fallbacks(id string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.failover[id])
+}
+
+// RecordResult counts the outcome of a generation for the circuit breaker; a nil err is a
+// success; timeouts count as failures.
+func (r *ProviderRegistry) RecordResult(id string, err error) {
+	r.mu.RLock()
+	breaker := r.breaker
+	r.mu.RUnlock()
+	if breaker != nil {
+		breaker.Record(id, err)
+	}
+}
+
+// SetSecretResolver replaces the resolver of secret references, e.g. to add Vault or AWS Secrets Manager
+func (r *ProviderRegistry) SetSecretResolver(resolver providers.SecretResolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secretResolver = resolver
+}
+
+// SetClaudeExtraArgs updates the extra CLI arguments of the built-in Claude provider, also while
+// the providers file overrides it
+func (r *ProviderRegistry) SetClaudeExtraArgs(extraArgs string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, provider := range []providers.AIProvider{r.providers["claude"], r.shadowed["claude"]} {
+		if claude, ok := provider.(*providers.ClaudeProvider); ok {
+			claude.SetExtraArgs(extraArgs)
+		}
+	}
+}
+
+// Initialized reports whether the built-in providers were registered
+func (r *ProviderRegistry) Initialized() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.initialized
+}
+
 // RegisterDefaultProviders registers the default set of providers
 func (r *ProviderRegistry) RegisterDefaultProviders(cfg *config.Config) error {
+	r.mu.Lock()
+	r.envPolicy = providers.DefaultEnvPolicy.With(cfg.ProviderEnvAllowlist, cfg.ProviderEnvDenylist)
+	envPolicy := r.envPolicy
+	r.sandbox = providers.Sandbox{
+		WorkDir: cfg.ProviderWorkDir,
+		Wrapper: cfg.ProviderWrapper,
+		Limits: providers.ResourceLimits{
+			CPUSeconds: cfg.ProviderLimitCPUSeconds,
+			MemoryMB:   cfg.ProviderLimitMemoryMB,
+			FileSizeMB: cfg.ProviderLimitFileSizeMB,
+			OpenFiles:  cfg.ProviderLimitOpenFiles,
+		},
+	}
+	sandbox := r.sandbox
+	r.mu.Unlock()
+
 	// Register Claude provider
 	claudeProvider := providers.NewClaudeProvider(
 		cfg.ClaudeCLIPath,
@@ -107,53 +357,278 @@ func (r *ProviderRegistry) RegisterDefaultProviders(cfg *config.Config) error {
 		cfg.ClaudeSkipPermissions,
 		cfg.ClaudeExtraArgs,
 	)
+	claudeProvider.SetEnvPolicy(envPolicy.With(providers.ClaudeEnvAllowlist, nil))
+	claudeProvider.SetSessions(cfg.ClaudeResumeSessions)
+	claudeProvider.SetSandbox(sandbox)
 	if err := r.Register(claudeProvider); err != nil {
 		return fmt.Errorf("failed to register Claude provider: %w", err)
 	}
 
+	// Register GitHub Models provider, reusing the gh CLI's login
+	if cfg.GHCLIPath != "" {
+		ghProvider := providers.NewGHModelsProvider(cfg.GHCLIPath, cfg.LogDir, cfg.GHModels)
+		ghProvider.SetEnvPolicy(envPolicy.With(providers.GHEnvAllowlist, nil))
+		ghProvider.SetSandbox(sandbox)
+		if err := r.Register(ghProvider); err != nil {
+			return fmt.Errorf("failed to register GitHub Models provider: %w", err)
+		}
+	}
+
+	// Register the fake provider, answering with synthetic responses for load tests
+	if cfg.EnableFakeProvider {
+		fakeProvider := providers.NewFakeProvider("fake")
+		fakeProvider.DefaultReply = providers.FakeLoadReply(cfg.FakeProviderChunks, cfg.FakeProviderDelay)
+		if err := r.Register(fakeProvider); err != nil {
+			return fmt.Errorf("failed to register fake provider: %w", err)
+		}
+	}
+
 	// Future: Register Gemini provider
 	// geminiProvider := providers.NewGeminiProvider(cfg.GeminiCLIPath, cfg.LogDir)
 	// if err := r.Register(geminiProvider); err != nil {
 	//     return fmt.Errorf("failed to register Gemini provider: %w", err)
 	// }
 
+	r.mu.Lock()
+	r.initialized = true
+	r.mu.Unlock()
+
+	// Providers declared in the providers file are layered on top of the built-ins
+	if cfg.ProvidersFile == "" {
+		return nil
+	}
+
+	go r.watchProvidersFile(cfg.ProvidersFile, cfg.LogDir)
+
+	if _, err := os.Stat(cfg.ProvidersFile); os.IsNotExist(err) {
+		utils.Debug("Providers file %s not found, using built-in providers only", cfg.ProvidersFile)
+		return nil
+	}
+
+	return r.LoadProvidersFile(cfg.ProvidersFile, cfg.LogDir)
+}
+
+// LoadProvidersFile loads providers from a YAML/JSON file, replacing those from any previous load.
+// A provider with the same ID as a built-in one overrides it until it is removed from the file.
+// Changed and removed providers go through Replace and Deregister, so the load returns once their
+// in-flight generations finished or providerDrainTimeout passed.
+func (r *ProviderRegistry) LoadProvidersFile(path, logDir string) error {
+	configs, err := providers.LoadProvidersFile(path)
+	if err != nil {
+		return err
+	}
+	return r.applyProviderConfigs(configs, logDir, path)
+}
+
+// applyProviderConfigs makes configs the providers declared by the providers file at path. No
+// configs unload the file's providers, restoring the built-ins they overrode.
+func (r *ProviderRegistry) applyProviderConfigs(configs []providers.ProviderConfig, logDir, path string) error {
+	r.mu.RLock()
+	envPolicy := r.envPolicy
+	sandbox := r.sandbox
+	resolver := r.secretResolver
+	r.mu.RUnlock()
+
+	// Fetch secrets again on every load so rotated values are picked up
+	if cache, ok := resolver.(interface{ Forget() }); ok {
+		cache.Forget()
+	}
+
+	loaded := make([]providers.AIProvider, 0, len(configs))
+	for i := range configs {
+		if err := configs[i].ResolveSecrets(r.ctx, resolver); err != nil {
+			return err
+		}
+		pc := configs[i]
+		provider, err := providers.NewProviderFromConfig(pc, logDir, envPolicy)
+		if err != nil {
+			return fmt.Errorf("failed to create provider %s: %w", pc.ID, err)
+		}
+		if sandboxed, ok := provider.(providers.SandboxedProvider); ok {
+			sandboxed.SetSandbox(sandbox.With(pc.Sandbox))
+		}
+		loaded = append(loaded, provider)
+	}
+
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	r.mu.Lock()
+	previous := make(map[string]providers.ProviderConfig, len(r.fileConfigs))
+	for _, pc := range r.fileConfigs {
+		previous[pc.ID] = pc
+	}
+	next := make(map[string]bool, len(configs))
+	for _, pc := range configs {
+		next[pc.ID] = true
+	}
+
+	// Providers dropped from the file give way to the built-ins they replaced, if any
+	var replaced []providers.AIProvider
+	var removed []string
+	for id := range r.fileProviderIDs {
+		if next[id] {
+			continue
+		}
+		if builtin, ok := r.shadowed[id]; ok {
+			replaced = append(replaced, builtin)
+			delete(r.shadowed, id)
+		} else {
+			removed = append(removed, id)
+		}
+		delete(r.fileProviderIDs, id)
+	}
+
+	var added []string
+	for i, provider := range loaded {
+		id := provider.GetID()
+		if r.fileProviderIDs[id] {
+			if !reflect.DeepEqual(previous[id], configs[i]) {
+				replaced = append(replaced, provider)
+			}
+			continue
+		}
+		// Built-ins are only set aside, not closed, as they come back once removed from the file
+		if existing, ok := r.providers[id]; ok {
+			r.shadowed[id] = existing
+		}
+		r.providers[id] = provider
+		r.fileProviderIDs[id] = true
+		added = append(added, id)
+	}
+	r.fileConfigs = configs
+	r.mu.Unlock()
+
+	// Cached status may describe the previous definition
+	for _, id := range added {
+		r.invalidateStatus(id)
+	}
+
+	// Changed and removed providers finish their in-flight generations before they are closed
+	ctx, cancel := context.WithTimeout(r.ctx, providerDrainTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, provider := range replaced {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.Replace(ctx, provider); err != nil {
+				utils.Warn("Failed to replace provider %s: %v", provider.GetID(), err)
+			}
+		}()
+	}
+	for _, id := range removed {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.Deregister(ctx, id); err != nil {
+				utils.Warn("Failed to deregister provider %s: %v", id, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	utils.Info("Loaded %d provider(s) from %s", len(loaded), path)
 	return nil
 }
 
-// getCachedStatus retrieves provider status from Redis cache
-func (r *ProviderRegistry) getCachedStatus(providerID string) *providers.ProviderStatus {
-	if r.redisClient == nil {
-		return nil
+// ProviderConfigs returns the declarations loaded from the providers file
+func (r *ProviderRegistry) ProviderConfigs() []providers.ProviderConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]providers.ProviderConfig{}, r.fileConfigs...)
+}
+
+// ReplaceProvidersFile validates configs, writes them to the providers file and loads them
+func (r *ProviderRegistry) ReplaceProvidersFile(path, logDir string, configs []providers.ProviderConfig) error {
+	if err := providers.ValidateProviders(configs); err != nil {
+		return err
 	}
-	
-	key := fmt.Sprintf("provider_status:%s", providerID)
-	data, err := r.redisClient.Get(r.ctx, key).Result()
+	if err := utils.EnsureDirForFile(path); err != nil {
+		return err
+	}
+	if err := providers.WriteProvidersFile(path, configs); err != nil {
+		return err
+	}
+	return r.LoadProvidersFile(path, logDir)
+}
+
+// watchProvidersFile polls the providers file and reloads it when it changes
+func (r *ProviderRegistry) watchProvidersFile(path, logDir string) {
+	last, err := os.Stat(path)
 	if err != nil {
-		return nil
+		last = nil
 	}
-	
-	var status providers.ProviderStatus
-	if err := json.Unmarshal([]byte(data), &status); err != nil {
+
+	ticker := time.NewTicker(providersFilePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			last = r.syncProvidersFile(path, logDir, last)
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// syncProvidersFile reloads the providers file if it changed since last was seen of it (nil if it
+// didn't exist) and returns what is seen of it now. A deleted or renamed file declares no providers,
+// so like an edit emptying it, it restores the built-in providers.
+func (r *ProviderRegistry) syncProvidersFile(path, logDir string, last os.FileInfo) os.FileInfo {
+	info, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) || last == nil {
+			return last
+		}
+		utils.Info("Providers file %s was removed, unloading its providers", path)
+		if err := r.applyProviderConfigs(nil, logDir, path); err != nil {
+			utils.Warn("Failed to unload providers file: %v", err)
+		}
 		return nil
 	}
-	
-	return &status
+	if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+		return last
+	}
+
+	// Keep the current providers if the new file is invalid
+	if err := r.LoadProvidersFile(path, logDir); err != nil {
+		utils.Warn("Failed to reload providers file: %v", err)
+	}
+	return info
 }
 
-// cacheStatus stores provider status in Redis cache
-func (r *ProviderRegistry) cacheStatus(providerID string, status providers.ProviderStatus) {
-	if r.redisClient == nil {
+// invalidateStatus removes a provider's cached status
+func (r *ProviderRegistry) invalidateStatus(providerID string) {
+	if r.statusCache == nil {
 		return
 	}
+	r.statusCache.Delete(r.ctx, providerID)
+}
+
+// getCachedStatus retrieves provider status from the status cache
+func (r *ProviderRegistry) getCachedStatus(providerID string) *providers.ProviderStatus {
+	if r.statusCache == nil {
+		return nil
+	}
 	
-	key := fmt.Sprintf("provider_status:%s", providerID)
-	data, err := json.Marshal(status)
-	if err != nil {
+	status, ok := r.statusCache.Get(r.ctx, providerID)
+	if !ok {
+		return nil
+	}
+	return status
+}
+
+// cacheStatus stores provider status in the status cache
+func (r *ProviderRegistry) cacheStatus(providerID string, status providers.ProviderStatus) {
+	if r.statusCache == nil {
 		return
 	}
 	
 	// Cache for 5 minutes
-	r.redisClient.Set(r.ctx, key, data, 5*time.Minute)
+	r.statusCache.Set(r.ctx, providerID, status, 5*time.Minute)
 }
 
 // GetProviderStatus returns cached status for a specific provider
@@ -193,14 +668,21 @@ func (r *ProviderRegistry) backgroundStatusUpdater() {
 	}
 }
 
-// updateAllProviderStatus updates status for all providers in background
-func (r *ProviderRegistry) updateAllProviderStatus() {
+// snapshot returns a copy of the registered providers keyed by ID
+func (r *ProviderRegistry) snapshot() map[string]providers.AIProvider {
 	r.mu.RLock()
-	providerMap := make(map[string]providers.AIProvider)
+	defer r.mu.RUnlock()
+
+	providerMap := make(map[string]providers.AIProvider, len(r.providers))
 	for id, provider := range r.providers {
 		providerMap[id] = provider
 	}
-	r.mu.RUnlock()
+	return providerMap
+}
+
+// updateAllProviderStatus updates status for all providers in background
+func (r *ProviderRegistry) updateAllProviderStatus() {
+	providerMap := r.snapshot()
 	
 	// Update status for each provider concurrently
 	for id, provider := range providerMap {
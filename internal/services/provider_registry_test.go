@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider is a minimal AIProvider used to exercise registry bookkeeping
+type stubProvider struct {
+	id     string
+	closed bool
+}
+
+func (p *stubProvider) GetID() string          { return p.id }
+func (p *stubProvider) GetName() string        { return p.id }
+func (p *stubProvider) GetDescription() string { return "stub" }
+func (p *stubProvider) IsAvailable() bool      { return true }
+func (p *stubProvider) Close() error           { p.closed = true; return nil }
+
+func (p *stubProvider) GetStatus() providers.ProviderStatus {
+	return providers.ProviderStatus{Available: true, Status: "ready"}
+}
+
+func (p *stubProvider) GetModels() []providers.Model {
+	return nil
+}
+
+func (p *stubProvider) SendPrompt(ctx context.Context, prompt string, chatID int64) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(prompt)), nil
+}
+
+func (p *stubProvider) StreamResponse(ctx context.Context, prompt string, chatID int64, writer io.Writer) error {
+	_, err := io.WriteString(writer, prompt)
+	return err
+}
+
+func TestProviderRegistry_DeregisterDrains(t *testing.T) {
+	registry := NewProviderRegistry(nil)
+	stub := &stubProvider{id: "stub"}
+	require.NoError(t, registry.Register(stub))
+
+	_, release, err := registry.Acquire("stub")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- registry.Deregister(context.Background(), "stub")
+	}()
+
+	// No new generations once deregistration has started
+	assert.Eventually(t, func() bool {
+		_, err := registry.Get("stub")
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+
+	select {
+	case <-done:
+		t.Fatal("Deregister returned before the in-flight generation was released")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	release() // releasing twice is harmless
+	require.NoError(t, <-done)
+	assert.True(t, stub.closed)
+}
+
+func TestProviderRegistry_DeregisterTimeout(t *testing.T) {
+	registry := NewProviderRegistry(nil)
+	stub := &stubProvider{id: "stub"}
+	require.NoError(t, registry.Register(stub))
+
+	_, release, err := registry.Acquire("stub")
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = registry.Deregister(ctx, "stub")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, stub.closed)
+
+	assert.Error(t, registry.Deregister(context.Background(), "stub"))
+}
+
+func TestProviderRegistry_Replace(t *testing.T) {
+	registry := NewProviderRegistry(nil)
+	oldStub := &stubProvider{id: "stub"}
+	newStub := &stubProvider{id: "stub"}
+
+	assert.Error(t, registry.Replace(context.Background(), newStub))
+	require.NoError(t, registry.Register(oldStub))
+
+	_, release, err := registry.Acquire("stub")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- registry.Replace(context.Background(), newStub)
+	}()
+
+	assert.Eventually(t, func() bool {
+		p, err := registry.Get("stub")
+		return err == nil && p == newStub
+	}, time.Second, 5*time.Millisecond)
+
+	release()
+	require.NoError(t, <-done)
+	assert.True(t, oldStub.closed)
+	assert.False(t, newStub.closed)
+}
+
+func TestProviderRegistry_ReloadDrains(t *testing.T) {
+	registry := NewProviderRegistry(nil)
+	builtin := &stubProvider{id: "stub"}
+	require.NoError(t, registry.Register(builtin))
+
+	path := filepath.Join(t.TempDir(), "providers.yaml")
+	load := func(content string) chan error {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		done := make(chan error, 1)
+		go func() {
+			done <- registry.LoadProvidersFile(path, t.TempDir())
+		}()
+		return done
+	}
+	waitDone := func(done chan error) {
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("reload didn't return")
+		}
+	}
+	assertBlocked := func(done chan error) {
+		select {
+		case <-done:
+			t.Fatal("reload returned before the in-flight generation was released")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// Overriding a built-in sets it aside without closing it
+	waitDone(load("providers:\n  - {id: stub, type: http, base_url: http://localhost:9000/a}\n  - {id: relay, type: http, base_url: http://localhost:9000/a}\n"))
+	overridden, err := registry.Get("stub")
+	require.NoError(t, err)
+	assert.NotSame(t, builtin, overridden)
+	assert.False(t, builtin.closed)
+
+	// A changed provider is swapped at once, but the reload waits for its open generation
+	old, release, err := registry.Acquire("relay")
+	require.NoError(t, err)
+	done := load("providers:\n  - {id: stub, type: http, base_url: http://localhost:9000/a}\n  - {id: relay, type: http, base_url: http://localhost:9000/b}\n")
+	assert.Eventually(t, func() bool {
+		p, err := registry.Get("relay")
+		return err == nil && p != old
+	}, time.Second, 5*time.Millisecond)
+	assertBlocked(done)
+	release()
+	waitDone(done)
+
+	// Removing providers restores the built-in and drains the removed ones
+	_, releaseStub, err := registry.Acquire("stub")
+	require.NoError(t, err)
+	_, releaseRelay, err := registry.Acquire("relay")
+	require.NoError(t, err)
+	done = load("providers: []\n")
+	assert.Eventually(t, func() bool {
+		p, err := registry.Get("stub")
+		_, missing := registry.Get("relay")
+		return err == nil && p == builtin && missing != nil
+	}, time.Second, 5*time.Millisecond)
+	assertBlocked(done)
+	releaseStub()
+	assertBlocked(done)
+	releaseRelay()
+	waitDone(done)
+	assert.False(t, builtin.closed)
+}
+
+func TestProviderRegistry_ProvidersFileRemoved(t *testing.T) {
+	registry := NewProviderRegistry(nil)
+	builtin := &stubProvider{id: "stub"}
+	require.NoError(t, registry.Register(builtin))
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "providers.yaml")
+	content := []byte("providers:\n  - {id: stub, type: http, base_url: http://localhost:9000/a}\n  - {id: relay, type: http, base_url: http://localhost:9000/a}\n")
+	assertOverridden := func() {
+		overridden, err := registry.Get("stub")
+		require.NoError(t, err)
+		assert.NotSame(t, builtin, overridden)
+		_, err = registry.Get("relay")
+		assert.NoError(t, err)
+	}
+	assertRestored := func() {
+		restored, err := registry.Get("stub")
+		require.NoError(t, err)
+		assert.Same(t, builtin, restored)
+		_, err = registry.Get("relay")
+		assert.Error(t, err)
+		assert.Empty(t, registry.ProviderConfigs())
+	}
+
+	// A file that didn't exist is loaded once it appears, and left alone while unchanged
+	assert.Nil(t, registry.syncProvidersFile(path, dir, nil))
+	require.NoError(t, os.WriteFile(path, content, 0644))
+	seen := registry.syncProvidersFile(path, dir, nil)
+	require.NotNil(t, seen)
+	assertOverridden()
+	assert.Same(t, seen, registry.syncProvidersFile(path, dir, seen))
+
+	// Deleting the file restores the built-ins
+	require.NoError(t, os.Remove(path))
+	assert.Nil(t, registry.syncProvidersFile(path, dir, seen))
+	assertRestored()
+	assert.False(t, builtin.closed)
+
+	// So does renaming it
+	require.NoError(t, os.WriteFile(path, content, 0644))
+	seen = registry.syncProvidersFile(path, dir, nil)
+	assertOverridden()
+	require.NoError(t, os.Rename(path, path+".bak"))
+	assert.Nil(t, registry.syncProvidersFile(path, dir, seen))
+	assertRestored()
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+)
+
+// ErrQuotaExceeded is returned when a session has used up its daily or monthly prompts
+var ErrQuotaExceeded = errors.New("prompt quota exceeded")
+
+// Counters outlive their period a little so usage can still be read right after a reset
+const (
+	dailyQuotaTTL   = 48 * time.Hour
+	monthlyQuotaTTL = 32 * 24 * time.Hour
+)
+
+// QuotaService counts the prompts of each session per day and month (UTC) in a QuotaStore and
+// enforces the configured quotas; a limit of 0 leaves the period unlimited
+type QuotaService struct {
+	store   QuotaStore
+	mu      sync.RWMutex
+	daily   int64
+	monthly int64
+	now     func() time.Time
+}
+
+func NewQuotaService(store QuotaStore, daily, monthly int64) *QuotaService {
+	return &QuotaService{
+		store:   store,
+		daily:   daily,
+		monthly: monthly,
+		now:     time.Now,
+	}
+}
+
+// SetLimits replaces the daily and monthly quotas, e.g. when the configuration is reloaded
+func (s *QuotaService) SetLimits(daily, monthly int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.daily, s.monthly = daily, monthly
+}
+
+// limits returns the current daily and monthly quotas
+func (s *QuotaService) limits() (daily, monthly int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.daily, s.monthly
+}
+
+// Consume counts n prompts for a session. When that would exceed a quota nothing is counted
+// and ErrQuotaExceeded is returned along with the session's usage.
+func (s *QuotaService) Consume(sessionID string, n int64) (*models.QuotaStatus, error) {
+	ctx := context.Background()
+	now := s.now().UTC()
+	dayKey, monthKey := s.keys(sessionID, now)
+
+	day, month, err := s.store.Add(ctx, dayKey, monthKey, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count prompts: %w", err)
+	}
+
+	daily, monthly := s.limits()
+	if (daily <= 0 || day <= daily) && (monthly <= 0 || month <= monthly) {
+		return s.status(now, day, month), nil
+	}
+
+	// Give the prompts back so refused requests don't use up the quota
+	if _, _, err := s.store.Add(ctx, dayKey, monthKey, -n); err != nil {
+		return nil, fmt.Errorf("failed to release refused prompts: %w", err)
+	}
+	return s.status(now, day-n, month-n), ErrQuotaExceeded
+}
+
+// Status returns a session's prompt usage against its quotas; without a session nothing was used
+func (s *QuotaService) Status(sessionID string) (*models.QuotaStatus, error) {
+	ctx := context.Background()
+	now := s.now().UTC()
+	if sessionID == "" {
+		return s.status(now, 0, 0), nil
+	}
+	dayKey, monthKey := s.keys(sessionID, now)
+
+	day, month, err := s.store.Get(ctx, dayKey, monthKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt usage: %w", err)
+	}
+	return s.status(now, day, month), nil
+}
+
+// status builds the usage report for the day and month containing now
+func (s *QuotaService) status(now time.Time, daily, monthly int64) *models.QuotaStatus {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	dailyLimit, monthlyLimit := s.limits()
+	status := &models.QuotaStatus{
+		Daily:   quotaPeriod(dailyLimit, daily, dayStart.AddDate(0, 0, 1)),
+		Monthly: quotaPeriod(monthlyLimit, monthly, monthStart.AddDate(0, 1, 0)),
+	}
+	status.Exhausted = status.Daily.Remaining == 0 || status.Monthly.Remaining == 0
+	return status
+}
+
+// QuotaKey returns what prompts are counted against: the session, or for requests without one
+// (bearer tokens) the signed-in user. It is empty when neither is known.
+func QuotaKey(sessionID, userID string) string {
+	if sessionID != "" {
+		return sessionID
+	}
+	if userID != "" {
+		// Prefixed so a user can't share the counters of a session with the same ID
+		return "user:" + userID
+	}
+	return ""
+}
+
+// QuotaResetsAt returns when a session that was refused prompts is given new ones
+func QuotaResetsAt(status *models.QuotaStatus) time.Time {
+	if status.Daily.Limit > 0 && (status.Monthly.Limit <= 0 || status.Monthly.Remaining > 0) {
+		return status.Daily.ResetsAt
+	}
+	return status.Monthly.ResetsAt
+}
+
+// quotaPeriod reports usage against a limit, clamping the remaining prompts at 0
+func quotaPeriod(limit, used int64, resetsAt time.Time) models.QuotaPeriod {
+	period := models.QuotaPeriod{Limit: limit, Used: used, Remaining: -1, ResetsAt: resetsAt}
+	if limit > 0 {
+		period.Remaining = max(limit-used, 0)
+	}
+	return period
+}
+
+// keys returns the counters of a session's prompts for the day and month containing now
+func (s *QuotaService) keys(sessionID string, now time.Time) (day, month string) {
+	return fmt.Sprintf("quota:%s:%s", sessionID, now.Format("20060102")),
+		fmt.Sprintf("quota:%s:%s", sessionID, now.Format("200601"))
+}
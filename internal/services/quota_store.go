@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// QuotaStore keeps the prompt counters of each day and month; a counter is kept for the TTL of its
+// period after it was last changed
+type QuotaStore interface {
+	// Add adds n (which may be negative) to a day and a month counter and returns their new values
+	Add(ctx context.Context, dayKey, monthKey string, n int64) (day, month int64, err error)
+	// Get returns a day and a month counter, 0 for counters that don't exist
+	Get(ctx context.Context, dayKey, monthKey string) (day, month int64, err error)
+}
+
+// RedisQuotaStore keeps prompt counters in Redis, shared by every instance
+type RedisQuotaStore struct {
+	redis *redis.Client
+}
+
+func NewRedisQuotaStore(redisClient *redis.Client) *RedisQuotaStore {
+	return &RedisQuotaStore{redis: redisClient}
+}
+
+func (s *RedisQuotaStore) Add(ctx context.Context, dayKey, monthKey string, n int64) (int64, int64, error) {
+	var day, month *redis.IntCmd
+	_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		day = pipe.IncrBy(ctx, dayKey, n)
+		month = pipe.IncrBy(ctx, monthKey, n)
+		pipe.Expire(ctx, dayKey, dailyQuotaTTL)
+		pipe.Expire(ctx, monthKey, monthlyQuotaTTL)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return day.Val(), month.Val(), nil
+}
+
+func (s *RedisQuotaStore) Get(ctx context.Context, dayKey, monthKey string) (int64, int64, error) {
+	values, err := s.redis.MGet(ctx, dayKey, monthKey).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	var used [2]int64
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		if _, err := fmt.Sscan(value.(string), &used[i]); err != nil {
+			return 0, 0, fmt.Errorf("failed to parse prompt usage: %w", err)
+		}
+	}
+	return used[0], used[1], nil
+}
+
+// MemoryQuotaStore keeps prompt counters in this process when Redis is unavailable. Counters are
+// lost on restart and not shared with other instances.
+type MemoryQuotaStore struct {
+	mu       sync.Mutex
+	counters map[string]quotaCounter
+	now      func() time.Time
+}
+
+// quotaCounter is a stored counter and when it expires
+type quotaCounter struct {
+	value     int64
+	expiresAt time.Time
+}
+
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{
+		counters: make(map[string]quotaCounter),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryQuotaStore) Add(ctx context.Context, dayKey, monthKey string, n int64) (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop the counters of periods that are over
+	now := s.now()
+	for key, counter := range s.counters {
+		if !now.Before(counter.expiresAt) {
+			delete(s.counters, key)
+		}
+	}
+
+	day := s.counters[dayKey].value + n
+	month := s.counters[monthKey].value + n
+	s.counters[dayKey] = quotaCounter{value: day, expiresAt: now.Add(dailyQuotaTTL)}
+	s.counters[monthKey] = quotaCounter{value: month, expiresAt: now.Add(monthlyQuotaTTL)}
+	return day, month, nil
+}
+
+func (s *MemoryQuotaStore) Get(ctx context.Context, dayKey, monthKey string) (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(dayKey), s.get(monthKey), nil
+}
+
+// get returns a counter that hasn't expired, 0 otherwise; s.mu must be held
+func (s *MemoryQuotaStore) get(key string) int64 {
+	counter, ok := s.counters[key]
+	if !ok || !s.now().Before(counter.expiresAt) {
+		return 0
+	}
+	return counter.value
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaService_Status(t *testing.T) {
+	s := NewQuotaService(nil, 10, 100)
+	s.now = func() time.Time { return time.Date(2025, time.January, 31, 22, 30, 0, 0, time.UTC) }
+
+	// Without a session nothing is read from Redis
+	status, err := s.Status("")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), status.Daily.Remaining)
+	assert.Equal(t, time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC), status.Daily.ResetsAt)
+	assert.Equal(t, time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC), status.Monthly.ResetsAt)
+	assert.False(t, status.Exhausted)
+
+	status = s.status(s.now(), 10, 40)
+	assert.Equal(t, int64(0), status.Daily.Remaining)
+	assert.Equal(t, int64(60), status.Monthly.Remaining)
+	assert.True(t, status.Exhausted)
+	assert.Equal(t, status.Daily.ResetsAt, QuotaResetsAt(status))
+
+	status = s.status(s.now(), 3, 120)
+	assert.Equal(t, int64(0), status.Monthly.Remaining)
+	assert.Equal(t, status.Monthly.ResetsAt, QuotaResetsAt(status))
+
+	day, month := s.keys("abc", s.now())
+	assert.Equal(t, "quota:abc:20250131", day)
+	assert.Equal(t, "quota:abc:202501", month)
+
+	// Unlimited periods report -1 remaining
+	status = NewQuotaService(nil, 0, 5).status(s.now(), 42, 2)
+	assert.Equal(t, int64(-1), status.Daily.Remaining)
+	assert.Equal(t, int64(3), status.Monthly.Remaining)
+	assert.Equal(t, status.Monthly.ResetsAt, QuotaResetsAt(status))
+}
+
+func TestQuotaService_MemoryStore(t *testing.T) {
+	now := time.Date(2025, time.January, 15, 22, 30, 0, 0, time.UTC)
+	store := NewMemoryQuotaStore()
+	store.now = func() time.Time { return now }
+	s := NewQuotaService(store, 2, 3)
+	s.now = store.now
+
+	status, err := s.Consume("abc", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), status.Daily.Remaining)
+
+	// Over the daily quota nothing is counted
+	status, err = s.Consume("abc", 1)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, int64(2), status.Daily.Used)
+	assert.Equal(t, status.Daily.ResetsAt, QuotaResetsAt(status))
+
+	// Other sessions have their own counters
+	_, err = s.Consume("other", 1)
+	require.NoError(t, err)
+
+	// The next day is a new daily window in the same month, until the monthly quota is used up
+	now = now.Add(2 * time.Hour)
+	_, err = s.Consume("abc", 1)
+	require.NoError(t, err)
+	status, err = s.Consume("abc", 1)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, int64(3), status.Monthly.Used)
+	assert.Equal(t, int64(0), status.Monthly.Remaining)
+	assert.Equal(t, status.Monthly.ResetsAt, QuotaResetsAt(status))
+
+	status, err = s.Status("abc")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.Daily.Used)
+	assert.Equal(t, int64(3), status.Monthly.Used)
+	assert.True(t, status.Exhausted)
+
+	// Expired counters are forgotten
+	now = now.Add(monthlyQuotaTTL)
+	day, month, err := store.Get(context.Background(), "quota:abc:20250116", "quota:abc:202501")
+	require.NoError(t, err)
+	assert.Zero(t, day)
+	assert.Zero(t, month)
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Timeout of each readiness check, kept below the usual probe timeout
+const readinessCheckTimeout = 2 * time.Second
+
+// ReadinessService checks the dependencies an instance needs to serve traffic
+type ReadinessService struct {
+	db           database.Store
+	redisClient  *redis.Client
+	storeBackend string
+	registry     *ProviderRegistry
+}
+
+// NewReadinessService creates a readiness service. Redis is optional when sessions are kept
+// in memory (storeBackend is StoreBackendMemory), since the instance works without it.
+func NewReadinessService(db database.Store, redisClient *redis.Client, storeBackend string, registry *ProviderRegistry) *ReadinessService {
+	return &ReadinessService{db: db, redisClient: redisClient, storeBackend: storeBackend, registry: registry}
+}
+
+// Check runs every check; the instance is ready when all required components are healthy
+func (s *ReadinessService) Check(ctx context.Context) models.Readiness {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	readiness := models.Readiness{
+		Ready: true,
+		Components: []models.ReadinessCheck{
+			{DependencyStatus: checkDatabase(ctx, s.db)},
+			{DependencyStatus: checkRedis(ctx, s.redisClient), Optional: s.storeBackend == StoreBackendMemory},
+			{DependencyStatus: s.providersStatus()},
+		},
+	}
+	for _, component := range readiness.Components {
+		if !component.Healthy && !component.Optional {
+			readiness.Ready = false
+		}
+	}
+	return readiness
+}
+
+// providersStatus checks that the provider registry finished registering the built-in providers.
+// Provider availability isn't checked: a missing CLI shouldn't take the whole instance out of rotation.
+func (s *ReadinessService) providersStatus() models.DependencyStatus {
+	status := models.DependencyStatus{Name: "providers"}
+	switch {
+	case s.registry == nil || !s.registry.Initialized():
+		status.Error = "not initialized"
+	case len(s.registry.snapshot()) == 0:
+		status.Error = "no providers registered"
+	default:
+		status.Healthy = true
+	}
+	return status
+}
+
+// checkDatabase checks that the database answers a trivial query
+func checkDatabase(ctx context.Context, db database.Store) models.DependencyStatus {
+	status := models.DependencyStatus{Name: "database"}
+	if db == nil {
+		status.Error = "not configured"
+		return status
+	}
+	status.Name = string(db.Dialect())
+
+	start := time.Now()
+	var one int
+	if err := db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Healthy = true
+	status.LatencyMs = time.Since(start).Milliseconds()
+	return status
+}
+
+// checkRedis pings Redis
+func checkRedis(ctx context.Context, redisClient *redis.Client) models.DependencyStatus {
+	status := models.DependencyStatus{Name: "redis"}
+	if redisClient == nil {
+		status.Error = "not configured"
+		return status
+	}
+
+	start := time.Now()
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Healthy = true
+	status.LatencyMs = time.Since(start).Milliseconds()
+	return status
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessService_Check(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Nothing listens on port 1, so pings fail right away
+	unreachable := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	defer unreachable.Close()
+
+	registry := NewProviderRegistry(nil)
+	ctx := context.Background()
+
+	readiness := NewReadinessService(db, unreachable, StoreBackendRedis, registry).Check(ctx)
+	assert.False(t, readiness.Ready)
+	require.Len(t, readiness.Components, 3)
+	assert.True(t, readiness.Components[0].Healthy, "database")
+	assert.Equal(t, "redis", readiness.Components[1].Name)
+	assert.False(t, readiness.Components[1].Healthy)
+	assert.NotEmpty(t, readiness.Components[1].Error)
+	assert.Equal(t, "providers", readiness.Components[2].Name)
+	assert.Equal(t, "not initialized", readiness.Components[2].Error)
+
+	require.NoError(t, registry.Register(&stubProvider{id: "stub"}))
+	registry.initialized = true
+
+	// With sessions kept in memory, Redis is optional
+	readiness = NewReadinessService(db, unreachable, StoreBackendMemory, registry).Check(ctx)
+	assert.True(t, readiness.Ready)
+	assert.True(t, readiness.Components[1].Optional)
+	assert.True(t, readiness.Components[2].Healthy)
+
+	db.Close()
+	readiness = NewReadinessService(db, unreachable, StoreBackendMemory, registry).Check(ctx)
+	assert.False(t, readiness.Ready)
+	assert.NotEmpty(t, readiness.Components[0].Error)
+}
@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/jobs"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/utils"
+)
+
+// Retention rules, in the order they are applied
+const (
+	RetentionIdleChats  = "idle_chats"  // move idle chats to the trash
+	RetentionMessageCap = "message_cap" // delete the oldest messages of long chats
+	RetentionLogs       = "logs"        // delete old chat log files
+)
+
+// What started a retention run
+const (
+	RetentionTriggerSchedule = "schedule"
+	RetentionTriggerManual   = "manual"
+)
+
+// ErrRetentionRunning is returned when a retention run is requested while one is in progress
+var ErrRetentionRunning = apperrors.Conflict("a retention run is already in progress")
+
+// RetentionService applies the retention rules on an interval and on demand, keeping metrics per rule
+type RetentionService struct {
+	chatService *ChatService
+	policy      models.RetentionPolicy
+	logDir      string
+
+	running sync.Mutex // held for the duration of a run
+
+	mu      sync.Mutex
+	metrics map[string]*models.RetentionRuleMetrics
+	lastRun *models.RetentionRun
+}
+
+// NewRetentionService creates a retention service; chat log files are looked for in logDir
+func NewRetentionService(chatService *ChatService, policy models.RetentionPolicy, logDir string) *RetentionService {
+	s := &RetentionService{
+		chatService: chatService,
+		policy:      policy,
+		logDir:      logDir,
+		metrics:     make(map[string]*models.RetentionRuleMetrics),
+	}
+	for _, rule := range s.rules() {
+		s.metrics[rule] = &models.RetentionRuleMetrics{Rule: rule}
+	}
+	return s
+}
+
+// rules returns the enabled rules in the order they are applied
+func (s *RetentionService) rules() []string {
+	var rules []string
+	if s.policy.IdleChatDays > 0 {
+		rules = append(rules, RetentionIdleChats)
+	}
+	if s.policy.MaxMessagesPerChat > 0 {
+		rules = append(rules, RetentionMessageCap)
+	}
+	if s.policy.LogDays > 0 {
+		rules = append(rules, RetentionLogs)
+	}
+	return rules
+}
+
+// Enabled reports whether any retention rule is configured
+func (s *RetentionService) Enabled() bool {
+	return len(s.rules()) > 0
+}
+
+// Job applies the rules on the configured interval; it is exclusive so instances sharing a
+// database don't apply them twice. A manual run in progress skips the scheduled one.
+func (s *RetentionService) Job() jobs.Job {
+	return jobs.Job{
+		Name:       "retention",
+		Interval:   time.Duration(s.policy.IntervalSeconds) * time.Second,
+		RunAtStart: true,
+		Exclusive:  true,
+		Run: func(ctx context.Context) error {
+			if _, err := s.Run(ctx, RetentionTriggerSchedule, s.policy.DryRun); err != nil && !errors.Is(err, ErrRetentionRunning) {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// Run applies every enabled rule once; with dryRun nothing is removed and the results count what
+// would have been. A rule that fails doesn't stop the others, its error is reported in the result.
+func (s *RetentionService) Run(ctx context.Context, trigger string, dryRun bool) (*models.RetentionRun, error) {
+	if !s.running.TryLock() {
+		return nil, ErrRetentionRunning
+	}
+	defer s.running.Unlock()
+
+	run := &models.RetentionRun{StartedAt: time.Now(), Trigger: trigger, DryRun: dryRun, Rules: []models.RetentionRuleResult{}}
+	for _, rule := range s.rules() {
+		start := time.Now()
+		affected, err := s.apply(ctx, rule, dryRun)
+		result := models.RetentionRuleResult{Rule: rule, Affected: affected, DurationMs: time.Since(start).Milliseconds()}
+		if err != nil {
+			result.Error = err.Error()
+			utils.Error("Retention rule %s failed: %v", rule, err)
+		} else if affected > 0 {
+			if dryRun {
+				utils.Info("Retention rule %s would remove %d %s (dry run)", rule, affected, retentionUnit(rule))
+			} else {
+				utils.Info("Retention rule %s removed %d %s", rule, affected, retentionUnit(rule))
+			}
+		}
+		run.Rules = append(run.Rules, result)
+		s.record(run.StartedAt, dryRun, result)
+	}
+
+	s.mu.Lock()
+	s.lastRun = run
+	s.mu.Unlock()
+	return run, nil
+}
+
+// apply runs one rule
+func (s *RetentionService) apply(ctx context.Context, rule string, dryRun bool) (int64, error) {
+	switch rule {
+	case RetentionIdleChats:
+		return s.chatService.TrashIdleChats(ctx, time.Now().AddDate(0, 0, -s.policy.IdleChatDays), dryRun)
+	case RetentionMessageCap:
+		return s.chatService.CapChatMessages(ctx, s.policy.MaxMessagesPerChat, dryRun)
+	case RetentionLogs:
+		return purgeChatLogFiles(s.logDir, time.Now().AddDate(0, 0, -s.policy.LogDays), dryRun)
+	}
+	return 0, fmt.Errorf("unknown retention rule %q", rule)
+}
+
+// retentionUnit names what a rule counts, for log messages
+func retentionUnit(rule string) string {
+	switch rule {
+	case RetentionIdleChats:
+		return "chats"
+	case RetentionMessageCap:
+		return "messages"
+	default:
+		return "files"
+	}
+}
+
+// record adds the result of a rule to its metrics
+func (s *RetentionService) record(at time.Time, dryRun bool, result models.RetentionRuleResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.metrics[result.Rule]
+	m.Runs++
+	if dryRun {
+		m.DryRuns++
+	} else {
+		m.Removed += result.Affected
+	}
+	if result.Error != "" {
+		m.Errors++
+	}
+	m.LastRunAt = &at
+	m.LastAffected = result.Affected
+	m.LastDurationMs = result.DurationMs
+	m.LastError = result.Error
+}
+
+// Status returns the policy, the metrics of each enabled rule and the latest run
+func (s *RetentionService) Status() *models.RetentionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &models.RetentionStatus{Policy: s.policy, Metrics: []models.RetentionRuleMetrics{}, LastRun: s.lastRun}
+	for _, rule := range s.rules() {
+		status.Metrics = append(status.Metrics, *s.metrics[rule])
+	}
+	return status
+}
+
+// FormatRetentionMetrics renders retention metrics in the OpenMetrics text format
+func FormatRetentionMetrics(metrics []models.RetentionRuleMetrics) string {
+	var b strings.Builder
+
+	b.WriteString("# TYPE aigwhub_retention_runs counter\n")
+	b.WriteString("# HELP aigwhub_retention_runs Runs of a retention rule, including dry runs.\n")
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "aigwhub_retention_runs_total{rule=%q} %d\n", m.Rule, m.Runs)
+	}
+
+	b.WriteString("# TYPE aigwhub_retention_errors counter\n")
+	b.WriteString("# HELP aigwhub_retention_errors Runs of a retention rule that failed.\n")
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "aigwhub_retention_errors_total{rule=%q} %d\n", m.Rule, m.Errors)
+	}
+
+	b.WriteString("# TYPE aigwhub_retention_removed counter\n")
+	b.WriteString("# HELP aigwhub_retention_removed Chats, messages or files removed by a retention rule.\n")
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "aigwhub_retention_removed_total{rule=%q} %d\n", m.Rule, m.Removed)
+	}
+
+	b.WriteString("# TYPE aigwhub_retention_last_duration_seconds gauge\n")
+	b.WriteString("# UNIT aigwhub_retention_last_duration_seconds seconds\n")
+	b.WriteString("# HELP aigwhub_retention_last_duration_seconds Duration of the latest run of a retention rule.\n")
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "aigwhub_retention_last_duration_seconds{rule=%q} %.3f\n", m.Rule, float64(m.LastDurationMs)/1000)
+	}
+
+	b.WriteString("# EOF\n")
+	return b.String()
+}
+
+// purgeChatLogFiles removes the chat log files (chat_<id>.log) under dir that weren't written to
+// since before and returns how many were removed, or would be with dryRun
+func purgeChatLogFiles(dir string, before time.Time, dryRun bool) (int64, error) {
+	var count int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasPrefix(d.Name(), "chat_") || filepath.Ext(d.Name()) != ".log" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().Before(before) {
+			return nil
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("failed to purge chat logs: %w", err)
+	}
+	return count, nil
+}
+
+// TrashIdleChats moves the chats that haven't changed since before to the trash, where they are
+// purged after the deleted chat retention period, and returns how many were moved, or would be with dryRun
+func (s *ChatService) TrashIdleChats(ctx context.Context, before time.Time, dryRun bool) (int64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM chats WHERE deleted_at IS NULL AND updated_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find idle chats: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan idle chat: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find idle chats: %w", err)
+	}
+	if dryRun {
+		return int64(len(ids)), nil
+	}
+
+	var count int64
+	now := time.Now()
+	for _, id := range ids {
+		// A chat that received a message since it was found is no longer idle
+		query := `UPDATE chats SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL AND updated_at < ?`
+		result, err := s.db.ExecContext(ctx, query, now, id, before)
+		if err != nil {
+			return count, fmt.Errorf("failed to trash idle chat %d: %w", id, err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			count++
+			s.notify(ChatDeleted, id)
+		}
+	}
+	return count, nil
+}
+
+// CapChatMessages deletes the oldest messages of every chat with more than max, with their
+// feedback, and returns how many were deleted, or would be with dryRun
+func (s *ChatService) CapChatMessages(ctx context.Context, max int, dryRun bool) (int64, error) {
+	query := `SELECT chat_id, COUNT(*) FROM messages GROUP BY chat_id HAVING COUNT(*) > ?`
+	rows, err := s.db.QueryContext(ctx, query, max)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find long chats: %w", err)
+	}
+	excess := make(map[int64]int64)
+	var chatIDs []int64
+	for rows.Next() {
+		var chatID, count int64
+		if err := rows.Scan(&chatID, &count); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan long chat: %w", err)
+		}
+		excess[chatID] = count - int64(max)
+		chatIDs = append(chatIDs, chatID)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find long chats: %w", err)
+	}
+
+	var total int64
+	for _, chatID := range chatIDs {
+		if dryRun {
+			total += excess[chatID]
+			continue
+		}
+		n, err := s.deleteOldestMessages(ctx, chatID, excess[chatID])
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n > 0 {
+			s.notify(ChatMessage, chatID)
+		}
+	}
+	return total, nil
+}
+
+// deleteOldestMessages deletes the n oldest messages of a chat
+func (s *ChatService) deleteOldestMessages(ctx context.Context, chatID, n int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin capping chat %d: %w", chatID, err)
+	}
+	defer tx.Rollback()
+
+	var lastID int64
+	query := `SELECT id FROM messages WHERE chat_id = ? ORDER BY id LIMIT 1 OFFSET ?`
+	if err := tx.QueryRowContext(ctx, query, chatID, n-1).Scan(&lastID); err != nil {
+		return 0, fmt.Errorf("failed to find oldest messages of chat %d: %w", chatID, err)
+	}
+
+	// Dependent rows are updated explicitly so deleting doesn't rely on foreign key enforcement
+	deleted := `SELECT id FROM messages WHERE chat_id = ? AND id <= ?`
+	if _, err := tx.ExecContext(ctx, `DELETE FROM message_feedback WHERE message_id IN (`+deleted+`)`, chatID, lastID); err != nil {
+		return 0, fmt.Errorf("failed to delete message feedback: %w", err)
+	}
+	for _, table := range []string{"attachments", "scheduled_prompt_runs", "usage_records"} {
+		if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET message_id = NULL WHERE message_id IN (`+deleted+`)`, chatID, lastID); err != nil {
+			return 0, fmt.Errorf("failed to detach %s: %w", table, err)
+		}
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE chat_id = ? AND id <= ?`, chatID, lastID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete messages of chat %d: %w", chatID, err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted messages: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit capping chat %d: %w", chatID, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionService_Run(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := NewChatService(db)
+	ctx := context.Background()
+
+	idle, err := chatService.CreateChat(ctx, "Idle", "claude")
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE chats SET updated_at = ? WHERE id = ?`, time.Now().AddDate(0, 0, -40), idle.ID)
+	require.NoError(t, err)
+
+	long, err := chatService.CreateChat(ctx, "Long", "claude")
+	require.NoError(t, err)
+	var first *models.Message
+	for i := 0; i < 5; i++ {
+		msg, err := chatService.AddMessage(ctx, long.ID, "user", fmt.Sprintf("message %d", i))
+		require.NoError(t, err)
+		if first == nil {
+			first = msg
+		}
+	}
+	answer, err := chatService.AddProviderMessage(ctx, long.ID, "assistant", "answer", "claude")
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO message_feedback (message_id, chat_id, rating, comment, created_at, updated_at) VALUES (?, ?, 1, '', ?, ?)`, first.ID, long.ID, time.Now(), time.Now())
+	require.NoError(t, err)
+
+	logDir := t.TempDir()
+	oldLog := filepath.Join(logDir, "claude", "chat_1.log")
+	recentLog := filepath.Join(logDir, "claude", "chat_2.log")
+	systemLog := filepath.Join(logDir, "system.log")
+	for _, path := range []string{oldLog, recentLog, systemLog} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("USER: hi\n"), 0644))
+	}
+	old := time.Now().AddDate(0, 0, -10)
+	require.NoError(t, os.Chtimes(oldLog, old, old))
+	require.NoError(t, os.Chtimes(systemLog, old, old))
+
+	service := NewRetentionService(chatService, models.RetentionPolicy{
+		IdleChatDays: 30, MaxMessagesPerChat: 4, LogDays: 7, IntervalSeconds: 3600, DryRun: true,
+	}, logDir)
+	require.True(t, service.Enabled())
+
+	// A dry run only counts
+	run, err := service.Run(ctx, RetentionTriggerManual, true)
+	require.NoError(t, err)
+	assert.True(t, run.DryRun)
+	require.Len(t, run.Rules, 3)
+	assert.Equal(t, models.RetentionRuleResult{Rule: RetentionIdleChats, Affected: 1, DurationMs: run.Rules[0].DurationMs}, run.Rules[0])
+	assert.Equal(t, int64(2), run.Rules[1].Affected)
+	assert.Equal(t, int64(1), run.Rules[2].Affected)
+	_, err = chatService.GetChat(ctx, idle.ID)
+	assert.NoError(t, err)
+	assert.FileExists(t, oldLog)
+	count, err := chatService.CountMessages(ctx, long.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), count)
+
+	run, err = service.Run(ctx, RetentionTriggerManual, false)
+	require.NoError(t, err)
+	for _, result := range run.Rules {
+		assert.Empty(t, result.Error, result.Rule)
+	}
+	_, err = chatService.GetChat(ctx, idle.ID)
+	assert.ErrorIs(t, err, ErrChatNotFound, "idle chats are moved to the trash")
+	messages, err := chatService.GetMessages(ctx, long.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 4)
+	assert.Equal(t, "message 2", messages[0].Content)
+	assert.Equal(t, answer.ID, messages[3].ID)
+	var feedback int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM message_feedback`).Scan(&feedback))
+	assert.Zero(t, feedback)
+	assert.NoFileExists(t, oldLog)
+	assert.FileExists(t, recentLog)
+	assert.FileExists(t, systemLog)
+
+	// Nothing is left to remove
+	run, err = service.Run(ctx, RetentionTriggerSchedule, false)
+	require.NoError(t, err)
+	for _, result := range run.Rules {
+		assert.Zero(t, result.Affected, result.Rule)
+	}
+
+	status := service.Status()
+	assert.Equal(t, run, status.LastRun)
+	require.Len(t, status.Metrics, 3)
+	assert.Equal(t, RetentionMessageCap, status.Metrics[1].Rule)
+	assert.Equal(t, int64(3), status.Metrics[1].Runs)
+	assert.Equal(t, int64(1), status.Metrics[1].DryRuns)
+	assert.Equal(t, int64(2), status.Metrics[1].Removed)
+	assert.NotNil(t, status.Metrics[1].LastRunAt)
+
+	metrics := FormatRetentionMetrics(status.Metrics)
+	assert.Contains(t, metrics, `aigwhub_retention_removed_total{rule="message_cap"} 2`)
+	assert.Contains(t, metrics, `aigwhub_retention_runs_total{rule="logs"} 3`)
+	assert.True(t, strings.HasSuffix(metrics, "# EOF\n"))
+}
+
+func TestRetentionService_OneRunAtATime(t *testing.T) {
+	service := NewRetentionService(nil, models.RetentionPolicy{IntervalSeconds: 3600}, t.TempDir())
+	assert.False(t, service.Enabled())
+
+	service.running.Lock()
+	_, err := service.Run(context.Background(), RetentionTriggerManual, false)
+	assert.ErrorIs(t, err, ErrRetentionRunning)
+	service.running.Unlock()
+
+	run, err := service.Run(context.Background(), RetentionTriggerManual, false)
+	require.NoError(t, err)
+	assert.Empty(t, run.Rules)
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"slices"
+	"time"
+
+	"ai-gateway-hub/internal/providers"
+)
+
+// RetryPolicy decides whether a prompt that failed with a transient error is sent to the provider
+// again, and after how long. The zero policy never retries.
+type RetryPolicy struct {
+	MaxAttempts int           // attempts including the first; 1 or less disables retries
+	Backoff     time.Duration // wait before the first retry, doubled for each next one
+	MaxBackoff  time.Duration // longest wait between attempts
+	RetryOn     []string      // retried error classes, see providers.ClassifyError
+}
+
+// Retries reports whether a prompt that failed with err on the given attempt (from 1) is retried
+func (p RetryPolicy) Retries(attempt int, err error) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	class := providers.ClassifyError(err)
+	return class != "" && slices.Contains(p.RetryOn, class)
+}
+
+// Delay returns how long to wait before retrying after the given failed attempt (from 1)
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxBackoff)
+}
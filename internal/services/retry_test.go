@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/providers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Retries(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 4 * time.Second, RetryOn: []string{providers.ErrorClassNetwork, providers.ErrorClassServer}}
+	unavailable := &providers.StatusError{Provider: "gemini", StatusCode: 503, Status: "503 Service Unavailable"}
+
+	assert.True(t, policy.Retries(1, unavailable))
+	assert.True(t, policy.Retries(2, fmt.Errorf("request failed: %w", syscall.ECONNREFUSED)))
+	assert.False(t, policy.Retries(3, unavailable), "attempts are used up")
+	assert.False(t, policy.Retries(1, &providers.StatusError{Provider: "gemini", StatusCode: 429, Status: "429 Too Many Requests"}), "rate limits aren't in RetryOn")
+	assert.False(t, policy.Retries(1, &providers.StatusError{Provider: "gemini", StatusCode: 400, Status: "400 Bad Request"}))
+	assert.False(t, policy.Retries(1, context.DeadlineExceeded))
+	assert.False(t, policy.Retries(1, errors.New("invalid prompt")))
+	assert.False(t, RetryPolicy{}.Retries(1, unavailable), "the zero policy never retries")
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 6, Backoff: 500 * time.Millisecond, MaxBackoff: 3 * time.Second}
+	assert.Equal(t, 500*time.Millisecond, policy.Delay(1))
+	assert.Equal(t, time.Second, policy.Delay(2))
+	assert.Equal(t, 2*time.Second, policy.Delay(3))
+	assert.Equal(t, 3*time.Second, policy.Delay(4), "capped at MaxBackoff")
+	assert.Equal(t, 3*time.Second, policy.Delay(5))
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ai-gateway-hub/internal/database"
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+)
+
+// ErrScheduleNotFound is returned for unknown scheduled prompts
+var ErrScheduleNotFound = apperrors.NotFound("scheduled prompt not found")
+
+// Maximum length of a scheduled prompt's name
+const MaxScheduleNameLength = 100
+
+// Columns selected for a scheduled prompt, in the order scanScheduledPrompt expects
+const scheduledPromptColumns = "id, name, chat_id, provider, model, prompt, cron, timezone, enabled, next_run_at, last_run_at, created_at, updated_at"
+
+// Columns selected for a scheduled prompt run, in the order scanScheduledPromptRun expects
+const scheduledPromptRunColumns = "id, scheduled_prompt_id, chat_id, status, error, message_id, started_at, finished_at"
+
+// ScheduleService stores scheduled prompts and their execution history
+type ScheduleService struct {
+	db database.Store
+}
+
+func NewScheduleService(db database.Store) *ScheduleService {
+	return &ScheduleService{
+		db: db,
+	}
+}
+
+// ValidateScheduledPrompt checks the fields users set and normalizes whitespace
+func ValidateScheduledPrompt(p *models.ScheduledPrompt) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Prompt = strings.TrimSpace(p.Prompt)
+	p.Cron = strings.TrimSpace(p.Cron)
+	p.Timezone = strings.TrimSpace(p.Timezone)
+
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if utf8.RuneCountInString(p.Name) > MaxScheduleNameLength {
+		return fmt.Errorf("name must be at most %d characters", MaxScheduleNameLength)
+	}
+	if p.Prompt == "" {
+		return fmt.Errorf("prompt is required")
+	}
+	if p.Provider == "" {
+		return fmt.Errorf("provider is required")
+	}
+	if _, err := nextScheduledRun(p, time.Now()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// nextScheduledRun returns the next run after t in the prompt's timezone, in UTC
+func nextScheduledRun(p *models.ScheduledPrompt, t time.Time) (time.Time, error) {
+	schedule, err := ParseCron(p.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	location := time.Local
+	if p.Timezone != "" {
+		if location, err = time.LoadLocation(p.Timezone); err != nil {
+			return time.Time{}, fmt.Errorf("invalid timezone %q", p.Timezone)
+		}
+	}
+
+	next := schedule.Next(t.In(location))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never matches", p.Cron)
+	}
+	return next.UTC(), nil
+}
+
+// Create stores a new scheduled prompt and computes its first run
+func (s *ScheduleService) Create(p *models.ScheduledPrompt) (*models.ScheduledPrompt, error) {
+	if err := ValidateScheduledPrompt(p); err != nil {
+		return nil, err
+	}
+	next, err := s.nextRunFor(p, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	query := `
+		INSERT INTO scheduled_prompts (name, chat_id, provider, model, prompt, cron, timezone, enabled, next_run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + scheduledPromptColumns
+	created, err := scanScheduledPrompt(s.db.QueryRow(query,
+		p.Name, p.ChatID, p.Provider, p.Model, p.Prompt, p.Cron, p.Timezone, p.Enabled, next, now, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scheduled prompt: %w", err)
+	}
+	return created, nil
+}
+
+// Get retrieves a scheduled prompt
+func (s *ScheduleService) Get(id int64) (*models.ScheduledPrompt, error) {
+	query := `SELECT ` + scheduledPromptColumns + ` FROM scheduled_prompts WHERE id = ?`
+	p, err := scanScheduledPrompt(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled prompt: %w", err)
+	}
+	return p, nil
+}
+
+// List returns every scheduled prompt ordered by name
+func (s *ScheduleService) List() ([]*models.ScheduledPrompt, error) {
+	return s.list(`SELECT ` + scheduledPromptColumns + ` FROM scheduled_prompts ORDER BY name, id`)
+}
+
+// Due returns enabled prompts whose next run is not after now; prompts of deleted chats wait
+// until the chat is restored or purged
+func (s *ScheduleService) Due(now time.Time) ([]*models.ScheduledPrompt, error) {
+	query := `
+		SELECT ` + scheduledPromptColumns + `
+		FROM scheduled_prompts
+		WHERE enabled = ? AND next_run_at IS NOT NULL AND next_run_at <= ?
+			AND chat_id IN (SELECT id FROM chats WHERE deleted_at IS NULL)
+		ORDER BY next_run_at
+	`
+	return s.list(query, true, now.UTC())
+}
+
+// Update saves the user-editable fields of a scheduled prompt and recomputes its next run
+func (s *ScheduleService) Update(p *models.ScheduledPrompt) (*models.ScheduledPrompt, error) {
+	if err := ValidateScheduledPrompt(p); err != nil {
+		return nil, err
+	}
+	next, err := s.nextRunFor(p, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE scheduled_prompts
+		SET name = ?, chat_id = ?, provider = ?, model = ?, prompt = ?, cron = ?, timezone = ?, enabled = ?, next_run_at = ?, updated_at = ?
+		WHERE id = ?
+		RETURNING ` + scheduledPromptColumns
+	updated, err := scanScheduledPrompt(s.db.QueryRow(query,
+		p.Name, p.ChatID, p.Provider, p.Model, p.Prompt, p.Cron, p.Timezone, p.Enabled, next, time.Now(), p.ID))
+	if err == sql.ErrNoRows {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update scheduled prompt: %w", err)
+	}
+	return updated, nil
+}
+
+// Delete removes a scheduled prompt and its run history
+func (s *ScheduleService) Delete(id int64) error {
+	if _, err := s.db.Exec(`DELETE FROM scheduled_prompt_runs WHERE scheduled_prompt_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete scheduled prompt runs: %w", err)
+	}
+	result, err := s.db.Exec(`DELETE FROM scheduled_prompts WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete scheduled prompt: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+// Claim advances a due prompt's next run past now. It returns false when another instance
+// already claimed this run, so each run executes once even with several hub instances.
+// Runs missed while the hub was down are caught up with a single run.
+func (s *ScheduleService) Claim(p *models.ScheduledPrompt, now time.Time) (bool, error) {
+	next, err := nextScheduledRun(p, now)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		UPDATE scheduled_prompts
+		SET next_run_at = ?, last_run_at = ?
+		WHERE id = ? AND enabled = ? AND next_run_at <= ?
+	`
+	result, err := s.db.Exec(query, next, now.UTC(), p.ID, true, now.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim scheduled prompt: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim scheduled prompt: %w", err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+
+	p.NextRunAt = &next
+	p.LastRunAt = &now
+	return true, nil
+}
+
+// MarkRun records a run started outside the schedule (e.g. "run now")
+func (s *ScheduleService) MarkRun(p *models.ScheduledPrompt, now time.Time) error {
+	if _, err := s.db.Exec(`UPDATE scheduled_prompts SET last_run_at = ? WHERE id = ?`, now.UTC(), p.ID); err != nil {
+		return fmt.Errorf("failed to update scheduled prompt: %w", err)
+	}
+	p.LastRunAt = &now
+	return nil
+}
+
+// StartRun records that a run started
+func (s *ScheduleService) StartRun(p *models.ScheduledPrompt) (*models.ScheduledPromptRun, error) {
+	query := `
+		INSERT INTO scheduled_prompt_runs (scheduled_prompt_id, chat_id, status, started_at)
+		VALUES (?, ?, ?, ?)
+		RETURNING ` + scheduledPromptRunColumns
+	run, err := scanScheduledPromptRun(s.db.QueryRow(query, p.ID, p.ChatID, models.ScheduledRunRunning, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record scheduled prompt run: %w", err)
+	}
+	return run, nil
+}
+
+// FinishRun records a run's outcome: the assistant message on success, the error otherwise
+func (s *ScheduleService) FinishRun(run *models.ScheduledPromptRun, messageID *int64, runErr error) error {
+	now := time.Now()
+	run.Status = models.ScheduledRunCompleted
+	run.MessageID = messageID
+	run.FinishedAt = &now
+	if runErr != nil {
+		run.Status = models.ScheduledRunFailed
+		run.Error = runErr.Error()
+	}
+
+	query := `UPDATE scheduled_prompt_runs SET status = ?, error = ?, message_id = ?, finished_at = ? WHERE id = ?`
+	if _, err := s.db.Exec(query, run.Status, run.Error, messageID, now, run.ID); err != nil {
+		return fmt.Errorf("failed to record scheduled prompt result: %w", err)
+	}
+	return nil
+}
+
+// ListRuns returns a prompt's most recent runs, newest first
+func (s *ScheduleService) ListRuns(scheduleID int64, limit int) ([]*models.ScheduledPromptRun, error) {
+	query := `
+		SELECT ` + scheduledPromptRunColumns + `
+		FROM scheduled_prompt_runs
+		WHERE scheduled_prompt_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`
+	rows, err := s.db.Query(query, scheduleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled prompt runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []*models.ScheduledPromptRun{}
+	for rows.Next() {
+		run, err := scanScheduledPromptRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled prompt run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// nextRunFor returns the next run of an enabled prompt, or nil when it is paused
+func (s *ScheduleService) nextRunFor(p *models.ScheduledPrompt, now time.Time) (*time.Time, error) {
+	if !p.Enabled {
+		return nil, nil
+	}
+	next, err := nextScheduledRun(p, now)
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// list runs a scheduled prompt query
+func (s *ScheduleService) list(query string, args ...any) ([]*models.ScheduledPrompt, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled prompts: %w", err)
+	}
+	defer rows.Close()
+
+	prompts := []*models.ScheduledPrompt{}
+	for rows.Next() {
+		p, err := scanScheduledPrompt(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled prompt: %w", err)
+		}
+		prompts = append(prompts, p)
+	}
+	return prompts, rows.Err()
+}
+
+// scanScheduledPrompt reads a scheduled prompt selected with scheduledPromptColumns
+func scanScheduledPrompt(row rowScanner) (*models.ScheduledPrompt, error) {
+	var p models.ScheduledPrompt
+	var nextRunAt, lastRunAt sql.NullTime
+	err := row.Scan(
+		&p.ID,
+		&p.Name,
+		&p.ChatID,
+		&p.Provider,
+		&p.Model,
+		&p.Prompt,
+		&p.Cron,
+		&p.Timezone,
+		&p.Enabled,
+		&nextRunAt,
+		&lastRunAt,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if nextRunAt.Valid {
+		p.NextRunAt = &nextRunAt.Time
+	}
+	if lastRunAt.Valid {
+		p.LastRunAt = &lastRunAt.Time
+	}
+	return &p, nil
+}
+
+// scanScheduledPromptRun reads a run selected with scheduledPromptRunColumns
+func scanScheduledPromptRun(row rowScanner) (*models.ScheduledPromptRun, error) {
+	var run models.ScheduledPromptRun
+	var messageID sql.NullInt64
+	var finishedAt sql.NullTime
+	err := row.Scan(
+		&run.ID,
+		&run.ScheduledPromptID,
+		&run.ChatID,
+		&run.Status,
+		&run.Error,
+		&messageID,
+		&run.StartedAt,
+		&finishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if messageID.Valid {
+		run.MessageID = &messageID.Int64
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	return &run, nil
+}
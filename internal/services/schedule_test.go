@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestScheduleService(t *testing.T) (*ScheduleService, *ChatService) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return NewScheduleService(db), NewChatService(db)
+}
+
+func newTestSchedule(t *testing.T, service *ScheduleService, chatID int64) *models.ScheduledPrompt {
+	p, err := service.Create(&models.ScheduledPrompt{
+		Name:     " Daily summary ",
+		ChatID:   chatID,
+		Provider: "stub",
+		Prompt:   "Summarize the news",
+		Cron:     "0 9 * * *",
+		Timezone: "UTC",
+		Enabled:  true,
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestValidateScheduledPrompt(t *testing.T) {
+	valid := func() *models.ScheduledPrompt {
+		return &models.ScheduledPrompt{Name: "n", Provider: "stub", Prompt: "p", Cron: "@daily"}
+	}
+	require.NoError(t, ValidateScheduledPrompt(valid()))
+
+	for name, mutate := range map[string]func(p *models.ScheduledPrompt){
+		"empty name":       func(p *models.ScheduledPrompt) { p.Name = "  " },
+		"long name":        func(p *models.ScheduledPrompt) { p.Name = string(make([]rune, MaxScheduleNameLength+1)) },
+		"empty prompt":     func(p *models.ScheduledPrompt) { p.Prompt = "" },
+		"no provider":      func(p *models.ScheduledPrompt) { p.Provider = "" },
+		"invalid cron":     func(p *models.ScheduledPrompt) { p.Cron = "every day" },
+		"never matches":    func(p *models.ScheduledPrompt) { p.Cron = "0 0 31 4 *" },
+		"unknown timezone": func(p *models.ScheduledPrompt) { p.Timezone = "Mars/Olympus" },
+	} {
+		p := valid()
+		mutate(p)
+		assert.Error(t, ValidateScheduledPrompt(p), name)
+	}
+}
+
+func TestScheduleService_CRUD(t *testing.T) {
+	service, chatService := newTestScheduleService(t)
+	chat, err := chatService.CreateChat(context.Background(), "News", "stub")
+	require.NoError(t, err)
+
+	p := newTestSchedule(t, service, chat.ID)
+	assert.Equal(t, "Daily summary", p.Name, "whitespace is trimmed")
+	require.NotNil(t, p.NextRunAt)
+	assert.Equal(t, 9, p.NextRunAt.UTC().Hour())
+	assert.Nil(t, p.LastRunAt)
+
+	got, err := service.Get(p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, p.Prompt, got.Prompt)
+
+	got.Enabled = false
+	got.Cron = "@hourly"
+	updated, err := service.Update(got)
+	require.NoError(t, err)
+	assert.False(t, updated.Enabled)
+	assert.Equal(t, "@hourly", updated.Cron)
+	assert.Nil(t, updated.NextRunAt, "paused prompts have no next run")
+
+	list, err := service.List()
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	require.NoError(t, service.Delete(p.ID))
+	_, err = service.Get(p.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	assert.ErrorIs(t, service.Delete(p.ID), ErrScheduleNotFound)
+}
+
+func TestScheduleService_DueAndClaim(t *testing.T) {
+	service, chatService := newTestScheduleService(t)
+	chat, err := chatService.CreateChat(context.Background(), "News", "stub")
+	require.NoError(t, err)
+	p := newTestSchedule(t, service, chat.ID)
+
+	due, err := service.Due(p.NextRunAt.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	now := p.NextRunAt.Add(time.Minute)
+	due, err = service.Due(now)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+
+	claimed, err := service.Claim(due[0], now)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	assert.True(t, due[0].NextRunAt.After(now))
+
+	// A second instance holding the same due prompt loses the race
+	claimed, err = service.Claim(p, now)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	// Prompts of deleted chats wait for the chat to be restored
+	require.NoError(t, chatService.DeleteChat(context.Background(), chat.ID))
+	due, err = service.Due(now.Add(48 * time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestSchedulerService_RunDue(t *testing.T) {
+	service, chatService := newTestScheduleService(t)
+	registry := NewProviderRegistry(nil)
+	require.NoError(t, registry.Register(&stubProvider{id: "stub"}))
+
+	chat, err := chatService.CreateChat(context.Background(), "News", "stub")
+	require.NoError(t, err)
+	p := newTestSchedule(t, service, chat.ID)
+
+	scheduler := NewSchedulerService(service, chatService, registry, nil)
+	var notified []*models.ScheduledPromptRun
+	scheduler.OnRun(func(p *models.ScheduledPrompt, run *models.ScheduledPromptRun, prompt, response *models.Message) {
+		assert.NotNil(t, prompt)
+		if assert.NotNil(t, response) {
+			assert.Equal(t, "Summarize the news", response.Content, "the stub echoes the prompt")
+		}
+		notified = append(notified, run)
+	})
+
+	assert.Equal(t, 1, scheduler.RunDue(p.NextRunAt.Add(time.Minute)))
+	scheduler.Wait()
+	require.Len(t, notified, 1)
+
+	messages, err := chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "user", messages[0].Role)
+	assert.Equal(t, "assistant", messages[1].Role)
+	assert.Equal(t, "stub", messages[1].Provider)
+
+	runs, err := service.ListRuns(p.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, models.ScheduledRunCompleted, runs[0].Status)
+	require.NotNil(t, runs[0].MessageID)
+	assert.Equal(t, messages[1].ID, *runs[0].MessageID)
+	assert.NotNil(t, runs[0].FinishedAt)
+
+	// Already claimed
+	assert.Equal(t, 0, scheduler.RunDue(p.NextRunAt.Add(time.Minute)))
+}
+
+func TestSchedulerService_TriggerRecordsFailure(t *testing.T) {
+	service, chatService := newTestScheduleService(t)
+	chat, err := chatService.CreateChat(context.Background(), "News", "stub")
+	require.NoError(t, err)
+	p := newTestSchedule(t, service, chat.ID)
+
+	// No provider registered
+	scheduler := NewSchedulerService(service, chatService, NewProviderRegistry(nil), nil)
+	require.NoError(t, scheduler.Trigger(p))
+	scheduler.Wait()
+
+	runs, err := service.ListRuns(p.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, models.ScheduledRunFailed, runs[0].Status)
+	assert.NotEmpty(t, runs[0].Error)
+	assert.Nil(t, runs[0].MessageID)
+
+	got, err := service.Get(p.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, got.LastRunAt)
+}
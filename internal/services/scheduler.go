@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/jobs"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/moderation"
+	"ai-gateway-hub/internal/processing"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+)
+
+const (
+	// How often the scheduler looks for due prompts
+	SchedulerInterval = 30 * time.Second
+
+	// Maximum time a scheduled prompt may run
+	ScheduledPromptTimeout = 5 * time.Minute
+)
+
+// ErrScheduleRunning is returned when a prompt is triggered while its previous run is still going
+var ErrScheduleRunning = apperrors.Conflict("scheduled prompt is already running")
+
+// ScheduledRunListener is notified when a scheduled run finishes. prompt and response are the
+// messages added to the chat; either may be nil when the run failed before saving it.
+type ScheduledRunListener func(p *models.ScheduledPrompt, run *models.ScheduledPromptRun, prompt, response *models.Message)
+
+// SchedulerService runs due scheduled prompts and delivers the responses into their chats
+type SchedulerService struct {
+	scheduleService *ScheduleService
+	chatService     *ChatService
+	registry        *ProviderRegistry
+	usageService    *UsageService
+	moderation      *ModerationService // nil moderates nothing
+	processing      *ProcessingService // nil transforms nothing
+	interval        time.Duration
+	listeners       []ScheduledRunListener
+	running         map[int64]bool
+	mu              sync.Mutex
+	ctx             context.Context
+	cancel          context.CancelFunc
+	runs            sync.WaitGroup
+}
+
+func NewSchedulerService(scheduleService *ScheduleService, chatService *ChatService, registry *ProviderRegistry, usageService *UsageService) *SchedulerService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SchedulerService{
+		scheduleService: scheduleService,
+		chatService:     chatService,
+		registry:        registry,
+		usageService:    usageService,
+		interval:        SchedulerInterval,
+		running:         make(map[int64]bool),
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// OnRun registers a listener for finished runs; listeners must be registered before its job starts
+func (s *SchedulerService) OnRun(listener ScheduledRunListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// SetModeration moderates scheduled prompts and their responses; call it before Start
+func (s *SchedulerService) SetModeration(moderationService *ModerationService) {
+	s.moderation = moderationService
+}
+
+// SetProcessing transforms scheduled prompts and their responses with the configured processors;
+// call it before Start
+func (s *SchedulerService) SetProcessing(processingService *ProcessingService) {
+	s.processing = processingService
+}
+
+// process transforms content with the processing service
+func (s *SchedulerService) process(p *models.ScheduledPrompt, stage, content string) string {
+	if s.processing == nil {
+		return content
+	}
+	return s.processing.Process(s.ctx, stage, p.ChatID, p.Provider, content)
+}
+
+// moderate checks content with the moderation service, returning an error if it was blocked
+func (s *SchedulerService) moderate(p *models.ScheduledPrompt, direction, content string) error {
+	if s.moderation == nil {
+		return nil
+	}
+	verdict := s.moderation.Check(s.ctx, moderation.Request{Direction: direction, ChatID: p.ChatID, Provider: p.Provider, Content: content})
+	if verdict.Blocked() {
+		return fmt.Errorf("%s was blocked by moderation (%s)", direction, verdict.Rule)
+	}
+	return nil
+}
+
+// Job checks for due prompts on every interval. Claiming a prompt already keeps instances from
+// running it twice, so the job isn't exclusive.
+func (s *SchedulerService) Job() jobs.Job {
+	return jobs.Job{
+		Name:     "scheduled_prompts",
+		Interval: s.interval,
+		Run: func(ctx context.Context) error {
+			s.RunDue(time.Now())
+			return nil
+		},
+	}
+}
+
+// Stop cancels running prompts and waits for them to finish
+func (s *SchedulerService) Stop() {
+	s.cancel()
+	s.runs.Wait()
+}
+
+// RunDue starts every prompt due at now and returns how many were started
+func (s *SchedulerService) RunDue(now time.Time) int {
+	due, err := s.scheduleService.Due(now)
+	if err != nil {
+		utils.Error("Failed to load due scheduled prompts: %v", err)
+		return 0
+	}
+
+	started := 0
+	for _, p := range due {
+		if !s.begin(p.ID) {
+			continue
+		}
+		claimed, err := s.scheduleService.Claim(p, now)
+		if err != nil {
+			utils.Error("Failed to claim scheduled prompt %d: %v", p.ID, err)
+		}
+		if !claimed {
+			s.end(p.ID)
+			continue
+		}
+		s.start(p)
+		started++
+	}
+	return started
+}
+
+// Trigger runs a prompt now, outside its schedule
+func (s *SchedulerService) Trigger(p *models.ScheduledPrompt) error {
+	if !s.begin(p.ID) {
+		return ErrScheduleRunning
+	}
+	if err := s.scheduleService.MarkRun(p, time.Now()); err != nil {
+		s.end(p.ID)
+		return err
+	}
+	s.start(p)
+	return nil
+}
+
+// Wait blocks until every started run has finished
+func (s *SchedulerService) Wait() {
+	s.runs.Wait()
+}
+
+// begin marks a prompt as running, returning false if it already is
+func (s *SchedulerService) begin(id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[id] {
+		return false
+	}
+	s.running[id] = true
+	return true
+}
+
+// end marks a prompt as no longer running
+func (s *SchedulerService) end(id int64) {
+	s.mu.Lock()
+	delete(s.running, id)
+	s.mu.Unlock()
+}
+
+// start executes a prompt marked as running in the background
+func (s *SchedulerService) start(p *models.ScheduledPrompt) {
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		defer s.end(p.ID)
+		s.execute(p)
+	}()
+}
+
+// execute runs a prompt, records the run and notifies listeners
+func (s *SchedulerService) execute(p *models.ScheduledPrompt) {
+	run, err := s.scheduleService.StartRun(p)
+	if err != nil {
+		utils.Error("Failed to start scheduled prompt %d: %v", p.ID, err)
+		return
+	}
+	utils.Info("Running scheduled prompt %d (%s) with %s", p.ID, p.Name, p.Provider)
+
+	promptMsg, responseMsg, runErr := s.generate(p)
+
+	var messageID *int64
+	if responseMsg != nil {
+		messageID = &responseMsg.ID
+	}
+	if err := s.scheduleService.FinishRun(run, messageID, runErr); err != nil {
+		utils.Error("Failed to record scheduled prompt %d result: %v", p.ID, err)
+	}
+	if runErr != nil {
+		utils.Warn("Scheduled prompt %d failed: %v", p.ID, runErr)
+	}
+
+	for _, listener := range s.listeners {
+		listener(p, run, promptMsg, responseMsg)
+	}
+}
+
+// generate sends the prompt to the provider and adds the prompt and response to the chat
+func (s *SchedulerService) generate(p *models.ScheduledPrompt) (*models.Message, *models.Message, error) {
+	chat, err := s.chatService.GetChat(s.ctx, p.ChatID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider, release, err := s.registry.Acquire(p.Provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	if !provider.IsAvailable() {
+		return nil, nil, fmt.Errorf("provider %s is not available", p.Provider)
+	}
+	if p.Model != "" && !providers.SupportsModel(provider, p.Model) {
+		return nil, nil, fmt.Errorf("model %s is not supported by %s", p.Model, p.Provider)
+	}
+
+	if err := s.moderate(p, moderation.DirectionPrompt, p.Prompt); err != nil {
+		return nil, nil, err
+	}
+
+	promptMsg, err := s.chatService.AddMessage(s.ctx, p.ChatID, "user", p.Prompt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, ScheduledPromptTimeout)
+	defer cancel()
+	ctx = providers.WithModel(ctx, p.Model)
+
+	input := BuildProviderInput(chat.SystemPrompt, s.process(p, processing.StagePre, p.Prompt))
+	var response strings.Builder
+	startedAt := time.Now()
+	err = provider.StreamResponse(ctx, input, p.ChatID, &response)
+	latency := time.Since(startedAt)
+	// Stopping the scheduler isn't the provider's failure
+	if s.ctx.Err() == nil {
+		s.registry.RecordResult(p.Provider, err)
+	}
+	s.recordUsage(p, &promptMsg.ID, models.UsageInput, input)
+	if err != nil {
+		return promptMsg, nil, fmt.Errorf("failed to get response: %w", err)
+	}
+	if response.Len() == 0 {
+		return promptMsg, nil, fmt.Errorf("%s returned an empty response", p.Provider)
+	}
+
+	content, oversized := s.chatService.MessageLimits().TruncateResponse(s.process(p, processing.StagePost, response.String()))
+	if oversized {
+		utils.Warn("Response to scheduled prompt %d is %d bytes, over the %d byte limit", p.ID, response.Len(), s.chatService.MessageLimits().MaxResponseBytes)
+	}
+	if err := s.moderate(p, moderation.DirectionResponse, content); err != nil {
+		s.recordUsage(p, nil, models.UsageOutput, content)
+		return promptMsg, nil, err
+	}
+
+	responseMsg, err := s.chatService.AddProviderMessage(s.ctx, p.ChatID, "assistant", content, p.Provider)
+	if err != nil {
+		return promptMsg, nil, err
+	}
+	if p.Model != "" {
+		if err := s.chatService.SetMessageModel(s.ctx, responseMsg.ID, p.Model); err != nil {
+			utils.Warn("Failed to record model of scheduled prompt %d response: %v", p.ID, err)
+		}
+		responseMsg.Model = p.Model
+	}
+	meta := &models.MessageMetadata{
+		Model:           p.Model,
+		Provider:        p.Provider,
+		LatencyMs:       latency.Milliseconds(),
+		TokensIn:        EstimateTokens(input),
+		TokensOut:       EstimateTokens(content),
+		TokensEstimated: true,
+		FinishReason:    models.FinishStop,
+	}
+	if oversized && s.chatService.MessageLimits().TruncatesResponses() {
+		meta.FinishReason = models.FinishLength
+	}
+	if err := s.chatService.SetMessageMetadata(s.ctx, responseMsg.ID, meta); err != nil {
+		utils.Warn("Failed to record metadata of scheduled prompt %d response: %v", p.ID, err)
+	} else {
+		responseMsg.Metadata = meta
+	}
+	s.recordUsage(p, &responseMsg.ID, models.UsageOutput, responseMsg.Content)
+
+	return promptMsg, responseMsg, nil
+}
+
+// recordUsage stores estimated usage for a run; failures are only logged
+func (s *SchedulerService) recordUsage(p *models.ScheduledPrompt, messageID *int64, direction, content string) {
+	if s.usageService == nil {
+		return
+	}
+	if err := s.usageService.RecordContent(p.ChatID, messageID, p.Provider, direction, content); err != nil {
+		utils.Error("Failed to record usage for scheduled prompt %d: %v", p.ID, err)
+	}
+}
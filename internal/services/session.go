@@ -3,56 +3,51 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"ai-gateway-hub/internal/models"
-
-	"github.com/go-redis/redis/v8"
 )
 
-// SessionService handles session management using Redis
+// SessionService handles session management on top of a SessionStore (Redis, or memory without it)
 type SessionService struct {
-	redis *redis.Client
+	store SessionStore
+	now   func() time.Time
 }
 
-func NewSessionService(redisClient *redis.Client) *SessionService {
+func NewSessionService(store SessionStore) *SessionService {
 	return &SessionService{
-		redis: redisClient,
+		store: store,
+		now:   time.Now,
 	}
 }
 
-// CreateSession creates a new session
-func (s *SessionService) CreateSession(sessionID string, chatID *int64, ttl time.Duration) error {
-	ctx := context.Background()
-	session := &models.Session{
-		ID:        sessionID,
-		ChatID:    chatID,
-		CreatedAt: time.Now(),
-	}
+// CreateSession creates a new session; a ttl of 0 creates one that never expires
+func (s *SessionService) CreateSession(ctx context.Context, sessionID string, chatID *int64, ttl time.Duration) error {
+	return s.create(ctx, &models.Session{ID: sessionID, ChatID: chatID}, ttl)
+}
 
-	if ttl > 0 {
-		expiresAt := time.Now().Add(ttl)
-		session.ExpiresAt = &expiresAt
-	}
+// CreateClientSession creates a new session bound to the client that received the session cookie
+func (s *SessionService) CreateClientSession(ctx context.Context, sessionID, clientIP, userAgent string, ttl time.Duration) error {
+	return s.create(ctx, &models.Session{ID: sessionID, ClientIP: clientIP, UserAgent: userAgent}, ttl)
+}
 
-	data, err := json.Marshal(session)
-	if err != nil {
-		return fmt.Errorf("failed to marshal session: %w", err)
-	}
+// create stamps a new session and stores it for ttl
+func (s *SessionService) create(ctx context.Context, session *models.Session, ttl time.Duration) error {
+	now := s.now()
+	session.CreatedAt = now
+	session.LastSeen = now
+	session.ExpiresAt = expiresAt(now, ttl)
 
-	return s.redis.Set(ctx, s.key(sessionID), data, ttl).Err()
+	return s.write(ctx, session, ttl)
 }
 
 // GetSession retrieves a session by ID
-func (s *SessionService) GetSession(sessionID string) (*models.Session, error) {
-	ctx := context.Background()
-	data, err := s.redis.Get(ctx, s.key(sessionID)).Bytes()
+func (s *SessionService) GetSession(ctx context.Context, sessionID string) (*models.Session, error) {
+	data, err := s.store.Get(ctx, sessionID)
 	if err != nil {
-		if err == redis.Nil {
-			return nil, fmt.Errorf("session not found")
-		}
-		return nil, fmt.Errorf("failed to get session: %w", err)
+		return nil, err
 	}
 
 	var session models.Session
@@ -64,56 +59,143 @@ func (s *SessionService) GetSession(sessionID string) (*models.Session, error) {
 }
 
 // UpdateSession updates an existing session
-func (s *SessionService) UpdateSession(sessionID string, chatID *int64) error {
-	ctx := context.Background()
-	
+func (s *SessionService) UpdateSession(ctx context.Context, sessionID string, chatID *int64) error {
 	// Get current session
-	session, err := s.GetSession(sessionID)
+	session, err := s.GetSession(ctx, sessionID)
 	if err != nil {
 		return err
 	}
 
-	// Update chat ID
 	session.ChatID = chatID
 
-	// Calculate remaining TTL
-	ttl := time.Until(*session.ExpiresAt)
-	if ttl <= 0 {
+	return s.save(ctx, session)
+}
+
+// AttachInstance records which hub instance holds the session's WebSocket connection
+func (s *SessionService) AttachInstance(ctx context.Context, sessionID, instanceID string) error {
+	session, err := s.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.InstanceID == instanceID {
+		return nil
+	}
+	session.InstanceID = instanceID
+
+	return s.save(ctx, session)
+}
+
+// SetRole grants a role to the session, or revokes it with an empty role
+func (s *SessionService) SetRole(ctx context.Context, sessionID, role string) error {
+	session, err := s.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	session.Role = role
+
+	return s.save(ctx, session)
+}
+
+// save writes a modified session back, keeping its remaining TTL. Sessions without an expiry
+// stay that way.
+func (s *SessionService) save(ctx context.Context, session *models.Session) error {
+	ttl, err := s.store.TTL(ctx, session.ID)
+	if errors.Is(err, ErrSessionNotFound) {
 		return fmt.Errorf("session expired")
 	}
+	if err != nil {
+		return err
+	}
+
+	return s.write(ctx, session, ttl)
+}
 
-	// Save updated session
+// write stores the session for ttl, or without expiry when ttl is 0
+func (s *SessionService) write(ctx context.Context, session *models.Session, ttl time.Duration) error {
 	data, err := json.Marshal(session)
 	if err != nil {
 		return fmt.Errorf("failed to marshal session: %w", err)
 	}
 
-	return s.redis.Set(ctx, s.key(sessionID), data, ttl).Err()
+	return s.store.Set(ctx, session.ID, data, ttl)
+}
+
+// expiresAt returns when a session renewed at now for ttl expires, nil if it never does
+func expiresAt(now time.Time, ttl time.Duration) *time.Time {
+	if ttl <= 0 {
+		return nil
+	}
+	t := now.Add(ttl)
+	return &t
 }
 
 // DeleteSession removes a session
-func (s *SessionService) DeleteSession(sessionID string) error {
-	ctx := context.Background()
-	return s.redis.Del(ctx, s.key(sessionID)).Err()
+func (s *SessionService) DeleteSession(ctx context.Context, sessionID string) error {
+	return s.store.Delete(ctx, sessionID)
 }
 
-// ExtendSession extends the TTL of a session
-func (s *SessionService) ExtendSession(sessionID string, duration time.Duration) error {
-	ctx := context.Background()
-	return s.redis.Expire(ctx, s.key(sessionID), duration).Err()
+// ExtendSession records activity on a session and slides its expiration to duration from now.
+// A duration of 0 keeps the session from expiring. Returns ErrSessionNotFound for sessions that
+// no longer exist.
+func (s *SessionService) ExtendSession(ctx context.Context, sessionID string, duration time.Duration) error {
+	session, err := s.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	session.LastSeen = now
+	session.ExpiresAt = expiresAt(now, duration)
+
+	return s.write(ctx, session, duration)
 }
 
 // GetActiveSessions returns count of active sessions
-func (s *SessionService) GetActiveSessions() (int64, error) {
-	ctx := context.Background()
-	keys, err := s.redis.Keys(ctx, "session:*").Result()
+func (s *SessionService) GetActiveSessions(ctx context.Context) (int64, error) {
+	ids, err := s.store.IDs(ctx)
 	if err != nil {
 		return 0, err
 	}
-	return int64(len(keys)), nil
+	return int64(len(ids)), nil
+}
+
+// ListSessions returns all active sessions with their remaining TTL
+func (s *SessionService) ListSessions(ctx context.Context) ([]*models.SessionInfo, error) {
+	ids, err := s.store.IDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*models.SessionInfo, 0, len(ids))
+	for _, sessionID := range ids {
+		info, err := s.GetSessionInfo(ctx, sessionID)
+		if errors.Is(err, ErrSessionNotFound) {
+			// Session expired between listing and GET
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, info)
+	}
+
+	return sessions, nil
 }
 
-// key generates the Redis key for a session
-func (s *SessionService) key(sessionID string) string {
-	return fmt.Sprintf("session:%s", sessionID)
-}
\ No newline at end of file
+// GetSessionInfo returns a session with its remaining TTL
+func (s *SessionService) GetSessionInfo(ctx context.Context, sessionID string) (*models.SessionInfo, error) {
+	session, err := s.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := s.store.TTL(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SessionInfo{
+		Session:    session,
+		TTLSeconds: int64(ttl.Seconds()),
+	}, nil
+}
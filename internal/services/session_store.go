@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrSessionNotFound is returned for sessions that don't exist or expired
+var ErrSessionNotFound = apperrors.NotFound("session not found")
+
+// Where sessions and cached provider statuses are kept
+const (
+	StoreBackendRedis  = "redis"
+	StoreBackendMemory = "memory"
+)
+
+// SessionStore keeps serialized sessions with an optional lifetime; a TTL of 0 never expires
+type SessionStore interface {
+	Get(ctx context.Context, id string) ([]byte, error)
+	Set(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+	Expire(ctx context.Context, id string, ttl time.Duration) error
+	// TTL returns the remaining lifetime of a session, 0 when it never expires
+	TTL(ctx context.Context, id string) (time.Duration, error)
+	// IDs lists the sessions that haven't expired
+	IDs(ctx context.Context) ([]string, error)
+}
+
+// RedisSessionStore keeps sessions in Redis so they are shared by every instance
+type RedisSessionStore struct {
+	redis *redis.Client
+}
+
+func NewRedisSessionStore(redisClient *redis.Client) *RedisSessionStore {
+	return &RedisSessionStore{redis: redisClient}
+}
+
+func (s *RedisSessionStore) Get(ctx context.Context, id string) ([]byte, error) {
+	data, err := s.redis.Get(ctx, s.key(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return data, nil
+}
+
+func (s *RedisSessionStore) Set(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return s.redis.Set(ctx, s.key(id), data, ttl).Err()
+}
+
+func (s *RedisSessionStore) Delete(ctx context.Context, id string) error {
+	return s.redis.Del(ctx, s.key(id)).Err()
+}
+
+func (s *RedisSessionStore) Expire(ctx context.Context, id string, ttl time.Duration) error {
+	return s.redis.Expire(ctx, s.key(id), ttl).Err()
+}
+
+func (s *RedisSessionStore) TTL(ctx context.Context, id string) (time.Duration, error) {
+	ttl, err := s.redis.TTL(ctx, s.key(id)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get session TTL: %w", err)
+	}
+	switch {
+	case ttl == -2:
+		// Key no longer exists
+		return 0, ErrSessionNotFound
+	case ttl < 0:
+		// No expiration
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (s *RedisSessionStore) IDs(ctx context.Context) ([]string, error) {
+	var ids []string
+	iter := s.redis.Scan(ctx, 0, "session:*", 100).Iterator()
+	for iter.Next(ctx) {
+		ids = append(ids, strings.TrimPrefix(iter.Val(), "session:"))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan sessions: %w", err)
+	}
+	return ids, nil
+}
+
+// key generates the Redis key for a session
+func (s *RedisSessionStore) key(id string) string {
+	return fmt.Sprintf("session:%s", id)
+}
+
+// MemorySessionStore keeps sessions in this process when Redis is unavailable. Sessions are lost
+// on restart and not shared with other instances.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	now      func() time.Time
+}
+
+// memorySession is a stored session; a zero expiresAt never expires
+type memorySession struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]memorySession),
+		now:      time.Now,
+	}
+}
+
+func (s *MemorySessionStore) Get(ctx context.Context, id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.get(id)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return session.data, nil
+}
+
+func (s *MemorySessionStore) Set(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[id] = memorySession{data: data, expiresAt: s.expiresAt(ttl)}
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *MemorySessionStore) Expire(ctx context.Context, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Like Redis, extending a missing session is a no-op
+	if session, ok := s.get(id); ok {
+		session.expiresAt = s.expiresAt(ttl)
+		s.sessions[id] = session
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) TTL(ctx context.Context, id string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.get(id)
+	if !ok {
+		return 0, ErrSessionNotFound
+	}
+	if session.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return session.expiresAt.Sub(s.now()), nil
+}
+
+func (s *MemorySessionStore) IDs(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		if _, ok := s.get(id); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// get returns a session that hasn't expired, dropping it once it has; s.mu must be held
+func (s *MemorySessionStore) get(id string) (memorySession, bool) {
+	session, ok := s.sessions[id]
+	if !ok {
+		return memorySession{}, false
+	}
+	if !session.expiresAt.IsZero() && !s.now().Before(session.expiresAt) {
+		delete(s.sessions, id)
+		return memorySession{}, false
+	}
+	return session, true
+}
+
+// expiresAt returns when a session stored now with the given lifetime expires
+func (s *MemorySessionStore) expiresAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return s.now().Add(ttl)
+}
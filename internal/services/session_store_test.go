@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/providers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemorySessionStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemorySessionStore()
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Set(ctx, "short", []byte("a"), time.Minute))
+	require.NoError(t, store.Set(ctx, "forever", []byte("b"), 0))
+
+	ttl, err := store.TTL(ctx, "short")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	ttl, err = store.TTL(ctx, "forever")
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+
+	ids, err := store.IDs(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"short", "forever"}, ids)
+
+	now = now.Add(2 * time.Minute)
+
+	_, err = store.Get(ctx, "short")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = store.TTL(ctx, "short")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	data, err := store.Get(ctx, "forever")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), data)
+
+	ids, err = store.IDs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"forever"}, ids)
+}
+
+func TestMemorySessionStore_Expire(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemorySessionStore()
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Set(ctx, "s1", []byte("a"), time.Minute))
+	require.NoError(t, store.Expire(ctx, "s1", time.Hour))
+
+	ttl, err := store.TTL(ctx, "s1")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, ttl)
+
+	// Extending a missing session doesn't create it
+	require.NoError(t, store.Expire(ctx, "missing", time.Hour))
+	_, err = store.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestSessionService_MemoryStore(t *testing.T) {
+	ctx := context.Background()
+	sessions := NewSessionService(NewMemorySessionStore())
+
+	chatID := int64(7)
+	require.NoError(t, sessions.CreateSession(ctx, "s1", nil, time.Hour))
+	require.NoError(t, sessions.UpdateSession(ctx, "s1", &chatID))
+	require.NoError(t, sessions.SetRole(ctx, "s1", "admin"))
+
+	session, err := sessions.GetSession(ctx, "s1")
+	require.NoError(t, err)
+	require.NotNil(t, session.ChatID)
+	assert.Equal(t, chatID, *session.ChatID)
+	assert.Equal(t, "admin", session.Role)
+
+	count, err := sessions.GetActiveSessions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	list, err := sessions.ListSessions(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Greater(t, list[0].TTLSeconds, int64(0))
+
+	require.NoError(t, sessions.DeleteSession(ctx, "s1"))
+	_, err = sessions.GetSession(ctx, "s1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.Error(t, sessions.SetRole(ctx, "s1", "admin"))
+}
+
+func TestMemoryStatusCache_Expiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cache := NewMemoryStatusCache()
+	cache.now = func() time.Time { return now }
+
+	cache.Set(ctx, "claude", providers.ProviderStatus{Available: true, Status: "ready"}, 30*time.Second)
+
+	status, ok := cache.Get(ctx, "claude")
+	require.True(t, ok)
+	assert.Equal(t, "ready", status.Status)
+
+	now = now.Add(time.Minute)
+	_, ok = cache.Get(ctx, "claude")
+	assert.False(t, ok)
+
+	cache.Set(ctx, "claude", providers.ProviderStatus{Status: "ready"}, time.Minute)
+	cache.Delete(ctx, "claude")
+	_, ok = cache.Get(ctx, "claude")
+	assert.False(t, ok)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newClockedSessionService returns a SessionService on a memory store whose clock the test moves
+func newClockedSessionService() (*SessionService, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	store := NewMemorySessionStore()
+	store.now = clock
+	sessions := NewSessionService(store)
+	sessions.now = clock
+	return sessions, &now
+}
+
+func TestSessionService_CreateStampsTimes(t *testing.T) {
+	ctx := context.Background()
+	sessions, now := newClockedSessionService()
+
+	require.NoError(t, sessions.CreateClientSession(ctx, "s1", "10.0.0.1", "test-agent", time.Hour))
+
+	session, err := sessions.GetSession(ctx, "s1")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", session.ClientIP)
+	assert.Equal(t, "test-agent", session.UserAgent)
+	assert.True(t, session.CreatedAt.Equal(*now))
+	assert.True(t, session.LastSeen.Equal(*now))
+	require.NotNil(t, session.ExpiresAt)
+	assert.True(t, session.ExpiresAt.Equal(now.Add(time.Hour)))
+}
+
+func TestSessionService_NoExpiry(t *testing.T) {
+	ctx := context.Background()
+	sessions, now := newClockedSessionService()
+
+	require.NoError(t, sessions.CreateSession(ctx, "s1", nil, 0))
+
+	session, err := sessions.GetSession(ctx, "s1")
+	require.NoError(t, err)
+	assert.Nil(t, session.ExpiresAt)
+
+	// Updating a session without ExpiresAt used to dereference nil
+	chatID := int64(3)
+	require.NoError(t, sessions.UpdateSession(ctx, "s1", &chatID))
+	require.NoError(t, sessions.AttachInstance(ctx, "s1", "instance-a"))
+
+	*now = now.Add(365 * 24 * time.Hour)
+
+	session, err = sessions.GetSession(ctx, "s1")
+	require.NoError(t, err)
+	assert.Nil(t, session.ExpiresAt)
+	require.NotNil(t, session.ChatID)
+	assert.Equal(t, chatID, *session.ChatID)
+	assert.Equal(t, "instance-a", session.InstanceID)
+
+	list, err := sessions.ListSessions(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Zero(t, list[0].TTLSeconds)
+}
+
+func TestSessionService_SlidingExpiration(t *testing.T) {
+	ctx := context.Background()
+	sessions, now := newClockedSessionService()
+
+	require.NoError(t, sessions.CreateSession(ctx, "s1", nil, time.Hour))
+
+	// Activity every 45 minutes keeps the session alive well past its first hour
+	for i := 0; i < 4; i++ {
+		*now = now.Add(45 * time.Minute)
+		require.NoError(t, sessions.ExtendSession(ctx, "s1", time.Hour))
+	}
+
+	session, err := sessions.GetSession(ctx, "s1")
+	require.NoError(t, err)
+	assert.True(t, session.LastSeen.Equal(*now))
+	require.NotNil(t, session.ExpiresAt)
+	assert.True(t, session.ExpiresAt.Equal(now.Add(time.Hour)))
+
+	// Without activity it expires an hour after it was last seen
+	*now = now.Add(time.Hour)
+	_, err = sessions.GetSession(ctx, "s1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.ErrorIs(t, sessions.ExtendSession(ctx, "s1", time.Hour), ErrSessionNotFound)
+}
+
+func TestSessionService_ExtendWithoutExpiry(t *testing.T) {
+	ctx := context.Background()
+	sessions, now := newClockedSessionService()
+
+	require.NoError(t, sessions.CreateSession(ctx, "s1", nil, time.Minute))
+	require.NoError(t, sessions.ExtendSession(ctx, "s1", 0))
+
+	*now = now.Add(24 * time.Hour)
+
+	session, err := sessions.GetSession(ctx, "s1")
+	require.NoError(t, err)
+	assert.Nil(t, session.ExpiresAt)
+}
+
+func TestSessionService_UpdateKeepsRemainingTTL(t *testing.T) {
+	ctx := context.Background()
+	sessions, now := newClockedSessionService()
+
+	require.NoError(t, sessions.CreateSession(ctx, "s1", nil, time.Hour))
+	*now = now.Add(40 * time.Minute)
+
+	chatID := int64(9)
+	require.NoError(t, sessions.UpdateSession(ctx, "s1", &chatID))
+
+	list, err := sessions.ListSessions(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, int64((20 * time.Minute).Seconds()), list[0].TTLSeconds)
+
+	*now = now.Add(20 * time.Minute)
+	_, err = sessions.GetSession(ctx, "s1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestSessionService_UpdateMissingSession(t *testing.T) {
+	ctx := context.Background()
+	sessions, _ := newClockedSessionService()
+
+	chatID := int64(1)
+	assert.ErrorIs(t, sessions.UpdateSession(ctx, "missing", &chatID), ErrSessionNotFound)
+	assert.ErrorIs(t, sessions.SetRole(ctx, "missing", "admin"), ErrSessionNotFound)
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+)
+
+// SettingsService stores hub-wide settings as JSON values in the app_settings table
+type SettingsService struct {
+	db database.Store
+}
+
+func NewSettingsService(db database.Store) *SettingsService {
+	return &SettingsService{db: db}
+}
+
+// Get decodes the setting stored under key into dest and reports whether it was set
+func (s *SettingsService) Get(key string, dest any) (bool, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM app_settings WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+
+	if err := json.Unmarshal([]byte(value), dest); err != nil {
+		return false, fmt.Errorf("failed to decode setting %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// All returns every stored setting as raw JSON keyed by setting key
+func (s *SettingsService) All() (map[string]json.RawMessage, error) {
+	rows, err := s.db.Query(`SELECT key, value FROM app_settings ORDER BY key`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := make(map[string]json.RawMessage)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan setting: %w", err)
+		}
+		settings[key] = json.RawMessage(value)
+	}
+	return settings, rows.Err()
+}
+
+// Set stores value as JSON under key, replacing any previous value
+func (s *SettingsService) Set(key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode setting %s: %w", key, err)
+	}
+
+	query := `
+		INSERT INTO app_settings (key, value, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`
+	if _, err := s.db.Exec(query, key, string(data), time.Now()); err != nil {
+		return fmt.Errorf("failed to save setting %s: %w", key, err)
+	}
+	return nil
+}
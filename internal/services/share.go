@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+)
+
+var (
+	// ErrShareNotFound is returned for share links that are forged, revoked, expired or whose chat
+	// was deleted
+	ErrShareNotFound = apperrors.NotFound("shared chat not found")
+	// ErrInvalidShareExpiry is returned for expiries that are negative or too long
+	ErrInvalidShareExpiry = apperrors.Validation("invalid share link expiry")
+)
+
+// MaxShareExpiry is the longest a share link can be valid for; links may also never expire
+const MaxShareExpiry = 365 * 24 * time.Hour
+
+// Maximum number of messages shown on a shared page, as on the chat page
+const maxSharedMessages = 1000
+
+// ShareService creates public read-only links to chats. A link's token is the share's random ID
+// signed with the share secret, so forged tokens are rejected without a database lookup; the
+// share row records the expiry, revocation and views.
+type ShareService struct {
+	db     database.Store
+	chats  *ChatService
+	secret []byte
+	now    func() time.Time
+}
+
+func NewShareService(db database.Store, chatService *ChatService, secret []byte) *ShareService {
+	return &ShareService{db: db, chats: chatService, secret: secret, now: time.Now}
+}
+
+// Columns selected for a share, in the order scanShare expects
+const shareColumns = "id, chat_id, created_at, expires_at, revoked_at, views, last_viewed_at"
+
+// scanShare reads a share selected with shareColumns and adds its token and URL
+func (s *ShareService) scanShare(row rowScanner) (*models.ChatShare, error) {
+	var share models.ChatShare
+	var expiresAt, revokedAt, lastViewedAt sql.NullTime
+	if err := row.Scan(&share.ID, &share.ChatID, &share.CreatedAt, &expiresAt, &revokedAt, &share.Views, &lastViewedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		share.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		share.RevokedAt = &revokedAt.Time
+	}
+	if lastViewedAt.Valid {
+		share.LastViewedAt = &lastViewedAt.Time
+	}
+	share.Token = s.sign(share.ID)
+	share.URL = "/share/" + share.Token
+	return &share, nil
+}
+
+// Create shares a chat; an expiresIn of 0 makes a link that is valid until revoked
+func (s *ShareService) Create(ctx context.Context, chatID int64, expiresIn time.Duration) (*models.ChatShare, error) {
+	if expiresIn < 0 || expiresIn > MaxShareExpiry {
+		return nil, fmt.Errorf("%w: expiry must be between 0 and %d hours", ErrInvalidShareExpiry, int(MaxShareExpiry.Hours()))
+	}
+	if _, err := s.chats.GetChat(ctx, chatID); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate share ID: %w", err)
+	}
+	id := base64.RawURLEncoding.EncodeToString(b)
+
+	now := s.now()
+	var expiresAt *time.Time
+	if expiresIn > 0 {
+		t := now.Add(expiresIn)
+		expiresAt = &t
+	}
+	query := `INSERT INTO chat_shares (id, chat_id, created_at, expires_at) VALUES (?, ?, ?, ?)`
+	if _, err := s.db.ExecContext(ctx, query, id, chatID, now, expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to create share: %w", err)
+	}
+	return s.get(ctx, id)
+}
+
+// get returns a share by ID, revoked and expired ones included
+func (s *ShareService) get(ctx context.Context, id string) (*models.ChatShare, error) {
+	query := `SELECT ` + shareColumns + ` FROM chat_shares WHERE id = ?`
+	share, err := s.scanShare(s.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrShareNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share: %w", err)
+	}
+	return share, nil
+}
+
+// List returns the shares of a chat, newest first, revoked and expired ones included
+func (s *ShareService) List(ctx context.Context, chatID int64) ([]*models.ChatShare, error) {
+	query := `SELECT ` + shareColumns + ` FROM chat_shares WHERE chat_id = ? ORDER BY created_at DESC`
+	rows, err := s.db.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shares: %w", err)
+	}
+	defer rows.Close()
+
+	shares := make([]*models.ChatShare, 0)
+	for rows.Next() {
+		share, err := s.scanShare(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share: %w", err)
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+// Revoke disables a share link of a chat for good
+func (s *ShareService) Revoke(ctx context.Context, chatID int64, id string) error {
+	query := `UPDATE chat_shares SET revoked_at = ? WHERE id = ? AND chat_id = ? AND revoked_at IS NULL`
+	result, err := s.db.ExecContext(ctx, query, s.now(), id, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// Open counts a view of a share link and returns its chat with the finished messages
+func (s *ShareService) Open(ctx context.Context, token string) (*models.ChatShare, *models.Chat, []*models.Message, error) {
+	id, ok := s.verify(token)
+	if !ok {
+		return nil, nil, nil, ErrShareNotFound
+	}
+
+	now := s.now()
+	query := `
+		UPDATE chat_shares SET views = views + 1, last_viewed_at = ?
+		WHERE id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)
+		AND chat_id IN (SELECT id FROM chats WHERE deleted_at IS NULL)
+	`
+	result, err := s.db.ExecContext(ctx, query, now, id, now)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to count share view: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return nil, nil, nil, ErrShareNotFound
+	}
+
+	share, err := s.get(ctx, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	chat, err := s.chats.GetChat(ctx, share.ChatID)
+	if errors.Is(err, ErrChatNotFound) {
+		return nil, nil, nil, ErrShareNotFound
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	messages, err := s.chats.GetMessages(ctx, chat.ID, maxSharedMessages, 0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	finished := make([]*models.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Status != models.MessageStreaming {
+			finished = append(finished, msg)
+		}
+	}
+	return share, chat, finished, nil
+}
+
+// sign returns the link token of a share ID
+func (s *ShareService) sign(id string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("chat_share:" + id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify returns the share ID of a link token with a valid signature
+func (s *ShareService) verify(token string) (string, bool) {
+	id, _, ok := strings.Cut(token, ".")
+	if !ok || id == "" {
+		return "", false
+	}
+	return id, hmac.Equal([]byte(token), []byte(s.sign(id)))
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareService(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	chatService := NewChatService(db)
+	chat, err := chatService.CreateChat(ctx, "Shared", "claude")
+	require.NoError(t, err)
+	_, err = chatService.AddMessage(ctx, chat.ID, "user", "Hello")
+	require.NoError(t, err)
+	streaming, err := chatService.AddProviderMessage(ctx, chat.ID, "assistant", "Partial", "claude")
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE messages SET status = ? WHERE id = ?`, models.MessageStreaming, streaming.ID)
+	require.NoError(t, err)
+
+	shares := NewShareService(db, chatService, []byte(strings.Repeat("s", 32)))
+	now := time.Now()
+	shares.now = func() time.Time { return now }
+
+	_, err = shares.Create(ctx, chat.ID, MaxShareExpiry+time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidShareExpiry)
+	_, err = shares.Create(ctx, 999, 0)
+	assert.ErrorIs(t, err, ErrChatNotFound)
+
+	share, err := shares.Create(ctx, chat.ID, time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, share.ExpiresAt)
+
+	// Streaming responses aren't shown until they finish
+	opened, openedChat, messages, err := shares.Open(ctx, share.Token)
+	require.NoError(t, err)
+	assert.Equal(t, chat.ID, openedChat.ID)
+	assert.Equal(t, int64(1), opened.Views)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Hello", messages[0].Content)
+
+	// Tokens are checked against the secret, not only looked up
+	other := NewShareService(db, chatService, []byte(strings.Repeat("o", 32)))
+	_, _, _, err = other.Open(ctx, share.Token)
+	assert.ErrorIs(t, err, ErrShareNotFound)
+	_, _, _, err = shares.Open(ctx, share.ID)
+	assert.ErrorIs(t, err, ErrShareNotFound)
+
+	// Expired links stop working and aren't counted
+	now = now.Add(2 * time.Hour)
+	_, _, _, err = shares.Open(ctx, share.Token)
+	assert.ErrorIs(t, err, ErrShareNotFound)
+
+	// Links of deleted chats stop working too
+	permanent, err := shares.Create(ctx, chat.ID, 0)
+	require.NoError(t, err)
+	require.NoError(t, chatService.DeleteChat(ctx, chat.ID))
+	_, _, _, err = shares.Open(ctx, permanent.Token)
+	assert.ErrorIs(t, err, ErrShareNotFound)
+
+	list, err := shares.List(ctx, chat.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, int64(0), list[0].Views)
+	assert.Equal(t, int64(1), list[1].Views)
+}
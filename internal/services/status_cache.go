@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"ai-gateway-hub/internal/providers"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// StatusCache keeps recent provider statuses so listing providers doesn't run a status check
+// each time. The cache is best effort: failures read as a miss.
+type StatusCache interface {
+	Get(ctx context.Context, providerID string) (*providers.ProviderStatus, bool)
+	Set(ctx context.Context, providerID string, status providers.ProviderStatus, ttl time.Duration)
+	Delete(ctx context.Context, providerID string)
+}
+
+// RedisStatusCache keeps provider statuses in Redis, shared by every instance
+type RedisStatusCache struct {
+	redis *redis.Client
+}
+
+func NewRedisStatusCache(redisClient *redis.Client) *RedisStatusCache {
+	return &RedisStatusCache{redis: redisClient}
+}
+
+func (c *RedisStatusCache) Get(ctx context.Context, providerID string) (*providers.ProviderStatus, bool) {
+	data, err := c.redis.Get(ctx, c.key(providerID)).Result()
+	if err != nil {
+		return nil, false
+	}
+
+	var status providers.ProviderStatus
+	if err := json.Unmarshal([]byte(data), &status); err != nil {
+		return nil, false
+	}
+	return &status, true
+}
+
+func (c *RedisStatusCache) Set(ctx context.Context, providerID string, status providers.ProviderStatus, ttl time.Duration) {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return
+	}
+	c.redis.Set(ctx, c.key(providerID), data, ttl)
+}
+
+func (c *RedisStatusCache) Delete(ctx context.Context, providerID string) {
+	c.redis.Del(ctx, c.key(providerID))
+}
+
+// key generates the Redis key for a provider's status
+func (c *RedisStatusCache) key(providerID string) string {
+	return fmt.Sprintf("provider_status:%s", providerID)
+}
+
+// MemoryStatusCache keeps provider statuses in this process when Redis is unavailable
+type MemoryStatusCache struct {
+	mu       sync.Mutex
+	statuses map[string]cachedStatus
+	now      func() time.Time
+}
+
+// cachedStatus is a provider status and when it stops being served
+type cachedStatus struct {
+	status    providers.ProviderStatus
+	expiresAt time.Time
+}
+
+func NewMemoryStatusCache() *MemoryStatusCache {
+	return &MemoryStatusCache{
+		statuses: make(map[string]cachedStatus),
+		now:      time.Now,
+	}
+}
+
+func (c *MemoryStatusCache) Get(ctx context.Context, providerID string) (*providers.ProviderStatus, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.statuses[providerID]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(cached.expiresAt) {
+		delete(c.statuses, providerID)
+		return nil, false
+	}
+	status := cached.status
+	return &status, true
+}
+
+func (c *MemoryStatusCache) Set(ctx context.Context, providerID string, status providers.ProviderStatus, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.statuses[providerID] = cachedStatus{status: status, expiresAt: c.now().Add(ttl)}
+}
+
+func (c *MemoryStatusCache) Delete(ctx context.Context, providerID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.statuses, providerID)
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrStreamNotFound is returned for streams that were never buffered or whose window passed
+var ErrStreamNotFound = apperrors.NotFound("stream not found or no longer buffered")
+
+// StreamBuffer keeps the chunks of streaming responses for a short window, so clients that lost
+// their connection mid-response can ask for the chunks they missed. Streams are keyed by chat and
+// stream ID; chunk positions start at 1.
+type StreamBuffer interface {
+	// Append adds the next chunk of a stream
+	Append(ctx context.Context, chatID int64, streamID, content string) error
+	// Finish marks a stream as ended
+	Finish(ctx context.Context, chatID int64, streamID string) error
+	// Since returns the chunks after position seq and whether the stream has ended
+	Since(ctx context.Context, chatID int64, streamID string, seq int64) ([]string, bool, error)
+}
+
+// RedisStreamBuffer keeps stream chunks in Redis, so a client can resume on any instance
+type RedisStreamBuffer struct {
+	redis  *redis.Client
+	window time.Duration
+}
+
+// NewRedisStreamBuffer keeps each stream until window passed without a new chunk
+func NewRedisStreamBuffer(redisClient *redis.Client, window time.Duration) *RedisStreamBuffer {
+	return &RedisStreamBuffer{redis: redisClient, window: window}
+}
+
+func (b *RedisStreamBuffer) Append(ctx context.Context, chatID int64, streamID, content string) error {
+	key := b.key(chatID, streamID)
+	pipe := b.redis.TxPipeline()
+	pipe.RPush(ctx, key, content)
+	pipe.Expire(ctx, key, b.window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to buffer stream chunk: %w", err)
+	}
+	return nil
+}
+
+func (b *RedisStreamBuffer) Finish(ctx context.Context, chatID int64, streamID string) error {
+	key := b.key(chatID, streamID)
+	pipe := b.redis.TxPipeline()
+	pipe.Set(ctx, key+":done", 1, b.window)
+	pipe.Expire(ctx, key, b.window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to finish buffered stream: %w", err)
+	}
+	return nil
+}
+
+func (b *RedisStreamBuffer) Since(ctx context.Context, chatID int64, streamID string, seq int64) ([]string, bool, error) {
+	if seq < 0 {
+		seq = 0
+	}
+	key := b.key(chatID, streamID)
+	pipe := b.redis.Pipeline()
+	length := pipe.LLen(ctx, key)
+	chunks := pipe.LRange(ctx, key, seq, -1)
+	done := pipe.Exists(ctx, key+":done")
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, false, fmt.Errorf("failed to read buffered stream: %w", err)
+	}
+
+	ended := done.Val() > 0
+	if length.Val() == 0 && !ended {
+		return nil, false, ErrStreamNotFound
+	}
+	return chunks.Val(), ended, nil
+}
+
+// key generates the Redis key holding a stream's chunks
+func (b *RedisStreamBuffer) key(chatID int64, streamID string) string {
+	return fmt.Sprintf("stream_buffer:%d:%s", chatID, streamID)
+}
+
+// MemoryStreamBuffer keeps stream chunks in this process when Redis is unavailable
+type MemoryStreamBuffer struct {
+	mu      sync.Mutex
+	streams map[string]*bufferedStream
+	window  time.Duration
+	now     func() time.Time
+}
+
+// bufferedStream is the chunks of one stream and when they are dropped
+type bufferedStream struct {
+	chunks    []string
+	done      bool
+	expiresAt time.Time
+}
+
+// NewMemoryStreamBuffer keeps each stream until window passed without a new chunk
+func NewMemoryStreamBuffer(window time.Duration) *MemoryStreamBuffer {
+	return &MemoryStreamBuffer{
+		streams: make(map[string]*bufferedStream),
+		window:  window,
+		now:     time.Now,
+	}
+}
+
+func (b *MemoryStreamBuffer) Append(ctx context.Context, chatID int64, streamID, content string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream := b.get(chatID, streamID)
+	if stream == nil {
+		// Streams nobody resumed are dropped when new ones start
+		b.sweep()
+		stream = &bufferedStream{}
+		b.streams[b.key(chatID, streamID)] = stream
+	}
+	stream.chunks = append(stream.chunks, content)
+	stream.expiresAt = b.now().Add(b.window)
+	return nil
+}
+
+func (b *MemoryStreamBuffer) Finish(ctx context.Context, chatID int64, streamID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream := b.get(chatID, streamID)
+	if stream == nil {
+		stream = &bufferedStream{}
+		b.streams[b.key(chatID, streamID)] = stream
+	}
+	stream.done = true
+	stream.expiresAt = b.now().Add(b.window)
+	return nil
+}
+
+func (b *MemoryStreamBuffer) Since(ctx context.Context, chatID int64, streamID string, seq int64) ([]string, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream := b.get(chatID, streamID)
+	if stream == nil {
+		return nil, false, ErrStreamNotFound
+	}
+	if seq < 0 {
+		seq = 0
+	}
+	if seq >= int64(len(stream.chunks)) {
+		return nil, stream.done, nil
+	}
+	return append([]string(nil), stream.chunks[seq:]...), stream.done, nil
+}
+
+// get returns a stream whose window hasn't passed, or nil; b.mu must be held
+func (b *MemoryStreamBuffer) get(chatID int64, streamID string) *bufferedStream {
+	key := b.key(chatID, streamID)
+	stream, ok := b.streams[key]
+	if !ok {
+		return nil
+	}
+	if !b.now().Before(stream.expiresAt) {
+		delete(b.streams, key)
+		return nil
+	}
+	return stream
+}
+
+// sweep drops every stream whose window passed; b.mu must be held
+func (b *MemoryStreamBuffer) sweep() {
+	now := b.now()
+	for key, stream := range b.streams {
+		if !now.Before(stream.expiresAt) {
+			delete(b.streams, key)
+		}
+	}
+}
+
+// key identifies a stream within the buffer
+func (b *MemoryStreamBuffer) key(chatID int64, streamID string) string {
+	return fmt.Sprintf("%d:%s", chatID, streamID)
+}
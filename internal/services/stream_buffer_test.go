@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStreamBuffer(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	buffer := NewMemoryStreamBuffer(time.Minute)
+	buffer.now = func() time.Time { return now }
+
+	_, _, err := buffer.Since(ctx, 1, "s1", 0)
+	assert.ErrorIs(t, err, ErrStreamNotFound)
+
+	for _, chunk := range []string{"a", "b", "c"} {
+		require.NoError(t, buffer.Append(ctx, 1, "s1", chunk))
+	}
+
+	chunks, done, err := buffer.Since(ctx, 1, "s1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, chunks)
+	assert.False(t, done)
+
+	chunks, _, err = buffer.Since(ctx, 1, "s1", 3)
+	require.NoError(t, err)
+	assert.Empty(t, chunks)
+
+	// Streams are keyed by chat as well
+	_, _, err = buffer.Since(ctx, 2, "s1", 0)
+	assert.ErrorIs(t, err, ErrStreamNotFound)
+
+	require.NoError(t, buffer.Finish(ctx, 1, "s1"))
+	chunks, done, err = buffer.Since(ctx, 1, "s1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, chunks)
+	assert.True(t, done)
+
+	// The window restarts with every chunk and ends a minute after the last
+	now = now.Add(time.Minute)
+	_, _, err = buffer.Since(ctx, 1, "s1", 0)
+	assert.ErrorIs(t, err, ErrStreamNotFound)
+}
+
+func TestMemoryStreamBuffer_SweepsExpiredStreams(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	buffer := NewMemoryStreamBuffer(time.Minute)
+	buffer.now = func() time.Time { return now }
+
+	require.NoError(t, buffer.Append(ctx, 1, "old", "a"))
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, buffer.Append(ctx, 1, "new", "b"))
+
+	assert.Len(t, buffer.streams, 1)
+}
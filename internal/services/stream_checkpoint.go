@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/utils"
+)
+
+// Longest a streamed response may take unless PROMPT_TIMEOUT says otherwise
+const StreamResponseTimeout = 5 * time.Minute
+
+// StreamCheckpointer saves a streaming assistant response every few bytes or seconds, so a crash
+// loses at most the last interval instead of the whole response
+type StreamCheckpointer struct {
+	ctx         context.Context
+	chatService *ChatService
+	chatID      int64
+	provider    string
+	everyBytes  int
+	every       time.Duration
+	message     *models.Message
+	savedBytes  int
+	savedAt     time.Time
+}
+
+// NewStreamCheckpointer returns a checkpointer for a response in a chat. It checkpoints once
+// everyBytes more bytes arrived or every has passed since the last checkpoint; zero disables either.
+func (s *ChatService) NewStreamCheckpointer(ctx context.Context, chatID int64, provider string, everyBytes int, every time.Duration) *StreamCheckpointer {
+	return &StreamCheckpointer{
+		ctx:         ctx,
+		chatService: s,
+		chatID:      chatID,
+		provider:    provider,
+		everyBytes:  everyBytes,
+		every:       every,
+		savedAt:     time.Now(),
+	}
+}
+
+// Update checkpoints the response received so far when a checkpoint is due; failures are only logged
+func (c *StreamCheckpointer) Update(content string) {
+	pending := len(content) - c.savedBytes
+	if pending <= 0 {
+		return
+	}
+	due := (c.everyBytes > 0 && pending >= c.everyBytes) || (c.every > 0 && time.Since(c.savedAt) >= c.every)
+	if !due {
+		return
+	}
+
+	var err error
+	if c.message == nil {
+		c.message, err = c.chatService.StartStreamingMessage(c.ctx, c.chatID, c.provider, content)
+	} else {
+		err = c.chatService.CheckpointMessage(c.ctx, c.message.ID, content)
+	}
+	if err != nil {
+		utils.Warn("Failed to checkpoint response for chat %d: %v", c.chatID, err)
+		return
+	}
+	c.savedBytes = len(content)
+	c.savedAt = time.Now()
+}
+
+// Finish saves the complete response, completing the checkpointed message if there is one
+func (c *StreamCheckpointer) Finish(content string) (*models.Message, error) {
+	if c.message == nil {
+		return c.chatService.AddProviderMessage(c.ctx, c.chatID, "assistant", content, c.provider)
+	}
+	return c.chatService.FinishStreamingMessage(c.ctx, c.chatID, c.message.ID, content)
+}
+
+// Discard removes the checkpointed message of a response that failed
+func (c *StreamCheckpointer) Discard() {
+	if c.message == nil {
+		return
+	}
+	if err := c.chatService.DiscardStreamingMessage(c.ctx, c.message.ID); err != nil {
+		utils.Warn("Failed to discard checkpointed response for chat %d: %v", c.chatID, err)
+	}
+	c.message = nil
+}
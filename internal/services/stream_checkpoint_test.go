@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCheckpointChat(t *testing.T) (*ChatService, *models.Chat) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	chatService := NewChatService(db)
+	chat, err := chatService.CreateChat(context.Background(), "Stream", "claude")
+	require.NoError(t, err)
+	return chatService, chat
+}
+
+func TestStreamCheckpointer_CheckpointsAndFinishes(t *testing.T) {
+	chatService, chat := newTestCheckpointChat(t)
+	checkpoint := chatService.NewStreamCheckpointer(context.Background(), chat.ID, "claude", 10, 0)
+
+	checkpoint.Update("short")
+	messages, err := chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, messages, "nothing is saved below the byte threshold")
+
+	partial := strings.Repeat("a", 12)
+	checkpoint.Update(partial)
+	messages, err = chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, models.MessageStreaming, messages[0].Status)
+	assert.Equal(t, partial, messages[0].Content)
+
+	checkpoint.Update(partial + strings.Repeat("b", 10))
+	msg, err := checkpoint.Finish(partial + strings.Repeat("b", 10) + "!")
+	require.NoError(t, err)
+	assert.Equal(t, messages[0].ID, msg.ID, "the checkpointed message is completed")
+	assert.Equal(t, models.MessageComplete, msg.Status)
+	assert.Equal(t, "claude", msg.Provider)
+
+	messages, err = chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.True(t, strings.HasSuffix(messages[0].Content, "b!"))
+}
+
+func TestStreamCheckpointer_Interval(t *testing.T) {
+	chatService, chat := newTestCheckpointChat(t)
+	checkpoint := chatService.NewStreamCheckpointer(context.Background(), chat.ID, "claude", 0, time.Millisecond)
+
+	time.Sleep(2 * time.Millisecond)
+	checkpoint.Update("a")
+	messages, err := chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "a", messages[0].Content)
+}
+
+func TestStreamCheckpointer_FinishWithoutCheckpoint(t *testing.T) {
+	chatService, chat := newTestCheckpointChat(t)
+	checkpoint := chatService.NewStreamCheckpointer(context.Background(), chat.ID, "claude", 1024, 0)
+
+	checkpoint.Update("hello")
+	msg, err := checkpoint.Finish("hello")
+	require.NoError(t, err)
+	assert.Equal(t, models.MessageComplete, msg.Status)
+	assert.Equal(t, "hello", msg.Content)
+}
+
+func TestStreamCheckpointer_Discard(t *testing.T) {
+	chatService, chat := newTestCheckpointChat(t)
+	checkpoint := chatService.NewStreamCheckpointer(context.Background(), chat.ID, "claude", 1, 0)
+
+	checkpoint.Update("partial")
+	checkpoint.Discard()
+
+	messages, err := chatService.GetMessages(context.Background(), chat.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestChatService_FlagInterruptedMessages(t *testing.T) {
+	chatService, chat := newTestCheckpointChat(t)
+	_, err := chatService.AddMessage(context.Background(), chat.ID, "user", "hi")
+	require.NoError(t, err)
+	streaming, err := chatService.StartStreamingMessage(context.Background(), chat.ID, "claude", "partial")
+	require.NoError(t, err)
+
+	flagged, err := chatService.FlagInterruptedMessages(context.Background(), time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), flagged, "recent streams may still be running elsewhere")
+
+	flagged, err = chatService.FlagInterruptedMessages(context.Background(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), flagged)
+
+	msg, err := chatService.GetMessage(context.Background(), chat.ID, streaming.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.MessageInterrupted, msg.Status)
+	assert.Equal(t, "partial", msg.Content)
+
+	// Interrupted messages are no longer updated by checkpoints
+	require.NoError(t, chatService.CheckpointMessage(context.Background(), streaming.ID, "more"))
+	msg, err = chatService.GetMessage(context.Background(), chat.ID, streaming.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "partial", msg.Content)
+}
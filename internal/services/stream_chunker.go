@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Stream chunking modes: what streamed output is held back until more of it arrives
+const (
+	StreamChunkingRune     = "rune"     // characters split between writes
+	StreamChunkingMarkdown = "markdown" // also unfinished code fence lines and emphasis markers
+)
+
+// maxHeldMarkdown is the most output held back for markdown; longer lines are sent as they arrive
+const maxHeldMarkdown = 256
+
+// StreamChunker decides how much of streamed output can be sent to clients rendering it as it
+// arrives, holding back the end that would break their rendering until more output arrives
+type StreamChunker struct {
+	markdown bool
+}
+
+func NewStreamChunker(mode string) StreamChunker {
+	return StreamChunker{markdown: mode == StreamChunkingMarkdown}
+}
+
+// Split returns the part of pending that is safe to send now and the part to hold back
+func (c StreamChunker) Split(pending string) (ready, held string) {
+	end := len(pending)
+	for i := len(pending) - 1; i >= 0 && i >= len(pending)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(pending[i]) {
+			if !utf8.FullRuneInString(pending[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+
+	if c.markdown {
+		if cut := markdownBoundary(pending[:end]); len(pending)-cut <= maxHeldMarkdown {
+			end = cut
+		}
+	}
+	return pending[:end], pending[end:]
+}
+
+// markdownBoundary returns where s can be cut without splitting a code fence line, which is held
+// until its newline, or a run of emphasis or code markers, which may go on
+func markdownBoundary(s string) int {
+	lineStart := strings.LastIndexByte(s, '\n') + 1
+	line := strings.TrimLeft(s[lineStart:], " ")
+	if line != "" && (line[0] == '`' || line[0] == '~') &&
+		(len(line) < 3 || strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~")) {
+		return lineStart
+	}
+	return len(strings.TrimRight(s, "*_~`"))
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamChunker_Split(t *testing.T) {
+	euro := "€"
+	tests := []struct {
+		name      string
+		mode      string
+		pending   string
+		wantReady string
+	}{
+		{name: "whole text", mode: StreamChunkingRune, pending: "Hello", wantReady: "Hello"},
+		{name: "split character", mode: StreamChunkingRune, pending: "1 " + euro[:2], wantReady: "1 "},
+		{name: "invalid bytes aren't held", mode: StreamChunkingRune, pending: "a\xff", wantReady: "a\xff"},
+		{name: "rune keeps markdown", mode: StreamChunkingRune, pending: "Code:\n``", wantReady: "Code:\n``"},
+		{name: "partial fence", mode: StreamChunkingMarkdown, pending: "Code:\n``", wantReady: "Code:\n"},
+		{name: "fence without newline", mode: StreamChunkingMarkdown, pending: "Code:\n  ```python", wantReady: "Code:\n"},
+		{name: "complete fence line", mode: StreamChunkingMarkdown, pending: "```python\nprint(1)", wantReady: "```python\nprint(1)"},
+		{name: "inline code", mode: StreamChunkingMarkdown, pending: "`x` is", wantReady: "`x` is"},
+		{name: "emphasis markers", mode: StreamChunkingMarkdown, pending: "This is **", wantReady: "This is "},
+		{name: "split character in markdown", mode: StreamChunkingMarkdown, pending: "*" + euro[:1], wantReady: ""},
+		{name: "long line is sent", mode: StreamChunkingMarkdown, pending: "```" + strings.Repeat("x", 300), wantReady: "```" + strings.Repeat("x", 300)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, held := NewStreamChunker(tt.mode).Split(tt.pending)
+			assert.Equal(t, tt.wantReady, ready)
+			assert.Equal(t, tt.pending, ready+held)
+		})
+	}
+}
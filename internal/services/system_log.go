@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/utils"
+)
+
+// ErrSystemLogNotFound is returned when system.log doesn't exist, e.g. before file logging started
+var ErrSystemLogNotFound = apperrors.NotFound("system log not found")
+
+// Most bytes a SystemLogTail reads at once, so a burst of logging is sent in several reads
+const maxSystemLogReadBytes = 1 << 20
+
+// SystemLogService follows system.log for the live log view of the admin pages
+type SystemLogService struct {
+	path         string
+	pollInterval time.Duration
+}
+
+// NewSystemLogService follows the system.log in logDir, looking for new lines every pollInterval
+func NewSystemLogService(logDir string, pollInterval time.Duration) *SystemLogService {
+	return &SystemLogService{path: filepath.Join(logDir, "system.log"), pollInterval: pollInterval}
+}
+
+// PollInterval returns how often followers look for new lines
+func (s *SystemLogService) PollInterval() time.Duration {
+	return s.pollInterval
+}
+
+// Tail opens system.log for following, with the last backlog lines still to be read
+func (s *SystemLogService) Tail(backlog int) (*SystemLogTail, error) {
+	file, info, err := openSystemLog(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := info.Size()
+	if backlog > 0 {
+		if offset, err = tailOffset(file, info.Size(), backlog); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read system log: %w", err)
+		}
+	}
+	return &SystemLogTail{path: s.path, file: file, info: info, offset: offset, last: models.LogEntry{Level: "info"}}, nil
+}
+
+// SystemLogTail reads the lines appended to system.log. A file truncated or replaced, e.g. by
+// logrotate, is read again from its start.
+type SystemLogTail struct {
+	path    string
+	file    *os.File
+	info    fs.FileInfo
+	offset  int64
+	partial string          // start of a line still being written
+	last    models.LogEntry // the latest entry, whose level and time continuation lines share
+}
+
+// Read returns the entries written since the last read
+func (t *SystemLogTail) Read() ([]models.LogEntry, error) {
+	if info, err := os.Stat(t.path); err == nil && !os.SameFile(info, t.info) {
+		file, info, err := openSystemLog(t.path)
+		if err != nil {
+			return nil, err
+		}
+		t.file.Close()
+		t.file, t.info, t.offset, t.partial = file, info, 0, ""
+	}
+
+	info, err := t.file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system log: %w", err)
+	}
+	if info.Size() < t.offset {
+		t.offset, t.partial = 0, ""
+	}
+	if info.Size() == t.offset {
+		return nil, nil
+	}
+
+	buf := make([]byte, min(info.Size()-t.offset, maxSystemLogReadBytes))
+	n, err := t.file.ReadAt(buf, t.offset)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read system log: %w", err)
+	}
+	t.offset += int64(n)
+
+	lines := strings.Split(t.partial+string(buf[:n]), "\n")
+	t.partial = lines[len(lines)-1]
+	var entries []models.LogEntry
+	for _, line := range lines[:len(lines)-1] {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		entry, ok := utils.ParseLogLine(line)
+		if !ok {
+			entry = models.LogEntry{Time: t.last.Time, Level: t.last.Level, Message: line}
+		}
+		t.last = entry
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Close closes the file
+func (t *SystemLogTail) Close() error {
+	return t.file.Close()
+}
+
+// openSystemLog opens system.log with its file information
+func openSystemLog(path string) (*os.File, fs.FileInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrSystemLogNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to open system log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to open system log: %w", err)
+	}
+	return file, info, nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemLogTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "system.log")
+	service := NewSystemLogService(dir, 10*time.Millisecond)
+
+	_, err := service.Tail(10)
+	assert.ErrorIs(t, err, ErrSystemLogNotFound)
+
+	require.NoError(t, os.WriteFile(path, []byte(
+		"[APP] 2026/10/16 - 10:00:00 | INFO | Starting\n"+
+			"[APP] 2026/10/16 - 10:00:01 | WARNING | Slow provider\n"+
+			"[APP] 2026/10/16 - 10:00:02 | ERROR | Failed: line one\n"+
+			"line two\n"), 0644))
+
+	tail, err := service.Tail(3)
+	require.NoError(t, err)
+	defer tail.Close()
+
+	entries, err := tail.Read()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "warning", entries[0].Level)
+	assert.Equal(t, "Slow provider", entries[0].Message)
+	assert.Equal(t, time.Date(2026, 10, 16, 10, 0, 1, 0, time.Local), entries[0].Time)
+	// Continuation lines belong to the entry before them
+	assert.Equal(t, models.LogEntry{Time: entries[1].Time, Level: "error", Message: "line two"}, entries[2])
+
+	entries, err = tail.Read()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Lines are returned once they are complete
+	appendLog := func(s string) {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		_, err = file.WriteString(s)
+		require.NoError(t, errors.Join(err, file.Close()))
+	}
+	appendLog("[APP] 2026/10/16 - 10:00:03 | DEBUG | Par")
+	entries, err = tail.Read()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	appendLog("tial\n")
+	entries, err = tail.Read()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Partial", entries[0].Message)
+
+	// A rotated log is read from its start
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("[APP] 2026/10/16 - 10:00:04 | INFO | Rotated\n"), 0644))
+	entries, err = tail.Read()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Rotated", entries[0].Message)
+
+	// So is a truncated one
+	require.NoError(t, os.WriteFile(path, []byte("[APP] 2026/10/16 - 10:00:05 | INFO | New\n"), 0644))
+	entries, err = tail.Read()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "New", entries[0].Message)
+}
+
+func TestSystemLogTail_NoBacklog(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "system.log"), []byte("[APP] 2026/10/16 - 10:00:00 | INFO | Old\n"), 0644))
+
+	tail, err := NewSystemLogService(dir, time.Second).Tail(0)
+	require.NoError(t, err)
+	defer tail.Close()
+	entries, err := tail.Read()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/tools"
+)
+
+// ErrToolNotFound is returned when a chat enables a tool that isn't available
+var ErrToolNotFound = apperrors.Validation("tool not found")
+
+// ToolService runs the server-side tools chats let providers call
+type ToolService struct {
+	db       database.Store
+	chats    *ChatService
+	registry *tools.Registry
+	maxSteps int
+	timeout  time.Duration
+}
+
+// NewToolService creates a tool service; a response may call at most maxSteps tools, each running
+// for at most timeout
+func NewToolService(db database.Store, chatService *ChatService, registry *tools.Registry, maxSteps int, timeout time.Duration) *ToolService {
+	return &ToolService{db: db, chats: chatService, registry: registry, maxSteps: maxSteps, timeout: timeout}
+}
+
+// MaxSteps is the most tool calls a response can make
+func (s *ToolService) MaxSteps() int {
+	return s.maxSteps
+}
+
+// enabled returns the names of the tools a chat enabled
+func (s *ToolService) enabled(ctx context.Context, chatID int64) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT tool FROM chat_tools WHERE chat_id = ?`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat tools: %w", err)
+	}
+	defer rows.Close()
+
+	enabled := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan chat tool: %w", err)
+		}
+		enabled[name] = true
+	}
+	return enabled, rows.Err()
+}
+
+// Toolset returns the available tools a chat enabled, in registration order; tools that are no
+// longer available are ignored
+func (s *ToolService) Toolset(ctx context.Context, chatID int64) (tools.Toolset, error) {
+	enabled, err := s.enabled(ctx, chatID)
+	if err != nil || len(enabled) == 0 {
+		return nil, err
+	}
+
+	var toolset tools.Toolset
+	for _, info := range s.registry.List() {
+		if enabled[info.Name] {
+			tool, _ := s.registry.Get(info.Name)
+			toolset = append(toolset, tool)
+		}
+	}
+	return toolset, nil
+}
+
+// Run runs a tool call of a toolset, bounded by the tool timeout
+func (s *ToolService) Run(ctx context.Context, toolset tools.Toolset, call tools.Call) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return toolset.Run(ctx, call)
+}
+
+// ChatTools lists the available tools with whether the chat enabled them
+func (s *ToolService) ChatTools(ctx context.Context, chatID int64) ([]models.ChatTool, error) {
+	if _, err := s.chats.GetChat(ctx, chatID); err != nil {
+		return nil, err
+	}
+	enabled, err := s.enabled(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := s.registry.List()
+	chatTools := make([]models.ChatTool, 0, len(infos))
+	for _, info := range infos {
+		chatTools = append(chatTools, models.ChatTool{Name: info.Name, Description: info.Description, Enabled: enabled[info.Name]})
+	}
+	return chatTools, nil
+}
+
+// SetChatTools replaces the tools a chat enables; an empty list disables tool calling
+func (s *ToolService) SetChatTools(ctx context.Context, chatID int64, names []string) error {
+	for _, name := range names {
+		if _, ok := s.registry.Get(name); !ok {
+			return fmt.Errorf("%w: %s", ErrToolNotFound, name)
+		}
+	}
+	if _, err := s.chats.GetChat(ctx, chatID); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chat_tools WHERE chat_id = ?`, chatID); err != nil {
+		return fmt.Errorf("failed to clear chat tools: %w", err)
+	}
+	now := time.Now()
+	query := `INSERT INTO chat_tools (chat_id, tool, created_at) VALUES (?, ?, ?) ON CONFLICT (chat_id, tool) DO NOTHING`
+	for _, name := range names {
+		if _, err := tx.ExecContext(ctx, query, chatID, name, now); err != nil {
+			return fmt.Errorf("failed to save chat tool: %w", err)
+		}
+	}
+	return tx.Commit()
+}
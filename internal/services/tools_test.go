@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/tools"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolService(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	chatService := NewChatService(db)
+	chat, err := chatService.CreateChat(ctx, "Tools", "claude")
+	require.NoError(t, err)
+	other, err := chatService.CreateChat(ctx, "Other", "claude")
+	require.NoError(t, err)
+
+	registry, err := tools.NewRegistry(tools.Calculator{}, tools.NewShell([]string{"echo"}, time.Second))
+	require.NoError(t, err)
+	toolService := NewToolService(db, chatService, registry, 3, time.Second)
+	assert.Equal(t, 3, toolService.MaxSteps())
+
+	// Chats use no tools until they enable some
+	toolset, err := toolService.Toolset(ctx, chat.ID)
+	require.NoError(t, err)
+	assert.Empty(t, toolset)
+
+	require.NoError(t, toolService.SetChatTools(ctx, chat.ID, []string{"shell", "calculator", "calculator"}))
+	toolset, err = toolService.Toolset(ctx, chat.ID)
+	require.NoError(t, err)
+	require.Len(t, toolset, 2)
+	assert.Equal(t, "calculator", toolset[0].Name())
+
+	out, err := toolService.Run(ctx, toolset, tools.Call{Name: "calculator", Input: "2 ^ 10"})
+	require.NoError(t, err)
+	assert.Equal(t, "1024", out)
+
+	chatTools, err := toolService.ChatTools(ctx, other.ID)
+	require.NoError(t, err)
+	require.Len(t, chatTools, 2)
+	assert.False(t, chatTools[0].Enabled)
+
+	// Setting the tools replaces them
+	require.NoError(t, toolService.SetChatTools(ctx, chat.ID, []string{"calculator"}))
+	chatTools, err = toolService.ChatTools(ctx, chat.ID)
+	require.NoError(t, err)
+	assert.True(t, chatTools[0].Enabled)
+	assert.False(t, chatTools[1].Enabled)
+
+	require.NoError(t, toolService.SetChatTools(ctx, chat.ID, nil))
+	toolset, err = toolService.Toolset(ctx, chat.ID)
+	require.NoError(t, err)
+	assert.Empty(t, toolset)
+
+	assert.ErrorIs(t, toolService.SetChatTools(ctx, chat.ID, []string{"http_fetch"}), ErrToolNotFound)
+	assert.ErrorIs(t, toolService.SetChatTools(ctx, 999, []string{"calculator"}), ErrChatNotFound)
+	_, err = toolService.ChatTools(ctx, 999)
+	assert.ErrorIs(t, err, ErrChatNotFound)
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+)
+
+// charsPerToken is the rough characters-per-token ratio used when a provider doesn't report usage
+const charsPerToken = 4
+
+// UsageService tracks byte and token usage per message and chat
+type UsageService struct {
+	db database.Store
+}
+
+func NewUsageService(db database.Store) *UsageService {
+	return &UsageService{db: db}
+}
+
+// EstimateTokens approximates the token count of a text from its character count
+func EstimateTokens(content string) int64 {
+	chars := int64(utf8.RuneCountInString(content))
+	return (chars + charsPerToken - 1) / charsPerToken
+}
+
+// RecordContent stores usage for a prompt or response, estimating tokens from its content
+func (s *UsageService) RecordContent(chatID int64, messageID *int64, provider, direction, content string) error {
+	return s.Record(&models.UsageRecord{
+		ChatID:    chatID,
+		MessageID: messageID,
+		Provider:  provider,
+		Direction: direction,
+		Bytes:     int64(len(content)),
+		Tokens:    EstimateTokens(content),
+		Estimated: true,
+	})
+}
+
+// Record stores a usage record, e.g. with token counts reported by a provider
+func (s *UsageService) Record(record *models.UsageRecord) error {
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO usage_records (chat_id, message_id, provider, direction, bytes, tokens, estimated, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+
+	err := s.db.QueryRow(query, record.ChatID, record.MessageID, record.Provider, record.Direction,
+		record.Bytes, record.Tokens, record.Estimated, record.CreatedAt.UnixMilli()).Scan(&record.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+
+	return nil
+}
+
+// GetChatUsage returns the usage totals and records of a chat
+func (s *UsageService) GetChatUsage(chatID int64) (*models.ChatUsage, error) {
+	query := `
+		SELECT id, chat_id, message_id, provider, direction, bytes, tokens, estimated, created_at
+		FROM usage_records
+		WHERE chat_id = ?
+		ORDER BY created_at ASC, id ASC
+	`
+
+	records, err := s.queryRecords(query, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.ChatUsage{ChatID: chatID, Records: records}
+	usage.UsageTotals, usage.Providers = sumUsage(records)
+	return usage, nil
+}
+
+// GetSummary aggregates usage across all chats since the given time
+func (s *UsageService) GetSummary(since time.Time) (*models.UsageSummary, error) {
+	query := `
+		SELECT id, chat_id, message_id, provider, direction, bytes, tokens, estimated, created_at
+		FROM usage_records
+		WHERE created_at >= ?
+		ORDER BY created_at ASC, id ASC
+	`
+
+	records, err := s.queryRecords(query, since.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+
+	chats := make(map[int64]bool)
+	for _, r := range records {
+		chats[r.ChatID] = true
+	}
+
+	summary := &models.UsageSummary{Since: since, Chats: int64(len(chats))}
+	summary.UsageTotals, summary.Providers = sumUsage(records)
+	return summary, nil
+}
+
+func (s *UsageService) queryRecords(query string, args ...interface{}) ([]*models.UsageRecord, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*models.UsageRecord
+	for rows.Next() {
+		var r models.UsageRecord
+		var messageID sql.NullInt64
+		var createdAt int64
+		if err := rows.Scan(&r.ID, &r.ChatID, &messageID, &r.Provider, &r.Direction, &r.Bytes, &r.Tokens, &r.Estimated, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan usage record: %w", err)
+		}
+		if messageID.Valid {
+			id := messageID.Int64
+			r.MessageID = &id
+		}
+		r.CreatedAt = time.UnixMilli(createdAt)
+		records = append(records, &r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate usage records: %w", err)
+	}
+
+	return records, nil
+}
+
+// sumUsage totals records overall and per provider (providers sorted by ID)
+func sumUsage(records []*models.UsageRecord) (models.UsageTotals, []*models.ProviderUsage) {
+	var total models.UsageTotals
+	byProvider := make(map[string]*models.ProviderUsage)
+
+	for _, r := range records {
+		p, ok := byProvider[r.Provider]
+		if !ok {
+			p = &models.ProviderUsage{Provider: r.Provider}
+			byProvider[r.Provider] = p
+		}
+		addUsage(&total, r)
+		addUsage(&p.UsageTotals, r)
+	}
+
+	providers := make([]*models.ProviderUsage, 0, len(byProvider))
+	for _, p := range byProvider {
+		providers = append(providers, p)
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Provider < providers[j].Provider
+	})
+
+	return total, providers
+}
+
+func addUsage(t *models.UsageTotals, r *models.UsageRecord) {
+	t.Count++
+	if r.Direction == models.UsageInput {
+		t.InputBytes += r.Bytes
+		t.InputTokens += r.Tokens
+	} else {
+		t.OutputBytes += r.Bytes
+		t.OutputTokens += r.Tokens
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, int64(0), EstimateTokens(""))
+	assert.Equal(t, int64(1), EstimateTokens("abc"))
+	assert.Equal(t, int64(2), EstimateTokens("hello"))
+	// Multi-byte characters count once
+	assert.Equal(t, int64(1), EstimateTokens("こんにちは"[:12]))
+}
+
+func TestUsageService(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	chatService := NewChatService(db)
+	usageService := NewUsageService(db)
+
+	chat, err := chatService.CreateChat(context.Background(), "Usage Chat", "claude")
+	require.NoError(t, err)
+	msg, err := chatService.AddMessage(context.Background(), chat.ID, "user", "12345678")
+	require.NoError(t, err)
+
+	require.NoError(t, usageService.RecordContent(chat.ID, &msg.ID, "claude", models.UsageInput, "12345678"))
+	require.NoError(t, usageService.RecordContent(chat.ID, &msg.ID, "gemini", models.UsageInput, "12345678"))
+	require.NoError(t, usageService.Record(&models.UsageRecord{
+		ChatID:    chat.ID,
+		Provider:  "claude",
+		Direction: models.UsageOutput,
+		Bytes:     100,
+		Tokens:    30,
+	}))
+
+	usage, err := usageService.GetChatUsage(chat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), usage.Count)
+	assert.Equal(t, int64(16), usage.InputBytes)
+	assert.Equal(t, int64(4), usage.InputTokens)
+	assert.Equal(t, int64(100), usage.OutputBytes)
+	assert.Equal(t, int64(30), usage.OutputTokens)
+	require.Len(t, usage.Providers, 2)
+	assert.Equal(t, "claude", usage.Providers[0].Provider)
+	assert.Equal(t, int64(32), usage.Providers[0].InputTokens+usage.Providers[0].OutputTokens)
+	require.Len(t, usage.Records, 3)
+	assert.True(t, usage.Records[0].Estimated)
+	assert.False(t, usage.Records[2].Estimated)
+	assert.Nil(t, usage.Records[2].MessageID)
+
+	summary, err := usageService.GetSummary(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), summary.Chats)
+	assert.Equal(t, int64(3), summary.Count)
+
+	summary, err = usageService.GetSummary(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, summary.Count)
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+)
+
+// AdminSettingsOwner owns the admin role's settings; other owners are "session:<id>" or "user:<id>"
+const AdminSettingsOwner = "admin"
+
+// UserSettingsService stores personal preferences in the user_settings table, so they follow the
+// admin or a session across browsers instead of living in cookies only
+type UserSettingsService struct {
+	db database.Store
+}
+
+func NewUserSettingsService(db database.Store) *UserSettingsService {
+	return &UserSettingsService{db: db}
+}
+
+// SessionSettingsOwner returns the settings owner of a browser session
+func SessionSettingsOwner(sessionID string) string {
+	return "session:" + sessionID
+}
+
+// UserSettingsOwner returns the settings owner of a user signed in with an access token
+func UserSettingsOwner(userID string) string {
+	return "user:" + userID
+}
+
+// Get returns the settings saved by owner, or nil when there are none
+func (s *UserSettingsService) Get(owner string) (*models.UserSettings, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT settings FROM user_settings WHERE owner = ?`, owner).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user settings: %w", err)
+	}
+
+	var settings models.UserSettings
+	if err := json.Unmarshal([]byte(value), &settings); err != nil {
+		return nil, fmt.Errorf("failed to decode user settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// Save replaces the settings of owner
+func (s *UserSettingsService) Save(owner string, settings models.UserSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to encode user settings: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_settings (owner, settings, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(owner) DO UPDATE SET settings = excluded.settings, updated_at = excluded.updated_at
+	`
+	if _, err := s.db.Exec(query, owner, string(data), time.Now()); err != nil {
+		return fmt.Errorf("failed to save user settings: %w", err)
+	}
+	return nil
+}
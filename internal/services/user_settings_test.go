@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserSettingsService(t *testing.T) {
+	db, err := database.InitTestDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	service := NewUserSettingsService(db)
+
+	settings, err := service.Get(AdminSettingsOwner)
+	require.NoError(t, err)
+	assert.Nil(t, settings, "nothing saved yet")
+
+	saved := models.UserSettings{Language: "ja", Theme: "dark", ChatInputBehavior: "enter_to_send", DefaultProvider: "claude"}
+	require.NoError(t, service.Save(AdminSettingsOwner, saved))
+	saved.DefaultModel = "sonnet"
+	require.NoError(t, service.Save(AdminSettingsOwner, saved))
+
+	settings, err = service.Get(AdminSettingsOwner)
+	require.NoError(t, err)
+	require.NotNil(t, settings)
+	assert.Equal(t, saved, *settings)
+
+	// Each session has its own settings
+	settings, err = service.Get(SessionSettingsOwner("abc"))
+	require.NoError(t, err)
+	assert.Nil(t, settings)
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	apperrors "ai-gateway-hub/internal/errors"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrInvalidWSTicket is returned for WebSocket tickets that were never issued, expired or were already used
+var ErrInvalidWSTicket = apperrors.Validation("invalid or expired WebSocket ticket")
+
+// TicketStore keeps issued WebSocket tickets until they are redeemed or expire
+type TicketStore interface {
+	// Put stores a ticket issued to a session
+	Put(ctx context.Context, ticket, sessionID string, ttl time.Duration) error
+	// Take removes a ticket and returns its session; ok is false for unknown or expired tickets
+	Take(ctx context.Context, ticket string) (sessionID string, ok bool, err error)
+}
+
+// RedisTicketStore keeps tickets in Redis, so a ticket issued by one instance is accepted by any
+type RedisTicketStore struct {
+	redis  *redis.Client
+	prefix string
+}
+
+func NewRedisTicketStore(redisClient *redis.Client) *RedisTicketStore {
+	return &RedisTicketStore{redis: redisClient, prefix: "ws_ticket"}
+}
+
+func (s *RedisTicketStore) Put(ctx context.Context, ticket, sessionID string, ttl time.Duration) error {
+	if err := s.redis.Set(ctx, s.key(ticket), sessionID, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store %s: %w", s.prefix, err)
+	}
+	return nil
+}
+
+func (s *RedisTicketStore) Take(ctx context.Context, ticket string) (string, bool, error) {
+	// Get and delete in one transaction so a ticket can't be redeemed twice
+	pipe := s.redis.TxPipeline()
+	get := pipe.Get(ctx, s.key(ticket))
+	pipe.Del(ctx, s.key(ticket))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return "", false, fmt.Errorf("failed to redeem %s: %w", s.prefix, err)
+	}
+	sessionID, err := get.Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to redeem %s: %w", s.prefix, err)
+	}
+	return sessionID, true, nil
+}
+
+func (s *RedisTicketStore) key(ticket string) string {
+	return fmt.Sprintf("%s:%s", s.prefix, ticket)
+}
+
+// MemoryTicketStore keeps tickets in this process when Redis is unavailable
+type MemoryTicketStore struct {
+	mu      sync.Mutex
+	tickets map[string]memoryTicket
+	now     func() time.Time
+}
+
+// memoryTicket is the session a ticket was issued to and when it expires
+type memoryTicket struct {
+	sessionID string
+	expiresAt time.Time
+}
+
+func NewMemoryTicketStore() *MemoryTicketStore {
+	return &MemoryTicketStore{
+		tickets: make(map[string]memoryTicket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryTicketStore) Put(ctx context.Context, ticket, sessionID string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop expired tickets that were never redeemed
+	now := s.now()
+	for id, t := range s.tickets {
+		if !now.Before(t.expiresAt) {
+			delete(s.tickets, id)
+		}
+	}
+	s.tickets[ticket] = memoryTicket{sessionID: sessionID, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryTicketStore) Take(ctx context.Context, ticket string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tickets[ticket]
+	if !ok {
+		return "", false, nil
+	}
+	delete(s.tickets, ticket)
+	if !s.now().Before(t.expiresAt) {
+		return "", false, nil
+	}
+	return t.sessionID, true, nil
+}
+
+// WSTicketService issues the tickets browsers pass when opening a WebSocket, since they can't set
+// headers on the upgrade request. A ticket is random, valid for a short time and redeemable once.
+type WSTicketService struct {
+	store TicketStore
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+func NewWSTicketService(store TicketStore, ttl time.Duration) *WSTicketService {
+	return &WSTicketService{store: store, ttl: ttl, now: time.Now}
+}
+
+// WSTicketGrant is who a ticket was issued to, as stored in the TicketStore
+type WSTicketGrant struct {
+	SessionID string `json:"session_id"` // empty for clients without a session
+	UserID    string `json:"user_id"`    // participant shown to the other viewers of a chat
+	Role      string `json:"role"`
+}
+
+// GuestUserID is the participant ID of a session without a signed-in user: stable for the
+// session, without revealing its ID
+func GuestUserID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return "guest-" + hex.EncodeToString(sum[:4])
+}
+
+// Issue creates a ticket for the session, participant and role of the request that asked for it
+func (s *WSTicketService) Issue(ctx context.Context, grant WSTicketGrant) (*models.WSTicket, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate WebSocket ticket: %w", err)
+	}
+	ticket := hex.EncodeToString(b)
+
+	value, err := json.Marshal(grant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode WebSocket ticket: %w", err)
+	}
+	if err := s.store.Put(ctx, ticket, string(value), s.ttl); err != nil {
+		return nil, err
+	}
+	return &models.WSTicket{Ticket: ticket, ExpiresAt: s.now().Add(s.ttl)}, nil
+}
+
+// Redeem uses up a ticket and returns who it was issued to
+func (s *WSTicketService) Redeem(ctx context.Context, ticket string) (*WSTicketGrant, error) {
+	if ticket == "" {
+		return nil, ErrInvalidWSTicket
+	}
+	value, ok, err := s.store.Take(ctx, ticket)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidWSTicket
+	}
+	var grant WSTicketGrant
+	if err := json.Unmarshal([]byte(value), &grant); err != nil {
+		return nil, ErrInvalidWSTicket
+	}
+	return &grant, nil
+}
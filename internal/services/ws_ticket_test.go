@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSTicketService_SingleUse(t *testing.T) {
+	ctx := context.Background()
+	service := NewWSTicketService(NewMemoryTicketStore(), 30*time.Second)
+
+	grant := WSTicketGrant{SessionID: "session-1", UserID: "alice", Role: "user"}
+	ticket, err := service.Issue(ctx, grant)
+	require.NoError(t, err)
+	assert.Len(t, ticket.Ticket, 64)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), ticket.ExpiresAt, time.Second)
+
+	other, err := service.Issue(ctx, grant)
+	require.NoError(t, err)
+	assert.NotEqual(t, ticket.Ticket, other.Ticket)
+
+	redeemed, err := service.Redeem(ctx, ticket.Ticket)
+	require.NoError(t, err)
+	assert.Equal(t, grant, *redeemed)
+
+	_, err = service.Redeem(ctx, ticket.Ticket)
+	assert.ErrorIs(t, err, ErrInvalidWSTicket)
+	_, err = service.Redeem(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidWSTicket)
+	_, err = service.Redeem(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrInvalidWSTicket)
+}
+
+func TestMemoryTicketStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTicketStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Put(ctx, "a", "session-a", 30*time.Second))
+	require.NoError(t, store.Put(ctx, "b", "", 30*time.Second))
+
+	now = now.Add(31 * time.Second)
+	_, ok, err := store.Take(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ok, "expired tickets can't be redeemed")
+
+	// Issuing drops tickets that expired without being redeemed
+	require.NoError(t, store.Put(ctx, "c", "session-c", 30*time.Second))
+	assert.Len(t, store.tickets, 1)
+
+	sessionID, ok, err := store.Take(ctx, "c")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "session-c", sessionID)
+}
+
+func TestGuestUserID(t *testing.T) {
+	id := GuestUserID("session-1")
+	assert.Regexp(t, `^guest-[0-9a-f]{8}$`, id)
+	assert.Equal(t, id, GuestUserID("session-1"))
+	assert.NotEqual(t, id, GuestUserID("session-2"))
+	assert.NotContains(t, id, "session-1")
+}
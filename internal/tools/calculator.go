@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Calculator evaluates arithmetic expressions, so providers don't have to do the math
+type Calculator struct{}
+
+func (Calculator) Name() string { return "calculator" }
+
+func (Calculator) Description() string {
+	return `Evaluates an arithmetic expression with + - * / % ^ and parentheses. Input: the expression, e.g. "(2 + 3) * 4.5"`
+}
+
+func (Calculator) Run(_ context.Context, input string) (string, error) {
+	value, err := Evaluate(input)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64), nil
+}
+
+// maxExpressionLength bounds the recursion of the parser
+const maxExpressionLength = 1024
+
+// Evaluate computes an arithmetic expression; ^ is exponentiation and binds tighter than unary minus
+func Evaluate(expr string) (float64, error) {
+	if len(expr) > maxExpressionLength {
+		return 0, fmt.Errorf("expression is longer than %d characters", maxExpressionLength)
+	}
+	p := &exprParser{input: expr}
+	value, err := p.expression()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos+1)
+	}
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return value, nil
+}
+
+// exprParser is a recursive descent parser of
+//
+//	expression = term { ("+" | "-") term }
+//	term       = unary { ("*" | "/" | "%") unary }
+//	unary      = ("+" | "-") unary | power
+//	power      = primary [ "^" unary ]
+//	primary    = number | "(" expression ")"
+type exprParser struct {
+	input string
+	pos   int
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t' || p.input[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+// accept consumes the next character if it is one of ops
+func (p *exprParser) accept(ops string) (byte, bool) {
+	p.skipSpace()
+	if p.pos < len(p.input) && strings.IndexByte(ops, p.input[p.pos]) >= 0 {
+		p.pos++
+		return p.input[p.pos-1], true
+	}
+	return 0, false
+}
+
+func (p *exprParser) expression() (float64, error) {
+	value, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op, ok := p.accept("+-")
+		if !ok {
+			return value, nil
+		}
+		right, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			value += right
+		} else {
+			value -= right
+		}
+	}
+}
+
+func (p *exprParser) term() (float64, error) {
+	value, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op, ok := p.accept("*/%")
+		if !ok {
+			return value, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			value *= right
+		case '/':
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			value /= right
+		case '%':
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			value = math.Mod(value, right)
+		}
+	}
+}
+
+func (p *exprParser) unary() (float64, error) {
+	if op, ok := p.accept("+-"); ok {
+		value, err := p.unary()
+		if op == '-' {
+			value = -value
+		}
+		return value, err
+	}
+	return p.power()
+}
+
+func (p *exprParser) power() (float64, error) {
+	base, err := p.primary()
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := p.accept("^"); !ok {
+		return base, nil
+	}
+	exponent, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+func (p *exprParser) primary() (float64, error) {
+	if _, ok := p.accept("("); ok {
+		value, err := p.expression()
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		return value, nil
+	}
+
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	// Exponents, as in 1.5e3
+	if p.pos > start && p.pos < len(p.input) && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
+		next := p.pos + 1
+		if next < len(p.input) && (p.input[next] == '+' || p.input[next] == '-') {
+			next++
+		}
+		if next < len(p.input) && p.input[next] >= '0' && p.input[next] <= '9' {
+			p.pos = next
+			for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+				p.pos++
+			}
+		}
+	}
+	if p.pos == start {
+		if p.pos >= len(p.input) {
+			return 0, fmt.Errorf("unexpected end of expression")
+		}
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos+1)
+	}
+	value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+	}
+	return value, nil
+}
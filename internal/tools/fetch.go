@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress is returned for fetches of loopback, private and link-local addresses
+var errPrivateAddress = errors.New("fetching private addresses is not allowed")
+
+// Fetch gets web pages over HTTP(S). With allowed hosts it only fetches those hosts (and their
+// subdomains); otherwise it fetches any host but refuses private addresses, so providers can't
+// reach the server's network.
+type Fetch struct {
+	allowedHosts []string
+	client       *http.Client
+}
+
+// NewFetch creates the fetch tool; timeout bounds each fetch, redirects included
+func NewFetch(allowedHosts []string, timeout time.Duration) *Fetch {
+	f := &Fetch{}
+	for _, host := range allowedHosts {
+		f.allowedHosts = append(f.allowedHosts, strings.ToLower(strings.TrimSpace(host)))
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if len(f.allowedHosts) == 0 {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	f.client = &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			return f.check(req.URL)
+		},
+	}
+	return f
+}
+
+func (f *Fetch) Name() string { return "http_fetch" }
+
+func (f *Fetch) Description() string {
+	return `Fetches a web page or API response with an HTTP GET. Input: the URL, e.g. "https://example.com/page"`
+}
+
+func (f *Fetch) Run(ctx context.Context, input string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(input))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := f.check(u); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxResultBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return Truncate(fmt.Sprintf("HTTP %d %s\n\n%s", resp.StatusCode, resp.Header.Get("Content-Type"), body)), nil
+}
+
+// check allows http(s) URLs of the allowed hosts
+func (f *Fetch) check(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("only http and https URLs can be fetched")
+	}
+	if len(f.allowedHosts) == 0 {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range f.allowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s is not allowed", host)
+}
+
+// isPrivate reports whether an IP is not a public internet address
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() || ip.IsInterfaceLocalMulticast()
+}
@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Shell runs whitelisted commands. Input is split on whitespace and run without a shell, so pipes,
+// redirects, globs and variables are passed to the command as plain arguments.
+type Shell struct {
+	commands []string
+	timeout  time.Duration
+}
+
+// NewShell creates the shell tool running the named commands, each for at most timeout
+func NewShell(commands []string, timeout time.Duration) *Shell {
+	return &Shell{commands: commands, timeout: timeout}
+}
+
+func (s *Shell) Name() string { return "shell" }
+
+func (s *Shell) Description() string {
+	return fmt.Sprintf(`Runs a command on the server, without a shell (no pipes, redirects or quoting). Allowed commands: %s. Input: the command line, e.g. "%s"`,
+		strings.Join(s.commands, ", "), s.commands[0])
+}
+
+func (s *Shell) Run(ctx context.Context, input string) (string, error) {
+	args := strings.Fields(input)
+	if len(args) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+	if !s.allowed(args[0]) {
+		return "", fmt.Errorf("command %q is not allowed, expected one of %s", args[0], strings.Join(s.commands, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	var output limitedBuffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("command timed out after %s", s.timeout)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return Truncate(fmt.Sprintf("%s\n[exit status %d]", output.String(), exitErr.ExitCode())), nil
+		}
+		return "", fmt.Errorf("command failed: %w", err)
+	}
+	return Truncate(output.String()), nil
+}
+
+// limitedBuffer keeps the start of a command's output, enough to be truncated, and drops the rest
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := MaxResultBytes + 1 - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// allowed reports whether a command is whitelisted; paths are never allowed, so the whitelist
+// can't be bypassed with ./ or absolute paths
+func (s *Shell) allowed(name string) bool {
+	if strings.ContainsRune(name, '/') {
+		return false
+	}
+	for _, command := range s.commands {
+		if command == name {
+			return true
+		}
+	}
+	return false
+}
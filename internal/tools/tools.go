@@ -0,0 +1,161 @@
+// Package tools lets CLI providers call server-side tools. Providers are told which tools a chat
+// may use and how to call them; a response containing a <tool_call> marker has the tool run and
+// the provider re-prompted with the result until it answers without calling one.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Markers around tool calls in provider output and results in follow-up prompts
+const (
+	CallStart   = "<tool_call>"
+	CallEnd     = "</tool_call>"
+	resultStart = "<tool_result"
+	resultEnd   = "</tool_result>"
+)
+
+// MaxResultBytes is the most of a tool's output sent back to the provider
+const MaxResultBytes = 16 * 1024
+
+// Tool is a server-side function providers can call
+type Tool interface {
+	Name() string
+	// Description tells providers what the tool does and what input it takes
+	Description() string
+	Run(ctx context.Context, input string) (string, error)
+}
+
+// Info describes a tool for listings
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Registry holds the tools chats can enable
+type Registry struct {
+	tools []Tool
+}
+
+// NewRegistry creates a registry of tools, which must have unique names
+func NewRegistry(tools ...Tool) (*Registry, error) {
+	seen := make(map[string]bool)
+	for _, tool := range tools {
+		if seen[tool.Name()] {
+			return nil, fmt.Errorf("duplicate tool %s", tool.Name())
+		}
+		seen[tool.Name()] = true
+	}
+	return &Registry{tools: tools}, nil
+}
+
+// Get returns a tool by name
+func (r *Registry) Get(name string) (Tool, bool) {
+	for _, tool := range r.tools {
+		if tool.Name() == name {
+			return tool, true
+		}
+	}
+	return nil, false
+}
+
+// List describes the tools in registration order
+func (r *Registry) List() []Info {
+	infos := make([]Info, 0, len(r.tools))
+	for _, tool := range r.tools {
+		infos = append(infos, Info{Name: tool.Name(), Description: tool.Description()})
+	}
+	return infos
+}
+
+// Toolset is the tools a chat enabled
+type Toolset []Tool
+
+// Instructions tells providers which tools they can call and how
+func (ts Toolset) Instructions() string {
+	var b strings.Builder
+	b.WriteString("You can call the following tools. To call one, reply with a single line\n")
+	b.WriteString(CallStart + `{"name": "<tool>", "input": "<input>"}` + CallEnd + "\n")
+	b.WriteString("and stop there; the result will be sent to you in a " + resultStart + "> block so you can continue.\n")
+	b.WriteString("Only call a tool when you need it to answer.\n\nTools:\n")
+	for _, tool := range ts {
+		fmt.Fprintf(&b, "- %s: %s\n", tool.Name(), tool.Description())
+	}
+	return b.String()
+}
+
+// Run runs a call with the tool of the set it names
+func (ts Toolset) Run(ctx context.Context, call Call) (string, error) {
+	if call.Err != nil {
+		return "", call.Err
+	}
+	for _, tool := range ts {
+		if tool.Name() == call.Name {
+			return tool.Run(ctx, call.Input)
+		}
+	}
+	return "", fmt.Errorf("unknown tool %q", call.Name)
+}
+
+// Call is a tool call found in provider output
+type Call struct {
+	Name  string
+	Input string
+	Err   error // set when the call isn't valid JSON with a tool name
+}
+
+// FindCall returns the first complete tool call in text and the offset just past its end marker
+func FindCall(text string) (Call, int, bool) {
+	start := strings.Index(text, CallStart)
+	if start < 0 {
+		return Call{}, 0, false
+	}
+	length := strings.Index(text[start+len(CallStart):], CallEnd)
+	if length < 0 {
+		return Call{}, 0, false
+	}
+	body := strings.TrimSpace(text[start+len(CallStart) : start+len(CallStart)+length])
+	end := start + len(CallStart) + length + len(CallEnd)
+
+	var raw struct {
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	}
+	if err := json.Unmarshal([]byte(body), &raw); err != nil || raw.Name == "" {
+		return Call{Err: fmt.Errorf(`invalid tool call, expected {"name": "<tool>", "input": "<input>"}`)}, end, true
+	}
+	call := Call{Name: raw.Name}
+	// Inputs are strings, but structured inputs are passed on as JSON
+	if len(raw.Input) > 0 && json.Unmarshal(raw.Input, &call.Input) != nil {
+		call.Input = string(raw.Input)
+	}
+	return call, end, true
+}
+
+// FormatResult is the follow-up prompt giving a provider the result of its tool call
+func FormatResult(call Call, output string, err error) string {
+	name := call.Name
+	if name == "" {
+		name = "unknown"
+	}
+	if err != nil {
+		output = "error: " + err.Error()
+	}
+	output = Truncate(output)
+	return fmt.Sprintf("%s name=%q>\n%s\n%s\nContinue your answer using this result.", resultStart, name, output, resultEnd)
+}
+
+// Truncate cuts output to MaxResultBytes, on a UTF-8 boundary
+func Truncate(output string) string {
+	if len(output) <= MaxResultBytes {
+		return output
+	}
+	cut := MaxResultBytes
+	for cut > 0 && output[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return output[:cut] + "\n[truncated]"
+}
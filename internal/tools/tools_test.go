@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	for expr, want := range map[string]float64{
+		"1 + 2 * 3":     7,
+		"(1 + 2) * 3":   9,
+		"-2 ^ 2":        -4,
+		"2 ^ 3 ^ 2":     512,
+		"10 % 4 - 1":    1,
+		"1.5e3 / -3":    -500,
+		" ( ( 4 ) ) ":   4,
+		"7 / 2":         3.5,
+		"2 * -(3 + -1)": -4,
+	} {
+		got, err := Evaluate(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, want, got, expr)
+	}
+
+	for _, expr := range []string{"", "1 +", "(1 + 2", "1 / 0", "2 $ 3", "1 2", "10 ^ 400"} {
+		_, err := Evaluate(expr)
+		assert.Error(t, err, expr)
+	}
+
+	out, err := Calculator{}.Run(context.Background(), "0.1 + 0.2")
+	require.NoError(t, err)
+	assert.Equal(t, "0.30000000000000004", out)
+}
+
+func TestFindCall(t *testing.T) {
+	text := "Let me check.\n<tool_call>{\"name\": \"calculator\", \"input\": \"6 * 7\"}</tool_call>\nThe answer is 42."
+	call, end, ok := FindCall(text)
+	require.True(t, ok)
+	assert.Equal(t, Call{Name: "calculator", Input: "6 * 7"}, call)
+	assert.Equal(t, "Let me check.\n<tool_call>{\"name\": \"calculator\", \"input\": \"6 * 7\"}</tool_call>", text[:end])
+
+	// Structured inputs are passed on as JSON
+	call, _, ok = FindCall(`<tool_call>{"name": "lookup", "input": {"id": 1}}</tool_call>`)
+	require.True(t, ok)
+	assert.Equal(t, `{"id": 1}`, call.Input)
+
+	call, _, ok = FindCall(`<tool_call>calculator 6 * 7</tool_call>`)
+	require.True(t, ok)
+	assert.Error(t, call.Err)
+
+	_, _, ok = FindCall("no call here")
+	assert.False(t, ok)
+	_, _, ok = FindCall(`<tool_call>{"name": "calculator", "input": "1"}`)
+	assert.False(t, ok, "incomplete calls are not run")
+}
+
+func TestToolset(t *testing.T) {
+	registry, err := NewRegistry(Calculator{}, NewShell([]string{"echo"}, time.Second))
+	require.NoError(t, err)
+	_, err = NewRegistry(Calculator{}, Calculator{})
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"calculator", "shell"}, []string{registry.List()[0].Name, registry.List()[1].Name})
+	calculator, ok := registry.Get("calculator")
+	require.True(t, ok)
+
+	toolset := Toolset{calculator}
+	assert.Contains(t, toolset.Instructions(), "- calculator: Evaluates")
+	assert.NotContains(t, toolset.Instructions(), "- shell:")
+
+	out, err := toolset.Run(context.Background(), Call{Name: "calculator", Input: "6 * 7"})
+	require.NoError(t, err)
+	assert.Equal(t, "42", out)
+	_, err = toolset.Run(context.Background(), Call{Name: "shell", Input: "echo hi"})
+	assert.EqualError(t, err, `unknown tool "shell"`)
+
+	result := FormatResult(Call{Name: "calculator"}, "42", nil)
+	assert.Equal(t, "<tool_result name=\"calculator\">\n42\n</tool_result>\nContinue your answer using this result.", result)
+	assert.Contains(t, FormatResult(Call{Name: "calculator"}, "", assert.AnError), "error: "+assert.AnError.Error())
+}
+
+func TestShell(t *testing.T) {
+	shell := NewShell([]string{"echo", "false"}, 5*time.Second)
+
+	out, err := shell.Run(context.Background(), "echo hello  world; rm -rf /")
+	require.NoError(t, err)
+	assert.Equal(t, "hello world; rm -rf /\n", out, "arguments are not interpreted by a shell")
+
+	out, err = shell.Run(context.Background(), "false")
+	require.NoError(t, err)
+	assert.Contains(t, out, "[exit status 1]")
+
+	for _, input := range []string{"", "ls", "/bin/echo hi", "./echo hi"} {
+		_, err := shell.Run(context.Background(), input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	// Without allowed hosts, private addresses are refused
+	_, err := NewFetch(nil, 5*time.Second).Run(context.Background(), server.URL)
+	assert.ErrorIs(t, err, errPrivateAddress)
+
+	allowed := NewFetch([]string{"127.0.0.1"}, 5*time.Second)
+	out, err := allowed.Run(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP 200 text/plain\n\nhello", out)
+
+	_, err = allowed.Run(context.Background(), "https://example.com")
+	assert.EqualError(t, err, "host example.com is not allowed")
+	_, err = allowed.Run(context.Background(), "file:///etc/passwd")
+	assert.Error(t, err)
+}
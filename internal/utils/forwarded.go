@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+)
+
+type forwardedHTTPSKey struct{}
+
+// WithForwardedHTTPS returns a copy of ctx recording that a trusted proxy received the request over HTTPS
+func WithForwardedHTTPS(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forwardedHTTPSKey{}, true)
+}
+
+// IsHTTPS reports whether the client connected over HTTPS, directly or through a trusted proxy
+func IsHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	forwarded, _ := r.Context().Value(forwardedHTTPSKey{}).(bool)
+	return forwarded
+}
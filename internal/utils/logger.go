@@ -6,6 +6,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"ai-gateway-hub/internal/models"
 
 	"github.com/sirupsen/logrus"
 )
@@ -25,6 +28,42 @@ func (f *GinStyleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	return []byte(logLine), nil
 }
 
+// ParseLogLine parses a line written by GinStyleFormatter. Other lines, such as the continuation
+// of a multi-line message, aren't entries.
+func ParseLogLine(line string) (models.LogEntry, bool) {
+	rest, ok := strings.CutPrefix(line, "[APP] ")
+	if !ok {
+		return models.LogEntry{}, false
+	}
+	parts := strings.SplitN(rest, " | ", 3)
+	if len(parts) != 3 {
+		return models.LogEntry{}, false
+	}
+	timestamp, err := time.ParseInLocation("2006/01/02 - 15:04:05", parts[0], time.Local)
+	if err != nil {
+		return models.LogEntry{}, false
+	}
+	level, err := logrus.ParseLevel(parts[1])
+	if err != nil {
+		return models.LogEntry{}, false
+	}
+	return models.LogEntry{Time: timestamp, Level: level.String(), Message: parts[2]}, true
+}
+
+// LogLevelAllows reports whether entries of level pass a filter of the minimum level min, e.g.
+// "warn" lets warnings and errors through
+func LogLevelAllows(min, level string) bool {
+	minLevel, err := logrus.ParseLevel(min)
+	if err != nil {
+		return true
+	}
+	entryLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		return true
+	}
+	return entryLevel <= minLevel
+}
+
 // InitLogger initializes the global logger with specified level
 func InitLogger(levelStr string) {
 	logger = logrus.New()
@@ -35,6 +74,17 @@ func InitLogger(levelStr string) {
 	
 	// Set Gin-style formatter
 	logger.SetFormatter(&GinStyleFormatter{})
+
+	// Mask secrets and personal data first, then keep recent errors for the admin dashboard
+	logger.AddHook(redactHook{})
+	logger.AddHook(recentErrors)
+}
+
+// SetLogLevel changes the level of the global logger, e.g. when the configuration is reloaded
+func SetLogLevel(levelStr string) {
+	if logger != nil {
+		logger.SetLevel(parseLogLevel(levelStr))
+	}
 }
 
 // InitFileLogging sets up file logging in addition to console logging
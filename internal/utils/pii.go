@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Kinds of personal data and credentials the PII redactor recognises
+const (
+	PIIEmail      = "email"
+	PIIAPIKey     = "api_key"
+	PIICreditCard = "credit_card"
+)
+
+// piiPatterns matches the built-in kinds. Card numbers are 13 to 19 digits, optionally grouped
+// with spaces or dashes, and only masked when they pass the Luhn check.
+var piiPatterns = map[string]*regexp.Regexp{
+	PIIEmail: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	PIIAPIKey: regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{20,}|AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|AIza[0-9A-Za-z_-]{35}|xox[abprs]-[A-Za-z0-9-]{10,})` +
+		`|(?i:bearer)\s+[A-Za-z0-9._~+/-]{20,}=*`),
+	PIICreditCard: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+}
+
+// PIIKinds lists the built-in kinds in the order they are applied
+func PIIKinds() []string {
+	return []string{PIIAPIKey, PIIEmail, PIICreditCard}
+}
+
+// piiRule masks the matches of a pattern that pass valid, if set
+type piiRule struct {
+	pattern     *regexp.Regexp
+	valid       func(string) bool
+	placeholder string
+}
+
+// PIIRedactor masks personal data and credentials in text
+type PIIRedactor struct {
+	rules []piiRule
+}
+
+// NewPIIRedactor creates a redactor for the given built-in kinds and custom regular expressions.
+// Built-in matches are replaced with a placeholder naming the kind, custom ones with RedactedPlaceholder.
+func NewPIIRedactor(kinds []string, custom []string) (*PIIRedactor, error) {
+	r := &PIIRedactor{}
+	for _, kind := range PIIKinds() {
+		if !containsString(kinds, kind) {
+			continue
+		}
+		rule := piiRule{pattern: piiPatterns[kind], placeholder: "[" + strings.ToUpper(kind) + "]"}
+		if kind == PIICreditCard {
+			rule.valid = luhnValid
+		}
+		r.rules = append(r.rules, rule)
+	}
+	for _, kind := range kinds {
+		if piiPatterns[kind] == nil {
+			return nil, fmt.Errorf("unknown PII kind %q", kind)
+		}
+	}
+	for _, expr := range custom {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PII pattern %q: %w", expr, err)
+		}
+		r.rules = append(r.rules, piiRule{pattern: pattern, placeholder: RedactedPlaceholder})
+	}
+	return r, nil
+}
+
+// Redact masks every match of the redactor's rules in s
+func (r *PIIRedactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, rule := range r.rules {
+		if rule.valid == nil {
+			s = rule.pattern.ReplaceAllLiteralString(s, rule.placeholder)
+			continue
+		}
+		s = rule.pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if rule.valid(match) {
+				return rule.placeholder
+			}
+			return match
+		})
+	}
+	return s
+}
+
+// luhnValid reports whether the digits in s form a number passing the Luhn checksum
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// piiRedaction is the redactor applied to system logs and chat log files, if any
+var piiRedaction struct {
+	mu       sync.RWMutex
+	redactor *PIIRedactor
+	logs     bool
+	chatLogs bool
+}
+
+// SetPIIRedaction sets the redactor applied to system logs and chat log files; a nil redactor
+// turns redaction off
+func SetPIIRedaction(redactor *PIIRedactor, logs, chatLogs bool) {
+	piiRedaction.mu.Lock()
+	defer piiRedaction.mu.Unlock()
+	piiRedaction.redactor = redactor
+	piiRedaction.logs = logs
+	piiRedaction.chatLogs = chatLogs
+}
+
+// piiRedactorFor returns the redactor applied to system logs or to chat logs, or nil
+func piiRedactorFor(chatLogs bool) *PIIRedactor {
+	piiRedaction.mu.RLock()
+	defer piiRedaction.mu.RUnlock()
+	if (chatLogs && !piiRedaction.chatLogs) || (!chatLogs && !piiRedaction.logs) {
+		return nil
+	}
+	return piiRedaction.redactor
+}
+
+// RedactPII masks personal data in s if redaction of system logs is on
+func RedactPII(s string) string {
+	return piiRedactorFor(false).Redact(s)
+}
+
+// ChatLog is a chat log file. With PII redaction on for chat logs, writes are held back until a
+// line is complete so values streamed in several chunks are still masked.
+type ChatLog struct {
+	file     *os.File
+	redactor *PIIRedactor
+	pending  []byte
+}
+
+// CreateChatLog opens a chat log file for appending, creating it and its directory if needed
+func CreateChatLog(path string) (*ChatLog, error) {
+	file, err := CreateFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ChatLog{file: file, redactor: piiRedactorFor(true)}, nil
+}
+
+// Write implements io.Writer
+func (l *ChatLog) Write(p []byte) (int, error) {
+	if l.redactor == nil {
+		return l.file.Write(p)
+	}
+
+	l.pending = append(l.pending, p...)
+	end := bytes.LastIndexByte(l.pending, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	if err := l.writeRedacted(l.pending[:end+1]); err != nil {
+		return 0, err
+	}
+	l.pending = append(l.pending[:0], l.pending[end+1:]...)
+	return len(p), nil
+}
+
+// writeRedacted masks personal data in complete lines and writes them to the file
+func (l *ChatLog) writeRedacted(lines []byte) error {
+	_, err := l.file.WriteString(l.redactor.Redact(string(lines)))
+	return err
+}
+
+// Close writes what is left of the last line and closes the file
+func (l *ChatLog) Close() error {
+	if len(l.pending) > 0 {
+		if err := l.writeRedacted(l.pending); err != nil {
+			l.file.Close()
+			return err
+		}
+		l.pending = nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"sync"
+
+	"ai-gateway-hub/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Number of error log entries kept for the admin dashboard
+const RecentErrorsSize = 50
+
+// recentErrorsHook keeps the latest error-level entries in a ring buffer
+type recentErrorsHook struct {
+	mu      sync.Mutex
+	entries []models.LogEntry
+	next    int
+}
+
+var recentErrors = &recentErrorsHook{}
+
+// Levels implements logrus.Hook
+func (h *recentErrorsHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+// Fire implements logrus.Hook
+func (h *recentErrorsHook) Fire(entry *logrus.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	e := models.LogEntry{Time: entry.Time, Level: entry.Level.String(), Message: entry.Message}
+	if len(h.entries) < RecentErrorsSize {
+		h.entries = append(h.entries, e)
+		return nil
+	}
+	h.entries[h.next] = e
+	h.next = (h.next + 1) % RecentErrorsSize
+	return nil
+}
+
+// RecentErrors returns the latest error log entries, newest first
+func RecentErrors() []models.LogEntry {
+	recentErrors.mu.Lock()
+	defer recentErrors.mu.Unlock()
+
+	n := len(recentErrors.entries)
+	result := make([]models.LogEntry, 0, n)
+	for i := 0; i < n; i++ {
+		// Walk backwards from the most recently written slot
+		idx := (recentErrors.next - 1 - i + n) % n
+		result = append(result, recentErrors.entries[idx])
+	}
+	return result
+}
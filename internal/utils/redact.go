@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RedactedPlaceholder replaces secret values in logs and API responses
+const RedactedPlaceholder = "[REDACTED]"
+
+// minSecretLength keeps very short values, which would mangle unrelated text, out of the redactor
+const minSecretLength = 4
+
+// secretRegistry holds the secret values that must never appear in logs
+type secretRegistry struct {
+	mu       sync.RWMutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+var secrets = &secretRegistry{values: make(map[string]bool)}
+
+// RegisterSecret marks a value as secret so Redact, and every log line, masks it
+func RegisterSecret(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minSecretLength {
+		return
+	}
+
+	secrets.mu.Lock()
+	defer secrets.mu.Unlock()
+	if secrets.values[value] {
+		return
+	}
+	secrets.values[value] = true
+
+	// Longer values first, so a secret containing another is masked whole
+	values := make([]string, 0, len(secrets.values))
+	for v := range secrets.values {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	pairs := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		pairs = append(pairs, v, RedactedPlaceholder)
+	}
+	secrets.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact masks every registered secret value in s
+func Redact(s string) string {
+	secrets.mu.RLock()
+	replacer := secrets.replacer
+	secrets.mu.RUnlock()
+
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// redactHook masks secrets, and personal data if PII redaction is on, in log entries before other hooks and the formatter see them
+type redactHook struct{}
+
+// Levels implements logrus.Hook
+func (redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = RedactPII(Redact(entry.Message))
+	return nil
+}
@@ -2,32 +2,46 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"embed"
+	"errors"
+	"flag"
+	"fmt"
 	"html/template"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"ai-gateway-hub/internal/auth"
+	"ai-gateway-hub/internal/buildinfo"
 	"ai-gateway-hub/internal/config"
 	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/documents"
+	"ai-gateway-hub/internal/encryption"
 	"ai-gateway-hub/internal/handlers"
 	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/jobs"
 	"ai-gateway-hub/internal/middleware"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/moderation"
+	"ai-gateway-hub/internal/processing"
+	"ai-gateway-hub/internal/secrets"
+	"ai-gateway-hub/internal/server"
 	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/tools"
 	"ai-gateway-hub/internal/utils"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/go-redis/redis/v8"
 )
 
-// Version information (set during build)
-var version = "dev"
-
 //go:embed web/templates/*.html web/templates/pages/*.html web/templates/components/*.html
 var templateFiles embed.FS
 
@@ -38,18 +52,40 @@ var localeFiles embed.FS
 var envExampleFile embed.FS
 
 func main() {
-	// Initialize path manager first
-	if err := utils.InitPathManager(); err != nil {
-		log.Fatalf("Failed to initialize path manager: %v", err)
+	// Without a subcommand the server runs, so `ai-gateway-hub -migrate up` keeps working
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printUsage(os.Stdout)
+		return
 	}
 
-	// Load .env file if exists to get log configuration early
-	if err := godotenv.Load(); err != nil {
-		log.Printf("No .env file found or failed to load: %v", err)
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		os.Exit(2)
 	}
+	if err := cmd.run(args); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
 
-	// Load configuration with environment-specific settings
-	cfg := config.LoadWithEnvironment()
+// runServe runs the HTTP server until it receives SIGINT or SIGTERM
+func runServe(args []string) error {
+	startedAt := time.Now()
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	migrateCmd := flags.String("migrate", "", "deprecated, use the migrate command: run database migrations and exit")
+	migrateSteps := flags.Int("steps", 1, "number of migrations to roll back with -migrate down")
+	flags.Parse(args)
+
+	cfg, envFile, err := loadConfig()
+	if err != nil {
+		return err
+	}
 
 	// Validate configuration
 	validationResult := config.ValidateEnvironment(cfg)
@@ -57,8 +93,20 @@ func main() {
 		log.Fatalf("Configuration validation failed:\n%s", validationResult.Summary())
 	}
 
-	// Initialize logging early
+	// Initialize logging early, masking configured credentials
 	utils.InitLogger(cfg.LogLevel)
+	utils.RegisterSecret(cfg.AdminToken)
+	utils.RegisterSecret(cfg.ConfigBundleSecret)
+	utils.RegisterSecret(cfg.ShareLinkSecret)
+	utils.RegisterSecret(cfg.VaultToken)
+	utils.RegisterSecret(cfg.JWTSecret)
+	if cfg.EnablePIIRedaction {
+		redactor, err := utils.NewPIIRedactor(cfg.PIIRedactTypes, cfg.PIIRedactPatterns)
+		if err != nil {
+			log.Fatalf("Failed to set up PII redaction: %v", err)
+		}
+		utils.SetPIIRedaction(redactor, cfg.RedactsPII("logs"), cfg.RedactsPII("chat_logs"))
+	}
 	
 	// Initialize file logging
 	if err := utils.InitFileLogging(cfg.LogDir); err != nil {
@@ -68,7 +116,9 @@ func main() {
 		utils.SetAsDefaultLogger()
 	}
 	
+	build := buildinfo.Get(cfg.FeatureFlags())
 	utils.Info("AI Gateway Hub starting...")
+	utils.Info("Build: %s", build.LogLine())
 	utils.Info("Environment: %s", config.GetCurrentEnvironment())
 	utils.Info("Log level: %s", cfg.LogLevel)
 	
@@ -86,85 +136,257 @@ func main() {
 	if err := initializeI18n(); err != nil {
 		utils.Warn("Failed to initialize i18n: %v. Using default strings.", err)
 	}
+	// Customized translations that silently fall back to English are worth a warning
+	for _, issues := range i18n.Get().Validate() {
+		for _, warning := range issues.Warnings(i18n.Get().DefaultLanguage()) {
+			utils.Warn("Translations %s: %s", issues.Language, warning)
+		}
+	}
+	// Pick up edits to the extracted locale files without a restart
+	go i18n.Get().Watch(context.Background(), i18n.WatchInterval)
 
 	// Extract .env.example (always update)
 	if err := extractEnvExample(); err != nil {
 		utils.Warn("Failed to extract .env.example: %v", err)
 	}
 
+	// Run a migration command instead of the server
+	if *migrateCmd != "" {
+		return runMigrateCommand(cfg, *migrateCmd, *migrateSteps)
+	}
+
 	// Initialize database
-	db, err := database.InitSQLite(cfg.SQLiteDBFile)
+	db, err := openDatabase(cfg)
 	if err != nil {
-		utils.Fatal("Failed to initialize SQLite: %v", err)
+		utils.Fatal("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
-	// Initialize Redis
-	redisClient := database.InitRedis(cfg.RedisAddr)
+	// Initialize Redis; without it sessions, provider statuses and prompt quotas are kept in memory
+	redisClient, err := database.InitRedis(cfg.RedisAddr)
+	if err != nil && cfg.RedisRequired {
+		utils.Fatal("Redis is required: %v", err)
+	}
 	defer redisClient.Close()
 
+	storeBackend := services.StoreBackendRedis
+	var sessionStore services.SessionStore = services.NewRedisSessionStore(redisClient)
+	var statusCache services.StatusCache = services.NewRedisStatusCache(redisClient)
+	var quotaStore services.QuotaStore = services.NewRedisQuotaStore(redisClient)
+	if err != nil {
+		utils.Warn("%v; keeping sessions, provider statuses and prompt quotas in memory, they are lost on restart and not shared between instances", err)
+		storeBackend = services.StoreBackendMemory
+		sessionStore = services.NewMemorySessionStore()
+		statusCache = services.NewMemoryStatusCache()
+		quotaStore = services.NewMemoryQuotaStore()
+	}
+
 	// Initialize services
-	sessionService := services.NewSessionService(redisClient)
+	sessionService := services.NewSessionService(sessionStore)
 	chatService := services.NewChatService(db)
-	providerRegistry := services.NewProviderRegistry(redisClient)
+	chatService.SetMessageLimits(services.MessageLimits{
+		MaxPromptLength:  cfg.MaxPromptLength,
+		MaxResponseBytes: cfg.MaxResponseBytes,
+		OversizedAction:  cfg.OversizedResponseAction,
+	})
+	generationService := services.NewGenerationService(db)
+	usageService := services.NewUsageService(db)
+	quotaService := services.NewQuotaService(quotaStore, int64(cfg.DailyPromptQuota), int64(cfg.MonthlyPromptQuota))
+	feedbackService := services.NewFeedbackService(db)
+	analyticsService := services.NewAnalyticsService(db)
+	settingsService := services.NewSettingsService(db)
+	userSettingsService := services.NewUserSettingsService(db)
+	greetingService := services.NewGreetingService(settingsService, chatService)
+	attachmentService := services.NewAttachmentService(db, cfg.AttachmentsDir, int64(cfg.AttachmentMaxSizeMB)<<20, cfg.AttachmentAllowedTypes)
+	chatLogService := services.NewChatLogService(cfg.LogDir)
+	systemLogService := services.NewSystemLogService(cfg.LogDir, 500*time.Millisecond)
+	providerRegistry := services.NewProviderRegistry(statusCache)
+	secretManager := secrets.NewManager(secretsOptions(cfg))
+	providerRegistry.SetSecretResolver(secretManager)
+	if cfg.ProviderCircuitThreshold > 0 {
+		providerRegistry.SetCircuitBreaker(services.NewCircuitBreaker(cfg.ProviderCircuitThreshold, cfg.ProviderCircuitCooldown))
+	}
+	providerRegistry.SetFailoverChains(cfg.FailoverChains())
+	// Encrypt message content at rest; messages written before it was enabled stay readable
+	messageCipher, err := newMessageCipher(cfg, secretManager)
+	if err != nil {
+		utils.Fatal("Failed to set up message encryption: %v", err)
+	}
+	if messageCipher != nil {
+		chatService.SetEncryption(messageCipher)
+		feedbackService.SetEncryption(messageCipher)
+	}
+	// Stateless sign-in with JWTs when AUTH_MODE=jwt
+	authService, err := newAuthService(cfg, redisClient, storeBackend)
+	if err != nil {
+		utils.Fatal("Failed to set up JWT authentication: %v", err)
+	}
 	
 	// Register providers
 	if err := providerRegistry.RegisterDefaultProviders(cfg); err != nil {
 		utils.Warn("Failed to register default providers: %v", err)
 	}
+	// Share links are signed with SHARE_LINK_SECRET, or with a random secret until the next restart
+	shareSecret := []byte(cfg.ShareLinkSecret)
+	if len(shareSecret) == 0 {
+		shareSecret = make([]byte, 32)
+		if _, err := rand.Read(shareSecret); err != nil {
+			utils.Fatal("Failed to generate share link secret: %v", err)
+		}
+	}
+	shareService := services.NewShareService(db, chatService, shareSecret)
+	configBundleService := services.NewConfigBundleService(cfg, providerRegistry, settingsService, greetingService)
+	adminStatsService := services.NewAdminStatsService(db, redisClient, storeBackend, sessionService, providerRegistry)
+	readinessService := services.NewReadinessService(db, redisClient, storeBackend, providerRegistry)
+	// Re-read .env and the environment on SIGHUP or from the admin API, applying the runtime settings
+	configReloadService := services.NewConfigReloadService(cfg, func() (*config.Config, error) {
+		if err := envFile.Refresh(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		return config.LoadWithEnvironment(), nil
+	}, quotaService, providerRegistry)
+	scheduleService := services.NewScheduleService(db)
+	schedulerService := services.NewSchedulerService(scheduleService, chatService, providerRegistry, usageService)
+	// Moderation of prompts and responses; recorded events can be listed even while it is disabled
+	moderationPipeline := moderation.NewPipeline(false)
+	if cfg.EnableModeration {
+		moderationPipeline, err = newModerationPipeline(cfg, secretManager)
+		if err != nil {
+			utils.Fatal("Failed to set up moderation: %v", err)
+		}
+	}
+	moderationService := services.NewModerationService(db, moderationPipeline)
+	if cfg.EnableModeration {
+		schedulerService.SetModeration(moderationService)
+	}
+	// Document collections; the documents processor adds the chunks closest to a prompt to it in
+	// chats linked to a collection
+	embedder, err := newEmbedder(cfg, secretManager)
+	if err != nil {
+		utils.Fatal("Failed to set up the embedder: %v", err)
+	}
+	documentService := services.NewDocumentService(db, chatService, embedder, services.NewDatabaseVectorStore(db), services.DocumentOptions{
+		MaxSize:      int64(cfg.DocumentMaxSizeMB) << 20,
+		ChunkSize:    cfg.RAGChunkSize,
+		ChunkOverlap: cfg.RAGChunkOverlap,
+		TopK:         cfg.RAGTopK,
+	})
+	// Processors transforming prompts and responses, configured by PROCESSORS_FILE
+	processingPipeline, err := newProcessingPipeline(cfg, secretManager,
+		processing.Step{Name: "documents", Stage: processing.StagePre, Enabled: true, Processor: documentService})
+	if err != nil {
+		utils.Fatal("Failed to set up processors: %v", err)
+	}
+	processingService := services.NewProcessingService(db, chatService, processingPipeline)
+	schedulerService.SetProcessing(processingService)
+	// Server-side tools chats can let providers call, enabled by TOOLS_ENABLED
+	toolRegistry, err := newToolRegistry(cfg)
+	if err != nil {
+		utils.Fatal("Failed to set up tools: %v", err)
+	}
+	toolService := services.NewToolService(db, chatService, toolRegistry, cfg.ToolsMaxSteps, cfg.ToolsTimeout)
+	// One-shot completions for scripts, moderated and processed like WebSocket prompts
+	completionService := services.NewCompletionService(chatService, providerRegistry, usageService, cfg.PromptTimeouts)
+	if cfg.EnableModeration {
+		completionService.SetModeration(moderationService)
+	}
+	completionService.SetProcessing(processingService)
+
+	// Responses still streaming when the server stopped were only checkpointed; flag them as interrupted.
+	// Other instances may be streaming right now, so with the backplane only streams past the timeout are flagged.
+	interruptedBefore := time.Now()
+	if cfg.EnableWSBackplane {
+		interruptedBefore = interruptedBefore.Add(-cfg.LongestPromptTimeout())
+	}
+	if count, err := chatService.FlagInterruptedMessages(context.Background(), interruptedBefore); err != nil {
+		utils.Warn("Failed to flag interrupted responses: %v", err)
+	} else if count > 0 {
+		utils.Info("Flagged %d interrupted responses", count)
+	}
+
+	// Background jobs, started once the hub is up
+	var backgroundJobs []jobs.Job
+
+	// Schedule provider health checks
+	healthService := services.NewHealthCheckService(providerRegistry, redisClient, cfg.HealthCheckInterval, cfg.HealthCheckHistorySize)
+	if cfg.EnableHealthChecks {
+		backgroundJobs = append(backgroundJobs, healthService.Job())
+	}
+
+	// Schedule purging of deleted chats
+	if cfg.DeletedChatRetentionDays > 0 {
+		purgeService := services.NewChatPurgeService(chatService, attachmentService, time.Duration(cfg.DeletedChatRetentionDays)*24*time.Hour)
+		backgroundJobs = append(backgroundJobs, purgeService.Job())
+		utils.Info("Deleted chats will be purged after %d days", cfg.DeletedChatRetentionDays)
+	}
+
+	// Schedule the retention rules; they can also be run from the admin API
+	retentionService := services.NewRetentionService(chatService, models.RetentionPolicy{
+		IdleChatDays:       cfg.RetentionIdleChatDays,
+		MaxMessagesPerChat: cfg.RetentionMaxMessagesPerChat,
+		LogDays:            cfg.RetentionLogDays,
+		IntervalSeconds:    int(cfg.RetentionInterval / time.Second),
+		DryRun:             cfg.RetentionDryRun,
+	}, cfg.LogDir)
+	if retentionService.Enabled() {
+		backgroundJobs = append(backgroundJobs, retentionService.Job())
+	}
 
 	// Setup logging level and Gin mode based on configuration
 	setupLogging(cfg.LogLevel)
 
 	// Initialize Gin router with custom logging
 	router := gin.New()
-	
-	// Load embedded HTML templates FIRST (before any routes or middleware)
-	templateFS, err := fs.Sub(templateFiles, "web/templates")
-	if err != nil {
-		log.Fatalf("Failed to create template file system: %v", err)
+	// Only honor X-Forwarded-For from the configured reverse proxies (gin trusts every proxy by default)
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		utils.Fatal("Invalid TRUSTED_PROXIES: %v", err)
 	}
+	router.Use(middleware.ProxyHeadersMiddleware(cfg.TrustedProxies))
 	
-	// Create template with functions - language will be passed via template data
-	tmpl := template.New("").Funcs(template.FuncMap{
-		"T": func(lang any, key string, args ...any) string {
-			langStr := "en"
-			if lang != nil {
-				if l, ok := lang.(string); ok && l != "" {
-					langStr = l
-				}
-			}
-			return i18n.T(langStr, key, args...)
-		},
-	})
-	tmpl = template.Must(tmpl.ParseFS(templateFS, "*.html", "pages/*.html", "components/*.html"))
-	router.SetHTMLTemplate(tmpl)
+	// Load HTML templates FIRST (before any routes or middleware): from TEMPLATE_DIR with
+	// reloading in development, otherwise embedded
+	loadTemplates(router, cfg)
 	
+	// Assign request IDs first so the access log and handlers can include them
+	router.Use(middleware.RequestIDMiddleware())
+
 	// Add custom logging middleware that writes to our logger
-	router.Use(gin.LoggerWithWriter(utils.GetLogger().Out))
+	router.Use(middleware.RequestLogger())
 	router.Use(gin.Recovery())
 
+	// Compress pages and API responses such as chat histories; the WebSocket endpoint streams its own frames
+	if cfg.EnableCompression {
+		router.Use(middleware.CompressionMiddleware(middleware.CompressionOptions{
+			Level:         cfg.CompressionLevel,
+			MinSize:       cfg.CompressionMinSize,
+			ContentTypes:  cfg.CompressionTypes,
+			ExcludedPaths: []string{"/ws"},
+		}))
+	}
+
 	// Setup middleware
 	router.Use(middleware.I18nMiddleware())
+	router.Use(middleware.ThemeMiddleware())
+	if authService != nil {
+		// Requests with a valid access token skip the session and CSRF checks
+		router.Use(middleware.JWTMiddleware(cfg, authService))
+	}
+	router.Use(middleware.SessionMiddleware(sessionService, cfg.SessionTimeout))
+	router.Use(middleware.CSRFMiddleware(cfg))
 
-	// Setup CORS with environment-specific settings
+	// Setup CORS with the origins from ALLOWED_ORIGINS (shared with the WebSocket origin check)
 	corsConfig := cors.Config{
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.CSRFHeaderName, utils.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", utils.RequestIDHeader},
 		AllowCredentials: true,
 	}
-	
-	// Environment-specific CORS origins
-	if config.GetCurrentEnvironment() == config.Production {
-		// Production should use specific allowed origins
-		corsConfig.AllowOrigins = []string{
-			"https://yourdomain.com",
-			"https://www.yourdomain.com",
-		}
+	if cfg.AllowsAllOrigins() {
+		// Answered with "Access-Control-Allow-Origin: *", which browsers never combine with cookies
+		corsConfig.AllowAllOrigins = true
+		corsConfig.AllowCredentials = false
 	} else {
-		// Development allows all origins for easier testing
-		corsConfig.AllowOrigins = []string{"*"}
+		corsConfig.AllowOriginFunc = cfg.OriginAllowed
 	}
 	
 	router.Use(cors.New(corsConfig))
@@ -173,51 +395,288 @@ func main() {
 	router.Static("/static", cfg.StaticDir)
 
 	// Initialize WebSocket hub
-	hub := handlers.NewHub(sessionService, chatService, providerRegistry)
+	hub := handlers.NewHub(sessionService, chatService, providerRegistry, generationService, usageService, attachmentService)
+	if cfg.InstanceID != "" {
+		hub.SetInstanceID(cfg.InstanceID)
+	}
+	hub.SetStreamCheckpoints(cfg.StreamCheckpointBytes, cfg.StreamCheckpointInterval)
+	hub.SetStreamFlush(cfg.StreamFlushBytes, cfg.StreamFlushInterval)
+	hub.SetStreamChunking(cfg.StreamChunkingFor)
+	if cfg.StreamResumeWindow > 0 {
+		if storeBackend == services.StoreBackendRedis {
+			hub.SetStreamResume(services.NewRedisStreamBuffer(redisClient, cfg.StreamResumeWindow))
+		} else {
+			hub.SetStreamResume(services.NewMemoryStreamBuffer(cfg.StreamResumeWindow))
+		}
+	}
+	// WebSocket connections authenticate with tickets from POST /api/ws/ticket
+	var ticketStore services.TicketStore = services.NewMemoryTicketStore()
+	if storeBackend == services.StoreBackendRedis {
+		ticketStore = services.NewRedisTicketStore(redisClient)
+	}
+	wsTicketService := services.NewWSTicketService(ticketStore, cfg.WSTicketTTL)
+	hub.SetTickets(wsTicketService)
+	hub.SetPromptTimeouts(cfg.PromptTimeouts)
+	hub.SetRetryPolicy(services.RetryPolicy{
+		MaxAttempts: cfg.ProviderRetryAttempts,
+		Backoff:     cfg.ProviderRetryBackoff,
+		MaxBackoff:  cfg.ProviderRetryMaxBackoff,
+		RetryOn:     cfg.ProviderRetryOn,
+	})
+	hub.SetPromptQuotas(quotaService)
+	if cfg.EnableModeration {
+		hub.SetModeration(moderationService)
+	}
+	hub.SetProcessing(processingService)
+	hub.SetTools(toolService)
+	// Prompt drafts survive reloads and are shared between devices through Redis
+	var draftStore services.DraftStore = services.NewMemoryDraftStore()
+	if storeBackend == services.StoreBackendRedis {
+		draftStore = services.NewRedisDraftStore(redisClient)
+	}
+	draftService := services.NewDraftService(draftStore, chatService, cfg.DraftTTL)
+	hub.SetDrafts(draftService)
+	if cfg.EnableWSBackplane {
+		if err := hub.EnableBackplane(redisClient); err != nil {
+			utils.Warn("WebSocket backplane unavailable, messages stay on this instance: %v", err)
+		} else {
+			defer hub.StopBackplane()
+		}
+	}
 	go hub.Run()
+	chatService.OnChange(hub.NotifyChatListChanged)
+
+	// Run scheduled prompts, delivering the responses to the clients viewing their chats
+	schedulerService.OnRun(hub.NotifyScheduledRun)
+	if cfg.EnableScheduledPrompts {
+		backgroundJobs = append(backgroundJobs, schedulerService.Job())
+	}
+	defer schedulerService.Stop()
+
+	// Run the background jobs; with Redis, exclusive jobs run on one instance at a time
+	var jobLocker jobs.Locker = jobs.NewLocalLocker()
+	if storeBackend == services.StoreBackendRedis {
+		jobLocker = jobs.NewRedisLocker(redisClient, hub.InstanceID())
+	}
+	jobScheduler := jobs.NewScheduler(jobLocker)
+	for _, job := range backgroundJobs {
+		if err := jobScheduler.Register(job); err != nil {
+			utils.Fatal("Failed to register background job: %v", err)
+		}
+	}
+	jobScheduler.Start()
+	defer jobScheduler.Stop()
 
 	// Initialize API handlers with proper dependency injection
 	apiHandlers := handlers.NewAPIHandlers(log.Default())
 
 	// Setup routes
+	router.GET("/healthz", handlers.LivenessHandler())
+	router.GET("/readyz", handlers.ReadinessHandler(readinessService))
 	router.GET("/", handlers.IndexHandler())
-	router.GET("/chat/:id", handlers.ChatHandler(chatService))
-	router.GET("/settings", handlers.SettingsHandler())
+	router.GET("/chat/:id", handlers.ChatHandler(chatService, attachmentService))
+	router.GET("/share/:token", handlers.SharedChatHandler(shareService))
+	router.GET("/new", middleware.RequireRole(cfg, sessionService, models.RoleUser), handlers.NewChatFromTemplateHandler(chatService, providerRegistry, greetingService))
+	router.POST("/new", middleware.RequireRole(cfg, sessionService, models.RoleUser), handlers.NewChatFromTemplateHandler(chatService, providerRegistry, greetingService))
+	router.GET("/settings", handlers.SettingsHandler(func(c *gin.Context) bool {
+		return middleware.IsAdmin(c, cfg, sessionService)
+	}))
+
+	// Admin pages
+	adminOnly := middleware.AdminMiddleware(cfg, sessionService)
+
+	// Personal settings belong to the admin role across browsers, otherwise to the signed-in user
+	// or the session; requests without either keep them in cookies only
+	settingsOwner := func(c *gin.Context) string {
+		if middleware.IsAdmin(c, cfg, sessionService) {
+			return services.AdminSettingsOwner
+		}
+		if claims, ok := middleware.AuthClaims(c); ok {
+			return services.UserSettingsOwner(claims.Subject)
+		}
+		if sessionID := c.GetString(middleware.SessionContextKey); sessionID != "" {
+			return services.SessionSettingsOwner(sessionID)
+		}
+		return ""
+	}
+	router.GET(middleware.AdminLoginPath, handlers.AdminLoginPageHandler(cfg))
+	router.POST(middleware.AdminLoginPath, handlers.AdminLoginHandler(cfg, sessionService))
+	router.POST("/admin/logout", handlers.AdminLogoutHandler(sessionService))
+	router.GET("/admin", adminOnly, handlers.AdminDashboardHandler(adminStatsService))
+	router.GET("/admin/logs", adminOnly, handlers.AdminLogsHandler())
 
-	// API routes
-	api := router.Group("/api")
+	// WebSocket connections act as the signed-in user, or as a guest of their session, with the
+	// role of the request that got their ticket
+	wsIdentity := func(c *gin.Context) (string, string) {
+		role := middleware.RequestRole(c, cfg, sessionService)
+		if claims, ok := middleware.AuthClaims(c); ok {
+			return claims.Subject, role
+		}
+		if sessionID := c.GetString(middleware.SessionContextKey); sessionID != "" {
+			return services.GuestUserID(sessionID), role
+		}
+		return "", role
+	}
+
+	// API routes; reads need the viewer role and writes the user role
+	api := router.Group("/api", middleware.RBACMiddleware(cfg, sessionService))
 	{
-		api.GET("/health", handlers.HealthCheckHandler(redisClient, version))
+		api.GET("/health", handlers.HealthCheckHandler(redisClient, storeBackend, build))
+		api.GET("/version", handlers.VersionHandler(build))
 		api.GET("/chats", apiHandlers.GetChatsHandler(chatService))
-		api.POST("/chats", apiHandlers.CreateChatHandler(chatService))
+		api.POST("/chats", apiHandlers.CreateChatHandler(chatService, greetingService))
+		api.POST("/chats/bulk", apiHandlers.BulkChatsHandler(chatService))
+		api.GET("/chats/:id", apiHandlers.GetChatHandler(chatService))
 		api.DELETE("/chats/:id", apiHandlers.DeleteChatHandler(chatService))
+		api.POST("/chats/:id/fork", apiHandlers.ForkChatHandler(chatService))
+		api.POST("/chats/:id/archive", apiHandlers.ArchiveChatHandler(chatService))
+		api.POST("/chats/:id/restore", apiHandlers.RestoreChatHandler(chatService))
+		api.PUT("/chats/:id/provider", apiHandlers.SwitchProviderHandler(chatService, providerRegistry))
+		api.PUT("/chats/:id/system-prompt", apiHandlers.UpdateSystemPromptHandler(chatService))
+		api.GET("/chats/:id/messages", apiHandlers.GetMessagesHandler(chatService))
+		api.PUT("/chats/:id/messages/:msgid", apiHandlers.UpdateMessageHandler(chatService))
+		api.GET("/chats/:id/export", apiHandlers.ExportChatHandler(chatService))
+		api.POST("/chats/:id/share", apiHandlers.CreateShareHandler(shareService))
+		api.GET("/chats/:id/shares", apiHandlers.GetSharesHandler(shareService))
+		api.DELETE("/chats/:id/shares/:shareId", apiHandlers.RevokeShareHandler(shareService))
+		api.GET("/chats/:id/collection", apiHandlers.GetChatCollectionHandler(documentService))
+		api.PUT("/chats/:id/collection", apiHandlers.SetChatCollectionHandler(documentService))
+		api.GET("/collections", apiHandlers.GetCollectionsHandler(documentService))
+		api.POST("/collections", apiHandlers.CreateCollectionHandler(documentService))
+		api.DELETE("/collections/:id", apiHandlers.DeleteCollectionHandler(documentService))
+		api.GET("/collections/:id/documents", apiHandlers.GetDocumentsHandler(documentService))
+		api.POST("/collections/:id/documents", apiHandlers.UploadDocumentHandler(documentService))
+		api.DELETE("/collections/:id/documents/:documentId", apiHandlers.DeleteDocumentHandler(documentService))
+		api.GET("/collections/:id/search", apiHandlers.SearchCollectionHandler(documentService))
+		api.GET("/chats/:id/processors", apiHandlers.GetChatProcessorsHandler(processingService))
+		api.PUT("/chats/:id/processors/:name", apiHandlers.SetChatProcessorHandler(processingService))
+		api.GET("/chats/:id/tools", apiHandlers.GetChatToolsHandler(toolService))
+		api.PUT("/chats/:id/tools", apiHandlers.SetChatToolsHandler(toolService))
+		api.GET("/chats/:id/draft", apiHandlers.GetChatDraftHandler(draftService))
+		api.PUT("/chats/:id/draft", apiHandlers.SaveChatDraftHandler(draftService))
+		api.POST("/chats/:id/tags", apiHandlers.TagChatHandler(chatService))
+		api.DELETE("/chats/:id/tags/:tag", apiHandlers.UntagChatHandler(chatService))
+		api.PUT("/chats/:id/folder", apiHandlers.MoveChatToFolderHandler(chatService))
+		api.POST("/chats/:id/attachments", apiHandlers.UploadAttachmentHandler(chatService, attachmentService))
+		api.GET("/chats/:id/attachments", apiHandlers.GetAttachmentsHandler(chatService, attachmentService))
+		api.GET("/chats/:id/attachments/:attachmentId", apiHandlers.DownloadAttachmentHandler(attachmentService))
+		api.DELETE("/chats/:id/attachments/:attachmentId", apiHandlers.DeleteAttachmentHandler(attachmentService))
+		api.GET("/chats/:id/generations", apiHandlers.GetChatGenerationsHandler(generationService))
+		// Provider logs hold raw CLI output of every chat, and chats have no owner, so only admins read them
+		api.GET("/chats/:id/logs", adminOnly, apiHandlers.GetChatLogHandler(chatService, chatLogService))
+		api.GET("/generations/stats", apiHandlers.GetGenerationStatsHandler(generationService))
+		api.GET("/chats/:id/usage", apiHandlers.GetChatUsageHandler(chatService, usageService))
+		api.GET("/chats/:id/feedback", apiHandlers.GetChatFeedbackHandler(chatService, feedbackService))
+		api.POST("/messages/:id/feedback", apiHandlers.RateMessageHandler(feedbackService))
+		api.DELETE("/messages/:id/feedback", apiHandlers.ClearMessageFeedbackHandler(feedbackService))
+		api.GET("/feedback", apiHandlers.GetFeedbackHandler(feedbackService))
+		api.GET("/feedback/summary", apiHandlers.GetFeedbackSummaryHandler(feedbackService))
+		api.GET("/usage/summary", apiHandlers.GetUsageSummaryHandler(usageService))
+		api.GET("/usage/quota", apiHandlers.GetUsageQuotaHandler(quotaService))
+		api.POST("/ws/ticket", apiHandlers.IssueWSTicketHandler(wsTicketService, wsIdentity))
+		api.GET("/ws/schema", apiHandlers.WebSocketSchemaHandler())
+		api.GET("/docs", handlers.APIDocsHandler(build))
+		api.GET("/docs/openapi.json", handlers.OpenAPIHandler(build))
+		if authService != nil {
+			api.POST("/auth/login", apiHandlers.LoginHandler(authService))
+			api.POST("/auth/refresh", apiHandlers.RefreshTokenHandler(authService))
+			api.POST("/auth/logout", apiHandlers.LogoutHandler(authService))
+			api.GET("/auth/me", apiHandlers.CurrentUserHandler())
+		}
+		api.GET("/analytics/activity", apiHandlers.GetActivityHandler(analyticsService))
+
+		api.GET("/tags", apiHandlers.GetTagsHandler(chatService))
+		api.DELETE("/tags/:id", apiHandlers.DeleteTagHandler(chatService))
+		api.GET("/folders", apiHandlers.GetFoldersHandler(chatService))
+		api.POST("/folders", apiHandlers.CreateFolderHandler(chatService))
+		api.PUT("/folders/:id", apiHandlers.RenameFolderHandler(chatService))
+		api.DELETE("/folders/:id", apiHandlers.DeleteFolderHandler(chatService))
+
+		api.GET("/schedules", apiHandlers.GetSchedulesHandler(scheduleService))
+		api.POST("/schedules", apiHandlers.CreateScheduleHandler(scheduleService, chatService, providerRegistry))
+		api.GET("/schedules/:id", apiHandlers.GetScheduleHandler(scheduleService))
+		api.PUT("/schedules/:id", apiHandlers.UpdateScheduleHandler(scheduleService, chatService, providerRegistry))
+		api.DELETE("/schedules/:id", apiHandlers.DeleteScheduleHandler(scheduleService))
+		api.POST("/schedules/:id/run", middleware.PromptQuotaMiddleware(quotaService), apiHandlers.RunScheduleHandler(scheduleService, schedulerService))
+		api.GET("/schedules/:id/runs", apiHandlers.GetScheduleRunsHandler(scheduleService))
+		api.POST("/complete", middleware.PromptQuotaMiddleware(quotaService), apiHandlers.CompleteHandler(completionService))
+		api.GET("/session", apiHandlers.CurrentSessionHandler(sessionService))
 		api.GET("/providers", apiHandlers.GetProvidersHandler(providerRegistry))
 		api.GET("/providers/:id/status", apiHandlers.GetProviderStatusHandler(providerRegistry))
-		api.GET("/settings", apiHandlers.GetSettingsHandler())
-		api.POST("/settings", apiHandlers.UpdateSettingsHandler())
+		api.GET("/providers/:id/models", apiHandlers.GetProviderModelsHandler(providerRegistry))
+		api.GET("/providers/:id/config", adminOnly, apiHandlers.GetProviderConfigHandler(providerRegistry))
+		api.GET("/providers/:id/health/history", apiHandlers.GetProviderHealthHistoryHandler(providerRegistry, healthService))
+		api.GET("/providers/cancellations", apiHandlers.GetCancellationStatsHandler())
+		api.GET("/settings", apiHandlers.GetSettingsHandler(userSettingsService, settingsOwner))
+		api.POST("/settings", apiHandlers.UpdateSettingsHandler(userSettingsService, providerRegistry, settingsOwner))
 		api.POST("/logs/client", apiHandlers.LogClientErrorHandler())
 	}
 
-	// WebSocket endpoint
-	router.GET("/ws", handlers.WebSocketHandler(hub))
+	// Admin API routes
+	adminAPI := router.Group("/api/admin", adminOnly)
+	{
+		adminAPI.GET("/stats", apiHandlers.GetAdminStatsHandler(adminStatsService))
+		adminAPI.GET("/logs/stream", apiHandlers.LogStreamHandler(systemLogService))
+		adminAPI.GET("/sessions", apiHandlers.GetSessionsHandler(sessionService))
+		adminAPI.DELETE("/sessions/:id", apiHandlers.DeleteSessionHandler(sessionService))
+		adminAPI.PUT("/sessions/:id/role", apiHandlers.SetSessionRoleHandler(sessionService))
+		adminAPI.GET("/greeting", apiHandlers.GetGreetingHandler(greetingService))
+		adminAPI.PUT("/greeting", apiHandlers.UpdateGreetingHandler(greetingService))
+		adminAPI.GET("/config/export", apiHandlers.ExportConfigBundleHandler(configBundleService))
+		adminAPI.POST("/config/import", apiHandlers.ImportConfigBundleHandler(configBundleService))
+		adminAPI.POST("/config/reload", apiHandlers.ReloadConfigHandler(configReloadService))
+		adminAPI.GET("/instances", apiHandlers.GetHubInstancesHandler(hub))
+		adminAPI.GET("/moderation/events", apiHandlers.GetModerationEventsHandler(moderationService))
+		adminAPI.GET("/retention", apiHandlers.GetRetentionHandler(retentionService))
+		adminAPI.POST("/retention/run", apiHandlers.RunRetentionHandler(retentionService))
+		adminAPI.GET("/jobs", apiHandlers.ListJobsHandler(jobScheduler))
+		adminAPI.POST("/jobs/:name/run", apiHandlers.RunJobHandler(jobScheduler))
+		adminAPI.POST("/i18n/reload", apiHandlers.ReloadTranslationsHandler(i18n.Get()))
+		adminAPI.GET("/i18n/validate", apiHandlers.ValidateTranslationsHandler(i18n.Get()))
+	}
 
-	// Get port from configuration
-	port := cfg.Port
+	// Runtime diagnostics for admins, with pprof profiles when enabled
+	debugAPI := router.Group("/api/debug", adminOnly)
+	{
+		debugAPI.GET("/info", handlers.DebugInfoHandler(cfg, build, startedAt))
+		if cfg.EnablePprof {
+			debugAPI.GET("/pprof/*profile", handlers.PprofHandler())
+		}
+	}
 
-	// Create HTTP server with graceful shutdown support
-	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: router,
+	// OpenAI compatible API, for OpenAI SDKs and tools pointed at the hub
+	if cfg.EnableOpenAIAPI {
+		v1 := router.Group("/v1", middleware.RBACMiddleware(cfg, sessionService))
+		{
+			v1.GET("/models", apiHandlers.OpenAIModelsHandler(providerRegistry))
+			v1.POST("/chat/completions", middleware.PromptQuotaMiddleware(quotaService), apiHandlers.OpenAIChatCompletionsHandler(providerRegistry, completionService))
+		}
 	}
 
+	// WebSocket endpoint
+	router.GET("/ws", handlers.WebSocketHandler(hub, cfg))
+
+	// Create HTTP(S) server with graceful shutdown support
+	srv := server.New(cfg, router)
+
 	// Start server in a goroutine
 	go func() {
-		utils.Info("Starting AI Gateway Hub on port %s", port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			utils.Fatal("Failed to start server: %v", err)
 		}
 	}()
 
+	// Reload the runtime settings on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if _, err := configReloadService.Reload(); err != nil {
+				utils.Warn("Failed to reload configuration: %v", err)
+			}
+		}
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -233,6 +692,290 @@ func main() {
 	}
 
 	utils.Info("Server exited")
+	return nil
+}
+
+// loadConfig loads .env, without overriding the environment, and the configuration
+func loadConfig() (*config.Config, *config.EnvFile, error) {
+	if err := utils.InitPathManager(); err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize path manager: %w", err)
+	}
+
+	// Load .env file if exists to get log configuration early
+	envFile, err := config.LoadEnvFile(".env")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Failed to load .env: %v", err)
+	}
+
+	// Load configuration with environment-specific settings
+	return config.LoadWithEnvironment(), envFile, nil
+}
+
+// openDatabase opens the configured database, applying pending migrations when AUTO_MIGRATE
+// is enabled and refusing to start on an outdated schema otherwise
+func openDatabase(cfg *config.Config) (*database.DB, error) {
+	dialect, err := database.ParseDialect(cfg.DBDriver)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AutoMigrate {
+		return database.Init(dialect, cfg.DatabaseDSN(), sqliteOptions(cfg))
+	}
+
+	db, err := database.Open(dialect, cfg.DatabaseDSN(), sqliteOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	pending, err := database.PendingMigrations(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if len(pending) > 0 {
+		db.Close()
+		return nil, fmt.Errorf("%d database migrations are pending and AUTO_MIGRATE is disabled; run the migrate up command", len(pending))
+	}
+	return db, nil
+}
+
+// sqliteOptions returns the configured SQLite pragmas and connection pool size
+func sqliteOptions(cfg *config.Config) database.SQLiteOptions {
+	return database.SQLiteOptions{
+		JournalMode:  cfg.SQLiteJournalMode,
+		BusyTimeout:  cfg.SQLiteBusyTimeout,
+		Synchronous:  cfg.SQLiteSynchronous,
+		ForeignKeys:  cfg.SQLiteForeignKeys,
+		MaxOpenConns: cfg.SQLiteMaxOpenConns,
+		MaxIdleConns: cfg.SQLiteMaxIdleConns,
+	}
+}
+
+// secretsOptions returns the configured secret manager backends
+func secretsOptions(cfg *config.Config) secrets.Options {
+	return secrets.Options{
+		VaultAddr:      cfg.VaultAddr,
+		VaultToken:     cfg.VaultToken,
+		VaultNamespace: cfg.VaultNamespace,
+		AWSCLIPath:     cfg.AWSCLIPath,
+	}
+}
+
+// newModerationPipeline builds the moderators configured by MODERATION_RULES_FILE and MODERATION_URL
+func newModerationPipeline(cfg *config.Config, resolver *secrets.Manager) (*moderation.Pipeline, error) {
+	var moderators []moderation.Moderator
+	if cfg.ModerationRulesFile != "" {
+		rules, err := moderation.LoadRulesFile(cfg.ModerationRulesFile)
+		if err != nil {
+			return nil, err
+		}
+		ruleModerator, err := moderation.NewRuleModerator(rules)
+		if err != nil {
+			return nil, fmt.Errorf("invalid moderation rules: %w", err)
+		}
+		moderators = append(moderators, ruleModerator)
+		utils.Info("Loaded %d moderation rules from %s", len(rules), cfg.ModerationRulesFile)
+	}
+	if cfg.ModerationURL != "" {
+		apiKey := cfg.ModerationAPIKey
+		if secrets.IsReference(apiKey) {
+			resolved, err := resolver.Resolve(context.Background(), apiKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve MODERATION_API_KEY: %w", err)
+			}
+			apiKey = resolved
+		}
+		moderators = append(moderators, moderation.NewHTTPModerator(cfg.ModerationURL, apiKey, cfg.ModerationTimeout))
+	}
+	return moderation.NewPipeline(cfg.ModerationFailClosed, moderators...), nil
+}
+
+// newProcessingPipeline builds the built-in processors and those configured by PROCESSORS_FILE,
+// resolving the API keys of plugins that are secret references
+func newProcessingPipeline(cfg *config.Config, resolver *secrets.Manager, builtin ...processing.Step) (*processing.Pipeline, error) {
+	if cfg.ProcessorsFile == "" {
+		return processing.NewPipeline(builtin...)
+	}
+	entries, err := processing.LoadFile(cfg.ProcessorsFile)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if secrets.IsReference(entries[i].APIKey) {
+			resolved, err := resolver.Resolve(context.Background(), entries[i].APIKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve the api_key of processor %s: %w", entries[i].Name, err)
+			}
+			entries[i].APIKey = resolved
+		}
+	}
+	pipeline, err := processing.Build(entries, builtin...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid processors: %w", err)
+	}
+	utils.Info("Loaded %d processors from %s", len(entries), cfg.ProcessorsFile)
+	return pipeline, nil
+}
+
+// newToolRegistry returns the tools named by TOOLS_ENABLED
+func newToolRegistry(cfg *config.Config) (*tools.Registry, error) {
+	var enabled []tools.Tool
+	for _, name := range cfg.ToolsEnabled {
+		switch name {
+		case "calculator":
+			enabled = append(enabled, tools.Calculator{})
+		case "http_fetch":
+			enabled = append(enabled, tools.NewFetch(cfg.ToolsFetchAllowedHosts, cfg.ToolsTimeout))
+		case "shell":
+			enabled = append(enabled, tools.NewShell(cfg.ToolsShellCommands, cfg.ToolsTimeout))
+		default:
+			return nil, fmt.Errorf("unknown tool %s", name)
+		}
+	}
+	return tools.NewRegistry(enabled...)
+}
+
+// newEmbedder returns the embedder chosen by EMBEDDING_PROVIDER
+func newEmbedder(cfg *config.Config, resolver *secrets.Manager) (documents.Embedder, error) {
+	if cfg.EmbeddingProvider != "http" {
+		return documents.NewHashEmbedder(cfg.EmbeddingDimensions), nil
+	}
+	apiKey := cfg.EmbeddingAPIKey
+	if secrets.IsReference(apiKey) {
+		resolved, err := resolver.Resolve(context.Background(), apiKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve EMBEDDING_API_KEY: %w", err)
+		}
+		apiKey = resolved
+	}
+	utils.RegisterSecret(apiKey)
+	return documents.NewHTTPEmbedder(cfg.EmbeddingURL, apiKey, cfg.EmbeddingModel, cfg.EmbeddingTimeout), nil
+}
+
+// newAuthService returns the sign-in service of the jwt auth mode, or nil in the session mode
+func newAuthService(cfg *config.Config, redisClient *redis.Client, storeBackend string) (*services.AuthService, error) {
+	if cfg.AuthMode != config.AuthModeJWT {
+		return nil, nil
+	}
+
+	var signer *auth.Signer
+	var err error
+	switch cfg.JWTAlgorithm {
+	case auth.HS256:
+		signer, err = auth.NewHS256Signer([]byte(cfg.JWTSecret), cfg.JWTIssuer)
+	case auth.RS256:
+		var privateKey *rsa.PrivateKey
+		var publicKey *rsa.PublicKey
+		data, readErr := os.ReadFile(cfg.JWTPrivateKeyFile)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read JWT_PRIVATE_KEY_FILE: %w", readErr)
+		}
+		if privateKey, err = auth.ParseRSAPrivateKey(data); err != nil {
+			return nil, fmt.Errorf("invalid JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+		if cfg.JWTPublicKeyFile != "" {
+			data, readErr := os.ReadFile(cfg.JWTPublicKeyFile)
+			if readErr != nil {
+				return nil, fmt.Errorf("failed to read JWT_PUBLIC_KEY_FILE: %w", readErr)
+			}
+			if publicKey, err = auth.ParseRSAPublicKey(data); err != nil {
+				return nil, fmt.Errorf("invalid JWT_PUBLIC_KEY_FILE: %w", err)
+			}
+		}
+		signer, err = auth.NewRS256Signer(privateKey, publicKey, cfg.JWTIssuer)
+	default:
+		err = fmt.Errorf("unsupported JWT_ALGORITHM %q", cfg.JWTAlgorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := auth.LoadUsers(cfg.AuthUsersFile)
+	if err != nil {
+		return nil, err
+	}
+	utils.Info("JWT authentication enabled (%s, %d users)", signer.Algorithm(), users.Len())
+
+	var refreshTokens services.TicketStore = services.NewMemoryTicketStore()
+	if storeBackend == services.StoreBackendRedis {
+		refreshTokens = services.NewRedisRefreshTokenStore(redisClient)
+	} else {
+		utils.Warn("Refresh tokens are kept in memory without Redis and are lost on restart")
+	}
+	return services.NewAuthService(signer, users, refreshTokens, cfg.JWTAccessTTL, cfg.JWTRefreshTTL), nil
+}
+
+// newMessageCipher returns the cipher configured by MESSAGE_ENCRYPTION_KEY, which may be a secret
+// reference, or nil when message encryption is disabled
+func newMessageCipher(cfg *config.Config, resolver *secrets.Manager) (*encryption.Cipher, error) {
+	if !cfg.EnableMessageEncryption {
+		return nil, nil
+	}
+	value := cfg.MessageEncryptionKey
+	if secrets.IsReference(value) {
+		resolved, err := resolver.Resolve(context.Background(), value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve MESSAGE_ENCRYPTION_KEY: %w", err)
+		}
+		value = resolved
+	}
+	utils.RegisterSecret(value)
+	key, err := encryption.ParseKey(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MESSAGE_ENCRYPTION_KEY: %w", err)
+	}
+	return encryption.NewCipher(key)
+}
+
+// runMigrateCommand applies, rolls back or lists schema migrations
+func runMigrateCommand(cfg *config.Config, command string, steps int) error {
+	dialect, err := database.ParseDialect(cfg.DBDriver)
+	if err != nil {
+		return err
+	}
+	db, err := database.Open(dialect, cfg.DatabaseDSN(), sqliteOptions(cfg))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch command {
+	case "up":
+		applied, err := database.Migrate(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migrations\n", applied)
+	case "down":
+		if steps <= 0 {
+			return fmt.Errorf("-steps must be positive")
+		}
+		reverted, err := database.Rollback(db, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migrations\n", reverted)
+	case "status":
+		statuses, err := database.MigrationStatuses(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q (expected up, down or status)", command)
+	}
+
+	version, err := database.SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Schema version: %d\n", version)
+	return nil
 }
 
 // setupLogging configures Gin mode based on log level
@@ -256,6 +999,29 @@ func setupLogging(logLevel string) {
 	}
 }
 
+// loadTemplates sets the router's HTML templates. With TEMPLATE_RELOAD they are parsed from
+// TEMPLATE_DIR and parsed again on every change; the embedded templates are the fallback.
+func loadTemplates(router *gin.Engine, cfg *config.Config) {
+	if cfg.TemplateReload {
+		html, err := server.NewReloadableHTML(cfg.TemplateDir)
+		if err == nil {
+			err = html.Watch(context.Background())
+		}
+		if err == nil {
+			router.HTMLRender = html
+			utils.Info("Using templates from %s, reloaded on change", cfg.TemplateDir)
+			return
+		}
+		utils.Warn("Template reloading unavailable, using embedded templates: %v", err)
+	}
+
+	templateFS, err := fs.Sub(templateFiles, "web/templates")
+	if err != nil {
+		log.Fatalf("Failed to create template file system: %v", err)
+	}
+	router.SetHTMLTemplate(template.Must(server.ParseTemplates(templateFS)))
+}
+
 // initializeI18n initializes i18n system with local files if they exist, otherwise embedded files
 func initializeI18n() error {
 	// Check if local locales directory exists and has files
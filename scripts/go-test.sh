@@ -35,7 +35,7 @@ case $TEST_TYPE in
         ;;
     "e2e")
         echo "🌐 Running E2E tests..."
-        docker exec -w /workspace devcontainer-app-1 go test -v ./test/e2e/...
+        docker exec -w /workspace -e E2E_REDIS_ADDR=redis:6379 devcontainer-app-1 go test -v ./test/e2e/...
         ;;
     "coverage")
         echo "📊 Running tests with coverage..."
@@ -53,7 +53,7 @@ case $TEST_TYPE in
         docker exec -w /workspace devcontainer-app-1 go test -v ./test/integration/...
         echo ""
         echo "3️⃣ E2E Tests:"
-        docker exec -w /workspace devcontainer-app-1 go test -v ./test/e2e/...
+        docker exec -w /workspace -e E2E_REDIS_ADDR=redis:6379 devcontainer-app-1 go test -v ./test/e2e/...
         ;;
     "clean")
         echo "🧹 Cleaning test artifacts..."
@@ -6,110 +6,22 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"strings"
 	"testing"
-	"time"
-
-	"ai-gateway-hub/internal/config"
-	"ai-gateway-hub/internal/database"
-	"ai-gateway-hub/internal/handlers"
-	"ai-gateway-hub/internal/middleware"
-	"ai-gateway-hub/internal/services"
-	"ai-gateway-hub/internal/utils"
-
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
 )
 
-func setupTestServer(t *testing.T) (*gin.Engine, func()) {
-	// Create temporary directory for test database
-	tempDir, err := os.MkdirTemp("", "e2e_test")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
-	}
-
-	// Setup path manager
-	originalDir, _ := os.Getwd()
-	os.Chdir(tempDir)
-	utils.InitPathManager()
-
-	// Setup test configuration
-	cfg := &config.Config{
-		Port:                        "8080",
-		SQLiteDBFile:               "./test.db",
-		RedisAddr:                   "localhost:6379",
-		LogDir:                      "./logs",
-		LogLevel:                    "info",
-		MaxSessions:                 100,
-		SessionTimeout:              3600 * time.Second,
-		WebSocketTimeout:            7200 * time.Second,
-		ClaudeCLIPath:               "echo", // Use echo for testing
-		GeminiCLIPath:               "echo",
-		EnableProviderAutoDiscovery: true,
-		EnableHealthChecks:          true,
-	}
-
-	// Initialize database
-	db, err := database.InitSQLite(cfg.SQLiteDBFile)
-	if err != nil {
-		t.Fatalf("Failed to initialize test database: %v", err)
-	}
-
-	// Initialize Redis (will fail if not available, but test continues)
-	redisClient := database.InitRedis(cfg.RedisAddr)
-
-	// Initialize services
-	sessionService := services.NewSessionService(redisClient)
-	chatService := services.NewChatService(db)
-	providerRegistry := services.NewProviderRegistry(redisClient)
-
-	// Register test providers
-	if err := providerRegistry.RegisterDefaultProviders(cfg); err != nil {
-		t.Logf("Warning: Failed to register providers: %v", err)
-	}
-
-	// Setup Gin router
-	gin.SetMode(gin.TestMode)
-	router := gin.New()
-	router.Use(middleware.I18nMiddleware())
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:  []string{"*"},
-		AllowMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:  []string{"Origin", "Content-Type", "Accept"},
-		ExposeHeaders: []string{"Content-Length"},
-	}))
-	
-	// Setup API routes only for E2E testing (skip HTML template routes)
-
-	api := router.Group("/api")
-	{
-		api.GET("/health", handlers.HealthCheckHandler(redisClient, "test"))
-		api.GET("/chats", handlers.GetChatsHandler(chatService))
-		api.POST("/chats", handlers.CreateChatHandler(chatService))
-		api.DELETE("/chats/:id", handlers.DeleteChatHandler(chatService))
-		api.GET("/providers", handlers.GetProvidersHandler(providerRegistry))
-	}
-
-	// Initialize WebSocket hub
-	hub := handlers.NewHub(sessionService, chatService, providerRegistry)
-	go hub.Run()
-	router.GET("/ws", handlers.WebSocketHandler(hub))
-
-	// Cleanup function
-	cleanup := func() {
-		db.Close()
-		redisClient.Close()
-		os.Chdir(originalDir)
-		os.RemoveAll(tempDir)
-	}
-
-	return router, cleanup
+func TestHealthAPI(t *testing.T) {
+	healthStatus(t, newRedisTestServer(t).router, "healthy", "redis")
 }
 
-func TestHealthAPI(t *testing.T) {
-	router, cleanup := setupTestServer(t)
-	defer cleanup()
+func TestHealthAPIWithoutRedis(t *testing.T) {
+	// Sessions fell back to memory, which is reported as degraded
+	healthStatus(t, newTestServer(t).router, "degraded", "memory")
+}
 
+// healthStatus checks the status and session store reported by GET /api/health
+func healthStatus(t *testing.T, router http.Handler, wantStatus, wantStore string) {
+	t.Helper()
 	t.Run("GET /api/health", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/api/health", nil)
 		w := httptest.NewRecorder()
@@ -125,15 +37,17 @@ func TestHealthAPI(t *testing.T) {
 			t.Fatalf("Failed to parse response: %v", err)
 		}
 
-		if status, ok := response["status"]; !ok || status != "healthy" {
-			t.Errorf("Expected status 'healthy', got %v", status)
+		if status, ok := response["status"]; !ok || status != wantStatus {
+			t.Errorf("Expected status %q, got %v", wantStatus, status)
+		}
+		if store := response["session_store"]; store != wantStore {
+			t.Errorf("Expected session store %q, got %v", wantStore, store)
 		}
 	})
 }
 
 func TestProvidersAPI(t *testing.T) {
-	router, cleanup := setupTestServer(t)
-	defer cleanup()
+	router := newTestServer(t).router
 
 	t.Run("GET /api/providers", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/api/providers", nil)
@@ -144,40 +58,41 @@ func TestProvidersAPI(t *testing.T) {
 			t.Errorf("Expected status 200, got %d", w.Code)
 		}
 
-		var providers []map[string]interface{}
-		err := json.Unmarshal(w.Body.Bytes(), &providers)
+		var response struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
 		if err != nil {
 			t.Fatalf("Failed to parse response: %v", err)
 		}
 
-		if len(providers) == 0 {
+		if len(response.Data) == 0 {
 			t.Error("Expected at least one provider")
 		}
 
-		// Check that Claude provider is present
+		// Check that the fake provider is present
 		found := false
-		for _, provider := range providers {
-			if id, ok := provider["id"]; ok && id == "claude" {
+		for _, provider := range response.Data {
+			if id, ok := provider["id"]; ok && id == "fake" {
 				found = true
 				break
 			}
 		}
 		if !found {
-			t.Error("Claude provider not found in response")
+			t.Error("Fake provider not found in response")
 		}
 	})
 }
 
 func TestChatsAPI(t *testing.T) {
-	router, cleanup := setupTestServer(t)
-	defer cleanup()
+	router := newTestServer(t).router
 
 	var chatID float64
 
 	t.Run("POST /api/chats - Create Chat", func(t *testing.T) {
 		chatData := map[string]string{
 			"title":    "Test Chat",
-			"provider": "claude",
+			"provider": "fake",
 		}
 		jsonData, _ := json.Marshal(chatData)
 
@@ -190,11 +105,14 @@ func TestChatsAPI(t *testing.T) {
 			t.Errorf("Expected status 201, got %d", w.Code)
 		}
 
-		var response map[string]interface{}
-		err := json.Unmarshal(w.Body.Bytes(), &response)
+		var envelope struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &envelope)
 		if err != nil {
 			t.Fatalf("Failed to parse response: %v", err)
 		}
+		response := envelope.Data
 
 		if id, ok := response["id"]; ok {
 			chatID = id.(float64)
@@ -206,8 +124,8 @@ func TestChatsAPI(t *testing.T) {
 			t.Errorf("Expected title 'Test Chat', got %v", title)
 		}
 
-		if provider := response["provider"]; provider != "claude" {
-			t.Errorf("Expected provider 'claude', got %v", provider)
+		if provider := response["provider"]; provider != "fake" {
+			t.Errorf("Expected provider 'fake', got %v", provider)
 		}
 	})
 
@@ -220,11 +138,16 @@ func TestChatsAPI(t *testing.T) {
 			t.Errorf("Expected status 200, got %d", w.Code)
 		}
 
-		var chats []map[string]interface{}
-		err := json.Unmarshal(w.Body.Bytes(), &chats)
+		var response struct {
+			Data struct {
+				Items []map[string]interface{} `json:"items"`
+			} `json:"data"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
 		if err != nil {
 			t.Fatalf("Failed to parse response: %v", err)
 		}
+		chats := response.Data.Items
 
 		if len(chats) == 0 {
 			t.Error("Expected at least one chat")
@@ -282,12 +205,11 @@ func TestChatsAPI(t *testing.T) {
 }
 
 func TestCreateChatValidation(t *testing.T) {
-	router, cleanup := setupTestServer(t)
-	defer cleanup()
+	router := newTestServer(t).router
 
 	t.Run("POST /api/chats - Missing Title", func(t *testing.T) {
 		chatData := map[string]string{
-			"provider": "claude",
+			"provider": "fake",
 		}
 		jsonData, _ := json.Marshal(chatData)
 
@@ -296,8 +218,8 @@ func TestCreateChatValidation(t *testing.T) {
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("Expected status 400 for missing title, got %d", w.Code)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status 422 for missing title, got %d", w.Code)
 		}
 	})
 
@@ -312,8 +234,8 @@ func TestCreateChatValidation(t *testing.T) {
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("Expected status 400 for missing provider, got %d", w.Code)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status 422 for missing provider, got %d", w.Code)
 		}
 	})
 
@@ -323,26 +245,36 @@ func TestCreateChatValidation(t *testing.T) {
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("Expected status 400 for invalid JSON, got %d", w.Code)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status 422 for invalid JSON, got %d", w.Code)
 		}
 	})
 }
 
 func TestIndexPage(t *testing.T) {
-	t.Skip("Index page requires HTML templates, skipping in E2E tests")
+	router := newTestServer(t).router
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "<html") || !strings.Contains(body, "AI Gateway Hub") {
+		t.Errorf("Expected the index page, got %.200s", body)
+	}
 }
 
 func TestCORSHeaders(t *testing.T) {
-	router, cleanup := setupTestServer(t)
-	defer cleanup()
+	router := newTestServer(t).router
 
 	t.Run("OPTIONS /api/chats - CORS Preflight", func(t *testing.T) {
 		req, _ := http.NewRequest("OPTIONS", "/api/chats", nil)
 		req.Header.Set("Origin", "http://localhost:3000")
 		req.Header.Set("Access-Control-Request-Method", "POST")
 		req.Header.Set("Access-Control-Request-Headers", "Content-Type")
-		
+
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
@@ -355,4 +287,4 @@ func TestCORSHeaders(t *testing.T) {
 			t.Errorf("Expected Access-Control-Allow-Origin '*', got '%s'", origin)
 		}
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,365 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/buildinfo"
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/handlers"
+	"ai-gateway-hub/internal/i18n"
+	"ai-gateway-hub/internal/middleware"
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/server"
+	"ai-gateway-hub/internal/services"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
+)
+
+// redisImage is the image of the Redis container tests start when E2E_REDIS_ADDR isn't set
+const redisImage = "redis:7-alpine"
+
+var (
+	redisOnce      sync.Once
+	redisAddr      string
+	redisErr       error
+	redisContainer string
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if redisContainer != "" {
+		if out, err := exec.Command("docker", "rm", "-f", redisContainer).CombinedOutput(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to remove Redis container %s: %v: %s\n", redisContainer, err, out)
+		}
+	}
+	os.Exit(code)
+}
+
+// startRedis returns a client of the Redis server the tests share: the one at E2E_REDIS_ADDR, or a
+// container started with Docker on first use and removed after the tests. Without either the test
+// fails, unless E2E_SKIP_REDIS=true opts out of the tests that need Redis.
+func startRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	redisOnce.Do(func() {
+		if addr := os.Getenv("E2E_REDIS_ADDR"); addr != "" {
+			redisAddr = addr
+			return
+		}
+		redisAddr, redisErr = startRedisContainer()
+	})
+	if redisErr != nil {
+		if os.Getenv("E2E_SKIP_REDIS") == "true" {
+			t.Skipf("Redis unavailable and E2E_SKIP_REDIS=true: %v", redisErr)
+		}
+		t.Fatalf("Redis is required, set E2E_REDIS_ADDR, install Docker or set E2E_SKIP_REDIS=true: %v", redisErr)
+	}
+
+	client, err := database.InitRedis(redisAddr)
+	if err != nil {
+		client.Close()
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// startRedisContainer runs Redis on a free local port and waits until it answers
+func startRedisContainer() (string, error) {
+	out, err := exec.Command("docker", "run", "-d", "--rm", "-p", "127.0.0.1::6379", redisImage).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to start %s container: %w", redisImage, err)
+	}
+	redisContainer = strings.TrimSpace(string(out))
+
+	out, err = exec.Command("docker", "port", redisContainer, "6379/tcp").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the port of the Redis container: %w", err)
+	}
+	// Docker lists a mapping per address family; the first is the IPv4 one requested
+	addr := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		err := client.Ping(context.Background()).Err()
+		if err == nil {
+			return addr, nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("redis container at %s didn't become ready: %w", addr, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// testServer is the gateway wired like main.go wires it, with the given providers, listening on a
+// local port. Sessions, tickets and stream buffers are kept in Redis, or in memory as main.go does
+// without it.
+type testServer struct {
+	*httptest.Server
+	router      *gin.Engine
+	chatService *services.ChatService
+	client      *http.Client // keeps the session cookie
+}
+
+// newTestServer starts a server with in-memory stores and the given providers, or a FakeProvider
+// named fake
+func newTestServer(t *testing.T, aiProviders ...providers.AIProvider) *testServer {
+	t.Helper()
+	return startTestServer(t, nil, aiProviders)
+}
+
+// newRedisTestServer starts a server with Redis-backed stores, see startRedis
+func newRedisTestServer(t *testing.T, aiProviders ...providers.AIProvider) *testServer {
+	t.Helper()
+	return startTestServer(t, startRedis(t), aiProviders)
+}
+
+// startTestServer starts a server keeping its state in redisClient, or in memory when it is nil
+func startTestServer(t *testing.T, redisClient *redis.Client, aiProviders []providers.AIProvider) *testServer {
+	t.Helper()
+	storeBackend := services.StoreBackendRedis
+	var sessionStore services.SessionStore
+	var statusCache services.StatusCache
+	var ticketStore services.TicketStore
+	var streamBuffer services.StreamBuffer
+	if redisClient != nil {
+		sessionStore = services.NewRedisSessionStore(redisClient)
+		statusCache = services.NewRedisStatusCache(redisClient)
+		ticketStore = services.NewRedisTicketStore(redisClient)
+		streamBuffer = services.NewRedisStreamBuffer(redisClient, time.Minute)
+	} else {
+		// Like main.go when Redis is down, with a client that can't connect for the health check
+		storeBackend = services.StoreBackendMemory
+		redisClient = redis.NewClient(&redis.Options{Addr: "127.0.0.1:0", MaxRetries: -1})
+		t.Cleanup(func() { redisClient.Close() })
+		sessionStore = services.NewMemorySessionStore()
+		statusCache = services.NewMemoryStatusCache()
+		ticketStore = services.NewMemoryTicketStore()
+		streamBuffer = services.NewMemoryStreamBuffer(time.Minute)
+	}
+
+	// Pages are rendered with the repository's templates and translations
+	webDir, _ := filepath.Abs("../../web/templates")
+	localesDir, _ := filepath.Abs("../../locales")
+	if err := i18n.Init(localesDir, "en"); err != nil {
+		t.Fatalf("Failed to load translations: %v", err)
+	}
+	templates, err := server.ParseTemplates(os.DirFS(webDir))
+	if err != nil {
+		t.Fatalf("Failed to parse templates: %v", err)
+	}
+
+	tempDir := t.TempDir()
+	originalDir, _ := os.Getwd()
+	os.Chdir(tempDir)
+	t.Cleanup(func() { os.Chdir(originalDir) })
+	utils.InitPathManager()
+
+	cfg := &config.Config{
+		SQLiteDBFile:     "./test.db",
+		RedisAddr:        redisClient.Options().Addr,
+		LogDir:           "./logs",
+		LogLevel:         "info",
+		MaxSessions:      100,
+		SessionTimeout:   time.Hour,
+		WebSocketTimeout: 2 * time.Hour,
+	}
+
+	db, err := database.InitSQLite(cfg.SQLiteDBFile)
+	if err != nil {
+		t.Fatalf("Failed to initialize test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	sessionService := services.NewSessionService(sessionStore)
+	chatService := services.NewChatService(db)
+	providerRegistry := services.NewProviderRegistry(statusCache)
+	if len(aiProviders) == 0 {
+		aiProviders = []providers.AIProvider{providers.NewFakeProvider("fake")}
+	}
+	for _, provider := range aiProviders {
+		if err := providerRegistry.Register(provider); err != nil {
+			t.Fatalf("Failed to register provider %s: %v", provider.GetID(), err)
+		}
+	}
+
+	hub := handlers.NewHub(sessionService, chatService, providerRegistry, nil, nil, nil)
+	ticketService := services.NewWSTicketService(ticketStore, time.Minute)
+	hub.SetTickets(ticketService)
+	hub.SetStreamResume(streamBuffer)
+	hub.SetPromptTimeouts(func(providerID string) (time.Duration, time.Duration) {
+		return 10 * time.Second, 2 * time.Second
+	})
+	go hub.Run()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.SetHTMLTemplate(templates)
+	router.Use(middleware.I18nMiddleware())
+	router.Use(middleware.SessionMiddleware(sessionService, cfg.SessionTimeout))
+	router.Use(cors.New(cors.Config{
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:  []string{"Origin", "Content-Type", "Accept"},
+		ExposeHeaders: []string{"Content-Length"},
+	}))
+
+	router.GET("/", handlers.IndexHandler())
+
+	apiHandlers := handlers.NewAPIHandlers(nil)
+	api := router.Group("/api")
+	{
+		api.GET("/health", handlers.HealthCheckHandler(redisClient, storeBackend, buildinfo.BuildInfo{Version: "test"}))
+		api.GET("/chats", apiHandlers.GetChatsHandler(chatService))
+		api.POST("/chats", apiHandlers.CreateChatHandler(chatService, nil))
+		api.GET("/chats/:id", apiHandlers.GetChatHandler(chatService))
+		api.DELETE("/chats/:id", apiHandlers.DeleteChatHandler(chatService))
+		api.GET("/chats/:id/messages", apiHandlers.GetMessagesHandler(chatService))
+		api.GET("/providers", apiHandlers.GetProvidersHandler(providerRegistry))
+		api.POST("/ws/ticket", apiHandlers.IssueWSTicketHandler(ticketService, func(c *gin.Context) (string, string) {
+			return services.GuestUserID(c.GetString(middleware.SessionContextKey)), models.RoleUser
+		}))
+	}
+	router.GET("/ws", handlers.WebSocketHandler(hub, cfg))
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	jar, _ := cookiejar.New(nil)
+	return &testServer{Server: server, router: router, chatService: chatService, client: &http.Client{Jar: jar}}
+}
+
+// do sends a JSON request with the server's session and decodes the data of the response into out
+func (s *testServer) do(t *testing.T, method, path string, body, out any) int {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatalf("Failed to encode request: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, s.URL+path, &payload)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode < 300 {
+		envelope := struct {
+			Data any `json:"data"`
+		}{Data: out}
+		if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+			t.Fatalf("Failed to decode response of %s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// createChat creates a chat with a provider
+func (s *testServer) createChat(t *testing.T, provider string) *models.Chat {
+	t.Helper()
+	var chat models.Chat
+	if code := s.do(t, http.MethodPost, "/api/chats", map[string]string{"title": "E2E chat", "provider": provider}, &chat); code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating a chat, got %d", code)
+	}
+	return &chat
+}
+
+// wsClient is a WebSocket connection to the test server speaking the current protocol version
+type wsClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+// dial opens a WebSocket with a ticket issued for the server's session
+func (s *testServer) dial(t *testing.T) *wsClient {
+	t.Helper()
+	var ticket models.WSTicket
+	if code := s.do(t, http.MethodPost, "/api/ws/ticket", nil, &ticket); code != http.StatusOK {
+		t.Fatalf("Expected status 200 issuing a ticket, got %d", code)
+	}
+
+	url := "ws" + strings.TrimPrefix(s.URL, "http") + "/ws?ticket=" + ticket.Ticket
+	conn, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {s.URL}})
+	if err != nil {
+		t.Fatalf("Failed to open WebSocket: %v", err)
+	}
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+	return &wsClient{t: t, conn: conn}
+}
+
+// send writes a message of the given type
+func (c *wsClient) send(msgType string, data models.WSMsgData) {
+	c.t.Helper()
+	msg := models.WebSocketMessage{Type: msgType, Version: models.WSProtocolVersion, Data: data}
+	if err := c.conn.WriteJSON(msg); err != nil {
+		c.t.Fatalf("Failed to send %s: %v", msgType, err)
+	}
+}
+
+// readUntil collects messages until one of the given type arrives, acknowledging frames as the
+// browser client does
+func (c *wsClient) readUntil(msgType string, timeout time.Duration) []models.WebSocketMessage {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	var received []models.WebSocketMessage
+	for {
+		var msg models.WebSocketMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			c.t.Fatalf("Failed to read WebSocket message while waiting for %s: %v (received %s)", msgType, err, messageTypes(received))
+		}
+		received = append(received, msg)
+		if msg.ID > 0 {
+			c.conn.WriteJSON(models.WebSocketMessage{Type: "ack", Version: models.WSProtocolVersion, Ack: msg.ID})
+		}
+		if msg.Type == msgType {
+			return received
+		}
+	}
+}
+
+// messageTypes lists the types of messages, for failure messages
+func messageTypes(messages []models.WebSocketMessage) string {
+	types := make([]string, len(messages))
+	for i, msg := range messages {
+		types[i] = msg.Type
+	}
+	return strings.Join(types, ", ")
+}
+
+// streamedContent joins the ai_response chunks of messages
+func streamedContent(messages []models.WebSocketMessage) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		if msg.Type == "ai_response" {
+			b.WriteString(msg.Data.Content)
+		}
+	}
+	return b.String()
+}
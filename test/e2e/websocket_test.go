@@ -0,0 +1,148 @@
+package e2e
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/models"
+	"ai-gateway-hub/internal/providers"
+)
+
+// chatMessages loads the saved messages of a chat
+func (s *testServer) chatMessages(t *testing.T, chatID int64) []*models.Message {
+	t.Helper()
+	var page models.MessagePage
+	if code := s.do(t, http.MethodGet, fmt.Sprintf("/api/chats/%d/messages", chatID), nil, &page); code != http.StatusOK {
+		t.Fatalf("Expected status 200 loading messages, got %d", code)
+	}
+	return page.Items
+}
+
+// indexOfType returns the position of the first message of a type, or -1
+func indexOfType(messages []models.WebSocketMessage, msgType string) int {
+	for i, msg := range messages {
+		if msg.Type == msgType {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestWebSocketStreaming(t *testing.T) {
+	fake := providers.NewFakeProvider("fake").Script(providers.FakeReply{Chunks: []string{"Hello", ", ", "world"}, Delay: 20 * time.Millisecond})
+	srv := newTestServer(t, fake)
+	chat := srv.createChat(t, "fake")
+	ws := srv.dial(t)
+
+	ws.send("ai_prompt", models.WSMsgData{ChatID: chat.ID, Provider: "fake", Content: "Say hello"})
+	received := ws.readUntil("ai_response_saved", 5*time.Second)
+
+	// The status, the chunks and the completion arrive in order
+	thinking, started := indexOfType(received, "ai_thinking"), indexOfType(received, "provider_started")
+	firstChunk, end := indexOfType(received, "ai_response"), indexOfType(received, "ai_response_end")
+	if thinking < 0 || started < thinking || firstChunk < started || end < firstChunk {
+		t.Fatalf("Unexpected message order: %s", messageTypes(received))
+	}
+	if content := streamedContent(received); content != "Hello, world" {
+		t.Errorf("Expected streamed content 'Hello, world', got %q", content)
+	}
+	streamID := received[firstChunk].Data.StreamID
+	if streamID == "" || received[end].Data.StreamID != streamID {
+		t.Errorf("Expected the completion to name stream %q, got %q", streamID, received[end].Data.StreamID)
+	}
+
+	prompts := fake.Prompts()
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "Say hello") {
+		t.Errorf("Expected the provider to get the prompt once, got %q", prompts)
+	}
+
+	messages := srv.chatMessages(t, chat.ID)
+	if len(messages) != 2 {
+		t.Fatalf("Expected the prompt and the response to be saved, got %d messages", len(messages))
+	}
+	if messages[0].Role != "user" || messages[1].Role != "assistant" || messages[1].Content != "Hello, world" {
+		t.Errorf("Unexpected saved messages: %s %q, %s %q", messages[0].Role, messages[0].Content, messages[1].Role, messages[1].Content)
+	}
+}
+
+func TestWebSocketProviderError(t *testing.T) {
+	fake := providers.NewFakeProvider("fake").Script(providers.FakeReply{Chunks: []string{"Partial"}, Err: errors.New("provider crashed")})
+	srv := newTestServer(t, fake)
+	chat := srv.createChat(t, "fake")
+	ws := srv.dial(t)
+
+	ws.send("ai_prompt", models.WSMsgData{ChatID: chat.ID, Provider: "fake", Content: "Hi"})
+	received := ws.readUntil("error", 5*time.Second)
+
+	if indexOfType(received, "ai_response_end") < 0 {
+		t.Errorf("Expected the stream to be completed before the error, got %s", messageTypes(received))
+	}
+	if content := received[len(received)-1].Data.Content; !strings.Contains(content, "provider crashed") {
+		t.Errorf("Expected the error to name the provider's failure, got %q", content)
+	}
+	if messages := srv.chatMessages(t, chat.ID); len(messages) != 1 {
+		t.Errorf("Expected only the prompt to be saved after a failure, got %d messages", len(messages))
+	}
+
+	// The connection keeps working after a failed response
+	ws.send("ai_prompt", models.WSMsgData{ChatID: chat.ID, Provider: "fake", Content: "Again"})
+	if content := streamedContent(ws.readUntil("ai_response_saved", 5*time.Second)); content != "Fake response" {
+		t.Errorf("Expected the default reply, got %q", content)
+	}
+}
+
+func TestWebSocketIdleTimeout(t *testing.T) {
+	// The harness allows 2s between chunks
+	fake := providers.NewFakeProvider("fake").Script(providers.FakeReply{Chunks: []string{"Slow"}, Delay: 5 * time.Second})
+	srv := newTestServer(t, fake)
+	chat := srv.createChat(t, "fake")
+	ws := srv.dial(t)
+
+	ws.send("ai_prompt", models.WSMsgData{ChatID: chat.ID, Provider: "fake", Content: "Hi"})
+	received := ws.readUntil("ai_response_end", 5*time.Second)
+
+	timeout := indexOfType(received, "ai_response_timeout")
+	if timeout < 0 {
+		t.Fatalf("Expected ai_response_timeout before the completion, got %s", messageTypes(received))
+	}
+	if action := received[timeout].Data.Action; action != "idle" {
+		t.Errorf("Expected an idle timeout, got %q", action)
+	}
+	if content := streamedContent(received); content != "" {
+		t.Errorf("Expected nothing to be streamed, got %q", content)
+	}
+}
+
+func TestWebSocketStreamResume(t *testing.T) {
+	fake := providers.NewFakeProvider("fake").Script(providers.FakeReply{Chunks: []string{"one ", "two ", "three"}, Delay: 100 * time.Millisecond})
+	srv := newRedisTestServer(t, fake)
+	chat := srv.createChat(t, "fake")
+
+	// The first connection drops after the first chunk
+	first := srv.dial(t)
+	first.send("ai_prompt", models.WSMsgData{ChatID: chat.ID, Provider: "fake", Content: "Count"})
+	received := first.readUntil("ai_response", 5*time.Second)
+	chunk := received[len(received)-1].Data
+	first.conn.Close()
+
+	// The response is still saved
+	deadline := time.Now().Add(5 * time.Second)
+	for len(srv.chatMessages(t, chat.ID)) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("The response wasn't saved after the client disconnected")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// A new connection gets the rest of the stream from the Redis buffer
+	second := srv.dial(t)
+	second.send("resume_stream", models.WSMsgData{ChatID: chat.ID, Provider: "fake", StreamID: chunk.StreamID, StreamSeq: chunk.StreamSeq})
+	resumed := second.readUntil("ai_response_end", 5*time.Second)
+	if content := chunk.Content + streamedContent(resumed); content != "one two three" {
+		t.Errorf("Expected the resumed stream to complete the response, got %q", content)
+	}
+}
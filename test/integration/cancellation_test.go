@@ -0,0 +1,228 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+)
+
+// maxCancellationLatency bounds how long a cancelled provider may take to return. It is
+// below providers.ProcessWaitDelay so that only killing the whole process group passes.
+const maxCancellationLatency = time.Second
+
+// streamingScript is a fake provider CLI that streams tokens forever and leaves a background
+// child holding its stdout, like CLIs that spawn helper processes
+const streamingScript = `#!/bin/sh
+trap '' TERM
+sleep 60 &
+echo $! > %q
+while :; do echo token; sleep 0.05; done
+`
+
+// cancellationHarness is a fake streaming provider CLI plus the pid file of the child it spawns
+type cancellationHarness struct {
+	script  string
+	pidFile string
+	logDir  string
+}
+
+func newCancellationHarness(t *testing.T) *cancellationHarness {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("cancellation harness relies on /bin/sh and /proc")
+	}
+	if err := utils.InitPathManager(); err != nil {
+		t.Fatalf("Failed to init path manager: %v", err)
+	}
+
+	dir := t.TempDir()
+	h := &cancellationHarness{
+		script:  filepath.Join(dir, "fake-provider.sh"),
+		pidFile: filepath.Join(dir, "child.pid"),
+		logDir:  filepath.Join(dir, "logs"),
+	}
+	if err := os.WriteFile(h.script, []byte(fmt.Sprintf(streamingScript, h.pidFile)), 0755); err != nil {
+		t.Fatalf("Failed to write fake provider: %v", err)
+	}
+	return h
+}
+
+// childPid returns the pid of the background child the fake provider spawned
+func (h *cancellationHarness) childPid(t *testing.T) int {
+	t.Helper()
+	deadline := time.Now().Add(maxCancellationLatency)
+	for {
+		data, err := os.ReadFile(h.pidFile)
+		if err == nil {
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+				return pid
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Fake provider did not record its child pid")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// assertProcessGone fails unless pid exits (or is left as a zombie) within maxCancellationLatency
+func assertProcessGone(t *testing.T, pid int) {
+	t.Helper()
+	deadline := time.Now().Add(maxCancellationLatency)
+	for {
+		stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			return
+		}
+		// The state follows the parenthesized command name
+		if fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:])); len(fields) > 0 && fields[0] == "Z" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Child process %d still running after cancellation", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// firstTokenWriter signals when the provider has streamed its first output
+type firstTokenWriter struct {
+	once    sync.Once
+	started chan struct{}
+}
+
+func newFirstTokenWriter() *firstTokenWriter {
+	return &firstTokenWriter{started: make(chan struct{})}
+}
+
+func (w *firstTokenWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	return len(p), nil
+}
+
+// cancellationCount returns the number of recorded cancellations of a provider
+func cancellationCount(providerID string) int64 {
+	for _, s := range providers.CancellationMetrics() {
+		if s.Provider == providerID {
+			return s.Count
+		}
+	}
+	return 0
+}
+
+// assertStreamCancels streams from provider, cancels after the first token and checks that
+// the call returns, the process tree dies and the cancellation is measured in bounded time
+func assertStreamCancels(t *testing.T, h *cancellationHarness, provider providers.AIProvider) {
+	t.Helper()
+	before := cancellationCount(provider.GetID())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	writer := newFirstTokenWriter()
+	done := make(chan error, 1)
+	go func() {
+		done <- provider.StreamResponse(ctx, "hello", 1, writer)
+	}()
+
+	select {
+	case <-writer.started:
+	case err := <-done:
+		t.Fatalf("Provider finished before streaming: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Provider did not stream a token")
+	}
+	pid := h.childPid(t)
+
+	cancelledAt := time.Now()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected an error from a cancelled stream")
+		}
+		t.Logf("%s returned %v after cancellation", provider.GetID(), time.Since(cancelledAt))
+	case <-time.After(maxCancellationLatency):
+		t.Fatalf("%s did not return within %v of cancellation", provider.GetID(), maxCancellationLatency)
+	}
+
+	assertProcessGone(t, pid)
+
+	if got := cancellationCount(provider.GetID()); got != before+1 {
+		t.Errorf("Expected cancellation to be recorded once, count went from %d to %d", before, got)
+	}
+}
+
+func TestCLIProviderStreamCancellation(t *testing.T) {
+	h := newCancellationHarness(t)
+	provider := providers.NewCLIProvider(providers.ProviderConfig{
+		ID:      "cancel-cli",
+		Name:    "Cancel CLI",
+		Type:    providers.ProviderTypeCLI,
+		Command: h.script,
+	}, h.logDir, providers.DefaultEnvPolicy)
+
+	assertStreamCancels(t, h, provider)
+}
+
+func TestClaudeProviderStreamCancellation(t *testing.T) {
+	h := newCancellationHarness(t)
+	provider := providers.NewClaudeProvider(h.script, h.logDir, false, "")
+
+	assertStreamCancels(t, h, provider)
+}
+
+func TestCLIProviderSendPromptCancellation(t *testing.T) {
+	h := newCancellationHarness(t)
+	provider := providers.NewCLIProvider(providers.ProviderConfig{
+		ID:      "cancel-cli-reader",
+		Name:    "Cancel CLI Reader",
+		Type:    providers.ProviderTypeCLI,
+		Command: h.script,
+	}, h.logDir, providers.DefaultEnvPolicy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reader, err := provider.SendPrompt(ctx, "hello", 1)
+	if err != nil {
+		t.Fatalf("SendPrompt failed: %v", err)
+	}
+	if _, err := reader.Read(make([]byte, 16)); err != nil {
+		t.Fatalf("Failed to read first token: %v", err)
+	}
+	pid := h.childPid(t)
+
+	cancel()
+
+	// The pipe must reach EOF and Close must reap the process once the tree is killed
+	done := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, reader)
+		reader.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(maxCancellationLatency):
+		t.Fatalf("Reader was not closed within %v of cancellation", maxCancellationLatency)
+	}
+
+	assertProcessGone(t, pid)
+	if got := cancellationCount("cancel-cli-reader"); got != 1 {
+		t.Errorf("Expected one recorded cancellation, got %d", got)
+	}
+}
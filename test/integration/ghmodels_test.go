@@ -0,0 +1,110 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+)
+
+// fakeGHScript answers like gh with the models extension: `models run <model>` echoes the
+// model and the prompt read from stdin, and fails for the model named "broken"
+const fakeGHScript = `#!/bin/sh
+case "$1 $2" in
+"--version "*) echo "gh version 2.70.0 (2025-04-01)"; echo "https://github.com/cli/cli/releases/tag/v2.70.0" ;;
+"models --help") echo "GitHub Models CLI extension" ;;
+"models run")
+	if [ "$3" = "broken" ]; then echo "unknown model: broken" >&2; exit 1; fi
+	printf '[%s] ' "$3"; cat ;;
+*) echo "unknown command $1" >&2; exit 1 ;;
+esac
+`
+
+// newFakeGH writes the fake gh CLI and returns its path and a log directory
+func newFakeGH(t *testing.T, script string) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh relies on /bin/sh")
+	}
+	if err := utils.InitPathManager(); err != nil {
+		t.Fatalf("Failed to init path manager: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gh")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake gh: %v", err)
+	}
+	return path, filepath.Join(dir, "logs")
+}
+
+func TestGHModelsProvider(t *testing.T) {
+	ghPath, logDir := newFakeGH(t, fakeGHScript)
+	provider := providers.NewGHModelsProvider(ghPath, logDir, []string{"openai/gpt-4o-mini", "broken"})
+
+	status := provider.GetStatus()
+	if !status.Available || status.Status != "ready" {
+		t.Fatalf("Expected ready status, got %+v", status)
+	}
+	if status.Version != "gh version 2.70.0 (2025-04-01)" {
+		t.Errorf("Expected the first line of gh --version, got %q", status.Version)
+	}
+
+	t.Run("StreamsDefaultModel", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := provider.StreamResponse(context.Background(), "hello there", 1, &out); err != nil {
+			t.Fatalf("StreamResponse failed: %v", err)
+		}
+		if out.String() != "[openai/gpt-4o-mini] hello there" {
+			t.Errorf("Unexpected output %q", out.String())
+		}
+	})
+
+	t.Run("SendPrompt", func(t *testing.T) {
+		reader, err := provider.SendPrompt(context.Background(), "hi", 2)
+		if err != nil {
+			t.Fatalf("SendPrompt failed: %v", err)
+		}
+		out, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil || string(out) != "[openai/gpt-4o-mini] hi" {
+			t.Errorf("Unexpected output %q (err %v)", out, err)
+		}
+	})
+
+	t.Run("ReportsErrors", func(t *testing.T) {
+		ctx := providers.WithModel(context.Background(), "broken")
+		err := provider.StreamResponse(ctx, "hi", 3, io.Discard)
+		if err == nil || !strings.Contains(err.Error(), "unknown model: broken") {
+			t.Errorf("Expected the gh error output, got %v", err)
+		}
+	})
+}
+
+func TestGHModelsProviderStatus(t *testing.T) {
+	t.Run("NotInstalled", func(t *testing.T) {
+		provider := providers.NewGHModelsProvider("/nonexistent/gh", t.TempDir(), nil)
+		if status := provider.GetStatus(); status.Status != "not_installed" || status.Available {
+			t.Errorf("Expected not_installed, got %+v", status)
+		}
+	})
+
+	t.Run("MissingExtension", func(t *testing.T) {
+		ghPath, logDir := newFakeGH(t, "#!/bin/sh\n[ \"$1\" = --version ] && echo 'gh version 2.70.0' && exit 0\nexit 1\n")
+		provider := providers.NewGHModelsProvider(ghPath, logDir, nil)
+		status := provider.GetStatus()
+		if status.Status != "not_configured" || status.Available {
+			t.Errorf("Expected not_configured, got %+v", status)
+		}
+		if models := provider.GetModels(); len(models) != len(providers.GHModels) || !models[0].Default {
+			t.Errorf("Expected the default model list, got %+v", models)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+)
+
+// sandboxProbeScript reports where and under which limits it runs
+const sandboxProbeScript = `#!/bin/sh
+echo "pwd=$(pwd)"
+echo "nofile=$(ulimit -n)"
+echo "cpu=$(ulimit -t)"
+`
+
+// wrapperScript stands in for firejail/docker: it marks the output and runs the command it is given
+const wrapperScript = `#!/bin/sh
+echo "wrapped"
+exec "$@"
+`
+
+func TestSandboxedCLIProvider(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox test relies on /bin/sh ulimit")
+	}
+	if err := utils.InitPathManager(); err != nil {
+		t.Fatalf("Failed to init path manager: %v", err)
+	}
+
+	dir := t.TempDir()
+	probe := filepath.Join(dir, "probe.sh")
+	wrapper := filepath.Join(dir, "wrapper.sh")
+	for path, script := range map[string]string{probe: sandboxProbeScript, wrapper: wrapperScript} {
+		if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	workDir := filepath.Join(dir, "work")
+	provider := providers.NewCLIProvider(providers.ProviderConfig{
+		ID:      "probe",
+		Type:    providers.ProviderTypeCLI,
+		Command: probe,
+		Sandbox: providers.Sandbox{Limits: providers.ResourceLimits{OpenFiles: 64}},
+	}, filepath.Join(dir, "logs"), providers.DefaultEnvPolicy)
+
+	// The server-wide sandbox supplies what the provider doesn't set itself
+	server := providers.Sandbox{
+		WorkDir: workDir,
+		Wrapper: []string{wrapper},
+		Limits:  providers.ResourceLimits{CPUSeconds: 30, OpenFiles: 256},
+	}
+	provider.SetSandbox(server.With(providers.Sandbox{Limits: providers.ResourceLimits{OpenFiles: 64}}))
+
+	var out bytes.Buffer
+	if err := provider.StreamResponse(context.Background(), "hi", 1, &out); err != nil {
+		t.Fatalf("StreamResponse failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	expected := []string{"wrapped", "pwd=" + workDir, "nofile=64", "cpu=30"}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestSandboxValidate(t *testing.T) {
+	if err := (providers.Sandbox{Wrapper: []string{""}}).Validate(); err == nil {
+		t.Error("Expected an empty wrapper command to be rejected")
+	}
+	if err := (providers.Sandbox{Limits: providers.ResourceLimits{CPUSeconds: -1}}).Validate(); err == nil {
+		t.Error("Expected negative limits to be rejected")
+	}
+	if err := (providers.Sandbox{WorkDir: "/srv/ai", Wrapper: []string{"firejail", "--quiet", "--"}}).Validate(); err != nil {
+		t.Errorf("Expected a valid sandbox, got %v", err)
+	}
+}
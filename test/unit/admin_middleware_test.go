@@ -0,0 +1,90 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAdminRouter(cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// Like main with TRUSTED_PROXIES empty: forwarding headers are never honored
+	router.SetTrustedProxies(nil)
+	adminOnly := middleware.AdminMiddleware(cfg, nil)
+	router.GET("/admin", adminOnly, func(c *gin.Context) { c.String(http.StatusOK, "dashboard") })
+	router.GET("/api/admin/stats", adminOnly, func(c *gin.Context) { c.String(http.StatusOK, "stats") })
+	return router
+}
+
+func TestAdminMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminToken string
+		loopback   bool
+		path       string
+		remoteAddr string
+		auth       string
+		wantStatus int
+	}{
+		{name: "no token, loopback client", loopback: true, path: "/admin", remoteAddr: "127.0.0.1:5000", wantStatus: http.StatusOK},
+		{name: "no token, remote client", loopback: true, path: "/api/admin/stats", remoteAddr: "203.0.113.7:5000", wantStatus: http.StatusForbidden},
+		{name: "no token outside development", path: "/api/admin/stats", remoteAddr: "127.0.0.1:5000", wantStatus: http.StatusForbidden},
+		{name: "valid bearer token", adminToken: "0123456789abcdef", path: "/api/admin/stats", remoteAddr: "203.0.113.7:5000", auth: "Bearer 0123456789abcdef", wantStatus: http.StatusOK},
+		{name: "wrong bearer token", adminToken: "0123456789abcdef", path: "/api/admin/stats", remoteAddr: "203.0.113.7:5000", auth: "Bearer nope", wantStatus: http.StatusForbidden},
+		{name: "token set, loopback is not enough", adminToken: "0123456789abcdef", path: "/api/admin/stats", remoteAddr: "127.0.0.1:5000", wantStatus: http.StatusForbidden},
+		{name: "page redirects to login", adminToken: "0123456789abcdef", path: "/admin", remoteAddr: "203.0.113.7:5000", wantStatus: http.StatusSeeOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAdminRouter(&config.Config{AdminToken: tt.adminToken, AdminLoopback: tt.loopback})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			if tt.wantStatus == http.StatusSeeOther {
+				assert.Equal(t, middleware.AdminLoginPath, resp.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestAdminMiddleware_IgnoresForwardedLoopback(t *testing.T) {
+	router := newAdminRouter(&config.Config{AdminLoopback: true})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/stats", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+	req.Header.Set("X-Forwarded-For", "127.0.0.1")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestConfig_ValidateAdminToken(t *testing.T) {
+	cfg := config.Load()
+	cfg.AdminToken = ""
+
+	cfg.AdminLoopback = true
+	assert.Contains(t, strings.Join(cfg.Validate().Warnings, "\n"), "every loopback client is an admin")
+
+	cfg.AdminLoopback = false
+	assert.Contains(t, strings.Join(cfg.Validate().Warnings, "\n"), "admin dashboard and API are disabled")
+
+	cfg.AdminToken = "0123456789abcdef"
+	assert.NotContains(t, strings.Join(cfg.Validate().Warnings, "\n"), "ADMIN_TOKEN")
+}
@@ -0,0 +1,125 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/auth"
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/middleware"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{EnableCSRF: true, AdminToken: "0123456789abcdef"}
+
+	hash, err := auth.HashPassword("secret")
+	require.NoError(t, err)
+	users, err := auth.ParseUsers([]byte("users:\n  - id: alice\n    password_hash: \"" + hash + "\"\n    roles: [admin]\n  - id: bob\n    password_hash: \"" + hash + "\"\n"))
+	require.NoError(t, err)
+	signer, err := auth.NewHS256Signer([]byte("0123456789abcdef0123456789abcdef"), "hub")
+	require.NoError(t, err)
+	authService := services.NewAuthService(signer, users, services.NewMemoryTicketStore(), time.Minute, time.Hour)
+	sessionService := services.NewSessionService(services.NewMemorySessionStore())
+
+	router := gin.New()
+	router.Use(middleware.JWTMiddleware(cfg, authService))
+	router.Use(middleware.SessionMiddleware(sessionService, time.Hour))
+	router.Use(middleware.CSRFMiddleware(cfg))
+	router.POST("/api/settings", func(c *gin.Context) {
+		claims, _ := middleware.AuthClaims(c)
+		subject := ""
+		if claims != nil {
+			subject = claims.Subject
+		}
+		c.String(http.StatusOK, subject)
+	})
+	router.GET("/api/admin/stats", middleware.AdminMiddleware(cfg, sessionService), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	login := func(user string) string {
+		tokens, err := authService.Login(context.Background(), user, "secret")
+		require.NoError(t, err)
+		return tokens.AccessToken
+	}
+	do := func(method, path, bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A valid access token needs no CSRF token and gets no session
+	w := do(http.MethodPost, "/api/settings", login("bob"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bob", w.Body.String())
+	for _, cookie := range w.Result().Cookies() {
+		assert.NotEqual(t, middleware.SessionCookieName, cookie.Name)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/api/settings", "forged.token.value").Code)
+	// Without a bearer token the session based checks apply
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/api/settings", "").Code)
+
+	// The admin role of the token grants the admin routes
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/admin/stats", login("alice")).Code)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/admin/stats", login("bob")).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/admin/stats", cfg.AdminToken).Code)
+}
+
+func TestPromptQuotaMiddleware_BearerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+
+	hash, err := auth.HashPassword("secret")
+	require.NoError(t, err)
+	users, err := auth.ParseUsers([]byte("users:\n  - id: bob\n    password_hash: \"" + hash + "\"\n"))
+	require.NoError(t, err)
+	signer, err := auth.NewHS256Signer([]byte("0123456789abcdef0123456789abcdef"), "hub")
+	require.NoError(t, err)
+	authService := services.NewAuthService(signer, users, services.NewMemoryTicketStore(), time.Minute, time.Hour)
+	sessionService := services.NewSessionService(services.NewMemorySessionStore())
+	quotaService := services.NewQuotaService(services.NewMemoryQuotaStore(), 2, 0)
+
+	router := gin.New()
+	router.Use(middleware.JWTMiddleware(cfg, authService))
+	router.Use(middleware.SessionMiddleware(sessionService, time.Hour))
+	router.POST("/api/complete", middleware.PromptQuotaMiddleware(quotaService), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tokens, err := authService.Login(context.Background(), "bob", "secret")
+	require.NoError(t, err)
+	complete := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/complete", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Token requests have no session, so their prompts are counted for the token's user
+	assert.Equal(t, http.StatusOK, complete().Code)
+	assert.Equal(t, http.StatusOK, complete().Code)
+	w := complete()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	status, err := quotaService.Status(services.QuotaKey("", "bob"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), status.Daily.Used)
+	assert.True(t, status.Exhausted)
+
+	// A session with the user's name doesn't share the counters
+	status, err = quotaService.Status("bob")
+	require.NoError(t, err)
+	assert.Zero(t, status.Daily.Used)
+}
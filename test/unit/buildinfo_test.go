@@ -0,0 +1,35 @@
+package unit
+
+import (
+	"runtime"
+	"testing"
+
+	"ai-gateway-hub/internal/buildinfo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildInfo_Get(t *testing.T) {
+	origVersion, origCommit, origDate := buildinfo.Version, buildinfo.Commit, buildinfo.BuildDate
+	defer func() {
+		buildinfo.Version, buildinfo.Commit, buildinfo.BuildDate = origVersion, origCommit, origDate
+	}()
+
+	buildinfo.Version = "v1.2.3"
+	buildinfo.Commit = "0123456789abcdef0123"
+	buildinfo.BuildDate = "2025-01-02T03:04:05Z"
+
+	info := buildinfo.Get(map[string]bool{"ENABLE_HEALTH_CHECKS": true, "ENABLE_WS_BACKPLANE": false})
+
+	assert.Equal(t, "v1.2.3", info.Version)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.Equal(t, "0123456789ab", info.ShortCommit())
+	assert.Equal(t, []string{"ENABLE_HEALTH_CHECKS"}, info.EnabledFeatures())
+	assert.Equal(t, "v1.2.3 (0123456789ab) built 2025-01-02T03:04:05Z "+runtime.Version(), info.String())
+}
+
+func TestBuildInfo_StringWithoutMetadata(t *testing.T) {
+	info := buildinfo.BuildInfo{Version: "dev", GoVersion: "go1.23.0"}
+	assert.Equal(t, "dev go1.23.0", info.String())
+	assert.Empty(t, info.EnabledFeatures())
+}
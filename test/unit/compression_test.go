@@ -0,0 +1,84 @@
+package unit
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCompressionRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.CompressionMiddleware(middleware.CompressionOptions{
+		Level:         5,
+		MinSize:       256,
+		ContentTypes:  strings.Split(config.DefaultCompressionTypes, ","),
+		ExcludedPaths: []string{"/ws"},
+	}))
+
+	history := strings.Repeat(`{"role": "assistant", "content": "hello"},`, 100)
+	router.GET("/api/history", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(history)) })
+	router.GET("/api/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	router.GET("/image", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(history)) })
+	router.GET("/ws", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(history)) })
+	router.GET("/events", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		for i := 0; i < 50; i++ {
+			c.Writer.WriteString("data: chunk of a streamed response\n\n")
+			c.Writer.Flush()
+		}
+	})
+	return router
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	router := newCompressionRouter()
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	plain := get("/api/history", "")
+	assert.Empty(t, plain.Header().Get("Content-Encoding"))
+
+	w := get("/api/history", "br;q=1.0, gzip;q=0.8")
+	require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Less(t, w.Body.Len(), plain.Body.Len())
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, plain.Body.String(), string(body))
+
+	w = get("/api/history", "gzip;q=0, deflate")
+	require.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	body, err = io.ReadAll(flate.NewReader(w.Body))
+	require.NoError(t, err)
+	assert.Equal(t, plain.Body.String(), string(body))
+
+	// Below the size threshold, other content types, excluded paths and event streams pass through
+	for _, path := range []string{"/api/small", "/image", "/ws", "/events"} {
+		w := get(path, "gzip")
+		assert.Empty(t, w.Header().Get("Content-Encoding"), path)
+		assert.Equal(t, get(path, "").Body.String(), w.Body.String(), path)
+	}
+	assert.Empty(t, get("/api/history", "identity").Header().Get("Content-Encoding"))
+	assert.Equal(t, "gzip", get("/api/history", "*").Header().Get("Content-Encoding"))
+}
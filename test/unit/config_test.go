@@ -2,10 +2,16 @@ package unit
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfigLoad(t *testing.T) {
@@ -164,4 +170,583 @@ func TestConfigLoad(t *testing.T) {
 			t.Error("Expected default health checks true for invalid value")
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestConfigSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		got  func(cfg *config.Config) any
+		want any
+	}{
+		{name: "auth mode default", got: func(c *config.Config) any { return c.AuthMode }, want: config.AuthModeSession},
+		{name: "JWT algorithm default", got: func(c *config.Config) any { return c.JWTAlgorithm }, want: "HS256"},
+		{name: "JWT access TTL default", got: func(c *config.Config) any { return c.JWTAccessTTL }, want: 15 * time.Minute},
+		{name: "JWT refresh TTL default", got: func(c *config.Config) any { return c.JWTRefreshTTL }, want: 7 * 24 * time.Hour},
+		{name: "default role", got: func(c *config.Config) any { return c.DefaultRole }, want: "user"},
+		{name: "circuit threshold", env: map[string]string{"PROVIDER_CIRCUIT_THRESHOLD": "3"}, got: func(c *config.Config) any { return c.ProviderCircuitThreshold }, want: 3},
+		{name: "circuit cooldown in seconds", env: map[string]string{"PROVIDER_CIRCUIT_COOLDOWN": "60"}, got: func(c *config.Config) any { return c.ProviderCircuitCooldown }, want: time.Minute},
+		{name: "embedding provider default", got: func(c *config.Config) any { return c.EmbeddingProvider }, want: "hash"},
+		{name: "draft TTL default", got: func(c *config.Config) any { return c.DraftTTL }, want: 7 * 24 * time.Hour},
+		{name: "message encryption off by default", got: func(c *config.Config) any { return c.EnableMessageEncryption }, want: false},
+		{
+			name: "failover chains",
+			env:  map[string]string{"PROVIDER_FAILOVER": "claude > gemini > ollama, gemini>ollama"},
+			got:  func(c *config.Config) any { return c.FailoverChains() },
+			want: map[string][]string{"claude": {"gemini", "ollama"}, "gemini": {"ollama"}},
+		},
+		{name: "fake provider off by default", got: func(c *config.Config) any { return c.EnableFakeProvider }, want: false},
+		{name: "fake provider chunks default", got: func(c *config.Config) any { return c.FakeProviderChunks }, want: 20},
+		{name: "fake provider delay default", got: func(c *config.Config) any { return c.FakeProviderDelay }, want: 50 * time.Millisecond},
+		{name: "max prompt length default", got: func(c *config.Config) any { return c.MaxPromptLength }, want: 100000},
+		{name: "max response bytes default", got: func(c *config.Config) any { return c.MaxResponseBytes }, want: 2097152},
+		{name: "oversized response action default", got: func(c *config.Config) any { return c.OversizedResponseAction }, want: "truncate"},
+		{name: "moderation off by default", got: func(c *config.Config) any { return c.EnableModeration }, want: false},
+		{name: "moderation timeout default", got: func(c *config.Config) any { return c.ModerationTimeout }, want: 5 * time.Second},
+		{name: "moderation fails open by default", got: func(c *config.Config) any { return c.ModerationFailClosed }, want: false},
+		{name: "daily prompt quota default", got: func(c *config.Config) any { return c.DailyPromptQuota }, want: 0},
+		{name: "monthly prompt quota default", got: func(c *config.Config) any { return c.MonthlyPromptQuota }, want: 0},
+		{name: "idle chat retention default", got: func(c *config.Config) any { return c.RetentionIdleChatDays }, want: 0},
+		{name: "messages per chat retention default", got: func(c *config.Config) any { return c.RetentionMaxMessagesPerChat }, want: 0},
+		{name: "log retention default", got: func(c *config.Config) any { return c.RetentionLogDays }, want: 0},
+		{name: "retention interval default", got: func(c *config.Config) any { return c.RetentionInterval }, want: time.Hour},
+		{name: "retention dry run default", got: func(c *config.Config) any { return c.RetentionDryRun }, want: false},
+		{name: "retry attempts default", got: func(c *config.Config) any { return c.ProviderRetryAttempts }, want: 3},
+		{name: "retry backoff default", got: func(c *config.Config) any { return c.ProviderRetryBackoff }, want: 500 * time.Millisecond},
+		{name: "retry max backoff default", got: func(c *config.Config) any { return c.ProviderRetryMaxBackoff }, want: 8 * time.Second},
+		{name: "retry classes default", got: func(c *config.Config) any { return c.ProviderRetryOn }, want: []string{"network", "rate_limit", "server"}},
+		{name: "retry attempts", env: map[string]string{"PROVIDER_RETRY_ATTEMPTS": "5"}, got: func(c *config.Config) any { return c.ProviderRetryAttempts }, want: 5},
+		{name: "retry backoff in milliseconds", env: map[string]string{"PROVIDER_RETRY_BACKOFF": "200"}, got: func(c *config.Config) any { return c.ProviderRetryBackoff }, want: 200 * time.Millisecond},
+		{name: "retry classes", env: map[string]string{"PROVIDER_RETRY_ON": "server, crash"}, got: func(c *config.Config) any { return c.ProviderRetryOn }, want: []string{"server", "crash"}},
+		{name: "stream chunking default", got: func(c *config.Config) any { return c.StreamChunkingFor("claude") }, want: "rune"},
+		{name: "provider stream chunking", env: map[string]string{"PROVIDER_STREAM_CHUNKING": "claude = markdown"}, got: func(c *config.Config) any { return c.StreamChunkingFor("claude") }, want: "markdown"},
+		{name: "stream chunking of other providers", env: map[string]string{"PROVIDER_STREAM_CHUNKING": "claude = markdown"}, got: func(c *config.Config) any { return c.StreamChunkingFor("gemini") }, want: "rune"},
+		{
+			name: "provider prompt timeouts",
+			env:  map[string]string{"PROVIDER_PROMPT_TIMEOUTS": "claude=600,gemini = 90", "PROVIDER_IDLE_TIMEOUTS": "claude=0"},
+			got: func(c *config.Config) any {
+				total, idle := c.PromptTimeouts("claude")
+				return []time.Duration{total, idle}
+			},
+			// An idle timeout of 0 disables it for the provider
+			want: []time.Duration{10 * time.Minute, 0},
+		},
+		{
+			name: "prompt timeouts of a provider without an idle timeout",
+			env:  map[string]string{"PROVIDER_PROMPT_TIMEOUTS": "claude=600,gemini = 90", "PROVIDER_IDLE_TIMEOUTS": "claude=0"},
+			got: func(c *config.Config) any {
+				total, idle := c.PromptTimeouts("gemini")
+				return []time.Duration{total, idle}
+			},
+			want: []time.Duration{90 * time.Second, 2 * time.Minute},
+		},
+		{
+			name: "prompt timeouts of other providers",
+			env:  map[string]string{"PROVIDER_PROMPT_TIMEOUTS": "claude=600,gemini = 90"},
+			got: func(c *config.Config) any {
+				total, idle := c.PromptTimeouts("other")
+				return []time.Duration{total, idle}
+			},
+			want: []time.Duration{5 * time.Minute, 2 * time.Minute},
+		},
+		{name: "longest prompt timeout", env: map[string]string{"PROVIDER_PROMPT_TIMEOUTS": "claude=600,gemini = 90"}, got: func(c *config.Config) any { return c.LongestPromptTimeout() }, want: 10 * time.Minute},
+		{name: "enabled tools default", got: func(c *config.Config) any { return c.ToolsEnabled }, want: []string{"calculator"}},
+		{name: "tool steps default", got: func(c *config.Config) any { return c.ToolsMaxSteps }, want: 5},
+		{name: "WebSocket ticket TTL default", got: func(c *config.Config) any { return c.WSTicketTTL }, want: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			assert.Equal(t, tt.want, tt.got(config.Load()))
+		})
+	}
+}
+
+// validationMessages returns the messages that mention field, nil if there are none
+func validationMessages(messages []string, field string) []string {
+	var matching []string
+	for _, message := range messages {
+		if strings.Contains(message, field) {
+			matching = append(matching, message)
+		}
+	}
+	return matching
+}
+
+func TestConfigValidate(t *testing.T) {
+	usersFile := filepath.Join(t.TempDir(), "users.yaml")
+	require.NoError(t, os.WriteFile(usersFile, []byte("users: []\n"), 0600))
+	moderationRules := filepath.Join(t.TempDir(), "moderation.yaml")
+	require.NoError(t, os.WriteFile(moderationRules, []byte("rules: []\n"), 0600))
+	missingFile := filepath.Join(t.TempDir(), "missing.yaml")
+	jwt := func(c *config.Config) {
+		c.AuthMode = config.AuthModeJWT
+		c.AuthUsersFile = usersFile
+		c.JWTSecret = strings.Repeat("s", 32)
+	}
+
+	// Each case checks every error and warning that mentions field
+	tests := []struct {
+		name         string
+		env          map[string]string
+		modify       func(cfg *config.Config)
+		field        string
+		wantErrors   []string
+		wantWarnings []string
+	}{
+		{name: "JWT settings unused in session mode", field: "JWT_"},
+		{name: "unknown auth mode", modify: func(c *config.Config) { c.AuthMode = "oauth" }, field: "AUTH_MODE", wantErrors: []string{"AUTH_MODE must be session or jwt"}},
+		{name: "JWT mode", modify: jwt, field: "JWT_"},
+		{
+			name:       "short JWT secret",
+			modify:     func(c *config.Config) { jwt(c); c.JWTSecret = "short" },
+			field:      "JWT_SECRET",
+			wantErrors: []string{"JWT_SECRET must be at least 32 characters with JWT_ALGORITHM=HS256"},
+		},
+		{
+			name:       "refresh TTL shorter than access TTL",
+			modify:     func(c *config.Config) { jwt(c); c.JWTRefreshTTL = time.Minute },
+			field:      "JWT_REFRESH_TTL",
+			wantErrors: []string{"JWT_REFRESH_TTL must not be shorter than JWT_ACCESS_TTL"},
+		},
+		{
+			name:       "RS256 without a private key",
+			modify:     func(c *config.Config) { jwt(c); c.JWTAlgorithm = "RS256" },
+			field:      "JWT_PRIVATE_KEY_FILE",
+			wantErrors: []string{"JWT_PRIVATE_KEY_FILE is required with JWT_ALGORITHM=RS256"},
+		},
+		{
+			name:       "missing users file",
+			modify:     func(c *config.Config) { jwt(c); c.AuthUsersFile = missingFile },
+			field:      "AUTH_USERS_FILE",
+			wantErrors: []string{"AUTH_USERS_FILE not found: " + missingFile},
+		},
+		{name: "viewer default role", modify: func(c *config.Config) { c.DefaultRole = "viewer" }, field: "DEFAULT_ROLE"},
+		// Admin is never a default: it is granted by the admin token, the login page or a user's roles
+		{name: "admin default role", modify: func(c *config.Config) { c.DefaultRole = "admin" }, field: "DEFAULT_ROLE", wantErrors: []string{"DEFAULT_ROLE must be viewer or user"}},
+
+		{name: "circuit breaker", env: map[string]string{"PROVIDER_CIRCUIT_THRESHOLD": "3", "PROVIDER_CIRCUIT_COOLDOWN": "60"}, field: "PROVIDER_CIRCUIT"},
+		{
+			name:       "negative circuit threshold",
+			modify:     func(c *config.Config) { c.ProviderCircuitThreshold = -1 },
+			field:      "PROVIDER_CIRCUIT",
+			wantErrors: []string{"PROVIDER_CIRCUIT_THRESHOLD must not be negative (0 disables the circuit breaker)"},
+		},
+		{
+			name:       "no circuit cooldown",
+			modify:     func(c *config.Config) { c.ProviderCircuitThreshold = 5; c.ProviderCircuitCooldown = 0 },
+			field:      "PROVIDER_CIRCUIT",
+			wantErrors: []string{"PROVIDER_CIRCUIT_COOLDOWN must be at least 1 second"},
+		},
+		// The cooldown doesn't matter while the breaker is disabled
+		{name: "circuit breaker disabled", modify: func(c *config.Config) { c.ProviderCircuitThreshold = 0; c.ProviderCircuitCooldown = 0 }, field: "PROVIDER_CIRCUIT"},
+
+		{name: "hash embeddings", field: "EMBEDDING_"},
+		{
+			name:       "chunk overlap as large as the chunk",
+			modify:     func(c *config.Config) { c.RAGChunkOverlap = c.RAGChunkSize },
+			field:      "RAG_CHUNK_OVERLAP",
+			wantErrors: []string{"RAG_CHUNK_OVERLAP must be at least 0 and less than RAG_CHUNK_SIZE"},
+		},
+		{
+			name:       "HTTP embeddings without URL and model",
+			modify:     func(c *config.Config) { c.EmbeddingProvider = "http" },
+			field:      "EMBEDDING_",
+			wantErrors: []string{`EMBEDDING_URL must be an http or https URL, got ""`, "EMBEDDING_MODEL is required with EMBEDDING_PROVIDER=http"},
+		},
+		{
+			name: "HTTP embeddings",
+			modify: func(c *config.Config) {
+				c.EmbeddingProvider = "http"
+				c.EmbeddingURL = "https://api.openai.com/v1/embeddings"
+				c.EmbeddingModel = "text-embedding-3-small"
+			},
+			field: "EMBEDDING_",
+		},
+		{name: "unknown embedding provider", modify: func(c *config.Config) { c.EmbeddingProvider = "vec" }, field: "EMBEDDING_", wantErrors: []string{`EMBEDDING_PROVIDER must be hash or http, got "vec"`}},
+
+		{name: "draft TTL", field: "DRAFT_TTL"},
+		{name: "no draft TTL", modify: func(c *config.Config) { c.DraftTTL = 0 }, field: "DRAFT_TTL", wantErrors: []string{"DRAFT_TTL must be positive"}},
+
+		{name: "message encryption off", field: "MESSAGE_ENCRYPTION_KEY"},
+		{
+			name:       "message encryption without a key",
+			modify:     func(c *config.Config) { c.EnableMessageEncryption = true },
+			field:      "MESSAGE_ENCRYPTION_KEY",
+			wantErrors: []string{"MESSAGE_ENCRYPTION_KEY is required when ENABLE_MESSAGE_ENCRYPTION is set"},
+		},
+		{
+			name:       "short message encryption key",
+			modify:     func(c *config.Config) { c.EnableMessageEncryption = true; c.MessageEncryptionKey = "dG9vIHNob3J0" },
+			field:      "MESSAGE_ENCRYPTION_KEY",
+			wantErrors: []string{"MESSAGE_ENCRYPTION_KEY must be a 32 byte key in base64 or hex, or a secret reference"},
+		},
+		{
+			name: "base64 message encryption key",
+			modify: func(c *config.Config) {
+				c.EnableMessageEncryption = true
+				c.MessageEncryptionKey = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+			},
+			field: "MESSAGE_ENCRYPTION_KEY",
+		},
+		{
+			name: "hex message encryption key",
+			modify: func(c *config.Config) {
+				c.EnableMessageEncryption = true
+				c.MessageEncryptionKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+			},
+			field: "MESSAGE_ENCRYPTION_KEY",
+		},
+		{
+			name: "message encryption key reference",
+			modify: func(c *config.Config) {
+				c.EnableMessageEncryption = true
+				c.MessageEncryptionKey = "vault:secret/data/ai#message_key"
+			},
+			field: "MESSAGE_ENCRYPTION_KEY",
+		},
+
+		{name: "failover chains", env: map[string]string{"PROVIDER_FAILOVER": "claude > gemini > ollama, gemini>ollama"}, field: "PROVIDER_FAILOVER"},
+		{name: "failover chain without fallback", modify: func(c *config.Config) { c.ProviderFailover = []string{"claude"} }, field: "PROVIDER_FAILOVER", wantErrors: []string{`PROVIDER_FAILOVER: failover chain "claude" has no fallback, expected primary>fallback>...`}},
+		{name: "failover chain with an empty provider", modify: func(c *config.Config) { c.ProviderFailover = []string{"claude>>gemini"} }, field: "PROVIDER_FAILOVER", wantErrors: []string{`PROVIDER_FAILOVER: invalid failover chain "claude>>gemini", expected distinct providers as primary>fallback>...`}},
+		{name: "failover chain with a repeated provider", modify: func(c *config.Config) { c.ProviderFailover = []string{"claude>gemini>claude"} }, field: "PROVIDER_FAILOVER", wantErrors: []string{`PROVIDER_FAILOVER: invalid failover chain "claude>gemini>claude", expected distinct providers as primary>fallback>...`}},
+		{
+			name:       "two failover chains for a primary",
+			modify:     func(c *config.Config) { c.ProviderFailover = []string{"claude>gemini", "claude>ollama"} },
+			field:      "PROVIDER_FAILOVER",
+			wantErrors: []string{"PROVIDER_FAILOVER: claude has more than one failover chain"},
+		},
+
+		// Only checked when the provider is enabled
+		{name: "fake provider disabled", modify: func(c *config.Config) { c.FakeProviderChunks = 0 }, field: "FAKE_PROVIDER"},
+		{
+			name: "fake provider",
+			modify: func(c *config.Config) {
+				c.EnableFakeProvider = true
+				c.FakeProviderChunks = 0
+				c.FakeProviderDelay = -time.Millisecond
+			},
+			field:      "FAKE_PROVIDER",
+			wantErrors: []string{"FAKE_PROVIDER_CHUNKS must be at least 1", "FAKE_PROVIDER_DELAY must not be negative"},
+		},
+
+		{name: "negative prompt length", modify: func(c *config.Config) { c.MaxPromptLength = -1 }, field: "MAX_PROMPT_LENGTH", wantErrors: []string{"MAX_PROMPT_LENGTH must not be negative"}},
+		{name: "negative response bytes", modify: func(c *config.Config) { c.MaxResponseBytes = -1 }, field: "MAX_RESPONSE_BYTES", wantErrors: []string{"MAX_RESPONSE_BYTES must not be negative"}},
+		{name: "unlimited prompts and responses", modify: func(c *config.Config) { c.MaxPromptLength = 0; c.MaxResponseBytes = 0 }, field: "MAX_"},
+		{
+			name:       "unknown oversized response action",
+			modify:     func(c *config.Config) { c.OversizedResponseAction = "drop" },
+			field:      "OVERSIZED_RESPONSE_ACTION",
+			wantErrors: []string{`OVERSIZED_RESPONSE_ACTION must be truncate or warn, got "drop"`},
+		},
+		{name: "warn of oversized responses", modify: func(c *config.Config) { c.OversizedResponseAction = "warn" }, field: "OVERSIZED_RESPONSE_ACTION"},
+
+		{
+			name:         "moderation without rules or URL",
+			modify:       func(c *config.Config) { c.EnableModeration = true },
+			field:        "MODERATION_",
+			wantWarnings: []string{"ENABLE_MODERATION is set without MODERATION_RULES_FILE or MODERATION_URL, so nothing is moderated"},
+		},
+		{
+			name:       "missing moderation rules",
+			modify:     func(c *config.Config) { c.EnableModeration = true; c.ModerationRulesFile = missingFile },
+			field:      "MODERATION_RULES_FILE",
+			wantErrors: []string{"MODERATION_RULES_FILE: stat " + missingFile + ": no such file or directory"},
+		},
+		{
+			name:       "moderation URL without http",
+			modify:     func(c *config.Config) { c.EnableModeration = true; c.ModerationURL = "ftp://moderation.example.com" },
+			field:      "MODERATION_URL",
+			wantErrors: []string{`MODERATION_URL must be an http or https URL, got "ftp://moderation.example.com"`},
+		},
+		{
+			name: "no moderation timeout",
+			modify: func(c *config.Config) {
+				c.EnableModeration = true
+				c.ModerationURL = "https://moderation.example.com/check"
+				c.ModerationTimeout = 0
+			},
+			field:      "MODERATION_",
+			wantErrors: []string{"MODERATION_TIMEOUT must be positive"},
+		},
+		{
+			name: "moderation",
+			modify: func(c *config.Config) {
+				c.EnableModeration = true
+				c.ModerationRulesFile = moderationRules
+				c.ModerationURL = "https://moderation.example.com/check"
+			},
+			field: "MODERATION_",
+		},
+
+		{name: "origin without scheme", modify: func(c *config.Config) { c.AllowedOrigins = []string{"hub.example.com"} }, field: "ALLOWED_ORIGINS", wantErrors: []string{`ALLOWED_ORIGINS entry "hub.example.com" is invalid: must start with http:// or https://`}},
+		{name: "origin with a path", modify: func(c *config.Config) { c.AllowedOrigins = []string{"https://hub.example.com/app"} }, field: "ALLOWED_ORIGINS", wantErrors: []string{`ALLOWED_ORIGINS entry "https://hub.example.com/app" is invalid: must be a scheme and host without a path`}},
+		{name: "bad origin pattern", modify: func(c *config.Config) { c.AllowedOrigins = []string{"https://[hub.example.com"} }, field: "ALLOWED_ORIGINS", wantErrors: []string{`ALLOWED_ORIGINS entry "https://[hub.example.com" is invalid: syntax error in pattern`}},
+		{
+			name:         "any origin",
+			modify:       func(c *config.Config) { c.AllowedOrigins = []string{"*"} },
+			field:        "ALLOWED_ORIGINS",
+			wantWarnings: []string{"ALLOWED_ORIGINS=* lets any site call the API and open WebSocket connections"},
+		},
+		{
+			name: "origins",
+			modify: func(c *config.Config) {
+				c.AllowedOrigins = []string{"https://hub.example.com", "https://*.example.com", "http://localhost:3000"}
+			},
+			field: "ALLOWED_ORIGINS",
+		},
+
+		{name: "no processors file", modify: func(c *config.Config) { c.ProcessorsFile = "" }, field: "PROCESSORS_FILE"},
+		{
+			name:       "missing processors file",
+			modify:     func(c *config.Config) { c.ProcessorsFile = missingFile },
+			field:      "PROCESSORS_FILE",
+			wantErrors: []string{"PROCESSORS_FILE: stat " + missingFile + ": no such file or directory"},
+		},
+		{name: "processors file", modify: func(c *config.Config) { c.ProcessorsFile = "../../processors.example.yaml" }, field: "PROCESSORS_FILE"},
+
+		{
+			name:       "negative prompt quotas",
+			modify:     func(c *config.Config) { c.DailyPromptQuota = -1; c.MonthlyPromptQuota = -1 },
+			field:      "PROMPT_QUOTA",
+			wantErrors: []string{"DAILY_PROMPT_QUOTA must not be negative", "MONTHLY_PROMPT_QUOTA must not be negative"},
+		},
+		{
+			name:         "daily prompt quota above the monthly one",
+			modify:       func(c *config.Config) { c.DailyPromptQuota = 100; c.MonthlyPromptQuota = 50 },
+			field:        "PROMPT_QUOTA",
+			wantWarnings: []string{"DAILY_PROMPT_QUOTA is larger than MONTHLY_PROMPT_QUOTA"},
+		},
+
+		{name: "retention", field: "RETENTION_"},
+		{
+			name: "negative retention",
+			modify: func(c *config.Config) {
+				c.RetentionIdleChatDays = -1
+				c.RetentionMaxMessagesPerChat = -1
+				c.RetentionLogDays = -1
+				c.RetentionInterval = 0
+			},
+			field: "RETENTION_",
+			wantErrors: []string{
+				"RETENTION_IDLE_CHAT_DAYS must not be negative",
+				"RETENTION_MAX_MESSAGES_PER_CHAT must not be negative",
+				"RETENTION_LOG_DAYS must not be negative",
+				"RETENTION_INTERVAL must be positive",
+			},
+		},
+		{
+			name:         "idle chats moved to a trash that is never purged",
+			modify:       func(c *config.Config) { c.RetentionIdleChatDays = 90; c.DeletedChatRetentionDays = 0 },
+			field:        "RETENTION_",
+			wantWarnings: []string{"RETENTION_IDLE_CHAT_DAYS moves idle chats to the trash, but DELETED_CHAT_RETENTION_DAYS=0 never purges them"},
+		},
+
+		{name: "provider retries", field: "PROVIDER_RETRY"},
+		{name: "no retry attempts", modify: func(c *config.Config) { c.ProviderRetryAttempts = 0 }, field: "PROVIDER_RETRY", wantErrors: []string{"PROVIDER_RETRY_ATTEMPTS must be at least 1 (1 disables retries)"}},
+		{name: "no retry backoff", modify: func(c *config.Config) { c.ProviderRetryBackoff = 0 }, field: "PROVIDER_RETRY_BACKOFF", wantErrors: []string{"PROVIDER_RETRY_BACKOFF must be positive"}},
+		{
+			name:       "retry max backoff below the backoff",
+			modify:     func(c *config.Config) { c.ProviderRetryMaxBackoff = 100 * time.Millisecond },
+			field:      "PROVIDER_RETRY",
+			wantErrors: []string{"PROVIDER_RETRY_MAX_BACKOFF must not be shorter than PROVIDER_RETRY_BACKOFF"},
+		},
+		{
+			name:       "unknown retry class",
+			modify:     func(c *config.Config) { c.ProviderRetryOn = []string{"timeout"} },
+			field:      "PROVIDER_RETRY",
+			wantErrors: []string{`PROVIDER_RETRY_ON must list network, rate_limit, server or crash, got "timeout"`},
+		},
+
+		{
+			name:         "no share link secret",
+			modify:       func(c *config.Config) { c.ShareLinkSecret = "" },
+			field:        "SHARE_LINK_SECRET",
+			wantWarnings: []string{"SHARE_LINK_SECRET is empty, shared chat links stop working when the server restarts"},
+		},
+		{name: "short share link secret", modify: func(c *config.Config) { c.ShareLinkSecret = "short" }, field: "SHARE_LINK_SECRET", wantErrors: []string{"SHARE_LINK_SECRET must be at least 32 characters"}},
+		{name: "share link secret", modify: func(c *config.Config) { c.ShareLinkSecret = strings.Repeat("s", 32) }, field: "SHARE_LINK_SECRET"},
+
+		{name: "provider stream chunking", env: map[string]string{"PROVIDER_STREAM_CHUNKING": "claude = markdown"}, field: "STREAM_CHUNKING"},
+		{
+			name: "unknown stream chunking",
+			modify: func(c *config.Config) {
+				c.StreamChunking = "word"
+				c.ProviderStreamChunking = []string{"claude", "gemini=html"}
+			},
+			field: "STREAM_CHUNKING",
+			wantErrors: []string{
+				`STREAM_CHUNKING must be rune or markdown, got "word"`,
+				`PROVIDER_STREAM_CHUNKING: invalid entry "claude", expected provider=mode`,
+				`PROVIDER_STREAM_CHUNKING: mode of "gemini=html" must be rune or markdown`,
+			},
+		},
+
+		{name: "no prompt timeout", modify: func(c *config.Config) { c.PromptTimeout = 0 }, field: "PROMPT_TIMEOUT", wantErrors: []string{"PROMPT_TIMEOUT must be positive"}},
+		{name: "negative prompt idle timeout", modify: func(c *config.Config) { c.PromptIdleTimeout = -time.Second }, field: "PROMPT_IDLE_TIMEOUT", wantErrors: []string{"PROMPT_IDLE_TIMEOUT must not be negative"}},
+		{
+			name:       "provider timeout without seconds",
+			modify:     func(c *config.Config) { c.ProviderPromptTimeouts = []string{"claude"} },
+			field:      "PROVIDER_PROMPT_TIMEOUTS",
+			wantErrors: []string{`PROVIDER_PROMPT_TIMEOUTS: invalid timeout "claude", expected provider=seconds`},
+		},
+		{
+			name:       "zero provider timeout",
+			modify:     func(c *config.Config) { c.ProviderPromptTimeouts = []string{"claude=0"} },
+			field:      "PROVIDER_PROMPT_TIMEOUTS",
+			wantErrors: []string{`PROVIDER_PROMPT_TIMEOUTS: timeout "claude=0" must be positive`},
+		},
+		{
+			name:       "provider idle timeout without a provider",
+			modify:     func(c *config.Config) { c.ProviderIdleTimeouts = []string{"=30"} },
+			field:      "PROVIDER_IDLE_TIMEOUTS",
+			wantErrors: []string{`PROVIDER_IDLE_TIMEOUTS: invalid timeout "=30", expected provider=seconds`},
+		},
+		{
+			name: "provider timeouts",
+			modify: func(c *config.Config) {
+				c.ProviderPromptTimeouts = []string{"claude=600"}
+				c.ProviderIdleTimeouts = []string{"claude=0"}
+			},
+			field: "TIMEOUT",
+		},
+
+		{name: "tools", field: "TOOLS_"},
+		{
+			name:   "shell tool without commands and an unknown tool",
+			modify: func(c *config.Config) { c.ToolsEnabled = []string{"calculator", "http_fetch", "shell", "python"} },
+			field:  "TOOLS_",
+			wantErrors: []string{
+				"TOOLS_SHELL_COMMANDS is required when the shell tool is enabled",
+				`TOOLS_ENABLED has unknown tool "python", expected calculator, http_fetch or shell`,
+			},
+		},
+		{
+			name: "shell command with a path",
+			modify: func(c *config.Config) {
+				c.ToolsEnabled = []string{"shell"}
+				c.ToolsShellCommands = []string{"date", "/bin/sh"}
+			},
+			field:      "TOOLS_",
+			wantErrors: []string{`TOOLS_SHELL_COMMANDS must name commands on the PATH, got "/bin/sh"`},
+		},
+		{
+			name:       "no tool steps or timeout",
+			modify:     func(c *config.Config) { c.ToolsMaxSteps = 0; c.ToolsTimeout = 0 },
+			field:      "TOOLS_",
+			wantErrors: []string{"TOOLS_MAX_STEPS must be between 1 and 20", "TOOLS_TIMEOUT must be positive"},
+		},
+
+		{name: "WebSocket ticket TTL", field: "WS_TICKET_TTL"},
+		{name: "no WebSocket ticket TTL", modify: func(c *config.Config) { c.WSTicketTTL = 0 }, field: "WS_TICKET_TTL", wantErrors: []string{"WS_TICKET_TTL must be positive"}},
+		{
+			name:         "long WebSocket ticket TTL",
+			modify:       func(c *config.Config) { c.WSTicketTTL = 10 * time.Minute },
+			field:        "WS_TICKET_TTL",
+			wantWarnings: []string{"WS_TICKET_TTL is over 5 minutes, leaked tickets stay usable for a long time"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg := config.Load()
+			if tt.modify != nil {
+				tt.modify(cfg)
+			}
+
+			result := cfg.Validate()
+			assert.Equal(t, tt.wantErrors, validationMessages(result.Errors, tt.field))
+			assert.Equal(t, tt.wantWarnings, validationMessages(result.Warnings, tt.field))
+		})
+	}
+}
+
+func TestConfig_OriginAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{name: "default allows localhost", origin: "http://localhost:3000", want: true},
+		{name: "default allows IPv6 loopback", origin: "http://[::1]:8080", want: true},
+		{name: "default rejects lookalike host", origin: "http://localhost.evil.example", want: false},
+		{name: "default rejects remote origin", origin: "https://evil.example", want: false},
+		{name: "exact match", allowed: []string{"https://hub.example.com"}, origin: "https://hub.example.com", want: true},
+		{name: "match ignores case", allowed: []string{"https://hub.example.com"}, origin: "https://Hub.Example.com", want: true},
+		{name: "configured list replaces localhost default", allowed: []string{"https://hub.example.com"}, origin: "http://localhost:3000", want: false},
+		{name: "subdomain wildcard", allowed: []string{"https://*.example.com"}, origin: "https://team.example.com", want: true},
+		{name: "subdomain wildcard needs a subdomain", allowed: []string{"https://*.example.com"}, origin: "https://example.com", want: false},
+		{name: "subdomain wildcard checks the suffix", allowed: []string{"https://*.example.com"}, origin: "https://example.com.evil.example", want: false},
+		{name: "wildcard checks the scheme", allowed: []string{"https://*.example.com"}, origin: "http://team.example.com", want: false},
+		{name: "star allows any origin", allowed: []string{"*"}, origin: "https://anything.example", want: true},
+		{name: "empty origin", allowed: []string{"*"}, origin: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{AllowedOrigins: tt.allowed}
+			assert.Equal(t, tt.want, cfg.OriginAllowed(tt.origin))
+		})
+	}
+}
+
+func TestConfig_ApplyReloadable(t *testing.T) {
+	cfg := &config.Config{Port: "8080", LogLevel: "info", DailyPromptQuota: 10, AllowedOrigins: []string{"https://hub.example.com"}}
+	next := &config.Config{Port: "9090", LogLevel: "debug", DailyPromptQuota: 10, ClaudeExtraArgs: "--verbose",
+		AllowedOrigins: []string{"https://hub.example.com", "https://*.example.org"}}
+
+	changes := cfg.ApplyReloadable(next)
+	assert.Equal(t, []config.Change{
+		{Key: "LOG_LEVEL", Old: "info", New: "debug"},
+		{Key: "CLAUDE_EXTRA_ARGS", Old: "", New: "--verbose"},
+		{Key: "ALLOWED_ORIGINS", Old: "https://hub.example.com", New: "https://hub.example.com,https://*.example.org"},
+	}, changes)
+	assert.True(t, cfg.OriginAllowed("https://team.example.org"))
+	assert.Equal(t, "8080", cfg.Port, "settings that need a restart are left alone")
+
+	assert.Empty(t, cfg.ApplyReloadable(next))
+}
+
+func TestEnvFile_Refresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte("RELOAD_TEST_FILE=one\nRELOAD_TEST_REMOVED=x\nRELOAD_TEST_PRESET=file\n"), 0644))
+	t.Setenv("RELOAD_TEST_PRESET", "environment")
+	for _, key := range []string{"RELOAD_TEST_FILE", "RELOAD_TEST_REMOVED", "RELOAD_TEST_ADDED"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	envFile, err := config.LoadEnvFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "one", os.Getenv("RELOAD_TEST_FILE"))
+	assert.Equal(t, "environment", os.Getenv("RELOAD_TEST_PRESET"), "the environment wins over the file")
+
+	require.NoError(t, os.WriteFile(path, []byte("RELOAD_TEST_FILE=two\nRELOAD_TEST_ADDED=new\nRELOAD_TEST_PRESET=changed\n"), 0644))
+	require.NoError(t, envFile.Refresh())
+	assert.Equal(t, "two", os.Getenv("RELOAD_TEST_FILE"))
+	assert.Equal(t, "new", os.Getenv("RELOAD_TEST_ADDED"))
+	assert.Equal(t, "environment", os.Getenv("RELOAD_TEST_PRESET"))
+	_, set := os.LookupEnv("RELOAD_TEST_REMOVED")
+	assert.False(t, set, "variables removed from the file are unset")
+}
+
+func TestProviderRegistry_FakeProvider(t *testing.T) {
+	cfg := config.Load()
+	registry := services.NewProviderRegistry(nil)
+	require.NoError(t, registry.RegisterDefaultProviders(cfg))
+	_, err := registry.Get("fake")
+	assert.Error(t, err, "the fake provider is only registered when enabled")
+
+	cfg.EnableFakeProvider = true
+	registry = services.NewProviderRegistry(nil)
+	require.NoError(t, registry.RegisterDefaultProviders(cfg))
+	_, err = registry.Get("fake")
+	assert.NoError(t, err)
+}
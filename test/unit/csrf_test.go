@@ -0,0 +1,119 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCSRFRouter(cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.CSRFMiddleware(cfg))
+	router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, c.GetString(middleware.CSRFContextKey)) })
+	router.POST("/api/settings", func(c *gin.Context) { c.String(http.StatusOK, "saved") })
+	return router
+}
+
+func TestCSRFMiddleware_IssuesToken(t *testing.T) {
+	router := newCSRFRouter(&config.Config{EnableCSRF: true})
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	cookies := resp.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, middleware.CSRFCookieName, cookies[0].Name)
+	assert.False(t, cookies[0].HttpOnly, "scripts must be able to read the token")
+	assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	assert.Equal(t, cookies[0].Value, resp.Body.String(), "templates get the cookie's token")
+
+	// An existing token is kept
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: "existing-token"})
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Empty(t, resp.Result().Cookies())
+	assert.Equal(t, "existing-token", resp.Body.String())
+}
+
+func TestCSRFMiddleware_MutatingRequests(t *testing.T) {
+	const token = "csrf-token-value"
+	const adminToken = "0123456789abcdef"
+
+	tests := []struct {
+		name       string
+		enabled    bool
+		header     string
+		form       string
+		auth       string
+		wantStatus int
+	}{
+		{name: "missing token", enabled: true, wantStatus: http.StatusForbidden},
+		{name: "wrong header token", enabled: true, header: "other-token", wantStatus: http.StatusForbidden},
+		{name: "valid header token", enabled: true, header: token, wantStatus: http.StatusOK},
+		{name: "valid form token", enabled: true, form: token, wantStatus: http.StatusOK},
+		{name: "admin bearer token is exempt", enabled: true, auth: "Bearer " + adminToken, wantStatus: http.StatusOK},
+		{name: "other bearer token is not exempt", enabled: true, auth: "Bearer guess", wantStatus: http.StatusForbidden},
+		{name: "disabled", enabled: false, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newCSRFRouter(&config.Config{EnableCSRF: tt.enabled, AdminToken: adminToken})
+
+			var req *http.Request
+			if tt.form != "" {
+				form := url.Values{middleware.CSRFFormField: {tt.form}}
+				req = httptest.NewRequest(http.MethodPost, "/api/settings", strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = httptest.NewRequest(http.MethodPost, "/api/settings", strings.NewReader(`{}`))
+				req.Header.Set("Content-Type", "application/json")
+			}
+			req.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: token})
+			if tt.header != "" {
+				req.Header.Set(middleware.CSRFHeaderName, tt.header)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			if tt.wantStatus == http.StatusForbidden {
+				assert.Contains(t, resp.Body.String(), "CSRF_INVALID")
+			}
+		})
+	}
+}
+
+func TestCSRFMiddleware_OpenAIAPI(t *testing.T) {
+	router := newCSRFRouter(&config.Config{EnableCSRF: true})
+	router.POST("/v1/chat/completions", func(c *gin.Context) { c.String(http.StatusOK, "completed") })
+
+	// OpenAI clients send no cookies and no CSRF token
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// Requests with a browser session still need the token
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`))
+	req.AddCookie(&http.Cookie{Name: middleware.SessionCookieName, Value: "session"})
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
@@ -0,0 +1,64 @@
+package unit
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSQLite_Pragmas(t *testing.T) {
+	utils.InitPathManager()
+
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "tuned.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	pragmas, err := database.SQLitePragmas(db)
+	require.NoError(t, err)
+	assert.Equal(t, "wal", pragmas["journal_mode"])
+	assert.Equal(t, "5000", pragmas["busy_timeout"])
+	assert.Equal(t, "NORMAL", pragmas["synchronous"])
+	assert.Equal(t, "1", pragmas["foreign_keys"])
+	assert.Equal(t, 16, db.Stats().MaxOpenConnections)
+
+	// Parameters in the path win over the options
+	opts := database.SQLiteOptions{JournalMode: "WAL", BusyTimeout: time.Second, MaxOpenConns: 1}
+	db2, err := database.OpenSQLiteWithOptions(filepath.Join(t.TempDir(), "explicit.db")+"?_journal_mode=DELETE", opts)
+	require.NoError(t, err)
+	defer db2.Close()
+
+	pragmas, err = database.SQLitePragmas(db2)
+	require.NoError(t, err)
+	assert.Equal(t, "delete", pragmas["journal_mode"])
+	assert.Equal(t, "1000", pragmas["busy_timeout"])
+	assert.Equal(t, "0", pragmas["foreign_keys"])
+	assert.Equal(t, 1, db2.Stats().MaxOpenConnections)
+}
+
+func TestConfig_ValidateSQLiteTuning(t *testing.T) {
+	cfg := config.Load()
+	assert.Equal(t, "WAL", cfg.SQLiteJournalMode)
+	assert.Equal(t, 5*time.Second, cfg.SQLiteBusyTimeout)
+	assert.True(t, cfg.SQLiteForeignKeys)
+	assert.NotContains(t, strings.Join(cfg.Validate().Errors, "\n"), "SQLITE_")
+
+	cfg.SQLiteJournalMode = "fast"
+	cfg.SQLiteSynchronous = "sometimes"
+	cfg.SQLiteMaxOpenConns = -1
+	errors := strings.Join(cfg.Validate().Errors, "\n")
+	assert.Contains(t, errors, "SQLITE_JOURNAL_MODE must be")
+	assert.Contains(t, errors, "SQLITE_SYNCHRONOUS must be")
+	assert.Contains(t, errors, "SQLITE_MAX_OPEN_CONNS and SQLITE_MAX_IDLE_CONNS must not be negative")
+
+	cfg = config.Load()
+	cfg.SQLiteBusyTimeout = 0
+	assert.Contains(t, strings.Join(cfg.Validate().Warnings, "\n"), "SQLITE_BUSY_TIMEOUT is 0")
+}
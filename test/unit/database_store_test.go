@@ -0,0 +1,44 @@
+package unit
+
+import (
+	"testing"
+
+	"ai-gateway-hub/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDialect(t *testing.T) {
+	for driver, want := range map[string]database.Dialect{
+		"":           database.SQLite,
+		"sqlite":     database.SQLite,
+		"sqlite3":    database.SQLite,
+		"postgres":   database.Postgres,
+		"PostgreSQL": database.Postgres,
+	} {
+		got, err := database.ParseDialect(driver)
+		require.NoError(t, err, driver)
+		assert.Equal(t, want, got, driver)
+	}
+
+	_, err := database.ParseDialect("mysql")
+	assert.Error(t, err)
+}
+
+func TestDialectRebind(t *testing.T) {
+	query := `SELECT * FROM chats WHERE id = ? AND title != '?' AND provider = ?`
+
+	assert.Equal(t, query, database.SQLite.Rebind(query))
+	assert.Equal(t, `SELECT * FROM chats WHERE id = $1 AND title != '?' AND provider = $2`, database.Postgres.Rebind(query))
+}
+
+func TestDialectUnixSeconds(t *testing.T) {
+	assert.Equal(t, "CAST(strftime('%s', created_at) AS INTEGER)", database.SQLite.UnixSeconds("created_at"))
+	assert.Equal(t, "CAST(EXTRACT(EPOCH FROM created_at) AS BIGINT)", database.Postgres.UnixSeconds("created_at"))
+}
+
+func TestOpenPostgresRequiresDSN(t *testing.T) {
+	_, err := database.Open(database.Postgres, "", database.DefaultSQLiteOptions())
+	assert.Error(t, err)
+}
@@ -162,18 +162,21 @@ func TestSQLiteDatabase(t *testing.T) {
 func TestRedisConnection(t *testing.T) {
 	t.Run("InitRedis", func(t *testing.T) {
 		// Test with a non-existent Redis server
-		client := database.InitRedis("localhost:9999")
+		client, err := database.InitRedis("localhost:9999")
 		if client == nil {
 			t.Fatal("InitRedis returned nil client")
 		}
 		
-		// The client should be created even if connection fails
-		// Actual connection errors will occur when operations are performed
+		// The client should be created even if connection fails, with the error
+		// reported so callers can fall back to in-memory stores
 		defer client.Close()
+		if err == nil {
+			t.Error("Expected an error connecting to a non-existent Redis server")
+		}
 	})
 
 	t.Run("InitRedis_DefaultAddress", func(t *testing.T) {
-		client := database.InitRedis("localhost:6379")
+		client, _ := database.InitRedis("localhost:6379")
 		if client == nil {
 			t.Fatal("InitRedis returned nil client")
 		}
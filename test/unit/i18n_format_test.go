@@ -0,0 +1,92 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/i18n"
+)
+
+func TestI18nFormatRelativeTime(t *testing.T) {
+	if err := i18n.Init("../../locales", "en"); err != nil {
+		t.Fatalf("Failed to initialize i18n: %v", err)
+	}
+
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		lang     string
+		ago      time.Duration
+		expected string
+	}{
+		{"just now", "en", 20 * time.Second, "Just now"},
+		{"one minute", "en", 90 * time.Second, "1 minute ago"},
+		{"minutes", "en", 3 * time.Minute, "3 minutes ago"},
+		{"minutes ja", "ja", 3 * time.Minute, "3分前"},
+		{"one hour", "en", 61 * time.Minute, "1 hour ago"},
+		{"hours ja", "ja", 5 * time.Hour, "5時間前"},
+		{"yesterday", "en", 30 * time.Hour, "Yesterday"},
+		{"days", "en", 4 * 24 * time.Hour, "4 days ago"},
+		{"older falls back to date", "en", 30 * 24 * time.Hour, "Feb 14, 2024"},
+		{"older falls back to date ja", "ja", 30 * 24 * time.Hour, "2024年2月14日"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := i18n.FormatRelativeTime(tt.lang, now.Add(-tt.ago), now)
+			if got != tt.expected {
+				t.Errorf("FormatRelativeTime(%s, -%v) = %q, want %q", tt.lang, tt.ago, got, tt.expected)
+			}
+		})
+	}
+
+	if got := i18n.FormatRelativeTime("en", time.Time{}, now); got != "" {
+		t.Errorf("FormatRelativeTime(zero) = %q, want empty", got)
+	}
+}
+
+func TestI18nFormatDateTime(t *testing.T) {
+	if err := i18n.Init("../../locales", "en"); err != nil {
+		t.Fatalf("Failed to initialize i18n: %v", err)
+	}
+
+	ts := time.Date(2024, 1, 5, 14, 7, 0, 0, time.Local)
+	if got := i18n.FormatDate("en", ts); got != "Jan 5, 2024" {
+		t.Errorf("FormatDate(en) = %q", got)
+	}
+	if got := i18n.FormatDateTime("en", ts); got != "Jan 5, 2024 2:07 PM" {
+		t.Errorf("FormatDateTime(en) = %q", got)
+	}
+	if got := i18n.FormatDateTime("ja", ts); got != "2024年1月5日 14:07" {
+		t.Errorf("FormatDateTime(ja) = %q", got)
+	}
+	if got := i18n.FormatTime("ja", ts); got != "14:07" {
+		t.Errorf("FormatTime(ja) = %q", got)
+	}
+}
+
+func TestI18nFormatNumber(t *testing.T) {
+	if err := i18n.Init("../../locales", "en"); err != nil {
+		t.Fatalf("Failed to initialize i18n: %v", err)
+	}
+
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1,000"},
+		{int64(1234567), "1,234,567"},
+		{-9876543, "-9,876,543"},
+		{1234.5, "1,234.5"},
+		{3.14159, "3.14"},
+		{"not a number", ""},
+	}
+
+	for _, tt := range tests {
+		if got := i18n.FormatNumber("en", tt.value); got != tt.expected {
+			t.Errorf("FormatNumber(%v) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/i18n"
+)
+
+func writeLocale(t *testing.T, dir, lang, content string) {
+	t.Helper()
+	path := filepath.Join(dir, lang, "messages.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestI18nReload(t *testing.T) {
+	dir := t.TempDir()
+	writeLocale(t, dir, "en", `{"app": {"title": "Hub"}, "chat": {"count": "%d chats"}}`)
+	writeLocale(t, dir, "ja", `{"app": {"title": "ハブ"}, "chat": {"count": "%dチャット"}}`)
+
+	localizer, err := i18n.NewLocalizer(dir, "en")
+	if err != nil {
+		t.Fatalf("NewLocalizer failed: %v", err)
+	}
+
+	// Edited translations are used after a reload
+	writeLocale(t, dir, "en", `{"app": {"title": "Gateway"}, "chat": {"count": "%d chats"}, "nav": {"home": "Home"}}`)
+	// A broken file keeps the previous translations of its language only
+	writeLocale(t, dir, "ja", `{"app": {"title": "ゲートウェイ"`)
+
+	reports, err := localizer.Reload()
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(reports) != 2 || !reports[0].Loaded || reports[1].Loaded {
+		t.Fatalf("Expected en loaded and ja rejected, got %+v", reports)
+	}
+	if reports[1].Error == "" {
+		t.Error("Expected the ja parse error in the report")
+	}
+	if got := localizer.Translate("en", "app.title"); got != "Gateway" {
+		t.Errorf("en app.title = %q, want Gateway", got)
+	}
+	if got := localizer.Translate("ja", "app.title"); got != "ハブ" {
+		t.Errorf("ja app.title = %q, want the previous translation", got)
+	}
+
+	// Missing keys and mismatched placeholders are warnings
+	writeLocale(t, dir, "ja", `{"app": {"title": "ゲートウェイ"}, "chat": {"count": "%sチャット"}}`)
+	reports, err = localizer.Reload()
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if !reports[1].Loaded || len(reports[1].Warnings) != 2 {
+		t.Fatalf("Expected ja loaded with 2 warnings, got %+v", reports[1])
+	}
+	if !strings.Contains(reports[1].Warnings[0], "nav.home") || !strings.HasPrefix(reports[1].Warnings[1], "chat.count") {
+		t.Errorf("Unexpected warnings: %v", reports[1].Warnings)
+	}
+	if got := localizer.Translate("ja", "app.title"); got != "ゲートウェイ" {
+		t.Errorf("ja app.title = %q, want ゲートウェイ", got)
+	}
+}
+
+func TestI18nReloadEmbedded(t *testing.T) {
+	var localizer i18n.Localizer
+	if _, err := localizer.Reload(); !errors.Is(err, i18n.ErrEmbedded) {
+		t.Errorf("Reload of embedded translations = %v, want ErrEmbedded", err)
+	}
+}
@@ -0,0 +1,51 @@
+package unit
+
+import (
+	"reflect"
+	"testing"
+
+	"ai-gateway-hub/internal/i18n"
+)
+
+func TestI18nValidate(t *testing.T) {
+	dir := t.TempDir()
+	writeLocale(t, dir, "en", `{"app": {"title": "Hub"}, "chat": {"count": "%d chats in %s", "rate": "100%% done"}, "nav": {"home": "Home"}}`)
+	writeLocale(t, dir, "ja", `{"app": {"title": "ハブ"}, "chat": {"count": "%sの%dチャット", "rate": "%d%%完了"}, "nav": {"homepage": "ホーム"}}`)
+
+	localizer, err := i18n.NewLocalizer(dir, "en")
+	if err != nil {
+		t.Fatalf("NewLocalizer failed: %v", err)
+	}
+
+	issues := localizer.Validate()
+	if len(issues) != 1 || issues[0].Language != "ja" || issues[0].OK() {
+		t.Fatalf("Expected issues for ja only, got %+v", issues)
+	}
+	ja := issues[0]
+	if !reflect.DeepEqual(ja.Missing, []string{"nav.home"}) {
+		t.Errorf("Missing = %v, want [nav.home]", ja.Missing)
+	}
+	if !reflect.DeepEqual(ja.Extra, []string{"nav.homepage"}) {
+		t.Errorf("Extra = %v, want [nav.homepage]", ja.Extra)
+	}
+	// Reordered verbs are fine, an added one is not
+	if len(ja.Mismatched) != 1 || ja.Mismatched[0].Key != "chat.rate" || !reflect.DeepEqual(ja.Mismatched[0].Actual, []string{"%d"}) {
+		t.Errorf("Mismatched = %+v, want chat.rate with [%%d]", ja.Mismatched)
+	}
+	if warnings := ja.Warnings("en"); len(warnings) != 3 {
+		t.Errorf("Expected 3 warnings, got %v", warnings)
+	}
+}
+
+// The shipped translations must stay complete
+func TestI18nShippedLocalesValid(t *testing.T) {
+	localizer, err := i18n.NewLocalizer("../../locales", "en")
+	if err != nil {
+		t.Fatalf("NewLocalizer failed: %v", err)
+	}
+	for _, issues := range localizer.Validate() {
+		if !issues.OK() {
+			t.Errorf("Translations %s: %v", issues.Language, issues.Warnings("en"))
+		}
+	}
+}
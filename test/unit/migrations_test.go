@@ -0,0 +1,120 @@
+package unit
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"ai-gateway-hub/internal/database"
+	"ai-gateway-hub/internal/utils"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func tableExists(t *testing.T, db *database.DB, table string) bool {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?", table).Scan(&count); err != nil {
+		t.Fatalf("Failed to inspect schema: %v", err)
+	}
+	return count > 0
+}
+
+func TestMigrations(t *testing.T) {
+	utils.InitPathManager()
+
+	t.Run("MigrateAndRollback", func(t *testing.T) {
+		db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "migrate.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite failed: %v", err)
+		}
+		defer db.Close()
+
+		pending, err := database.PendingMigrations(db)
+		if err != nil || len(pending) == 0 {
+			t.Fatalf("Expected pending migrations on an empty database, got %d (%v)", len(pending), err)
+		}
+
+		applied, err := database.Migrate(db)
+		if err != nil {
+			t.Fatalf("Migrate failed: %v", err)
+		}
+		if applied != len(pending) {
+			t.Errorf("Expected %d migrations applied, got %d", len(pending), applied)
+		}
+		if !tableExists(t, db, "chats") {
+			t.Error("chats table was not created")
+		}
+
+		// Running again is a no-op
+		if applied, err := database.Migrate(db); err != nil || applied != 0 {
+			t.Errorf("Second Migrate applied %d migrations (%v), want 0", applied, err)
+		}
+
+		version, err := database.SchemaVersion(db)
+		if err != nil || version != pending[len(pending)-1].Version {
+			t.Errorf("SchemaVersion = %d (%v), want %d", version, err, pending[len(pending)-1].Version)
+		}
+
+		reverted, err := database.Rollback(db, len(pending))
+		if err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+		if reverted != len(pending) {
+			t.Errorf("Expected %d migrations reverted, got %d", len(pending), reverted)
+		}
+		if tableExists(t, db, "chats") {
+			t.Error("chats table still exists after rolling back every migration")
+		}
+		if version, _ := database.SchemaVersion(db); version != 0 {
+			t.Errorf("SchemaVersion after rollback = %d, want 0", version)
+		}
+	})
+
+	t.Run("UpgradesLegacyDatabase", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "legacy.db")
+		legacy, err := sql.Open("sqlite3", dbPath)
+		if err != nil {
+			t.Fatalf("Failed to open legacy database: %v", err)
+		}
+		_, err = legacy.Exec(`
+			CREATE TABLE chats (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				title TEXT NOT NULL,
+				provider TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE messages (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				chat_id INTEGER NOT NULL,
+				role TEXT NOT NULL,
+				content TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			INSERT INTO chats (title, provider) VALUES ('old chat', 'claude');
+		`)
+		legacy.Close()
+		if err != nil {
+			t.Fatalf("Failed to create legacy schema: %v", err)
+		}
+
+		db, err := database.InitSQLite(dbPath)
+		if err != nil {
+			t.Fatalf("InitSQLite failed on legacy database: %v", err)
+		}
+		defer db.Close()
+
+		var title string
+		var deletedAt sql.NullTime
+		if err := db.QueryRow("SELECT title, deleted_at FROM chats").Scan(&title, &deletedAt); err != nil {
+			t.Fatalf("Legacy chat is not readable with the new schema: %v", err)
+		}
+		if title != "old chat" || deletedAt.Valid {
+			t.Errorf("Unexpected legacy chat after upgrade: %q deleted=%v", title, deletedAt.Valid)
+		}
+		if _, err := db.Exec("INSERT INTO messages (chat_id, role, content, provider) VALUES (1, 'user', 'hi', 'claude')"); err != nil {
+			t.Errorf("messages.provider missing after upgrade: %v", err)
+		}
+	})
+}
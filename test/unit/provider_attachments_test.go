@@ -0,0 +1,94 @@
+package unit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ai-gateway-hub/internal/providers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendAttachmentReferences(t *testing.T) {
+	attachments := []providers.Attachment{
+		{Path: "/data/attachments/1/abc.md", Filename: "notes.md", ContentType: "text/markdown", Size: 8},
+	}
+
+	assert.Equal(t, "hello", providers.AppendAttachmentReferences("hello", nil, "@"))
+	assert.Equal(t,
+		"hello\n\nAttached files:\n- @/data/attachments/1/abc.md (notes.md, text/markdown, 8 bytes)\n",
+		providers.AppendAttachmentReferences("hello", attachments, "@"))
+}
+
+func TestAttachmentsContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, providers.AttachmentsFromContext(ctx))
+	assert.Equal(t, ctx, providers.WithAttachments(ctx, nil), "no attachments keep the context")
+
+	attachments := []providers.Attachment{{Path: "/tmp/a.txt", Filename: "a.txt"}}
+	assert.Equal(t, attachments, providers.AttachmentsFromContext(providers.WithAttachments(ctx, attachments)))
+}
+
+func TestProviderCapabilities(t *testing.T) {
+	pc := providers.ProviderConfig{ID: "gemini", Type: providers.ProviderTypeCLI, Command: "gemini", Capabilities: []string{"telepathy"}}
+	assert.ErrorContains(t, pc.Validate(), "unknown capability")
+
+	pc.Capabilities = []string{providers.CapabilityVision}
+	require.NoError(t, pc.Validate())
+	vision, err := providers.NewProviderFromConfig(pc, t.TempDir(), providers.DefaultEnvPolicy)
+	require.NoError(t, err)
+	assert.True(t, providers.HasCapability(vision, providers.CapabilityVision))
+	assert.Equal(t, []string{providers.CapabilityVision}, vision.GetStatus().Capabilities)
+
+	pc.Capabilities = nil
+	plain, err := providers.NewProviderFromConfig(pc, t.TempDir(), providers.DefaultEnvPolicy)
+	require.NoError(t, err)
+	assert.False(t, providers.HasCapability(plain, providers.CapabilityVision))
+
+	claude := providers.NewClaudeProvider("claude", t.TempDir(), false, "")
+	assert.True(t, providers.HasCapability(claude, providers.CapabilityVision))
+}
+
+func TestHTTPProviderSendsImages(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "pixel.png")
+	require.NoError(t, os.WriteFile(imagePath, []byte("\x89PNG\r\n\x1a\n"), 0600))
+	notesPath := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(notesPath, []byte("notes"), 0600))
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	provider := providers.NewHTTPProvider(providers.ProviderConfig{
+		ID:           "vision-relay",
+		Type:         providers.ProviderTypeHTTP,
+		BaseURL:      server.URL,
+		Capabilities: []string{providers.CapabilityVision},
+	}, t.TempDir())
+
+	ctx := providers.WithAttachments(context.Background(), []providers.Attachment{
+		{Path: imagePath, Filename: "pixel.png", ContentType: "image/png"},
+		{Path: notesPath, Filename: "notes.txt", ContentType: "text/plain"},
+	})
+	resp, err := provider.SendPrompt(ctx, "describe", 1)
+	require.NoError(t, err)
+	resp.Close()
+
+	images, ok := body["images"].([]any)
+	require.True(t, ok, "images are embedded for vision providers")
+	require.Len(t, images, 1, "only image attachments are embedded")
+	image := images[0].(map[string]any)
+	assert.Equal(t, "image/png", image["media_type"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n")), image["data"])
+}
@@ -0,0 +1,133 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usageBuffer records output and reported token usage like the WebSocket writer does
+type usageBuffer struct {
+	bytes.Buffer
+	input, output int64
+}
+
+func (b *usageBuffer) ReportUsage(inputTokens, outputTokens int64) {
+	b.input, b.output = inputTokens, outputTokens
+}
+
+// newRelayGateway serves an OpenAI-style streaming chat completions API and records the last request
+func newRelayGateway(t *testing.T, body *map[string]any, header *http.Header) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data": []}`))
+	})
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		*header = r.Header.Clone()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(body))
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, content := range []string{"Hel", "lo"} {
+			fmt.Fprintf(w, "data: {\"choices\": [{\"delta\": {\"content\": %q}}]}\n\n", content)
+		}
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "data: {\"choices\": [], \"usage\": {\"prompt_tokens\": 7, \"completion_tokens\": 2}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestRelayProvider(t *testing.T) {
+	var body map[string]any
+	var header http.Header
+	require.NoError(t, utils.InitPathManager())
+	server := newRelayGateway(t, &body, &header)
+	defer server.Close()
+
+	t.Setenv("RELAY_TOKEN", "secret")
+	pc := providers.ProviderConfig{
+		ID:       "openrouter",
+		Type:     providers.ProviderTypeRelay,
+		BaseURL:  server.URL + "/v1/",
+		Headers:  map[string]string{"Authorization": "Bearer ${RELAY_TOKEN}", "X-Title": "AI Gateway Hub"},
+		Models:   []string{"fast", "openai/gpt-4o"},
+		ModelMap: map[string]string{"fast": "openai/gpt-4o-mini"},
+	}
+	require.NoError(t, pc.Validate())
+	provider, err := providers.NewProviderFromConfig(pc, t.TempDir(), providers.EnvPolicy{})
+	require.NoError(t, err)
+
+	status := provider.GetStatus()
+	assert.True(t, status.Available)
+	assert.Equal(t, "ready", status.Status)
+
+	t.Run("StreamsMappedModel", func(t *testing.T) {
+		var out usageBuffer
+		require.NoError(t, provider.StreamResponse(context.Background(), "hi", 1, &out))
+
+		assert.Equal(t, "Hello", out.String())
+		assert.Equal(t, int64(7), out.input)
+		assert.Equal(t, int64(2), out.output)
+		assert.Equal(t, "openai/gpt-4o-mini", body["model"], "the default model is mapped")
+		assert.Equal(t, true, body["stream"])
+		assert.Equal(t, []any{map[string]any{"role": "user", "content": "hi"}}, body["messages"])
+		assert.Equal(t, "Bearer secret", header.Get("Authorization"))
+		assert.Equal(t, "AI Gateway Hub", header.Get("X-Title"))
+	})
+
+	t.Run("UnmappedModelPassesThrough", func(t *testing.T) {
+		ctx := providers.WithModel(context.Background(), "openai/gpt-4o")
+		resp, err := provider.SendPrompt(ctx, "hi", 1)
+		require.NoError(t, err)
+		defer resp.Close()
+
+		out, err := io.ReadAll(resp)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello", string(out))
+		assert.Equal(t, "openai/gpt-4o", body["model"])
+	})
+}
+
+func TestRelayProviderErrors(t *testing.T) {
+	require.NoError(t, utils.InitPathManager())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {\"content\": \"partial\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"error\": {\"message\": \"upstream overloaded\"}}\n\n")
+	}))
+	defer server.Close()
+
+	provider := providers.NewRelayProvider(providers.ProviderConfig{
+		ID:      "litellm",
+		Type:    providers.ProviderTypeRelay,
+		BaseURL: server.URL,
+		Models:  []string{"gpt-4o"},
+	}, t.TempDir())
+
+	status := provider.GetStatus()
+	assert.False(t, status.Available)
+	assert.Equal(t, "not_configured", status.Status)
+
+	var out bytes.Buffer
+	err := provider.StreamResponse(context.Background(), "hi", 1, &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upstream overloaded")
+	assert.Equal(t, "partial", out.String())
+}
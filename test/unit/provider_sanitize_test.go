@@ -0,0 +1,103 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizer(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{name: "plain text", output: "Hello,\n\tworld — ✓", want: "Hello,\n\tworld — ✓"},
+		{name: "colors", output: "\x1b[1;32mgreen\x1b[0m text", want: "green text"},
+		{name: "cursor movement", output: "⠋ Thinking\r\x1b[2K\x1b[1Aanswer", want: "answer"},
+		{name: "spinner redrawn with carriage returns", output: "⠋ Thinking\r⠙ Thinking\rDone\nnext\r", want: "Done\nnext"},
+		{name: "CRLF line endings", output: "one\r\ntwo\r\n", want: "one\ntwo\n"},
+		{name: "window title", output: "\x1b]0;claude\x07done", want: "done"},
+		{name: "hyperlink", output: "\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", want: "link"},
+		{name: "charset and keypad", output: "\x1b(Ba\x1b=b", want: "ab"},
+		{name: "control characters", output: "a\x00b\x08c\x7fd\r\n", want: "abcd\n"},
+		{name: "C1 controls", output: "a\u0085b\u009b31mc\u009d0;title\u009cd\u0090q\u009c", want: "abcd"},
+		{name: "Latin-1 supplement", output: "£5 © ÿ", want: "£5 © ÿ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			sanitizer := providers.NewSanitizer(&out)
+			n, err := sanitizer.Write([]byte(tt.output))
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.output), n)
+			require.NoError(t, sanitizer.Flush())
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+
+	// Sequences split between writes are dropped whole
+	var out bytes.Buffer
+	sanitizer := providers.NewSanitizer(&out)
+	for _, chunk := range []string{"red: \x1b", "[3", "1mred\x1b]0;ti", "tle\x1b", "\\!\xc2", "\x9b0m £", "\r", "\n"} {
+		_, err := sanitizer.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "red: red! £\n", out.String())
+
+	// Spinner frames in separate writes replace each other, and a line is passed on once it ends
+	out.Reset()
+	sanitizer = providers.NewSanitizer(&out)
+	for _, chunk := range []string{"⠋ Working", "\r⠙ Working", "\r", "Answer"} {
+		_, err := sanitizer.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	assert.Empty(t, out.String())
+	_, err := sanitizer.Write([]byte("\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "Answer\n", out.String())
+
+	// An unterminated sequence doesn't swallow the rest of the output
+	out.Reset()
+	sanitizer = providers.NewSanitizer(&out)
+	_, err = sanitizer.Write([]byte("\x1b]0;" + strings.Repeat("x", 5000)))
+	require.NoError(t, err)
+	require.NoError(t, sanitizer.Flush())
+	assert.NotEmpty(t, out.String())
+}
+
+func TestCLIProvider_SanitizesOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	require.NoError(t, utils.InitPathManager())
+	dir := t.TempDir()
+	script := filepath.Join(dir, "colorful-cli")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nprintf '\\033[1;34mHello\\033[0m world\\r\\n'\n"), 0755))
+
+	provider := providers.NewCLIProvider(providers.ProviderConfig{
+		ID:      "colorful",
+		Name:    "Colorful",
+		Type:    providers.ProviderTypeCLI,
+		Command: script,
+	}, dir, providers.DefaultEnvPolicy)
+
+	var out bytes.Buffer
+	require.NoError(t, provider.StreamResponse(context.Background(), "hi", 1, &out))
+	assert.Equal(t, "Hello world\n", out.String())
+
+	log, err := os.ReadFile(filepath.Join(dir, "colorful", "chat_1.log"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(log), "\x1b", "the chat log is sanitized too")
+}
@@ -0,0 +1,56 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ai-gateway-hub/internal/providers"
+	"ai-gateway-hub/internal/secrets"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderSecretReferences(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "relay_key")
+	require.NoError(t, os.WriteFile(keyPath, []byte("sk-from-file-1234\n"), 0600))
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	pc := providers.ProviderConfig{
+		ID:      "relay",
+		Type:    providers.ProviderTypeHTTP,
+		BaseURL: server.URL,
+		Headers: map[string]string{"Authorization": "Bearer ${file:" + keyPath + "}"},
+	}
+	require.NoError(t, pc.Validate())
+	require.NoError(t, pc.ResolveSecrets(context.Background(), secrets.NewManager(secrets.Options{})))
+
+	resp, err := providers.NewHTTPProvider(pc, t.TempDir()).SendPrompt(context.Background(), "hi", 1)
+	require.NoError(t, err)
+	resp.Close()
+
+	assert.Equal(t, "Bearer sk-from-file-1234", authorization)
+	assert.Equal(t, "key="+utils.RedactedPlaceholder, utils.Redact("key=sk-from-file-1234"))
+
+	t.Run("UnresolvableSecretFailsTheLoad", func(t *testing.T) {
+		broken := providers.ProviderConfig{
+			ID:      "broken",
+			Env:     map[string]string{"API_KEY": "${file:/nonexistent/secret}"},
+			Command: "cat",
+		}
+		err := broken.ResolveSecrets(context.Background(), secrets.NewManager(secrets.Options{}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "provider broken")
+	})
+}
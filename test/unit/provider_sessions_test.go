@@ -0,0 +1,47 @@
+package unit
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"ai-gateway-hub/internal/providers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSessionID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	first, err := providers.NewSessionID()
+	require.NoError(t, err)
+	second, err := providers.NewSessionID()
+	require.NoError(t, err)
+
+	assert.Regexp(t, uuid, first)
+	assert.Regexp(t, uuid, second)
+	assert.NotEqual(t, first, second)
+}
+
+func TestSessionContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, providers.SessionFromContext(ctx))
+	assert.Equal(t, ctx, providers.WithSession(ctx, nil), "no session keeps the context")
+
+	session := &providers.Session{ID: "abc"}
+	assert.Same(t, session, providers.SessionFromContext(providers.WithSession(ctx, session)))
+}
+
+func TestClaudeProviderSupportsSessions(t *testing.T) {
+	claude := providers.NewClaudeProvider("claude", t.TempDir(), false, "")
+	assert.True(t, providers.SupportsSessions(claude))
+
+	claude.SetSessions(false)
+	assert.False(t, providers.SupportsSessions(claude))
+
+	pc := providers.ProviderConfig{ID: "gemini", Type: providers.ProviderTypeCLI, Command: "gemini"}
+	cli, err := providers.NewProviderFromConfig(pc, t.TempDir(), providers.DefaultEnvPolicy)
+	require.NoError(t, err)
+	assert.False(t, providers.SupportsSessions(cli))
+}
@@ -43,4 +43,15 @@ func TestClaudeProviderGetStatus(t *testing.T) {
 			t.Logf("Provider status: %+v", status)
 		})
 	}
-}
\ No newline at end of file
+}
+func TestProviderBranding(t *testing.T) {
+	claude := providers.NewClaudeProvider("claude", "/tmp", false, "")
+	branding := providers.GetBranding(claude)
+
+	if branding.IconURL != providers.ProviderIconBasePath+"/claude.svg" {
+		t.Errorf("Expected Claude icon URL, got '%s'", branding.IconURL)
+	}
+	if branding.Color == "" {
+		t.Error("Expected Claude brand color to be set")
+	}
+}
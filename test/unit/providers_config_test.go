@@ -0,0 +1,176 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/providers"
+)
+
+func TestLoadProvidersFile(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	t.Run("ValidYAML", func(t *testing.T) {
+		path := writeFile("providers.yaml", `
+providers:
+  - id: gemini
+    command: gemini
+    timeout: 90s
+  - id: relay
+    name: Relay
+    type: http
+    base_url: http://localhost:9000/generate
+`)
+		configs, err := providers.LoadProvidersFile(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(configs) != 2 {
+			t.Fatalf("Expected 2 providers, got %d", len(configs))
+		}
+		if configs[0].Type != providers.ProviderTypeCLI {
+			t.Errorf("Expected default type 'cli', got '%s'", configs[0].Type)
+		}
+		if configs[0].Name != "gemini" {
+			t.Errorf("Expected name to default to id, got '%s'", configs[0].Name)
+		}
+		if d, _ := configs[0].TimeoutDuration(); d != 90*time.Second {
+			t.Errorf("Expected timeout 90s, got %v", d)
+		}
+	})
+
+	t.Run("ValidJSON", func(t *testing.T) {
+		path := writeFile("providers.json", `{"providers": [{"id": "echo", "command": "cat"}]}`)
+		configs, err := providers.LoadProvidersFile(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(configs) != 1 || configs[0].ID != "echo" {
+			t.Errorf("Unexpected providers: %+v", configs)
+		}
+	})
+
+	invalid := map[string]string{
+		"DuplicateID":    "providers:\n  - {id: a, command: x}\n  - {id: a, command: y}\n",
+		"MissingCommand": "providers:\n  - {id: a, type: cli}\n",
+		"BadBaseURL":     "providers:\n  - {id: a, type: http, base_url: ftp://host}\n",
+		"BadType":        "providers:\n  - {id: a, type: grpc, command: x}\n",
+		"BadTimeout":     "providers:\n  - {id: a, command: x, timeout: soon}\n",
+		"BadID":          "providers:\n  - {id: 'Bad ID', command: x}\n",
+		"RelayNoModels":  "providers:\n  - {id: a, type: relay, base_url: http://host/v1}\n",
+		"UnlistedMapped": "providers:\n  - {id: a, type: relay, base_url: http://host/v1, models: [fast], model_map: {slow: gpt-4o}}\n",
+		"MapOnCLI":       "providers:\n  - {id: a, command: x, models: [fast], model_arg: -m, model_map: {fast: gpt-4o}}\n",
+		"NegativeLimit":  "providers:\n  - {id: a, command: x, sandbox: {limits: {memory_mb: -1}}}\n",
+	}
+	for name, content := range invalid {
+		t.Run(name, func(t *testing.T) {
+			path := writeFile(name+".yaml", content)
+			if _, err := providers.LoadProvidersFile(path); err == nil {
+				t.Error("Expected validation error, got nil")
+			}
+		})
+	}
+}
+
+func TestProviderModelSelection(t *testing.T) {
+	pc := providers.ProviderConfig{
+		ID:       "gemini",
+		Type:     providers.ProviderTypeCLI,
+		Command:  "gemini",
+		Models:   []string{"gemini-2.5-pro", "gemini-2.5-flash"},
+		ModelArg: "-m",
+	}
+	if err := pc.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+
+	provider, err := providers.NewProviderFromConfig(pc, t.TempDir(), providers.DefaultEnvPolicy)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	models := provider.GetModels()
+	if len(models) != 2 || !models[0].Default || models[1].Default {
+		t.Errorf("Expected two models with the first as default, got %+v", models)
+	}
+	if !providers.SupportsModel(provider, "gemini-2.5-flash") {
+		t.Error("Expected gemini-2.5-flash to be supported")
+	}
+	if providers.SupportsModel(provider, "--help") {
+		t.Error("Expected unknown model to be rejected")
+	}
+
+	ctx := providers.WithModel(context.Background(), "gemini-2.5-flash")
+	if got := providers.ModelFromContext(ctx); got != "gemini-2.5-flash" {
+		t.Errorf("Expected model from context, got %q", got)
+	}
+	if got := providers.ModelFromContext(context.Background()); got != "" {
+		t.Errorf("Expected no model by default, got %q", got)
+	}
+
+	invalid := []providers.ProviderConfig{
+		{ID: "a", Command: "a", Models: []string{"m"}},
+		{ID: "b", Command: "b", Models: []string{"-rf"}, ModelArg: "-m"},
+	}
+	for _, pc := range invalid {
+		if err := pc.Validate(); err == nil {
+			t.Errorf("Expected %s to be rejected", pc.ID)
+		}
+	}
+
+	claude := providers.NewClaudeProvider("claude", t.TempDir(), false, "")
+	if !providers.SupportsModel(claude, "opus") {
+		t.Error("Expected Claude to support opus")
+	}
+}
+
+func TestEnvPolicy(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin",
+		"HOME=/root",
+		"LC_ALL=C",
+		"DATABASE_PASSWORD=secret",
+		"ANTHROPIC_API_KEY=key",
+		"GEMINI_API_KEY=key",
+	}
+
+	filtered := providers.DefaultEnvPolicy.Filter(environ)
+	expected := []string{"PATH=/usr/bin", "HOME=/root", "LC_ALL=C"}
+	if len(filtered) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, filtered)
+	}
+	for i := range expected {
+		if filtered[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], filtered[i])
+		}
+	}
+
+	claude := providers.DefaultEnvPolicy.With(providers.ClaudeEnvAllowlist, nil)
+	if !claude.Allows("ANTHROPIC_API_KEY") || claude.Allows("GEMINI_API_KEY") {
+		t.Error("Expected Claude policy to pass only ANTHROPIC_* keys")
+	}
+
+	policy := providers.DefaultEnvPolicy.With([]string{"*"}, []string{"*_PASSWORD"})
+	if !policy.Allows("GEMINI_API_KEY") {
+		t.Error("Expected * to allow everything")
+	}
+	if policy.Allows("DATABASE_PASSWORD") {
+		t.Error("Expected deny patterns to win over allow patterns")
+	}
+
+	// With must not mutate the receiver
+	if providers.DefaultEnvPolicy.Allows("GEMINI_API_KEY") {
+		t.Error("Expected DefaultEnvPolicy to be unchanged")
+	}
+}
@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/middleware"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newProxyRouter echoes the client IP, host and scheme the handlers see
+func newProxyRouter(t *testing.T, trustedProxies []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies(trustedProxies))
+	router.Use(middleware.ProxyHeadersMiddleware(trustedProxies))
+	router.GET("/", func(c *gin.Context) {
+		scheme := "http"
+		if utils.IsHTTPS(c.Request) {
+			scheme = "https"
+		}
+		c.String(http.StatusOK, "%s %s %s", c.ClientIP(), c.Request.Host, scheme)
+	})
+	return router
+}
+
+func TestProxyHeadersMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		want       string
+	}{
+		{name: "no trusted proxies", remoteAddr: "10.0.0.5:40000", want: "10.0.0.5 hub.internal:8080 http"},
+		{name: "trusted proxy IP", trusted: []string{"10.0.0.5"}, remoteAddr: "10.0.0.5:40000", want: "203.0.113.7 hub.example.com https"},
+		{name: "trusted proxy CIDR", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.5:40000", want: "203.0.113.7 hub.example.com https"},
+		{name: "untrusted peer", trusted: []string{"10.0.0.0/8"}, remoteAddr: "198.51.100.9:40000", want: "198.51.100.9 hub.internal:8080 http"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Host = "hub.internal:8080"
+			// The client's own (spoofed) entry comes first; the proxy appends the real address
+			req.Header.Set("X-Forwarded-For", "127.0.0.1, 203.0.113.7")
+			req.Header.Set("X-Forwarded-Host", "hub.example.com")
+			req.Header.Set("X-Forwarded-Proto", "https")
+
+			resp := httptest.NewRecorder()
+			newProxyRouter(t, tt.trusted).ServeHTTP(resp, req)
+			assert.Equal(t, tt.want, resp.Body.String())
+		})
+	}
+}
+
+func TestAdminMiddleware_LoopbackBehindProxy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies([]string{"127.0.0.1"}))
+	router.GET("/api/admin/stats", middleware.AdminMiddleware(&config.Config{AdminLoopback: true}, nil), func(c *gin.Context) { c.String(http.StatusOK, "stats") })
+
+	request := func(forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/stats", nil)
+		req.RemoteAddr = "127.0.0.1:40000"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	// A local reverse proxy forwarding a remote client doesn't make the client an admin
+	assert.Equal(t, http.StatusForbidden, request("127.0.0.1, 203.0.113.7"))
+	assert.Equal(t, http.StatusOK, request("127.0.0.1"))
+}
+
+func TestConfig_ValidateTrustedProxies(t *testing.T) {
+	cfg := config.Load()
+	cfg.TrustedProxies = []string{"127.0.0.1", "10.0.0.0/8", "::1", "proxy.local", "10.0.0.0/33", "0.0.0.0/0"}
+
+	result := cfg.Validate()
+	errs := strings.Join(result.Errors, "\n")
+	assert.Contains(t, errs, `"proxy.local" is not a valid IP address`)
+	assert.Contains(t, errs, `"10.0.0.0/33" is not a valid CIDR`)
+	assert.NotContains(t, errs, `"10.0.0.0/8"`)
+	assert.NotContains(t, errs, `"::1"`)
+	assert.Contains(t, strings.Join(result.Warnings, "\n"), `"0.0.0.0/0" trusts every client`)
+}
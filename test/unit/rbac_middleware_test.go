@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai-gateway-hub/internal/auth"
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/middleware"
+	"ai-gateway-hub/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRBACRouter(cfg *config.Config, roles []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.SetTrustedProxies(nil)
+	if roles != nil {
+		router.Use(func(c *gin.Context) {
+			c.Set(middleware.AuthClaimsContextKey, &auth.Claims{Subject: "alice", Roles: roles})
+		})
+	}
+	api := router.Group("/api", middleware.RBACMiddleware(cfg, nil))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, middleware.RequestRole(c, cfg, nil)) }
+	api.GET("/chats", ok)
+	api.POST("/chats", ok)
+	api.POST("/ws/ticket", ok)
+	router.GET("/new", middleware.RequireRole(cfg, nil, models.RoleUser), ok)
+	// Session IDs are credentials, so listing and expiring sessions is admin only
+	adminAPI := router.Group("/api/admin", middleware.AdminMiddleware(cfg, nil))
+	adminAPI.GET("/sessions", ok)
+	adminAPI.DELETE("/sessions/:id", ok)
+	// Chats have no owner, so only admins read their raw provider logs
+	api.GET("/chats/:id/logs", middleware.AdminMiddleware(cfg, nil), ok)
+	return router
+}
+
+func TestRBACMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultRole string
+		roles       []string
+		method      string
+		path        string
+		wantStatus  int
+	}{
+		{name: "viewer reads chats", roles: []string{"viewer"}, method: http.MethodGet, path: "/api/chats", wantStatus: http.StatusOK},
+		{name: "viewer can't create chats", roles: []string{"viewer"}, method: http.MethodPost, path: "/api/chats", wantStatus: http.StatusForbidden},
+		{name: "viewer gets WebSocket tickets", roles: []string{"viewer"}, method: http.MethodPost, path: "/api/ws/ticket", wantStatus: http.StatusOK},
+		{name: "viewer can't open new chats", roles: []string{"viewer"}, method: http.MethodGet, path: "/new", wantStatus: http.StatusForbidden},
+		{name: "user creates chats", roles: []string{"viewer", "user"}, method: http.MethodPost, path: "/api/chats", wantStatus: http.StatusOK},
+		{name: "token without roles gets the default", defaultRole: "viewer", roles: []string{}, method: http.MethodPost, path: "/api/chats", wantStatus: http.StatusForbidden},
+		{name: "session gets the default", defaultRole: "user", method: http.MethodPost, path: "/api/chats", wantStatus: http.StatusOK},
+		{name: "viewer default for sessions", defaultRole: "viewer", method: http.MethodPost, path: "/api/chats", wantStatus: http.StatusForbidden},
+		{name: "viewer can't list sessions", roles: []string{"viewer"}, method: http.MethodGet, path: "/api/admin/sessions", wantStatus: http.StatusForbidden},
+		{name: "user can't list sessions", roles: []string{"user"}, method: http.MethodGet, path: "/api/admin/sessions", wantStatus: http.StatusForbidden},
+		{name: "user can't expire sessions", roles: []string{"user"}, method: http.MethodDelete, path: "/api/admin/sessions/abc", wantStatus: http.StatusForbidden},
+		{name: "user default can't list sessions", defaultRole: "user", method: http.MethodGet, path: "/api/admin/sessions", wantStatus: http.StatusForbidden},
+		{name: "admin lists sessions", roles: []string{"admin"}, method: http.MethodGet, path: "/api/admin/sessions", wantStatus: http.StatusOK},
+		{name: "viewer can't read chat logs", roles: []string{"viewer"}, method: http.MethodGet, path: "/api/chats/1/logs", wantStatus: http.StatusForbidden},
+		{name: "user can't read chat logs", roles: []string{"user"}, method: http.MethodGet, path: "/api/chats/1/logs", wantStatus: http.StatusForbidden},
+		{name: "user default can't read chat logs", defaultRole: "user", method: http.MethodGet, path: "/api/chats/1/logs", wantStatus: http.StatusForbidden},
+		{name: "admin reads chat logs", roles: []string{"admin"}, method: http.MethodGet, path: "/api/chats/1/logs", wantStatus: http.StatusOK},
+		{name: "admin expires sessions", roles: []string{"admin"}, method: http.MethodDelete, path: "/api/admin/sessions/abc", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{AdminToken: "0123456789abcdef", DefaultRole: tt.defaultRole}
+			router := newRBACRouter(cfg, tt.roles)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.RemoteAddr = "203.0.113.7:5000"
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			if tt.wantStatus == http.StatusForbidden {
+				assert.Contains(t, resp.Body.String(), `"code":"FORBIDDEN"`)
+			}
+		})
+	}
+}
+
+func TestRequestRole_Admin(t *testing.T) {
+	cfg := &config.Config{AdminToken: "0123456789abcdef", DefaultRole: "viewer"}
+	router := newRBACRouter(cfg, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/chats", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+	req.Header.Set("Authorization", "Bearer 0123456789abcdef")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, models.RoleAdmin, resp.Body.String())
+}
+
+func TestRoleHelpers(t *testing.T) {
+	assert.True(t, models.IsValidRole("viewer"))
+	assert.False(t, models.IsValidRole("root"))
+	assert.False(t, models.IsValidRole(""))
+
+	assert.True(t, models.RoleAllows(models.RoleAdmin, models.RoleUser))
+	assert.True(t, models.RoleAllows(models.RoleUser, models.RoleUser))
+	assert.False(t, models.RoleAllows(models.RoleViewer, models.RoleUser))
+	assert.False(t, models.RoleAllows("", models.RoleViewer))
+
+	assert.Equal(t, models.RoleUser, models.HighestRole([]string{"viewer", "user", "unknown"}))
+	assert.Equal(t, "", models.HighestRole(nil))
+}
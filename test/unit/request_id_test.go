@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/middleware"
+	"ai-gateway-hub/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRequestIDRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.GET("/id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"context": c.GetString(middleware.RequestIDContextKey),
+			"request": utils.RequestIDFromContext(c.Request.Context()),
+		})
+	})
+	return router
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	router := newRequestIDRouter()
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string // empty: a new ID is generated
+	}{
+		{name: "generated", header: ""},
+		{name: "honored", header: "client-abc.123_x", expected: "client-abc.123_x"},
+		{name: "invalid characters", header: "bad id\nInjected: 1"},
+		{name: "too long", header: strings.Repeat("a", 65)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/id", nil)
+			if tt.header != "" {
+				req.Header.Set(utils.RequestIDHeader, tt.header)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			requestID := resp.Header().Get(utils.RequestIDHeader)
+			if tt.expected != "" {
+				assert.Equal(t, tt.expected, requestID)
+			} else {
+				assert.Len(t, requestID, 16)
+				assert.NotEqual(t, tt.header, requestID)
+			}
+
+			var body map[string]string
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Equal(t, requestID, body["context"])
+			assert.Equal(t, requestID, body["request"])
+		})
+	}
+}
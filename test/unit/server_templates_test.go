@@ -0,0 +1,62 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai-gateway-hub/internal/server"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTemplatesShipped(t *testing.T) {
+	_, err := server.ParseTemplates(os.DirFS("../../web/templates"))
+	require.NoError(t, err)
+}
+
+func TestReloadableHTML(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	for _, sub := range []string{"pages", "components"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, sub), 0755))
+	}
+	page := filepath.Join(dir, "pages", "index.html")
+	write := func(path, content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write(filepath.Join(dir, "layout.html"), `{{define "layout"}}layout{{end}}`)
+	write(filepath.Join(dir, "components", "footer.html"), `{{define "footer"}}footer{{end}}`)
+	write(page, `{{define "pages/index.html"}}Hello {{.name}}{{end}}`)
+
+	html, err := server.NewReloadableHTML(dir)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, html.Watch(ctx))
+
+	router := gin.New()
+	router.HTMLRender = html
+	router.GET("/", func(c *gin.Context) { c.HTML(http.StatusOK, "pages/index.html", gin.H{"name": "hub"}) })
+	render := func() string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Body.String()
+	}
+	assert.Equal(t, "Hello hub", render())
+
+	write(page, `{{define "pages/index.html"}}Welcome to {{.name}}{{end}}`)
+	assert.Eventually(t, func() bool { return render() == "Welcome to hub" }, 3*time.Second, 20*time.Millisecond)
+
+	// A broken edit keeps the previous templates
+	write(page, `{{define "pages/index.html"}}{{if}}{{end}}`)
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, "Welcome to hub", render())
+	assert.Error(t, html.Reload())
+}
@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai-gateway-hub/internal/config"
+	"ai-gateway-hub/internal/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort string
+		host      string
+		target    string
+		want      string
+	}{
+		{name: "default port", httpsPort: "443", host: "hub.example.com", target: "/chat/1?lang=ja", want: "https://hub.example.com/chat/1?lang=ja"},
+		{name: "drops the HTTP port", httpsPort: "443", host: "hub.example.com:80", target: "/", want: "https://hub.example.com/"},
+		{name: "custom HTTPS port", httpsPort: "8443", host: "hub.example.com:8080", target: "/settings", want: "https://hub.example.com:8443/settings"},
+		{name: "IPv6 host", httpsPort: "443", host: "[::1]:8080", target: "/", want: "https://[::1]/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Host = tt.host
+			resp := httptest.NewRecorder()
+			server.RedirectHandler(tt.httpsPort).ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusMovedPermanently, resp.Code)
+			assert.Equal(t, tt.want, resp.Header().Get("Location"))
+		})
+	}
+
+	// Request bodies are not sent to the HTTPS port again
+	req := httptest.NewRequest(http.MethodPost, "/api/chats", strings.NewReader(`{}`))
+	resp := httptest.NewRecorder()
+	server.RedirectHandler("443").ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestConfig_ValidateTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, []byte("cert"), 0600))
+	require.NoError(t, os.WriteFile(keyFile, []byte("key"), 0600))
+
+	tests := []struct {
+		name      string
+		apply     func(cfg *config.Config)
+		wantError string
+	}{
+		{name: "plain HTTP", apply: func(cfg *config.Config) {}},
+		{name: "certificate files", apply: func(cfg *config.Config) {
+			cfg.TLSCertFile, cfg.TLSKeyFile, cfg.HTTPRedirectPort = certFile, keyFile, "8081"
+		}},
+		{name: "certificate without key", apply: func(cfg *config.Config) {
+			cfg.TLSCertFile = certFile
+		}, wantError: "TLS_CERT_FILE and TLS_KEY_FILE must be set together"},
+		{name: "missing certificate file", apply: func(cfg *config.Config) {
+			cfg.TLSCertFile, cfg.TLSKeyFile = filepath.Join(dir, "missing.pem"), keyFile
+		}, wantError: "TLS_CERT_FILE"},
+		{name: "autocert", apply: func(cfg *config.Config) {
+			cfg.TLSAutocert, cfg.TLSAutocertHosts, cfg.TLSAutocertCacheDir, cfg.HTTPRedirectPort = true, []string{"hub.example.com"}, filepath.Join(dir, "autocert"), "80"
+		}},
+		{name: "autocert without hosts", apply: func(cfg *config.Config) {
+			cfg.TLSAutocert, cfg.TLSAutocertCacheDir = true, filepath.Join(dir, "autocert")
+		}, wantError: "TLS_AUTOCERT_HOSTS is required"},
+		{name: "autocert with certificate files", apply: func(cfg *config.Config) {
+			cfg.TLSAutocert, cfg.TLSAutocertHosts, cfg.TLSAutocertCacheDir = true, []string{"hub.example.com"}, filepath.Join(dir, "autocert")
+			cfg.TLSCertFile, cfg.TLSKeyFile = certFile, keyFile
+		}, wantError: "cannot be combined"},
+		{name: "redirect port equals PORT", apply: func(cfg *config.Config) {
+			cfg.TLSCertFile, cfg.TLSKeyFile, cfg.HTTPRedirectPort = certFile, keyFile, cfg.Port
+		}, wantError: "HTTP_REDIRECT_PORT must differ from PORT"},
+		{name: "invalid redirect port", apply: func(cfg *config.Config) {
+			cfg.TLSCertFile, cfg.TLSKeyFile, cfg.HTTPRedirectPort = certFile, keyFile, "http"
+		}, wantError: "HTTP_REDIRECT_PORT must be a port number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Load()
+			tt.apply(cfg)
+
+			errs := strings.Join(cfg.Validate().Errors, "\n")
+			if tt.wantError != "" {
+				assert.Contains(t, errs, tt.wantError)
+			} else {
+				assert.NotContains(t, errs, "TLS_")
+				assert.NotContains(t, errs, "HTTP_REDIRECT_PORT")
+			}
+		})
+	}
+}
@@ -9,14 +9,36 @@ const CHAT_CONFIG = {
     RECONNECT_MAX_DELAY: 60000,  // Maximum reconnection delay
     RECONNECT_JITTER_MAX: 5000,  // Maximum jitter to add
     STATUS_CHECK_INTERVAL: 30000,
+    ACK_EVERY_FRAMES: 16,        // Acknowledge numbered frames at least this often
+    RESEND_RETRY_DELAY: 2000,    // Ask again for missing frames or stream chunks after this long
     DEFAULT_INPUT_BEHAVIOR: 'enter_to_send'
 };
 
 const MESSAGE_TYPES = {
     AI_PROMPT: 'ai_prompt',
+    AI_REGENERATE: 'ai_regenerate',
+    AI_THINKING: 'ai_thinking',
+    PROVIDER_STARTED: 'provider_started',
+    AI_PROGRESS: 'ai_progress',
     AI_RESPONSE: 'ai_response',
     AI_RESPONSE_END: 'ai_response_end',
+    AI_RESPONSE_TIMEOUT: 'ai_response_timeout',
+    AI_FAILOVER: 'ai_failover',
+    AI_RETRY: 'ai_retry',
+    AI_RESPONSE_OVERSIZED: 'ai_response_oversized',
+    MESSAGE_BLOCKED: 'message_blocked',
+    AI_RESPONSE_SAVED: 'ai_response_saved',
+    SCHEDULED_RUN: 'scheduled_run',
+    USER_MESSAGE: 'user_message',
+    PRESENCE: 'presence',
+    USER_JOINED: 'user_joined',
+    USER_LEFT: 'user_left',
+    TOOL_CALL: 'tool_call',
+    TOOL_RESULT: 'tool_result',
     SESSION_STATUS: 'session_status',
+    ACK: 'ack',
+    RESEND: 'resend',
+    RESUME_STREAM: 'resume_stream',
     ERROR: 'error'
 };
 
@@ -59,6 +81,10 @@ class WebSocketManager {
         this.maxReconnectAttempts = 5;
         this.eventHandlers = new Map();
         this.instanceKey = instanceKey;
+        this.upgradeRequired = false;
+        this.fetchingTicket = false; // a ticket for the next connection is being requested
+        this.streams = new Map(); // streamed responses by stream ID: chunks received, provider, ended
+        this.resetFrames();
         
         // Store instance globally
         window._wsManagerInstances[instanceKey] = this;
@@ -70,9 +96,9 @@ class WebSocketManager {
     /**
      * Establish WebSocket connection
      */
-    connect() {
+    async connect() {
         // Don't create new connection if already connecting or connected
-        if (this.ws && (this.ws.readyState === WebSocket.CONNECTING || this.ws.readyState === WebSocket.OPEN)) {
+        if (this.fetchingTicket || (this.ws && (this.ws.readyState === WebSocket.CONNECTING || this.ws.readyState === WebSocket.OPEN))) {
             console.log('WebSocket already connecting/connected, skipping new connection');
             return;
         }
@@ -86,8 +112,20 @@ class WebSocketManager {
         }
         
         console.log('Creating new WebSocket connection');
-        const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-        const wsUrl = `${protocol}//${window.location.host}/ws`;
+        let wsUrl;
+        this.fetchingTicket = true;
+        try {
+            // The connection is authenticated with a single-use ticket
+            wsUrl = await apiUtils.webSocketUrl();
+        } catch (error) {
+            console.error('Failed to get WebSocket ticket:', error);
+            if (this.reconnectAttempts < this.maxReconnectAttempts) {
+                this.scheduleReconnect();
+            }
+            return;
+        } finally {
+            this.fetchingTicket = false;
+        }
         
         this.ws = new WebSocket(wsUrl);
         this.setupEventHandlers();
@@ -100,19 +138,31 @@ class WebSocketManager {
         this.ws.onopen = () => {
             console.log('WebSocket connected');
             this.connected = true;
+            this.resetFrames(); // frame IDs restart with every connection
             this.reconnectAttempts = 0; // Reset counter on successful connection
             this.emit('connected');
             
             // Send session status immediately since onopen guarantees connection is ready
             this.sendSessionStatus();
+
+            // Responses that were streaming when the connection dropped continue where they stopped
+            this.resumeStreams();
         };
 
         this.ws.onmessage = (event) => {
+            let message;
             try {
-                const message = JSON.parse(event.data);
-                this.emit('message', message);
+                message = JSON.parse(event.data);
             } catch (error) {
                 console.error('Failed to parse WebSocket message:', error);
+                return;
+            }
+            if (message.type === MESSAGE_TYPES.ERROR && message.data && message.data.action === 'upgrade_required') {
+                // The server no longer speaks this page's protocol; reconnecting won't help
+                this.upgradeRequired = true;
+            }
+            if (this.acceptFrame(message) && this.acceptStreamChunk(message)) {
+                this.emit('message', message);
             }
         };
 
@@ -122,7 +172,9 @@ class WebSocketManager {
             this.emit('disconnected');
             
             // Only attempt reconnection if under the limit
-            if (this.reconnectAttempts < this.maxReconnectAttempts) {
+            if (this.upgradeRequired) {
+                console.warn('Server requires a newer WebSocket protocol, reload the page to reconnect');
+            } else if (this.reconnectAttempts < this.maxReconnectAttempts) {
                 this.scheduleReconnect();
             } else {
                 console.warn('Max reconnection attempts reached, stopping reconnection');
@@ -164,13 +216,137 @@ class WebSocketManager {
         return success;
     }
 
+    /**
+     * Forget the frames of a previous connection
+     */
+    resetFrames() {
+        this.lastFrameId = 0;
+        this.unackedFrames = 0;
+        this.resendRequestedAt = 0;
+    }
+
+    /**
+     * Track numbered frames, returning whether the message should be handled. Duplicates are
+     * dropped; a frame after a gap is dropped too and the missing frames are requested again.
+     */
+    acceptFrame(message) {
+        if (!message.id) {
+            return true;
+        }
+        if (message.id <= this.lastFrameId) {
+            return false;
+        }
+        if (message.id > this.lastFrameId + 1) {
+            if (Date.now() - this.resendRequestedAt > CHAT_CONFIG.RESEND_RETRY_DELAY) {
+                console.warn(`Missing WebSocket frames ${this.lastFrameId + 1}-${message.id - 1}, requesting resend`);
+                this.resendRequestedAt = Date.now();
+                this.send({ type: MESSAGE_TYPES.RESEND, ack: this.lastFrameId, data: {} });
+            }
+            return false;
+        }
+
+        this.lastFrameId = message.id;
+        this.resendRequestedAt = 0;
+        this.unackedFrames++;
+        const ended = message.type === MESSAGE_TYPES.AI_RESPONSE_END || message.type === 'ai_response_multi_end';
+        if (ended || this.unackedFrames >= CHAT_CONFIG.ACK_EVERY_FRAMES) {
+            this.unackedFrames = 0;
+            this.send({ type: MESSAGE_TYPES.ACK, ack: this.lastFrameId, data: {} });
+        }
+        return true;
+    }
+
+    /**
+     * Track the chunks of streamed responses, returning whether the message should be handled.
+     * Chunks already shown are dropped; after a gap the missing chunks are requested with
+     * resume_stream, which replays them from the server's buffer.
+     */
+    acceptStreamChunk(message) {
+        const data = message.data || {};
+        if (!data.stream_id) {
+            return true;
+        }
+        let stream = this.streams.get(data.stream_id);
+
+        if (message.type === MESSAGE_TYPES.ERROR && data.action === 'stream_expired') {
+            if (stream) {
+                stream.ended = true;
+            }
+            return true;
+        }
+
+        if (message.type === MESSAGE_TYPES.AI_RESPONSE) {
+            const start = data.stream_start || 0;
+            if (!stream) {
+                // A client that started viewing mid-stream takes it from here
+                stream = { seq: start, provider: data.provider, ended: false, resumeRequestedAt: 0 };
+                this.streams.set(data.stream_id, stream);
+            }
+            if (stream.ended || data.stream_seq <= stream.seq) {
+                return false;
+            }
+            if (start !== stream.seq) {
+                this.requestStreamResume(data.stream_id, stream);
+                return false;
+            }
+            stream.seq = data.stream_seq;
+            stream.resumeRequestedAt = 0;
+            return true;
+        }
+
+        if (message.type === MESSAGE_TYPES.AI_RESPONSE_END && stream) {
+            if (stream.ended) {
+                return false;
+            }
+            if ((data.stream_seq || 0) > stream.seq) {
+                // Chunks are missing; the replay ends with the completion again
+                this.requestStreamResume(data.stream_id, stream);
+                return false;
+            }
+            stream.ended = true;
+        }
+        return true;
+    }
+
+    /**
+     * Ask for the chunks of unfinished streams missed while disconnected
+     */
+    resumeStreams() {
+        this.streams.forEach((stream, streamId) => {
+            if (!stream.ended) {
+                stream.resumeRequestedAt = 0;
+                this.requestStreamResume(streamId, stream);
+            }
+        });
+    }
+
+    /**
+     * Request the chunks of a stream after the ones received, at most every RESEND_RETRY_DELAY
+     */
+    requestStreamResume(streamId, stream) {
+        if (Date.now() - stream.resumeRequestedAt <= CHAT_CONFIG.RESEND_RETRY_DELAY) {
+            return;
+        }
+        console.warn(`Resuming stream ${streamId} after chunk ${stream.seq}`);
+        stream.resumeRequestedAt = Date.now();
+        this.send({
+            type: MESSAGE_TYPES.RESUME_STREAM,
+            data: {
+                chat_id: this.chatId,
+                provider: stream.provider,
+                stream_id: streamId,
+                stream_seq: stream.seq
+            }
+        });
+    }
+
     /**
      * Send message through WebSocket
      */
     send(message) {
         if (this.connected && this.ws && this.ws.readyState === WebSocket.OPEN) {
             try {
-                this.ws.send(JSON.stringify(message));
+                this.ws.send(JSON.stringify({ ...message, version: window.WS_PROTOCOL_VERSION }));
                 return true;
             } catch (error) {
                 console.error('Failed to send WebSocket message:', error);
@@ -409,7 +585,7 @@ class ProviderStatusManager {
 /**
  * Main chat interface factory for Alpine.js
  */
-window.createChatInterface = function(chatId, provider, initialMessages = []) {
+window.createChatInterface = function(chatId, provider, initialMessages = [], initialPrompt = '', systemPrompt = '', autoSendPrompt = true) {
     // Prevent multiple chat interfaces for the same chat using global registry
     const interfaceKey = `chat_${chatId}_${provider}`;
     
@@ -437,27 +613,127 @@ window.createChatInterface = function(chatId, provider, initialMessages = []) {
         provider: provider,
         messages: [...initialMessages], // Initialize with server-provided messages
         newMessage: '',
+        pendingPrompt: null, // user message sent but not answered yet, taken back if the server refuses it
         connected: false,
         isTyping: false,
+        generationStatus: null,      // phase ('thinking' or 'responding'), elapsed time and bytes of the running response
+        userId: '',                  // participant ID of this connection, sent with the chat's presence
+        participants: [],            // participants viewing this chat, this one included
         currentResponse: '',
         providerStatus: {},
         streamTimeout: null,
+        pendingPrompt: initialPrompt || '',
+        autoSendPrompt: autoSendPrompt, // false for prompts from links on other sites, which the user sends
+        models: [],
+        selectedModel: '',
+        editingMessageId: null,
+        editContent: '',
+        systemPrompt: systemPrompt || '',
+        systemPromptOpen: false,
+        savingSystemPrompt: false,
+        pendingAttachments: [],
+        uploadingAttachment: false,
+        feedback: {},                // ratings (1 or -1) by message ID
+        draftTimer: null,            // pending save of the prompt draft
+        savedDraft: '',              // draft content the server has
 
         // Initialization
         init() {
             this.setupWebSocket();
             this.setupStatusManager();
             this.setupMessageScrolling();
+            this.loadModels();
+            this.loadFeedback();
+            this.loadDraft();
+        },
+
+        /**
+         * Restore the prompt draft saved before a reload or on another device
+         */
+        async loadDraft() {
+            if (this.pendingPrompt) return;
+            try {
+                const response = await fetch(`/api/chats/${this.chatId}/draft`);
+                if (!response.ok) return;
+                const result = await response.json();
+                const content = (result.data && result.data.content) || '';
+                this.savedDraft = content;
+                if (content && !this.newMessage) {
+                    this.newMessage = content;
+                }
+            } catch (error) {
+                console.error('Failed to load draft:', error);
+            }
+        },
+
+        /**
+         * Save the prompt draft once typing pauses
+         */
+        scheduleDraftSave() {
+            clearTimeout(this.draftTimer);
+            this.draftTimer = setTimeout(() => this.saveDraft(), 1000);
+        },
+
+        async saveDraft() {
+            const content = this.newMessage;
+            if (content === this.savedDraft) return;
+            try {
+                const response = await fetch(`/api/chats/${this.chatId}/draft`, {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ content: content })
+                });
+                if (response.ok) {
+                    this.savedDraft = content;
+                }
+            } catch (error) {
+                console.error('Failed to save draft:', error);
+            }
+        },
+
+        /**
+         * Load the models the provider accepts per request; the selector stays hidden if there are none
+         */
+        async loadModels() {
+            try {
+                const response = await fetch(`/api/providers/${this.provider}/models`);
+                if (!response.ok) return;
+                const result = await response.json();
+                this.models = Array.isArray(result.data) ? result.data : [];
+                this.selectDefaultModel();
+            } catch (error) {
+                console.error('Failed to load models:', error);
+            }
+        },
+
+        /**
+         * Preselect the default model from the settings when this chat uses the default provider
+         */
+        async selectDefaultModel() {
+            if (this.models.length === 0) return;
+            try {
+                const response = await fetch('/api/settings');
+                if (!response.ok) return;
+                const result = await response.json();
+                const settings = result.data || result;
+                if (settings.defaultProvider === this.provider && this.models.some(m => m.id === settings.defaultModel)) {
+                    this.selectedModel = settings.defaultModel;
+                }
+            } catch (error) {
+                console.error('Failed to load default model:', error);
+            }
         },
 
         setupWebSocket() {
             wsManager.on('connected', () => {
                 this.connected = true;
+                this.sendPendingPrompt();
             });
 
             wsManager.on('disconnected', () => {
                 this.connected = false;
                 this.isTyping = false;
+                this.generationStatus = null;
             });
 
             wsManager.on('message', (message) => {
@@ -485,20 +761,170 @@ window.createChatInterface = function(chatId, provider, initialMessages = []) {
 
         // Message handling
         handleMessage(message) {
+            // Messages relayed from other connections may belong to another chat
+            if (message.data && message.data.chat_id && Number(message.data.chat_id) !== Number(this.chatId)) {
+                return;
+            }
             switch (message.type) {
+                case MESSAGE_TYPES.AI_THINKING:
+                case MESSAGE_TYPES.PROVIDER_STARTED:
+                case MESSAGE_TYPES.AI_PROGRESS:
+                    this.handleGenerationStatus(message);
+                    break;
                 case MESSAGE_TYPES.AI_RESPONSE:
                     this.handleAIResponse(message);
                     break;
                 case MESSAGE_TYPES.AI_RESPONSE_END:
                     this.handleCompleteResponse();
                     break;
+                case MESSAGE_TYPES.AI_RESPONSE_TIMEOUT:
+                    this.handleResponseTimeout(message);
+                    break;
+                case MESSAGE_TYPES.AI_RESPONSE_OVERSIZED:
+                case MESSAGE_TYPES.AI_FAILOVER:
+                    uiUtils.showNotification(message.data.content, 'warning', 8000);
+                    break;
+                case MESSAGE_TYPES.AI_RETRY:
+                    uiUtils.showNotification(message.data.content, 'info', 5000);
+                    break;
+                case MESSAGE_TYPES.MESSAGE_BLOCKED:
+                    this.handleMessageBlocked(message);
+                    break;
+                case MESSAGE_TYPES.AI_RESPONSE_SAVED:
+                    this.handleResponseSaved(message);
+                    break;
+                case MESSAGE_TYPES.SCHEDULED_RUN:
+                    this.handleScheduledRun(message);
+                    break;
+                case MESSAGE_TYPES.USER_MESSAGE:
+                    this.handleUserMessage(message);
+                    break;
+                case MESSAGE_TYPES.PRESENCE:
+                case MESSAGE_TYPES.USER_JOINED:
+                case MESSAGE_TYPES.USER_LEFT:
+                    this.handlePresence(message);
+                    break;
+                case MESSAGE_TYPES.TOOL_CALL:
+                case MESSAGE_TYPES.TOOL_RESULT:
+                    this.handleToolStep(message);
+                    break;
                 case MESSAGE_TYPES.ERROR:
                     this.handleError(message);
                     break;
             }
         },
 
+        // A prompt was sent in this chat from another tab or device: show it before its response streams
+        handleUserMessage(message) {
+            const data = message.data;
+            if (this.messages.some(m => m.dbId && m.dbId === data.message_id)) {
+                return;
+            }
+            this.messages.push({
+                id: `user_${data.message_id}`,
+                dbId: data.message_id,
+                role: 'user',
+                user_id: data.user_id,
+                content: data.content
+            });
+        },
+
+        // Another participant joined or left this chat, or the chat's participants were sent on subscribing
+        handlePresence(message) {
+            const data = message.data;
+            if (message.type === MESSAGE_TYPES.PRESENCE) {
+                this.userId = data.user_id || '';
+                this.participants = data.participants || [];
+            } else if (message.type === MESSAGE_TYPES.USER_JOINED) {
+                if (!this.participants.includes(data.user_id)) {
+                    this.participants = [...this.participants, data.user_id].sort();
+                }
+            } else {
+                this.participants = this.participants.filter(p => p !== data.user_id);
+            }
+        },
+
+        // Label of a message's author: prompts of other participants show who sent them
+        messageAuthor(message, you) {
+            if (message.user_id && this.userId && message.user_id !== this.userId) {
+                return message.user_id;
+            }
+            return you;
+        },
+
+        // A scheduled prompt ran in this chat: show its prompt and response
+        handleScheduledRun(message) {
+            const data = message.data;
+            if (data.prompt) {
+                this.messages.push({
+                    id: `scheduled_${data.schedule_id}_${Date.now()}`,
+                    role: 'user',
+                    content: data.prompt
+                });
+            }
+            if (data.action === 'failed') {
+                uiUtils.showNotification(`Scheduled prompt failed: ${data.content}`, 'error', 8000);
+                return;
+            }
+            this.messages.push({
+                id: data.message_id || `scheduled_${data.schedule_id}_${Date.now()}_response`,
+                dbId: data.message_id,
+                role: 'assistant',
+                content: data.content,
+                provider: data.provider
+            });
+        },
+
+        // The provider is working on a response: show how long it has been running and how much it wrote
+        handleGenerationStatus(message) {
+            const data = message.data;
+            this.isTyping = true;
+            let phase = this.generationStatus ? this.generationStatus.phase : 'thinking';
+            if (message.type === MESSAGE_TYPES.AI_THINKING) {
+                phase = 'thinking';
+            } else if (message.type === MESSAGE_TYPES.PROVIDER_STARTED || data.bytes_streamed > 0) {
+                phase = 'responding';
+            }
+            this.generationStatus = {
+                phase: phase,
+                elapsedMs: data.elapsed_ms || 0,
+                bytes: data.bytes_streamed || 0
+            };
+        },
+
+        // Text of the status line under the typing indicator
+        formatGenerationStatus() {
+            const status = this.generationStatus;
+            if (!status) {
+                return '';
+            }
+            const labels = this.generationLabels || { thinking: 'Thinking', responding: 'Responding' };
+            const parts = [labels[status.phase], `${Math.floor(status.elapsedMs / 1000)}s`];
+            if (status.bytes > 0) {
+                parts.push(status.bytes < 1024 ? `${status.bytes} B` : `${(status.bytes / 1024).toFixed(1)} KB`);
+            }
+            return parts.join(' · ');
+        },
+
+        // Details line of a saved response: model, latency and tokens, ~ marking estimates
+        formatMessageMetadata(message) {
+            const meta = message.metadata;
+            if (!meta) {
+                return '';
+            }
+            const approx = meta.tokens_estimated ? '~' : '';
+            const parts = [meta.model || meta.provider, `${(meta.latency_ms / 1000).toFixed(1)}s`, `${approx}${meta.tokens_in} → ${approx}${meta.tokens_out} tokens`];
+            if (meta.finish_reason === 'length') {
+                parts.push('truncated');
+            }
+            if (meta.failed_over && meta.failed_over.length > 0) {
+                parts.push(`${meta.provider} after ${meta.failed_over.join(', ')} failed`);
+            }
+            return parts.filter(Boolean).join(' · ');
+        },
+
         handleAIResponse(message) {
+            this.pendingPrompt = null;
             if (message.data.stream) {
                 this.handleStreamingResponse(message);
             } else {
@@ -511,16 +937,18 @@ window.createChatInterface = function(chatId, provider, initialMessages = []) {
             console.log('Message content length:', message.data.content.length);
             console.log('Current messages count:', this.messages.length);
             
-            // Initialize streaming if this is the first chunk
+            const lastMessage = this.messages[this.messages.length - 1];
+
+            // Initialize streaming if this is the first chunk; a response checkpointed before
+            // the page was loaded continues from its saved content
             if (!this.isTyping) {
                 this.isTyping = true;
-                this.currentResponse = '';
+                this.currentResponse = lastMessage && lastMessage.isStreaming ? lastMessage.content : '';
                 console.log('Starting new streaming response');
             }
             
             this.currentResponse += message.data.content;
             
-            const lastMessage = this.messages[this.messages.length - 1];
             console.log('Last message role:', lastMessage?.role, 'isStreaming:', lastMessage?.isStreaming);
             
             if (lastMessage && lastMessage.role === 'assistant' && lastMessage.isStreaming) {
@@ -543,6 +971,7 @@ window.createChatInterface = function(chatId, provider, initialMessages = []) {
 
         handleCompleteResponse() {
             this.isTyping = false;
+            this.generationStatus = null;
             if (this.currentResponse) {
                 const lastMessage = this.messages[this.messages.length - 1];
                 if (lastMessage && lastMessage.isStreaming) {
@@ -552,10 +981,122 @@ window.createChatInterface = function(chatId, provider, initialMessages = []) {
             }
         },
 
+        // A response was stopped by its overall or idle timeout; what streamed so far isn't saved
+        handleResponseTimeout(message) {
+            const lastMessage = this.messages[this.messages.length - 1];
+            if (lastMessage && lastMessage.isStreaming) {
+                lastMessage.status = 'interrupted';
+            }
+            this.handleCompleteResponse();
+            uiUtils.showNotification(message.data.content, 'warning', 8000);
+        },
+
+        // Moderation blocked a prompt, which goes back into the input, or withdrew a streamed response
+        handleMessageBlocked(message) {
+            const data = message.data;
+            if (data.action === 'prompt') {
+                this.isTyping = false;
+                this.generationStatus = null;
+                this.restorePendingPrompt();
+                uiUtils.showNotification(data.content, 'error', 8000);
+                return;
+            }
+            for (let i = this.messages.length - 1; i >= 0; i--) {
+                const m = this.messages[i];
+                if (m.role === 'assistant' && !m.dbId && (!m.provider || m.provider === data.provider)) {
+                    m.content = data.content;
+                    m.status = 'blocked';
+                    break;
+                }
+            }
+            uiUtils.showNotification(data.content, 'warning', 8000);
+        },
+
+        // The provider called a tool while responding: list the call, then its output, under the response
+        handleToolStep(message) {
+            const data = message.data;
+            for (let i = this.messages.length - 1; i >= 0; i--) {
+                const m = this.messages[i];
+                if (m.role === 'assistant' && !m.dbId && (!m.provider || m.provider === data.provider)) {
+                    m.toolSteps = m.toolSteps || [];
+                    if (message.type === MESSAGE_TYPES.TOOL_CALL) {
+                        m.toolSteps.push({ tool: data.tool, input: data.content, output: null, failed: false });
+                    } else {
+                        const step = m.toolSteps[m.toolSteps.length - 1];
+                        if (step) {
+                            step.output = data.content;
+                            step.failed = data.action === 'failed';
+                        }
+                    }
+                    return;
+                }
+            }
+        },
+
+        // A streamed response was saved; its ID lets it be rated
+        handleResponseSaved(message) {
+            const data = message.data;
+            for (let i = this.messages.length - 1; i >= 0; i--) {
+                const m = this.messages[i];
+                if (m.role === 'assistant' && !m.dbId && (!m.provider || m.provider === data.provider)) {
+                    m.dbId = data.message_id;
+                    // Post processors changed the response after it streamed
+                    if (data.content) {
+                        m.content = data.content;
+                    }
+                    m.metadata = data.metadata || null;
+                    return;
+                }
+            }
+        },
+
         handleError(message) {
             this.isTyping = false;
+            this.generationStatus = null;
+            if (message.data.action === 'quota_exceeded') {
+                this.handleQuotaExceeded(message);
+                return;
+            }
+            if (message.data.action === 'prompt_rejected' || message.data.action === 'forbidden') {
+                this.restorePendingPrompt();
+                uiUtils.showNotification(message.data.content, 'error', 8000);
+                return;
+            }
+            // A prompt the server couldn't read was never saved
+            if (message.data.action === 'protocol_error') {
+                this.restorePendingPrompt();
+            }
             // Show error using unified notification system
-            uiUtils.showNotification(`WebSocket Error: ${message.data.content}`, 'error', 8000);
+            // The request ID lets a reported error be found in the server logs
+            const requestId = message.data.request_id ? ` (ID: ${message.data.request_id})` : '';
+            // In compare mode the provider tells which column failed
+            const provider = message.data.provider ? `[${message.data.provider}] ` : '';
+            uiUtils.showNotification(`WebSocket Error: ${provider}${message.data.content}${requestId}`, 'error', 8000);
+        },
+
+        // The session has used up its prompt quota: take the refused prompt back into the input
+        handleQuotaExceeded(message) {
+            this.restorePendingPrompt();
+
+            const quota = message.data.quota;
+            let text = this.quotaExceededMessage || message.data.content;
+            if (quota) {
+                const period = quota.daily.limit > 0 && quota.daily.remaining === 0 ? quota.daily : quota.monthly;
+                text += ` (${new Date(period.resets_at).toLocaleString()})`;
+            }
+            uiUtils.showNotification(text, 'error', 8000);
+        },
+
+        // Takes a prompt the server refused out of the conversation and back into the input
+        restorePendingPrompt() {
+            const prompt = this.pendingPrompt;
+            this.pendingPrompt = null;
+            if (prompt && this.messages[this.messages.length - 1] === prompt) {
+                this.messages.pop();
+                if (!this.newMessage) {
+                    this.newMessage = prompt.content;
+                }
+            }
         },
 
         // User interactions
@@ -564,18 +1105,21 @@ window.createChatInterface = function(chatId, provider, initialMessages = []) {
         },
 
         sendMessage() {
-            if (!this.connected || !this.newMessage.trim() || this.isTyping) return;
+            if (!this.connected || !this.newMessage.trim() || this.isTyping || this.uploadingAttachment) return;
             
             const content = this.newMessage.trim();
             
             console.log('sendMessage called by interface:', `chat_${this.chatId}_${this.provider}`);
             console.log('Current messages count before send:', this.messages.length);
             
+            const attachments = this.pendingAttachments;
+
             // Add user message to UI
             const userMessage = {
                 id: Date.now(),
                 role: 'user',
-                content: content
+                content: content,
+                attachments: attachments
             };
             this.messages.push(userMessage);
             console.log('User message added to UI:', userMessage.id);
@@ -586,14 +1130,21 @@ window.createChatInterface = function(chatId, provider, initialMessages = []) {
                 data: {
                     chat_id: this.chatId,
                     provider: this.provider,
+                    model: this.selectedModel || undefined,
                     content: content,
+                    attachment_ids: attachments.length ? attachments.map(a => a.id) : undefined,
                     timestamp: new Date().toISOString()
                 }
             });
             
             if (success) {
-                // Clear input and show typing indicator only if send was successful
+                // Clear input and show typing indicator only if send was successful; the server
+                // drops the draft once the prompt is saved
                 this.newMessage = '';
+                clearTimeout(this.draftTimer);
+                this.savedDraft = '';
+                this.pendingAttachments = [];
+                this.pendingPrompt = userMessage;
                 this.isTyping = true;
                 this.currentResponse = '';
                 console.log('Message sent successfully via WebSocket');
@@ -605,6 +1156,268 @@ window.createChatInterface = function(chatId, provider, initialMessages = []) {
             }
         },
 
+        /**
+         * Discard the responses after a user message (the latest one by default) and stream a new answer
+         */
+        regenerate(userMessage = null) {
+            if (!this.connected || this.isTyping) return;
+
+            // Find the user message to answer again and drop everything after it from the UI
+            let index = userMessage ? this.messages.indexOf(userMessage) : -1;
+            if (index < 0) {
+                for (let i = this.messages.length - 1; i >= 0; i--) {
+                    if (this.messages[i].role === 'user') {
+                        index = i;
+                        break;
+                    }
+                }
+            }
+            if (index < 0) return;
+
+            const target = this.messages[index];
+            const success = wsManager.send({
+                type: MESSAGE_TYPES.AI_REGENERATE,
+                data: {
+                    chat_id: this.chatId,
+                    provider: this.provider,
+                    model: this.selectedModel || undefined,
+                    message_id: target.dbId || undefined
+                }
+            });
+
+            if (success) {
+                this.messages.splice(index + 1);
+                this.pendingPrompt = null;
+                this.isTyping = true;
+                this.currentResponse = '';
+            } else {
+                uiUtils.showNotification('Failed to regenerate. Please check your connection.', 'error');
+            }
+        },
+
+        /**
+         * Thumbs-up/down feedback on saved assistant messages
+         */
+        async loadFeedback() {
+            try {
+                const response = await fetch(`/api/chats/${this.chatId}/feedback`);
+                if (!response.ok) return;
+                const result = await response.json();
+                const feedback = {};
+                (Array.isArray(result.data) ? result.data : []).forEach(f => {
+                    feedback[f.message_id] = f.rating;
+                });
+                this.feedback = feedback;
+            } catch (error) {
+                console.error('Failed to load feedback:', error);
+            }
+        },
+
+        // Rating a message again with the same rating clears it
+        async rateMessage(message, rating, commentPrompt) {
+            if (!message.dbId) return;
+
+            const clear = this.feedback[message.dbId] === rating;
+            let comment = '';
+            if (!clear && rating < 0 && commentPrompt) {
+                const answer = prompt(commentPrompt);
+                if (answer === null) return;
+                comment = answer.trim();
+            }
+
+            try {
+                const response = await fetch(`/api/messages/${message.dbId}/feedback`, {
+                    method: clear ? 'DELETE' : 'POST',
+                    headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': apiUtils.csrfToken() },
+                    body: clear ? undefined : JSON.stringify({ rating: rating, comment: comment })
+                });
+                const result = await response.json();
+                if (!response.ok) {
+                    throw new Error(result.error || 'Failed to save feedback');
+                }
+                const feedback = { ...this.feedback };
+                if (clear) {
+                    delete feedback[message.dbId];
+                } else {
+                    feedback[message.dbId] = rating;
+                }
+                this.feedback = feedback;
+            } catch (error) {
+                console.error('Failed to save feedback:', error);
+                uiUtils.showNotification(error.message, 'error');
+            }
+        },
+
+        /**
+         * Inline editing of persisted user messages
+         */
+        // Branch the chat at a message and open the branch
+        async forkFrom(message) {
+            if (!message.dbId) return;
+            try {
+                const response = await fetch(`/api/chats/${this.chatId}/fork?from_message=${message.dbId}`, {
+                    method: 'POST',
+                    headers: { 'X-CSRF-Token': apiUtils.csrfToken() }
+                });
+                const result = await response.json();
+                if (!response.ok) {
+                    throw new Error(result.error || 'Failed to fork chat');
+                }
+                window.location.href = `/chat/${result.data.id}`;
+            } catch (error) {
+                console.error('Failed to fork chat:', error);
+                uiUtils.showNotification(error.message, 'error');
+            }
+        },
+
+        startEdit(message) {
+            if (!message.dbId || this.isTyping) return;
+            this.editingMessageId = message.id;
+            this.editContent = message.content;
+        },
+
+        cancelEdit() {
+            this.editingMessageId = null;
+            this.editContent = '';
+        },
+
+        async saveEdit(message) {
+            const content = this.editContent.trim();
+            if (!content) return;
+
+            try {
+                const response = await fetch(`/api/chats/${this.chatId}/messages/${message.dbId}`, {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': apiUtils.csrfToken() },
+                    body: JSON.stringify({ content: content })
+                });
+                const result = await response.json();
+                if (!response.ok) {
+                    throw new Error(result.error || 'Failed to edit message');
+                }
+                message.content = result.data.content;
+                this.cancelEdit();
+                this.regenerate(message);
+            } catch (error) {
+                console.error('Failed to edit message:', error);
+                uiUtils.showNotification(error.message, 'error');
+            }
+        },
+
+        /**
+         * Upload the files chosen in the attachment input; they are sent with the next message
+         */
+        async uploadAttachments(event) {
+            const files = Array.from(event.target.files || []);
+            event.target.value = '';
+            await this.uploadFiles(files);
+        },
+
+        /**
+         * Upload images pasted into the message input (e.g. screenshots)
+         */
+        async pasteAttachments(event) {
+            const items = Array.from((event.clipboardData && event.clipboardData.items) || []);
+            const files = items
+                .filter(item => item.kind === 'file' && item.type.startsWith('image/'))
+                .map(item => item.getAsFile())
+                .filter(Boolean);
+            if (!files.length) return;
+
+            event.preventDefault();
+            await this.uploadFiles(files);
+        },
+
+        async uploadFiles(files) {
+            if (!files.length) return;
+
+            this.uploadingAttachment = true;
+            try {
+                for (const file of files) {
+                    const form = new FormData();
+                    form.append('file', file);
+                    const response = await fetch(`/api/chats/${this.chatId}/attachments`, {
+                        method: 'POST',
+                        headers: { 'X-CSRF-Token': apiUtils.csrfToken() },
+                        body: form
+                    });
+                    const result = await response.json();
+                    if (!response.ok) {
+                        throw new Error(`${file.name}: ${result.error || 'Failed to upload attachment'}`);
+                    }
+                    this.pendingAttachments.push(result.data);
+                }
+            } catch (error) {
+                console.error('Failed to upload attachment:', error);
+                uiUtils.showNotification(error.message, 'error');
+            } finally {
+                this.uploadingAttachment = false;
+            }
+        },
+
+        /**
+         * Remove an uploaded attachment that has not been sent yet
+         */
+        async removeAttachment(attachment) {
+            this.pendingAttachments = this.pendingAttachments.filter(a => a.id !== attachment.id);
+            try {
+                await fetch(this.attachmentURL(attachment), {
+                    method: 'DELETE',
+                    headers: { 'X-CSRF-Token': apiUtils.csrfToken() }
+                });
+            } catch (error) {
+                console.error('Failed to delete attachment:', error);
+            }
+        },
+
+        attachmentURL(attachment) {
+            return `/api/chats/${this.chatId}/attachments/${attachment.id}`;
+        },
+
+        /**
+         * Send a prompt handed over by the /new template URL once the socket is ready
+         */
+        sendPendingPrompt() {
+            if (!this.pendingPrompt) return;
+
+            this.newMessage = this.pendingPrompt;
+            this.pendingPrompt = '';
+
+            // Drop the prompt from the URL so a reload does not send it again
+            if (window.history && window.history.replaceState) {
+                window.history.replaceState(null, '', window.location.pathname);
+            }
+
+            if (this.autoSendPrompt) {
+                this.sendMessage();
+            }
+        },
+
+        /**
+         * Save the chat's system prompt; it is prepended to every following prompt
+         */
+        async saveSystemPrompt() {
+            this.savingSystemPrompt = true;
+            try {
+                const response = await fetch(`/api/chats/${this.chatId}/system-prompt`, {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': apiUtils.csrfToken() },
+                    body: JSON.stringify({ system_prompt: this.systemPrompt })
+                });
+                const result = await response.json();
+                if (!response.ok) {
+                    throw new Error(result.error || 'Failed to save system prompt');
+                }
+                this.systemPrompt = result.data.system_prompt;
+                uiUtils.showNotification(result.message || 'System prompt saved', 'success');
+            } catch (error) {
+                console.error('Failed to save system prompt:', error);
+                uiUtils.showNotification(error.message, 'error');
+            } finally {
+                this.savingSystemPrompt = false;
+            }
+        },
+
         // UI helpers
         getPlaceholderText() {
             return inputManager.getPlaceholderText('Type your message...');
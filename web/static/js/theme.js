@@ -26,9 +26,13 @@ class ThemeManager {
     }
 
     /**
-     * Get theme from localStorage with fallback
+     * Get the theme the server rendered the page with, then from localStorage with fallback
      */
     getStoredTheme() {
+        const rendered = document.documentElement.dataset.theme;
+        if (Object.values(THEMES).includes(rendered)) {
+            return rendered;
+        }
         return localStorage.getItem(STORAGE_KEYS.THEME) || THEMES.LIGHT;
     }
 
@@ -3,10 +3,27 @@
  * Common functionality used across the application
  */
 
+/**
+ * WebSocket protocol version sent with every message (models.WSProtocolVersion on the server)
+ */
+window.WS_PROTOCOL_VERSION = 2;
+
 /**
  * API utilities
  */
 window.apiUtils = {
+    /**
+     * CSRF token the server requires on POST, PUT, PATCH and DELETE requests
+     */
+    csrfToken() {
+        const meta = document.querySelector('meta[name="csrf-token"]');
+        if (meta && meta.content) {
+            return meta.content;
+        }
+        const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]*)/);
+        return match ? decodeURIComponent(match[1]) : '';
+    },
+
     /**
      * Perform fetch with error handling
      */
@@ -14,17 +31,25 @@ window.apiUtils = {
         const defaultOptions = {
             headers: {
                 'Content-Type': 'application/json',
+                'X-CSRF-Token': this.csrfToken(),
             },
         };
 
-        const mergedOptions = { ...defaultOptions, ...options };
+        const mergedOptions = {
+            ...defaultOptions,
+            ...options,
+            headers: { ...defaultOptions.headers, ...options.headers },
+        };
 
         try {
             const response = await fetch(url, mergedOptions);
             
             if (!response.ok) {
                 const errorData = await response.json().catch(() => ({}));
-                throw new Error(errorData.error || `HTTP ${response.status}: ${response.statusText}`);
+                const error = new Error(errorData.error || `HTTP ${response.status}: ${response.statusText}`);
+                // Lets server logs be correlated with client-reported errors
+                error.requestId = errorData.request_id || response.headers.get('X-Request-ID');
+                throw error;
             }
 
             return await response.json();
@@ -34,6 +59,16 @@ window.apiUtils = {
         }
     },
 
+    /**
+     * URL of a new WebSocket connection, authenticated with a single-use ticket
+     */
+    async webSocketUrl() {
+        const response = await this.post('/api/ws/ticket', {});
+        const ticket = (response.data || response).ticket;
+        const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
+        return `${protocol}//${window.location.host}/ws?ticket=${encodeURIComponent(ticket)}`;
+    },
+
     /**
      * GET request
      */
@@ -395,13 +430,15 @@ window.errorUtils = {
                 stack: error?.stack || 'No stack trace',
                 url: window.location.href,
                 userAgent: navigator.userAgent,
-                level: level
+                level: level,
+                requestId: error?.requestId || ''
             };
 
             await fetch('/api/logs/client', {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
+                    'X-CSRF-Token': window.apiUtils.csrfToken(),
                 },
                 body: JSON.stringify(errorData)
             });